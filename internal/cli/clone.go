@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/internal/transfer"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	cloneTarget           string
+	cloneAnonymize        bool
+	cloneRules            string
+	cloneSalt             string
+	cloneBatchSize        int
+	clonePackage          string
+	cloneAllowDestructive bool
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Copy the schema and data of the database to another, optionally anonymized",
+	Long: `Copy the database to a target database, for example to refresh a staging
+environment from production.
+
+The schema of the models is applied to the target first, creating the target
+database when it does not exist. The rows of every model table are then
+streamed from the source with COPY, referenced tables first, in one
+transaction on the target. Target tables are emptied before they are loaded
+and their sequences moved past the copied values.
+
+With --anonymize, column values are masked on the way. A column's mask comes
+from its anonymize tag, e.g. storm:"anonymize:email", or from a rules file
+given with --rules, which takes precedence:
+
+  users:
+    email: email
+    full_name: hash
+    api_token: null
+    phone: fixed:000-000-0000
+
+Masks:
+  null           replace the value with NULL
+  email          replace the value with a fake address, user_<hash>@example.com
+  hash           replace the value with a salted hash
+  fixed:<value>  replace the value with <value>
+  keep           copy the value as it is
+
+Hashes are salted, random per run unless --salt is given, and deterministic
+within a run: equal values stay equal, so unique values stay unique. Columns
+tagged masked or sensitive without a mask are listed as a warning.`,
+	Example: `  storm clone --target postgres://localhost/app_staging
+  storm clone --url $PRODUCTION_URL --target $STAGING_URL --anonymize
+  storm clone --target $STAGING_URL --anonymize --rules anonymize.yaml --salt refresh-2024`,
+	RunE:         runClone,
+	SilenceUsage: true,
+}
+
+func init() {
+	cloneCmd.Flags().StringVar(&cloneTarget, "target", "", "Database URL to copy into (required)")
+	cloneCmd.Flags().BoolVar(&cloneAnonymize, "anonymize", false, "Mask column values using anonymize tags and the rules file")
+	cloneCmd.Flags().StringVar(&cloneRules, "rules", "", "YAML file of masks by table and column (implies --anonymize)")
+	cloneCmd.Flags().StringVar(&cloneSalt, "salt", "", "Salt of hashed values, to mask alike across runs (default: random)")
+	cloneCmd.Flags().IntVar(&cloneBatchSize, "batch-size", 1000, "Rows per COPY batch")
+	cloneCmd.Flags().StringVar(&clonePackage, "package", "", "Path to package containing models")
+	cloneCmd.Flags().BoolVar(&cloneAllowDestructive, "allow-destructive", false, "Allow destructive schema changes on the target")
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	if cloneTarget == "" {
+		return fmt.Errorf("clone target is required: use --target")
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("no database connection: use --url or set database.url in storm.yaml")
+	}
+	if cloneTarget == databaseURL {
+		return fmt.Errorf("clone target must be a different database than the source")
+	}
+
+	packagePath := clonePackage
+	if packagePath == "" && stormConfig != nil {
+		packagePath = stormConfig.Models.Spec()
+	}
+	if packagePath == "" {
+		packagePath = "./models"
+	}
+
+	var rules map[string]map[string]transfer.Mask
+	if cloneRules != "" {
+		var err error
+		if rules, err = readCloneRules(cloneRules); err != nil {
+			return err
+		}
+	}
+	anonymize := cloneAnonymize || cloneRules != ""
+
+	tables, warnings, err := cloneModelTables(packagePath, anonymize, rules)
+	if err != nil {
+		return err
+	}
+	clone := transfer.Clone{Tables: tables, Salt: cloneSalt, BatchSize: cloneBatchSize}
+	if err := clone.Validate(); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if anonymize {
+		printCloneMasks(out, tables, warnings)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	source, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer source.Close()
+	if err := source.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	if err := migrator.EnsureDatabaseExists(cloneTarget); err != nil {
+		return err
+	}
+	target, err := sql.Open("postgres", cloneTarget)
+	if err != nil {
+		return fmt.Errorf("failed to open target database connection: %w", err)
+	}
+	defer target.Close()
+	if err := target.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping target database: %w", err)
+	}
+
+	naming, err := modelNaming()
+	if err != nil {
+		return err
+	}
+	result, err := migrator.NewAtlasMigrator(migrator.NewDBConfig(cloneTarget)).GenerateMigration(ctx, target, migrator.MigrationOptions{
+		PackagePath:      packagePath,
+		AllowDestructive: cloneAllowDestructive,
+		PushToDB:         true,
+		Naming:           naming,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply the schema to the target: %w", err)
+	}
+	if result.HasDestructive && !cloneAllowDestructive {
+		return fmt.Errorf("the target schema needs destructive changes; rerun with --allow-destructive to apply them")
+	}
+
+	count, err := clone.Run(ctx, source, target, func(p transfer.CloneProgress) {
+		fmt.Fprintf(out, "  %s: %d rows copied (%s)\n", p.Table, p.Rows, p.Elapsed.Round(10*time.Millisecond))
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Cloned %d rows of %d tables\n", count, len(tables))
+	return nil
+}
+
+// cloneModelTables returns the tables of the models of packagePath in the
+// order they are loaded, with their masks when anonymizing, and warnings for
+// masked or sensitive columns left without a mask
+func cloneModelTables(packagePath string, anonymize bool, rules map[string]map[string]transfer.Mask) ([]transfer.CloneTable, []string, error) {
+	models, schema, err := parseModelSchema(packagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	for table := range rules {
+		if _, ok := schema.Tables[table]; !ok {
+			return nil, nil, fmt.Errorf("clone rules name table %s, which no model in %s maps to", table, packagePath)
+		}
+	}
+
+	tags := make(map[string]map[string]string)
+	for _, model := range models {
+		for _, field := range model.Fields {
+			if !field.IsRelationship && field.DBName != "" {
+				tags[model.TableName+"."+field.DBName] = field.DBDef
+			}
+		}
+	}
+
+	var tables []transfer.CloneTable
+	var warnings []string
+	for _, name := range schema.GetTableNames() {
+		model := schema.Tables[name]
+		table := transfer.CloneTable{Name: name, Masks: make(map[string]transfer.Mask)}
+		for _, column := range model.Columns {
+			table.Columns = append(table.Columns, modelColumn(column))
+			if column.IsPrimaryKey {
+				table.Key = append(table.Key, column.Name)
+			}
+			if column.IsAutoIncrement {
+				table.Serial = append(table.Serial, column.Name)
+			}
+			if !anonymize {
+				continue
+			}
+
+			dbDef := tags[name+"."+column.Name]
+			if rule, ok := dbDef["anonymize"]; ok {
+				mask, err := transfer.ParseMask(rule)
+				if err != nil {
+					return nil, nil, fmt.Errorf("column %s.%s: %w", name, column.Name, err)
+				}
+				table.Masks[column.Name] = mask
+			}
+			if mask, ok := rules[name][column.Name]; ok {
+				table.Masks[column.Name] = mask
+			}
+			if _, masked := table.Masks[column.Name]; !masked {
+				_, isMasked := dbDef["masked"]
+				_, isSensitive := dbDef["sensitive"]
+				if isMasked || isSensitive {
+					warnings = append(warnings, fmt.Sprintf("%s.%s is tagged masked or sensitive but has no anonymize rule; it is copied as it is", name, column.Name))
+				}
+			}
+		}
+		for _, constraint := range model.Constraints {
+			if constraint.Type == "PRIMARY KEY" && len(table.Key) == 0 {
+				table.Key = constraint.Columns
+			}
+		}
+		tables = append(tables, table)
+	}
+	return tables, warnings, nil
+}
+
+// readCloneRules reads a rules file mapping tables to the masks of their
+// columns. YAML reads a bare null as empty, which is the null mask.
+func readCloneRules(path string) (map[string]map[string]transfer.Mask, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clone rules: %w", err)
+	}
+
+	var raw map[string]map[string]string
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&raw); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse clone rules %s: %w", path, err)
+	}
+
+	rules := make(map[string]map[string]transfer.Mask, len(raw))
+	for table, columns := range raw {
+		rules[table] = make(map[string]transfer.Mask, len(columns))
+		for column, rule := range columns {
+			if rule == "" {
+				rule = transfer.MaskNull
+			}
+			mask, err := transfer.ParseMask(rule)
+			if err != nil {
+				return nil, fmt.Errorf("clone rules %s: %s.%s: %w", path, table, column, err)
+			}
+			rules[table][column] = mask
+		}
+	}
+	return rules, nil
+}
+
+func printCloneMasks(out io.Writer, tables []transfer.CloneTable, warnings []string) {
+	fmt.Fprintln(out, "Masks:")
+	masked := 0
+	for _, table := range tables {
+		columns := make([]string, 0, len(table.Masks))
+		for column := range table.Masks {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		for _, column := range columns {
+			fmt.Fprintf(out, "  %s.%s: %s\n", table.Name, column, table.Masks[column])
+			masked++
+		}
+	}
+	if masked == 0 {
+		fmt.Fprintln(out, "  none")
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(out, "Warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/transfer"
+)
+
+func TestCloneModelTables(t *testing.T) {
+	dir := t.TempDir()
+	source := `package models
+
+type User struct {
+	_ struct{} ` + "`" + `storm:"table:users"` + "`" + `
+
+	ID       int64   ` + "`" + `db:"id" storm:"column:id;type:bigserial;primary_key"` + "`" + `
+	Email    string  ` + "`" + `db:"email" storm:"column:email;type:varchar(255);not_null;unique;anonymize:email"` + "`" + `
+	Name     string  ` + "`" + `db:"name" storm:"column:name;type:text;not_null;anonymize:hash"` + "`" + `
+	SSN      *string ` + "`" + `db:"ssn" storm:"column:ssn;type:text;sensitive"` + "`" + `
+	APIToken *string ` + "`" + `db:"api_token" storm:"column:api_token;type:text"` + "`" + `
+}
+
+type Post struct {
+	_ struct{} ` + "`" + `storm:"table:posts"` + "`" + `
+
+	ID     int64  ` + "`" + `db:"id" storm:"column:id;type:bigserial;primary_key"` + "`" + `
+	UserID int64  ` + "`" + `db:"user_id" storm:"column:user_id;type:bigint;not_null;foreign_key:users.id"` + "`" + `
+	Title  string ` + "`" + `db:"title" storm:"column:title;type:text;not_null"` + "`" + `
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rulesPath := filepath.Join(dir, "anonymize.yaml")
+	rulesFile := "users:\n  name: keep\n  api_token: null\n"
+	if err := os.WriteFile(rulesPath, []byte(rulesFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := readCloneRules(rulesPath)
+	if err != nil {
+		t.Fatalf("readCloneRules failed: %v", err)
+	}
+
+	tables, warnings, err := cloneModelTables(dir, true, rules)
+	if err != nil {
+		t.Fatalf("cloneModelTables failed: %v", err)
+	}
+	if len(tables) != 2 || tables[0].Name != "users" || tables[1].Name != "posts" {
+		t.Fatalf("expected users before posts, got %+v", tables)
+	}
+
+	users := tables[0]
+	expected := map[string]transfer.Mask{
+		"email":     {Kind: transfer.MaskEmail},
+		"name":      {Kind: transfer.MaskKeep},
+		"api_token": {Kind: transfer.MaskNull},
+	}
+	if len(users.Masks) != len(expected) {
+		t.Errorf("expected masks %v, got %v", expected, users.Masks)
+	}
+	for column, mask := range expected {
+		if users.Masks[column] != mask {
+			t.Errorf("expected %s masked with %s, got %s", column, mask, users.Masks[column])
+		}
+	}
+	if strings.Join(users.Key, ",") != "id" || strings.Join(users.Serial, ",") != "id" {
+		t.Errorf("expected id as key and serial, got %v and %v", users.Key, users.Serial)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "users.ssn") {
+		t.Errorf("expected a warning for users.ssn, got %v", warnings)
+	}
+
+	tables, _, err = cloneModelTables(dir, false, nil)
+	if err != nil || len(tables[0].Masks) != 0 {
+		t.Errorf("expected no masks without anonymizing, got %v (%v)", tables[0].Masks, err)
+	}
+
+	rules["orders"] = map[string]transfer.Mask{"total": {Kind: transfer.MaskNull}}
+	if _, _, err := cloneModelTables(dir, true, rules); err == nil || !strings.Contains(err.Error(), "table orders") {
+		t.Errorf("expected an unknown table error, got %v", err)
+	}
+}
+
+func TestReadCloneRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anonymize.yaml")
+	if err := os.WriteFile(path, []byte("users:\n  email: scramble\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCloneRules(path); err == nil || !strings.Contains(err.Error(), `users.email: unknown mask "scramble"`) {
+		t.Errorf("expected an unknown mask error, got %v", err)
+	}
+}
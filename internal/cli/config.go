@@ -4,7 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/eleven-am/storm/internal/privileges"
+	"github.com/eleven-am/storm/pkg/storm"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,29 +26,198 @@ type StormConfig struct {
 		MaxConnections int    `yaml:"max_connections"`
 	} `yaml:"database"`
 
-	Models struct {
-		Package string `yaml:"package"`
-	} `yaml:"models"`
+	Models ModelsConfig `yaml:"models"`
 
 	Migrations struct {
 		Directory string `yaml:"directory"`
 		Table     string `yaml:"table"`
 		AutoApply bool   `yaml:"auto_apply"`
+		Naming    string `yaml:"naming,omitempty"`
+		Header    string `yaml:"header,omitempty"`
 	} `yaml:"migrations"`
 
 	ORM struct {
-		GenerateHooks bool `yaml:"generate_hooks"`
-		GenerateTests bool `yaml:"generate_tests"`
-		GenerateMocks bool `yaml:"generate_mocks"`
+		GenerateHooks    bool   `yaml:"generate_hooks"`
+		GenerateTests    bool   `yaml:"generate_tests"`
+		GenerateMocks    bool   `yaml:"generate_mocks"`
+		GenerateHandlers bool   `yaml:"generate_handlers"`
+		GenerateDTOs     bool   `yaml:"generate_dtos"`
+		TemplatesDir     string `yaml:"templates_dir,omitempty"`
 	} `yaml:"orm"`
 
 	Schema struct {
 		StrictMode       bool   `yaml:"strict_mode"`
 		NamingConvention string `yaml:"naming_convention"`
+		// Plurals adds irregular plurals used to derive table names, e.g. person: people
+		Plurals map[string]string `yaml:"plurals,omitempty"`
+		// TableNames overrides the derived table name of a model, keyed by struct name
+		TableNames map[string]string `yaml:"table_names,omitempty"`
 	} `yaml:"schema"`
+
+	// Privileges declares roles and grants managed by storm grants
+	Privileges privileges.Config `yaml:"privileges,omitempty"`
+
+	// Environments holds per-environment overrides, e.g. a production block
+	// with its own database settings, applied on top of the base config
+	Environments map[string]yaml.Node `yaml:"environments,omitempty"`
+
+	// Databases holds named database targets, e.g. an analytics block with
+	// its own database, models and migrations settings, applied on top of the
+	// base config when selected with --database
+	Databases map[string]yaml.Node `yaml:"databases,omitempty"`
+
+	// Environment is the environment the config was loaded for, if any
+	Environment string `yaml:"-"`
+
+	// Target is the database target the config was loaded for, if any
+	Target string `yaml:"-"`
+}
+
+// ModelsConfig lists the packages holding model definitions. package may be a
+// single directory or a list; packages also accepts per-package output dirs.
+// include and exclude pick which structs of those packages are models, by
+// struct name or path.Match pattern.
+type ModelsConfig struct {
+	Package  string         `yaml:"package,omitempty"`
+	Packages []ModelPackage `yaml:"packages,omitempty"`
+	Include  []string       `yaml:"include,omitempty"`
+	Exclude  []string       `yaml:"exclude,omitempty"`
+}
+
+// ModelPackage is a models directory or pattern and where its code is generated
+type ModelPackage struct {
+	Path   string `yaml:"path"`
+	Output string `yaml:"output,omitempty"`
+}
+
+// UnmarshalYAML accepts either a plain path or a mapping with path and output
+func (p *ModelPackage) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.Path = node.Value
+		return nil
+	}
+	type plain ModelPackage
+	return node.Decode((*plain)(p))
+}
+
+// UnmarshalYAML lets package hold a list, which is treated like packages
+func (m *ModelsConfig) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Package  yaml.Node      `yaml:"package"`
+		Packages []ModelPackage `yaml:"packages"`
+		Include  []string       `yaml:"include"`
+		Exclude  []string       `yaml:"exclude"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	m.Packages = raw.Packages
+	m.Include = raw.Include
+	m.Exclude = raw.Exclude
+	switch raw.Package.Kind {
+	case yaml.ScalarNode:
+		m.Package = raw.Package.Value
+	case yaml.SequenceNode:
+		var listed []ModelPackage
+		if err := raw.Package.Decode(&listed); err != nil {
+			return err
+		}
+		m.Packages = append(listed, m.Packages...)
+	case 0:
+	default:
+		return fmt.Errorf("line %d: models.package must be a path or a list of paths", raw.Package.Line)
+	}
+	return nil
+}
+
+// All returns every configured models package, with package listed first
+func (m ModelsConfig) All() []ModelPackage {
+	var all []ModelPackage
+	if m.Package != "" {
+		all = append(all, ModelPackage{Path: m.Package})
+	}
+	return append(all, m.Packages...)
+}
+
+// Spec joins the configured packages into one specification that parses them
+// together, so every command sees a single schema
+func (m ModelsConfig) Spec() string {
+	var paths []string
+	for _, pkg := range m.All() {
+		paths = append(paths, pkg.Path)
+	}
+	return parser.JoinPackageSpec(paths)
+}
+
+// Resolve expands patterns into one entry per directory. {name} in an output is
+// replaced with the path segment the pattern matched, e.g. "billing" for
+// ./domains/billing/models matched by ./domains/*/models, or the directory name
+// for plain paths. A pattern matching several directories needs {name} in its
+// output, or no output at all.
+func (m ModelsConfig) Resolve() ([]ModelPackage, error) {
+	var resolved []ModelPackage
+	seen := make(map[string]string)
+
+	for _, pkg := range m.All() {
+		dirs, err := parser.ResolvePackageDirs(pkg.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(dirs) > 1 && pkg.Output != "" && !strings.Contains(pkg.Output, "{name}") {
+			return nil, fmt.Errorf("models package %s matches %d directories: use {name} in its output", pkg.Path, len(dirs))
+		}
+
+		for _, dir := range dirs {
+			output := dir
+			if pkg.Output != "" {
+				output = filepath.Clean(strings.ReplaceAll(pkg.Output, "{name}", domainName(pkg.Path, dir)))
+			}
+			if previous, ok := seen[filepath.Clean(output)]; ok {
+				return nil, fmt.Errorf("models packages %s and %s generate into the same output %s", previous, dir, output)
+			}
+			seen[filepath.Clean(output)] = dir
+			resolved = append(resolved, ModelPackage{Path: dir, Output: output})
+		}
+	}
+
+	return resolved, nil
 }
 
+// domainName is the first segment of dir below the fixed prefix of pattern
+func domainName(pattern, dir string) string {
+	var prefix []string
+	for _, segment := range strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/") {
+		if segment == "..." || strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		prefix = append(prefix, segment)
+	}
+
+	rel, err := filepath.Rel(filepath.FromSlash(strings.Join(prefix, "/")), dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return filepath.Base(dir)
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")[0]
+}
+
+// LoadStormConfig loads the config for the environment named by STORM_ENV
 func LoadStormConfig(path string) (*StormConfig, error) {
+	return LoadStormConfigForEnv(path, os.Getenv("STORM_ENV"))
+}
+
+// LoadStormConfigForEnv loads the config, applies the named environment block
+// on top of the base settings and expands ${VAR} references in values.
+// Unknown keys and invalid values are reported together as a ConfigValidationError.
+func LoadStormConfigForEnv(path, env string) (*StormConfig, error) {
+	return LoadStormConfigForTarget(path, env, "")
+}
+
+// LoadStormConfigForTarget loads the config like LoadStormConfigForEnv, then
+// applies the databases.<target> block, so the target's database, models and
+// migrations settings replace the base ones. Environment blocks may override
+// targets too, under their own databases section.
+func LoadStormConfigForTarget(path, env, target string) (*StormConfig, error) {
 	if path == "" {
 		locations := []string{"storm.yaml", "storm.yml", ".storm.yaml", ".storm.yml"}
 		for _, loc := range locations {
@@ -51,6 +227,9 @@ func LoadStormConfig(path string) (*StormConfig, error) {
 			}
 		}
 		if path == "" {
+			if target != "" {
+				return nil, fmt.Errorf("database %q is not defined: no config file found", target)
+			}
 			return nil, nil
 		}
 	}
@@ -60,10 +239,36 @@ func LoadStormConfig(path string) (*StormConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	validator := &configValidator{file: path}
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	if len(root.Content) > 0 {
+		doc = root.Content[0]
+	}
+	validator.checkKeys(doc, reflect.TypeOf(StormConfig{}), "")
+
+	if env != "" {
+		if err := applyEnvironment(doc, env); err != nil {
+			return nil, err
+		}
+	}
+	if target != "" {
+		if err := applyDatabaseTarget(doc, target); err != nil {
+			return nil, err
+		}
+	}
+	expandConfigVars(doc)
+
 	var config StormConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := doc.Decode(&config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	config.Environment = env
+	config.Target = target
 
 	if config.Database.Driver == "" {
 		config.Database.Driver = "postgres"
@@ -71,7 +276,7 @@ func LoadStormConfig(path string) (*StormConfig, error) {
 	if config.Database.MaxConnections == 0 {
 		config.Database.MaxConnections = 25
 	}
-	if config.Models.Package == "" {
+	if config.Models.Package == "" && len(config.Models.Packages) == 0 {
 		config.Models.Package = "./models"
 	}
 	if config.Migrations.Directory == "" {
@@ -84,9 +289,144 @@ func LoadStormConfig(path string) (*StormConfig, error) {
 		config.Schema.NamingConvention = "snake_case"
 	}
 
+	validator.checkValues(&config, doc)
+	if err := validator.err(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// applyEnvironment merges the environments.<env> block into the top level of doc
+func applyEnvironment(doc *yaml.Node, env string) error {
+	environments := mappingValue(doc, "environments")
+	if environments == nil || environments.Kind != yaml.MappingNode {
+		return fmt.Errorf("environment %q is not defined: config has no environments section", env)
+	}
+
+	block := mappingValue(environments, env)
+	if block == nil {
+		var names []string
+		for i := 0; i+1 < len(environments.Content); i += 2 {
+			names = append(names, environments.Content[i].Value)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("environment %q is not defined (available: %s)", env, strings.Join(names, ", "))
+	}
+	if block.Kind != yaml.MappingNode {
+		return fmt.Errorf("environment %q must be a mapping of settings", env)
+	}
+
+	mergeMapping(doc, block)
+	return nil
+}
+
+// applyDatabaseTarget merges the databases.<name> block into the top level of doc
+func applyDatabaseTarget(doc *yaml.Node, name string) error {
+	databases := mappingValue(doc, "databases")
+	if databases == nil || databases.Kind != yaml.MappingNode {
+		return fmt.Errorf("database %q is not defined: config has no databases section", name)
+	}
+
+	block := mappingValue(databases, name)
+	if block == nil {
+		var names []string
+		for i := 0; i+1 < len(databases.Content); i += 2 {
+			names = append(names, databases.Content[i].Value)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("database %q is not defined (available: %s)", name, strings.Join(names, ", "))
+	}
+	if block.Kind != yaml.MappingNode {
+		return fmt.Errorf("database %q must be a mapping of settings", name)
+	}
+
+	mergeMapping(doc, block)
+	return nil
+}
+
+// mergeMapping overlays src onto dst, merging nested mappings key by key
+func mergeMapping(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		found := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value != key.Value {
+				continue
+			}
+			found = true
+			if dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeMapping(dst.Content[j+1], value)
+			} else {
+				dst.Content[j+1] = value
+			}
+			break
+		}
+
+		if !found {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
+
+// mappingValue returns the value stored under key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+var configVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandConfigVars replaces ${VAR} and ${VAR:-default} in scalar values.
+// The environments section is left alone since only the selected block applies.
+func expandConfigVars(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "environments" {
+				continue
+			}
+			expandConfigVars(node.Content[i+1])
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			expandConfigVars(child)
+		}
+	case yaml.ScalarNode:
+		expanded := expandConfigValue(node.Value)
+		if expanded != node.Value {
+			node.Value = expanded
+			if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 {
+				// Re-resolve plain scalars so ${PORT} can fill an integer field
+				node.Tag = ""
+			}
+		}
+	}
+}
+
+// expandConfigValue expands ${VAR} references; unset variables fall back to
+// their default, or to an empty string when none is given
+func expandConfigValue(value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return configVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+		parts := configVarPattern.FindStringSubmatch(match)
+		if v, ok := os.LookupEnv(parts[1]); ok && v != "" {
+			return v
+		}
+		return parts[3]
+	})
+}
+
 func GetConfigPath() string {
 	if path := os.Getenv("STORM_CONFIG"); path != "" {
 		return path
@@ -122,3 +462,32 @@ func SaveStormConfig(config *StormConfig, path string) error {
 
 	return nil
 }
+
+// applySchemaSettings copies the naming settings of the loaded storm.yaml into config
+func applySchemaSettings(config *storm.Config) {
+	if stormConfig == nil {
+		return
+	}
+	if stormConfig.Schema.NamingConvention != "" {
+		config.NamingConvention = stormConfig.Schema.NamingConvention
+	}
+	config.Plurals = stormConfig.Schema.Plurals
+	config.TableNames = stormConfig.Schema.TableNames
+	config.IncludeModels = stormConfig.Models.Include
+	config.ExcludeModels = stormConfig.Models.Exclude
+}
+
+// modelNaming returns the table and column naming configured in storm.yaml,
+// along with the models.include and models.exclude lists
+func modelNaming() (*parser.Naming, error) {
+	if stormConfig == nil {
+		return parser.DefaultNaming(), nil
+	}
+	naming, err := parser.BuildNaming(stormConfig.Schema.NamingConvention, stormConfig.Schema.Plurals, stormConfig.Schema.TableNames)
+	if err != nil {
+		return nil, err
+	}
+	naming.Include = stormConfig.Models.Include
+	naming.Exclude = stormConfig.Models.Exclude
+	return naming, nil
+}
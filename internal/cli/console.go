@@ -0,0 +1,493 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eleven-am/storm/internal/introspect"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consolePackage string
+	consoleMaxRows int
+)
+
+var consoleCmd = &cobra.Command{
+	Use:   "console",
+	Short: "Interactive shell for querying the database",
+	Long: `Open an interactive shell connected to the database from storm.yaml (or --url).
+
+Enter SQL statements terminated by ';', or fluent queries written like the
+generated repository API against the models package:
+
+  Users.Query().Where(Users.Email.Contains("@example.com")).OrderBy(Users.CreatedAt.Desc()).Limit(10).Find()
+  Posts.Where(storm.Or(Posts.Status.Eq("draft"), Posts.PublishedAt.IsNull())).Count()
+
+Fluent queries are read-only. Type \? for the list of shell commands.`,
+	RunE: runConsole,
+}
+
+func init() {
+	consoleCmd.Flags().StringVar(&consolePackage, "package", "", "Path to package containing models")
+	consoleCmd.Flags().IntVar(&consoleMaxRows, "max-rows", 100, "Maximum number of rows to print per query")
+}
+
+const consoleHelp = `Shell commands:
+  \dt              list tables
+  \d <table>       describe a table's columns, keys and indexes
+  \models [model]  list models, or the columns of one model
+  \x               toggle expanded output
+  \?               show this help
+  \q               quit
+
+Anything else is run as SQL (end statements with ';') or, when it starts with a
+model such as Users, as a fluent query: Where, OrderBy, Limit, Offset, then
+Find, First, Count or Exists.`
+
+// console is an interactive session against a database
+type console struct {
+	db       *sql.DB
+	out      io.Writer
+	models   map[string]*consoleModel
+	maxRows  int
+	expanded bool
+}
+
+func runConsole(cmd *cobra.Command, args []string) error {
+	if databaseURL == "" {
+		return fmt.Errorf("no database connection: use --url or set database.url in storm.yaml")
+	}
+
+	packagePath := consolePackage
+	if packagePath == "" && stormConfig != nil {
+		packagePath = stormConfig.Models.Spec()
+	}
+	if packagePath == "" {
+		packagePath = "./models"
+	}
+
+	out := cmd.OutOrStdout()
+	models, err := loadConsoleModels(packagePath)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: fluent queries unavailable, failed to load models from %s: %v\n", packagePath, err)
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	fmt.Fprintf(out, "Connected to %s\n", redactURL(databaseURL))
+	fmt.Fprintln(out, `Type \? for help, \q to quit.`)
+
+	c := &console{db: db, out: out, models: models, maxRows: consoleMaxRows}
+	return c.run(ctx, cmd.InOrStdin())
+}
+
+// run reads commands until \q or end of input. SQL may span several lines
+// and runs once a line ends with ';'.
+func (c *console) run(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var statement strings.Builder
+	c.prompt(false)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		pending := statement.Len() > 0
+
+		switch {
+		case !pending && line == "":
+		case !pending && (line == "exit" || line == "quit"):
+			return nil
+		case !pending && strings.HasPrefix(line, `\`):
+			if c.meta(ctx, line) {
+				return nil
+			}
+		case !pending && isFluentQuery(line, c.models):
+			c.report(c.runFluent(ctx, line))
+		default:
+			statement.WriteString(line)
+			statement.WriteString("\n")
+			if strings.HasSuffix(line, ";") {
+				c.report(c.runSQL(ctx, statement.String()))
+				statement.Reset()
+			}
+		}
+		c.prompt(statement.Len() > 0)
+	}
+
+	fmt.Fprintln(c.out)
+	return scanner.Err()
+}
+
+func (c *console) prompt(continuation bool) {
+	if continuation {
+		fmt.Fprint(c.out, "storm-> ")
+	} else {
+		fmt.Fprint(c.out, "storm=> ")
+	}
+}
+
+func (c *console) report(err error) {
+	if err != nil {
+		fmt.Fprintf(c.out, "Error: %v\n", err)
+	}
+}
+
+// meta runs a backslash command, reporting whether the console should exit
+func (c *console) meta(ctx context.Context, line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case `\q`, `\quit`:
+		return true
+	case `\?`, `\h`, `\help`:
+		fmt.Fprintln(c.out, consoleHelp)
+	case `\x`:
+		c.expanded = !c.expanded
+		state := "off"
+		if c.expanded {
+			state = "on"
+		}
+		fmt.Fprintf(c.out, "Expanded display is %s.\n", state)
+	case `\dt`:
+		c.report(c.listTables(ctx))
+	case `\d`:
+		if len(fields) < 2 {
+			c.report(c.listTables(ctx))
+		} else {
+			c.report(c.describeTable(ctx, fields[1]))
+		}
+	case `\models`:
+		name := ""
+		if len(fields) > 1 {
+			name = fields[1]
+		}
+		c.report(c.listModels(name))
+	default:
+		fmt.Fprintf(c.out, "Unknown command %s. Type \\? for help.\n", fields[0])
+	}
+	return false
+}
+
+// runSQL executes a raw statement, printing rows for queries and the number
+// of affected rows for everything else
+func (c *console) runSQL(ctx context.Context, statement string) error {
+	statement = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(statement), ";"))
+	if statement == "" {
+		return nil
+	}
+
+	start := time.Now()
+	if !returnsRows(statement) {
+		result, err := c.db.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		affected, _ := result.RowsAffected()
+		fmt.Fprintf(c.out, "OK, %d row(s) affected (%s)\n", affected, elapsed(start))
+		return nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return c.printRows(rows, start)
+}
+
+// runFluent builds and runs a fluent query
+func (c *console) runFluent(ctx context.Context, line string) error {
+	query, err := buildFluentQuery(line, c.models)
+	if err != nil {
+		return err
+	}
+	if debug {
+		fmt.Fprintf(c.out, "%s %v\n", query.SQL, query.Args)
+	}
+
+	start := time.Now()
+	switch query.Terminal {
+	case "Count":
+		var count int64
+		if err := c.db.QueryRowContext(ctx, query.SQL, query.Args...).Scan(&count); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "%d (%s)\n", count, elapsed(start))
+		return nil
+	case "Exists":
+		rows, err := c.db.QueryContext(ctx, query.SQL, query.Args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		exists := rows.Next()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "%t (%s)\n", exists, elapsed(start))
+		return nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, query.SQL, query.Args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return c.printRows(rows, start)
+}
+
+func (c *console) listTables(ctx context.Context) error {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT table_schema AS schema, table_name AS name
+		FROM information_schema.tables
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+		AND table_type = 'BASE TABLE'
+		ORDER BY table_schema, table_name`)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+	return c.printRows(rows, time.Now())
+}
+
+func (c *console) describeTable(ctx context.Context, name string) error {
+	schemaName, tableName := "public", name
+	if schema, table, ok := strings.Cut(name, "."); ok {
+		schemaName, tableName = schema, table
+	}
+
+	table, err := introspect.NewInspector(c.db, "postgres").GetTable(ctx, schemaName, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to describe %s: %w", name, err)
+	}
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+
+	fmt.Fprintf(c.out, "Table \"%s.%s\"\n", schemaName, tableName)
+	var rows [][]string
+	for _, col := range table.Columns {
+		nullable, def := "not null", ""
+		if col.IsNullable {
+			nullable = ""
+		}
+		if col.DefaultValue != nil {
+			def = *col.DefaultValue
+		}
+		rows = append(rows, []string{col.Name, col.DataType, nullable, def})
+	}
+	c.printTable([]string{"column", "type", "nullable", "default"}, rows)
+
+	if table.PrimaryKey != nil {
+		fmt.Fprintf(c.out, "Primary key: %s (%s)\n", table.PrimaryKey.Name, strings.Join(table.PrimaryKey.Columns, ", "))
+	}
+	if len(table.Indexes) > 0 {
+		fmt.Fprintln(c.out, "Indexes:")
+		for _, index := range table.Indexes {
+			columns := make([]string, len(index.Columns))
+			for i, col := range index.Columns {
+				columns[i] = col.Name
+				if col.Expression != "" {
+					columns[i] = col.Expression
+				}
+			}
+			kind := ""
+			if index.IsUnique {
+				kind = " UNIQUE"
+			}
+			fmt.Fprintf(c.out, "  %s%s (%s)\n", index.Name, kind, strings.Join(columns, ", "))
+		}
+	}
+	if len(table.ForeignKeys) > 0 {
+		fmt.Fprintln(c.out, "Foreign keys:")
+		for _, fk := range table.ForeignKeys {
+			fmt.Fprintf(c.out, "  %s (%s) REFERENCES %s(%s) ON DELETE %s\n",
+				fk.Name, strings.Join(fk.Columns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", "), fk.OnDelete)
+		}
+	}
+	return nil
+}
+
+func (c *console) listModels(name string) error {
+	if len(c.models) == 0 {
+		return fmt.Errorf("no models loaded")
+	}
+
+	if name != "" {
+		model, ok := c.models[name]
+		if !ok {
+			return fmt.Errorf("unknown model %s", name)
+		}
+		var rows [][]string
+		for _, col := range model.Columns {
+			rows = append(rows, []string{model.Name + "s." + col.Field, col.Name, col.GoType})
+		}
+		fmt.Fprintf(c.out, "Model %s (table %s)\n", model.Name, model.Table)
+		c.printTable([]string{"field", "column", "type"}, rows)
+		return nil
+	}
+
+	var rows [][]string
+	for key, model := range c.models {
+		if key == model.Name {
+			rows = append(rows, []string{model.Name, model.Name + "s", model.Table, fmt.Sprint(len(model.Columns))})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+	c.printTable([]string{"model", "query as", "table", "columns"}, rows)
+	return nil
+}
+
+// printRows prints up to maxRows rows of a result set
+func (c *console) printRows(rows *sql.Rows, start time.Time) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var data [][]string
+	truncated := false
+	for rows.Next() {
+		if c.maxRows > 0 && len(data) == c.maxRows {
+			truncated = true
+			break
+		}
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+
+		row := make([]string, len(columns))
+		for i, value := range values {
+			row[i] = formatConsoleValue(value)
+		}
+		data = append(data, row)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	c.printTable(columns, data)
+	if truncated {
+		fmt.Fprintf(c.out, "(first %d rows shown, use --max-rows or LIMIT to change)\n", len(data))
+	} else {
+		fmt.Fprintf(c.out, "(%d row(s), %s)\n", len(data), elapsed(start))
+	}
+	return nil
+}
+
+// printTable prints rows as an aligned table, or one record per block in expanded mode
+func (c *console) printTable(headers []string, rows [][]string) {
+	if c.expanded {
+		width := 0
+		for _, header := range headers {
+			width = max(width, utf8.RuneCountInString(header))
+		}
+		for i, row := range rows {
+			fmt.Fprintf(c.out, "-[ RECORD %d ]-\n", i+1)
+			for j, value := range row {
+				fmt.Fprintf(c.out, "%s | %s\n", pad(headers[j], width), value)
+			}
+		}
+		return
+	}
+
+	const maxWidth = 60
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = utf8.RuneCountInString(header)
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			row[i] = truncateCell(value, maxWidth)
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+		}
+	}
+
+	line := func(cells []string) {
+		padded := make([]string, len(cells))
+		for i, cell := range cells {
+			padded[i] = pad(cell, widths[i])
+		}
+		fmt.Fprintf(c.out, " %s\n", strings.TrimRight(strings.Join(padded, " | "), " "))
+	}
+
+	line(headers)
+	separators := make([]string, len(widths))
+	for i, width := range widths {
+		separators[i] = strings.Repeat("-", width)
+	}
+	fmt.Fprintf(c.out, "-%s-\n", strings.Join(separators, "-+-"))
+	for _, row := range rows {
+		line(row)
+	}
+}
+
+// returnsRows reports whether a statement produces a result set
+func returnsRows(statement string) bool {
+	keyword := strings.ToUpper(strings.Fields(statement)[0])
+	switch keyword {
+	case "SELECT", "WITH", "SHOW", "EXPLAIN", "VALUES", "TABLE":
+		return true
+	}
+	return strings.Contains(strings.ToUpper(statement), "RETURNING")
+}
+
+func formatConsoleValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+func truncateCell(value string, width int) string {
+	value = strings.ReplaceAll(value, "\n", "\\n")
+	if utf8.RuneCountInString(value) <= width {
+		return value
+	}
+	return string([]rune(value)[:width-1]) + "…"
+}
+
+func pad(value string, width int) string {
+	return value + strings.Repeat(" ", width-utf8.RuneCountInString(value))
+}
+
+func elapsed(start time.Time) string {
+	return time.Since(start).Round(time.Millisecond).String()
+}
+
+// redactURL hides the password of a connection URL
+func redactURL(dsn string) string {
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.Scheme == "" {
+		return "database"
+	}
+	return parsed.Redacted()
+}
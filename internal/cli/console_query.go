@@ -0,0 +1,534 @@
+package cli
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+
+// consoleModel is a model from the models package that fluent console
+// queries can be written against
+type consoleModel struct {
+	Name    string
+	Table   string
+	Columns []consoleColumn
+}
+
+// consoleColumn is a model field stored in a database column
+type consoleColumn struct {
+	Field  string
+	Name   string
+	GoType string
+}
+
+// fluentQuery is a read query built from a fluent console expression
+type fluentQuery struct {
+	Model    *consoleModel
+	Terminal string // Find, First, Count or Exists
+	SQL      string
+	Args     []interface{}
+}
+
+// loadConsoleModels parses the models package into the models fluent
+// queries can use, keyed by both the model name and its generated column
+// variable (User and Users)
+func loadConsoleModels(packagePath string) (map[string]*consoleModel, error) {
+	naming, err := modelNaming()
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := stormParser.NewStructParserWithNaming(naming).ParseDirectory(packagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make(map[string]*consoleModel)
+	for _, table := range tables {
+		if _, hasTable := table.TableLevel["table"]; !hasTable {
+			continue
+		}
+
+		model := &consoleModel{Name: table.StructName, Table: table.TableName}
+		for _, field := range table.Fields {
+			if field.IsRelationship || field.DBName == "-" || strings.Contains(field.StormTag, "relation:") {
+				continue
+			}
+			model.Columns = append(model.Columns, consoleColumn{Field: field.Name, Name: field.DBName, GoType: field.Type})
+		}
+
+		models[model.Name] = model
+		models[model.Name+"s"] = model
+	}
+	return models, nil
+}
+
+// column finds a column by Go field name or database column name
+func (m *consoleModel) column(name string) (consoleColumn, bool) {
+	for _, col := range m.Columns {
+		if col.Field == name || col.Name == name {
+			return col, true
+		}
+	}
+	return consoleColumn{}, false
+}
+
+// isFluentQuery reports whether a console line is a fluent query rather than SQL
+func isFluentQuery(line string, models map[string]*consoleModel) bool {
+	root, _, _ := strings.Cut(line, ".")
+	if root == "db" {
+		root, _, _ = strings.Cut(strings.TrimPrefix(line, "db."), ".")
+	}
+	_, ok := models[root]
+	return ok
+}
+
+// buildFluentQuery translates an expression written like the generated
+// repository API into SQL, for example:
+//
+//	Users.Query().Where(Users.Email.Contains("@example.com")).OrderBy(Users.CreatedAt.Desc()).Limit(10).Find()
+func buildFluentQuery(input string, models map[string]*consoleModel) (*fluentQuery, error) {
+	expr, err := parser.ParseExpr(strings.TrimSuffix(strings.TrimSpace(input), ";"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	var calls []*ast.CallExpr
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil, fmt.Errorf("invalid query: expected a method call")
+		}
+		calls = append([]*ast.CallExpr{call}, calls...)
+		expr = selector.X
+	}
+
+	model, err := resolveModel(expr, models)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &fluentQuery{Model: model, Terminal: "Find"}
+	var conditions []orm.Condition
+	var orders []string
+	var limit, offset *uint64
+
+	for i, call := range calls {
+		method := call.Fun.(*ast.SelectorExpr).Sel.Name
+		switch method {
+		case "Query":
+		case "Where":
+			for _, arg := range call.Args {
+				condition, err := evalCondition(arg, models)
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, condition)
+			}
+		case "OrderBy":
+			for _, arg := range call.Args {
+				order, err := evalOrder(arg, models)
+				if err != nil {
+					return nil, err
+				}
+				orders = append(orders, order)
+			}
+		case "Limit", "Offset":
+			if len(call.Args) != 1 {
+				return nil, fmt.Errorf("%s expects one argument", method)
+			}
+			n, err := evalUint(call.Args[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", method, err)
+			}
+			if method == "Limit" {
+				limit = &n
+			} else {
+				offset = &n
+			}
+		case "Find", "First", "Count", "Exists":
+			if i != len(calls)-1 {
+				return nil, fmt.Errorf("%s must be the last call", method)
+			}
+			q.Terminal = method
+		default:
+			return nil, fmt.Errorf("%s is not supported in the console; use Where, OrderBy, Limit, Offset, Find, First, Count or Exists", method)
+		}
+	}
+
+	builder := squirrel.Select("*").From(model.Table).PlaceholderFormat(squirrel.Dollar)
+	if q.Terminal == "Count" {
+		builder = squirrel.Select("COUNT(*)").From(model.Table).PlaceholderFormat(squirrel.Dollar)
+	}
+	for _, condition := range conditions {
+		builder = builder.Where(condition.ToSqlizer())
+	}
+
+	switch q.Terminal {
+	case "Count":
+	case "Exists":
+		builder = builder.RemoveColumns().Column("1").Limit(1)
+	default:
+		builder = builder.OrderBy(orders...)
+		if q.Terminal == "First" {
+			one := uint64(1)
+			limit = &one
+		}
+		if limit != nil {
+			builder = builder.Limit(*limit)
+		}
+		if offset != nil {
+			builder = builder.Offset(*offset)
+		}
+	}
+
+	q.SQL, q.Args, err = builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	return q, nil
+}
+
+// resolveModel resolves the root of a fluent query: Users, User or db.Users
+func resolveModel(expr ast.Expr, models map[string]*consoleModel) (*consoleModel, error) {
+	var name string
+	switch root := expr.(type) {
+	case *ast.Ident:
+		name = root.Name
+	case *ast.SelectorExpr:
+		name = root.Sel.Name
+	default:
+		return nil, fmt.Errorf("invalid query: expected a model such as Users")
+	}
+
+	model, ok := models[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %s", name)
+	}
+	return model, nil
+}
+
+// resolveColumn resolves a column reference such as Users.Email
+func resolveColumn(expr ast.Expr, models map[string]*consoleModel) (*consoleModel, consoleColumn, error) {
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return nil, consoleColumn{}, fmt.Errorf("expected a column such as Users.Email")
+	}
+
+	model, err := resolveModel(selector.X, models)
+	if err != nil {
+		return nil, consoleColumn{}, err
+	}
+	col, ok := model.column(selector.Sel.Name)
+	if !ok {
+		return nil, consoleColumn{}, fmt.Errorf("model %s has no column %s", model.Name, selector.Sel.Name)
+	}
+	return model, col, nil
+}
+
+// evalCondition evaluates a column condition such as Users.Age.Gte(18), or
+// storm.And, storm.Or and storm.Not over other conditions
+func evalCondition(expr ast.Expr, models map[string]*consoleModel) (orm.Condition, error) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return orm.Condition{}, fmt.Errorf("expected a condition such as Users.Email.Eq(\"a@example.com\")")
+	}
+
+	var method string
+	var receiver ast.Expr
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		method = fun.Name
+	case *ast.SelectorExpr:
+		method, receiver = fun.Sel.Name, fun.X
+		if pkg, ok := receiver.(*ast.Ident); ok && (pkg.Name == "storm" || pkg.Name == "orm") {
+			receiver = nil
+		}
+	}
+
+	if receiver == nil || isCondition(receiver) {
+		conditions := make([]orm.Condition, 0, len(call.Args)+1)
+		if receiver != nil {
+			condition, err := evalCondition(receiver, models)
+			if err != nil {
+				return orm.Condition{}, err
+			}
+			conditions = append(conditions, condition)
+		}
+		for _, arg := range call.Args {
+			condition, err := evalCondition(arg, models)
+			if err != nil {
+				return orm.Condition{}, err
+			}
+			conditions = append(conditions, condition)
+		}
+
+		switch {
+		case method == "And" && len(conditions) > 0:
+			return orm.And(conditions...), nil
+		case method == "Or" && len(conditions) > 0:
+			return orm.Or(conditions...), nil
+		case method == "Not" && len(conditions) == 1:
+			return orm.Not(conditions[0]), nil
+		}
+		return orm.Condition{}, fmt.Errorf("unsupported condition %s", method)
+	}
+
+	model, col, err := resolveColumn(receiver, models)
+	if err != nil {
+		return orm.Condition{}, err
+	}
+
+	args := make([]interface{}, len(call.Args))
+	for i, arg := range call.Args {
+		if args[i], err = evalValue(arg, col.GoType); err != nil {
+			return orm.Condition{}, fmt.Errorf("%s.%s: %w", col.Field, method, err)
+		}
+	}
+	return columnCondition(orm.Column[interface{}]{Name: col.Name, Table: model.Table}, method, args)
+}
+
+// isCondition reports whether expr is a call producing a condition rather than a column reference
+func isCondition(expr ast.Expr) bool {
+	_, ok := expr.(*ast.CallExpr)
+	return ok
+}
+
+// columnCondition applies a column method of the generated column types
+func columnCondition(col orm.Column[interface{}], method string, args []interface{}) (orm.Condition, error) {
+	expect := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s expects %d argument(s), got %d", method, n, len(args))
+		}
+		return nil
+	}
+
+	switch method {
+	case "Eq", "NotEq":
+		if err := expect(1); err != nil {
+			return orm.Condition{}, err
+		}
+		if method == "Eq" {
+			return col.Eq(args[0]), nil
+		}
+		return col.NotEq(args[0]), nil
+	case "In":
+		return col.In(args...), nil
+	case "NotIn":
+		return col.NotIn(args...), nil
+	case "IsNull", "IsNotNull":
+		if err := expect(0); err != nil {
+			return orm.Condition{}, err
+		}
+		if method == "IsNull" {
+			return col.IsNull(), nil
+		}
+		return col.IsNotNull(), nil
+	case "IsTrue", "IsFalse":
+		if err := expect(0); err != nil {
+			return orm.Condition{}, err
+		}
+		bools := orm.BoolColumn{Column: orm.Column[bool]{Name: col.Name, Table: col.Table}}
+		if method == "IsTrue" {
+			return bools.IsTrue(), nil
+		}
+		return bools.IsFalse(), nil
+	case "Like", "ILike", "StartsWith", "EndsWith", "Contains", "Regexp":
+		if err := expect(1); err != nil {
+			return orm.Condition{}, err
+		}
+		value, ok := args[0].(string)
+		if !ok {
+			return orm.Condition{}, fmt.Errorf("%s expects a string", method)
+		}
+		return stringCondition(orm.StringColumn{Column: orm.Column[string]{Name: col.Name, Table: col.Table}}, method, value), nil
+	case "Gt", "Gte", "Lt", "Lte", "Between", "After", "Before", "Since", "Until":
+		n := 1
+		if method == "Between" {
+			n = 2
+		}
+		if err := expect(n); err != nil {
+			return orm.Condition{}, err
+		}
+		switch args[0].(type) {
+		case int64:
+			return compare(orm.ComparableColumn[int64]{Column: orm.Column[int64]{Name: col.Name, Table: col.Table}}, method, args)
+		case float64:
+			return compare(orm.ComparableColumn[float64]{Column: orm.Column[float64]{Name: col.Name, Table: col.Table}}, method, args)
+		case string:
+			return compare(orm.ComparableColumn[string]{Column: orm.Column[string]{Name: col.Name, Table: col.Table}}, method, args)
+		case time.Time:
+			return compare(orm.ComparableColumn[time.Time]{Column: orm.Column[time.Time]{Name: col.Name, Table: col.Table}}, method, args)
+		}
+		return orm.Condition{}, fmt.Errorf("%s expects a number, string or time", method)
+	case "Today", "ThisWeek", "ThisMonth", "LastNDays":
+		times := orm.TimeColumn{ComparableColumn: orm.ComparableColumn[time.Time]{Column: orm.Column[time.Time]{Name: col.Name, Table: col.Table}}}
+		if method == "LastNDays" {
+			if err := expect(1); err != nil {
+				return orm.Condition{}, err
+			}
+			days, ok := args[0].(int64)
+			if !ok {
+				return orm.Condition{}, fmt.Errorf("LastNDays expects a number of days")
+			}
+			return times.LastNDays(int(days)), nil
+		}
+		if err := expect(0); err != nil {
+			return orm.Condition{}, err
+		}
+		switch method {
+		case "Today":
+			return times.Today(), nil
+		case "ThisWeek":
+			return times.ThisWeek(), nil
+		}
+		return times.ThisMonth(), nil
+	}
+	return orm.Condition{}, fmt.Errorf("unsupported column method %s", method)
+}
+
+func stringCondition(col orm.StringColumn, method, value string) orm.Condition {
+	switch method {
+	case "Like":
+		return col.Like(value)
+	case "ILike":
+		return col.ILike(value)
+	case "StartsWith":
+		return col.StartsWith(value)
+	case "EndsWith":
+		return col.EndsWith(value)
+	case "Contains":
+		return col.Contains(value)
+	}
+	return col.Regexp(value)
+}
+
+// compare applies a comparison once every argument has the column's type
+func compare[T orm.Comparable](col orm.ComparableColumn[T], method string, args []interface{}) (orm.Condition, error) {
+	values := make([]T, len(args))
+	for i, arg := range args {
+		value, ok := arg.(T)
+		if !ok {
+			return orm.Condition{}, fmt.Errorf("%s arguments must have the same type", method)
+		}
+		values[i] = value
+	}
+
+	switch method {
+	case "Gt", "After":
+		return col.Gt(values[0]), nil
+	case "Gte", "Since":
+		return col.Gte(values[0]), nil
+	case "Lt", "Before":
+		return col.Lt(values[0]), nil
+	case "Lte", "Until":
+		return col.Lte(values[0]), nil
+	}
+	return col.Between(values[0], values[1]), nil
+}
+
+// evalOrder evaluates an OrderBy argument: a string, a column or column.Asc()/Desc()
+func evalOrder(expr ast.Expr, models map[string]*consoleModel) (string, error) {
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		return strconv.Unquote(lit.Value)
+	}
+
+	direction := ""
+	if call, ok := expr.(*ast.CallExpr); ok {
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (selector.Sel.Name != "Asc" && selector.Sel.Name != "Desc") {
+			return "", fmt.Errorf("OrderBy expects a column, column.Asc(), column.Desc() or a string")
+		}
+		direction = " " + strings.ToUpper(selector.Sel.Name)
+		expr = selector.X
+	}
+
+	model, col, err := resolveColumn(expr, models)
+	if err != nil {
+		return "", err
+	}
+	return model.Table + "." + col.Name + direction, nil
+}
+
+func evalUint(expr ast.Expr) (uint64, error) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return 0, fmt.Errorf("expected a non-negative integer")
+	}
+	return strconv.ParseUint(lit.Value, 0, 64)
+}
+
+// evalValue evaluates a literal argument, converting strings to times for
+// time.Time columns so comparisons match the column type
+func evalValue(expr ast.Expr, goType string) (interface{}, error) {
+	switch v := expr.(type) {
+	case *ast.BasicLit:
+		switch v.Kind {
+		case token.INT:
+			return strconv.ParseInt(v.Value, 0, 64)
+		case token.FLOAT:
+			return strconv.ParseFloat(v.Value, 64)
+		case token.STRING, token.CHAR:
+			s, err := strconv.Unquote(v.Value)
+			if err != nil || goType != "time.Time" {
+				return s, err
+			}
+			return parseConsoleTime(s)
+		}
+	case *ast.Ident:
+		switch v.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case *ast.UnaryExpr:
+		if v.Op == token.SUB {
+			value, err := evalValue(v.X, goType)
+			switch n := value.(type) {
+			case int64:
+				return -n, err
+			case float64:
+				return -n, err
+			}
+		}
+	case *ast.ParenExpr:
+		return evalValue(v.X, goType)
+	}
+	return nil, fmt.Errorf("unsupported value %s", exprString(expr))
+}
+
+func parseConsoleTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or YYYY-MM-DD", s)
+}
+
+func exprString(expr ast.Expr) string {
+	switch v := expr.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.SelectorExpr:
+		return exprString(v.X) + "." + v.Sel.Name
+	case *ast.CallExpr:
+		return exprString(v.Fun) + "(...)"
+	}
+	return fmt.Sprintf("%T", expr)
+}
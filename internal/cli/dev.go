@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	devPackage          string
+	devOutput           string
+	devMigrate          bool
+	devAllowDestructive bool
+	devInterval         time.Duration
+	devDebounce         time.Duration
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Watch models and regenerate code on change",
+	Long: `Watch the models package and re-run ORM code generation whenever a model file changes.
+
+With --migrate, the schema diff is also applied to the development database after
+each successful generation. Generated files and tests are ignored, and bursts of
+changes are debounced into a single run. Press Ctrl+C to stop.`,
+	RunE: runDev,
+}
+
+func init() {
+	devCmd.Flags().StringVar(&devPackage, "package", "", "Path to package containing models")
+	devCmd.Flags().StringVar(&devOutput, "output", "", "Output directory for generated code (default: same as package)")
+	devCmd.Flags().BoolVar(&devMigrate, "migrate", false, "Apply schema changes to the development database after each generation")
+	devCmd.Flags().BoolVar(&devAllowDestructive, "allow-destructive", false, "Allow potentially destructive schema changes with --migrate")
+	devCmd.Flags().DurationVar(&devInterval, "interval", 500*time.Millisecond, "How often to check the models package for changes")
+	devCmd.Flags().DurationVar(&devDebounce, "debounce", 300*time.Millisecond, "Quiet period to wait for after a change before regenerating")
+}
+
+func runDev(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := storm.GenerateOptions{PackagePath: devPackage, OutputDir: devOutput}
+	if stormConfig != nil {
+		if opts.PackagePath == "" {
+			packages := stormConfig.Models.All()
+			if len(packages) > 1 {
+				return fmt.Errorf("storm dev watches a single models package but %d are configured: choose one with --package", len(packages))
+			}
+			if len(packages) == 1 {
+				opts.PackagePath = packages[0].Path
+				if opts.OutputDir == "" {
+					opts.OutputDir = packages[0].Output
+				}
+			}
+		}
+		opts.IncludeHooks = stormConfig.ORM.GenerateHooks
+		opts.IncludeTests = stormConfig.ORM.GenerateTests
+		opts.IncludeMocks = stormConfig.ORM.GenerateMocks
+		opts.IncludeHandlers = stormConfig.ORM.GenerateHandlers
+		opts.IncludeDTOs = stormConfig.ORM.GenerateDTOs
+		opts.TemplatesDir = stormConfig.ORM.TemplatesDir
+	}
+	if opts.PackagePath == "" {
+		opts.PackagePath = "./models"
+	}
+	if opts.OutputDir == "" {
+		opts.OutputDir = opts.PackagePath
+	}
+
+	if devMigrate && databaseURL == "" {
+		return fmt.Errorf("--migrate requires a database connection: use --url or set database.url in storm.yaml")
+	}
+	if devInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	config := storm.NewConfig()
+	config.ModelsPackage = opts.PackagePath
+	config.Debug = debug
+	applySchemaSettings(config)
+	config.DatabaseURL = "postgres://localhost/dummy"
+	if devMigrate {
+		config.DatabaseURL = databaseURL
+	}
+
+	stormClient, err := storm.NewWithConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Storm client: %w", err)
+	}
+	defer stormClient.Close()
+
+	out := cmd.OutOrStdout()
+	run := func(changed []string) {
+		runDevCycle(ctx, out, stormClient, opts, config.DatabaseURL, changed)
+	}
+
+	watcher := newModelWatcher(opts.PackagePath, devDebounce)
+	if _, err := watcher.poll(time.Now()); err != nil {
+		return fmt.Errorf("failed to read models package %s: %w", opts.PackagePath, err)
+	}
+
+	fmt.Fprintf(out, "Watching %s for changes (Ctrl+C to stop)\n", opts.PackagePath)
+	run(nil)
+
+	ticker := time.NewTicker(devInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(out, "Stopped watching")
+			return nil
+		case now := <-ticker.C:
+			changed, err := watcher.poll(now)
+			if err != nil {
+				devLog(out, "Failed to scan models: %v", err)
+				continue
+			}
+			if len(changed) > 0 {
+				run(changed)
+			}
+		}
+	}
+}
+
+// runDevCycle regenerates code and, with --migrate, pushes the schema diff.
+// Failures are reported but never stop the watcher.
+func runDevCycle(ctx context.Context, out io.Writer, stormClient *storm.Storm, opts storm.GenerateOptions, dsn string, changed []string) {
+	if len(changed) > 0 {
+		devLog(out, "Changed: %s", summarizeChanges(changed))
+	}
+
+	start := time.Now()
+	if err := stormClient.Generate(ctx, opts); err != nil {
+		devLog(out, "✗ Code generation failed: %v", err)
+		return
+	}
+	devLog(out, "✓ Generated code in %s (%s)", opts.OutputDir, time.Since(start).Round(time.Millisecond))
+
+	if !devMigrate {
+		return
+	}
+
+	naming, err := modelNaming()
+	if err != nil {
+		devLog(out, "✗ Schema update failed: %v", err)
+		return
+	}
+
+	start = time.Now()
+	changes, err := pushSchemaChanges(ctx, dsn, migrator.MigrationOptions{
+		PackagePath:      opts.PackagePath,
+		AllowDestructive: devAllowDestructive,
+		PushToDB:         true,
+		Naming:           naming,
+	})
+	switch {
+	case err != nil:
+		devLog(out, "✗ Schema update failed: %v", err)
+	case changes == 0:
+		devLog(out, "✓ Database schema is up to date")
+	default:
+		devLog(out, "✓ Applied %d schema change(s) (%s)", changes, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// pushSchemaChanges applies the difference between the models and the database, returning the number of changes
+func pushSchemaChanges(ctx context.Context, dsn string, opts migrator.MigrationOptions) (int, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return 0, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	atlasMigrator := migrator.NewAtlasMigrator(migrator.NewDBConfig(dsn))
+	result, err := atlasMigrator.GenerateMigration(ctx, db, opts)
+	if err != nil {
+		return 0, err
+	}
+	return len(result.Changes), nil
+}
+
+func devLog(out io.Writer, format string, args ...interface{}) {
+	fmt.Fprintf(out, "[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
+
+func summarizeChanges(files []string) string {
+	const shown = 3
+	names := make([]string, 0, shown)
+	for i, file := range files {
+		if i == shown {
+			break
+		}
+		names = append(names, filepath.Base(file))
+	}
+
+	summary := strings.Join(names, ", ")
+	if len(files) > shown {
+		summary += fmt.Sprintf(" and %d more", len(files)-shown)
+	}
+	return summary
+}
+
+// modelWatcher detects changes to hand-written Go files in a directory by
+// polling their modification times. Generated files and tests are ignored so
+// regenerating code does not trigger another run.
+type modelWatcher struct {
+	dir      string
+	debounce time.Duration
+	files    map[string]fileStamp
+	pending  map[string]bool
+	lastSeen time.Time
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newModelWatcher(dir string, debounce time.Duration) *modelWatcher {
+	return &modelWatcher{
+		dir:      dir,
+		debounce: debounce,
+		pending:  make(map[string]bool),
+	}
+}
+
+// poll rescans the directory and returns the changed files once no further
+// change has been seen for the debounce period
+func (w *modelWatcher) poll(now time.Time) ([]string, error) {
+	files, err := scanModelFiles(w.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.files != nil {
+		for path, stamp := range files {
+			if previous, ok := w.files[path]; !ok || previous != stamp {
+				w.pending[path] = true
+				w.lastSeen = now
+			}
+		}
+		for path := range w.files {
+			if _, ok := files[path]; !ok {
+				w.pending[path] = true
+				w.lastSeen = now
+			}
+		}
+	}
+	w.files = files
+
+	if len(w.pending) == 0 || now.Sub(w.lastSeen) < w.debounce {
+		return nil, nil
+	}
+
+	changed := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	w.pending = make(map[string]bool)
+	return changed, nil
+}
+
+func scanModelFiles(dir string) (map[string]fileStamp, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fileStamp, len(matches))
+	for _, path := range matches {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || isGeneratedFile(path) {
+			continue
+		}
+		files[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return files, nil
+}
+
+// isGeneratedFile reports whether path carries the standard
+// "Code generated ... DO NOT EDIT." comment before its package clause
+func isGeneratedFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		if strings.HasPrefix(line, "// Code generated ") && strings.HasSuffix(line, " DO NOT EDIT.") {
+			return true
+		}
+	}
+	return false
+}
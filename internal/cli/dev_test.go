@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModelWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	user := write("user.go", "package models\n\ntype User struct{}\n")
+	write("user_repository.go", "// Code generated by storm orm generate-orm; DO NOT EDIT.\n\npackage models\n")
+	write("user_test.go", "package models\n")
+
+	debounce := 300 * time.Millisecond
+	watcher := newModelWatcher(dir, debounce)
+	start := time.Now()
+
+	if changed, err := watcher.poll(start); err != nil || changed != nil {
+		t.Fatalf("first poll should only record state, got %v, %v", changed, err)
+	}
+
+	write("user_repository.go", "// Code generated by storm orm generate-orm; DO NOT EDIT.\n\npackage models\n\n// regenerated\n")
+	write("user_test.go", "package models\n\n// edited\n")
+	if changed, _ := watcher.poll(start.Add(time.Second)); changed != nil {
+		t.Errorf("generated and test files should be ignored, got %v", changed)
+	}
+
+	write("user.go", "package models\n\ntype User struct{ ID int }\n")
+	post := write("post.go", "package models\n\ntype Post struct{}\n")
+
+	if changed, _ := watcher.poll(start.Add(2 * time.Second)); changed != nil {
+		t.Errorf("changes should wait for the debounce period, got %v", changed)
+	}
+
+	changed, err := watcher.poll(start.Add(2*time.Second + debounce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{post, user}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected %v, got %v", want, changed)
+	}
+
+	if err := os.Remove(post); err != nil {
+		t.Fatal(err)
+	}
+	watcher.poll(start.Add(3 * time.Second))
+	changed, _ = watcher.poll(start.Add(4 * time.Second))
+	if want := []string{post}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected removed file %v, got %v", want, changed)
+	}
+
+	if _, err := newModelWatcher(filepath.Join(dir, "missing"), debounce).poll(start); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
+
+func TestSummarizeChanges(t *testing.T) {
+	if got := summarizeChanges([]string{"models/user.go"}); got != "user.go" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+
+	got := summarizeChanges([]string{"a.go", "b.go", "c.go", "d.go", "e.go"})
+	if got != "a.go, b.go, c.go and 2 more" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+}
+
+func TestRunDevRequiresDatabaseForMigrate(t *testing.T) {
+	origMigrate := devMigrate
+	origDatabaseURL := databaseURL
+	origStormConfig := stormConfig
+	defer func() {
+		devMigrate = origMigrate
+		databaseURL = origDatabaseURL
+		stormConfig = origStormConfig
+	}()
+
+	devMigrate = true
+	databaseURL = ""
+	stormConfig = nil
+
+	err := runDev(devCmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "--migrate requires a database connection") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
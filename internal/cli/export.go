@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/eleven-am/storm/internal/transfer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOptions transfer.Export
+	exportOutput  string
+	exportNoCopy  bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the rows of a table as CSV, JSON lines or Parquet",
+	Long: `Export the rows of a table, optionally filtered, as CSV, JSON lines or Parquet.
+
+Rows are streamed as they are read, so tables larger than memory can be
+exported. CSV is produced by a COPY run through psql when it is installed,
+which is the fastest path; pass --no-copy to read the rows through the driver
+instead. JSON lines are built by the database with row_to_json. Parquet files
+are uncompressed, with a row group every 10000 rows.
+
+Without --output the data is written to stdout and messages to stderr.`,
+	Example: `  storm export --table users --format csv --output users.csv
+  storm export --table orders --where "created_at > now() - interval '7 days'" --format jsonl
+  storm export --table events --columns id,kind,payload --order-by id --limit 100000 --format parquet -o events.parquet`,
+	RunE:         runExport,
+	SilenceUsage: true,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportOptions.Table, "table", "", "Table to export (required)")
+	exportCmd.Flags().StringVar(&exportOptions.Format, "format", transfer.FormatCSV, "Output format (csv, jsonl, parquet)")
+	exportCmd.Flags().StringSliceVar(&exportOptions.Columns, "columns", nil, "Columns to export (default: all)")
+	exportCmd.Flags().StringVar(&exportOptions.Where, "where", "", "SQL condition the exported rows must match")
+	exportCmd.Flags().StringVar(&exportOptions.OrderBy, "order-by", "", "SQL expression ordering the rows")
+	exportCmd.Flags().IntVar(&exportOptions.Limit, "limit", 0, "Maximum number of rows to export")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write (default: stdout)")
+	exportCmd.Flags().BoolVar(&exportNoCopy, "no-copy", false, "Read CSV rows through the driver instead of COPY")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	if err := exportOptions.Validate(); err != nil {
+		return err
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("no database connection: use --url or set database.url in storm.yaml")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var out io.Writer = cmd.OutOrStdout()
+	if exportOutput != "" {
+		file, err := os.Create(exportOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", exportOutput, err)
+		}
+		defer file.Close()
+		out = file
+	}
+	messages := cmd.ErrOrStderr()
+
+	if exportUsesCopy() {
+		if err := exportOptions.CopyCSV(ctx, databaseURL, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(messages, "Exported %s with COPY\n", exportOptions.Table)
+		return nil
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	count, err := exportOptions.Run(ctx, db, out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(messages, "Exported %d rows from %s\n", count, exportOptions.Table)
+	return nil
+}
+
+// exportUsesCopy reports whether a CSV export can take the COPY fast path
+func exportUsesCopy() bool {
+	if exportNoCopy || (exportOptions.Format != "" && exportOptions.Format != transfer.FormatCSV) {
+		return false
+	}
+	_, err := exec.LookPath("psql")
+	return err == nil
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/transfer"
+)
+
+func TestExportUsesCopy(t *testing.T) {
+	defer func() { exportOptions, exportNoCopy = transfer.Export{}, false }()
+
+	exportOptions = transfer.Export{Table: "users", Format: transfer.FormatJSONL}
+	if exportUsesCopy() {
+		t.Error("expected a JSON lines export to skip COPY")
+	}
+
+	exportOptions.Format = transfer.FormatCSV
+	exportNoCopy = true
+	if exportUsesCopy() {
+		t.Error("expected --no-copy to skip COPY")
+	}
+}
+
+func TestRunExportRequiresTable(t *testing.T) {
+	defer func() { exportOptions = transfer.Export{} }()
+
+	exportOptions = transfer.Export{Format: transfer.FormatCSV}
+	err := runExport(exportCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "export table is required") {
+		t.Errorf("expected a missing table error, got %v", err)
+	}
+}
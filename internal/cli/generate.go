@@ -40,6 +40,7 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	config := storm.NewConfig()
 	config.ModelsPackage = absPath
 	config.Debug = debug
+	applySchemaSettings(config)
 
 	stormClient, err := storm.NewWithConfig(config)
 	if err != nil {
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/eleven-am/storm/internal/privileges"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grantsFile   string
+	grantsDir    string
+	grantsName   string
+	grantsDryRun bool
+	grantsFormat string
+)
+
+var grantsCmd = &cobra.Command{
+	Use:   "grants",
+	Short: "Manage declared roles and grants",
+	Long: `Manage the roles and table grants declared in the privileges section of
+storm.yaml, or in a privileges.yaml file:
+
+  privileges:
+    roles:
+      - name: app_readonly
+      - name: reporting
+        login: true
+        member_of: [app_readonly]
+    grants:
+      - role: app_readonly
+        privileges: [SELECT]
+        tables: ["*"]`,
+}
+
+var grantsGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write a migration creating the declared roles and grants",
+	Long: `Write UP and DOWN migration files that create the declared roles and grant
+their privileges. The UP migration can be applied again without error, so it
+can be regenerated whenever the declarations change.`,
+	RunE:         runGrantsGenerate,
+	SilenceUsage: true,
+}
+
+var grantsDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the declared roles and grants with the database",
+	Long: `Report declared roles, memberships and grants the database is missing, and
+grants held by declared roles that are not declared.
+
+Returns exit code 1 if the database has drifted. Use --format json for CI.`,
+	RunE:         runGrantsDiff,
+	SilenceUsage: true,
+}
+
+func init() {
+	grantsCmd.PersistentFlags().StringVar(&grantsFile, "file", "", "Privileges file (default: privileges section of storm.yaml, then ./privileges.yaml)")
+
+	grantsGenerateCmd.Flags().StringVar(&grantsDir, "output", "", "Output directory for migration files (default from storm.yaml or ./migrations)")
+	grantsGenerateCmd.Flags().StringVar(&grantsName, "name", "grants", "Migration name")
+	grantsGenerateCmd.Flags().BoolVar(&grantsDryRun, "dry-run", false, "Print migration without creating files")
+
+	grantsDiffCmd.Flags().StringVar(&grantsFormat, "format", "text", "Output format (text, json)")
+
+	grantsCmd.AddCommand(grantsGenerateCmd)
+	grantsCmd.AddCommand(grantsDiffCmd)
+}
+
+// loadPrivileges reads the declarations from --file, storm.yaml or ./privileges.yaml
+func loadPrivileges() (*privileges.Config, error) {
+	if grantsFile != "" {
+		return privileges.Load(grantsFile)
+	}
+	if stormConfig != nil && !stormConfig.Privileges.IsEmpty() {
+		config := stormConfig.Privileges
+		return &config, config.Validate()
+	}
+	if _, err := os.Stat("privileges.yaml"); err == nil {
+		return privileges.Load("privileges.yaml")
+	}
+	return nil, fmt.Errorf("no privileges declared: add a privileges section to storm.yaml or use --file")
+}
+
+func runGrantsGenerate(cmd *cobra.Command, args []string) error {
+	config, err := loadPrivileges()
+	if err != nil {
+		return err
+	}
+
+	layout := migrationFileLayout(true)
+	layout.Author = gitUserName()
+
+	out := cmd.OutOrStdout()
+	if grantsDryRun {
+		now := time.Now()
+		upContent, err := layout.Content(grantsName, "up", config.UpSQL(), now)
+		if err != nil {
+			return err
+		}
+		downContent, err := layout.Content(grantsName, "down", config.DownSQL(), now)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "-- UP\n%s\n-- DOWN\n%s", upContent, downContent)
+		return nil
+	}
+
+	dir := grantsDir
+	if dir == "" && stormConfig != nil {
+		dir = stormConfig.Migrations.Directory
+	}
+	if dir == "" {
+		dir = "./migrations"
+	}
+
+	upFile, downFile, err := layout.WriteFiles(dir, grantsName, config.UpSQL(), config.DownSQL())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Created migration files:\n")
+	fmt.Fprintf(out, "  UP:   %s\n", upFile)
+	fmt.Fprintf(out, "  DOWN: %s\n", downFile)
+	return nil
+}
+
+func runGrantsDiff(cmd *cobra.Command, args []string) error {
+	if grantsFormat != "text" && grantsFormat != "json" {
+		return fmt.Errorf("unsupported format %q: use text or json", grantsFormat)
+	}
+
+	config, err := loadPrivileges()
+	if err != nil {
+		return err
+	}
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	state, err := privileges.Inspect(ctx, db, config)
+	if err != nil {
+		return err
+	}
+	drift := privileges.Diff(config, state)
+
+	out := cmd.OutOrStdout()
+	if grantsFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(drift); err != nil {
+			return fmt.Errorf("failed to encode drift: %w", err)
+		}
+	} else {
+		printGrantsDrift(out, drift)
+	}
+
+	if !drift.IsEmpty() {
+		return fmt.Errorf("grants have drifted: %d difference(s)", len(drift.Lines()))
+	}
+	return nil
+}
+
+func printGrantsDrift(out io.Writer, drift privileges.Drift) {
+	if drift.IsEmpty() {
+		fmt.Fprintln(out, "✓ Roles and grants match the declarations")
+		return
+	}
+	for _, line := range drift.Lines() {
+		fmt.Fprintf(out, "  ✗ %s\n", line)
+	}
+	fmt.Fprintln(out, "\nRun 'storm grants generate' and apply the migration to restore the declared grants")
+}
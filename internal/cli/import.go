@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/eleven-am/storm/internal/transfer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importOptions transfer.Import
+	importPackage string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Load CSV or JSON lines into a table through its model",
+	Long: `Load a CSV file with a header row, or JSON lines, into a table. The file is
+read from stdin when no file or "-" is given.
+
+Input fields are matched to the columns of the table's model by name; use
+--map for fields named differently and --ignore for fields to skip. Every value
+is checked against the type of its column before it is loaded, and required
+columns missing from the input are reported up front. In CSV, empty fields are
+NULL.
+
+Rows are loaded with COPY in batches, inside one transaction: an invalid row
+leaves the table as it was. With --upsert, rows whose key already exists are
+updated instead; the columns must be the primary key or a unique key of the
+model.`,
+	Example: `  storm import users.csv --table users
+  storm import users.jsonl --table users --map email_address=email --ignore notes
+  storm import products.csv --table products --upsert sku --batch-size 5000`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         runImport,
+	SilenceUsage: true,
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importOptions.Table, "table", "", "Table to load (required)")
+	importCmd.Flags().StringVar(&importOptions.Format, "format", "", "Input format, csv or jsonl (default: from the file extension, else csv)")
+	importCmd.Flags().StringToStringVar(&importOptions.Mapping, "map", nil, "Input fields loaded into columns named differently, as field=column")
+	importCmd.Flags().StringSliceVar(&importOptions.Ignore, "ignore", nil, "Input fields to skip")
+	importCmd.Flags().StringSliceVar(&importOptions.Upsert, "upsert", nil, "Update rows whose values in these unique columns already exist")
+	importCmd.Flags().IntVar(&importOptions.BatchSize, "batch-size", 1000, "Rows per COPY batch")
+	importCmd.Flags().StringVar(&importPackage, "package", "", "Path to package containing models")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	if strings.TrimSpace(importOptions.Table) == "" {
+		return fmt.Errorf("import table is required")
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("no database connection: use --url or set database.url in storm.yaml")
+	}
+
+	packagePath := importPackage
+	if packagePath == "" && stormConfig != nil {
+		packagePath = stormConfig.Models.Spec()
+	}
+	if packagePath == "" {
+		packagePath = "./models"
+	}
+
+	options := importOptions
+	var err error
+	options.Columns, options.UniqueKeys, err = importModelColumns(packagePath, options.Table)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = cmd.InOrStdin()
+	if len(args) == 1 && args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", args[0], err)
+		}
+		defer file.Close()
+		input = file
+		if options.Format == "" && strings.EqualFold(filepath.Ext(args[0]), ".jsonl") {
+			options.Format = transfer.FormatJSONL
+		}
+	}
+	if options.Format == "" {
+		options.Format = transfer.FormatCSV
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	count, err := options.Run(ctx, db, input, func(p transfer.ImportProgress) {
+		fmt.Fprintf(out, "  batch %d: %d rows loaded (%s)\n", p.Batches, p.Rows, p.Elapsed.Round(10*time.Millisecond))
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Imported %d rows into %s\n", count, options.Table)
+	return nil
+}
+
+// importModelColumns returns the columns and the primary and unique keys of
+// the model of table, found in the models of packagePath
+func importModelColumns(packagePath, table string) ([]transfer.ImportColumn, [][]string, error) {
+	_, schema, err := parseModelSchema(packagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := table
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	model, ok := schema.Tables[strings.Trim(name, `"`)]
+	if !ok {
+		return nil, nil, fmt.Errorf("no model in %s maps to table %s", packagePath, table)
+	}
+
+	var columns []transfer.ImportColumn
+	var keys [][]string
+	for _, column := range model.Columns {
+		columns = append(columns, modelColumn(column))
+		if column.IsUnique {
+			keys = append(keys, []string{column.Name})
+		}
+	}
+	for _, constraint := range model.Constraints {
+		if constraint.Type == "PRIMARY KEY" || constraint.Type == "UNIQUE" {
+			keys = append(keys, constraint.Columns)
+		}
+	}
+	for _, index := range model.Indexes {
+		if index.IsUnique && index.Where == "" {
+			keys = append(keys, index.Columns)
+		}
+	}
+	return columns, keys, nil
+}
+
+// parseModelSchema parses the models of packagePath and the schema they
+// declare
+func parseModelSchema(packagePath string) ([]parser.TableDefinition, *generator.DatabaseSchema, error) {
+	naming, err := modelNaming()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	models, err := parser.NewStructParserWithNaming(naming).ParseDirectory(packagePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse models in %s: %w", packagePath, err)
+	}
+	schema, err := generator.NewSchemaGenerator().GenerateSchema(models)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read models in %s: %w", packagePath, err)
+	}
+	return models, schema, nil
+}
+
+func modelColumn(column generator.SchemaColumn) transfer.ImportColumn {
+	return transfer.ImportColumn{
+		Name:       column.Name,
+		Type:       column.Type,
+		Nullable:   column.IsNullable,
+		HasDefault: column.DefaultValue != nil || column.IsAutoIncrement,
+	}
+}
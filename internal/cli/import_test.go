@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportModelColumns(t *testing.T) {
+	dir := t.TempDir()
+	source := `package models
+
+import "time"
+
+type Product struct {
+	_ struct{} ` + "`" + `storm:"table:products"` + "`" + `
+
+	ID        int64     ` + "`" + `db:"id" storm:"column:id;type:bigserial;primary_key"` + "`" + `
+	SKU       string    ` + "`" + `db:"sku" storm:"column:sku;type:varchar(64);not_null;unique"` + "`" + `
+	Name      *string   ` + "`" + `db:"name" storm:"column:name;type:text"` + "`" + `
+	CreatedAt time.Time ` + "`" + `db:"created_at" storm:"column:created_at;type:timestamptz;not_null;default:now()"` + "`" + `
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "product.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	columns, keys, err := importModelColumns(dir, "public.products")
+	if err != nil {
+		t.Fatalf("importModelColumns failed: %v", err)
+	}
+
+	var described []string
+	for _, column := range columns {
+		described = append(described, column.Name+":"+column.Type)
+		switch column.Name {
+		case "sku":
+			if column.Nullable || column.HasDefault {
+				t.Errorf("expected sku to be required, got %+v", column)
+			}
+		case "name":
+			if !column.Nullable {
+				t.Errorf("expected name to be nullable, got %+v", column)
+			}
+		case "id", "created_at":
+			if !column.HasDefault {
+				t.Errorf("expected %s to have a default, got %+v", column.Name, column)
+			}
+		}
+	}
+	if got := strings.Join(described, " "); !strings.Contains(got, "sku:varchar(64)") || len(columns) != 4 {
+		t.Errorf("unexpected columns %s", got)
+	}
+
+	var keyNames []string
+	for _, key := range keys {
+		keyNames = append(keyNames, strings.Join(key, ","))
+	}
+	if got := strings.Join(keyNames, " "); !strings.Contains(got, "sku") || !strings.Contains(got, "id") {
+		t.Errorf("expected the id and sku keys, got %s", got)
+	}
+
+	if _, _, err := importModelColumns(dir, "orders"); err == nil || !strings.Contains(err.Error(), "no model") {
+		t.Errorf("expected a missing model error, got %v", err)
+	}
+}
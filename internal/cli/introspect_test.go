@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/introspect"
+)
+
+func TestPrintModelChanges(t *testing.T) {
+	changes := []introspect.ModelChange{
+		{File: "models/user.go", Model: "User", Column: "legacy", Kind: introspect.ModelChangeRemovedColumn, Detail: "column no longer exists in users; remove the field or restore the column"},
+		{File: "models/user.go", Model: "User", Column: "created_at", Kind: introspect.ModelChangeAddField, Detail: "added field CreatedAt"},
+	}
+
+	var out bytes.Buffer
+	printModelChanges(&out, changes, true)
+	output := out.String()
+
+	expected := []string{
+		"Would update:\n  models/user.go User.created_at: added field CreatedAt",
+		"Needs review:\n  models/user.go User.legacy: column no longer exists",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	out.Reset()
+	printModelChanges(&out, nil, false)
+	if !strings.Contains(out.String(), "in sync") {
+		t.Errorf("expected an in sync message, got %q", out.String())
+	}
+}
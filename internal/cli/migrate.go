@@ -7,8 +7,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eleven-am/storm/internal/dialect"
 	"github.com/eleven-am/storm/internal/logger"
 	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/internal/parser"
 	"github.com/eleven-am/storm/pkg/storm"
 	_ "github.com/lib/pq"
 	"github.com/spf13/cobra"
@@ -41,12 +43,12 @@ Uses Storm's migration engine for schema comparison and migration generation.`,
 }
 
 func init() {
-	migrateCmd.Flags().StringVar(&dbHost, "host", "localhost", "Database host")
-	migrateCmd.Flags().StringVar(&dbPort, "port", "5432", "Database port")
-	migrateCmd.Flags().StringVar(&dbUser, "user", "", "Database user")
-	migrateCmd.Flags().StringVar(&dbPassword, "password", "", "Database password")
-	migrateCmd.Flags().StringVar(&dbName, "dbname", "", "Database name")
-	migrateCmd.Flags().StringVar(&dbSSLMode, "sslmode", "disable", "SSL mode (disable, require, verify-ca, verify-full)")
+	migrateCmd.PersistentFlags().StringVar(&dbHost, "host", "localhost", "Database host")
+	migrateCmd.PersistentFlags().StringVar(&dbPort, "port", "5432", "Database port")
+	migrateCmd.PersistentFlags().StringVar(&dbUser, "user", "", "Database user")
+	migrateCmd.PersistentFlags().StringVar(&dbPassword, "password", "", "Database password")
+	migrateCmd.PersistentFlags().StringVar(&dbName, "dbname", "", "Database name")
+	migrateCmd.PersistentFlags().StringVar(&dbSSLMode, "sslmode", "disable", "SSL mode (disable, require, verify-ca, verify-full)")
 
 	migrateCmd.Flags().StringVar(&outputDir, "output", "", "Output directory for migration files")
 	migrateCmd.Flags().StringVar(&migratePackagePath, "package", "", "Path to package containing models")
@@ -65,8 +67,8 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 		if outputDir == "" && stormConfig.Migrations.Directory != "" {
 			outputDir = stormConfig.Migrations.Directory
 		}
-		if migratePackagePath == "" && stormConfig.Models.Package != "" {
-			migratePackagePath = stormConfig.Models.Package
+		if migratePackagePath == "" {
+			migratePackagePath = stormConfig.Models.Spec()
 		}
 	}
 
@@ -77,14 +79,9 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 		migratePackagePath = "./models"
 	}
 
-	var dsn string
-	if databaseURL != "" {
-		dsn = databaseURL
-	} else if dbUser != "" && dbName != "" {
-		dsn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-			dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode)
-	} else {
-		return fmt.Errorf("database connection required: use --url flag, individual connection flags, or specify in storm.yaml")
+	dsn, err := migrateDatabaseURL()
+	if err != nil {
+		return err
 	}
 
 	logger.CLI().Debug("Using database URL: %s", dsn)
@@ -105,6 +102,11 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	config.ModelsPackage = migratePackagePath
 	config.MigrationsDir = outputDir
 	config.Debug = debug
+	if stormConfig != nil {
+		config.MigrationFileNaming = stormConfig.Migrations.Naming
+		config.MigrationHeader = stormConfig.Migrations.Header
+	}
+	applySchemaSettings(config)
 
 	stormClient, err := storm.NewWithConfig(config)
 	if err != nil {
@@ -150,6 +152,18 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// migrateDatabaseURL resolves the connection from --url, storm.yaml or the individual connection flags
+func migrateDatabaseURL() (string, error) {
+	if databaseURL != "" {
+		return databaseURL, nil
+	}
+	if dbUser != "" && dbName != "" {
+		return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+			dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode), nil
+	}
+	return "", fmt.Errorf("database connection required: use --url flag, individual connection flags, or specify in storm.yaml")
+}
+
 // ensureDatabaseExistsFromURL creates the database if it doesn't exist
 func ensureDatabaseExistsFromURL(ctx context.Context, databaseURL string) error {
 	dbName := extractDatabaseNameFromURL(databaseURL)
@@ -178,7 +192,7 @@ func ensureDatabaseExistsFromURL(ctx context.Context, databaseURL string) error
 
 	if !exists {
 
-		createSQL := fmt.Sprintf("CREATE DATABASE %s", quoteIdentifierCLI(dbName))
+		createSQL := fmt.Sprintf("CREATE DATABASE %s", dialect.QuoteIdentifier(dbName))
 		logger.DB().Info("Creating database: %s", dbName)
 
 		if _, err := adminDB.ExecContext(ctx, createSQL); err != nil {
@@ -225,27 +239,16 @@ func buildAdminDatabaseURLFromURL(databaseURL string) string {
 	return databaseURL
 }
 
-// quoteIdentifierCLI properly quotes PostgreSQL identifiers
-func quoteIdentifierCLI(name string) string {
-	return fmt.Sprintf("\"%s\"", name)
-}
-
 // executePushMigration executes migration directly using Atlas migrator
 func executePushMigration(ctx context.Context, config *storm.Config, createDBIfNotExists bool, allowDestructive bool, packagePath string) error {
 	logger.CLI().Info("Executing push migration...")
 
-	db, err := sql.Open("postgres", config.DatabaseURL)
+	naming, err := parser.BuildNaming(config.NamingConvention, config.Plurals, config.TableNames)
 	if err != nil {
-		return fmt.Errorf("failed to open database connection: %w", err)
+		return err
 	}
-	defer db.Close()
-
-	if err := db.PingContext(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	dbConfig := migrator.NewDBConfig(config.DatabaseURL)
-	atlasMigrator := migrator.NewAtlasMigrator(dbConfig)
+	naming.Include = config.IncludeModels
+	naming.Exclude = config.ExcludeModels
 
 	opts := migrator.MigrationOptions{
 		PackagePath:         packagePath,
@@ -254,14 +257,15 @@ func executePushMigration(ctx context.Context, config *storm.Config, createDBIfN
 		AllowDestructive:    allowDestructive,
 		PushToDB:            true,
 		CreateDBIfNotExists: createDBIfNotExists,
+		Naming:              naming,
 	}
 
-	result, err := atlasMigrator.GenerateMigration(ctx, db, opts)
+	changes, err := pushSchemaChanges(ctx, config.DatabaseURL, opts)
 	if err != nil {
 		return fmt.Errorf("failed to execute push migration: %w", err)
 	}
 
-	if len(result.Changes) == 0 {
+	if changes == 0 {
 		logger.CLI().Info("No schema changes detected! Database is up to date.")
 	}
 
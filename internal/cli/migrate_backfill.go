@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/spf13/cobra"
+)
+
+var migrateBackfill migrator.Backfill
+
+var migrateBackfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Add a NOT NULL column to a populated table without long locks",
+	Long: `Add a NOT NULL column to a table that already holds rows, following the safe
+online pattern:
+
+  1. add the column as nullable
+  2. fill it in batches, pausing between them
+  3. add a CHECK (column IS NOT NULL) constraint NOT VALID
+  4. VALIDATE the constraint, which does not block writes
+  5. SET NOT NULL, which PostgreSQL 12+ does without another scan, and drop the check
+
+Every step can be repeated, so an interrupted backfill is resumed by running
+the same command again. Deploy code that writes the column for new rows first.`,
+	Example: `  storm migrate backfill --table users --column slug --type text --value "lower(name)"
+  storm migrate backfill --table orders --column region --type text --value "'eu'" --batch-size 5000 --pause 200ms`,
+	RunE:         runMigrateBackfill,
+	SilenceUsage: true,
+}
+
+func init() {
+	migrateBackfillCmd.Flags().StringVar(&migrateBackfill.Table, "table", "", "Table to add the column to")
+	migrateBackfillCmd.Flags().StringVar(&migrateBackfill.Column, "column", "", "Column to add")
+	migrateBackfillCmd.Flags().StringVar(&migrateBackfill.Type, "type", "", "SQL type of the column")
+	migrateBackfillCmd.Flags().StringVar(&migrateBackfill.Value, "value", "", "SQL expression computing the value of each existing row")
+	migrateBackfillCmd.Flags().StringVar(&migrateBackfill.Key, "key", "id", "Unique column the batches walk in order")
+	migrateBackfillCmd.Flags().IntVar(&migrateBackfill.BatchSize, "batch-size", 1000, "Rows updated per batch")
+	migrateBackfillCmd.Flags().DurationVar(&migrateBackfill.Pause, "pause", 0, "Pause between batches")
+
+	migrateCmd.AddCommand(migrateBackfillCmd)
+}
+
+func runMigrateBackfill(cmd *cobra.Command, args []string) error {
+	if err := migrateBackfill.Validate(); err != nil {
+		return err
+	}
+
+	dsn, err := migrateDatabaseURL()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if err := migrateBackfill.Run(ctx, db, backfillReporter(out)); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s.%s is now NOT NULL\n", migrateBackfill.Table, migrateBackfill.Column)
+	return nil
+}
+
+// backfillReporter prints each step of a backfill as it starts and the rows
+// filled after every batch
+func backfillReporter(out io.Writer) func(migrator.BackfillProgress) {
+	steps := map[string]string{
+		migrator.BackfillStepAddColumn:  "Adding nullable column",
+		migrator.BackfillStepBackfill:   "Backfilling rows",
+		migrator.BackfillStepAddCheck:   "Adding NOT VALID check constraint",
+		migrator.BackfillStepValidate:   "Validating check constraint",
+		migrator.BackfillStepSetNotNull: "Setting NOT NULL",
+	}
+	return func(p migrator.BackfillProgress) {
+		if p.Step == migrator.BackfillStepBackfill && p.Batches > 0 {
+			fmt.Fprintf(out, "  batch %d: %d rows filled (%s)\n", p.Batches, p.Rows, p.Elapsed.Round(10*time.Millisecond))
+			return
+		}
+		fmt.Fprintf(out, "%s...\n", steps[p.Step])
+	}
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/eleven-am/storm/internal/migrator"
+)
+
+func TestBackfillReporter(t *testing.T) {
+	var out bytes.Buffer
+	report := backfillReporter(&out)
+
+	report(migrator.BackfillProgress{Step: migrator.BackfillStepBackfill})
+	report(migrator.BackfillProgress{Step: migrator.BackfillStepBackfill, Batches: 2, Rows: 2000, Elapsed: 1234 * time.Millisecond})
+	report(migrator.BackfillProgress{Step: migrator.BackfillStepSetNotNull, Batches: 2, Rows: 2000})
+
+	expected := "Backfilling rows...\n  batch 2: 2000 rows filled (1.23s)\nSetting NOT NULL...\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
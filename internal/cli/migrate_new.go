@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateNewDir    string
+	migrateNewAuthor string
+	migrateNewTicket string
+)
+
+var migrateNewCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "Create an empty up/down migration pair",
+	Long: `Create empty UP and DOWN files for a hand-written migration.
+
+The files are named and headed like generated migrations, following
+migrations.naming (timestamp or sequential) and migrations.header in
+storm.yaml, and are applied, checksummed and tracked like any other migration.`,
+	Example: `  storm migrate new backfill_user_slugs
+  storm migrate new add_audit_trigger --ticket OPS-142`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runMigrateNew,
+	SilenceUsage: true,
+}
+
+func init() {
+	migrateNewCmd.Flags().StringVar(&migrateNewDir, "dir", "", "Migrations directory (default from storm.yaml or ./migrations)")
+	migrateNewCmd.Flags().StringVar(&migrateNewAuthor, "author", "", "Author for the header (default: git user.name)")
+	migrateNewCmd.Flags().StringVar(&migrateNewTicket, "ticket", "", "Ticket reference for the header")
+
+	migrateCmd.AddCommand(migrateNewCmd)
+}
+
+func runMigrateNew(cmd *cobra.Command, args []string) error {
+	dir := migrateNewDir
+	if dir == "" && stormConfig != nil {
+		dir = stormConfig.Migrations.Directory
+	}
+	if dir == "" {
+		dir = "./migrations"
+	}
+	return createMigrationFiles(cmd.OutOrStdout(), dir, args[0], migrateNewAuthor, migrateNewTicket)
+}
+
+// migrationFileLayout returns the migration file naming and header
+// configured in storm.yaml. Hand-written migrations get DefaultHeader when
+// no header is configured.
+func migrationFileLayout(handWritten bool) migrator.FileLayout {
+	var layout migrator.FileLayout
+	if stormConfig != nil {
+		layout.Naming = stormConfig.Migrations.Naming
+		layout.Header = stormConfig.Migrations.Header
+	}
+	if layout.Header == "" && handWritten {
+		layout.Header = migrator.DefaultHeader
+	}
+	return layout
+}
+
+// createMigrationFiles writes an empty up/down pair named name to dir
+func createMigrationFiles(out io.Writer, dir, name, author, ticket string) error {
+	layout := migrationFileLayout(true)
+	layout.Author = author
+	if layout.Author == "" {
+		layout.Author = gitUserName()
+	}
+	layout.Ticket = ticket
+
+	upFile, downFile, err := layout.WriteFiles(dir, name, "", "")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Created migration files:\n")
+	fmt.Fprintf(out, "  UP:   %s\n", upFile)
+	fmt.Fprintf(out, "  DOWN: %s\n", downFile)
+	return nil
+}
+
+// gitUserName returns the configured git user.name, or an empty string
+func gitUserName() string {
+	output, err := exec.Command("git", "config", "user.name").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
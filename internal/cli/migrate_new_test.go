@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunMigrateNew(t *testing.T) {
+	origConfig, origDir, origAuthor, origTicket := stormConfig, migrateNewDir, migrateNewAuthor, migrateNewTicket
+	defer func() {
+		stormConfig, migrateNewDir, migrateNewAuthor, migrateNewTicket = origConfig, origDir, origAuthor, origTicket
+	}()
+
+	dir := t.TempDir()
+	stormConfig = &StormConfig{}
+	stormConfig.Migrations.Directory = dir
+	stormConfig.Migrations.Naming = "sequential"
+	stormConfig.Migrations.Header = "-- {{ .Name }} by {{ .Author }} for {{ .Ticket }}"
+	migrateNewDir, migrateNewAuthor, migrateNewTicket = "", "Ada", "OPS-142"
+
+	var out bytes.Buffer
+	migrateNewCmd.SetOut(&out)
+	defer migrateNewCmd.SetOut(nil)
+
+	if err := runMigrateNew(migrateNewCmd, []string{"backfill_slugs"}); err != nil {
+		t.Fatalf("runMigrateNew failed: %v", err)
+	}
+
+	upFile := filepath.Join(dir, "0001_backfill_slugs.up.sql")
+	content, err := os.ReadFile(upFile)
+	if err != nil {
+		t.Fatalf("expected %s to be created: %v", upFile, err)
+	}
+	if string(content) != "-- backfill_slugs by Ada for OPS-142\n\n" {
+		t.Errorf("unexpected up file content %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0001_backfill_slugs.down.sql")); err != nil {
+		t.Errorf("expected the down file to be created: %v", err)
+	}
+	if !strings.Contains(out.String(), upFile) {
+		t.Errorf("expected the created files to be listed, got %q", out.String())
+	}
+
+	if err := runMigrateNew(migrateNewCmd, []string{"second"}); err != nil {
+		t.Fatalf("runMigrateNew failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0002_second.up.sql")); err != nil {
+		t.Errorf("expected the next migration to be numbered 0002: %v", err)
+	}
+}
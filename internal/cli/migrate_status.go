@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateStatusDir    string
+	migrateStatusStates []string
+	migrateStatusSort   string
+	migrateStatusDesc   bool
+	migrateStatusFormat string
+)
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of every migration",
+	Long: `List each migration with its state, when it was applied, how long it took,
+whether its file still matches the SQL recorded when it was applied, and who
+applied it with which Storm version. The JSON output also has the SHA-256 of
+the applied SQL.
+
+States:
+  applied  recorded in the database and present on disk
+  pending  present on disk but not yet applied
+  missing  recorded in the database but its file is gone`,
+	RunE:         runMigrateStatus,
+	SilenceUsage: true,
+}
+
+func init() {
+	migrateStatusCmd.Flags().StringVar(&migrateStatusDir, "dir", "", "Migrations directory (default from storm.yaml or ./migrations)")
+	migrateStatusCmd.Flags().StringSliceVar(&migrateStatusStates, "state", nil, "Only show migrations in these states (applied, pending, missing)")
+	migrateStatusCmd.Flags().StringVar(&migrateStatusSort, "sort", "name", "Sort by name, applied_at, duration or state")
+	migrateStatusCmd.Flags().BoolVar(&migrateStatusDesc, "desc", false, "Sort in descending order")
+	migrateStatusCmd.Flags().StringVar(&migrateStatusFormat, "format", "text", "Output format (text, json)")
+
+	migrateCmd.AddCommand(migrateStatusCmd)
+}
+
+func runMigrateStatus(cmd *cobra.Command, args []string) error {
+	opts, err := migrateStatusOptions()
+	if err != nil {
+		return err
+	}
+	if migrateStatusFormat != "text" && migrateStatusFormat != "json" {
+		return fmt.Errorf("unsupported format %q (expected text or json)", migrateStatusFormat)
+	}
+
+	dsn, err := migrateDatabaseURL()
+	if err != nil {
+		return err
+	}
+
+	config := storm.NewConfig()
+	config.DatabaseURL = dsn
+	config.MigrationsDir = "./migrations"
+	config.Debug = debug
+	if stormConfig != nil {
+		if stormConfig.Migrations.Directory != "" {
+			config.MigrationsDir = stormConfig.Migrations.Directory
+		}
+		if stormConfig.Migrations.Table != "" {
+			config.MigrationsTable = stormConfig.Migrations.Table
+		}
+	}
+	if migrateStatusDir != "" {
+		config.MigrationsDir = migrateStatusDir
+	}
+
+	stormClient, err := storm.NewWithConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Storm client: %w", err)
+	}
+	defer stormClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	status, err := stormClient.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if migrateStatusFormat == "json" {
+		return printMigrationStatusJSON(out, status, opts)
+	}
+
+	fmt.Fprintf(out, "Migrations in %s\n\n", config.MigrationsDir)
+	printMigrationStatus(out, status, opts)
+	return nil
+}
+
+// migrateStatusOptions converts the status flags into list options
+func migrateStatusOptions() (storm.MigrationListOptions, error) {
+	opts := storm.MigrationListOptions{Descending: migrateStatusDesc}
+
+	sortBy, err := storm.ParseMigrationSortField(migrateStatusSort)
+	if err != nil {
+		return opts, err
+	}
+	opts.SortBy = sortBy
+
+	for _, value := range migrateStatusStates {
+		state, err := storm.ParseMigrationState(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.States = append(opts.States, state)
+	}
+
+	return opts, nil
+}
+
+// printMigrationStatus prints the migrations as an aligned table followed by a summary
+func printMigrationStatus(out io.Writer, status *storm.MigrationStatus, opts storm.MigrationListOptions) {
+	migrations := status.List(opts)
+
+	if len(migrations) == 0 {
+		fmt.Fprintln(out, "No migrations found.")
+	} else {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATE\tAPPLIED AT\tDURATION\tCHECKSUM\tAPPLIED BY\tSTORM")
+		for _, info := range migrations {
+			appliedAt, duration := "-", "-"
+			if info.AppliedAt != nil {
+				appliedAt = info.AppliedAt.Local().Format("2006-01-02 15:04:05")
+				duration = info.Duration.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", info.Name, info.State, appliedAt, duration, checksumLabel(info),
+				valueOrDash(info.AppliedBy), valueOrDash(info.StormVersion))
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintf(out, "\n%d applied, %d pending, %d missing\n", status.Applied, status.Pending, status.Missing)
+	if status.Current != "" {
+		fmt.Fprintf(out, "Current: %s\n", status.Current)
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func checksumLabel(info *storm.MigrationInfo) string {
+	switch {
+	case info.State == storm.MigrationStatePending:
+		return "-"
+	case info.State == storm.MigrationStateMissing:
+		return "file missing"
+	case !info.ChecksumValid:
+		return "modified"
+	default:
+		return "ok"
+	}
+}
+
+type migrationStatusJSON struct {
+	Current    string              `json:"current"`
+	Applied    int                 `json:"applied"`
+	Pending    int                 `json:"pending"`
+	Missing    int                 `json:"missing"`
+	Available  int                 `json:"available"`
+	Migrations []migrationInfoJSON `json:"migrations"`
+}
+
+type migrationInfoJSON struct {
+	Name          string     `json:"name"`
+	State         string     `json:"state"`
+	AppliedAt     *time.Time `json:"applied_at,omitempty"`
+	Checksum      string     `json:"checksum"`
+	ChecksumValid bool       `json:"checksum_valid"`
+	DurationMS    int64      `json:"duration_ms"`
+	AppliedBy     string     `json:"applied_by,omitempty"`
+	StormVersion  string     `json:"storm_version,omitempty"`
+	SQLHash       string     `json:"sql_hash,omitempty"`
+}
+
+func printMigrationStatusJSON(out io.Writer, status *storm.MigrationStatus, opts storm.MigrationListOptions) error {
+	report := migrationStatusJSON{
+		Current:    status.Current,
+		Applied:    status.Applied,
+		Pending:    status.Pending,
+		Missing:    status.Missing,
+		Available:  status.Available,
+		Migrations: []migrationInfoJSON{},
+	}
+
+	for _, info := range status.List(opts) {
+		report.Migrations = append(report.Migrations, migrationInfoJSON{
+			Name:          info.Name,
+			State:         string(info.State),
+			AppliedAt:     info.AppliedAt,
+			Checksum:      info.Checksum,
+			ChecksumValid: info.ChecksumValid,
+			DurationMS:    info.Duration.Milliseconds(),
+			AppliedBy:     info.AppliedBy,
+			StormVersion:  info.StormVersion,
+			SQLHash:       info.SQLHash,
+		})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eleven-am/storm/pkg/storm"
+)
+
+func testMigrationStatus() *storm.MigrationStatus {
+	applied := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &storm.MigrationStatus{
+		Current:   "002_add_email",
+		Applied:   2,
+		Pending:   1,
+		Missing:   0,
+		Available: 3,
+		Migrations: []*storm.MigrationInfo{
+			{Name: "001_create_users", State: storm.MigrationStateApplied, AppliedAt: &applied, Checksum: "1d", ChecksumValid: true, Duration: 15 * time.Millisecond, AppliedBy: "ada@ci", StormVersion: "1.0.0", SQLHash: "9f86d081"},
+			{Name: "002_add_email", State: storm.MigrationStateApplied, AppliedAt: &applied, Checksum: "0", ChecksumValid: false, Duration: 2 * time.Millisecond},
+			{Name: "003_add_posts", State: storm.MigrationStatePending, Checksum: "1b", ChecksumValid: true},
+		},
+	}
+}
+
+func TestPrintMigrationStatus(t *testing.T) {
+	var out bytes.Buffer
+	printMigrationStatus(&out, testMigrationStatus(), storm.MigrationListOptions{})
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[0], "CHECKSUM") {
+		t.Fatalf("expected a header row, got %q", lines[0])
+	}
+
+	for _, want := range []string{
+		"001_create_users  applied",
+		"15ms",
+		"modified",
+		"003_add_posts     pending  -",
+		"ada@ci",
+		"1.0.0",
+		"2 applied, 1 pending, 0 missing",
+		"Current: 002_add_email",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	printMigrationStatus(&out, testMigrationStatus(), storm.MigrationListOptions{States: []storm.MigrationState{storm.MigrationStateMissing}})
+	if !strings.Contains(out.String(), "No migrations found.") {
+		t.Errorf("expected empty listing, got:\n%s", out.String())
+	}
+}
+
+func TestPrintMigrationStatusJSON(t *testing.T) {
+	var out bytes.Buffer
+	opts := storm.MigrationListOptions{SortBy: storm.SortMigrationsByDuration, Descending: true}
+	if err := printMigrationStatusJSON(&out, testMigrationStatus(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var report migrationStatusJSON
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if report.Applied != 2 || report.Pending != 1 || len(report.Migrations) != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Migrations[0].Name != "001_create_users" || report.Migrations[0].DurationMS != 15 ||
+		report.Migrations[0].AppliedBy != "ada@ci" || report.Migrations[0].SQLHash != "9f86d081" {
+		t.Errorf("expected slowest migration first, got %+v", report.Migrations[0])
+	}
+	if report.Migrations[2].AppliedAt != nil || !strings.Contains(out.String(), `"state": "pending"`) {
+		t.Errorf("expected pending migration without applied time, got %+v", report.Migrations[2])
+	}
+}
+
+func TestMigrateStatusOptions(t *testing.T) {
+	origStates, origSort, origDesc := migrateStatusStates, migrateStatusSort, migrateStatusDesc
+	defer func() {
+		migrateStatusStates, migrateStatusSort, migrateStatusDesc = origStates, origSort, origDesc
+	}()
+
+	migrateStatusStates = []string{"pending", "missing"}
+	migrateStatusSort = "applied_at"
+	migrateStatusDesc = true
+
+	opts, err := migrateStatusOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.SortBy != storm.SortMigrationsByAppliedAt || !opts.Descending || len(opts.States) != 2 {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	migrateStatusStates = []string{"done"}
+	if _, err := migrateStatusOptions(); err == nil {
+		t.Error("expected an error for an unknown state")
+	}
+
+	migrateStatusStates = nil
+	migrateStatusSort = "size"
+	if _, err := migrateStatusOptions(); err == nil {
+		t.Error("expected an error for an unknown sort field")
+	}
+}
+
+func TestMigrateStatusCommand(t *testing.T) {
+	found := false
+	for _, cmd := range migrateCmd.Commands() {
+		if cmd.Name() == "status" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected migrate to have a status subcommand")
+	}
+
+	for _, flag := range []string{"state", "sort", "desc", "format", "dir"} {
+		if migrateStatusCmd.Flags().Lookup(flag) == nil {
+			t.Errorf("expected status flag --%s", flag)
+		}
+	}
+	if migrateCmd.PersistentFlags().Lookup("user") == nil {
+		t.Error("expected connection flags to be shared with status")
+	}
+}
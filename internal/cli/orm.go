@@ -4,16 +4,20 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/eleven-am/storm/internal/parser"
 	"github.com/eleven-am/storm/pkg/storm"
 	"github.com/spf13/cobra"
 )
 
 var (
-	ormPackage      string
-	ormOutput       string
-	ormIncludeHooks bool
-	ormIncludeTests bool
-	ormIncludeMocks bool
+	ormPackage         string
+	ormOutput          string
+	ormIncludeHooks    bool
+	ormIncludeTests    bool
+	ormIncludeMocks    bool
+	ormIncludeHandlers bool
+	ormIncludeDTOs     bool
+	ormTemplatesDir    string
 )
 
 var ormCmd = &cobra.Command{
@@ -36,15 +40,15 @@ func init() {
 	ormCmd.Flags().BoolVar(&ormIncludeHooks, "hooks", false, "Generate lifecycle hooks")
 	ormCmd.Flags().BoolVar(&ormIncludeTests, "tests", false, "Generate test files")
 	ormCmd.Flags().BoolVar(&ormIncludeMocks, "mocks", false, "Generate mock implementations")
+	ormCmd.Flags().BoolVar(&ormIncludeHandlers, "handlers", false, "Generate CRUD HTTP handlers")
+	ormCmd.Flags().BoolVar(&ormIncludeDTOs, "dtos", false, "Generate request/response DTOs")
+	ormCmd.Flags().StringVar(&ormTemplatesDir, "templates", "", "Directory of custom templates overriding or extending the built-in ones")
 }
 
 func runORM(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
 	if stormConfig != nil {
-		if ormPackage == "" && stormConfig.Models.Package != "" {
-			ormPackage = stormConfig.Models.Package
-		}
 		if !cmd.Flags().Changed("hooks") && stormConfig.ORM.GenerateHooks {
 			ormIncludeHooks = stormConfig.ORM.GenerateHooks
 		}
@@ -54,26 +58,46 @@ func runORM(cmd *cobra.Command, args []string) error {
 		if !cmd.Flags().Changed("mocks") && stormConfig.ORM.GenerateMocks {
 			ormIncludeMocks = stormConfig.ORM.GenerateMocks
 		}
+		if !cmd.Flags().Changed("handlers") && stormConfig.ORM.GenerateHandlers {
+			ormIncludeHandlers = stormConfig.ORM.GenerateHandlers
+		}
+		if !cmd.Flags().Changed("dtos") && stormConfig.ORM.GenerateDTOs {
+			ormIncludeDTOs = stormConfig.ORM.GenerateDTOs
+		}
+		if ormTemplatesDir == "" && stormConfig.ORM.TemplatesDir != "" {
+			ormTemplatesDir = stormConfig.ORM.TemplatesDir
+		}
 	}
 
-	if ormPackage == "" {
-		ormPackage = "./models"
-	}
-	if ormOutput == "" {
-		ormOutput = ormPackage
+	targets, err := ormTargets()
+	if err != nil {
+		return err
 	}
 
 	if verbose {
-		cmd.Printf("Models package: %s\n", ormPackage)
-		cmd.Printf("Output directory: %s\n", ormOutput)
+		for _, target := range targets {
+			cmd.Printf("Models package: %s\n", target.Path)
+			cmd.Printf("Output directory: %s\n", target.Output)
+		}
 		cmd.Printf("Generate hooks: %v\n", ormIncludeHooks)
 		cmd.Printf("Generate tests: %v\n", ormIncludeTests)
 		cmd.Printf("Generate mocks: %v\n", ormIncludeMocks)
+		cmd.Printf("Generate handlers: %v\n", ormIncludeHandlers)
+		cmd.Printf("Generate DTOs: %v\n", ormIncludeDTOs)
+		if ormTemplatesDir != "" {
+			cmd.Printf("Templates directory: %s\n", ormTemplatesDir)
+		}
+	}
+
+	var paths []string
+	for _, target := range targets {
+		paths = append(paths, target.Path)
 	}
 
 	config := storm.NewConfig()
-	config.ModelsPackage = ormPackage
+	config.ModelsPackage = parser.JoinPackageSpec(paths)
 	config.Debug = debug
+	applySchemaSettings(config)
 	config.DatabaseURL = "postgres://localhost/dummy"
 
 	stormClient, err := storm.NewWithConfig(config)
@@ -82,20 +106,57 @@ func runORM(cmd *cobra.Command, args []string) error {
 	}
 	defer stormClient.Close()
 
-	fmt.Printf("Generating ORM code from models in %s\n", ormPackage)
+	for _, target := range targets {
+		fmt.Printf("Generating ORM code from models in %s\n", target.Path)
+
+		opts := storm.GenerateOptions{
+			PackagePath:     target.Path,
+			OutputDir:       target.Output,
+			IncludeHooks:    ormIncludeHooks,
+			IncludeTests:    ormIncludeTests,
+			IncludeMocks:    ormIncludeMocks,
+			IncludeHandlers: ormIncludeHandlers,
+			IncludeDTOs:     ormIncludeDTOs,
+			TemplatesDir:    ormTemplatesDir,
+			Database:        targetDB,
+		}
+
+		if err := stormClient.Generate(ctx, opts); err != nil {
+			return fmt.Errorf("failed to generate ORM code for %s: %w", target.Path, err)
+		}
 
-	opts := storm.GenerateOptions{
-		PackagePath:  ormPackage,
-		OutputDir:    ormOutput,
-		IncludeHooks: ormIncludeHooks,
-		IncludeTests: ormIncludeTests,
-		IncludeMocks: ormIncludeMocks,
+		fmt.Printf("ORM code generated successfully in %s\n", target.Output)
 	}
+	return nil
+}
 
-	if err := stormClient.Generate(ctx, opts); err != nil {
-		return fmt.Errorf("failed to generate ORM code: %w", err)
+// ormTargets lists the packages to generate and their output directories.
+// --package selects a single package; otherwise every configured package is
+// generated into its own output.
+func ormTargets() ([]ModelPackage, error) {
+	if ormPackage != "" || stormConfig == nil {
+		target := ModelPackage{Path: ormPackage, Output: ormOutput}
+		if target.Path == "" {
+			target.Path = "./models"
+		}
+		if target.Output == "" {
+			target.Output = target.Path
+		}
+		return []ModelPackage{target}, nil
 	}
 
-	fmt.Printf("ORM code generated successfully in %s\n", ormOutput)
-	return nil
+	targets, err := stormConfig.Models.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		targets = []ModelPackage{{Path: "./models", Output: "./models"}}
+	}
+	if ormOutput != "" {
+		if len(targets) > 1 {
+			return nil, fmt.Errorf("--output applies to a single package: choose one with --package or set output per package in storm.yaml")
+		}
+		targets[0].Output = ormOutput
+	}
+	return targets, nil
 }
@@ -208,3 +208,45 @@ func TestORMCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestORMTargets(t *testing.T) {
+	origPackage, origOutput, origConfig := ormPackage, ormOutput, stormConfig
+	defer func() {
+		ormPackage, ormOutput, stormConfig = origPackage, origOutput, origConfig
+	}()
+
+	stormConfig = &StormConfig{}
+	stormConfig.Models.Packages = []ModelPackage{
+		{Path: "./users/models", Output: "./users/repo"},
+		{Path: "./billing/models"},
+	}
+
+	t.Run("generates every configured package", func(t *testing.T) {
+		ormPackage, ormOutput = "", ""
+		targets, err := ormTargets()
+		if err != nil {
+			t.Fatalf("ormTargets failed: %v", err)
+		}
+		if len(targets) != 2 || targets[0].Output != "users/repo" || targets[1].Output != "billing/models" {
+			t.Errorf("unexpected targets: %+v", targets)
+		}
+	})
+
+	t.Run("--package selects one package", func(t *testing.T) {
+		ormPackage, ormOutput = "./users/models", ""
+		targets, err := ormTargets()
+		if err != nil {
+			t.Fatalf("ormTargets failed: %v", err)
+		}
+		if len(targets) != 1 || targets[0].Output != "./users/models" {
+			t.Errorf("unexpected targets: %+v", targets)
+		}
+	})
+
+	t.Run("--output needs a single package", func(t *testing.T) {
+		ormPackage, ormOutput = "", "./generated"
+		if _, err := ormTargets(); err == nil {
+			t.Error("expected an error when --output is used with several packages")
+		}
+	})
+}
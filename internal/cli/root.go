@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"fmt"
+	"log/slog"
+	"os"
+
 	"github.com/eleven-am/storm/internal/logger"
 	"github.com/eleven-am/storm/pkg/storm"
 	"github.com/spf13/cobra"
@@ -9,10 +13,13 @@ import (
 // Global configuration variables
 var (
 	configFile  string
+	environment string
+	targetDB    string
 	stormConfig *StormConfig
 	databaseURL string
 	debug       bool
 	verbose     bool
+	logFormat   string
 )
 
 func NewRootCommand() *cobra.Command {
@@ -28,7 +35,14 @@ Storm provides powerful tools for:
 - Database schema introspection and analysis
 - Modern CLI with rich output capabilities`,
 		Version: storm.Version,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch logFormat {
+			case "text":
+			case "json":
+				logger.SetSlogHandler(slog.NewJSONHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: slog.LevelDebug}))
+			default:
+				return fmt.Errorf("unsupported log format %q: use text or json", logFormat)
+			}
 
 			if verbose {
 				logger.SetLevel(logger.DebugLevel)
@@ -38,10 +52,21 @@ Storm provides powerful tools for:
 				logger.SetLevel(logger.WarnLevel)
 			}
 
+			if environment == "" {
+				environment = os.Getenv("STORM_ENV")
+			}
+
+			if targetDB == "" {
+				targetDB = os.Getenv("STORM_DATABASE")
+			}
+
 			var err error
-			stormConfig, err = LoadStormConfig(configFile)
-			if err != nil {
-				logger.Debug("Failed to load config file: %v", err)
+			stormConfig, err = LoadStormConfigForTarget(configFile, environment, targetDB)
+			if err != nil && targetDB != "" {
+				// Falling back to the base settings would run against the wrong database
+				return err
+			} else if err != nil {
+				logger.Warn("Failed to load config file: %v", err)
 			} else {
 				logger.Debug("Loaded config from %s", configFile)
 			}
@@ -56,13 +81,17 @@ Storm provides powerful tools for:
 					logger.Debug("Strict mode enabled from config")
 				}
 			}
+			return nil
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default: storm.yaml)")
+	rootCmd.PersistentFlags().StringVar(&environment, "env", "", "config environment to use (default: $STORM_ENV)")
+	rootCmd.PersistentFlags().StringVar(&targetDB, "database", "", "named database from the databases section of storm.yaml (default: $STORM_DATABASE)")
 	rootCmd.PersistentFlags().StringVar(&databaseURL, "url", "", "database connection URL")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "log format (text, json)")
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(migrateCmd)
@@ -72,6 +101,13 @@ Storm provides powerful tools for:
 	rootCmd.AddCommand(introspectCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(ormCmd)
+	rootCmd.AddCommand(devCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(consoleCmd)
+	rootCmd.AddCommand(grantsCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(cloneCmd)
 
 	return rootCmd
 }
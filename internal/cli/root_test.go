@@ -54,6 +54,13 @@ func TestNewRootCommand(t *testing.T) {
 			"introspect",
 			"version",
 			"orm",
+			"dev",
+			"validate",
+			"console",
+			"grants",
+			"export",
+			"import",
+			"clone",
 		}
 
 		for _, expectedCmd := range expectedCommands {
@@ -75,9 +82,11 @@ func TestNewRootCommand(t *testing.T) {
 
 		expectedFlags := []string{
 			"config",
+			"env",
 			"url",
 			"debug",
 			"verbose",
+			"log-format",
 		}
 
 		for _, expectedFlag := range expectedFlags {
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validatePackage string
+	validateFormat  string
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check models for errors without a database",
+	Long: `Statically check the models package and report every problem at once.
+
+This command checks for:
+- Invalid storm and dbdef tags
+- Foreign keys referencing missing tables or columns
+- Relationships with missing targets or key columns
+- Table and column names that are reserved SQL keywords (warning)
+- Go types without a PostgreSQL mapping that fall back to TEXT (warning)
+
+Returns exit code 1 if any errors are found. Use --format json for CI.`,
+	SilenceUsage: true,
+	RunE:         runValidate,
+}
+
+func init() {
+	validateCmd.Flags().StringVar(&validatePackage, "package", "", "Path to package containing models")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format (text, json)")
+}
+
+// validationReport is the result of checking a models package
+type validationReport struct {
+	Package  string                 `json:"package"`
+	Models   int                    `json:"models"`
+	Valid    bool                   `json:"valid"`
+	Errors   int                    `json:"errors"`
+	Warnings int                    `json:"warnings"`
+	Issues   []generator.ModelIssue `json:"issues"`
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	if validateFormat != "text" && validateFormat != "json" {
+		return fmt.Errorf("unsupported format %q: use text or json", validateFormat)
+	}
+
+	packagePath := validatePackage
+	if packagePath == "" && stormConfig != nil {
+		packagePath = stormConfig.Models.Spec()
+	}
+	if packagePath == "" {
+		packagePath = "./models"
+	}
+
+	report, err := checkModelsPackage(packagePath)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if validateFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+	} else {
+		printValidationReport(out, report)
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("model validation failed with %d error(s)", report.Errors)
+	}
+	return nil
+}
+
+// checkModelsPackage parses every model in packagePath and checks them together
+func checkModelsPackage(packagePath string) (*validationReport, error) {
+	naming, err := modelNaming()
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := parser.NewStructParserWithNaming(naming).ParseDirectory(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse models in %s: %w", packagePath, err)
+	}
+
+	var models []parser.TableDefinition
+	for _, table := range tables {
+		if _, hasTable := table.TableLevel["table"]; hasTable || table.TableTag != "" {
+			models = append(models, table)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models found in %s", packagePath)
+	}
+
+	report := &validationReport{
+		Package: packagePath,
+		Models:  len(models),
+		Issues:  generator.NewSchemaGenerator().CheckModels(models),
+	}
+	if report.Issues == nil {
+		report.Issues = []generator.ModelIssue{}
+	}
+	for _, issue := range report.Issues {
+		if issue.Severity == generator.SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+	return report, nil
+}
+
+func printValidationReport(out io.Writer, report *validationReport) {
+	fmt.Fprintf(out, "Checked %d models in %s\n", report.Models, report.Package)
+	if len(report.Issues) == 0 {
+		fmt.Fprintln(out, "✓ No problems found")
+		return
+	}
+
+	fmt.Fprintln(out)
+	for _, issue := range report.Issues {
+		symbol := "✗"
+		if issue.Severity == generator.SeverityWarning {
+			symbol = "⚠"
+		}
+		fmt.Fprintf(out, "  %s [%s] %s\n", symbol, issue.Category, issue)
+	}
+	fmt.Fprintf(out, "\n%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+}
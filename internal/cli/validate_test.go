@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validModels = `package models
+
+type User struct {
+	_ struct{} ` + "`" + `storm:"table:users"` + "`" + `
+
+	ID    string ` + "`" + `db:"id" storm:"column:id;type:uuid;primary_key"` + "`" + `
+	Email string ` + "`" + `db:"email" storm:"column:email;type:text;not_null"` + "`" + `
+}
+`
+
+const brokenModels = `package models
+
+type User struct {
+	_ struct{} ` + "`" + `storm:"table:users"` + "`" + `
+
+	ID    string ` + "`" + `db:"id" storm:"column:id;type:uuid;primary_key"` + "`" + `
+	Email string ` + "`" + `db:"email" storm:"column:email;type:text;uniqe"` + "`" + `
+	Order int    ` + "`" + `db:"order" storm:"column:order;type:integer"` + "`" + `
+	Team  *Team  ` + "`" + `storm:"relation:belongs_to:Team"` + "`" + `
+}
+`
+
+func TestRunValidate(t *testing.T) {
+	origPackage := validatePackage
+	origFormat := validateFormat
+	origConfig := stormConfig
+	defer func() {
+		validatePackage = origPackage
+		validateFormat = origFormat
+		stormConfig = origConfig
+		validateCmd.SetOut(nil)
+	}()
+
+	writeModels := func(t *testing.T, source string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	run := func(packagePath, format string) (string, error) {
+		var out bytes.Buffer
+		validateCmd.SetOut(&out)
+		validatePackage = packagePath
+		validateFormat = format
+		stormConfig = nil
+		err := runValidate(validateCmd, []string{})
+		return out.String(), err
+	}
+
+	t.Run("passes for valid models", func(t *testing.T) {
+		out, err := run(writeModels(t, validModels), "text")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.Contains(out, "No problems found") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+	})
+
+	t.Run("reports all problems as text", func(t *testing.T) {
+		out, err := run(writeModels(t, brokenModels), "text")
+		if err == nil {
+			t.Fatal("expected validation to fail")
+		}
+		if !strings.Contains(err.Error(), "2 error(s)") {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		for _, want := range []string{
+			"[tag] User.Email",
+			"[reserved_word] User.Order",
+			"[relationship] User.Team: target model 'Team' is not defined",
+			"2 error(s), 1 warning(s)",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected output to contain %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("reports problems as json", func(t *testing.T) {
+		out, err := run(writeModels(t, brokenModels), "json")
+		if err == nil {
+			t.Fatal("expected validation to fail")
+		}
+
+		var report validationReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+		}
+		if report.Valid || report.Models != 1 || report.Errors != 2 || report.Warnings != 1 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		if len(report.Issues) != 3 {
+			t.Errorf("expected 3 issues, got %d", len(report.Issues))
+		}
+	})
+
+	t.Run("uses the models package from config", func(t *testing.T) {
+		dir := writeModels(t, validModels)
+		validateCmd.SetOut(&bytes.Buffer{})
+		validatePackage = ""
+		validateFormat = "text"
+		stormConfig = &StormConfig{}
+		stormConfig.Models.Package = dir
+
+		if err := runValidate(validateCmd, []string{}); err != nil {
+			t.Errorf("expected config package to validate, got %v", err)
+		}
+	})
+
+	t.Run("fails without models", func(t *testing.T) {
+		_, err := run(t.TempDir(), "text")
+		if err == nil || !strings.Contains(err.Error(), "no models found") {
+			t.Errorf("expected no models error, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		_, err := run(writeModels(t, validModels), "xml")
+		if err == nil || !strings.Contains(err.Error(), "unsupported format") {
+			t.Errorf("expected unsupported format error, got %v", err)
+		}
+	})
+}
@@ -45,6 +45,7 @@ func runVerify(cmd *cobra.Command, args []string) error {
 	config.DatabaseURL = dsn
 	config.ModelsPackage = verifyPackagePath
 	config.Debug = debug
+	applySchemaSettings(config)
 
 	stormClient, err := storm.NewWithConfig(config)
 	if err != nil {
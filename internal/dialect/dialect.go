@@ -0,0 +1,112 @@
+// Package dialect holds the PostgreSQL identifier rules shared by the schema
+// generator, the migrator and the ORM query builder
+package dialect
+
+import (
+	"strings"
+)
+
+// IsReservedKeyword reports whether name is a PostgreSQL keyword that cannot be
+// used as a bare table or column name
+func IsReservedKeyword(name string) bool {
+	return reservedKeywords[strings.ToLower(name)]
+}
+
+// NeedsQuoting reports whether name must be quoted to be used as an identifier:
+// it is a reserved keyword, or it is not made of letters, digits, underscores
+// and dollar signs starting with a letter or underscore. Upper-case letters do
+// not force quoting, so mixed-case names keep PostgreSQL's case folding.
+func NeedsQuoting(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case (r >= '0' && r <= '9') || r == '$':
+			if i == 0 {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return IsReservedKeyword(name)
+}
+
+// QuoteIdentifier quotes name, escaping any embedded double quotes
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteIdentifierIfNeeded quotes name only when NeedsQuoting requires it.
+// Names that are already quoted and the * wildcard are returned unchanged.
+func QuoteIdentifierIfNeeded(name string) string {
+	if name == "*" || isQuoted(name) || !NeedsQuoting(name) {
+		return name
+	}
+	return QuoteIdentifier(name)
+}
+
+// QuoteQualifiedIfNeeded quotes each part of a dotted name such as
+// schema.table or table.column when it needs quoting
+func QuoteQualifiedIfNeeded(name string) string {
+	if isQuoted(name) || !strings.Contains(name, ".") {
+		return QuoteIdentifierIfNeeded(name)
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdentifierIfNeeded(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func isQuoted(name string) bool {
+	return len(name) >= 2 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`)
+}
+
+// reservedKeywords are the PostgreSQL keywords that cannot be used as bare
+// column or table names: the reserved keywords, the keywords that may only be
+// type or function names, and the keywords that may only be column names in
+// some positions. See the SQL Key Words appendix of the PostgreSQL manual.
+var reservedKeywords = toSet(
+	// Reserved
+	"all", "analyse", "analyze", "and", "any", "array", "as", "asc", "asymmetric",
+	"both", "case", "cast", "check", "collate", "column", "constraint", "create",
+	"current_catalog", "current_date", "current_role", "current_time",
+	"current_timestamp", "current_user", "default", "deferrable", "desc",
+	"distinct", "do", "else", "end", "except", "false", "fetch", "for", "foreign",
+	"from", "grant", "group", "having", "in", "initially", "intersect", "into",
+	"lateral", "leading", "limit", "localtime", "localtimestamp", "not", "null",
+	"offset", "on", "only", "or", "order", "placing", "primary", "references",
+	"returning", "select", "session_user", "some", "symmetric", "system_user",
+	"table", "then", "to", "trailing", "true", "union", "unique", "user", "using",
+	"variadic", "when", "where", "window", "with",
+
+	// Reserved, except as type or function names
+	"authorization", "binary", "collation", "concurrently", "cross",
+	"current_schema", "freeze", "full", "ilike", "inner", "is", "isnull", "join",
+	"left", "like", "natural", "notnull", "outer", "overlaps", "right", "similar",
+	"tablesample", "verbose",
+
+	// Non-reserved, but cannot be function or type names
+	"between", "bigint", "bit", "boolean", "char", "character", "coalesce", "dec",
+	"decimal", "exists", "extract", "float", "greatest", "grouping", "inout", "int",
+	"integer", "interval", "json", "json_array", "json_arrayagg", "json_exists",
+	"json_object", "json_objectagg", "json_query", "json_scalar", "json_serialize",
+	"json_table", "json_value", "least", "merge_action", "national", "nchar",
+	"none", "normalize", "numeric", "out", "overlay", "position", "precision",
+	"real", "row", "setof", "smallint", "substring", "time", "timestamp", "treat",
+	"trim", "values", "varchar", "xmlattributes", "xmlconcat", "xmlelement",
+	"xmlexists", "xmlforest", "xmlnamespaces", "xmlparse", "xmlpi", "xmlroot",
+	"xmlserialize", "xmltable",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,67 @@
+package dialect
+
+import "testing"
+
+func TestIsReservedKeyword(t *testing.T) {
+	for _, word := range []string{"user", "ORDER", "group", "limit", "join", "between", "timestamp", "system_user"} {
+		if !IsReservedKeyword(word) {
+			t.Errorf("expected %q to be reserved", word)
+		}
+	}
+	for _, word := range []string{"users", "name", "key", "index", "text", "date", "password"} {
+		if IsReservedKeyword(word) {
+			t.Errorf("expected %q not to be reserved", word)
+		}
+	}
+}
+
+func TestQuoteIdentifierIfNeeded(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"users", "users"},
+		{"created_at", "created_at"},
+		{"userId", "userId"},
+		{"price$", "price$"},
+		{"user", `"user"`},
+		{"Order", `"Order"`},
+		{"1st_place", `"1st_place"`},
+		{"first name", `"first name"`},
+		{"odd-name", `"odd-name"`},
+		{`say"hi`, `"say""hi"`},
+		{`"user"`, `"user"`},
+		{"*", "*"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := QuoteIdentifierIfNeeded(tt.input); got != tt.expected {
+				t.Errorf("QuoteIdentifierIfNeeded(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteQualifiedIfNeeded(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"users.id", "users.id"},
+		{"user.order", `"user"."order"`},
+		{"public.user", `public."user"`},
+		{"users.*", "users.*"},
+		{"group", `"group"`},
+		{`"user"`, `"user"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := QuoteQualifiedIfNeeded(tt.input); got != tt.expected {
+				t.Errorf("QuoteQualifiedIfNeeded(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// typeCategory groups PostgreSQL types whose defaults are checked the same way
+type typeCategory int
+
+const (
+	categoryOther typeCategory = iota
+	categoryString
+	categoryNumeric
+	categoryBoolean
+	categoryTemporal
+	categoryJSON
+	categoryUUID
+	categoryBytes
+	categoryArray
+)
+
+func (c typeCategory) String() string {
+	switch c {
+	case categoryString:
+		return "string"
+	case categoryNumeric:
+		return "numeric"
+	case categoryBoolean:
+		return "boolean"
+	case categoryTemporal:
+		return "date/time"
+	case categoryJSON:
+		return "JSON"
+	case categoryUUID:
+		return "UUID"
+	case categoryBytes:
+		return "binary"
+	case categoryArray:
+		return "array"
+	}
+	return "other"
+}
+
+// categoryOf returns the category of a PostgreSQL type such as VARCHAR(255),
+// TIMESTAMPTZ or TEXT[]. Enums and other user defined types are categoryOther.
+func categoryOf(pgType string) typeCategory {
+	t := strings.ToLower(strings.TrimSpace(pgType))
+	if strings.HasSuffix(t, "[]") {
+		return categoryArray
+	}
+	if i := strings.Index(t, "("); i >= 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+
+	switch t {
+	case "text", "varchar", "character varying", "char", "character", "bpchar", "citext", "name":
+		return categoryString
+	case "smallint", "integer", "int", "int2", "int4", "int8", "bigint", "real", "double precision",
+		"float4", "float8", "numeric", "decimal", "serial", "bigserial", "smallserial", "money":
+		return categoryNumeric
+	case "boolean", "bool":
+		return categoryBoolean
+	case "timestamp", "timestamptz", "timestamp with time zone", "timestamp without time zone",
+		"date", "time", "timetz", "time with time zone", "time without time zone", "interval":
+		return categoryTemporal
+	case "json", "jsonb":
+		return categoryJSON
+	case "uuid":
+		return categoryUUID
+	case "bytea":
+		return categoryBytes
+	}
+	return categoryOther
+}
+
+var (
+	defaultNumber     = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	defaultWord       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	defaultFunction   = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.]*)\s*\(`)
+	defaultCastType   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .]*(\(\s*\d+\s*(,\s*\d+\s*)?\))?(\[\])*$`)
+	defaultUUIDFormat = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+)
+
+// defaultKeywords are the SQL value keywords a default may be, with the
+// category of their result
+var defaultKeywords = map[string]typeCategory{
+	"current_timestamp": categoryTemporal,
+	"current_date":      categoryTemporal,
+	"current_time":      categoryTemporal,
+	"localtimestamp":    categoryTemporal,
+	"localtime":         categoryTemporal,
+	"current_user":      categoryString,
+	"session_user":      categoryString,
+	"null":              categoryOther,
+	"true":              categoryBoolean,
+	"false":             categoryBoolean,
+}
+
+// defaultFunctions are the functions commonly used as defaults whose result
+// category is known. Other functions are accepted without a type check.
+var defaultFunctions = map[string]typeCategory{
+	"now":                   categoryTemporal,
+	"clock_timestamp":       categoryTemporal,
+	"statement_timestamp":   categoryTemporal,
+	"transaction_timestamp": categoryTemporal,
+	"gen_random_uuid":       categoryUUID,
+	"uuid_generate_v1":      categoryUUID,
+	"uuid_generate_v4":      categoryUUID,
+	"nextval":               categoryNumeric,
+}
+
+// defaultValueError reports a field whose default does not fit its column
+type defaultValueError struct {
+	field string
+	err   error
+}
+
+func (e *defaultValueError) Error() string {
+	return fmt.Sprintf("invalid default for field %s: %v", e.field, e.err)
+}
+
+func (e *defaultValueError) Unwrap() error {
+	return e.err
+}
+
+// formatDefault checks the default of column against its type and returns
+// the SQL it is written as in a DEFAULT clause. Quoted literals, numbers,
+// keywords, function calls and casts such as '{}'::jsonb are checked; a bare
+// word on a text or enum column is quoted, and any other expression is
+// passed through once its quotes and parentheses balance.
+func formatDefault(column SchemaColumn) (string, error) {
+	if column.DefaultValue == nil {
+		return "", nil
+	}
+	raw := strings.TrimSpace(*column.DefaultValue)
+	if raw == "" {
+		return "", fmt.Errorf("default value is empty")
+	}
+	if err := checkBalanced(raw); err != nil {
+		return "", fmt.Errorf("default %s: %w", raw, err)
+	}
+
+	category := categoryOf(column.Type)
+	value, castType := splitCast(raw)
+	if castType != "" {
+		if !defaultCastType.MatchString(castType) {
+			return "", fmt.Errorf("default %s casts to invalid type %q", raw, castType)
+		}
+		castCategory := categoryOf(castType)
+		if !compatible(category, castCategory) {
+			return "", fmt.Errorf("default %s is cast to %s, which does not fit %s column", raw, castType, column.Type)
+		}
+		if castCategory != categoryOther {
+			category = castCategory
+		}
+		if err := checkDefaultValue(value, category, nil); err != nil {
+			return "", fmt.Errorf("default %s: %w", raw, err)
+		}
+		return raw, nil
+	}
+
+	if err := checkDefaultValue(value, category, column.EnumValues); err != nil {
+		return "", fmt.Errorf("default %s: %w for %s column", raw, err, column.Type)
+	}
+
+	switch {
+	case defaultNumber.MatchString(value) && category == categoryString:
+		return quoteLiteral(value), nil
+	case defaultWord.MatchString(value):
+		if _, keyword := defaultKeywords[strings.ToLower(value)]; keyword {
+			return value, nil
+		}
+		return quoteLiteral(value), nil
+	case category == categoryString && !strings.ContainsAny(value, `'"()`):
+		return quoteLiteral(value), nil
+	case isBareStructure(value, category):
+		return quoteLiteral(value), nil
+	}
+	return value, nil
+}
+
+// checkDefaultValue checks a default without its cast against the category
+// of the column, or of the cast when there is one
+func checkDefaultValue(value string, category typeCategory, enumValues []string) error {
+	if literal, ok := unquoteLiteral(value); ok {
+		return checkLiteral(literal, category, enumValues)
+	}
+
+	if defaultNumber.MatchString(value) {
+		if category == categoryString || category == categoryNumeric || category == categoryOther {
+			return nil
+		}
+		return fmt.Errorf("is a number, not a %s value", category)
+	}
+
+	if defaultWord.MatchString(value) {
+		if result, keyword := defaultKeywords[strings.ToLower(value)]; keyword {
+			if !compatible(category, result) {
+				return fmt.Errorf("is a %s value", result)
+			}
+			return nil
+		}
+		return checkLiteral(value, category, enumValues)
+	}
+
+	if isBareStructure(value, category) {
+		return checkLiteral(value, category, nil)
+	}
+
+	if match := defaultFunction.FindStringSubmatch(value); match != nil && closesAtEnd(value, len(match[0])-1) {
+		if result, known := defaultFunctions[strings.ToLower(match[1])]; known && !compatible(category, result) {
+			return fmt.Errorf("returns a %s value", result)
+		}
+	}
+	return nil
+}
+
+// checkLiteral checks the text of a literal parses as a value of category
+func checkLiteral(literal string, category typeCategory, enumValues []string) error {
+	if enumValues != nil {
+		for _, v := range enumValues {
+			if v == literal {
+				return nil
+			}
+		}
+		return fmt.Errorf("is not one of %s", strings.Join(enumValues, ", "))
+	}
+
+	switch category {
+	case categoryNumeric:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(literal), 64); err != nil {
+			return fmt.Errorf("is not a number")
+		}
+	case categoryBoolean:
+		switch strings.ToLower(strings.TrimSpace(literal)) {
+		case "true", "false", "t", "f", "yes", "no", "y", "n", "on", "off", "1", "0":
+		default:
+			return fmt.Errorf("is not a boolean")
+		}
+	case categoryUUID:
+		if !defaultUUIDFormat.MatchString(strings.Trim(strings.TrimSpace(literal), "{}")) {
+			return fmt.Errorf("is not a UUID")
+		}
+	case categoryJSON:
+		if !json.Valid([]byte(literal)) {
+			return fmt.Errorf("is not valid JSON")
+		}
+	case categoryArray:
+		trimmed := strings.TrimSpace(literal)
+		if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+			return fmt.Errorf("is not an array literal such as '{}'")
+		}
+	}
+	return nil
+}
+
+// isBareStructure reports whether value is an unquoted JSON document or
+// array literal, such as {} on a JSONB or TEXT[] column
+func isBareStructure(value string, category typeCategory) bool {
+	switch category {
+	case categoryJSON:
+		return strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")
+	case categoryArray:
+		return strings.HasPrefix(value, "{")
+	}
+	return false
+}
+
+// compatible reports whether a value of category value can be assigned to a
+// column of category column. Anything can be assigned to a text column, and
+// categories that are not known are not checked.
+func compatible(column, value typeCategory) bool {
+	return column == value || column == categoryString || column == categoryOther || value == categoryOther
+}
+
+// splitCast splits a trailing top-level ::type cast off an expression
+func splitCast(s string) (string, string) {
+	depth, quoted, cast := 0, false, -1
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ':' && depth == 0 && i+1 < len(s) && s[i+1] == ':':
+			cast = i
+			i++
+		}
+	}
+	if cast < 0 {
+		return s, ""
+	}
+	return strings.TrimSpace(s[:cast]), strings.TrimSpace(s[cast+2:])
+}
+
+// checkBalanced reports unterminated quotes and unbalanced parentheses
+func checkBalanced(s string) error {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses")
+			}
+		}
+	}
+	if quote == '\'' {
+		return fmt.Errorf("unterminated string literal")
+	}
+	if quote == '"' {
+		return fmt.Errorf("unterminated quoted identifier")
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
+
+// closesAtEnd reports whether the parenthesis opened at open closes at the
+// end of s, so s is a single function call
+func closesAtEnd(s string, open int) bool {
+	depth, quoted := 0, false
+	for i := open; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i == len(s)-1
+			}
+		}
+	}
+	return false
+}
+
+// unquoteLiteral returns the text of a single string literal, undoubling its
+// quotes, or of an escape string such as E'tab\t'. It reports false for
+// anything else.
+func unquoteLiteral(s string) (string, bool) {
+	if len(s) > 0 && (s[0] == 'E' || s[0] == 'e') {
+		s = s[1:]
+	}
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return "", false
+	}
+	body := s[1 : len(s)-1]
+	if strings.Contains(strings.ReplaceAll(body, "''", ""), "'") {
+		return "", false
+	}
+	return strings.ReplaceAll(body, "''", "'"), true
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
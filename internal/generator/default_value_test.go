@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		colType  string
+		enum     []string
+		value    string
+		expected string
+		err      string
+	}{
+		{"function", "TIMESTAMPTZ", nil, "now()", "now()", ""},
+		{"keyword", "TIMESTAMPTZ", nil, "CURRENT_TIMESTAMP", "CURRENT_TIMESTAMP", ""},
+		{"expression", "TIMESTAMPTZ", nil, "now() at time zone 'utc'", "now() at time zone 'utc'", ""},
+		{"uuid function", "UUID", nil, "gen_random_uuid()", "gen_random_uuid()", ""},
+		{"sequence", "BIGINT", nil, "nextval('orders_id_seq'::regclass)", "nextval('orders_id_seq'::regclass)", ""},
+		{"bare word on text", "VARCHAR(3)", nil, "USD", "'USD'", ""},
+		{"quoted text", "TEXT", nil, "'it''s'", "'it''s'", ""},
+		{"number on text", "TEXT", nil, "42", "'42'", ""},
+		{"text expression", "VARCHAR(20)", nil, "upper(substring(name from 1 for 3))", "upper(substring(name from 1 for 3))", ""},
+		{"number", "INTEGER", nil, "-1.5", "-1.5", ""},
+		{"boolean", "BOOLEAN", nil, "true", "true", ""},
+		{"jsonb cast", "JSONB", nil, "'{}'::jsonb", "'{}'::jsonb", ""},
+		{"bare json", "JSONB", nil, `{"theme":"dark"}`, `'{"theme":"dark"}'`, ""},
+		{"empty array", "TEXT[]", nil, "'{}'", "'{}'", ""},
+		{"enum value", "users_status_enum", []string{"pending", "active"}, "pending", "'pending'", ""},
+		{"interval literal", "INTERVAL", nil, "'1 day'", "'1 day'", ""},
+
+		{"word on integer", "INTEGER", nil, "free", "", "is not a number"},
+		{"number on boolean", "BOOLEAN", nil, "1", "", "is a number, not a boolean value"},
+		{"function on boolean", "BOOLEAN", nil, "now()", "", "returns a date/time value"},
+		{"keyword on integer", "INTEGER", nil, "CURRENT_DATE", "", "is a date/time value"},
+		{"invalid json", "JSONB", nil, "'{oops}'::jsonb", "", "is not valid JSON"},
+		{"cast to another type", "INTEGER", nil, "'{}'::jsonb", "", "does not fit INTEGER column"},
+		{"bad cast type", "TEXT", nil, "'x'::text;drop", "", "invalid type"},
+		{"bad uuid", "UUID", nil, "'not-a-uuid'", "", "is not a UUID"},
+		{"not an array", "TEXT[]", nil, "'a,b'", "", "is not an array literal"},
+		{"unknown enum value", "users_status_enum", []string{"pending", "active"}, "archived", "", "is not one of pending, active"},
+		{"unterminated", "TEXT", nil, "'open", "", "unterminated string literal"},
+		{"unbalanced", "TIMESTAMPTZ", nil, "now(", "", "unbalanced parentheses"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := tt.value
+			formatted, err := formatDefault(SchemaColumn{Name: "col", Type: tt.colType, EnumValues: tt.enum, DefaultValue: &value})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if formatted != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, formatted)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	parser2 "github.com/eleven-am/storm/internal/parser"
+)
+
+// Severities of a ModelIssue
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Categories of a ModelIssue
+const (
+	CategoryTag          = "tag"
+	CategoryForeignKey   = "foreign_key"
+	CategoryRelationship = "relationship"
+	CategoryReservedWord = "reserved_word"
+	CategoryTypeMapping  = "type_mapping"
+)
+
+// ModelIssue is a problem found in a model definition by CheckModels
+type ModelIssue struct {
+	Severity string `json:"severity"`
+	Category string `json:"category"`
+	Model    string `json:"model"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i ModelIssue) String() string {
+	if i.Field != "" {
+		return fmt.Sprintf("%s.%s: %s", i.Model, i.Field, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.Model, i.Message)
+}
+
+// checkedModel is the column and relationship information CheckModels
+// gathers from a table before checking references between tables
+type checkedModel struct {
+	def           parser2.TableDefinition
+	columns       map[string]bool
+	foreignKeys   map[string]*ForeignKeyRef
+	relationships map[string]*parser2.ParsedStormTag
+}
+
+// CheckModels statically checks parsed model definitions and returns every
+// problem found instead of stopping at the first one. Tag errors, missing
+// foreign key targets and relationship misconfigurations are errors; reserved
+// keyword identifiers and Go types that silently fall back to TEXT are warnings.
+func (g *SchemaGenerator) CheckModels(tables []parser2.TableDefinition) []ModelIssue {
+	var issues []ModelIssue
+	report := func(severity, category, model, field, format string, args ...interface{}) {
+		issues = append(issues, ModelIssue{
+			Severity: severity,
+			Category: category,
+			Model:    model,
+			Field:    field,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	stormParser := parser2.NewStormTagParser()
+	models := make(map[string]*checkedModel, len(tables))
+	byTable := make(map[string]*checkedModel, len(tables))
+	var ordered []*checkedModel
+
+	for _, table := range tables {
+		model := &checkedModel{
+			def:           table,
+			columns:       make(map[string]bool),
+			foreignKeys:   make(map[string]*ForeignKeyRef),
+			relationships: make(map[string]*parser2.ParsedStormTag),
+		}
+		name := table.StructName
+
+		if table.TableTag != "" {
+			if _, err := stormParser.ParseStormTag(table.TableTag, false); err != nil {
+				report(SeverityError, CategoryTag, name, "", "invalid table tag: %v", err)
+			}
+		}
+		if other, exists := byTable[table.TableName]; exists {
+			report(SeverityError, CategoryTag, name, "", "table name '%s' is already used by %s", table.TableName, other.def.StructName)
+		} else {
+			byTable[table.TableName] = model
+		}
+		if IsReservedKeyword(table.TableName) {
+			report(SeverityWarning, CategoryReservedWord, name, "", "table name '%s' is a reserved SQL keyword and must be quoted in raw queries", table.TableName)
+		}
+
+		for _, field := range table.Fields {
+			if field.StormTag != "" && isRelationshipTag(field.StormTag) {
+				parsed, err := stormParser.ParseStormTag(field.StormTag, true)
+				if err != nil {
+					report(SeverityError, CategoryTag, name, field.Name, "invalid relationship tag: %v", err)
+					continue
+				}
+				model.relationships[field.Name] = parsed
+				if parsed.RelationType == "has_many" || parsed.RelationType == "has_many_through" {
+					if !field.IsArray {
+						report(SeverityError, CategoryRelationship, name, field.Name, "%s relationship must be a slice", parsed.RelationType)
+					}
+				} else if field.IsArray {
+					report(SeverityError, CategoryRelationship, name, field.Name, "%s relationship must not be a slice", parsed.RelationType)
+				}
+				continue
+			}
+
+			dbDef, dbName := field.DBDef, field.DBName
+			switch {
+			case field.StormTag != "":
+				parsed, err := stormParser.ParseStormTag(field.StormTag, false)
+				if err != nil {
+					report(SeverityError, CategoryTag, name, field.Name, "invalid storm tag: %v", err)
+					continue
+				}
+				if parsed.Ignore {
+					continue
+				}
+				dbDef = parsed.ToDBDefAttributes()
+				if field.DBTag == "" && parsed.Column != "" {
+					dbName = parsed.Column
+				}
+			case field.DBDefTag != "":
+				if err := g.tagParser.ValidateDBDefTag(field.DBDefTag); err != nil {
+					report(SeverityError, CategoryTag, name, field.Name, "invalid dbdef tag: %v", err)
+					continue
+				}
+			}
+
+			if _, isComputed := dbDef["computed"]; isComputed || dbName == "-" {
+				continue
+			}
+			model.columns[dbName] = true
+
+			if IsReservedKeyword(dbName) {
+				report(SeverityWarning, CategoryReservedWord, name, field.Name, "column name '%s' is a reserved SQL keyword and must be quoted in raw queries", dbName)
+			}
+			if g.tagParser.GetType(dbDef) == "" && g.tagParser.GetEnum(dbDef) == nil {
+				if _, ok := postgresTypeForGo(field.Type); !ok {
+					if _, resolved := postgresTypeForGo(field.ResolvedType); field.ResolveError != "" {
+						report(SeverityError, CategoryTypeMapping, name, field.Name, "cannot resolve Go type '%s' (%s); set an explicit type", goTypeString(field), field.ResolveError)
+					} else if !resolved {
+						report(SeverityWarning, CategoryTypeMapping, name, field.Name, "Go type '%s' has no PostgreSQL mapping and falls back to TEXT; set an explicit type", goTypeString(field))
+					}
+				}
+			}
+
+			if _, hasDefault := dbDef["default"]; hasDefault {
+				checked := field
+				checked.DBDef, checked.DBName = dbDef, dbName
+				var defaultErr *defaultValueError
+				if _, err := g.generateColumn(checked, table.TableName); errors.As(err, &defaultErr) {
+					report(SeverityError, CategoryTag, name, field.Name, "%v", defaultErr.err)
+				}
+			}
+
+			if fkRef := g.tagParser.GetForeignKey(dbDef); fkRef != "" {
+				fk, err := g.parseForeignKeyRef(fkRef)
+				if err != nil {
+					report(SeverityError, CategoryTag, name, field.Name, "invalid foreign key: %v", err)
+				} else {
+					model.foreignKeys[field.Name] = fk
+				}
+			}
+		}
+
+		for _, err := range g.checkTableLevel(table) {
+			report(SeverityError, CategoryTag, name, "", "%v", err)
+		}
+
+		models[name] = model
+		ordered = append(ordered, model)
+	}
+
+	for _, model := range ordered {
+		name := model.def.StructName
+
+		for _, field := range model.def.Fields {
+			if fk, ok := model.foreignKeys[field.Name]; ok {
+				target, exists := byTable[fk.ReferencedTable]
+				switch {
+				case !exists:
+					report(SeverityError, CategoryForeignKey, name, field.Name, "foreign key references table '%s', which is not defined by any model", fk.ReferencedTable)
+				case !target.columns[fk.ReferencedColumn]:
+					report(SeverityError, CategoryForeignKey, name, field.Name, "foreign key references column '%s.%s', which does not exist", fk.ReferencedTable, fk.ReferencedColumn)
+				}
+			}
+
+			rel, ok := model.relationships[field.Name]
+			if !ok {
+				continue
+			}
+			checkRelationship(model, rel, models, byTable, func(severity, format string, args ...interface{}) {
+				report(severity, CategoryRelationship, name, field.Name, format, args...)
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Model < issues[j].Model
+	})
+	return issues
+}
+
+// checkTableLevel validates the index, unique and check definitions of a
+// table, which schema generation would otherwise reject or silently skip
+func (g *SchemaGenerator) checkTableLevel(table parser2.TableDefinition) []error {
+	var errs []error
+	for _, key := range sortedKeys(table.TableLevel) {
+		value := table.TableLevel[key]
+		switch key {
+		case "index":
+			if _, err := g.parseIndexDefinition(value, table.TableName); err != nil {
+				errs = append(errs, fmt.Errorf("invalid index: %w", err))
+			}
+		case "unique":
+			for _, def := range strings.Split(value, ";") {
+				def = strings.TrimSpace(def)
+				if def == "" || strings.Contains(strings.ToLower(def), "where:") {
+					continue
+				}
+				if _, err := g.parseUniqueConstraint(def, table.TableName); err != nil {
+					errs = append(errs, fmt.Errorf("invalid unique constraint: %w", err))
+				}
+			}
+		case "check":
+			if _, err := g.parseCheckConstraint(value, table.TableName); err != nil {
+				errs = append(errs, fmt.Errorf("invalid check constraint: %w", err))
+			}
+		case "foreign_key":
+			for _, def := range strings.Split(value, ";") {
+				if strings.TrimSpace(def) == "" {
+					continue
+				}
+				if _, err := g.parseTableForeignKey(def, table.TableName); err != nil {
+					errs = append(errs, fmt.Errorf("invalid foreign key: %w", err))
+				}
+			}
+		case "policy":
+			for _, def := range strings.Split(value, ";") {
+				if strings.TrimSpace(def) == "" {
+					continue
+				}
+				if _, err := parsePolicy(def); err != nil {
+					errs = append(errs, fmt.Errorf("invalid policy: %w", err))
+				}
+			}
+			if _, enabled := table.TableLevel["enable_rls"]; !enabled {
+				errs = append(errs, fmt.Errorf("policies require enable_rls"))
+			}
+		case "source":
+			if _, readOnly := table.TableLevel["readonly"]; !readOnly {
+				errs = append(errs, fmt.Errorf("source requires readonly"))
+			}
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// checkRelationship verifies that the target model and every key column a
+// relationship relies on exist. A join table that no model defines is only a
+// warning since it may be managed by hand-written migrations.
+func checkRelationship(model *checkedModel, rel *parser2.ParsedStormTag, models map[string]*checkedModel, byTable map[string]*checkedModel, report func(severity, format string, args ...interface{})) {
+	target, exists := models[rel.RelationTarget]
+	if !exists {
+		report(SeverityError, "target model '%s' is not defined", rel.RelationTarget)
+		return
+	}
+
+	require := func(owner *checkedModel, column, role string) {
+		if !owner.columns[column] {
+			report(SeverityError, "%s '%s' not found in %s", role, column, owner.def.StructName)
+		}
+	}
+
+	switch rel.RelationType {
+	case "belongs_to":
+		require(model, rel.RelationForeignKey, "foreign key column")
+		require(target, rel.RelationTargetKey, "target key column")
+		if rel.Polymorphic != "" {
+			require(model, rel.Polymorphic+"_type", "polymorphic type column")
+		}
+
+	case "has_one", "has_many":
+		require(target, rel.RelationForeignKey, "foreign key column")
+		require(model, rel.RelationSourceKey, "source key column")
+		if rel.Polymorphic != "" {
+			require(target, rel.Polymorphic+"_type", "polymorphic type column")
+		}
+
+	case "has_many_through":
+		require(model, rel.RelationSourceKey, "source key column")
+		require(target, rel.RelationTargetKey, "target key column")
+		join, exists := byTable[rel.JoinTable]
+		if !exists {
+			report(SeverityWarning, "join table '%s' is not defined by any model", rel.JoinTable)
+			return
+		}
+		require(join, rel.SourceFK, "source foreign key column")
+		require(join, rel.TargetFK, "target foreign key column")
+	}
+}
+
+// isRelationshipTag reports whether a storm tag declares a relationship
+// rather than a column
+func isRelationshipTag(tag string) bool {
+	for _, attr := range strings.Split(tag, ";") {
+		if strings.HasPrefix(strings.TrimSpace(attr), "relation:") {
+			return true
+		}
+	}
+	return false
+}
+
+func goTypeString(field parser2.FieldDefinition) string {
+	goType := field.Type
+	if field.IsPointer {
+		goType = "*" + goType
+	}
+	if field.IsArray {
+		goType = "[]" + goType
+	}
+	return goType
+}
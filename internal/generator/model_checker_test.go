@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+func TestSchemaGenerator_CheckModels(t *testing.T) {
+	gen := NewSchemaGenerator()
+
+	users := parser.TableDefinition{
+		StructName: "User",
+		TableName:  "users",
+		TableTag:   "table:users",
+		TableLevel: map[string]string{"table": "users"},
+		Fields: []parser.FieldDefinition{
+			{Name: "ID", Type: "string", DBName: "id", StormTag: "column:id;type:uuid;primary_key"},
+			{Name: "Email", Type: "string", DBName: "email", StormTag: "column:email;type:text;not_null"},
+			{Name: "Posts", Type: "Post", IsArray: true, StormTag: "relation:has_many:Post;foreign_key:user_id"},
+		},
+	}
+	posts := parser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		TableTag:   "table:posts",
+		TableLevel: map[string]string{"table": "posts", "index": "idx_posts_user_id,user_id"},
+		Fields: []parser.FieldDefinition{
+			{Name: "ID", Type: "string", DBName: "id", StormTag: "column:id;type:uuid;primary_key"},
+			{Name: "UserID", Type: "string", DBName: "user_id", StormTag: "column:user_id;type:uuid;foreign_key:users.id"},
+			{Name: "Author", Type: "User", IsPointer: true, StormTag: "relation:belongs_to:User;foreign_key:user_id"},
+		},
+	}
+
+	findIssue := func(issues []ModelIssue, category, field string) (ModelIssue, bool) {
+		for _, issue := range issues {
+			if issue.Category == category && issue.Field == field {
+				return issue, true
+			}
+		}
+		return ModelIssue{}, false
+	}
+
+	t.Run("valid models have no issues", func(t *testing.T) {
+		issues := gen.CheckModels([]parser.TableDefinition{users, posts})
+		if len(issues) != 0 {
+			t.Errorf("expected no issues, got %v", issues)
+		}
+	})
+
+	t.Run("reports every problem at once", func(t *testing.T) {
+		broken := parser.TableDefinition{
+			StructName: "Order",
+			TableName:  "order",
+			TableTag:   "table:order;index:idx_order_only_name",
+			TableLevel: map[string]string{"table": "order", "index": "idx_order_only_name"},
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "string", DBName: "id", StormTag: "column:id;type:uuid;primary_key"},
+				{Name: "Status", Type: "string", DBName: "status", StormTag: "column:status;nullable"},
+				{Name: "User", Type: "string", DBName: "user", StormTag: "column:user;type:uuid;foreign_key:users.uuid"},
+				{Name: "ShopID", Type: "string", DBName: "shop_id", StormTag: "column:shop_id;type:uuid;foreign_key:shops.id"},
+				{Name: "Reference", Type: "uuid.UUID", DBName: "reference", StormTag: "column:reference;not_null"},
+				{Name: "Total", Type: "int64", DBName: "total", StormTag: "column:total;default:free"},
+				{Name: "Items", Type: "Item", IsArray: true, StormTag: "relation:has_many:Item;foreign_key:order_id"},
+				{Name: "Customer", Type: "User", IsPointer: true, StormTag: "relation:belongs_to:User;foreign_key:customer_id"},
+				{Name: "Buyers", Type: "User", IsPointer: true, StormTag: "relation:has_many:User;foreign_key:order_id"},
+			},
+		}
+
+		issues := gen.CheckModels([]parser.TableDefinition{users, broken})
+
+		expected := []struct {
+			severity string
+			category string
+			field    string
+			contains string
+		}{
+			{SeverityWarning, CategoryReservedWord, "", "table name 'order'"},
+			{SeverityError, CategoryTag, "", "invalid index"},
+			{SeverityError, CategoryTag, "Status", "unknown flag attribute: nullable"},
+			{SeverityWarning, CategoryReservedWord, "User", "column name 'user'"},
+			{SeverityError, CategoryForeignKey, "User", "'users.uuid'"},
+			{SeverityError, CategoryForeignKey, "ShopID", "table 'shops'"},
+			{SeverityWarning, CategoryTypeMapping, "Reference", "uuid.UUID"},
+			{SeverityError, CategoryTag, "Total", "default free: is not a number for BIGINT column"},
+			{SeverityError, CategoryRelationship, "Items", "target model 'Item' is not defined"},
+			{SeverityError, CategoryRelationship, "Customer", "foreign key column 'customer_id' not found in Order"},
+			{SeverityError, CategoryRelationship, "Buyers", "must be a slice"},
+		}
+
+		for _, want := range expected {
+			issue, found := findIssue(issues, want.category, want.field)
+			if !found {
+				t.Errorf("expected %s issue for field %q, got %v", want.category, want.field, issues)
+				continue
+			}
+			if issue.Severity != want.severity {
+				t.Errorf("expected %s issue for field %q to be a %s, got %s", want.category, want.field, want.severity, issue.Severity)
+			}
+			if issue.Model != "Order" {
+				t.Errorf("expected issue to belong to Order, got %s", issue.Model)
+			}
+			if !strings.Contains(issue.Message, want.contains) {
+				t.Errorf("expected message containing %q, got %q", want.contains, issue.Message)
+			}
+		}
+	})
+
+	t.Run("checks has_many_through join tables", func(t *testing.T) {
+		tags := parser.TableDefinition{
+			StructName: "Tag",
+			TableName:  "tags",
+			TableLevel: map[string]string{"table": "tags"},
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "string", DBName: "id", StormTag: "column:id;type:uuid;primary_key"},
+			},
+		}
+		withTags := posts
+		withTags.Fields = append([]parser.FieldDefinition{}, posts.Fields...)
+		withTags.Fields = append(withTags.Fields, parser.FieldDefinition{
+			Name: "Tags", Type: "Tag", IsArray: true,
+			StormTag: "relation:has_many_through:Tag;join_table:post_tags;source_fk:post_id;target_fk:tag_id",
+		})
+
+		issues := gen.CheckModels([]parser.TableDefinition{users, withTags, tags})
+		issue, found := findIssue(issues, CategoryRelationship, "Tags")
+		if !found || issue.Severity != SeverityWarning {
+			t.Fatalf("expected a warning for the missing join table, got %v", issues)
+		}
+
+		postTags := parser.TableDefinition{
+			StructName: "PostTag",
+			TableName:  "post_tags",
+			TableLevel: map[string]string{"table": "post_tags"},
+			Fields: []parser.FieldDefinition{
+				{Name: "PostID", Type: "string", DBName: "post_id", StormTag: "column:post_id;type:uuid;primary_key"},
+			},
+		}
+
+		issues = gen.CheckModels([]parser.TableDefinition{users, withTags, tags, postTags})
+		issue, found = findIssue(issues, CategoryRelationship, "Tags")
+		if !found || !strings.Contains(issue.Message, "target foreign key column 'tag_id' not found in PostTag") {
+			t.Errorf("expected the missing target_fk column to be reported, got %v", issues)
+		}
+	})
+
+	t.Run("reports duplicate tables and invalid table tags", func(t *testing.T) {
+		duplicate := users
+		duplicate.StructName = "Account"
+		duplicate.TableTag = "table:users;colour:blue"
+
+		issues := gen.CheckModels([]parser.TableDefinition{users, duplicate})
+
+		var messages []string
+		for _, issue := range issues {
+			if issue.Model == "Account" && issue.Category == CategoryTag {
+				messages = append(messages, issue.Message)
+			}
+		}
+		joined := strings.Join(messages, "\n")
+		if !strings.Contains(joined, "already used by User") {
+			t.Errorf("expected duplicate table error, got %q", joined)
+		}
+		if !strings.Contains(joined, "invalid table tag") {
+			t.Errorf("expected invalid table tag error, got %q", joined)
+		}
+	})
+
+	t.Run("source requires readonly", func(t *testing.T) {
+		report := parser.TableDefinition{
+			StructName: "AuthorStats",
+			TableName:  "author_stats",
+			TableTag:   "readonly;source:author_stats_view",
+			TableLevel: map[string]string{"readonly": "", "source": "author_stats_view"},
+			Fields: []parser.FieldDefinition{
+				{Name: "AuthorID", Type: "string", DBName: "author_id", StormTag: "column:author_id;primary_key"},
+			},
+		}
+
+		if issues := gen.CheckModels([]parser.TableDefinition{users, posts, report}); len(issues) != 0 {
+			t.Errorf("expected no issues, got %v", issues)
+		}
+
+		delete(report.TableLevel, "readonly")
+		issues := gen.CheckModels([]parser.TableDefinition{users, posts, report})
+		if len(issues) != 1 || !strings.Contains(issues[0].Message, "source requires readonly") {
+			t.Errorf("expected source without readonly to be reported, got %v", issues)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/dialect"
+)
+
+// SchemaPolicy is a row level security policy on a table
+type SchemaPolicy struct {
+	Name        string
+	Restrictive bool
+	Command     string   // ALL, SELECT, INSERT, UPDATE or DELETE
+	Roles       []string // empty applies the policy to PUBLIC
+	Using       string
+	WithCheck   string
+}
+
+var (
+	policyName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	policyClause = regexp.MustCompile(`(?is)^(?:AS\s+(PERMISSIVE|RESTRICTIVE)\s+)?(?:FOR\s+(ALL|SELECT|INSERT|UPDATE|DELETE)\s+)?(?:TO\s+(.+?)\s+)?(USING|WITH\s+CHECK)\s*(.*)$`)
+	withCheck    = regexp.MustCompile(`(?i)^WITH\s+CHECK\b`)
+)
+
+// parsePolicy parses a policy tag such as
+// "tenant_isolation,USING tenant_id = current_setting('app.tenant')::uuid".
+// The clause takes the parts of CREATE POLICY after the table name in their
+// usual order: AS PERMISSIVE|RESTRICTIVE, FOR command, TO roles, USING expr
+// and WITH CHECK expr.
+func parsePolicy(def string) (SchemaPolicy, error) {
+	name, clause, ok := strings.Cut(def, ",")
+	name, clause = strings.TrimSpace(name), strings.TrimSpace(clause)
+	if !ok || name == "" || clause == "" {
+		return SchemaPolicy{}, fmt.Errorf("policy must be in the form name,clause: %s", def)
+	}
+	if !policyName.MatchString(name) {
+		return SchemaPolicy{}, fmt.Errorf("invalid policy name %q", name)
+	}
+
+	match := policyClause.FindStringSubmatch(clause)
+	if match == nil {
+		return SchemaPolicy{}, fmt.Errorf("policy %s needs a USING or WITH CHECK expression: %s", name, clause)
+	}
+
+	policy := SchemaPolicy{
+		Name:        name,
+		Restrictive: strings.EqualFold(match[1], "RESTRICTIVE"),
+		Command:     "ALL",
+	}
+	if match[2] != "" {
+		policy.Command = strings.ToUpper(match[2])
+	}
+	if match[3] != "" {
+		for _, role := range strings.Split(match[3], ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				policy.Roles = append(policy.Roles, role)
+			}
+		}
+	}
+
+	body := strings.TrimSpace(match[5])
+	if strings.EqualFold(match[4], "USING") {
+		policy.Using, policy.WithCheck = splitWithCheck(body)
+	} else {
+		policy.WithCheck = body
+	}
+	policy.Using = unwrapParens(policy.Using)
+	policy.WithCheck = unwrapParens(policy.WithCheck)
+
+	for _, expr := range []string{policy.Using, policy.WithCheck} {
+		if err := checkBalanced(expr); err != nil {
+			return SchemaPolicy{}, fmt.Errorf("policy %s: %w", name, err)
+		}
+	}
+	if strings.EqualFold(match[4], "USING") && policy.Using == "" {
+		return SchemaPolicy{}, fmt.Errorf("policy %s has an empty USING expression", name)
+	}
+	switch policy.Command {
+	case "INSERT":
+		if policy.Using != "" {
+			return SchemaPolicy{}, fmt.Errorf("policy %s: INSERT policies only take WITH CHECK", name)
+		}
+	case "SELECT", "DELETE":
+		if policy.WithCheck != "" {
+			return SchemaPolicy{}, fmt.Errorf("policy %s: %s policies only take USING", name, policy.Command)
+		}
+	}
+	if policy.Using == "" && policy.WithCheck == "" {
+		return SchemaPolicy{}, fmt.Errorf("policy %s needs a USING or WITH CHECK expression: %s", name, clause)
+	}
+	return policy, nil
+}
+
+// splitWithCheck splits a USING expression from a WITH CHECK expression
+// following it outside of quotes and parentheses
+func splitWithCheck(s string) (string, string) {
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (i == 0 || s[i-1] == ' ' || s[i-1] == ')'):
+			if loc := withCheck.FindStringIndex(s[i:]); loc != nil {
+				return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+loc[1]:])
+			}
+		}
+	}
+	return s, ""
+}
+
+// unwrapParens removes parentheses enclosing the whole of s
+func unwrapParens(s string) string {
+	s = strings.TrimSpace(s)
+	for strings.HasPrefix(s, "(") && closesAtEnd(s, 0) {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// GeneratePolicyDDL returns the CREATE POLICY statement for policy on tableName
+func (g *SQLGenerator) GeneratePolicyDDL(tableName string, policy SchemaPolicy) string {
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "CREATE POLICY %s ON %s", dialect.QuoteIdentifierIfNeeded(policy.Name), dialect.QuoteIdentifierIfNeeded(tableName))
+	if policy.Restrictive {
+		sql.WriteString(" AS RESTRICTIVE")
+	}
+	if policy.Command != "" && policy.Command != "ALL" {
+		sql.WriteString(" FOR " + policy.Command)
+	}
+	if len(policy.Roles) > 0 {
+		sql.WriteString(" TO " + strings.Join(policy.Roles, ", "))
+	}
+	if policy.Using != "" {
+		sql.WriteString(" USING (" + unwrapParens(policy.Using) + ")")
+	}
+	if policy.WithCheck != "" {
+		sql.WriteString(" WITH CHECK (" + unwrapParens(policy.WithCheck) + ")")
+	}
+	sql.WriteString(";")
+	return sql.String()
+}
+
+// generateRowSecurity returns the statements enabling row level security on
+// table and creating its policies
+func (g *SQLGenerator) generateRowSecurity(table SchemaTable) string {
+	if !table.RowSecurity {
+		return ""
+	}
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "ALTER TABLE %s ENABLE ROW LEVEL SECURITY;\n", dialect.QuoteIdentifierIfNeeded(table.Name))
+	for _, policy := range table.Policies {
+		sql.WriteString(g.GeneratePolicyDDL(table.Name, policy))
+		sql.WriteString("\n")
+	}
+	return sql.String()
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      string
+		expected SchemaPolicy
+		wantErr  string
+	}{
+		{
+			name: "using only",
+			def:  "tenant_isolation,USING tenant_id = current_setting('app.tenant')::uuid",
+			expected: SchemaPolicy{Name: "tenant_isolation", Command: "ALL",
+				Using: "tenant_id = current_setting('app.tenant')::uuid"},
+		},
+		{
+			name: "every clause",
+			def:  "owner_write, AS RESTRICTIVE FOR UPDATE TO app_user, admin USING (owner_id = current_user) WITH CHECK (owner_id = current_user AND NOT locked)",
+			expected: SchemaPolicy{Name: "owner_write", Restrictive: true, Command: "UPDATE", Roles: []string{"app_user", "admin"},
+				Using: "owner_id = current_user", WithCheck: "owner_id = current_user AND NOT locked"},
+		},
+		{
+			name:     "with check inside a string is not split",
+			def:      "notes,FOR SELECT USING body <> 'with check'",
+			expected: SchemaPolicy{Name: "notes", Command: "SELECT", Using: "body <> 'with check'"},
+		},
+		{
+			name:     "insert takes with check",
+			def:      "insert_own,for insert with check (owner_id = current_user)",
+			expected: SchemaPolicy{Name: "insert_own", Command: "INSERT", WithCheck: "owner_id = current_user"},
+		},
+		{name: "missing expression", def: "p,FOR SELECT TO app_user", wantErr: "needs a USING or WITH CHECK expression"},
+		{name: "insert with using", def: "p,FOR INSERT USING true", wantErr: "INSERT policies only take WITH CHECK"},
+		{name: "select with check", def: "p,FOR SELECT USING true WITH CHECK true", wantErr: "SELECT policies only take USING"},
+		{name: "unbalanced", def: "p,USING (a = b", wantErr: "unbalanced parentheses"},
+		{name: "invalid name", def: "my policy,USING true", wantErr: "invalid policy name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parsePolicy(tt.def)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(policy, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, policy)
+			}
+		})
+	}
+}
+
+func TestGenerateSchema_RowSecurity(t *testing.T) {
+	documents := parser.TableDefinition{
+		StructName: "Document",
+		TableName:  "documents",
+		Fields: []parser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "TenantID", DBName: "tenant_id", Type: "string", DBDef: map[string]string{"type": "uuid", "not_null": ""}},
+		},
+		TableLevel: map[string]string{
+			"enable_rls": "",
+			"policy":     "tenant_isolation,USING tenant_id = current_setting('app.tenant')::uuid;owner_read,AS RESTRICTIVE FOR SELECT TO app_user USING true",
+		},
+	}
+
+	schema, err := NewSchemaGenerator().GenerateSchema([]parser.TableDefinition{documents})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table := schema.Tables["documents"]; !table.RowSecurity || len(table.Policies) != 2 {
+		t.Fatalf("expected row security with 2 policies, got %+v", table)
+	}
+
+	sql := NewSQLGenerator().GenerateSchema(schema)
+	for _, want := range []string{
+		"-- Row level security\nALTER TABLE documents ENABLE ROW LEVEL SECURITY;\n",
+		"CREATE POLICY tenant_isolation ON documents USING (tenant_id = current_setting('app.tenant')::uuid);\n",
+		"CREATE POLICY owner_read ON documents AS RESTRICTIVE FOR SELECT TO app_user USING (true);\n",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected %q in:\n%s", want, sql)
+		}
+	}
+	if strings.Index(sql, "ENABLE ROW LEVEL SECURITY") < strings.Index(sql, "CREATE TABLE documents") {
+		t.Errorf("row level security should follow the tables:\n%s", sql)
+	}
+
+	delete(documents.TableLevel, "enable_rls")
+	if _, err := NewSchemaGenerator().GenerateSchema([]parser.TableDefinition{documents}); err == nil || !strings.Contains(err.Error(), "without enable_rls") {
+		t.Errorf("expected an error for policies without enable_rls, got %v", err)
+	}
+}
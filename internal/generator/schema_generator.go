@@ -2,9 +2,12 @@ package generator
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/eleven-am/storm/internal/dialect"
 	"github.com/eleven-am/storm/internal/logger"
 	parser2 "github.com/eleven-am/storm/internal/parser"
 )
@@ -21,14 +24,24 @@ type SchemaColumn struct {
 	ForeignKey      *ForeignKeyRef
 	CheckConstraint *string
 	EnumValues      []string
+	EnumCheck       bool // EnumValues are enforced by a CHECK constraint instead of an enum type
 }
 
 // ForeignKeyRef represents a foreign key reference
 type ForeignKeyRef struct {
-	ReferencedTable  string
-	ReferencedColumn string
-	OnDelete         string
-	OnUpdate         string
+	ReferencedTable   string
+	ReferencedColumn  string
+	ReferencedColumns []string // set instead of ReferencedColumn for composite keys
+	OnDelete          string
+	OnUpdate          string
+}
+
+// Columns returns the referenced columns in order
+func (fk *ForeignKeyRef) Columns() []string {
+	if len(fk.ReferencedColumns) > 0 {
+		return fk.ReferencedColumns
+	}
+	return []string{fk.ReferencedColumn}
 }
 
 // SchemaTable represents a table in the target database schema
@@ -37,16 +50,21 @@ type SchemaTable struct {
 	Columns     []SchemaColumn
 	Indexes     []SchemaIndex
 	Constraints []SchemaConstraint
+	RowSecurity bool // ENABLE ROW LEVEL SECURITY
+	Policies    []SchemaPolicy
 }
 
 // SchemaIndex represents a database index
 type SchemaIndex struct {
-	Name      string
-	Columns   []string
-	IsUnique  bool
-	IsPrimary bool
-	Type      string
-	Where     string
+	Name       string
+	Columns    []string
+	IsUnique   bool
+	IsPrimary  bool
+	Type       string
+	Where      string
+	Include    []string // non-key columns stored in the index (INCLUDE)
+	FillFactor int      // 0 leaves the access method's default
+	Tablespace string
 }
 
 // SchemaConstraint represents a table constraint
@@ -55,6 +73,7 @@ type SchemaConstraint struct {
 	Type       string
 	Definition string
 	Columns    []string
+	ForeignKey *ForeignKeyRef // set for foreign keys declared on the table
 }
 
 // DatabaseSchema represents the complete target database schema
@@ -81,13 +100,18 @@ func (g *SchemaGenerator) GenerateSchema(tables []parser2.TableDefinition) (*Dat
 	}
 
 	for _, tableDef := range tables {
+		// Read models map views and queries the application manages itself
+		if _, readOnly := tableDef.TableLevel["readonly"]; readOnly {
+			continue
+		}
+
 		schemaTable, err := g.generateTable(tableDef)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate schema for table %s: %w", tableDef.TableName, err)
 		}
 
 		for _, col := range schemaTable.Columns {
-			if len(col.EnumValues) > 0 {
+			if len(col.EnumValues) > 0 && !col.EnumCheck {
 				schema.EnumTypes[col.Type] = col.EnumValues
 			}
 		}
@@ -111,7 +135,10 @@ func (g *SchemaGenerator) generateTable(tableDef parser2.TableDefinition) (Schem
 	}
 
 	for _, field := range tableDef.Fields {
-		if field.IsRelationship {
+		if field.IsRelationship || field.DBName == "-" {
+			continue
+		}
+		if _, isComputed := field.DBDef["computed"]; isComputed {
 			continue
 		}
 		column, err := g.generateColumn(field, tableDef.TableName)
@@ -119,6 +146,15 @@ func (g *SchemaGenerator) generateTable(tableDef parser2.TableDefinition) (Schem
 			return table, fmt.Errorf("failed to generate column %s: %w", field.Name, err)
 		}
 		table.Columns = append(table.Columns, column)
+		if mode, ok := field.DBDef["ci_index"]; ok {
+			if mode == "" {
+				mode = "lower"
+			}
+			table.Indexes = append(table.Indexes, ciIndex(tableDef.TableName, column.Name, mode))
+		}
+		if column.EnumCheck {
+			table.Constraints = append(table.Constraints, enumCheckConstraint(tableDef.TableName, column))
+		}
 	}
 
 	err := g.processTableLevel(tableDef.TableLevel, &table)
@@ -126,17 +162,67 @@ func (g *SchemaGenerator) generateTable(tableDef parser2.TableDefinition) (Schem
 		return table, fmt.Errorf("failed to process table-level definitions: %w", err)
 	}
 
+	if err := g.addPolymorphicIndexes(tableDef, &table); err != nil {
+		return table, err
+	}
+
 	g.addImplicitConstraints(&table)
 
 	return table, nil
 }
 
+// addPolymorphicIndexes indexes the (type, id) column pair of every polymorphic
+// belongs_to relationship declared on the table
+func (g *SchemaGenerator) addPolymorphicIndexes(tableDef parser2.TableDefinition, table *SchemaTable) error {
+	stormParser := parser2.NewStormTagParser()
+
+	for _, field := range tableDef.Fields {
+		if !field.IsRelationship || field.StormTag == "" {
+			continue
+		}
+
+		parsed, err := stormParser.ParseStormTag(field.StormTag, true)
+		if err != nil {
+			return fmt.Errorf("failed to parse relationship %s: %w", field.Name, err)
+		}
+		if parsed.Polymorphic == "" || parsed.RelationType != "belongs_to" {
+			continue
+		}
+
+		columns := []string{parsed.Polymorphic + "_type", parsed.RelationForeignKey}
+		if hasIndexOnColumns(table.Indexes, columns) {
+			continue
+		}
+
+		table.Indexes = append(table.Indexes, SchemaIndex{
+			Name:    fmt.Sprintf("idx_%s_%s", table.Name, parsed.Polymorphic),
+			Columns: columns,
+		})
+	}
+
+	return nil
+}
+
+func hasIndexOnColumns(indexes []SchemaIndex, columns []string) bool {
+	for _, index := range indexes {
+		if strings.Join(index.Columns, ",") == strings.Join(columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *SchemaGenerator) generateColumn(field parser2.FieldDefinition, tableName string) (SchemaColumn, error) {
 	column := SchemaColumn{
 		Name: field.DBName,
 	}
 
-	pgType, err := g.mapGoTypeToPostgreSQL(field.Type, field.DBDef)
+	goType, err := g.columnGoType(field)
+	if err != nil {
+		return column, fmt.Errorf("failed to map type for field %s: %w", field.Name, err)
+	}
+
+	pgType, err := g.mapGoTypeToPostgreSQL(goType, field.DBDef)
 	if err != nil {
 		return column, fmt.Errorf("failed to map type for field %s: %w", field.Name, err)
 	}
@@ -187,7 +273,14 @@ func (g *SchemaGenerator) generateColumn(field parser2.FieldDefinition, tableNam
 		column.CheckConstraint = &checkExpr
 	}
 
-	if enumValues := g.tagParser.GetEnum(field.DBDef); enumValues != nil {
+	if enumValues := g.tagParser.GetEnum(field.DBDef); enumValues != nil && g.tagParser.GetEnumMode(field.DBDef) == "check" {
+		// the values go in a named table constraint, see enumCheckConstraint
+		column.EnumValues = enumValues
+		column.EnumCheck = true
+		if categoryOf(column.Type) != categoryString {
+			column.Type = "TEXT"
+		}
+	} else if enumValues != nil {
 		column.EnumValues = enumValues
 
 		enumTypeName := fmt.Sprintf("%s_%s_enum", tableName, column.Name)
@@ -201,9 +294,36 @@ func (g *SchemaGenerator) generateColumn(field parser2.FieldDefinition, tableNam
 		column.CheckConstraint = &checkStr
 	}
 
+	if _, err := formatDefault(column); err != nil {
+		return column, &defaultValueError{field: field.Name, err: err}
+	}
+
 	return column, nil
 }
 
+// columnGoType returns the Go type a field's column is mapped from: its
+// declared type when that has a mapping, otherwise the type the parser
+// resolved it to. A named type that could not be resolved is an error unless
+// the tag sets the column type.
+func (g *SchemaGenerator) columnGoType(field parser2.FieldDefinition) (string, error) {
+	if _, ok := postgresTypeForGo(field.Type); ok {
+		return field.Type, nil
+	}
+	if g.tagParser.GetType(field.DBDef) != "" || g.tagParser.GetEnum(field.DBDef) != nil {
+		return field.Type, nil
+	}
+	if _, encrypted := field.DBDef["encrypted"]; encrypted {
+		return field.Type, nil
+	}
+	if field.ResolveError != "" {
+		return "", fmt.Errorf("cannot resolve Go type '%s' (%s); set an explicit type in the storm tag", field.Type, field.ResolveError)
+	}
+	if field.ResolvedType != "" {
+		return field.ResolvedType, nil
+	}
+	return field.Type, nil
+}
+
 func (g *SchemaGenerator) mapGoTypeToPostgreSQL(goType string, dbDef map[string]string) (string, error) {
 	if pgType := g.tagParser.GetType(dbDef); pgType != "" {
 		switch strings.ToLower(pgType) {
@@ -215,60 +335,93 @@ func (g *SchemaGenerator) mapGoTypeToPostgreSQL(goType string, dbDef map[string]
 		return pgType, nil
 	}
 
+	if _, encrypted := dbDef["encrypted"]; encrypted {
+		return "BYTEA", nil
+	}
+
+	if pgType, ok := postgresTypeForGo(goType); ok {
+		return pgType, nil
+	}
+
+	logger.Schema().Warn("Unknown Go type '%s', defaulting to TEXT", goType)
+	return "TEXT", nil
+}
+
+// postgresTypeForGo returns the PostgreSQL type a Go type maps to when no
+// type is set in its tag, reporting false for types without a mapping
+func postgresTypeForGo(goType string) (string, bool) {
 	switch goType {
 	case "string":
-		return "TEXT", nil
+		return "TEXT", true
 	case "int", "int32":
-		return "INTEGER", nil
+		return "INTEGER", true
 	case "int64":
-		return "BIGINT", nil
+		return "BIGINT", true
 	case "int16":
-		return "SMALLINT", nil
+		return "SMALLINT", true
 	case "float32":
-		return "REAL", nil
+		return "REAL", true
 	case "float64":
-		return "DOUBLE PRECISION", nil
+		return "DOUBLE PRECISION", true
 	case "bool":
-		return "BOOLEAN", nil
+		return "BOOLEAN", true
 	case "time.Time":
-		return "TIMESTAMPTZ", nil
+		return "TIMESTAMPTZ", true
+	case "time.Duration":
+		return "INTERVAL", true
+	case "storm.Date", "orm.Date", "civil.Date":
+		return "DATE", true
+	case "storm.TimeOfDay", "orm.TimeOfDay", "civil.Time":
+		return "TIME", true
+	case "storm.DateTime", "orm.DateTime", "civil.DateTime":
+		return "TIMESTAMP", true
 	case "[]byte":
-		return "BYTEA", nil
+		return "BYTEA", true
 	case "pq.StringArray":
-		return "TEXT[]", nil
+		return "TEXT[]", true
 	case "pq.Int32Array":
-		return "INTEGER[]", nil
+		return "INTEGER[]", true
 	case "pq.Int64Array":
-		return "BIGINT[]", nil
+		return "BIGINT[]", true
 	case "pq.Float32Array":
-		return "REAL[]", nil
+		return "REAL[]", true
 	case "pq.Float64Array":
-		return "DOUBLE PRECISION[]", nil
+		return "DOUBLE PRECISION[]", true
 	case "pq.BoolArray":
-		return "BOOLEAN[]", nil
+		return "BOOLEAN[]", true
 	case "[]string":
-		return "TEXT[]", nil
+		return "TEXT[]", true
 	case "[]int", "[]int32":
-		return "INTEGER[]", nil
+		return "INTEGER[]", true
 	case "[]int64":
-		return "BIGINT[]", nil
+		return "BIGINT[]", true
 	case "[]float32":
-		return "REAL[]", nil
+		return "REAL[]", true
 	case "[]float64":
-		return "DOUBLE PRECISION[]", nil
+		return "DOUBLE PRECISION[]", true
 	case "[]bool":
-		return "BOOLEAN[]", nil
+		return "BOOLEAN[]", true
 	case "json.RawMessage", "JSONB":
-		return "JSONB", nil
+		return "JSONB", true
 	case "cuid.CUID", "CUID":
-		return "CHAR(25)", nil
-	default:
-		logger.Schema().Warn("Unknown Go type '%s', defaulting to TEXT", goType)
-		return "TEXT", nil
+		return "CHAR(25)", true
 	}
+	return "", false
 }
 
 func (g *SchemaGenerator) parseForeignKeyRef(fkRef string) (*ForeignKeyRef, error) {
+	if strings.Contains(fkRef, "(") {
+		names, fk, err := parseForeignKeyClause(fkRef)
+		if err != nil {
+			return nil, err
+		}
+		if names != "" || len(fk.ReferencedColumns) != 1 {
+			return nil, fmt.Errorf("composite foreign keys are declared on the table, e.g. _ struct{} `storm:\"foreign_key:%s\"`", fkRef)
+		}
+		fk.ReferencedColumn, fk.ReferencedColumns = fk.ReferencedColumns[0], nil
+		return fk, nil
+	}
+
 	parts := strings.Split(fkRef, ".")
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("foreign key must be in format 'table.column', got: %s", fkRef)
@@ -283,9 +436,10 @@ func (g *SchemaGenerator) parseForeignKeyRef(fkRef string) (*ForeignKeyRef, erro
 }
 
 func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, table *SchemaTable) error {
-	for key, value := range tableLevelDef {
+	for _, key := range sortedKeys(tableLevelDef) {
+		value := tableLevelDef[key]
 		switch key {
-		case "table":
+		case "table", "scope", "default_scope", "shard_key":
 			continue
 		case "index":
 			indexes, err := g.parseIndexDefinition(value, table.Name)
@@ -375,11 +529,39 @@ func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, tab
 				return fmt.Errorf("failed to parse check constraint: %w", err)
 			}
 			table.Constraints = append(table.Constraints, constraint)
+		case "foreign_key":
+			for _, def := range strings.Split(value, ";") {
+				if strings.TrimSpace(def) == "" {
+					continue
+				}
+				constraint, err := g.parseTableForeignKey(def, table.Name)
+				if err != nil {
+					return fmt.Errorf("failed to parse foreign key: %w", err)
+				}
+				table.Constraints = append(table.Constraints, constraint)
+			}
+		case "enable_rls":
+			table.RowSecurity = true
+		case "policy":
+			for _, def := range strings.Split(value, ";") {
+				if strings.TrimSpace(def) == "" {
+					continue
+				}
+				policy, err := parsePolicy(def)
+				if err != nil {
+					return fmt.Errorf("failed to parse policy: %w", err)
+				}
+				table.Policies = append(table.Policies, policy)
+			}
 		default:
 			logger.Schema().Warn("Unknown table-level attribute '%s'", key)
 		}
 	}
 
+	if len(table.Policies) > 0 && !table.RowSecurity {
+		return fmt.Errorf("table %s declares policies without enable_rls", table.Name)
+	}
+
 	return nil
 }
 
@@ -400,11 +582,7 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 			def = def[:whereIdx]
 		}
 
-		var indexType string
-		if usingIdx := strings.Index(def, " using:"); usingIdx != -1 {
-			indexType = def[usingIdx+7:]
-			def = def[:usingIdx]
-		}
+		def, options := splitIndexOptions(def)
 
 		parts := strings.Split(def, ",")
 		if len(parts) < 2 {
@@ -420,8 +598,8 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 		if whereClause != "" {
 			index.Where = whereClause
 		}
-		if indexType != "" {
-			index.Type = indexType
+		if err := applyIndexOptions(&index, options); err != nil {
+			return nil, err
 		}
 
 		for i := 1; i < len(parts); i++ {
@@ -456,6 +634,66 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 	return indexes, nil
 }
 
+// indexOptions are the options an index definition may end with, before any
+// where: clause, as in "idx_orders_user,user_id include:status,total fillfactor:70"
+var indexOptions = []string{"using", "include", "fillfactor", "tablespace"}
+
+// splitIndexOptions cuts the trailing options off an index definition,
+// returning the name and columns part and the options by name
+func splitIndexOptions(def string) (string, map[string]string) {
+	type marker struct {
+		key   string
+		start int
+	}
+	var markers []marker
+	for _, key := range indexOptions {
+		if i := strings.Index(def, " "+key+":"); i != -1 {
+			markers = append(markers, marker{key, i})
+		}
+	}
+	if len(markers) == 0 {
+		return def, nil
+	}
+	sort.Slice(markers, func(i, j int) bool { return markers[i].start < markers[j].start })
+
+	options := make(map[string]string, len(markers))
+	for i, m := range markers {
+		end := len(def)
+		if i+1 < len(markers) {
+			end = markers[i+1].start
+		}
+		options[m.key] = strings.TrimSpace(def[m.start+len(m.key)+2 : end])
+	}
+	return def[:markers[0].start], options
+}
+
+func applyIndexOptions(index *SchemaIndex, options map[string]string) error {
+	if using := options["using"]; using != "" {
+		index.Type = using
+	}
+	if include := options["include"]; include != "" {
+		for _, col := range strings.Split(include, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				index.Include = append(index.Include, col)
+			}
+		}
+	}
+	if fillFactor, ok := options["fillfactor"]; ok {
+		n, err := strconv.Atoi(fillFactor)
+		if err != nil || n < 10 || n > 100 {
+			return fmt.Errorf("index %s: fillfactor must be a whole number from 10 to 100, got %q", index.Name, fillFactor)
+		}
+		index.FillFactor = n
+	}
+	if tablespace, ok := options["tablespace"]; ok {
+		if tablespace == "" {
+			return fmt.Errorf("index %s: tablespace cannot be empty", index.Name)
+		}
+		index.Tablespace = tablespace
+	}
+	return nil
+}
+
 func (g *SchemaGenerator) parseUniqueConstraint(uniqueDef, tableName string) (SchemaConstraint, error) {
 	parts := strings.Split(uniqueDef, ",")
 	if len(parts) < 2 {
@@ -500,6 +738,115 @@ func (g *SchemaGenerator) parseCheckConstraint(checkDef, tableName string) (Sche
 	}, nil
 }
 
+var (
+	foreignKeyClause = regexp.MustCompile(`(?is)^(?:(.+?)\s+REFERENCES\s+)?([\w.]+)\s*\(([^)]*)\)(.*)$`)
+	foreignKeyAction = regexp.MustCompile(`(?i)ON\s+(DELETE|UPDATE)\s+(CASCADE|RESTRICT|NO\s+ACTION|SET\s+NULL|SET\s+DEFAULT)`)
+)
+
+// parseForeignKeyClause splits "[name,]col,... REFERENCES table(col,...) [ON ...]"
+// into the part before REFERENCES and the reference it makes
+func parseForeignKeyClause(def string) (string, *ForeignKeyRef, error) {
+	match := foreignKeyClause.FindStringSubmatch(strings.TrimSpace(def))
+	if match == nil {
+		return "", nil, fmt.Errorf("foreign key must be in format 'table(column, ...)', got: %s", def)
+	}
+
+	fk := &ForeignKeyRef{
+		ReferencedTable: match[2],
+		OnDelete:        "NO ACTION",
+		OnUpdate:        "NO ACTION",
+	}
+	fk.ReferencedColumns = splitColumnList(match[3])
+	if len(fk.ReferencedColumns) == 0 {
+		return "", nil, fmt.Errorf("foreign key must reference at least one column: %s", def)
+	}
+
+	rest := strings.TrimSpace(match[4])
+	for _, action := range foreignKeyAction.FindAllStringSubmatch(rest, -1) {
+		value := strings.ToUpper(strings.Join(strings.Fields(action[2]), " "))
+		if strings.EqualFold(action[1], "DELETE") {
+			fk.OnDelete = value
+		} else {
+			fk.OnUpdate = value
+		}
+	}
+	if strings.TrimSpace(foreignKeyAction.ReplaceAllString(rest, "")) != "" {
+		return "", nil, fmt.Errorf("unexpected %q after foreign key reference: %s", rest, def)
+	}
+	return strings.TrimSpace(match[1]), fk, nil
+}
+
+// parseTableForeignKey parses a foreign key declared on the table, which
+// may span several columns. In the short form orders(tenant_id,order_id) the
+// local columns have the same names as the referenced ones; the long form
+// fk_name,tenant_id,order_id REFERENCES orders(tenant_id,id) names them, and
+// optionally the constraint.
+func (g *SchemaGenerator) parseTableForeignKey(def, tableName string) (SchemaConstraint, error) {
+	names, fk, err := parseForeignKeyClause(def)
+	if err != nil {
+		return SchemaConstraint{}, err
+	}
+
+	columns := fk.ReferencedColumns
+	var name string
+	if names != "" {
+		columns = splitColumnList(names)
+		switch len(columns) - len(fk.ReferencedColumns) {
+		case 0:
+		case 1:
+			name, columns = columns[0], columns[1:]
+		default:
+			return SchemaConstraint{}, fmt.Errorf("foreign key %s must list as many columns as it references (%d)", def, len(fk.ReferencedColumns))
+		}
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s_%s_fkey", tableName, strings.Join(columns, "_"))
+	}
+
+	definition := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)",
+		strings.Join(columns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", "))
+	if fk.OnDelete != "NO ACTION" {
+		definition += fmt.Sprintf(" ON DELETE %s", fk.OnDelete)
+	}
+	if fk.OnUpdate != "NO ACTION" {
+		definition += fmt.Sprintf(" ON UPDATE %s", fk.OnUpdate)
+	}
+
+	return SchemaConstraint{
+		Name:       name,
+		Type:       "FOREIGN KEY",
+		Columns:    columns,
+		Definition: definition,
+		ForeignKey: fk,
+	}, nil
+}
+
+func splitColumnList(list string) []string {
+	var columns []string
+	for _, col := range strings.Split(list, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// enumCheckConstraint returns the named CHECK constraint holding the values
+// of an enum_mode:check column. The name stays the same as the values change,
+// so a migration rewrites the constraint rather than adding another.
+func enumCheckConstraint(tableName string, column SchemaColumn) SchemaConstraint {
+	values := make([]string, len(column.EnumValues))
+	for i, v := range column.EnumValues {
+		values[i] = quoteLiteral(v)
+	}
+	return SchemaConstraint{
+		Name:       fmt.Sprintf("chk_%s_%s", tableName, column.Name),
+		Type:       "CHECK",
+		Columns:    []string{column.Name},
+		Definition: fmt.Sprintf("%s IN (%s)", dialect.QuoteIdentifierIfNeeded(column.Name), strings.Join(values, ", ")),
+	}
+}
+
 func (g *SchemaGenerator) addImplicitConstraints(table *SchemaTable) {
 	var primaryKeyColumns []string
 
@@ -566,73 +913,103 @@ func (g *SchemaGenerator) addImplicitConstraints(table *SchemaTable) {
 	}
 }
 
+// GetTableNames returns the table names ordered so that referenced tables come
+// before the tables that reference them. Independent tables are ordered by name,
+// so the result is the same on every run. Tables in a foreign key cycle are
+// ordered as if the keys closing the cycle were absent; see ForeignKeyCycles.
 func (s *DatabaseSchema) GetTableNames() []string {
-	var names []string
-	for name := range s.Tables {
-		names = append(names, name)
-	}
-
-	sorted := s.sortTablesByDependencies(names)
+	sorted := s.sortTablesByDependencies(sortedKeys(s.Tables))
 	return sorted
 }
 
+// ForeignKeyCycles returns the cycles of foreign keys between tables. Each
+// cycle lists its tables in the order GetTableNames creates them, so the
+// foreign keys from the first table to the last cannot be created with the
+// first table and must be added once both tables exist.
+func (s *DatabaseSchema) ForeignKeyCycles() [][]string {
+	_, cycles := s.orderTables(sortedKeys(s.Tables))
+	return cycles
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (s *DatabaseSchema) sortTablesByDependencies(tables []string) []string {
-	dependencies := make(map[string][]string)
-	dependents := make(map[string][]string)
+	order, _ := s.orderTables(tables)
+	return order
+}
 
+// orderTables sorts tables so that every table comes after the tables it
+// references, returning the foreign key cycles it had to break to do so
+func (s *DatabaseSchema) orderTables(tables []string) ([]string, [][]string) {
+	dependents := make(map[string][]string)
 	for _, table := range tables {
-		dependencies[table] = []string{}
 		dependents[table] = []string{}
 	}
 
 	for _, tableName := range tables {
 		table := s.Tables[tableName]
-		for _, col := range table.Columns {
-			if col.ForeignKey != nil {
-				refTable := col.ForeignKey.ReferencedTable
+		for _, fk := range table.foreignKeys() {
+			refTable := fk.ref.ReferencedTable
+			if _, known := dependents[refTable]; known && refTable != tableName {
 				dependents[tableName] = append(dependents[tableName], refTable)
-				dependencies[refTable] = append(dependencies[refTable], tableName)
 			}
 		}
 	}
 
 	visited := make(map[string]bool)
 	visiting := make(map[string]bool)
-	var result []string
-
-	var visit func(string) bool
-	visit = func(table string) bool {
-		if visiting[table] {
-			return false
-		}
-		if visited[table] {
-			return true
-		}
+	var result, stack []string
+	var cycles [][]string
 
+	var visit func(string)
+	visit = func(table string) {
 		visiting[table] = true
+		stack = append(stack, table)
 
 		for _, dep := range dependents[table] {
-			if !visit(dep) {
-				return false
+			switch {
+			case visiting[dep]:
+				cycles = append(cycles, cycleFrom(stack, dep))
+			case !visited[dep]:
+				visit(dep)
 			}
 		}
 
+		stack = stack[:len(stack)-1]
 		visiting[table] = false
 		visited[table] = true
 		result = append(result, table)
-		return true
 	}
 
 	for _, table := range tables {
 		if !visited[table] {
-			if !visit(table) {
-				sort.Strings(tables)
-				return tables
-			}
+			visit(table)
 		}
 	}
 
-	return result
+	return result, cycles
+}
+
+// cycleFrom returns the tables of the cycle that closes when the table on top
+// of stack references start, in the order they are created: the top of the
+// stack first and start last
+func cycleFrom(stack []string, start string) []string {
+	var cycle []string
+	for i := len(stack) - 1; i >= 0; i-- {
+		cycle = append(cycle, stack[i])
+		if stack[i] == start {
+			break
+		}
+	}
+	return cycle
 }
 
 func (s *DatabaseSchema) HasTable(tableName string) bool {
@@ -648,33 +1025,50 @@ func (s *DatabaseSchema) GetTable(tableName string) (SchemaTable, bool) {
 func (g *SchemaGenerator) validateForeignKeys(schema *DatabaseSchema) error {
 	var errors []string
 
-	for tableName, table := range schema.Tables {
-		for _, column := range table.Columns {
-			if column.ForeignKey != nil {
-				referencedTable := column.ForeignKey.ReferencedTable
+	for _, tableName := range sortedKeys(schema.Tables) {
+		table := schema.Tables[tableName]
+		for _, fk := range table.foreignKeys() {
+			label := fmt.Sprintf("table '%s', column '%s'", tableName, strings.Join(fk.columns, ", "))
+			if len(fk.columns) > 1 {
+				label = fmt.Sprintf("table '%s', columns (%s)", tableName, strings.Join(fk.columns, ", "))
+			}
+			referencedTable := fk.ref.ReferencedTable
+			referencedColumns := fk.ref.Columns()
 
-				if !schema.HasTable(referencedTable) {
-					errors = append(errors, fmt.Sprintf(
-						"table '%s', column '%s': foreign key references non-existent table '%s'",
-						tableName, column.Name, referencedTable))
-					continue
-				}
+			if !schema.HasTable(referencedTable) {
+				errors = append(errors, fmt.Sprintf(
+					"%s: foreign key references non-existent table '%s'", label, referencedTable))
+				continue
+			}
 
-				refTable := schema.Tables[referencedTable]
-				columnExists := false
-				for _, refCol := range refTable.Columns {
-					if refCol.Name == column.ForeignKey.ReferencedColumn {
-						columnExists = true
-						break
-					}
+			refTable := schema.Tables[referencedTable]
+			missing := false
+			for _, refColumn := range referencedColumns {
+				if !refTable.hasColumn(refColumn) {
+					errors = append(errors, fmt.Sprintf(
+						"%s: foreign key references non-existent column '%s.%s'", label, referencedTable, refColumn))
+					missing = true
 				}
-
-				if !columnExists {
+			}
+			for _, column := range fk.columns {
+				if !table.hasColumn(column) {
 					errors = append(errors, fmt.Sprintf(
-						"table '%s', column '%s': foreign key references non-existent column '%s.%s'",
-						tableName, column.Name, referencedTable, column.ForeignKey.ReferencedColumn))
+						"%s: foreign key column '%s' does not exist", label, column))
+					missing = true
 				}
 			}
+			if missing {
+				continue
+			}
+
+			if len(fk.columns) != len(referencedColumns) {
+				errors = append(errors, fmt.Sprintf(
+					"%s: foreign key has %d columns but references %d", label, len(fk.columns), len(referencedColumns)))
+			} else if !refTable.hasKey(referencedColumns) {
+				errors = append(errors, fmt.Sprintf(
+					"%s: foreign key references %s(%s), which is not a primary key or unique constraint",
+					label, referencedTable, strings.Join(referencedColumns, ", ")))
+			}
 		}
 	}
 
@@ -684,3 +1078,82 @@ func (g *SchemaGenerator) validateForeignKeys(schema *DatabaseSchema) error {
 
 	return nil
 }
+
+// tableForeignKey is a foreign key of a table, whether declared on a column
+// or on the table
+type tableForeignKey struct {
+	columns []string
+	ref     *ForeignKeyRef
+}
+
+func (t SchemaTable) foreignKeys() []tableForeignKey {
+	var fks []tableForeignKey
+	for _, col := range t.Columns {
+		if col.ForeignKey != nil {
+			fks = append(fks, tableForeignKey{columns: []string{col.Name}, ref: col.ForeignKey})
+		}
+	}
+	for _, constraint := range t.Constraints {
+		if constraint.ForeignKey != nil {
+			fks = append(fks, tableForeignKey{columns: constraint.Columns, ref: constraint.ForeignKey})
+		}
+	}
+	return fks
+}
+
+func (t SchemaTable) hasColumn(name string) bool {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey reports whether columns, in any order, are the table's primary key
+// or are covered by a unique constraint or a unique index without a WHERE
+// clause, which PostgreSQL requires of the columns a foreign key references
+func (t SchemaTable) hasKey(columns []string) bool {
+	sameSet := func(other []string) bool {
+		if len(other) != len(columns) {
+			return false
+		}
+		for _, want := range columns {
+			found := false
+			for _, col := range other {
+				if col == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	var primaryKey []string
+	for _, col := range t.Columns {
+		if col.IsPrimaryKey {
+			primaryKey = append(primaryKey, col.Name)
+		}
+		if col.IsUnique && len(columns) == 1 && col.Name == columns[0] {
+			return true
+		}
+	}
+	if sameSet(primaryKey) {
+		return true
+	}
+	for _, constraint := range t.Constraints {
+		if (constraint.Type == "UNIQUE" || constraint.Type == "PRIMARY KEY") && sameSet(constraint.Columns) {
+			return true
+		}
+	}
+	for _, idx := range t.Indexes {
+		if (idx.IsUnique || idx.IsPrimary) && idx.Where == "" && sameSet(idx.Columns) {
+			return true
+		}
+	}
+	return false
+}
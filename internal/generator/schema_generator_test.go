@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -71,6 +72,26 @@ func TestSchemaGenerator_GenerateSchema(t *testing.T) {
 		}
 	})
 
+	t.Run("skips read models", func(t *testing.T) {
+		tables := []parser.TableDefinition{
+			{
+				TableName: "author_stats",
+				Fields: []parser.FieldDefinition{
+					{Name: "AuthorID", Type: "int", DBName: "author_id", DBDef: map[string]string{"primary_key": "true"}},
+				},
+				TableLevel: map[string]string{"readonly": "", "source": "author_stats_view"},
+			},
+		}
+
+		schema, err := gen.GenerateSchema(tables)
+		if err != nil {
+			t.Fatalf("GenerateSchema failed: %v", err)
+		}
+		if len(schema.Tables) != 0 {
+			t.Errorf("expected no tables, got %v", schema.Tables)
+		}
+	})
+
 	t.Run("generates schema with enum types", func(t *testing.T) {
 		tables := []parser.TableDefinition{
 			{
@@ -110,6 +131,97 @@ func TestSchemaGenerator_GenerateSchema(t *testing.T) {
 		}
 	})
 
+	t.Run("generates check constraint for enum_mode check", func(t *testing.T) {
+		tables := []parser.TableDefinition{
+			{
+				TableName: "users",
+				Fields: []parser.FieldDefinition{
+					{
+						Name:   "Status",
+						Type:   "string",
+						DBName: "status",
+						DBDef: map[string]string{
+							"enum":      "active,inactive",
+							"enum_mode": "check",
+						},
+					},
+				},
+				TableLevel: map[string]string{},
+			},
+		}
+
+		schema, err := gen.GenerateSchema(tables)
+		if err != nil {
+			t.Fatalf("GenerateSchema failed: %v", err)
+		}
+
+		if len(schema.EnumTypes) != 0 {
+			t.Errorf("expected no enum types, got %v", schema.EnumTypes)
+		}
+
+		table := schema.Tables["users"]
+		if table.Columns[0].Type != "TEXT" {
+			t.Errorf("expected type TEXT, got %s", table.Columns[0].Type)
+		}
+		if table.Columns[0].CheckConstraint != nil {
+			t.Errorf("expected no inline check, got %s", *table.Columns[0].CheckConstraint)
+		}
+
+		var found bool
+		for _, c := range table.Constraints {
+			if c.Name == "chk_users_status" && c.Type == "CHECK" {
+				found = true
+				if c.Definition != "status IN ('active', 'inactive')" {
+					t.Errorf("unexpected check definition: %s", c.Definition)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected constraint chk_users_status, got %v", table.Constraints)
+		}
+	})
+
+	t.Run("generates composite foreign keys declared on the table", func(t *testing.T) {
+		tables := []parser.TableDefinition{
+			{
+				TableName: "order_items",
+				Fields: []parser.FieldDefinition{
+					{Name: "TenantID", Type: "int", DBName: "tenant_id", DBDef: map[string]string{}},
+					{Name: "OrderID", Type: "int", DBName: "order_id", DBDef: map[string]string{}},
+				},
+				TableLevel: map[string]string{"foreign_key": "orders(tenant_id,order_id) ON DELETE CASCADE"},
+			},
+			{
+				TableName: "orders",
+				Fields: []parser.FieldDefinition{
+					{Name: "TenantID", Type: "int", DBName: "tenant_id", DBDef: map[string]string{"primary_key": ""}},
+					{Name: "OrderID", Type: "int", DBName: "order_id", DBDef: map[string]string{"primary_key": ""}},
+				},
+				TableLevel: map[string]string{},
+			},
+		}
+
+		schema, err := gen.GenerateSchema(tables)
+		if err != nil {
+			t.Fatalf("GenerateSchema failed: %v", err)
+		}
+
+		var fk *SchemaConstraint
+		for _, c := range schema.Tables["order_items"].Constraints {
+			if c.Type == "FOREIGN KEY" {
+				c := c
+				fk = &c
+			}
+		}
+		if fk == nil || fk.Name != "order_items_tenant_id_order_id_fkey" || fk.ForeignKey.OnDelete != "CASCADE" {
+			t.Fatalf("unexpected foreign key constraint: %+v", fk)
+		}
+
+		if names := schema.GetTableNames(); !reflect.DeepEqual(names, []string{"orders", "order_items"}) {
+			t.Errorf("expected orders before order_items, got %v", names)
+		}
+	})
+
 	t.Run("validates foreign keys", func(t *testing.T) {
 		tables := []parser.TableDefinition{
 			{
@@ -299,11 +411,56 @@ func TestSchemaGenerator_generateTable(t *testing.T) {
 			t.Error("should have primary key constraint")
 		}
 	})
+
+	t.Run("indexes polymorphic belongs_to columns", func(t *testing.T) {
+		tableDef := parser.TableDefinition{
+			TableName: "comments",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": "true"}},
+				{Name: "CommentableID", Type: "int", DBName: "commentable_id", DBDef: map[string]string{}},
+				{Name: "CommentableType", Type: "string", DBName: "commentable_type", DBDef: map[string]string{}},
+				{Name: "Post", Type: "*Post", IsRelationship: true, StormTag: "relation:belongs_to:Post;polymorphic:commentable"},
+				{Name: "Photo", Type: "*Photo", IsRelationship: true, StormTag: "relation:belongs_to:Photo;polymorphic:commentable"},
+			},
+			TableLevel: map[string]string{},
+		}
+
+		table, err := gen.generateTable(tableDef)
+		if err != nil {
+			t.Fatalf("generateTable failed: %v", err)
+		}
+
+		if len(table.Indexes) != 1 {
+			t.Fatalf("expected 1 index, got %d", len(table.Indexes))
+		}
+
+		index := table.Indexes[0]
+		if index.Name != "idx_comments_commentable" {
+			t.Errorf("expected index name 'idx_comments_commentable', got '%s'", index.Name)
+		}
+		if strings.Join(index.Columns, ",") != "commentable_type,commentable_id" {
+			t.Errorf("expected columns commentable_type,commentable_id, got %v", index.Columns)
+		}
+	})
 }
 
 func TestSchemaGenerator_generateColumn(t *testing.T) {
 	gen := NewSchemaGenerator()
 
+	t.Run("rejects a default that does not fit the column", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:   "Active",
+			Type:   "bool",
+			DBName: "active",
+			DBDef:  map[string]string{"default": "now()"},
+		}
+
+		_, err := gen.generateColumn(field, "users")
+		if err == nil || !strings.Contains(err.Error(), "invalid default for field Active: default now(): returns a date/time value for BOOLEAN column") {
+			t.Errorf("expected an invalid default error, got %v", err)
+		}
+	})
+
 	t.Run("generates basic column", func(t *testing.T) {
 		field := parser.FieldDefinition{
 			Name:      "Email",
@@ -496,6 +653,44 @@ func TestSchemaGenerator_generateColumn(t *testing.T) {
 			t.Errorf("expected type 'TEXT[]', got '%s'", column.Type)
 		}
 	})
+
+	t.Run("maps resolved named types", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:         "Priority",
+			Type:         "common.Priority",
+			ResolvedType: "int16",
+			DBName:       "priority",
+			DBDef:        map[string]string{},
+		}
+
+		column, err := gen.generateColumn(field, "tasks")
+		if err != nil {
+			t.Fatalf("generateColumn failed: %v", err)
+		}
+		if column.Type != "SMALLINT" {
+			t.Errorf("expected type 'SMALLINT', got '%s'", column.Type)
+		}
+	})
+
+	t.Run("rejects unresolved named types", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:         "OwnerID",
+			Type:         "ids.OwnerID",
+			ResolveError: "could not import example.com/ids",
+			DBName:       "owner_id",
+			DBDef:        map[string]string{},
+		}
+
+		_, err := gen.generateColumn(field, "tasks")
+		if err == nil || !strings.Contains(err.Error(), "cannot resolve Go type 'ids.OwnerID' (could not import example.com/ids)") {
+			t.Fatalf("expected a resolution error, got %v", err)
+		}
+
+		field.DBDef["type"] = "uuid"
+		if _, err := gen.generateColumn(field, "tasks"); err != nil {
+			t.Errorf("expected an explicit type to skip resolution, got %v", err)
+		}
+	})
 }
 
 func TestSchemaGenerator_mapGoTypeToPostgreSQL(t *testing.T) {
@@ -516,11 +711,18 @@ func TestSchemaGenerator_mapGoTypeToPostgreSQL(t *testing.T) {
 		{"float64", "float64", map[string]string{}, "DOUBLE PRECISION"},
 		{"bool", "bool", map[string]string{}, "BOOLEAN"},
 		{"time.Time", "time.Time", map[string]string{}, "TIMESTAMPTZ"},
+		{"time.Duration", "time.Duration", map[string]string{}, "INTERVAL"},
+		{"time.Duration as nanoseconds", "time.Duration", map[string]string{"type": "BIGINT"}, "BIGINT"},
+		{"storm.Date", "storm.Date", map[string]string{}, "DATE"},
+		{"storm.TimeOfDay", "storm.TimeOfDay", map[string]string{}, "TIME"},
+		{"civil.DateTime", "civil.DateTime", map[string]string{}, "TIMESTAMP"},
 		{"[]byte", "[]byte", map[string]string{}, "BYTEA"},
 		{"custom type with explicit db type", "CustomType", map[string]string{"type": "VARCHAR(255)"}, "VARCHAR(255)"},
 		{"CUID type", "string", map[string]string{"type": "cuid"}, "CHAR(25)"},
 		{"CUID2 type", "string", map[string]string{"type": "cuid2"}, "VARCHAR(32)"},
 		{"unknown type", "UnknownType", map[string]string{}, "TEXT"},
+		{"encrypted", "storm.EncryptedString", map[string]string{"encrypted": "randomized"}, "BYTEA"},
+		{"deterministic", "storm.DeterministicString", map[string]string{"encrypted": "deterministic"}, "BYTEA"},
 	}
 
 	for _, tt := range tests {
@@ -582,6 +784,99 @@ func TestSchemaGenerator_parseForeignKeyRef(t *testing.T) {
 			t.Errorf("expected referenced column 'id', got '%s'", fkRef.ReferencedColumn)
 		}
 	})
+
+	t.Run("parses parenthesized reference", func(t *testing.T) {
+		fkRef, err := gen.parseForeignKeyRef("users(email)")
+		if err != nil {
+			t.Fatalf("parseForeignKeyRef failed: %v", err)
+		}
+		if fkRef.ReferencedTable != "users" || fkRef.ReferencedColumn != "email" || fkRef.ReferencedColumns != nil {
+			t.Errorf("unexpected reference: %+v", fkRef)
+		}
+	})
+
+	t.Run("rejects composite reference on a column", func(t *testing.T) {
+		_, err := gen.parseForeignKeyRef("orders(tenant_id,order_id)")
+		if err == nil || !strings.Contains(err.Error(), "declared on the table") {
+			t.Errorf("expected composite foreign key error, got %v", err)
+		}
+	})
+}
+
+func TestSchemaGenerator_parseTableForeignKey(t *testing.T) {
+	gen := NewSchemaGenerator()
+
+	tests := []struct {
+		name       string
+		def        string
+		wantName   string
+		wantCols   []string
+		wantRefs   []string
+		wantDelete string
+		wantErr    string
+	}{
+		{
+			name:       "same column names",
+			def:        "orders(tenant_id,order_id)",
+			wantName:   "order_items_tenant_id_order_id_fkey",
+			wantCols:   []string{"tenant_id", "order_id"},
+			wantRefs:   []string{"tenant_id", "order_id"},
+			wantDelete: "NO ACTION",
+		},
+		{
+			name:       "named with actions",
+			def:        "fk_item_order,tenant_id,order_id REFERENCES orders(tenant_id, id) ON DELETE cascade",
+			wantName:   "fk_item_order",
+			wantCols:   []string{"tenant_id", "order_id"},
+			wantRefs:   []string{"tenant_id", "id"},
+			wantDelete: "CASCADE",
+		},
+		{
+			name:       "columns without a name",
+			def:        "tenant_id,order_id REFERENCES orders(tenant_id,id)",
+			wantName:   "order_items_tenant_id_order_id_fkey",
+			wantCols:   []string{"tenant_id", "order_id"},
+			wantRefs:   []string{"tenant_id", "id"},
+			wantDelete: "NO ACTION",
+		},
+		{
+			name:    "column count mismatch",
+			def:     "fk,a,b,c REFERENCES orders(tenant_id)",
+			wantErr: "as many columns as it references (1)",
+		},
+		{
+			name:    "unknown trailing text",
+			def:     "orders(id) DEFERRABLE",
+			wantErr: "unexpected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraint, err := gen.parseTableForeignKey(tt.def, "order_items")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTableForeignKey failed: %v", err)
+			}
+			if constraint.Name != tt.wantName || constraint.Type != "FOREIGN KEY" {
+				t.Errorf("unexpected constraint %s of type %s", constraint.Name, constraint.Type)
+			}
+			if !reflect.DeepEqual(constraint.Columns, tt.wantCols) {
+				t.Errorf("expected columns %v, got %v", tt.wantCols, constraint.Columns)
+			}
+			if constraint.ForeignKey.ReferencedTable != "orders" || !reflect.DeepEqual(constraint.ForeignKey.Columns(), tt.wantRefs) {
+				t.Errorf("unexpected reference %+v", constraint.ForeignKey)
+			}
+			if constraint.ForeignKey.OnDelete != tt.wantDelete {
+				t.Errorf("expected on delete %s, got %s", tt.wantDelete, constraint.ForeignKey.OnDelete)
+			}
+		})
+	}
 }
 
 func TestSchemaGenerator_processTableLevel(t *testing.T) {
@@ -825,6 +1120,33 @@ func TestSchemaGenerator_parseIndexDefinition(t *testing.T) {
 		}
 	})
 
+	t.Run("parses include and storage options", func(t *testing.T) {
+		indexes, err := gen.parseIndexDefinition("idx_orders_user,user_id,created_at desc include:status,total fillfactor:70 tablespace:fast_ssd where:status <> 'archived'", "orders")
+		if err != nil {
+			t.Fatalf("parseIndexDefinition failed: %v", err)
+		}
+
+		index := indexes[0]
+		if strings.Join(index.Columns, ",") != "user_id,created_at DESC" {
+			t.Errorf("unexpected columns: %v", index.Columns)
+		}
+		if strings.Join(index.Include, ",") != "status,total" {
+			t.Errorf("unexpected include columns: %v", index.Include)
+		}
+		if index.FillFactor != 70 || index.Tablespace != "fast_ssd" {
+			t.Errorf("unexpected storage options: fillfactor %d, tablespace %q", index.FillFactor, index.Tablespace)
+		}
+		if index.Where != "status <> 'archived'" {
+			t.Errorf("unexpected where clause: %s", index.Where)
+		}
+	})
+
+	t.Run("rejects out of range fillfactor", func(t *testing.T) {
+		if _, err := gen.parseIndexDefinition("idx_orders_user,user_id fillfactor:5", "orders"); err == nil {
+			t.Error("expected an error for fillfactor 5")
+		}
+	})
+
 	t.Run("parses multiple indexes", func(t *testing.T) {
 		indexes, err := gen.parseIndexDefinition("idx_users_email,email;idx_users_name,name", "users")
 		if err != nil {
@@ -913,7 +1235,7 @@ func TestSchemaGenerator_validateForeignKeys(t *testing.T) {
 				"users": {
 					Name: "users",
 					Columns: []SchemaColumn{
-						{Name: "id", Type: "INTEGER"},
+						{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
 					},
 				},
 				"posts": {
@@ -999,6 +1321,63 @@ func TestSchemaGenerator_validateForeignKeys(t *testing.T) {
 			t.Errorf("unexpected error message: %v", err)
 		}
 	})
+
+	t.Run("checks composite and unique references", func(t *testing.T) {
+		orders := SchemaTable{
+			Name: "orders",
+			Columns: []SchemaColumn{
+				{Name: "tenant_id", Type: "INTEGER", IsPrimaryKey: true},
+				{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+				{Name: "number", Type: "TEXT", IsUnique: true},
+				{Name: "status", Type: "TEXT"},
+			},
+		}
+		items := func(columns []string, refs ...string) SchemaTable {
+			return SchemaTable{
+				Name: "order_items",
+				Columns: []SchemaColumn{
+					{Name: "tenant_id", Type: "INTEGER"},
+					{Name: "order_id", Type: "INTEGER"},
+					{Name: "order_number", Type: "TEXT"},
+				},
+				Constraints: []SchemaConstraint{{
+					Name:       "fk_order",
+					Type:       "FOREIGN KEY",
+					Columns:    columns,
+					ForeignKey: &ForeignKeyRef{ReferencedTable: "orders", ReferencedColumns: refs},
+				}},
+			}
+		}
+
+		tests := []struct {
+			name    string
+			table   SchemaTable
+			wantErr string
+		}{
+			{"primary key", items([]string{"order_id", "tenant_id"}, "id", "tenant_id"), ""},
+			{"unique column", items([]string{"order_number"}, "number"), ""},
+			{"not a key", items([]string{"order_number"}, "status"), "not a primary key or unique constraint"},
+			{"part of a key", items([]string{"order_id"}, "id"), "not a primary key or unique constraint"},
+			{"column count", items([]string{"order_id"}, "tenant_id", "id"), "has 1 columns but references 2"},
+			{"missing local column", items([]string{"tenant", "order_id"}, "tenant_id", "id"), "column 'tenant' does not exist"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				schema := &DatabaseSchema{Tables: map[string]SchemaTable{"orders": orders, "order_items": tt.table}}
+				err := gen.validateForeignKeys(schema)
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("validateForeignKeys failed: %v", err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
 }
 
 func TestSchemaGenerator_addImplicitConstraints(t *testing.T) {
@@ -1166,6 +1545,86 @@ func TestDatabaseSchema_sortTablesByDependencies(t *testing.T) {
 	}
 }
 
+func TestDatabaseSchema_sortTablesByDependenciesSelfReference(t *testing.T) {
+	schema := &DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users": {
+				Name:    "users",
+				Columns: []SchemaColumn{{Name: "id", Type: "INTEGER"}},
+			},
+			"categories": {
+				Name: "categories",
+				Columns: []SchemaColumn{
+					{
+						Name: "parent_id",
+						Type: "INTEGER",
+						ForeignKey: &ForeignKeyRef{
+							ReferencedTable:  "categories",
+							ReferencedColumn: "id",
+						},
+					},
+					{
+						Name: "owner_id",
+						Type: "INTEGER",
+						ForeignKey: &ForeignKeyRef{
+							ReferencedTable:  "users",
+							ReferencedColumn: "id",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	sorted := schema.sortTablesByDependencies([]string{"categories", "users"})
+	if len(sorted) != 2 || sorted[0] != "users" || sorted[1] != "categories" {
+		t.Errorf("expected [users categories], got %v", sorted)
+	}
+}
+
+func cyclicSchema() *DatabaseSchema {
+	return &DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users": {
+				Name: "users",
+				Columns: []SchemaColumn{
+					{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+					{Name: "team_id", Type: "INTEGER", ForeignKey: &ForeignKeyRef{ReferencedTable: "teams", ReferencedColumn: "id"}},
+				},
+			},
+			"teams": {
+				Name: "teams",
+				Columns: []SchemaColumn{
+					{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+					{Name: "owner_id", Type: "INTEGER", ForeignKey: &ForeignKeyRef{ReferencedTable: "users", ReferencedColumn: "id"}},
+				},
+			},
+			"projects": {
+				Name: "projects",
+				Columns: []SchemaColumn{
+					{Name: "team_id", Type: "INTEGER", ForeignKey: &ForeignKeyRef{ReferencedTable: "teams", ReferencedColumn: "id"}},
+				},
+			},
+		},
+	}
+}
+
+func TestDatabaseSchema_ForeignKeyCycles(t *testing.T) {
+	schema := cyclicSchema()
+
+	if names := schema.GetTableNames(); !reflect.DeepEqual(names, []string{"users", "teams", "projects"}) {
+		t.Errorf("expected [users teams projects], got %v", names)
+	}
+	if cycles := schema.ForeignKeyCycles(); !reflect.DeepEqual(cycles, [][]string{{"users", "teams"}}) {
+		t.Errorf("expected one cycle [users teams], got %v", cycles)
+	}
+
+	acyclic := &DatabaseSchema{Tables: map[string]SchemaTable{"projects": schema.Tables["projects"], "teams": {Name: "teams"}}}
+	if cycles := acyclic.ForeignKeyCycles(); len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+}
+
 func TestDatabaseSchema_GetTableNames(t *testing.T) {
 	schema := &DatabaseSchema{
 		Tables: map[string]SchemaTable{
@@ -1215,3 +1674,29 @@ func TestDatabaseSchema_GetTableNames(t *testing.T) {
 		t.Error("users should come before posts in dependency order")
 	}
 }
+
+func TestDatabaseSchema_GetTableNamesDeterministic(t *testing.T) {
+	schema := &DatabaseSchema{Tables: map[string]SchemaTable{}}
+	for _, name := range []string{"tags", "accounts", "comments", "posts", "users", "audit_logs"} {
+		schema.Tables[name] = SchemaTable{Name: name}
+	}
+	schema.Tables["posts"] = SchemaTable{
+		Name: "posts",
+		Columns: []SchemaColumn{
+			{Name: "user_id", Type: "INTEGER", ForeignKey: &ForeignKeyRef{ReferencedTable: "users", ReferencedColumn: "id"}},
+		},
+	}
+	schema.Tables["comments"] = SchemaTable{
+		Name: "comments",
+		Columns: []SchemaColumn{
+			{Name: "post_id", Type: "INTEGER", ForeignKey: &ForeignKeyRef{ReferencedTable: "posts", ReferencedColumn: "id"}},
+		},
+	}
+
+	expected := []string{"accounts", "audit_logs", "users", "posts", "comments", "tags"}
+	for i := 0; i < 20; i++ {
+		if got := schema.GetTableNames(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("run %d: expected %v, got %v", i, expected, got)
+		}
+	}
+}
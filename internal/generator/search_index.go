@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/dialect"
+)
+
+// UnaccentFunction wraps unaccent, which PostgreSQL marks STABLE, in an
+// IMMUTABLE function so it can be used in index expressions. The ORM's
+// accent insensitive conditions call it by this name.
+const UnaccentFunction = "storm_unaccent"
+
+// UnaccentFunctionSQL creates the unaccent extension and UnaccentFunction
+const UnaccentFunctionSQL = `CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE OR REPLACE FUNCTION storm_unaccent(text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$;
+`
+
+// UsesUnaccent reports whether any of statements calls UnaccentFunction
+func UsesUnaccent(statements ...string) bool {
+	for _, stmt := range statements {
+		if strings.Contains(stmt, UnaccentFunction+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// ciIndex returns the index serving case insensitive lookups of column, which
+// for mode unaccent also ignore accents. text_pattern_ops lets the index
+// serve prefix LIKE patterns as well as equality.
+func ciIndex(tableName, column, mode string) SchemaIndex {
+	expr := fmt.Sprintf("lower(%s)", dialect.QuoteIdentifierIfNeeded(column))
+	name := fmt.Sprintf("idx_%s_%s_ci", tableName, column)
+	if mode == "unaccent" {
+		expr = fmt.Sprintf("%s(%s)", UnaccentFunction, expr)
+		name = fmt.Sprintf("idx_%s_%s_unaccent", tableName, column)
+	}
+	return SchemaIndex{
+		Name:    name,
+		Columns: []string{"(" + expr + ") text_pattern_ops"},
+	}
+}
+
+// isIndexExpression reports whether an index column is an expression, written
+// into the index as is rather than quoted as a name
+func isIndexExpression(column string) bool {
+	return strings.HasPrefix(column, "(")
+}
+
+func schemaUsesUnaccent(schema *DatabaseSchema) bool {
+	for _, table := range schema.Tables {
+		for _, index := range table.Indexes {
+			if UsesUnaccent(index.Columns...) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+func TestGenerateSchema_CIIndex(t *testing.T) {
+	users := parser.TableDefinition{
+		StructName: "User",
+		TableName:  "users",
+		Fields: []parser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"ci_index": "lower"}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{"ci_index": "unaccent"}},
+		},
+	}
+
+	schema, err := NewSchemaGenerator().GenerateSchema([]parser.TableDefinition{users})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sql := NewSQLGenerator().GenerateSchema(schema)
+	for _, want := range []string{
+		"CREATE INDEX idx_users_email_ci ON users ((lower(email)) text_pattern_ops);",
+		"CREATE INDEX idx_users_name_unaccent ON users ((storm_unaccent(lower(name))) text_pattern_ops);",
+		"CREATE EXTENSION IF NOT EXISTS unaccent;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected %q in:\n%s", want, sql)
+		}
+	}
+	if strings.Index(sql, "CREATE OR REPLACE FUNCTION storm_unaccent") > strings.Index(sql, "idx_users_name_unaccent") {
+		t.Errorf("the unaccent function should be created before the index using it:\n%s", sql)
+	}
+
+	users.Fields[2].DBDef = map[string]string{}
+	schema, err = NewSchemaGenerator().GenerateSchema([]parser.TableDefinition{users})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sql := NewSQLGenerator().GenerateSchema(schema); strings.Contains(sql, "unaccent") {
+		t.Errorf("expected no unaccent function without an unaccent index:\n%s", sql)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/eleven-am/storm/internal/dialect"
 	"github.com/eleven-am/storm/internal/logger"
 )
 
@@ -24,7 +25,7 @@ func NewSQLGenerator() *SQLGenerator {
 func (g *SQLGenerator) GenerateCreateTable(table SchemaTable) string {
 	var sql strings.Builder
 
-	sql.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", table.Name))
+	sql.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", dialect.QuoteQualifiedIfNeeded(table.Name)))
 
 	columns := make([]string, 0, len(table.Columns))
 	for _, col := range table.Columns {
@@ -61,7 +62,11 @@ func (g *SQLGenerator) GenerateCreateTable(table SchemaTable) string {
 			constraints = append(constraints, fmt.Sprintf("CONSTRAINT %s CHECK (%s)",
 				constraint.Name, constraint.Definition))
 		case "FOREIGN KEY":
-			continue
+			// foreign keys on a single column are written with the column
+			if constraint.ForeignKey == nil {
+				continue
+			}
+			constraints = append(constraints, g.generateForeignKeyConstraint(constraint))
 		}
 	}
 
@@ -94,7 +99,7 @@ func (g *SQLGenerator) generateColumnDDL(col SchemaColumn) string {
 	}
 
 	if col.DefaultValue != nil {
-		defaultValue := g.formatDefaultValue(col.Type, *col.DefaultValue)
+		defaultValue := g.formatDefaultValue(col)
 		parts = append(parts, fmt.Sprintf("DEFAULT %s", defaultValue))
 		logger.SQL().Debug("Column %s type %s default %s -> %s", col.Name, col.Type, *col.DefaultValue, defaultValue)
 	}
@@ -105,7 +110,7 @@ func (g *SQLGenerator) generateColumnDDL(col SchemaColumn) string {
 
 	if col.ForeignKey != nil {
 		parts = append(parts, fmt.Sprintf("REFERENCES %s(%s)",
-			col.ForeignKey.ReferencedTable, col.ForeignKey.ReferencedColumn))
+			dialect.QuoteQualifiedIfNeeded(col.ForeignKey.ReferencedTable), g.quoteColumnNameIfNeeded(col.ForeignKey.ReferencedColumn)))
 
 		if col.ForeignKey.OnDelete != "" && col.ForeignKey.OnDelete != "NO ACTION" {
 			parts = append(parts, fmt.Sprintf("ON DELETE %s", col.ForeignKey.OnDelete))
@@ -122,6 +127,65 @@ func (g *SQLGenerator) generateColumnDDL(col SchemaColumn) string {
 	return strings.Join(parts, " ")
 }
 
+func (g *SQLGenerator) generateForeignKeyConstraint(constraint SchemaConstraint) string {
+	fk := constraint.ForeignKey
+	columns := make([]string, len(constraint.Columns))
+	for i, col := range constraint.Columns {
+		columns[i] = g.quoteColumnNameIfNeeded(col)
+	}
+	referenced := make([]string, len(fk.Columns()))
+	for i, col := range fk.Columns() {
+		referenced[i] = g.quoteColumnNameIfNeeded(col)
+	}
+
+	sql := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(%s)", constraint.Name,
+		strings.Join(columns, ", "), dialect.QuoteQualifiedIfNeeded(fk.ReferencedTable), strings.Join(referenced, ", "))
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		sql += fmt.Sprintf(" ON DELETE %s", fk.OnDelete)
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		sql += fmt.Sprintf(" ON UPDATE %s", fk.OnUpdate)
+	}
+	return sql
+}
+
+// deferForeignKeys removes the foreign keys of table that reference refs,
+// returning the table without them and the ALTER TABLE statements adding them
+func (g *SQLGenerator) deferForeignKeys(table SchemaTable, refs map[string]bool) (SchemaTable, []string) {
+	var alters []string
+	addConstraint := func(constraint SchemaConstraint) {
+		alters = append(alters, fmt.Sprintf("ALTER TABLE %s ADD %s;\n",
+			dialect.QuoteQualifiedIfNeeded(table.Name), g.generateForeignKeyConstraint(constraint)))
+	}
+
+	columns := make([]SchemaColumn, len(table.Columns))
+	for i, col := range table.Columns {
+		if col.ForeignKey != nil && refs[col.ForeignKey.ReferencedTable] {
+			addConstraint(SchemaConstraint{
+				Name:       fmt.Sprintf("%s_%s_fkey", table.Name, col.Name),
+				Type:       "FOREIGN KEY",
+				Columns:    []string{col.Name},
+				ForeignKey: col.ForeignKey,
+			})
+			col.ForeignKey = nil
+		}
+		columns[i] = col
+	}
+
+	constraints := make([]SchemaConstraint, 0, len(table.Constraints))
+	for _, constraint := range table.Constraints {
+		if constraint.ForeignKey != nil && refs[constraint.ForeignKey.ReferencedTable] {
+			addConstraint(constraint)
+			continue
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	table.Columns = columns
+	table.Constraints = constraints
+	return table, alters
+}
+
 func (g *SQLGenerator) GenerateIndexDDL(tableName string, idx SchemaIndex) string {
 	var sql strings.Builder
 
@@ -133,7 +197,7 @@ func (g *SQLGenerator) GenerateIndexDDL(tableName string, idx SchemaIndex) strin
 
 	sql.WriteString(idx.Name)
 	sql.WriteString(" ON ")
-	sql.WriteString(tableName)
+	sql.WriteString(dialect.QuoteQualifiedIfNeeded(tableName))
 
 	if idx.Type != "" && idx.Type != "btree" {
 		sql.WriteString(" USING ")
@@ -144,11 +208,34 @@ func (g *SQLGenerator) GenerateIndexDDL(tableName string, idx SchemaIndex) strin
 
 	quotedColumns := make([]string, len(idx.Columns))
 	for i, col := range idx.Columns {
+		if isIndexExpression(col) {
+			quotedColumns[i] = col
+			continue
+		}
 		quotedColumns[i] = g.quoteColumnNameIfNeeded(col)
 	}
 	sql.WriteString(strings.Join(quotedColumns, ", "))
 	sql.WriteString(")")
 
+	if len(idx.Include) > 0 {
+		included := make([]string, len(idx.Include))
+		for i, col := range idx.Include {
+			included[i] = g.quoteColumnNameIfNeeded(col)
+		}
+		sql.WriteString(" INCLUDE (")
+		sql.WriteString(strings.Join(included, ", "))
+		sql.WriteString(")")
+	}
+
+	if idx.FillFactor > 0 {
+		sql.WriteString(fmt.Sprintf(" WITH (fillfactor = %d)", idx.FillFactor))
+	}
+
+	if idx.Tablespace != "" {
+		sql.WriteString(" TABLESPACE ")
+		sql.WriteString(dialect.QuoteIdentifierIfNeeded(idx.Tablespace))
+	}
+
 	if idx.Where != "" {
 		sql.WriteString(" WHERE ")
 		sql.WriteString(idx.Where)
@@ -205,10 +292,16 @@ func (g *SQLGenerator) GenerateSchema(schema *DatabaseSchema) string {
 
 	logger.SQL().Debug("Added extensions")
 
+	if schemaUsesUnaccent(schema) {
+		sql.WriteString("-- Accent insensitive search\n")
+		sql.WriteString(UnaccentFunctionSQL)
+		sql.WriteString("\n")
+	}
+
 	if len(schema.EnumTypes) > 0 {
 		sql.WriteString("-- Enum types\n")
-		for typeName, values := range schema.EnumTypes {
-			sql.WriteString(g.generateEnumType(typeName, values))
+		for _, typeName := range sortedKeys(schema.EnumTypes) {
+			sql.WriteString(g.generateEnumType(typeName, schema.EnumTypes[typeName]))
 			sql.WriteString("\n")
 		}
 		sql.WriteString("\n")
@@ -222,8 +315,25 @@ func (g *SQLGenerator) GenerateSchema(schema *DatabaseSchema) string {
 	tableNames := schema.GetTableNames()
 	logger.SQL().Debug("Generating %d tables: %v", len(tableNames), tableNames)
 
+	deferred := make(map[string]map[string]bool)
+	for _, cycle := range schema.ForeignKeyCycles() {
+		first, last := cycle[0], cycle[len(cycle)-1]
+		logger.SQL().Warn("Foreign keys form a cycle between tables %s; adding the foreign keys of %s referencing %s after the tables are created",
+			strings.Join(cycle, ", "), first, last)
+		if deferred[first] == nil {
+			deferred[first] = make(map[string]bool)
+		}
+		deferred[first][last] = true
+	}
+
+	var alterStatements []string
 	for _, tableName := range tableNames {
 		table := schema.Tables[tableName]
+		if refs := deferred[tableName]; len(refs) > 0 {
+			var alters []string
+			table, alters = g.deferForeignKeys(table, refs)
+			alterStatements = append(alterStatements, alters...)
+		}
 		logger.SQL().Debug("Processing table %s with %d columns", tableName, len(table.Columns))
 		sql.WriteString(fmt.Sprintf("-- Table: %s\n", tableName))
 		tableSQL := g.GenerateCreateTable(table)
@@ -232,41 +342,39 @@ func (g *SQLGenerator) GenerateSchema(schema *DatabaseSchema) string {
 		sql.WriteString("\n")
 	}
 
+	if len(alterStatements) > 0 {
+		sql.WriteString("-- Foreign keys completing cycles between tables\n")
+		for _, stmt := range alterStatements {
+			sql.WriteString(stmt)
+		}
+		sql.WriteString("\n")
+	}
+
+	var rowSecurity strings.Builder
+	for _, tableName := range tableNames {
+		rowSecurity.WriteString(g.generateRowSecurity(schema.Tables[tableName]))
+	}
+	if rowSecurity.Len() > 0 {
+		sql.WriteString("-- Row level security\n")
+		sql.WriteString(rowSecurity.String())
+		sql.WriteString("\n")
+	}
+
 	finalSQL := sql.String()
 	logger.SQL().Debug("Final SQL length: %d characters", len(finalSQL))
 	logger.SQL().Debug("First 500 chars: %s", finalSQL[:min(500, len(finalSQL))])
 	return finalSQL
 }
 
-// formatDefaultValue properly formats default values based on column type
-func (g *SQLGenerator) formatDefaultValue(colType, defaultValue string) string {
-
-	lower := strings.ToLower(defaultValue)
-	if strings.Contains(lower, "()") ||
-		lower == "true" || lower == "false" ||
-		strings.HasPrefix(lower, "nextval(") ||
-		strings.HasPrefix(lower, "'{") ||
-		strings.HasPrefix(lower, "'") ||
-		strings.HasPrefix(lower, "\"") {
-		return defaultValue
-	}
-
-	colTypeLower := strings.ToLower(colType)
-	if strings.Contains(colTypeLower, "varchar") ||
-		strings.Contains(colTypeLower, "text") ||
-		strings.Contains(colTypeLower, "char") {
-		return fmt.Sprintf("'%s'", defaultValue)
+// formatDefaultValue returns the SQL for a column's default. Defaults are
+// checked when the schema is generated, so one that does not parse here came
+// from elsewhere and is written as given.
+func (g *SQLGenerator) formatDefaultValue(col SchemaColumn) string {
+	formatted, err := formatDefault(col)
+	if err != nil {
+		return *col.DefaultValue
 	}
-
-	if strings.ContainsAny(defaultValue, "0123456789") &&
-		len(strings.Fields(defaultValue)) == 1 {
-
-		if _, err := fmt.Sscanf(defaultValue, "%f", new(float64)); err == nil {
-			return defaultValue
-		}
-	}
-
-	return defaultValue
+	return formatted
 }
 
 func (g *SQLGenerator) schemaUsesCUIDs(schema *DatabaseSchema) bool {
@@ -286,118 +394,12 @@ func (g *SQLGenerator) schemaUsesCUIDs(schema *DatabaseSchema) bool {
 	return false
 }
 
-// quoteColumnNameIfNeeded quotes column names that are PostgreSQL reserved keywords
+// quoteColumnNameIfNeeded quotes table and column names that PostgreSQL would not accept bare
 func (g *SQLGenerator) quoteColumnNameIfNeeded(name string) string {
+	return dialect.QuoteIdentifierIfNeeded(name)
+}
 
-	reservedKeywords := map[string]bool{
-		"user":       true,
-		"order":      true,
-		"group":      true,
-		"table":      true,
-		"column":     true,
-		"select":     true,
-		"insert":     true,
-		"update":     true,
-		"delete":     true,
-		"from":       true,
-		"where":      true,
-		"join":       true,
-		"left":       true,
-		"right":      true,
-		"inner":      true,
-		"outer":      true,
-		"on":         true,
-		"as":         true,
-		"by":         true,
-		"desc":       true,
-		"asc":        true,
-		"limit":      true,
-		"offset":     true,
-		"union":      true,
-		"all":        true,
-		"distinct":   true,
-		"between":    true,
-		"like":       true,
-		"in":         true,
-		"exists":     true,
-		"case":       true,
-		"when":       true,
-		"then":       true,
-		"else":       true,
-		"end":        true,
-		"null":       true,
-		"not":        true,
-		"and":        true,
-		"or":         true,
-		"primary":    true,
-		"foreign":    true,
-		"key":        true,
-		"references": true,
-		"unique":     true,
-		"index":      true,
-		"default":    true,
-		"check":      true,
-		"constraint": true,
-		"trigger":    true,
-		"procedure":  true,
-		"function":   true,
-		"view":       true,
-		"grant":      true,
-		"revoke":     true,
-		"role":       true,
-		"password":   true,
-		"timestamp":  true,
-		"date":       true,
-		"time":       true,
-		"interval":   true,
-		"array":      true,
-		"json":       true,
-		"jsonb":      true,
-		"uuid":       true,
-		"serial":     true,
-		"sequence":   true,
-		"cascade":    true,
-		"restrict":   true,
-		"action":     true,
-		"session":    true,
-		"current":    true,
-		"true":       true,
-		"false":      true,
-		"boolean":    true,
-		"integer":    true,
-		"decimal":    true,
-		"numeric":    true,
-		"real":       true,
-		"double":     true,
-		"precision":  true,
-		"varchar":    true,
-		"char":       true,
-		"text":       true,
-		"bytea":      true,
-		"bit":        true,
-		"values":     true,
-		"using":      true,
-		"returning":  true,
-		"with":       true,
-		"recursive":  true,
-		"window":     true,
-		"partition":  true,
-		"over":       true,
-		"rows":       true,
-		"range":      true,
-		"groups":     true,
-		"exclude":    true,
-		"others":     true,
-		"ties":       true,
-		"rollup":     true,
-		"cube":       true,
-		"grouping":   true,
-		"sets":       true,
-	}
-
-	if reservedKeywords[strings.ToLower(name)] {
-		return fmt.Sprintf(`"%s"`, name)
-	}
-
-	return name
+// IsReservedKeyword reports whether name is a PostgreSQL keyword that must be quoted when used as an identifier
+func IsReservedKeyword(name string) bool {
+	return dialect.IsReservedKeyword(name)
 }
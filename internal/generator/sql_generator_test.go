@@ -167,6 +167,35 @@ func TestSQLGenerator_GenerateCreateTable(t *testing.T) {
 	}
 }
 
+func TestSQLGenerator_GenerateCreateTableCompositeForeignKey(t *testing.T) {
+	gen := NewSQLGenerator()
+	schemaGen := NewSchemaGenerator()
+
+	constraint, err := schemaGen.parseTableForeignKey("fk_item_order,tenant_id,order_id REFERENCES orders(tenant_id,id) ON DELETE CASCADE", "order_items")
+	if err != nil {
+		t.Fatalf("parseTableForeignKey failed: %v", err)
+	}
+	table := SchemaTable{
+		Name: "order_items",
+		Columns: []SchemaColumn{
+			{Name: "tenant_id", Type: "INTEGER"},
+			{Name: "order_id", Type: "INTEGER"},
+			{Name: "user_id", Type: "INTEGER", ForeignKey: &ForeignKeyRef{ReferencedTable: "users", ReferencedColumn: "id"}},
+		},
+		Constraints: []SchemaConstraint{constraint},
+	}
+	schemaGen.addImplicitConstraints(&table)
+
+	sql := gen.GenerateCreateTable(table)
+	expected := "CONSTRAINT fk_item_order FOREIGN KEY (tenant_id, order_id) REFERENCES orders(tenant_id, id) ON DELETE CASCADE"
+	if !strings.Contains(sql, expected) {
+		t.Errorf("expected SQL to contain %q, got:\n%s", expected, sql)
+	}
+	if strings.Count(sql, "REFERENCES users") != 1 {
+		t.Errorf("expected the column foreign key once, got:\n%s", sql)
+	}
+}
+
 func TestSQLGenerator_GenerateIndexDDL(t *testing.T) {
 	gen := NewSQLGenerator()
 
@@ -214,6 +243,19 @@ func TestSQLGenerator_GenerateIndexDDL(t *testing.T) {
 			},
 			expected: "CREATE INDEX idx_active_users ON users (email) WHERE is_active = true;",
 		},
+		{
+			name:      "covering index with storage options",
+			tableName: "orders",
+			index: SchemaIndex{
+				Name:       "idx_orders_user",
+				Columns:    []string{"user_id"},
+				Include:    []string{"status", "total"},
+				FillFactor: 70,
+				Tablespace: "fast_ssd",
+				Where:      "status <> 'archived'",
+			},
+			expected: "CREATE INDEX idx_orders_user ON orders (user_id) INCLUDE (status, total) WITH (fillfactor = 70) TABLESPACE fast_ssd WHERE status <> 'archived';",
+		},
 	}
 
 	for _, tt := range tests {
@@ -461,3 +503,60 @@ func TestSQLGenerator_GenerateSchema_Extensions(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestSQLGenerator_GenerateSchema_ForeignKeyCycle(t *testing.T) {
+	gen := NewSQLGenerator()
+	schema := cyclicSchema()
+
+	sql := gen.GenerateSchema(schema)
+
+	usersTable := sql[strings.Index(sql, "CREATE TABLE users"):strings.Index(sql, "CREATE TABLE teams")]
+	if strings.Contains(usersTable, "REFERENCES") {
+		t.Errorf("users should be created without its foreign key to teams:\n%s", usersTable)
+	}
+	if !strings.Contains(sql, "owner_id INTEGER NOT NULL REFERENCES users(id)") {
+		t.Errorf("teams should reference users inline:\n%s", sql)
+	}
+
+	alter := "ALTER TABLE users ADD CONSTRAINT users_team_id_fkey FOREIGN KEY (team_id) REFERENCES teams(id);"
+	if !strings.Contains(sql, alter) {
+		t.Fatalf("expected %q in:\n%s", alter, sql)
+	}
+	if strings.Index(sql, alter) < strings.Index(sql, "CREATE TABLE projects") {
+		t.Errorf("the foreign key should be added after all tables are created:\n%s", sql)
+	}
+	if schema.Tables["users"].Columns[1].ForeignKey == nil {
+		t.Error("GenerateSchema should not modify the schema")
+	}
+}
+
+func TestSQLGenerator_GenerateSchema_Deterministic(t *testing.T) {
+	gen := NewSQLGenerator()
+
+	schema := DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users":    {Name: "users", Columns: []SchemaColumn{{Name: "id", Type: "SERIAL", IsPrimaryKey: true}}},
+			"teams":    {Name: "teams", Columns: []SchemaColumn{{Name: "id", Type: "SERIAL", IsPrimaryKey: true}}},
+			"projects": {Name: "projects", Columns: []SchemaColumn{{Name: "id", Type: "SERIAL", IsPrimaryKey: true}}},
+		},
+		EnumTypes: map[string][]string{
+			"user_status_enum":    {"active", "inactive"},
+			"team_role_enum":      {"owner", "member"},
+			"project_status_enum": {"open", "closed"},
+		},
+	}
+
+	first := gen.GenerateSchema(&schema)
+	for i := 0; i < 20; i++ {
+		if sql := gen.GenerateSchema(&schema); sql != first {
+			t.Fatalf("run %d produced different SQL:\n%s\n---\n%s", i, first, sql)
+		}
+	}
+
+	project := strings.Index(first, "CREATE TYPE project_status_enum")
+	team := strings.Index(first, "CREATE TYPE team_role_enum")
+	user := strings.Index(first, "CREATE TYPE user_status_enum")
+	if !(project < team && team < user) {
+		t.Error("enum types should be created in name order")
+	}
+}
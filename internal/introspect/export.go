@@ -299,7 +299,14 @@ func exportSQL(schema *DatabaseSchema) ([]byte, error) {
 		for _, fn := range schema.Functions {
 			args := make([]string, 0)
 			for _, arg := range fn.Arguments {
-				args = append(args, fmt.Sprintf("%s %s", arg.Name, arg.DataType))
+				if arg.Mode == "TABLE" {
+					continue
+				}
+				def := strings.TrimSpace(arg.Name + " " + arg.DataType)
+				if arg.Mode != "" && arg.Mode != "IN" {
+					def = arg.Mode + " " + def
+				}
+				args = append(args, def)
 			}
 			b.WriteString(fmt.Sprintf("CREATE FUNCTION %s(%s) RETURNS %s\n",
 				fn.Name, strings.Join(args, ", "), fn.ReturnType))
@@ -0,0 +1,299 @@
+package introspect
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// FunctionGenerator generates typed Storm methods calling the database
+// functions and procedures of a schema through storm.CallFunction and
+// storm.CallProcedure
+type FunctionGenerator struct {
+	schema      *DatabaseSchema
+	packageName string
+}
+
+func NewFunctionGenerator(schema *DatabaseSchema, packageName string) *FunctionGenerator {
+	return &FunctionGenerator{
+		schema:      schema,
+		packageName: packageName,
+	}
+}
+
+// generatedFunction is a database function with the Go types of its
+// arguments and result resolved
+type generatedFunction struct {
+	fn         *FunctionSchema
+	method     string
+	call       string
+	params     []functionField
+	columns    []functionField
+	resultType string
+	model      string
+	procedure  bool
+}
+
+// functionField is a Go parameter or result struct field of a function
+type functionField struct {
+	name   string
+	goType string
+	dbName string
+}
+
+// stormMethods are the exported methods of the generated Storm and the
+// storm.Storm it embeds, which function wrappers must not shadow
+var stormMethods = []string{
+	"AddMiddleware", "AutoMigrate", "AutoMigrateDestructive", "AutoMigrateDryRun",
+	"CallFunction", "CallProcedure", "DetectUnaccent", "Dialect", "DryRun", "ExecNamed", "ExecRaw",
+	"GetDB", "GetExecutor", "GetLogger", "IsCockroachDB", "OnTransaction",
+	"QueryNamed", "Raw", "Repositories", "SetDialect", "SetRawPolicy", "SetUnaccent", "Storm",
+	"WithTransaction", "WithTransactionOptions",
+}
+
+// GenerateFunctions returns the source of a file with a Storm method per
+// function and procedure, or an empty string when none can be wrapped.
+// Procedures with output arguments, trigger functions and functions with
+// types that cannot be scanned are skipped.
+func (g *FunctionGenerator) GenerateFunctions() (string, error) {
+	reserved := make(map[string]bool)
+	for _, name := range stormMethods {
+		reserved[name] = true
+	}
+	for _, table := range g.schema.Tables {
+		reserved[pluralize(structNameFromTable(table.Name))] = true
+	}
+
+	keys := make([]string, 0, len(g.schema.Functions))
+	for key := range g.schema.Functions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var functions []*generatedFunction
+	for _, key := range keys {
+		fn, err := g.resolveFunction(g.schema.Functions[key])
+		if err != nil {
+			fmt.Printf("Skipping function %s: %v\n", key, err)
+			continue
+		}
+		if reserved[fn.method] {
+			fmt.Printf("Skipping function %s: method %s clashes with a Storm field or method\n", key, fn.method)
+			continue
+		}
+		reserved[fn.method] = true
+		functions = append(functions, fn)
+	}
+	if len(functions) == 0 {
+		return "", nil
+	}
+
+	var body strings.Builder
+	for _, fn := range functions {
+		g.writeFunction(&body, fn)
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by storm introspect; DO NOT EDIT.\n")
+	b.WriteString("//\n")
+	b.WriteString("// This file was automatically generated from the functions of the database.\n")
+	b.WriteString("// Any changes made to this file will be lost when regenerating.\n\n")
+	b.WriteString(fmt.Sprintf("package %s\n\n", g.packageName))
+	b.WriteString("import (\n\t\"context\"\n")
+	if strings.Contains(body.String(), "time.") {
+		b.WriteString("\t\"time\"\n")
+	}
+	if strings.Contains(body.String(), "storm.") {
+		b.WriteString("\n\tstorm \"github.com/eleven-am/storm/pkg/storm-orm\"\n")
+	}
+	b.WriteString(")\n\n")
+	b.WriteString(body.String())
+
+	formatted, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format function wrappers: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// resolveFunction works out the Go signature of fn
+func (g *FunctionGenerator) resolveFunction(fn *FunctionSchema) (*generatedFunction, error) {
+	resolved := &generatedFunction{
+		fn:        fn,
+		method:    toCamelCase(fn.Name),
+		call:      fn.Name,
+		procedure: fn.IsProcedure,
+	}
+	if fn.Schema != "" && fn.Schema != "public" {
+		resolved.call = fn.Schema + "." + fn.Name
+	}
+	if !token.IsIdentifier(resolved.method) {
+		return nil, fmt.Errorf("name cannot be a Go identifier")
+	}
+
+	names := map[string]bool{"ctx": true, "s": true, "row": true, "rows": true, "result": true, "err": true, "context": true, "time": true, "storm": true}
+	for position, arg := range fn.Arguments {
+		goType, err := functionGoType(arg.DataType)
+		if err != nil {
+			return nil, err
+		}
+
+		switch arg.Mode {
+		case "OUT", "TABLE":
+		default:
+			name := paramName(arg.Name, position)
+			for names[name] {
+				name += "Arg"
+			}
+			names[name] = true
+			resolved.params = append(resolved.params, functionField{name: name, goType: goType})
+			if arg.Mode != "INOUT" {
+				continue
+			}
+		}
+		if arg.Name == "" {
+			return nil, fmt.Errorf("output argument %d has no name", position+1)
+		}
+		resolved.columns = append(resolved.columns, functionField{name: toCamelCase(arg.Name), goType: goType, dbName: arg.Name})
+	}
+
+	if fn.IsProcedure {
+		if len(resolved.columns) > 0 {
+			return nil, fmt.Errorf("procedures with output arguments are not wrapped")
+		}
+		return resolved, nil
+	}
+
+	returnType := strings.TrimPrefix(fn.ReturnType, "SETOF ")
+	switch {
+	case len(resolved.columns) == 1:
+		resolved.resultType = resolved.columns[0].goType
+		resolved.columns = nil
+	case len(resolved.columns) > 1:
+		resolved.resultType = resolved.method + "Result"
+	case returnType == "void":
+	case returnType == "record":
+		return nil, fmt.Errorf("returns record without OUT parameters")
+	case returnType == "trigger" || returnType == "event_trigger":
+		return nil, fmt.Errorf("trigger functions are not wrapped")
+	default:
+		if model := g.tableModel(returnType); model != "" {
+			resolved.resultType = model
+			resolved.model = model
+			break
+		}
+		goType, err := functionGoType(returnType)
+		if err != nil {
+			return nil, err
+		}
+		resolved.resultType = goType
+	}
+	return resolved, nil
+}
+
+// tableModel returns the model generated for the table named by a
+// composite return type, or an empty string when it names no table
+func (g *FunctionGenerator) tableModel(typeName string) string {
+	for key, table := range g.schema.Tables {
+		if key != typeName && table.Name != typeName && table.Schema+"."+table.Name != typeName {
+			continue
+		}
+		if table.PrimaryKey == nil || len(table.PrimaryKey.Columns) == 0 {
+			return ""
+		}
+		return structNameFromTable(table.Name)
+	}
+	return ""
+}
+
+// writeFunction writes the result struct and Storm method of fn
+func (g *FunctionGenerator) writeFunction(b *strings.Builder, fn *generatedFunction) {
+	if len(fn.columns) > 0 {
+		b.WriteString(fmt.Sprintf("// %s is a row returned by the %s function\n", fn.resultType, fn.call))
+		b.WriteString(fmt.Sprintf("type %s struct {\n", fn.resultType))
+		for _, column := range fn.columns {
+			b.WriteString(fmt.Sprintf("\t%s %s `db:\"%s\" json:\"%s\"`\n", column.name, column.goType, column.dbName, column.dbName))
+		}
+		b.WriteString("}\n\n")
+	}
+
+	params := []string{"ctx context.Context"}
+	args := []string{"ctx", fmt.Sprintf("%q", fn.call)}
+	for _, param := range fn.params {
+		params = append(params, param.name+" "+param.goType)
+		args = append(args, param.name)
+	}
+	call := fmt.Sprintf("s.Storm.CallFunction(%s)", strings.Join(args, ", "))
+
+	signature := fmt.Sprintf("func (s *Storm) %s(%s)", fn.method, strings.Join(params, ", "))
+	if fn.procedure {
+		b.WriteString(fmt.Sprintf("// %s calls the %s stored procedure\n", fn.method, fn.call))
+		b.WriteString(fmt.Sprintf("%s error {\n\treturn s.Storm.CallProcedure(%s).Exec()\n}\n\n", signature, strings.Join(args, ", ")))
+		return
+	}
+
+	b.WriteString(fmt.Sprintf("// %s calls the %s database function\n", fn.method, fn.call))
+	switch {
+	case fn.resultType == "":
+		b.WriteString(fmt.Sprintf("%s error {\n\treturn %s.Exec()\n}\n\n", signature, call))
+
+	case fn.fn.ReturnsSet:
+		b.WriteString(fmt.Sprintf("%s ([]%s, error) {\n", signature, fn.resultType))
+		b.WriteString(fmt.Sprintf("\trows := []%s{}\n", fn.resultType))
+		b.WriteString(fmt.Sprintf("\tif err := %s.Select(&rows); err != nil {\n\t\treturn nil, err\n\t}\n", call))
+		b.WriteString("\treturn rows, nil\n}\n\n")
+
+	case len(fn.columns) > 0 || fn.model != "":
+		b.WriteString(fmt.Sprintf("%s (*%s, error) {\n", signature, fn.resultType))
+		b.WriteString(fmt.Sprintf("\trow := &%s{}\n", fn.resultType))
+		b.WriteString(fmt.Sprintf("\tif err := %s.Scan(row); err != nil {\n\t\treturn nil, err\n\t}\n", call))
+		b.WriteString("\treturn row, nil\n}\n\n")
+
+	default:
+		b.WriteString(fmt.Sprintf("%s (%s, error) {\n", signature, fn.resultType))
+		b.WriteString(fmt.Sprintf("\tvar result %s\n", fn.resultType))
+		b.WriteString(fmt.Sprintf("\terr := %s.Scan(&result)\n", call))
+		b.WriteString("\treturn result, err\n}\n\n")
+	}
+}
+
+// functionGoType maps the type of a function argument or result to the Go
+// type it is bound and scanned as. Arrays other than text arrays need
+// pq.Array to be bound and intervals do not scan into a time.Duration, so
+// neither is supported.
+func functionGoType(dataType string) (string, error) {
+	udtName := ""
+	if strings.HasSuffix(dataType, "[]") {
+		udtName = strings.TrimSuffix(dataType, "[]")
+	}
+	goType, err := postgresTypeToGoType(dataType, udtName, false)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(goType, "[]") && goType != "[]byte" {
+		return "", fmt.Errorf("array type %s is not supported", dataType)
+	}
+	if goType == "time.Duration" {
+		return "", fmt.Errorf("type %s is not supported", dataType)
+	}
+	return goType, nil
+}
+
+// paramName returns the Go parameter name of a function argument, which
+// may be unnamed
+func paramName(name string, position int) string {
+	if name == "" {
+		return fmt.Sprintf("arg%d", position+1)
+	}
+	name = toCamelCase(strings.TrimLeft(name, "_"))
+	if name == "" {
+		return fmt.Sprintf("arg%d", position+1)
+	}
+	name = strings.ToLower(name[:1]) + name[1:]
+	if token.IsKeyword(name) {
+		name += "Arg"
+	}
+	return name
+}
@@ -0,0 +1,132 @@
+package introspect
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFunctionGenerator_GenerateFunctions(t *testing.T) {
+	schema := &DatabaseSchema{
+		Name: "test_db",
+		Tables: map[string]*TableSchema{
+			"users": {
+				Name:       "users",
+				Schema:     "public",
+				PrimaryKey: &PrimaryKeySchema{Columns: []string{"id"}},
+			},
+		},
+		Functions: map[string]*FunctionSchema{
+			"public.count_posts": {
+				Name:       "count_posts",
+				Schema:     "public",
+				ReturnType: "bigint",
+				Arguments:  []FunctionArgument{{Name: "author_id", DataType: "bigint", Mode: "IN"}},
+			},
+			"reporting.author_stats": {
+				Name:       "author_stats",
+				Schema:     "reporting",
+				ReturnType: "record",
+				Arguments: []FunctionArgument{
+					{Name: "since", DataType: "timestamp with time zone", Mode: "IN"},
+					{Name: "author_id", DataType: "bigint", Mode: "OUT"},
+					{Name: "posts", DataType: "bigint", Mode: "OUT"},
+				},
+			},
+			"public.post_tags": {
+				Name:       "post_tags",
+				Schema:     "public",
+				ReturnType: "SETOF text",
+				ReturnsSet: true,
+				Arguments:  []FunctionArgument{{DataType: "bigint", Mode: "IN"}},
+			},
+			"public.active_users": {
+				Name:       "active_users",
+				Schema:     "public",
+				ReturnType: "SETOF users",
+				ReturnsSet: true,
+			},
+			"public.refresh_stats": {
+				Name:       "refresh_stats",
+				Schema:     "public",
+				ReturnType: "void",
+				Arguments:  []FunctionArgument{{Name: "type", DataType: "text", Mode: "IN"}},
+			},
+			"public.archive_posts": {
+				Name:        "archive_posts",
+				Schema:      "public",
+				IsProcedure: true,
+				Arguments:   []FunctionArgument{{Name: "cutoff", DataType: "date", Mode: "IN"}},
+			},
+			"public.close_period": {
+				Name:        "close_period",
+				Schema:      "public",
+				IsProcedure: true,
+				Arguments:   []FunctionArgument{{Name: "closed", DataType: "integer", Mode: "INOUT"}},
+			},
+			"public.users":    {Name: "users", Schema: "public", ReturnType: "bigint"},
+			"public.ids":      {Name: "ids", Schema: "public", ReturnType: "integer[]"},
+			"public.anything": {Name: "anything", Schema: "public", ReturnType: "record"},
+		},
+	}
+
+	result, err := NewFunctionGenerator(schema, "models").GenerateFunctions()
+	if err != nil {
+		t.Fatalf("Failed to generate functions: %v", err)
+	}
+
+	expected := []string{
+		"package models",
+		"\t\"context\"\n\t\"time\"\n",
+		`func (s *Storm) CountPosts(ctx context.Context, authorId int64) (int64, error) {
+	var result int64
+	err := s.Storm.CallFunction(ctx, "count_posts", authorId).Scan(&result)
+	return result, err
+}`,
+		"type AuthorStatsResult struct {\n\tAuthorId int64 `db:\"author_id\" json:\"author_id\"`\n\tPosts    int64 `db:\"posts\" json:\"posts\"`\n}",
+		`func (s *Storm) AuthorStats(ctx context.Context, since time.Time) (*AuthorStatsResult, error) {
+	row := &AuthorStatsResult{}
+	if err := s.Storm.CallFunction(ctx, "reporting.author_stats", since).Scan(row); err != nil {`,
+		`func (s *Storm) PostTags(ctx context.Context, arg1 int64) ([]string, error) {
+	rows := []string{}
+	if err := s.Storm.CallFunction(ctx, "post_tags", arg1).Select(&rows); err != nil {`,
+		"func (s *Storm) ActiveUsers(ctx context.Context) ([]User, error) {",
+		`func (s *Storm) ArchivePosts(ctx context.Context, cutoff time.Time) error {
+	return s.Storm.CallProcedure(ctx, "archive_posts", cutoff).Exec()
+}`,
+		`func (s *Storm) RefreshStats(ctx context.Context, typeArg string) error {
+	return s.Storm.CallFunction(ctx, "refresh_stats", typeArg).Exec()
+}`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected generated code to contain:\n%s\n\ngot:\n%s", want, result)
+		}
+	}
+
+	for _, skipped := range []string{"ClosePeriod", "Users", "Ids", "Anything"} {
+		if strings.Contains(result, "func (s *Storm) "+skipped+"(") {
+			t.Errorf("Expected %s to be skipped", skipped)
+		}
+	}
+}
+
+func TestFunctionGenerator_NoFunctions(t *testing.T) {
+	schema := &DatabaseSchema{
+		Functions: map[string]*FunctionSchema{
+			"public.close_period": {
+				Name:        "close_period",
+				Schema:      "public",
+				IsProcedure: true,
+				Arguments:   []FunctionArgument{{Name: "closed", DataType: "integer", Mode: "INOUT"}},
+			},
+		},
+	}
+
+	result, err := NewFunctionGenerator(schema, "models").GenerateFunctions()
+	if err != nil {
+		t.Fatalf("Failed to generate functions: %v", err)
+	}
+	if result != "" {
+		t.Errorf("Expected no output, got:\n%s", result)
+	}
+}
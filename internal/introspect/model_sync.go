@@ -0,0 +1,459 @@
+package introspect
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of ModelChange
+const (
+	ModelChangeAddField      = "add_field"
+	ModelChangeUpdateTag     = "update_tag"
+	ModelChangeRemovedColumn = "removed_column"
+	ModelChangeNullability   = "nullability"
+)
+
+// ModelChange is a difference between a model and its table found by
+// ModelSync. Added fields and updated tags are written to the model; removed
+// columns and nullability mismatches of the Go type are only reported.
+type ModelChange struct {
+	File   string
+	Model  string
+	Column string
+	Kind   string
+	Detail string
+}
+
+// ModelSync updates existing model files to match the introspected schema
+// instead of regenerating them: fields are added for new columns and the
+// type, not_null and default options of storm tags are updated, while
+// everything else in the file, comments included, is left untouched.
+type ModelSync struct {
+	schema    *DatabaseSchema
+	generator *StructGenerator
+}
+
+func NewModelSync(schema *DatabaseSchema) *ModelSync {
+	return &ModelSync{
+		schema:    schema,
+		generator: NewStructGenerator(schema, ""),
+	}
+}
+
+// SyncDir syncs the Go files of dir, skipping tests and generated files, and
+// writes those that changed unless dryRun is set
+func (s *ModelSync) SyncDir(dir string, dryRun bool) ([]ModelChange, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var changes []ModelChange
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		synced, fileChanges, err := s.SyncSource(path, src)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, fileChanges...)
+
+		if dryRun || bytes.Equal(synced, src) {
+			continue
+		}
+		if err := os.WriteFile(path, synced, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return changes, nil
+}
+
+// sourceEdit replaces src[start:end] with text
+type sourceEdit struct {
+	start, end int
+	text       string
+}
+
+// SyncSource returns the source of a model file with its models brought in
+// line with their tables, and the changes found
+func (s *ModelSync) SyncSource(filename string, src []byte) ([]byte, []ModelChange, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	if ast.IsGenerated(file) {
+		return src, nil, nil
+	}
+
+	var (
+		edits   []sourceEdit
+		changes []ModelChange
+		added   []string
+	)
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			table := s.tableFor(typeSpec.Name.Name, structType)
+			if table == nil {
+				continue
+			}
+
+			change := func(kind, column, detail string) {
+				changes = append(changes, ModelChange{File: filename, Model: typeSpec.Name.Name, Column: column, Kind: kind, Detail: detail})
+			}
+
+			seen := make(map[string]bool)
+			insertAt := offset(structType.Fields.Closing)
+			for _, field := range structType.Fields.List {
+				column, stormTag := modelFieldColumn(field)
+				if column == "" {
+					continue
+				}
+				seen[column] = true
+				insertAt = lineEnd(src, offset(field.End()))
+
+				col := findColumn(table, column)
+				if col == nil {
+					change(ModelChangeRemovedColumn, column, fmt.Sprintf("column no longer exists in %s; remove the field or restore the column", table.Name))
+					continue
+				}
+
+				if _, pointer := field.Type.(*ast.StarExpr); !isPrimaryKey(col.Name, table) && pointer != col.IsNullable && canBeNull(field.Type) {
+					if col.IsNullable {
+						change(ModelChangeNullability, column, "column is nullable but the field is not a pointer")
+					} else {
+						change(ModelChangeNullability, column, "column is NOT NULL but the field is a pointer")
+					}
+				}
+
+				if stormTag == "" {
+					continue
+				}
+				updated, details := s.syncStormTag(stormTag, col, table)
+				if len(details) == 0 {
+					continue
+				}
+				start, end, ok := stormTagRange(src, offset(field.Tag.Pos()), offset(field.Tag.End()))
+				if !ok {
+					continue
+				}
+				edits = append(edits, sourceEdit{start: start, end: end, text: updated})
+				change(ModelChangeUpdateTag, column, strings.Join(details, ", "))
+			}
+
+			var fields strings.Builder
+			for _, col := range table.Columns {
+				if seen[col.Name] {
+					continue
+				}
+				fieldDef, err := s.generator.generateField(col, table)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to generate field %s of %s: %w", col.Name, typeSpec.Name.Name, err)
+				}
+				fields.WriteString(fieldDef)
+				added = append(added, fieldDef)
+				change(ModelChangeAddField, col.Name, fmt.Sprintf("added field %s", strings.Fields(fieldDef)[0]))
+			}
+			if fields.Len() > 0 {
+				edits = append(edits, sourceEdit{start: insertAt, end: insertAt, text: fields.String()})
+			}
+		}
+	}
+
+	if len(edits) == 0 {
+		return src, changes, nil
+	}
+	edits = append(edits, missingImports(file, fset, added)...)
+
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte(nil), src...)
+	for _, e := range edits {
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to format %s after syncing: %w", filename, err)
+	}
+	return formatted, changes, nil
+}
+
+// tableFor returns the table of a struct, named by the table: option of its
+// `_ struct{}` field or else by the struct name
+func (s *ModelSync) tableFor(name string, structType *ast.StructType) *TableSchema {
+	for _, field := range structType.Fields.List {
+		if len(field.Names) != 1 || field.Names[0].Name != "_" || field.Tag == nil {
+			continue
+		}
+		for _, part := range strings.Split(fieldTag(field).Get("storm"), ";") {
+			if tableName, ok := strings.CutPrefix(strings.TrimSpace(part), "table:"); ok {
+				if i := strings.LastIndex(tableName, "."); i >= 0 {
+					tableName = tableName[i+1:]
+				}
+				return s.schema.Tables[tableName]
+			}
+		}
+	}
+
+	for _, table := range sortedTables(s.schema.Tables) {
+		if structNameFromTable(table.Name) == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// syncStormTag returns the storm tag with its type, not_null and default
+// options matching the column, and a description of what changed. A type the
+// tag leaves to be inferred from the Go type stays out.
+func (s *ModelSync) syncStormTag(tag string, col *ColumnSchema, table *TableSchema) (string, []string) {
+	parts := strings.Split(tag, ";")
+	var details []string
+
+	find := func(name string) int {
+		for i, part := range parts {
+			key, _, _ := strings.Cut(strings.TrimSpace(part), ":")
+			if key == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	typeIdx := find("type")
+	modelType := ""
+	if typeIdx >= 0 {
+		_, modelType, _ = strings.Cut(parts[typeIdx], ":")
+		if dbType := s.generator.getDBType(col); !isSerialType(modelType) && normalizeSQLType(modelType) != normalizeSQLType(dbType) {
+			parts[typeIdx] = "type:" + dbType
+			details = append(details, fmt.Sprintf("type %s -> %s", strings.TrimSpace(modelType), dbType))
+		}
+	}
+
+	if !isPrimaryKey(col.Name, table) {
+		idx := find("not_null")
+		switch {
+		case !col.IsNullable && idx < 0:
+			parts = insertPart(parts, typeIdx+1, "not_null")
+			details = append(details, "added not_null")
+		case col.IsNullable && idx >= 0:
+			parts = append(parts[:idx], parts[idx+1:]...)
+			details = append(details, "removed not_null")
+		}
+	}
+
+	dbDefault := ""
+	if col.DefaultValue != nil {
+		dbDefault = cleanDefaultValue(*col.DefaultValue)
+	}
+	if !isSerialType(modelType) && !strings.HasPrefix(dbDefault, "nextval(") {
+		idx := find("default")
+		modelDefault := ""
+		if idx >= 0 {
+			_, modelDefault, _ = strings.Cut(parts[idx], ":")
+		}
+		if normalizeDefault(modelDefault) != normalizeDefault(dbDefault) {
+			switch {
+			case dbDefault == "":
+				parts = append(parts[:idx], parts[idx+1:]...)
+				details = append(details, fmt.Sprintf("removed default %s", modelDefault))
+			case idx < 0:
+				parts = append(parts, "default:"+dbDefault)
+				details = append(details, fmt.Sprintf("added default %s", dbDefault))
+			default:
+				parts[idx] = "default:" + dbDefault
+				details = append(details, fmt.Sprintf("default %s -> %s", modelDefault, dbDefault))
+			}
+		}
+	}
+
+	return strings.Join(parts, ";"), details
+}
+
+// modelFieldColumn returns the column of a struct field and its storm tag,
+// or an empty column for relationships, ignored and unexported fields
+func modelFieldColumn(field *ast.Field) (column, stormTag string) {
+	if len(field.Names) != 1 || !field.Names[0].IsExported() || field.Tag == nil {
+		return "", ""
+	}
+	tag := fieldTag(field)
+	stormTag = tag.Get("storm")
+	if stormTag == "-" || strings.Contains(stormTag, "relation:") {
+		return "", ""
+	}
+
+	for _, part := range strings.Split(stormTag, ";") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(part), "column:"); ok {
+			return name, stormTag
+		}
+	}
+	column, _, _ = strings.Cut(tag.Get("db"), ",")
+	if column == "-" {
+		return "", ""
+	}
+	return column, stormTag
+}
+
+func fieldTag(field *ast.Field) reflect.StructTag {
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+// stormTagRange returns the offsets of the storm tag value within the tag
+// literal at src[start:end]
+func stormTagRange(src []byte, start, end int) (int, int, bool) {
+	literal := string(src[start:end])
+	if !strings.HasPrefix(literal, "`") {
+		return 0, 0, false
+	}
+	i := strings.Index(literal, `storm:"`)
+	if i < 0 {
+		return 0, 0, false
+	}
+	valueStart := i + len(`storm:"`)
+	valueEnd := strings.Index(literal[valueStart:], `"`)
+	if valueEnd < 0 {
+		return 0, 0, false
+	}
+	return start + valueStart, start + valueStart + valueEnd, true
+}
+
+// missingImports returns edits importing the packages the added fields use
+func missingImports(file *ast.File, fset *token.FileSet, added []string) []sourceEdit {
+	needed := map[string]string{}
+	for _, fieldDef := range added {
+		if strings.Contains(fieldDef, "time.") {
+			needed["time"] = "\"time\""
+		}
+		if strings.Contains(fieldDef, "storm.") {
+			needed["github.com/eleven-am/storm/pkg/storm-orm"] = "storm \"github.com/eleven-am/storm/pkg/storm-orm\""
+		}
+	}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		delete(needed, path)
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+
+	specs := make([]string, 0, len(needed))
+	for _, spec := range needed {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() {
+			at := fset.Position(gen.Lparen).Offset + 1
+			return []sourceEdit{{start: at, end: at, text: "\n\t" + strings.Join(specs, "\n\t")}}
+		}
+	}
+	at := fset.Position(file.Name.End()).Offset
+	return []sourceEdit{{start: at, end: at, text: "\n\nimport (\n\t" + strings.Join(specs, "\n\t") + "\n)"}}
+}
+
+func findColumn(table *TableSchema, name string) *ColumnSchema {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// canBeNull reports whether a field type is one a pointer makes nullable,
+// rather than a slice, map or a type handling NULL itself
+func canBeNull(expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return !strings.HasPrefix(t.Sel.Name, "Null") && t.Sel.Name != "JSONData" && t.Sel.Name != "StringArray"
+	}
+	return false
+}
+
+// lineEnd returns the offset just past the end of the line holding offset
+func lineEnd(src []byte, offset int) int {
+	if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(src)
+}
+
+func insertPart(parts []string, at int, part string) []string {
+	if at <= 0 || at > len(parts) {
+		at = len(parts)
+	}
+	return append(parts[:at], append([]string{part}, parts[at:]...)...)
+}
+
+func isSerialType(sqlType string) bool {
+	switch strings.ToLower(strings.TrimSpace(sqlType)) {
+	case "serial", "bigserial", "smallserial", "serial4", "serial8", "serial2":
+		return true
+	}
+	return false
+}
+
+// normalizeSQLType maps the spellings of a type to one form, so aliases such
+// as int4 and integer compare equal
+func normalizeSQLType(sqlType string) string {
+	t := strings.Join(strings.Fields(strings.ToLower(sqlType)), " ")
+	t = strings.ReplaceAll(t, ", ", ",")
+	for _, alias := range [][2]string{
+		{"character varying", "varchar"}, {"character", "char"}, {"decimal", "numeric"},
+		{"timestamp with time zone", "timestamptz"}, {"timestamp without time zone", "timestamp"},
+		{"time with time zone", "timetz"}, {"time without time zone", "time"},
+		{"int4", "integer"}, {"int8", "bigint"}, {"int2", "smallint"}, {"int", "integer"},
+		{"bool", "boolean"}, {"float8", "double precision"}, {"float4", "real"},
+	} {
+		if t == alias[0] || strings.HasPrefix(t, alias[0]+"(") {
+			return alias[1] + strings.TrimPrefix(t, alias[0])
+		}
+	}
+	return t
+}
+
+func normalizeDefault(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "current_timestamp" {
+		return "now()"
+	}
+	return strings.Trim(value, "'")
+}
@@ -0,0 +1,129 @@
+package introspect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const syncModelSource = `package models
+
+// User is an account holder
+type User struct {
+	_ struct{} ` + "`storm:\"table:users\"`" + `
+
+	ID    int64  ` + "`db:\"id\" storm:\"column:id;type:bigserial;primary_key\"`" + `
+	Email string ` + "`db:\"email\" storm:\"column:email;type:varchar(100);unique\"`" + ` // login name
+	Nickname string ` + "`db:\"nickname\" storm:\"column:nickname;type:text;not_null\"`" + `
+	Legacy string ` + "`db:\"legacy\" storm:\"column:legacy;type:text\"`" + `
+
+	Posts []Post ` + "`storm:\"relation:has_many:Post;foreign_key:user_id\"`" + `
+}
+
+// DisplayName is kept as written
+func (u User) DisplayName() string { return u.Email }
+`
+
+func TestModelSync_SyncSource(t *testing.T) {
+	schema := &DatabaseSchema{
+		Tables: map[string]*TableSchema{
+			"users": {
+				Name: "users",
+				Columns: []*ColumnSchema{
+					{Name: "id", DataType: "bigint", DefaultValue: stringPtr("nextval('users_id_seq'::regclass)")},
+					{Name: "email", DataType: "character varying", CharMaxLength: intPtr(255)},
+					{Name: "nickname", DataType: "text", IsNullable: true},
+					{Name: "created_at", DataType: "timestamp with time zone", DefaultValue: stringPtr("now()")},
+				},
+				PrimaryKey: &PrimaryKeySchema{Name: "users_pkey", Columns: []string{"id"}},
+			},
+		},
+	}
+
+	synced, changes, err := NewModelSync(schema).SyncSource("models.go", []byte(syncModelSource))
+	if err != nil {
+		t.Fatalf("SyncSource failed: %v", err)
+	}
+	result := string(synced)
+
+	expectedContents := []string{
+		"// User is an account holder",
+		`storm:"column:email;type:varchar(255);not_null;unique"` + "` // login name",
+		`storm:"column:nickname;type:text"`,
+		`storm:"column:legacy;type:text"`,
+		"CreatedAt time.Time",
+		`storm:"column:created_at;type:timestamptz;not_null;default:now()"`,
+		"import (\n\t\"time\"\n)",
+		"func (u User) DisplayName() string { return u.Email }",
+	}
+	for _, expected := range expectedContents {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected synced code to contain %q.\nSynced:\n%s", expected, result)
+		}
+	}
+	if strings.Index(result, "CreatedAt") > strings.Index(result, "Posts") {
+		t.Errorf("Expected the new field before the relationships.\nSynced:\n%s", result)
+	}
+
+	kinds := make(map[string]string)
+	for _, change := range changes {
+		kinds[change.Column] += change.Kind + " "
+	}
+	expectedKinds := map[string]string{
+		"email":      "update_tag ",
+		"nickname":   "nullability update_tag ",
+		"legacy":     "removed_column ",
+		"created_at": "add_field ",
+	}
+	for column, expected := range expectedKinds {
+		if kinds[column] != expected {
+			t.Errorf("Expected %s changes for %s, got %q", expected, column, kinds[column])
+		}
+	}
+	if _, ok := kinds["id"]; ok {
+		t.Errorf("Expected no changes for the serial primary key, got %q", kinds["id"])
+	}
+}
+
+func TestModelSync_SyncDir(t *testing.T) {
+	dir := t.TempDir()
+	generated := "// Code generated by storm; DO NOT EDIT.\n\n" + syncModelSource
+	for name, content := range map[string]string{"user.go": syncModelSource, "user_gen.go": generated} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	schema := &DatabaseSchema{
+		Tables: map[string]*TableSchema{
+			"users": {
+				Name: "users",
+				Columns: []*ColumnSchema{
+					{Name: "id", DataType: "bigint"},
+					{Name: "email", DataType: "character varying", CharMaxLength: intPtr(100)},
+					{Name: "nickname", DataType: "text"},
+					{Name: "legacy", DataType: "text", IsNullable: true},
+				},
+				PrimaryKey: &PrimaryKeySchema{Name: "users_pkey", Columns: []string{"id"}},
+			},
+		},
+	}
+
+	changes, err := NewModelSync(schema).SyncDir(dir, true)
+	if err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Column != "email" || changes[0].Detail != "added not_null" ||
+		changes[1].Column != "legacy" || changes[1].Kind != ModelChangeNullability {
+		t.Errorf("Expected changes to email and legacy in user.go only, got %+v", changes)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "user.go"))
+	if err != nil {
+		t.Fatalf("failed to read user.go: %v", err)
+	}
+	if string(content) != syncModelSource {
+		t.Errorf("Expected a dry run to leave the file unchanged")
+	}
+}
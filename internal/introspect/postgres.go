@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -402,7 +403,9 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 				FROM generate_subscripts(idx.indkey, 1) as k
 				ORDER BY k
 			) as columns,
-			ts.spcname as tablespace
+			idx.indnkeyatts as key_columns,
+			ts.spcname as tablespace,
+			COALESCE(i.reloptions, '{}') as options
 		FROM pg_index idx
 		JOIN pg_class i ON i.oid = idx.indexrelid
 		JOIN pg_class t ON t.oid = idx.indrelid
@@ -429,6 +432,8 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 		var whereClause sql.NullString
 		var tablespace sql.NullString
 		var columnExprs pq.StringArray
+		var keyColumns int
+		var options pq.StringArray
 
 		err := rows.Scan(
 			&idx.Name,
@@ -438,7 +443,9 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 			&whereClause,
 			&idx.Type,
 			&columnExprs,
+			&keyColumns,
 			&tablespace,
+			&options,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan index: %w", err)
@@ -451,6 +458,19 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 			idx.TableSpace = tablespace.String
 		}
 
+		for _, option := range options {
+			if value, ok := strings.CutPrefix(option, "fillfactor="); ok {
+				idx.FillFactor, _ = strconv.Atoi(value)
+			}
+		}
+
+		if keyColumns < len(columnExprs) {
+			for _, expr := range columnExprs[keyColumns:] {
+				idx.Include = append(idx.Include, strings.Trim(expr, `"`))
+			}
+			columnExprs = columnExprs[:keyColumns]
+		}
+
 		for _, expr := range columnExprs {
 			col := IndexColumn{
 				Expression: expr,
@@ -710,6 +730,15 @@ func (i *Inspector) getPostgreSQLEnums(ctx context.Context) (map[string]*EnumSch
 	return enums, rows.Err()
 }
 
+// functionArgumentModes maps the pg_proc.proargmodes codes to argument modes
+var functionArgumentModes = map[string]string{
+	"i": "IN",
+	"o": "OUT",
+	"b": "INOUT",
+	"v": "VARIADIC",
+	"t": "TABLE",
+}
+
 func (i *Inspector) getPostgreSQLFunctions(ctx context.Context) (map[string]*FunctionSchema, error) {
 	query := `
 		SELECT 
@@ -718,7 +747,16 @@ func (i *Inspector) getPostgreSQLFunctions(ctx context.Context) (map[string]*Fun
 			pg_get_function_result(p.oid) as return_type,
 			l.lanname as language,
 			p.prosrc as definition,
-			p.provolatile = 'v' as is_volatile
+			p.provolatile = 'v' as is_volatile,
+			p.proretset as returns_set,
+			p.prokind = 'p' as is_procedure,
+			COALESCE(p.proargnames, ARRAY[]::text[]) as arg_names,
+			COALESCE(p.proargmodes::text[], ARRAY[]::text[]) as arg_modes,
+			ARRAY(
+				SELECT format_type(a.type, NULL)
+				FROM unnest(COALESCE(p.proallargtypes, p.proargtypes::oid[])) WITH ORDINALITY AS a(type, position)
+				ORDER BY a.position
+			) as arg_types
 		FROM pg_proc p
 		JOIN pg_namespace n ON n.oid = p.pronamespace
 		JOIN pg_language l ON l.oid = p.prolang
@@ -741,6 +779,7 @@ func (i *Inspector) getPostgreSQLFunctions(ctx context.Context) (map[string]*Fun
 		fn := &FunctionSchema{
 			Arguments: make([]FunctionArgument, 0),
 		}
+		var argNames, argModes, argTypes pq.StringArray
 
 		err := rows.Scan(
 			&fn.Schema,
@@ -749,11 +788,27 @@ func (i *Inspector) getPostgreSQLFunctions(ctx context.Context) (map[string]*Fun
 			&fn.Language,
 			&fn.Definition,
 			&fn.IsVolatile,
+			&fn.ReturnsSet,
+			&fn.IsProcedure,
+			&argNames,
+			&argModes,
+			&argTypes,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan function: %w", err)
 		}
 
+		for position, dataType := range argTypes {
+			arg := FunctionArgument{DataType: dataType, Mode: "IN"}
+			if position < len(argNames) {
+				arg.Name = argNames[position]
+			}
+			if position < len(argModes) {
+				arg.Mode = functionArgumentModes[argModes[position]]
+			}
+			fn.Arguments = append(fn.Arguments, arg)
+		}
+
 		functions[fmt.Sprintf("%s.%s", fn.Schema, fn.Name)] = fn
 	}
 
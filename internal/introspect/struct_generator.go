@@ -106,7 +106,7 @@ func (g *StructGenerator) generateTableStruct(table *TableSchema) (string, error
 					cols = append(cols, c.Name)
 				}
 			}
-			indexDef := fmt.Sprintf("index:%s,%s", idx.Name, strings.Join(cols, ","))
+			indexDef := fmt.Sprintf("index:%s,%s", idx.Name, strings.Join(cols, ",")) + indexTagOptions(idx)
 			if idx.Where != "" {
 				indexDef += fmt.Sprintf(" where:%s", idx.Where)
 			}
@@ -122,6 +122,10 @@ func (g *StructGenerator) generateTableStruct(table *TableSchema) (string, error
 					cols = append(cols, c.Name)
 				}
 			}
+			if options := indexTagOptions(idx); options != "" {
+				tableDefParts = append(tableDefParts, fmt.Sprintf("index:%s,%s,unique%s", idx.Name, strings.Join(cols, ","), options))
+				continue
+			}
 			tableDefParts = append(tableDefParts, fmt.Sprintf("unique:%s,%s", idx.Name, strings.Join(cols, ",")))
 		}
 	}
@@ -595,3 +599,22 @@ func pluralize(s string) string {
 	}
 	return s + "s"
 }
+
+// indexTagOptions returns the using:, include:, fillfactor: and tablespace:
+// options of an index tag, so a regenerated model creates the same index
+func indexTagOptions(idx *IndexSchema) string {
+	var options strings.Builder
+	if idx.Type != "" && idx.Type != "btree" {
+		options.WriteString(" using:" + idx.Type)
+	}
+	if len(idx.Include) > 0 {
+		options.WriteString(" include:" + strings.Join(idx.Include, ","))
+	}
+	if idx.FillFactor > 0 {
+		options.WriteString(fmt.Sprintf(" fillfactor:%d", idx.FillFactor))
+	}
+	if idx.TableSpace != "" {
+		options.WriteString(" tablespace:" + idx.TableSpace)
+	}
+	return options.String()
+}
@@ -240,6 +240,50 @@ func TestStructGenerator_ForeignKeys(t *testing.T) {
 	}
 }
 
+func TestStructGenerator_IndexOptions(t *testing.T) {
+	table := &TableSchema{
+		Name: "orders",
+		Columns: []*ColumnSchema{
+			{Name: "id", DataType: "integer"},
+			{Name: "user_id", DataType: "integer"},
+			{Name: "status", DataType: "text"},
+		},
+		PrimaryKey: &PrimaryKeySchema{Name: "orders_pkey", Columns: []string{"id"}},
+		Indexes: []*IndexSchema{
+			{
+				Name:       "idx_orders_user",
+				Columns:    []IndexColumn{{Name: "user_id"}},
+				Type:       "btree",
+				Include:    []string{"status"},
+				FillFactor: 70,
+				TableSpace: "fast_ssd",
+			},
+			{
+				Name:     "idx_orders_status",
+				Columns:  []IndexColumn{{Name: "status"}},
+				IsUnique: true,
+				Type:     "btree",
+				Include:  []string{"user_id"},
+			},
+		},
+	}
+
+	schema := &DatabaseSchema{Tables: map[string]*TableSchema{"orders": table}}
+	result, err := NewStructGenerator(schema, "models").GenerateStructs()
+	if err != nil {
+		t.Fatalf("Failed to generate structs: %v", err)
+	}
+
+	for _, expected := range []string{
+		"index:idx_orders_user,user_id include:status fillfactor:70 tablespace:fast_ssd",
+		"index:idx_orders_status,status,unique include:user_id",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected generated code to contain %q.\nGenerated:\n%s", expected, result)
+		}
+	}
+}
+
 func TestStructGenerator_TableNameConversion(t *testing.T) {
 	tests := []struct {
 		tableName    string
@@ -86,6 +86,8 @@ type IndexSchema struct {
 	Where      string
 	Type       string
 	TableSpace string
+	Include    []string // non-key columns from the INCLUDE clause
+	FillFactor int      // 0 when the index has no fillfactor set
 }
 
 // IndexColumn represents a column in an index
@@ -133,16 +135,19 @@ type EnumSchema struct {
 
 // FunctionSchema represents a stored function or procedure
 type FunctionSchema struct {
-	Name       string
-	Schema     string
-	Arguments  []FunctionArgument
-	ReturnType string
-	Language   string
-	Definition string
-	IsVolatile bool
+	Name        string
+	Schema      string
+	Arguments   []FunctionArgument
+	ReturnType  string
+	Language    string
+	Definition  string
+	IsVolatile  bool
+	ReturnsSet  bool
+	IsProcedure bool
 }
 
-// FunctionArgument represents a function argument
+// FunctionArgument represents a function argument. Mode is IN, OUT, INOUT,
+// VARIADIC or TABLE, the last for the columns of RETURNS TABLE.
 type FunctionArgument struct {
 	Name     string
 	DataType string
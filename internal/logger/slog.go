@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// slogLogger implements Logger on top of a slog.Logger, so storm's own
+// messages reach the handler an application already uses. Fields become
+// attributes, and the level set with SetLevel filters before the handler does.
+type slogLogger struct {
+	logger   *slog.Logger
+	level    *Level
+	progress *string
+}
+
+// NewSlogLogger returns a Logger writing through logger. A nil logger uses
+// slog.Default().
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := DebugLevel
+	return &slogLogger{logger: logger, level: &level, progress: new(string)}
+}
+
+// SetSlogHandler routes the global logger, and with it every component
+// logger, through handler
+func SetSlogHandler(handler slog.Handler) {
+	SetGlobalLogger(NewSlogLogger(slog.New(handler)))
+}
+
+// SlogLevel returns the slog level matching level
+func SlogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case SilentLevel:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func (l *slogLogger) log(level Level, format string, args ...interface{}) {
+	if level < *l.level {
+		return
+	}
+	l.logger.Log(context.Background(), SlogLevel(level), fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Debug(format string, args ...interface{}) { l.log(DebugLevel, format, args...) }
+func (l *slogLogger) Info(format string, args ...interface{})  { l.log(InfoLevel, format, args...) }
+func (l *slogLogger) Warn(format string, args ...interface{})  { l.log(WarnLevel, format, args...) }
+func (l *slogLogger) Error(format string, args ...interface{}) { l.log(ErrorLevel, format, args...) }
+
+func (l *slogLogger) Fatal(format string, args ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelError+4, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (l *slogLogger) WithField(key string, value interface{}) Logger {
+	return &slogLogger{logger: l.logger.With(key, value), level: l.level, progress: l.progress}
+}
+
+func (l *slogLogger) WithFields(fields map[string]interface{}) Logger {
+	args := make([]interface{}, 0, len(fields)*2)
+	for key, value := range fields {
+		args = append(args, key, value)
+	}
+	return &slogLogger{logger: l.logger.With(args...), level: l.level, progress: l.progress}
+}
+
+// Progress is reported as records rather than redrawn in place, since the
+// handler may not write to a terminal
+func (l *slogLogger) StartProgress(message string) {
+	*l.progress = message
+	l.log(InfoLevel, "%s", message)
+}
+
+func (l *slogLogger) UpdateProgress(message string) {
+	*l.progress = message
+	l.log(DebugLevel, "%s", message)
+}
+
+func (l *slogLogger) EndProgress(success bool) {
+	message := *l.progress
+	*l.progress = ""
+	if success {
+		l.log(InfoLevel, "%s: done", message)
+	} else {
+		l.log(ErrorLevel, "%s: failed", message)
+	}
+}
+
+// SetOutput is a no-op; the handler decides where records are written
+func (l *slogLogger) SetOutput(w io.Writer) {}
+
+func (l *slogLogger) SetLevel(level Level) {
+	*l.level = level
+}
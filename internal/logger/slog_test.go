@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	l.SetLevel(InfoLevel)
+
+	l.Debug("hidden")
+	l.WithField("component", "schema").WithFields(map[string]interface{}{"table": "users"}).Warn("unknown type %s", "money")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	expected := map[string]interface{}{"level": "WARN", "msg": "unknown type money", "component": "schema", "table": "users"}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, record[key])
+		}
+	}
+
+	t.Run("progress is logged as records", func(t *testing.T) {
+		buf.Reset()
+		l.StartProgress("Generating migrations")
+		l.EndProgress(false)
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		if len(lines) != 2 || !bytes.Contains(lines[1], []byte(`"msg":"Generating migrations: failed"`)) {
+			t.Errorf("unexpected progress records %q", buf.String())
+		}
+	})
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -23,15 +21,19 @@ type MigrationOptions struct {
 	AllowDestructive    bool
 	PushToDB            bool
 	CreateDBIfNotExists bool
+	Naming              *parser.Naming // Table and column naming, snake_case when nil
+	Files               FileLayout     // Names and header of the written migration files
 }
 
 // MigrationResult contains the results of migration generation
 type MigrationResult struct {
 	UpSQL          string
 	DownSQL        string
+	Statements     []string // The UP statements without comments
 	Changes        []schema.Change
 	HasDestructive bool
 	DestructiveOps []string
+	Irreversible   []string // Up statements the down migration cannot undo, with the reason
 	UpFilePath     string
 	DownFilePath   string
 }
@@ -60,7 +62,11 @@ func NewAtlasMigrator(config *DBConfig) *AtlasMigrator {
 func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB, opts MigrationOptions) (*MigrationResult, error) {
 
 	fmt.Println("Parsing Go structs...")
-	models, err := m.structParser.ParseDirectory(opts.PackagePath)
+	structParser := m.structParser
+	if opts.Naming != nil {
+		structParser = parser.NewStructParserWithNaming(opts.Naming)
+	}
+	models, err := structParser.ParseDirectory(opts.PackagePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse structs: %w", err)
 	}
@@ -106,6 +112,12 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 		upBuilder.WriteString("\n")
 	}
 
+	if generator.UsesUnaccent(upStatements...) {
+		upBuilder.WriteString("-- Accent insensitive search\n")
+		upBuilder.WriteString(generator.UnaccentFunctionSQL)
+		upBuilder.WriteString("\n")
+	}
+
 	for i, stmt := range upStatements {
 		var description string
 		if i < len(changes) {
@@ -121,36 +133,53 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 		upBuilder.WriteString("\n\n")
 	}
 
-	var downBuilder strings.Builder
-	downBuilder.WriteString("-- Migration DOWN generated by db-migrator using Atlas\n")
-	downBuilder.WriteString("-- Generated at: " + time.Now().UTC().Format(time.RFC3339) + "\n\n")
-	downBuilder.WriteString("-- WARNING: Reverse migration may cause data loss!\n")
-	downBuilder.WriteString("-- Review carefully before executing.\n\n")
-
+	var reversals strings.Builder
+	var irreversible []string
 	for i := len(upStatements) - 1; i >= 0; i-- {
-		reversed, err := m.migrationReverser.ReverseSQL(upStatements[i])
+		reversed, err := m.migrationReverser.Reverse(upStatements[i])
 		if err != nil {
-			downBuilder.WriteString(fmt.Sprintf("-- ERROR: Failed to reverse statement %d: %v\n", i+1, err))
-			downBuilder.WriteString(fmt.Sprintf("-- Original: %s\n\n", upStatements[i]))
+			irreversible = append(irreversible, fmt.Sprintf("statement %d: %v", i+1, err))
+			reversals.WriteString(fmt.Sprintf("-- Statement %d cannot be reversed automatically: %v\n", i+1, err))
+			for _, line := range strings.Split(stripLeadingComments(upStatements[i]), "\n") {
+				reversals.WriteString("-- " + line + "\n")
+			}
+			reversals.WriteString("\n")
 		} else if reversed != "" {
-			downBuilder.WriteString(fmt.Sprintf("-- Reversal of statement %d\n", i+1))
-			downBuilder.WriteString(reversed)
+			reversals.WriteString(fmt.Sprintf("-- Reversal of statement %d\n", i+1))
+			reversals.WriteString(reversed)
 			if !strings.HasSuffix(reversed, ";") {
-				downBuilder.WriteString(";")
+				reversals.WriteString(";")
 			}
-			downBuilder.WriteString("\n\n")
+			reversals.WriteString("\n\n")
 		}
 	}
 
+	var downBuilder strings.Builder
+	downBuilder.WriteString("-- Migration DOWN generated by db-migrator using Atlas\n")
+	downBuilder.WriteString("-- Generated at: " + time.Now().UTC().Format(time.RFC3339) + "\n\n")
+	if len(irreversible) > 0 {
+		downBuilder.WriteString("-- Rollback is refused while these lines remain. Add the missing\n")
+		downBuilder.WriteString("-- reversal below, then remove them.\n")
+		for _, reason := range irreversible {
+			downBuilder.WriteString(IrreversibleDirective + " " + reason + "\n")
+		}
+		downBuilder.WriteString("\n")
+	}
+	downBuilder.WriteString("-- WARNING: Reverse migration may cause data loss!\n")
+	downBuilder.WriteString("-- Review carefully before executing.\n\n")
+	downBuilder.WriteString(reversals.String())
+
 	upSQL := upBuilder.String()
 	downSQL := downBuilder.String()
 
 	result := &MigrationResult{
 		UpSQL:          upSQL,
 		DownSQL:        downSQL,
+		Statements:     upStatements,
 		Changes:        changes,
 		HasDestructive: destructiveCount > 0,
 		DestructiveOps: destructiveOps,
+		Irreversible:   irreversible,
 	}
 
 	if result.HasDestructive && !opts.AllowDestructive {
@@ -172,76 +201,67 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	}
 
 	if opts.PushToDB {
-		fmt.Println("Executing migration on database...")
-
-		var execStatements []string
-
-		if needsCUIDFunctions(upStatements) {
-			cuidSQL := generateCUIDFunctions()
-
-			fmt.Printf("Executing CUID functions...\n")
-			if _, err := sourceDB.ExecContext(ctx, cuidSQL); err != nil {
-				return nil, fmt.Errorf("failed to execute CUID functions: %w", err)
-			}
-		}
-
-		execStatements = append(execStatements, upStatements...)
-
-		for i, stmt := range execStatements {
-			fmt.Printf("Executing statement %d/%d...\n", i+1, len(execStatements))
-			if _, err := sourceDB.ExecContext(ctx, stmt); err != nil {
-				return nil, fmt.Errorf("failed to execute statement %d: %s\nError: %w", i+1, stmt, err)
-			}
+		if err := ExecuteStatements(ctx, sourceDB, upStatements); err != nil {
+			return nil, err
 		}
-		fmt.Printf("\nMigration executed successfully! Applied %d changes.\n", len(execStatements))
 		return result, nil
 	}
 
 	if opts.OutputDir != "" {
-		if err := m.writeMigrationFiles(opts.OutputDir, opts.MigrationName, upSQL, downSQL); err != nil {
-			return nil, fmt.Errorf("failed to write migration files: %w", err)
-		}
-
-		timestamp := time.Now().UTC().Format("20060102150405")
 		migrationName := opts.MigrationName
 		if migrationName == "" {
 			migrationName = "schema_update"
 		}
-		baseName := fmt.Sprintf("%s_%s", timestamp, migrationName)
-		result.UpFilePath = filepath.Join(opts.OutputDir, fmt.Sprintf("%s.up.sql", baseName))
-		result.DownFilePath = filepath.Join(opts.OutputDir, fmt.Sprintf("%s.down.sql", baseName))
+		upFile, downFile, err := opts.Files.WriteFiles(opts.OutputDir, migrationName, upSQL, downSQL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write migration files: %w", err)
+		}
+		result.UpFilePath = upFile
+		result.DownFilePath = downFile
 
 		fmt.Printf("\nMigration files created:\n")
 		fmt.Printf("  UP:   %s\n", result.UpFilePath)
 		fmt.Printf("  DOWN: %s\n", result.DownFilePath)
+
+		if len(irreversible) > 0 {
+			fmt.Println("\nThe DOWN migration cannot undo:")
+			for _, reason := range irreversible {
+				fmt.Printf("  - %s\n", reason)
+			}
+			fmt.Println("Rollback is refused until the reversal is written by hand.")
+		}
 	}
 
 	return result, nil
 }
 
-func (m *AtlasMigrator) writeMigrationFiles(outputDir, migrationName, upSQL, downSQL string) error {
+// ExecuteStatements runs migration statements on db in order, creating the
+// CUID and unaccent functions first when a statement needs them
+func ExecuteStatements(ctx context.Context, db *sql.DB, statements []string) error {
+	fmt.Println("Executing migration on database...")
 
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
+	if needsCUIDFunctions(statements) {
+		cuidSQL := generateCUIDFunctions()
 
-	timestamp := time.Now().UTC().Format("20060102150405")
-	if migrationName == "" {
-		migrationName = "schema_update"
+		fmt.Printf("Executing CUID functions...\n")
+		if _, err := db.ExecContext(ctx, cuidSQL); err != nil {
+			return fmt.Errorf("failed to execute CUID functions: %w", err)
+		}
 	}
 
-	baseName := fmt.Sprintf("%s_%s", timestamp, migrationName)
-	upFile := filepath.Join(outputDir, fmt.Sprintf("%s.up.sql", baseName))
-	downFile := filepath.Join(outputDir, fmt.Sprintf("%s.down.sql", baseName))
-
-	if err := os.WriteFile(upFile, []byte(upSQL), 0644); err != nil {
-		return fmt.Errorf("failed to write UP migration: %w", err)
+	if generator.UsesUnaccent(statements...) {
+		if _, err := db.ExecContext(ctx, generator.UnaccentFunctionSQL); err != nil {
+			return fmt.Errorf("failed to create unaccent function: %w", err)
+		}
 	}
 
-	if err := os.WriteFile(downFile, []byte(downSQL), 0644); err != nil {
-		return fmt.Errorf("failed to write DOWN migration: %w", err)
+	for i, stmt := range statements {
+		fmt.Printf("Executing statement %d/%d...\n", i+1, len(statements))
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement %d: %s\nError: %w", i+1, stmt, err)
+		}
 	}
-
+	fmt.Printf("\nMigration executed successfully! Applied %d changes.\n", len(statements))
 	return nil
 }
 
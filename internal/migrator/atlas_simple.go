@@ -129,22 +129,56 @@ func (m *SimplifiedAtlasMigrator) GenerateMigrationSimple(ctx context.Context, s
 		return nil, nil, fmt.Errorf("failed to calculate diff: %w", err)
 	}
 
-	if len(changes) == 0 {
-		return []string{}, changes, nil
+	upSQL = []string{}
+	if len(changes) > 0 {
+		upSQL, err = GenerateAtlasSQL(ctx, diffDriver, changes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate SQL: %w", err)
+		}
 	}
 
-	upSQL, err = GenerateAtlasSQL(ctx, diffDriver, changes)
+	securityChanges, err := rowSecurityChanges(ctx, sourceDB, tempDB, targetDDL, createDBIfNotExists)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate SQL: %w", err)
+		return nil, nil, err
+	}
+	for _, change := range securityChanges {
+		changes = append(changes, change)
+		upSQL = append(upSQL, change.Cmd)
 	}
 
 	return upSQL, changes, nil
 }
 
+// rowSecurityChanges compares the row level security of the source database
+// with the temp database the target DDL was applied to. Servers that cannot
+// report row level security are skipped when the target declares none.
+func rowSecurityChanges(ctx context.Context, sourceDB, tempDB *sql.DB, targetDDL string, createDBIfNotExists bool) ([]*PolicyChange, error) {
+	target, err := inspectRowSecurity(ctx, tempDB)
+	if err != nil {
+		if !strings.Contains(targetDDL, "ROW LEVEL SECURITY") {
+			logger.Atlas().Debug("Skipping row level security: %v", err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to inspect target row level security: %w", err)
+	}
+
+	current := map[string]*tableSecurity{}
+	if !createDBIfNotExists {
+		current, err = inspectRowSecurity(ctx, sourceDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect current row level security: %w", err)
+		}
+	}
+
+	return diffRowSecurity(current, target), nil
+}
+
 func IsDestructiveChange(change schema.Change) bool {
 	switch change.(type) {
 	case *schema.DropTable, *schema.DropColumn, *schema.DropIndex, *schema.DropForeignKey:
 		return true
+	case *PolicyChange:
+		return change.(*PolicyChange).Destructive
 	case *schema.ModifyTable:
 
 		mod := change.(*schema.ModifyTable)
@@ -179,6 +213,8 @@ func DescribeChange(change schema.Change) string {
 		return fmt.Sprintf("Add foreign key %s", c.F.Symbol)
 	case *schema.DropForeignKey:
 		return fmt.Sprintf("Drop foreign key %s", c.F.Symbol)
+	case *PolicyChange:
+		return c.Comment
 	default:
 		return fmt.Sprintf("Change type %T", change)
 	}
@@ -0,0 +1,189 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+)
+
+// Steps of a backfill, reported in BackfillProgress
+const (
+	BackfillStepAddColumn  = "add_column"
+	BackfillStepBackfill   = "backfill"
+	BackfillStepAddCheck   = "add_check"
+	BackfillStepValidate   = "validate"
+	BackfillStepSetNotNull = "set_not_null"
+)
+
+// Backfill adds a NOT NULL column to a populated table without holding long
+// locks: the column is added as nullable, filled in batches, guarded by a
+// CHECK constraint added NOT VALID and validated separately, and only then
+// marked NOT NULL, which PostgreSQL 12 and later do without rescanning the
+// table. Every step is safe to repeat, so a failed backfill is resumed by
+// running it again.
+type Backfill struct {
+	Table     string // Optionally schema-qualified
+	Column    string
+	Type      string        // SQL type of the column
+	Value     string        // SQL expression computing the value of each row, e.g. lower(email)
+	Key       string        // Unique column batches walk in order, "id" when empty
+	BatchSize int           // Rows per batch, 1000 when zero
+	Pause     time.Duration // Wait between batches to throttle the load on the database
+}
+
+// BackfillProgress reports the step a backfill reached and, during the
+// backfill step, the rows filled so far
+type BackfillProgress struct {
+	Step    string
+	Batches int
+	Rows    int64
+	Elapsed time.Duration
+}
+
+// Validate checks that the backfill names a table, column, type and value
+func (b Backfill) Validate() error {
+	switch {
+	case strings.TrimSpace(b.Table) == "":
+		return fmt.Errorf("backfill table is required")
+	case strings.TrimSpace(b.Column) == "":
+		return fmt.Errorf("backfill column is required")
+	case strings.TrimSpace(b.Type) == "":
+		return fmt.Errorf("backfill column type is required")
+	case strings.TrimSpace(b.Value) == "":
+		return fmt.Errorf("backfill value is required")
+	case b.BatchSize < 0:
+		return fmt.Errorf("backfill batch size cannot be negative")
+	}
+	return nil
+}
+
+// Run performs the backfill on db, calling progress, when set, as each step
+// starts and after every batch
+func (b Backfill) Run(ctx context.Context, db *sql.DB, progress func(BackfillProgress)) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if b.Key == "" {
+		b.Key = "id"
+	}
+	if b.BatchSize == 0 {
+		b.BatchSize = 1000
+	}
+
+	started := time.Now()
+	report := func(p BackfillProgress) {
+		if progress != nil {
+			p.Elapsed = time.Since(started)
+			progress(p)
+		}
+	}
+
+	table := dialect.QuoteQualifiedIfNeeded(b.Table)
+	column := dialect.QuoteIdentifierIfNeeded(b.Column)
+	check := dialect.QuoteIdentifier(b.constraintName())
+
+	report(BackfillProgress{Step: BackfillStepAddColumn})
+	addColumn := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, b.Type)
+	if _, err := db.ExecContext(ctx, addColumn); err != nil {
+		return fmt.Errorf("failed to add column %s: %w", b.Column, err)
+	}
+
+	filled := BackfillProgress{Step: BackfillStepBackfill}
+	report(filled)
+	if err := b.fill(ctx, db, table, column, func(rows int64) {
+		filled.Batches++
+		filled.Rows += rows
+		report(filled)
+	}); err != nil {
+		return err
+	}
+
+	report(BackfillProgress{Step: BackfillStepAddCheck, Batches: filled.Batches, Rows: filled.Rows})
+	var exists bool
+	if err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = $1 AND conrelid = $2::regclass)",
+		b.constraintName(), table).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up constraint %s: %w", b.constraintName(), err)
+	}
+	if !exists {
+		addCheck := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", table, check, column)
+		if _, err := db.ExecContext(ctx, addCheck); err != nil {
+			return fmt.Errorf("failed to add constraint %s: %w", b.constraintName(), err)
+		}
+	}
+
+	report(BackfillProgress{Step: BackfillStepValidate, Batches: filled.Batches, Rows: filled.Rows})
+	validate := fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, check)
+	if _, err := db.ExecContext(ctx, validate); err != nil {
+		return fmt.Errorf("rows of %s still have no %s (does %s return NULL for some rows?): %w", b.Table, b.Column, b.Value, err)
+	}
+
+	report(BackfillProgress{Step: BackfillStepSetNotNull, Batches: filled.Batches, Rows: filled.Rows})
+	for _, stmt := range []string{
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, check),
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement: %s: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// fill sets the column of the rows where it is NULL, a batch of keys at a
+// time in key order, calling batchDone with the rows each batch changed.
+// Walking the keys rather than the NULL rows ends the backfill even when the
+// value is NULL for some rows; VALIDATE reports those.
+func (b Backfill) fill(ctx context.Context, db *sql.DB, table, column string, batchDone func(rows int64)) error {
+	key := dialect.QuoteIdentifierIfNeeded(b.Key)
+	batch := func(after string) string {
+		return fmt.Sprintf(`
+		WITH batch AS (
+			SELECT %[3]s AS backfill_key FROM %[1]s%[5]s ORDER BY %[3]s LIMIT %[6]d
+		), updated AS (
+			UPDATE %[1]s SET %[2]s = %[4]s
+			FROM batch WHERE %[1]s.%[3]s = batch.backfill_key AND %[1]s.%[2]s IS NULL
+			RETURNING 1
+		)
+		SELECT (SELECT MAX(backfill_key)::text FROM batch), (SELECT COUNT(*) FROM updated)`,
+			table, column, key, b.Value, after, b.BatchSize)
+	}
+
+	var last sql.NullString
+	for {
+		query, args := batch(""), []interface{}{}
+		if last.Valid {
+			query, args = batch(" WHERE "+key+" > $1"), []interface{}{last.String}
+		}
+
+		var rows int64
+		if err := db.QueryRowContext(ctx, query, args...).Scan(&last, &rows); err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", b.Column, err)
+		}
+		if !last.Valid {
+			return nil
+		}
+		batchDone(rows)
+
+		if b.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.Pause):
+			}
+		}
+	}
+}
+
+// constraintName names the temporary CHECK constraint guarding the column
+func (b Backfill) constraintName() string {
+	table := b.Table
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	return strings.Trim(table, `"`) + "_" + b.Column + "_not_null"
+}
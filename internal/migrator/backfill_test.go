@@ -0,0 +1,75 @@
+package migrator
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBackfillRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	backfill := Backfill{Table: "users", Column: "slug", Type: "text", Value: "lower(name)", BatchSize: 2}
+
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE users ADD COLUMN IF NOT EXISTS slug text")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id AS backfill_key FROM users ORDER BY id LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"max", "count"}).AddRow("2", 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id AS backfill_key FROM users WHERE id > $1 ORDER BY id LIMIT 2")).WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"max", "count"}).AddRow("3", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id > $1")).WithArgs("3").
+		WillReturnRows(sqlmock.NewRows([]string{"max", "count"}).AddRow(nil, 0))
+	mock.ExpectQuery("SELECT EXISTS .* FROM pg_constraint").WithArgs("users_slug_not_null", "users").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE users ADD CONSTRAINT "users_slug_not_null" CHECK (slug IS NOT NULL) NOT VALID`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE users VALIDATE CONSTRAINT "users_slug_not_null"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE users ALTER COLUMN slug SET NOT NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE users DROP CONSTRAINT "users_slug_not_null"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var steps []string
+	var rows int64
+	err = backfill.Run(context.Background(), db, func(p BackfillProgress) {
+		if len(steps) == 0 || steps[len(steps)-1] != p.Step {
+			steps = append(steps, p.Step)
+		}
+		rows = p.Rows
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expected := "add_column backfill add_check validate set_not_null"
+	if got := strings.Join(steps, " "); got != expected {
+		t.Errorf("expected steps %s, got %s", expected, got)
+	}
+	if rows != 3 {
+		t.Errorf("expected 3 rows filled, got %d", rows)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestBackfillValidate(t *testing.T) {
+	valid := Backfill{Table: "users", Column: "slug", Type: "text", Value: "lower(name)"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected a valid backfill, got %v", err)
+	}
+
+	missing := valid
+	missing.Value = " "
+	if err := missing.Validate(); err == nil || !strings.Contains(err.Error(), "value is required") {
+		t.Errorf("expected a missing value error, got %v", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+
+	"github.com/eleven-am/storm/internal/dialect"
 )
 
 func EnsureDatabaseExists(dsn string) error {
@@ -88,8 +90,7 @@ func parseDSNForDB(dsn string) (dbName string, adminDSN string, err error) {
 }
 
 func quoteIdentifier(name string) string {
-
-	return fmt.Sprintf(`"%s"`, strings.ReplaceAll(name, `"`, `""`))
+	return dialect.QuoteIdentifier(name)
 }
 
 func GetDatabaseURL(host, port, user, password, dbname, sslmode string) string {
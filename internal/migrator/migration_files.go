@@ -0,0 +1,237 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Schemes for naming new migration files
+const (
+	// FileNamingTimestamp prefixes files with the UTC creation time, 20240131154500_name
+	FileNamingTimestamp = "timestamp"
+	// FileNamingSequential prefixes files with the number after the highest in the directory, 0007_name
+	FileNamingSequential = "sequential"
+)
+
+// DefaultHeader is the header of hand-written migrations when none is configured
+const DefaultHeader = `-- Migration: {{ .Name }}
+-- Created at: {{ .CreatedAt.Format "2006-01-02T15:04:05Z07:00" }}`
+
+var migrationName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FileLayout names new migration files and renders the header written at
+// the top of them
+type FileLayout struct {
+	Naming string // FileNamingTimestamp when empty
+	Header string // text/template rendered with HeaderData, no header when empty
+	Author string
+	Ticket string
+}
+
+// HeaderData is what a migration header template is rendered with
+type HeaderData struct {
+	Name      string
+	Direction string // up or down
+	Author    string
+	Ticket    string
+	CreatedAt time.Time
+}
+
+// Validate checks the naming scheme and that the header template parses
+func (l FileLayout) Validate() error {
+	switch l.Naming {
+	case "", FileNamingTimestamp, FileNamingSequential:
+	default:
+		return fmt.Errorf("unsupported migration file naming %q (expected %s or %s)", l.Naming, FileNamingTimestamp, FileNamingSequential)
+	}
+	if _, err := template.New("header").Parse(l.Header); err != nil {
+		return fmt.Errorf("invalid migration header: %w", err)
+	}
+	return nil
+}
+
+// Content returns the contents of a migration file: the rendered header,
+// if any, followed by sql
+func (l FileLayout) Content(name, direction, sql string, createdAt time.Time) (string, error) {
+	if l.Header == "" {
+		return sql, nil
+	}
+
+	tmpl, err := template.New("header").Parse(l.Header)
+	if err != nil {
+		return "", fmt.Errorf("invalid migration header: %w", err)
+	}
+	var header bytes.Buffer
+	data := HeaderData{Name: name, Direction: direction, Author: l.Author, Ticket: l.Ticket, CreatedAt: createdAt}
+	if err := tmpl.Execute(&header, data); err != nil {
+		return "", fmt.Errorf("failed to render migration header: %w", err)
+	}
+	return strings.TrimRight(header.String(), "\n") + "\n\n" + sql, nil
+}
+
+// WriteFiles writes the up and down files of a new migration named name to
+// dir, creating it when needed, and returns their paths. Existing files are
+// never overwritten.
+func (l FileLayout) WriteFiles(dir, name, upSQL, downSQL string) (upPath, downPath string, err error) {
+	if !migrationName.MatchString(name) {
+		return "", "", fmt.Errorf("migration name %q may only contain letters, digits, '_' and '-'", name)
+	}
+	if err := l.Validate(); err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	now := time.Now()
+	prefix := now.UTC().Format("20060102150405")
+	if l.Naming == FileNamingSequential {
+		next, err := nextSequence(dir)
+		if err != nil {
+			return "", "", err
+		}
+		prefix = fmt.Sprintf("%04d", next)
+	}
+
+	baseName := filepath.Join(dir, prefix+"_"+name)
+	upPath, downPath = baseName+".up.sql", baseName+".down.sql"
+
+	for _, file := range []struct{ path, direction, sql string }{
+		{upPath, "up", upSQL},
+		{downPath, "down", downSQL},
+	} {
+		content, err := l.Content(name, file.direction, file.sql, now)
+		if err != nil {
+			return "", "", err
+		}
+		if err := writeNewFile(file.path, content); err != nil {
+			if file.path == downPath {
+				os.Remove(upPath)
+			}
+			return "", "", fmt.Errorf("failed to write %s migration: %w", strings.ToUpper(file.direction), err)
+		}
+	}
+	return upPath, downPath, nil
+}
+
+// nextSequence returns the number after the highest numeric version of the
+// migrations in dir
+func nextSequence(dir string) (int, error) {
+	files, err := ListMigrationFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	highest := 0
+	for _, file := range files {
+		if number, err := strconv.Atoi(file.Version); err == nil && number > highest {
+			highest = number
+		}
+	}
+	return highest + 1, nil
+}
+
+// MigrationFile is the up file of a migration in a migrations directory
+type MigrationFile struct {
+	Name    string // File name without .up.sql, recorded when the migration is applied
+	Version string // Part of Name before the first '_'
+	UpPath  string
+}
+
+// ListMigrationFiles returns the migrations in dir and its subdirectories,
+// such as migrations/2024/, ordered by version, so generated and
+// hand-written migrations apply in one sequence. Numeric versions compare as
+// numbers and sort before other versions, which compare as text. Two files
+// sharing a version or a name are an error since their order would be
+// ambiguous. A missing dir holds no migrations.
+func ListMigrationFiles(dir string) ([]MigrationFile, error) {
+	var files []MigrationFile
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			return nil
+		}
+		name := strings.TrimSuffix(entry.Name(), ".up.sql")
+		version, _, _ := strings.Cut(name, "_")
+		files = append(files, MigrationFile{Name: name, Version: version, UpPath: path})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations in %s: %w", dir, err)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if c := compareVersions(files[i].Version, files[j].Version); c != 0 {
+			return c < 0
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	for i := 1; i < len(files); i++ {
+		previous, current := files[i-1], files[i]
+		if previous.Name == current.Name {
+			return nil, fmt.Errorf("migration %s is defined twice: %s and %s", current.Name, previous.UpPath, current.UpPath)
+		}
+		if previous.Version == current.Version {
+			return nil, fmt.Errorf("migrations %s and %s share version %s", previous.UpPath, current.UpPath, current.Version)
+		}
+	}
+	return files, nil
+}
+
+// compareVersions orders numeric versions by value, without overflowing on
+// long timestamps, and before any other version
+func compareVersions(a, b string) int {
+	aNumeric, bNumeric := isDigits(a), isDigits(b)
+	switch {
+	case aNumeric && bNumeric:
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func writeNewFile(path, content string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
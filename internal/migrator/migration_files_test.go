@@ -0,0 +1,201 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLayoutWriteFiles(t *testing.T) {
+	t.Run("names files with a timestamp by default", func(t *testing.T) {
+		dir := t.TempDir()
+		up, down, err := FileLayout{}.WriteFiles(dir, "add_users", "CREATE TABLE users ();", "DROP TABLE users;")
+		if err != nil {
+			t.Fatalf("WriteFiles failed: %v", err)
+		}
+
+		base := strings.TrimSuffix(filepath.Base(up), ".up.sql")
+		if len(base) != len("20060102150405_add_users") || !strings.HasSuffix(base, "_add_users") {
+			t.Errorf("expected a timestamped name, got %s", base)
+		}
+		if down != filepath.Join(dir, base+".down.sql") {
+			t.Errorf("expected the down file next to the up file, got %s", down)
+		}
+
+		content, _ := os.ReadFile(up)
+		if string(content) != "CREATE TABLE users ();" {
+			t.Errorf("expected the up file to hold only the SQL without a header, got %q", content)
+		}
+	})
+
+	t.Run("numbers files after the highest existing migration", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"0001_init.up.sql", "0007_add_posts.up.sql", "notes.up.sql"} {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		up, _, err := FileLayout{Naming: FileNamingSequential}.WriteFiles(dir, "add_tags", "", "")
+		if err != nil {
+			t.Fatalf("WriteFiles failed: %v", err)
+		}
+		if filepath.Base(up) != "0008_add_tags.up.sql" {
+			t.Errorf("expected 0008_add_tags.up.sql, got %s", filepath.Base(up))
+		}
+
+		first, _, err := FileLayout{Naming: FileNamingSequential}.WriteFiles(t.TempDir(), "init", "", "")
+		if err != nil {
+			t.Fatalf("WriteFiles failed: %v", err)
+		}
+		if filepath.Base(first) != "0001_init.up.sql" {
+			t.Errorf("expected 0001_init.up.sql, got %s", filepath.Base(first))
+		}
+	})
+
+	t.Run("renders the header", func(t *testing.T) {
+		layout := FileLayout{
+			Naming: FileNamingSequential,
+			Header: "-- {{ .Name }} ({{ .Direction }})\n-- Author: {{ .Author }}\n-- Ticket: {{ .Ticket }}\n",
+			Author: "Ada",
+			Ticket: "OPS-142",
+		}
+		up, down, err := layout.WriteFiles(t.TempDir(), "backfill", "UPDATE users SET slug = id;", "")
+		if err != nil {
+			t.Fatalf("WriteFiles failed: %v", err)
+		}
+
+		content, _ := os.ReadFile(up)
+		expected := "-- backfill (up)\n-- Author: Ada\n-- Ticket: OPS-142\n\nUPDATE users SET slug = id;"
+		if string(content) != expected {
+			t.Errorf("expected %q, got %q", expected, content)
+		}
+		content, _ = os.ReadFile(down)
+		if !strings.HasPrefix(string(content), "-- backfill (down)\n") {
+			t.Errorf("expected the down header, got %q", content)
+		}
+	})
+
+	t.Run("never overwrites a migration", func(t *testing.T) {
+		dir := t.TempDir()
+		layout := FileLayout{Naming: FileNamingSequential}
+		existing := filepath.Join(dir, "0001_init.down.sql")
+		if err := os.WriteFile(existing, []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := layout.WriteFiles(dir, "init", "", ""); err == nil {
+			t.Fatal("expected an error for an existing file")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "0001_init.up.sql")); !os.IsNotExist(err) {
+			t.Error("expected the up file to be removed when the down file cannot be written")
+		}
+		if content, _ := os.ReadFile(existing); string(content) != "keep" {
+			t.Errorf("expected the existing file to be kept, got %q", content)
+		}
+	})
+
+	t.Run("rejects invalid names and settings", func(t *testing.T) {
+		for _, tc := range []struct {
+			layout FileLayout
+			name   string
+			want   string
+		}{
+			{FileLayout{}, "../escape", "may only contain"},
+			{FileLayout{Naming: "numbered"}, "init", "unsupported migration file naming"},
+			{FileLayout{Header: "{{ .Author"}, "init", "invalid migration header"},
+			{FileLayout{Header: "{{ .Missing }}"}, "init", "failed to render migration header"},
+		} {
+			_, _, err := tc.layout.WriteFiles(t.TempDir(), tc.name, "", "")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		}
+	})
+}
+
+func TestFileLayoutContent(t *testing.T) {
+	createdAt := time.Date(2024, 1, 31, 15, 45, 0, 0, time.UTC)
+	content, err := FileLayout{Header: DefaultHeader}.Content("add_users", "up", "SELECT 1;", createdAt)
+	if err != nil {
+		t.Fatalf("Content failed: %v", err)
+	}
+	expected := "-- Migration: add_users\n-- Created at: 2024-01-31T15:45:00Z\n\nSELECT 1;"
+	if content != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+}
+
+func TestListMigrationFiles(t *testing.T) {
+	write := func(t *testing.T, dir string, names ...string) {
+		t.Helper()
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("orders migrations by version across subdirectories", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir,
+			"10_add_posts.up.sql",
+			"10_add_posts.down.sql",
+			"9_add_users.up.sql",
+			"2024/20240131154500_backfill_slugs.up.sql",
+			"2023/20231201000000_init.up.sql",
+			"0008_add_index.up.sql",
+			"README.md",
+		)
+
+		files, err := ListMigrationFiles(dir)
+		if err != nil {
+			t.Fatalf("ListMigrationFiles failed: %v", err)
+		}
+
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+		expected := "0008_add_index 9_add_users 10_add_posts 20231201000000_init 20240131154500_backfill_slugs"
+		if got := strings.Join(names, " "); got != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+		if files[4].UpPath != filepath.Join(dir, "2024", "20240131154500_backfill_slugs.up.sql") {
+			t.Errorf("unexpected path %s", files[4].UpPath)
+		}
+	})
+
+	t.Run("rejects migrations sharing a version", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "0002_add_users.up.sql", "2024/0002_add_posts.up.sql")
+
+		_, err := ListMigrationFiles(dir)
+		if err == nil || !strings.Contains(err.Error(), "share version 0002") {
+			t.Errorf("expected a shared version error, got %v", err)
+		}
+	})
+
+	t.Run("rejects a migration defined twice", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "0002_add_users.up.sql", "2024/0002_add_users.up.sql")
+
+		_, err := ListMigrationFiles(dir)
+		if err == nil || !strings.Contains(err.Error(), "defined twice") {
+			t.Errorf("expected a duplicate migration error, got %v", err)
+		}
+	})
+
+	t.Run("treats a missing directory as empty", func(t *testing.T) {
+		files, err := ListMigrationFiles(filepath.Join(t.TempDir(), "missing"))
+		if err != nil || len(files) != 0 {
+			t.Errorf("expected no migrations, got %v, %v", files, err)
+		}
+	})
+}
@@ -13,6 +13,111 @@ func NewMigrationReverser() *MigrationReverser {
 	return &MigrationReverser{}
 }
 
+// IrreversibleDirective starts a line of a down migration that cannot undo
+// its up migration, followed by the reason. Rollback refuses the migration
+// while the line is present.
+const IrreversibleDirective = "-- storm:irreversible"
+
+// IrreversibleError reports an up statement whose effect cannot be undone
+// automatically, such as DROP COLUMN
+type IrreversibleError struct {
+	Statement string
+	Reason    string
+}
+
+func (e *IrreversibleError) Error() string {
+	return e.Reason
+}
+
+var alterTablePrefix = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+\S+\s+`)
+
+// Reverse returns the statements undoing a statement planned by Atlas. Its
+// leading comments are ignored and the clauses of a multi-clause ALTER TABLE
+// are reversed one by one, last first. A statement that cannot be undone
+// returns an *IrreversibleError.
+func (mr *MigrationReverser) Reverse(stmt string) (string, error) {
+	stmt = stripLeadingComments(stmt)
+
+	clauses := []string{stmt}
+	if prefix := alterTablePrefix.FindString(stmt); prefix != "" {
+		if parts := splitClauses(stmt[len(prefix):]); len(parts) > 1 {
+			clauses = clauses[:0]
+			for _, part := range parts {
+				clauses = append(clauses, prefix+part)
+			}
+		}
+	}
+
+	var reversed []string
+	for i := len(clauses) - 1; i >= 0; i-- {
+		clause, err := mr.ReverseSQL(clauses[i])
+		if err != nil {
+			return "", &IrreversibleError{Statement: stmt, Reason: err.Error()}
+		}
+		if strings.HasPrefix(clause, "-- WARNING:") {
+			return "", &IrreversibleError{Statement: stmt, Reason: warningReason(clause)}
+		}
+		if clause != "" {
+			reversed = append(reversed, clause)
+		}
+	}
+	return strings.Join(reversed, ";\n"), nil
+}
+
+// IrreversibleReasons returns the reasons of the IrreversibleDirective lines
+// in a down migration
+func IrreversibleReasons(downSQL string) []string {
+	var reasons []string
+	for _, line := range strings.Split(downSQL, "\n") {
+		line = strings.TrimSpace(line)
+		if reason, ok := strings.CutPrefix(line, IrreversibleDirective); ok {
+			reasons = append(reasons, strings.TrimSpace(reason))
+		}
+	}
+	return reasons
+}
+
+// warningReason returns the first sentence of a reversal warning
+func warningReason(warning string) string {
+	line, _, _ := strings.Cut(strings.TrimPrefix(warning, "-- WARNING: "), "\n")
+	sentence, _, _ := strings.Cut(line, ". ")
+	return strings.TrimSuffix(sentence, ":")
+}
+
+func stripLeadingComments(stmt string) string {
+	stmt = strings.TrimSpace(stmt)
+	for strings.HasPrefix(stmt, "--") {
+		_, rest, _ := strings.Cut(stmt, "\n")
+		stmt = strings.TrimSpace(rest)
+	}
+	return stmt
+}
+
+// splitClauses splits on the commas outside parentheses and quotes
+func splitClauses(sql string) []string {
+	var clauses []string
+	depth, start := 0, 0
+	var quote rune
+	for i, char := range sql {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case char == ',' && depth == 0:
+			clauses = append(clauses, strings.TrimSpace(sql[start:i]))
+			start = i + 1
+		}
+	}
+	return append(clauses, strings.TrimSpace(sql[start:]))
+}
+
 func (mr *MigrationReverser) ReverseSQL(sql string) (string, error) {
 
 	normalizedSQL := strings.TrimSpace(strings.ToUpper(sql))
@@ -44,6 +149,10 @@ func (mr *MigrationReverser) ReverseSQL(sql string) (string, error) {
 		return mr.reverseCreateTrigger(sql)
 	case strings.HasPrefix(normalizedSQL, "DROP TRIGGER"):
 		return mr.reverseDropTrigger(sql)
+	case strings.HasPrefix(normalizedSQL, "CREATE POLICY"):
+		return mr.reverseCreatePolicy(sql)
+	case strings.HasPrefix(normalizedSQL, "DROP POLICY"):
+		return "-- WARNING: Cannot reverse DROP POLICY without original policy definition", nil
 	case strings.HasPrefix(normalizedSQL, "COMMENT ON"):
 
 		return "", nil
@@ -81,6 +190,12 @@ func (mr *MigrationReverser) reverseAlterTable(sql string) (string, error) {
 	tableName := tableMatches[1]
 
 	switch {
+	case strings.Contains(normalizedSQL, "ENABLE ROW LEVEL SECURITY"):
+		return fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", tableName), nil
+
+	case strings.Contains(normalizedSQL, "DISABLE ROW LEVEL SECURITY"):
+		return fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", tableName), nil
+
 	case strings.Contains(normalizedSQL, "ADD COLUMN"):
 
 		colRe := regexp.MustCompile(`(?i)ADD\s+COLUMN\s+([^\s]+)`)
@@ -111,6 +226,14 @@ func (mr *MigrationReverser) reverseAlterTable(sql string) (string, error) {
 
 	case strings.Contains(normalizedSQL, "ALTER COLUMN"):
 
+		colRe := regexp.MustCompile(`(?i)ALTER\s+COLUMN\s+([^\s]+)\s+(SET|DROP)\s+NOT\s+NULL\s*;?\s*$`)
+		if colMatches := colRe.FindStringSubmatch(sql); len(colMatches) == 3 {
+			action := "DROP"
+			if strings.EqualFold(colMatches[2], "DROP") {
+				action = "SET"
+			}
+			return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NOT NULL", tableName, colMatches[1], action), nil
+		}
 		return fmt.Sprintf("-- WARNING: Cannot automatically reverse ALTER COLUMN. Manual reversal required for:\n-- %s", sql), nil
 
 	case strings.Contains(normalizedSQL, "RENAME"):
@@ -217,6 +340,17 @@ func (mr *MigrationReverser) reverseCreateTrigger(sql string) (string, error) {
 	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", triggerName, tableName), nil
 }
 
+func (mr *MigrationReverser) reverseCreatePolicy(sql string) (string, error) {
+
+	re := regexp.MustCompile(`(?i)CREATE\s+POLICY\s+([^\s]+)\s+ON\s+([^\s;]+)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return "", fmt.Errorf("could not extract policy name and table from: %s", sql)
+	}
+
+	return fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s", matches[1], matches[2]), nil
+}
+
 func (mr *MigrationReverser) reverseDropTrigger(sql string) (string, error) {
 	return "-- WARNING: Cannot reverse DROP TRIGGER without original trigger definition", nil
 }
@@ -27,6 +27,16 @@ func TestMigrationReverser_ReverseSQL(t *testing.T) {
 			sql:      "ALTER TABLE users ADD COLUMN email VARCHAR(255)",
 			expected: "ALTER TABLE users DROP COLUMN IF EXISTS email",
 		},
+		{
+			name:     "CREATE POLICY reversal",
+			sql:      "CREATE POLICY tenant_isolation ON documents USING (tenant_id = current_setting('app.tenant')::uuid)",
+			expected: "DROP POLICY IF EXISTS tenant_isolation ON documents",
+		},
+		{
+			name:     "ENABLE ROW LEVEL SECURITY reversal",
+			sql:      "ALTER TABLE documents ENABLE ROW LEVEL SECURITY",
+			expected: "ALTER TABLE documents DISABLE ROW LEVEL SECURITY",
+		},
 		{
 			name:     "CREATE INDEX reversal",
 			sql:      "CREATE INDEX idx_users_email ON users(email)",
@@ -234,3 +244,78 @@ func TestMigrationReverser_DropOperations(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrationReverser_Reverse(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+		reason   string
+	}{
+		{
+			name:     "Atlas comment before the statement",
+			sql:      "-- create index \"idx_users_email\" to table: \"users\"\nCREATE INDEX \"idx_users_email\" ON \"users\" (\"email\")",
+			expected: `DROP INDEX IF EXISTS "idx_users_email"`,
+		},
+		{
+			name:     "Multi-clause ALTER TABLE reversed last clause first",
+			sql:      "-- modify \"users\" table\nALTER TABLE \"users\" ADD COLUMN \"price\" numeric(10, 2) NOT NULL DEFAULT 0, ADD COLUMN \"tags\" text NULL DEFAULT 'a,b', ALTER COLUMN \"name\" SET NOT NULL",
+			expected: "ALTER TABLE \"users\" ALTER COLUMN \"name\" DROP NOT NULL;\nALTER TABLE \"users\" DROP COLUMN IF EXISTS \"tags\";\nALTER TABLE \"users\" DROP COLUMN IF EXISTS \"price\"",
+		},
+		{
+			name:   "Irreversible clause",
+			sql:    `ALTER TABLE "users" ADD COLUMN "email" text NULL, DROP COLUMN "legacy"`,
+			reason: "Cannot reverse DROP COLUMN without original column definition",
+		},
+		{
+			name:   "Column type change",
+			sql:    `ALTER TABLE "users" ALTER COLUMN "age" TYPE bigint`,
+			reason: "Cannot automatically reverse ALTER COLUMN",
+		},
+		{
+			name:   "Unknown statement",
+			sql:    "GRANT SELECT ON users TO readonly_user",
+			reason: "Unable to automatically reverse the following statement",
+		},
+	}
+
+	reverser := NewMigrationReverser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reverser.Reverse(tt.sql)
+			if tt.reason != "" {
+				irreversible, ok := err.(*IrreversibleError)
+				if !ok || irreversible.Reason != tt.reason {
+					t.Errorf("Reverse() expected irreversible %q, got %q, %v", tt.reason, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reverse() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Reverse() mismatch:\nGot:      %s\nExpected: %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIrreversibleReasons(t *testing.T) {
+	downSQL := "-- Migration DOWN\n" +
+		"-- storm:irreversible statement 3: Cannot reverse DROP TABLE without original schema\n" +
+		"  -- storm:irreversible statement 1: Cannot reverse DROP COLUMN without original column definition\n" +
+		"DROP INDEX IF EXISTS idx_users_email;\n"
+
+	reasons := IrreversibleReasons(downSQL)
+	expected := []string{
+		"statement 3: Cannot reverse DROP TABLE without original schema",
+		"statement 1: Cannot reverse DROP COLUMN without original column definition",
+	}
+	if strings.Join(reasons, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %v, got %v", expected, reasons)
+	}
+	if reasons := IrreversibleReasons("DROP INDEX IF EXISTS idx_users_email;"); len(reasons) != 0 {
+		t.Errorf("expected no reasons, got %v", reasons)
+	}
+}
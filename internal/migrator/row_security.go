@@ -0,0 +1,164 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/dialect"
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+// PolicyChange is a row level security change. Atlas does not inspect row
+// level security, so these are found by comparing pg_policies and
+// pg_class.relrowsecurity of the two databases.
+type PolicyChange struct {
+	schema.Change
+	Table       string
+	Cmd         string
+	Comment     string
+	Destructive bool // disables row level security or drops a policy without replacing it
+}
+
+// tableSecurity is the row level security of one table
+type tableSecurity struct {
+	enabled  bool
+	policies map[string]generator.SchemaPolicy
+}
+
+// inspectRowSecurity reads the row level security of the tables in the
+// current schema of db
+func inspectRowSecurity(ctx context.Context, db *sql.DB) (map[string]*tableSecurity, error) {
+	tables := make(map[string]*tableSecurity)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.relname, c.relrowsecurity
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query row level security: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		var enabled bool
+		if err := rows.Scan(&name, &enabled); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row level security: %w", err)
+		}
+		tables[name] = &tableSecurity{enabled: enabled, policies: make(map[string]generator.SchemaPolicy)}
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT tablename, policyname, permissive, array_to_string(roles, ','), cmd,
+			COALESCE(qual, ''), COALESCE(with_check, '')
+		FROM pg_policies
+		WHERE schemaname = current_schema()`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, permissive, roles string
+		var policy generator.SchemaPolicy
+		if err := rows.Scan(&table, &policy.Name, &permissive, &roles, &policy.Command, &policy.Using, &policy.WithCheck); err != nil {
+			return nil, fmt.Errorf("failed to scan policy: %w", err)
+		}
+		policy.Restrictive = permissive == "RESTRICTIVE"
+		if roles != "public" {
+			policy.Roles = strings.Split(roles, ",")
+		}
+		if security, ok := tables[table]; ok {
+			security.policies[policy.Name] = policy
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read policies: %w", err)
+	}
+	return tables, nil
+}
+
+// diffRowSecurity returns the changes bringing the row level security of the
+// tables in target to what target declares. Tables only in current are left
+// to Atlas, which drops them with their policies.
+func diffRowSecurity(current, target map[string]*tableSecurity) []*PolicyChange {
+	var changes []*PolicyChange
+	sqlGenerator := generator.NewSQLGenerator()
+
+	names := make([]string, 0, len(target))
+	for name := range target {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := target[name]
+		have := current[name]
+		if have == nil {
+			have = &tableSecurity{policies: map[string]generator.SchemaPolicy{}}
+		}
+		table := dialect.QuoteIdentifierIfNeeded(name)
+
+		if want.enabled && !have.enabled {
+			changes = append(changes, &PolicyChange{
+				Table:   name,
+				Cmd:     fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", table),
+				Comment: fmt.Sprintf("Enable row level security on %s", name),
+			})
+		}
+
+		for _, policyName := range sortedPolicyNames(have.policies) {
+			existing := have.policies[policyName]
+			declared, kept := want.policies[policyName]
+			if kept && samePolicy(existing, declared) {
+				continue
+			}
+			changes = append(changes, &PolicyChange{
+				Table:       name,
+				Cmd:         fmt.Sprintf("DROP POLICY %s ON %s", dialect.QuoteIdentifierIfNeeded(policyName), table),
+				Comment:     fmt.Sprintf("Drop policy %s on %s", policyName, name),
+				Destructive: !kept,
+			})
+		}
+
+		for _, policyName := range sortedPolicyNames(want.policies) {
+			declared := want.policies[policyName]
+			if existing, exists := have.policies[policyName]; exists && samePolicy(existing, declared) {
+				continue
+			}
+			changes = append(changes, &PolicyChange{
+				Table:   name,
+				Cmd:     strings.TrimSuffix(sqlGenerator.GeneratePolicyDDL(name, declared), ";"),
+				Comment: fmt.Sprintf("Create policy %s on %s", policyName, name),
+			})
+		}
+
+		if !want.enabled && have.enabled {
+			changes = append(changes, &PolicyChange{
+				Table:       name,
+				Cmd:         fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", table),
+				Comment:     fmt.Sprintf("Disable row level security on %s", name),
+				Destructive: true,
+			})
+		}
+	}
+	return changes
+}
+
+func samePolicy(a, b generator.SchemaPolicy) bool {
+	return a.Name == b.Name && a.Restrictive == b.Restrictive && a.Command == b.Command &&
+		strings.Join(a.Roles, ",") == strings.Join(b.Roles, ",") && a.Using == b.Using && a.WithCheck == b.WithCheck
+}
+
+func sortedPolicyNames(policies map[string]generator.SchemaPolicy) []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
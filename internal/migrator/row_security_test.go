@@ -0,0 +1,83 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+func TestDiffRowSecurity(t *testing.T) {
+	tenant := generator.SchemaPolicy{Name: "tenant_isolation", Command: "ALL", Using: "(tenant_id = (current_setting('app.tenant'::text))::uuid)"}
+	owner := generator.SchemaPolicy{Name: "owner_write", Command: "UPDATE", Roles: []string{"app_user"}, Using: "(owner_id = CURRENT_USER)"}
+
+	security := func(enabled bool, policies ...generator.SchemaPolicy) *tableSecurity {
+		s := &tableSecurity{enabled: enabled, policies: map[string]generator.SchemaPolicy{}}
+		for _, p := range policies {
+			s.policies[p.Name] = p
+		}
+		return s
+	}
+	commands := func(changes []*PolicyChange) []string {
+		var cmds []string
+		for _, c := range changes {
+			cmds = append(cmds, c.Cmd)
+		}
+		return cmds
+	}
+
+	t.Run("enables security and creates policies on new tables", func(t *testing.T) {
+		changes := diffRowSecurity(map[string]*tableSecurity{}, map[string]*tableSecurity{"documents": security(true, tenant, owner)})
+		expected := []string{
+			"ALTER TABLE documents ENABLE ROW LEVEL SECURITY",
+			"CREATE POLICY owner_write ON documents FOR UPDATE TO app_user USING (owner_id = CURRENT_USER)",
+			"CREATE POLICY tenant_isolation ON documents USING (tenant_id = (current_setting('app.tenant'::text))::uuid)",
+		}
+		if got := commands(changes); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+		for _, c := range changes {
+			if IsDestructiveChange(c) {
+				t.Errorf("expected %q not to be destructive", c.Cmd)
+			}
+		}
+	})
+
+	t.Run("reports nothing when the policies match", func(t *testing.T) {
+		current := map[string]*tableSecurity{"documents": security(true, tenant)}
+		target := map[string]*tableSecurity{"documents": security(true, tenant), "users": security(false)}
+		if changes := diffRowSecurity(current, target); len(changes) != 0 {
+			t.Errorf("expected no changes, got %v", commands(changes))
+		}
+	})
+
+	t.Run("replaces changed policies and drops removed ones", func(t *testing.T) {
+		changed := owner
+		changed.Roles = nil
+		current := map[string]*tableSecurity{"documents": security(true, tenant, owner)}
+		target := map[string]*tableSecurity{"documents": security(true, changed)}
+
+		changes := diffRowSecurity(current, target)
+		expected := []string{
+			"DROP POLICY owner_write ON documents",
+			"DROP POLICY tenant_isolation ON documents",
+			"CREATE POLICY owner_write ON documents FOR UPDATE USING (owner_id = CURRENT_USER)",
+		}
+		if got := commands(changes); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+		if changes[0].Destructive || !changes[1].Destructive {
+			t.Error("expected only the removed policy to be destructive")
+		}
+		if DescribeChange(changes[1]) != "Drop policy tenant_isolation on documents" {
+			t.Errorf("unexpected description %q", DescribeChange(changes[1]))
+		}
+	})
+
+	t.Run("disables security no longer declared", func(t *testing.T) {
+		changes := diffRowSecurity(map[string]*tableSecurity{"documents": security(true)}, map[string]*tableSecurity{"documents": security(false)})
+		if len(changes) != 1 || changes[0].Cmd != "ALTER TABLE documents DISABLE ROW LEVEL SECURITY" || !IsDestructiveChange(changes[0]) {
+			t.Errorf("expected a destructive DISABLE, got %v", commands(changes))
+		}
+	})
+}
@@ -146,6 +146,11 @@ type TestProfile struct {
 		"storm.NumericColumn",
 		"storm.BoolColumn",
 		"storm.TimeColumn",
+		"type TestUserColumns struct",
+		`var TestUsers = newTestUserColumns("test_users")`,
+		"func (TestUserColumns) As(alias string) TestUserColumns",
+		"func (c TestUserColumns) Table() string",
+		"Table: table",
 	}
 
 	for _, expected := range expectedContent {
@@ -199,6 +204,29 @@ type TestProfile struct {
 		}
 	}
 
+	stormContent, err := os.ReadFile(filepath.Join(outputDir, "storm.go"))
+	if err != nil {
+		t.Fatalf("Failed to read storm.go: %v", err)
+	}
+
+	expectedStormContent := []string{
+		"func PreloadMetadata() error {",
+		"return storm.PreloadMetadata(",
+		"TestPostMetadata,",
+		"TestProfileMetadata,",
+		"TestUserMetadata,",
+		"func NewRepositories(db *sqlx.DB) (*Repositories, error) {",
+		"func (r *Repositories) TestUsers() *TestUserRepository {",
+		"func (s *Storm) Repositories() *Repositories {",
+		"func NewStormWithOptions(db *sqlx.DB, options ...storm.Option) *Storm {",
+	}
+
+	for _, expected := range expectedStormContent {
+		if !containsString(string(stormContent), expected) {
+			t.Errorf("Generated storm.go missing expected content: %s", expected)
+		}
+	}
+
 	t.Logf("Code generation test passed! Files created in: %s", outputDir)
 }
 
@@ -0,0 +1,166 @@
+package orm_generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// headerTemplateName is the custom template whose output is prepended to every generated file
+const headerTemplateName = "header"
+
+// builtinTemplates maps template names to their default source. A file named
+// <name>.tmpl in the templates directory replaces the template of that name.
+var builtinTemplates = map[string]string{
+	"metadata":      metadataTemplate,
+	"columns":       columnTemplate,
+	"repository":    repositoryTemplate,
+	"relationships": relationshipsTemplate,
+	"storm":         stormTemplate,
+	"mock":          mockTemplate,
+	"factory":       factoryTemplate,
+	"handler":       handlerTemplate,
+	"dto":           dtoTemplate,
+	"validate":      validateTemplate,
+	"queries":       queriesTemplate,
+}
+
+// templateFuncs returns the helpers available to built-in and custom templates:
+//
+//	lower, upper, title      change the case of a string
+//	camel, pascal, snake     convert identifiers: camel "user_id" = "userId", pascal "user_id" = "UserId", snake "UserID" = "user_id"
+//	plural, singular         inflect English nouns: plural "category" = "categories"
+//	goType                   map a PostgreSQL type to a Go type: goType "bigint" = "int64"
+//	dbType                   map a Go type to a PostgreSQL type: dbType "int64" = "BIGINT"
+//	join                     join a []string with a separator: join .Model.PrimaryKeys ", "
+//	hasPrefix, hasSuffix     report whether a string starts or ends with another
+//	contains                 report whether a string contains another
+//	replace                  replace every occurrence: replace .Name "ID" "Id"
+//	trim                     remove leading and trailing whitespace
+//	quote                    quote a string as a Go literal
+//	now                      return the current time
+//	sanitizeGoName           turn an arbitrary string into a valid Go identifier
+func (g *CodeGenerator) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":          strings.ToLower,
+		"upper":          strings.ToUpper,
+		"title":          strings.Title,
+		"camel":          toCamelCase,
+		"pascal":         toPascalCase,
+		"snake":          toSnakeCase,
+		"plural":         pluralize,
+		"singular":       singularize,
+		"goType":         g.mapDBTypeToGo,
+		"dbType":         g.mapGoTypeToPostgreSQL,
+		"join":           strings.Join,
+		"hasPrefix":      strings.HasPrefix,
+		"hasSuffix":      strings.HasSuffix,
+		"contains":       strings.Contains,
+		"replace":        strings.ReplaceAll,
+		"trim":           strings.TrimSpace,
+		"quote":          strconv.Quote,
+		"now":            time.Now,
+		"sanitizeGoName": sanitizeGoName,
+	}
+}
+
+// loadCustomTemplates parses every <name>.tmpl file in the templates directory.
+// Built-in names override the default template, "header" sets the file header
+// and any other name is an extra template rendered once per model.
+func (g *CodeGenerator) loadCustomTemplates(funcMap template.FuncMap) error {
+	g.extraTemplates = nil
+	if g.templateDir == "" {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(g.templateDir, "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("failed to list templates in %s: %w", g.templateDir, err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", file, err)
+		}
+
+		tmpl, err := template.New(name).Funcs(funcMap).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", file, err)
+		}
+
+		g.templates[name] = tmpl
+		if _, builtin := builtinTemplates[name]; !builtin && name != headerTemplateName {
+			g.extraTemplates = append(g.extraTemplates, name)
+		}
+	}
+	return nil
+}
+
+// generateCustomTemplates renders each extra template for every model into <model>_<template>.go
+func (g *CodeGenerator) generateCustomTemplates() error {
+	for _, name := range g.extraTemplates {
+		for _, model := range g.sortedModels() {
+			data := struct {
+				Package string
+				Model   *ModelMetadata
+				Now     time.Time
+			}{
+				Package: g.packageName,
+				Model:   model,
+				Now:     time.Now(),
+			}
+
+			filename := fmt.Sprintf("%s_%s.go", toSnakeCase(model.Name), toSnakeCase(name))
+			if err := g.executeTemplate(name, filename, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fileHeaderFor returns the comment block placed at the top of filename, from
+// the header template when one exists and otherwise from the configured text
+func (g *CodeGenerator) fileHeaderFor(filename string) (string, error) {
+	header := g.fileHeader
+	if tmpl, ok := g.templates[headerTemplateName]; ok {
+		data := struct {
+			Package string
+			File    string
+			Now     time.Time
+		}{
+			Package: g.packageName,
+			File:    filename,
+			Now:     time.Now(),
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to execute template %s: %w", headerTemplateName, err)
+		}
+		header = buf.String()
+	}
+
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", nil
+	}
+
+	lines := strings.Split(header, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines[i] = strings.TrimRight("// "+line, " ")
+		}
+	}
+	return strings.Join(lines, "\n") + "\n\n", nil
+}
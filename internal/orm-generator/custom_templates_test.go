@@ -0,0 +1,89 @@
+package orm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTemplateTestGenerator(t *testing.T, config GenerationConfig) *CodeGenerator {
+	config.PackageName = "testmodels"
+	config.OutputDir = t.TempDir()
+
+	generator := NewCodeGenerator(config)
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": "", "type": "bigserial"}},
+			{Name: "Title", DBName: "title", Type: "string", DBDef: map[string]string{"type": "text"}},
+		},
+	})
+	generator.models[post.Name] = post
+	return generator
+}
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestCustomTemplates(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplate(t, templateDir, "header.tmpl", "Copyright Acme Corp.\nFile: {{ .File }}")
+	writeTemplate(t, templateDir, "columns.tmpl", "package {{ .Package }}\n\n// Columns are disabled\n")
+	writeTemplate(t, templateDir, "audit.tmpl", `package {{ .Package }}
+
+// AuditTable returns the audit log table for {{ .Model.Name }}
+func ({{ .Model.Name }}) AuditTable() string {
+	return {{ quote (printf "%s_audit" .Model.TableName) }}
+}
+`)
+
+	generator := newTemplateTestGenerator(t, GenerationConfig{TemplateDir: templateDir})
+	assert.NoError(t, generator.GenerateAll())
+
+	columns, err := os.ReadFile(filepath.Join(generator.outputDir, "columns.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(columns), "// Columns are disabled")
+	assert.NotContains(t, string(columns), "PostColumns")
+
+	audit, err := os.ReadFile(filepath.Join(generator.outputDir, "post_audit.go"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(audit), "// Copyright Acme Corp.\n// File: post_audit.go\n\npackage testmodels"))
+	assert.Contains(t, string(audit), `return "posts_audit"`)
+
+	repository, err := os.ReadFile(filepath.Join(generator.outputDir, "post_repository.go"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(repository), "// Copyright Acme Corp.\n// File: post_repository.go\n\n//go:build !exclude_generated"))
+}
+
+func TestCustomTemplatesErrors(t *testing.T) {
+	t.Run("invalid template", func(t *testing.T) {
+		templateDir := t.TempDir()
+		writeTemplate(t, templateDir, "broken.tmpl", "package {{ .Package ")
+
+		generator := newTemplateTestGenerator(t, GenerationConfig{TemplateDir: templateDir})
+		assert.ErrorContains(t, generator.GenerateAll(), "broken.tmpl")
+	})
+
+	t.Run("output that is not valid Go", func(t *testing.T) {
+		templateDir := t.TempDir()
+		writeTemplate(t, templateDir, "extra.tmpl", "package {{ .Package }}\n\nfunc {")
+
+		generator := newTemplateTestGenerator(t, GenerationConfig{TemplateDir: templateDir})
+		assert.ErrorContains(t, generator.GenerateAll(), "post_extra.go")
+	})
+}
+
+func TestFileHeader(t *testing.T) {
+	generator := newTemplateTestGenerator(t, GenerationConfig{FileHeader: "Generated for Acme\n// Do not edit"})
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(generator.outputDir, "columns.go"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "// Generated for Acme\n// Do not edit\n\n"))
+}
@@ -0,0 +1,240 @@
+package orm_generator
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DTOField is a field of a generated request or response struct
+type DTOField struct {
+	Name     string // Go field name, shared with the model
+	Type     string // Go type expression
+	JSONName string // JSON key, including options such as omitempty
+	Masked   bool   // Whether responses show the value through storm.Mask
+}
+
+// DTOImport is an import required by the types of DTO fields
+type DTOImport struct {
+	Name string // Explicit import name, empty when it matches the path
+	Path string
+}
+
+var qualifiedType = regexp.MustCompile(`(\w+)\.\w+`)
+
+// ormImportPath is imported as storm by generated files
+const ormImportPath = "github.com/eleven-am/storm/pkg/storm-orm"
+
+func (g *CodeGenerator) generateDTOs() error {
+	for _, model := range g.sortedModels() {
+		requiredImports := make(map[string]bool)
+
+		var request []DTOField
+		if !model.ReadOnly {
+			request = g.buildDTOFields(model, true, requiredImports)
+		}
+		response := g.buildDTOFields(model, false, requiredImports)
+
+		data := struct {
+			Package        string
+			Model          *ModelMetadata
+			RequestFields  []DTOField
+			ResponseFields []DTOField
+			StdImports     []DTOImport
+			Imports        []DTOImport
+		}{
+			Package:        g.packageName,
+			Model:          model,
+			RequestFields:  request,
+			ResponseFields: response,
+		}
+		data.StdImports, data.Imports = g.dtoImports(requiredImports)
+		if hasMaskedField(response) {
+			data.Imports = addDTOImport(data.Imports, DTOImport{Name: "storm", Path: ormImportPath})
+		}
+
+		filename := fmt.Sprintf("%s_dto.go", toSnakeCase(model.Name))
+		if err := g.executeTemplate("dto", filename, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDTOFields returns the columns exposed through the API. Columns marked
+// json_ignore (or json:"-") are never exposed; request structs additionally
+// leave out primary keys, auto-generated, timestamp and computed columns since
+// the database owns them, and immutable columns since clients may not set them.
+func (g *CodeGenerator) buildDTOFields(model *ModelMetadata, request bool, requiredImports map[string]bool) []DTOField {
+	var fields []DTOField
+	for _, col := range model.Columns {
+		if col.IsJSONIgnored {
+			continue
+		}
+		if request && (col.IsPrimaryKey || col.IsAutoGenerated || col.Computed != "" ||
+			col.IsImmutable || col.AutoCreateTime || col.AutoUpdateTime) {
+			continue
+		}
+
+		fieldType, packages, ok := g.dtoFieldType(col)
+		if !ok {
+			fmt.Printf("Skipping DTO field %s.%s: cannot resolve the package of type %s\n", model.Name, col.Name, col.Type)
+			continue
+		}
+		for _, pkg := range packages {
+			requiredImports[pkg] = true
+		}
+
+		name := jsonName(col.Tags["json"])
+		if name == "" {
+			name = col.DBName
+		}
+		if strings.Contains(col.Tags["json"], ",omitempty") {
+			name += ",omitempty"
+		}
+
+		fields = append(fields, DTOField{
+			Name:     col.Name,
+			Type:     fieldType,
+			JSONName: name,
+			Masked:   !request && col.IsMasked,
+		})
+	}
+	return fields
+}
+
+// dtoFieldType returns the Go type of col along with the package names it
+// references, reporting false when one of them is not imported by the models
+func (g *CodeGenerator) dtoFieldType(col FieldMetadata) (string, []string, bool) {
+	if col.Type == "" {
+		return "", nil, false
+	}
+
+	var packages []string
+	for _, match := range qualifiedType.FindAllStringSubmatch(col.Type, -1) {
+		if _, ok := g.importPath(match[1]); !ok {
+			return "", nil, false
+		}
+		packages = append(packages, match[1])
+	}
+
+	fieldType := col.Type
+	if col.IsPointer {
+		fieldType = "*" + fieldType
+	}
+	if col.IsArray {
+		fieldType = "[]" + fieldType
+	}
+	return fieldType, packages, true
+}
+
+// importPath resolves a package name used in a model field type
+func (g *CodeGenerator) importPath(name string) (string, bool) {
+	if importPath, ok := g.imports[name]; ok {
+		return importPath, true
+	}
+	switch name {
+	case "time":
+		return "time", true
+	case "json":
+		return "encoding/json", true
+	}
+	return "", false
+}
+
+// dtoImports resolves package names into standard library and third-party imports
+func (g *CodeGenerator) dtoImports(names map[string]bool) (std, thirdParty []DTOImport) {
+	for name := range names {
+		importPath, _ := g.importPath(name)
+		imp := DTOImport{Path: importPath}
+		if path.Base(importPath) != name {
+			imp.Name = name
+		}
+
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			thirdParty = append(thirdParty, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+
+	byPath := func(imports []DTOImport) func(i, j int) bool {
+		return func(i, j int) bool { return imports[i].Path < imports[j].Path }
+	}
+	sort.Slice(std, byPath(std))
+	sort.Slice(thirdParty, byPath(thirdParty))
+	return std, thirdParty
+}
+
+func hasMaskedField(fields []DTOField) bool {
+	for _, field := range fields {
+		if field.Masked {
+			return true
+		}
+	}
+	return false
+}
+
+// addDTOImport adds imp to imports, keeping them sorted by path, unless it is
+// already present under the same name
+func addDTOImport(imports []DTOImport, imp DTOImport) []DTOImport {
+	for _, existing := range imports {
+		if existing == imp {
+			return imports
+		}
+	}
+	imports = append(imports, imp)
+	sort.SliceStable(imports, func(i, j int) bool { return imports[i].Path < imports[j].Path })
+	return imports
+}
+
+// jsonName returns the key part of a json struct tag
+func jsonName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// findPackageImports returns the imports of the non-test files in
+// packagePath keyed by the name they are referenced with
+func findPackageImports(packagePath string) map[string]string {
+	imports := make(map[string]string)
+
+	matches, err := filepath.Glob(filepath.Join(packagePath, "*.go"))
+	if err != nil {
+		return imports
+	}
+
+	fileSet := token.NewFileSet()
+	for _, file := range matches {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		src, err := parser.ParseFile(fileSet, file, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+
+		for _, spec := range src.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+
+			name := path.Base(importPath)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name != "_" && name != "." {
+				imports[name] = importPath
+			}
+		}
+	}
+	return imports
+}
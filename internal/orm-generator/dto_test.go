@@ -0,0 +1,72 @@
+package orm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTOGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+		IncludeDTOs: true,
+	})
+	generator.imports["pq"] = "github.com/lib/pq"
+
+	user := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "User",
+		TableName:  "users",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": "", "type": "uuid", "default": "gen_random_uuid()"}},
+			{Name: "Email", DBName: "email", Type: "string", JSONTag: "emailAddress", DBDef: map[string]string{"type": "text"}},
+			{Name: "PasswordHash", DBName: "password_hash", Type: "string", DBDef: map[string]string{"type": "text", "json_ignore": ""}},
+			{Name: "Secret", DBName: "secret", Type: "string", JSONTag: "-", DBDef: map[string]string{"type": "text"}},
+			{Name: "Tags", DBName: "tags", Type: "pq.StringArray", JSONTag: "tags,omitempty", DBDef: map[string]string{"type": "text[]"}},
+			{Name: "Meta", DBName: "meta", Type: "custom.Meta", DBDef: map[string]string{"type": "jsonb"}},
+			{Name: "CreatedAt", DBName: "created_at", Type: "time.Time", DBDef: map[string]string{"type": "timestamptz", "default": "now()"}},
+		},
+	})
+	generator.models[user.Name] = user
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "user_dto.go"))
+	assert.NoError(t, err)
+	generated := string(content)
+
+	assert.Contains(t, generated, "\t\"time\"\n\n\t\"github.com/lib/pq\"\n")
+	assert.Contains(t, generated, "type UserRequest struct {\n\tEmail string         `json:\"emailAddress\"`\n\tTags  pq.StringArray `json:\"tags,omitempty\"`\n}")
+	assert.Contains(t, generated, "\tID        string         `json:\"id\"`")
+	assert.Contains(t, generated, "\tCreatedAt time.Time      `json:\"created_at\"`")
+	assert.Contains(t, generated, "func (r UserRequest) ToModel() *User {")
+	assert.Contains(t, generated, "func (r UserRequest) ApplyTo(m *User) {")
+	assert.Contains(t, generated, "func UserResponseFromModel(m *User) UserResponse {")
+	assert.Contains(t, generated, "func UserResponsesFromModels(models []User) []UserResponse {")
+	assert.NotContains(t, generated, "PasswordHash")
+	assert.NotContains(t, generated, "Secret")
+	assert.NotContains(t, generated, "Meta", "types from unknown packages are skipped")
+
+	t.Run("not generated by default", func(t *testing.T) {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: outputDir})
+		generator.models[user.Name] = user
+		assert.NoError(t, generator.GenerateAll())
+
+		_, err := os.Stat(filepath.Join(outputDir, "user_dto.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestFindPackageImports(t *testing.T) {
+	dir := t.TempDir()
+	src := "package models\n\nimport (\n\t\"time\"\n\n\tsq \"github.com/Masterminds/squirrel\"\n\t_ \"github.com/lib/pq\"\n)\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644))
+
+	imports := findPackageImports(dir)
+	assert.Equal(t, map[string]string{"time": "time", "sq": "github.com/Masterminds/squirrel"}, imports)
+}
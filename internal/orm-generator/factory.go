@@ -0,0 +1,146 @@
+package orm_generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FactoryField describes how a generated factory fills and overrides a column
+type FactoryField struct {
+	Name    string // Go field name
+	Type    string // Go type of the With setter, empty when no typed setter is generated
+	Default string // Go expression for the fake default, empty to keep the zero value
+}
+
+func (g *CodeGenerator) generateFactories() error {
+	for _, model := range g.sortedModels() {
+		fields := buildFactoryFields(model)
+
+		data := struct {
+			Package   string
+			Model     *ModelMetadata
+			Fields    []FactoryField
+			UsesSeq   bool
+			NeedsFmt  bool
+			NeedsTime bool
+		}{
+			Package: g.packageName,
+			Model:   model,
+			Fields:  fields,
+		}
+
+		for _, field := range fields {
+			data.UsesSeq = data.UsesSeq || strings.Contains(field.Default, "seq")
+			data.NeedsFmt = data.NeedsFmt || strings.Contains(field.Default, "fmt.")
+			data.NeedsTime = data.NeedsTime || strings.Contains(field.Default, "time.") || strings.Contains(field.Type, "time.")
+		}
+
+		filename := fmt.Sprintf("%s_factory.go", toSnakeCase(model.Name))
+		if err := g.executeTemplate("factory", filename, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildFactoryFields derives a setter and a fake default for every column.
+// Auto-generated, computed and foreign key columns keep their zero value so the
+// database or the caller provides them.
+func buildFactoryFields(model *ModelMetadata) []FactoryField {
+	foreignKeys := make(map[string]bool)
+	for _, rel := range model.Relationships {
+		if rel.Relationship != nil && rel.Relationship.Type == "belongs_to" {
+			foreignKeys[rel.Relationship.ForeignKey] = true
+		}
+	}
+
+	fields := make([]FactoryField, 0, len(model.Columns))
+	for _, col := range model.Columns {
+		field := FactoryField{
+			Name: col.Name,
+			Type: factoryFieldType(col),
+		}
+		if !col.IsAutoGenerated && col.Computed == "" && !foreignKeys[col.DBName] {
+			field.Default = factoryDefault(col)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// factoryFieldType returns the Go type of a column setter. Types from packages
+// other than time are skipped since the factory file does not import them.
+func factoryFieldType(col FieldMetadata) string {
+	if col.Type == "" || (strings.Contains(col.Type, ".") && !strings.HasPrefix(col.Type, "time.")) {
+		return ""
+	}
+
+	fieldType := col.Type
+	if col.IsPointer {
+		fieldType = "*" + fieldType
+	}
+	if col.IsArray {
+		fieldType = "[]" + fieldType
+	}
+	return fieldType
+}
+
+// factoryDefault returns a Go expression producing a fake value for col, using
+// the per-factory sequence number seq to keep unique columns distinct
+func factoryDefault(col FieldMetadata) string {
+	if col.IsPointer || col.IsArray {
+		return ""
+	}
+
+	dbType := strings.ToLower(col.DBDef["type"])
+	if dbType == "" {
+		dbType = strings.ToLower(col.DBType)
+	}
+	if strings.Contains(dbType, "serial") {
+		return ""
+	}
+
+	switch col.Type {
+	case "string":
+		if enum := col.DBDef["enum"]; enum != "" {
+			return strconv.Quote(strings.TrimSpace(strings.Split(enum, ",")[0]))
+		}
+		if strings.Contains(dbType, "uuid") {
+			return `fmt.Sprintf("00000000-0000-4000-8000-%012d", seq)`
+		}
+		if strings.Contains(col.DBName, "email") {
+			return `fmt.Sprintf("user%d@example.com", seq)`
+		}
+		prefix := col.DBName + "-"
+		if limit := varcharLength(dbType); limit > 0 && limit < len(prefix)+10 {
+			return "fmt.Sprint(seq)"
+		}
+		return fmt.Sprintf("fmt.Sprintf(%q, seq)", prefix+"%d")
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return col.Type + "(seq)"
+	case "bool":
+		if col.DefaultValue == "true" {
+			return "true"
+		}
+	case "time.Time":
+		return "time.Now()"
+	}
+	return ""
+}
+
+// varcharLength returns n for varchar(n) and char(n) types, or 0
+func varcharLength(dbType string) int {
+	open := strings.Index(dbType, "(")
+	if open == -1 || !strings.HasSuffix(dbType, ")") {
+		return 0
+	}
+	if base := dbType[:open]; base != "varchar" && base != "char" && base != "character varying" {
+		return 0
+	}
+	n, err := strconv.Atoi(dbType[open+1 : len(dbType)-1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,79 @@
+package orm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactoryDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		col      FieldMetadata
+		expected string
+	}{
+		{"email", FieldMetadata{DBName: "email", Type: "string"}, `fmt.Sprintf("user%d@example.com", seq)`},
+		{"uuid", FieldMetadata{DBName: "token", Type: "string", DBDef: map[string]string{"type": "uuid"}}, `fmt.Sprintf("00000000-0000-4000-8000-%012d", seq)`},
+		{"enum", FieldMetadata{DBName: "role", Type: "string", DBDef: map[string]string{"enum": "admin,member"}}, `"admin"`},
+		{"short varchar", FieldMetadata{DBName: "code", Type: "string", DBDef: map[string]string{"type": "varchar(8)"}}, "fmt.Sprint(seq)"},
+		{"text", FieldMetadata{DBName: "title", Type: "string"}, `fmt.Sprintf("title-%d", seq)`},
+		{"integer", FieldMetadata{DBName: "count", Type: "int32"}, "int32(seq)"},
+		{"serial", FieldMetadata{DBName: "id", Type: "int64", DBDef: map[string]string{"type": "serial"}}, ""},
+		{"bool default true", FieldMetadata{DBName: "active", Type: "bool", DefaultValue: "true"}, "true"},
+		{"bool", FieldMetadata{DBName: "archived", Type: "bool"}, ""},
+		{"time", FieldMetadata{DBName: "starts_at", Type: "time.Time"}, "time.Now()"},
+		{"pointer", FieldMetadata{DBName: "bio", Type: "string", IsPointer: true}, ""},
+		{"json", FieldMetadata{DBName: "data", Type: "json.RawMessage"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, factoryDefault(tt.col))
+		})
+	}
+}
+
+func TestFactoryGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName:  "testmodels",
+		OutputDir:    outputDir,
+		IncludeTests: true,
+	})
+
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": "", "type": "uuid", "default": "gen_random_uuid()"}},
+			{Name: "Title", DBName: "title", Type: "string", DBDef: map[string]string{"type": "text"}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", DBDef: map[string]string{"type": "uuid"}},
+			{Name: "Author", Type: "Author", IsPointer: true, IsRelationship: true, StormTag: "relation:belongs_to:Author;foreign_key:author_id"},
+		},
+	})
+	generator.models[post.Name] = post
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "post_factory.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `f.record.Title = fmt.Sprintf("title-%d", seq)`)
+	assert.Equal(t, 1, strings.Count(string(content), "f.record.AuthorID = "), "only the setter assigns AuthorID")
+	assert.Equal(t, 1, strings.Count(string(content), "f.record.ID = "), "only the setter assigns ID")
+	assert.Contains(t, string(content), "func (f *PostFactory) WithAuthorID(value string) *PostFactory {")
+	assert.Contains(t, string(content), "func (f *PostFactory) Create(ctx context.Context, repo interface {")
+
+	t.Run("not generated by default", func(t *testing.T) {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: outputDir})
+		generator.models[post.Name] = post
+		assert.NoError(t, generator.GenerateAll())
+
+		_, err := os.Stat(filepath.Join(outputDir, "post_factory.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -11,39 +11,73 @@ import (
 	"sort"
 	"strings"
 	"text/template"
-	"time"
 
 	stormParser "github.com/eleven-am/storm/internal/parser"
 )
 
 // CodeGenerator handles generation of type-safe ORM code
 type CodeGenerator struct {
-	tagParser   *ORMTagParser
-	packageName string
-	outputDir   string
-	templates   map[string]*template.Template
-	models      map[string]*ModelMetadata
+	tagParser       *ORMTagParser
+	packageName     string
+	outputDir       string
+	includeMocks    bool
+	includeTests    bool
+	includeHandlers bool
+	includeDTOs     bool
+	templateDir     string
+	fileHeader      string
+	database        string // Named database target, see GenerationConfig.Database
+	naming          *stormParser.Naming
+	templates       map[string]*template.Template
+	extraTemplates  []string // Custom templates rendered once per model
+	models          map[string]*ModelMetadata
+
+	customValidators map[string]bool   // Models with a hand-written Validate method
+	imports          map[string]string // Import paths of the models package keyed by name
+	queries          []NamedQuery      // Named queries of the models package's queries directory
 }
 
 // GenerationConfig configures code generation
 type GenerationConfig struct {
-	PackageName  string   // Package name for generated code
-	OutputDir    string   // Output directory
-	Models       []string // Model names to generate (empty = all)
-	Features     []string // Features to generate (columns, repositories, etc.)
-	TemplateDir  string   // Custom template directory
-	FileHeader   string   // Custom file header
-	IncludeTests bool     // Whether to generate tests
-	IncludeDocs  bool     // Whether to generate documentation
+	PackageName     string   // Package name for generated code
+	OutputDir       string   // Output directory
+	Models          []string // Model names to generate (empty = all)
+	Features        []string // Features to generate (columns, repositories, etc.)
+	TemplateDir     string   // Directory of <name>.tmpl files overriding or extending the built-in templates
+	FileHeader      string   // Comment prepended to every generated file, unless header.tmpl exists
+	IncludeTests    bool     // Whether to generate tests
+	IncludeMocks    bool     // Whether to generate repository interfaces and mocks
+	IncludeHandlers bool     // Whether to generate CRUD HTTP handlers
+	IncludeDTOs     bool     // Whether to generate request/response DTOs
+	IncludeDocs     bool     // Whether to generate documentation
+	Database        string   // Named database target of the models, adds a New<Database>Storm accessor
+
+	Naming *stormParser.Naming // Table and column naming of the models, snake_case when nil
 }
 
 func NewCodeGenerator(config GenerationConfig) *CodeGenerator {
+	tagParser := NewORMTagParser()
+	if config.Naming != nil {
+		tagParser.stormParser = stormParser.NewStormTagParserWithNaming(config.Naming)
+	}
+
 	return &CodeGenerator{
-		tagParser:   NewORMTagParser(),
-		packageName: config.PackageName,
-		outputDir:   config.OutputDir,
-		templates:   make(map[string]*template.Template),
-		models:      make(map[string]*ModelMetadata),
+		tagParser:       tagParser,
+		packageName:     config.PackageName,
+		outputDir:       config.OutputDir,
+		includeMocks:    config.IncludeMocks,
+		includeTests:    config.IncludeTests,
+		includeHandlers: config.IncludeHandlers,
+		includeDTOs:     config.IncludeDTOs,
+		templateDir:     config.TemplateDir,
+		fileHeader:      config.FileHeader,
+		database:        config.Database,
+		naming:          config.Naming,
+		templates:       make(map[string]*template.Template),
+		models:          make(map[string]*ModelMetadata),
+
+		customValidators: make(map[string]bool),
+		imports:          make(map[string]string),
 	}
 }
 
@@ -56,7 +90,7 @@ func (g *CodeGenerator) DiscoverModels(packagePath string) error {
 		g.packageName = packageName
 	}
 
-	structParser := stormParser.NewStructParser()
+	structParser := stormParser.NewStructParserWithNaming(g.naming)
 	tables, err := structParser.ParseDirectory(packagePath)
 	if err != nil {
 		return fmt.Errorf("failed to parse directory %s: %w", packagePath, err)
@@ -64,7 +98,9 @@ func (g *CodeGenerator) DiscoverModels(packagePath string) error {
 
 	var dbModels []stormParser.TableDefinition
 	for _, table := range tables {
-		if _, hasExplicitTable := table.TableLevel["table"]; hasExplicitTable {
+		_, hasExplicitTable := table.TableLevel["table"]
+		_, readOnly := table.TableLevel["readonly"]
+		if hasExplicitTable || readOnly {
 			dbModels = append(dbModels, table)
 		}
 	}
@@ -79,6 +115,18 @@ func (g *CodeGenerator) DiscoverModels(packagePath string) error {
 		g.models[metadata.Name] = metadata
 	}
 
+	for name := range findCustomValidators(packagePath) {
+		g.customValidators[name] = true
+	}
+
+	for name, path := range findPackageImports(packagePath) {
+		g.imports[name] = path
+	}
+
+	if err := g.loadQueries(filepath.Join(packagePath, QueriesDir)); err != nil {
+		return fmt.Errorf("failed to load queries: %w", err)
+	}
+
 	return nil
 }
 
@@ -97,6 +145,8 @@ func (g *CodeGenerator) convertTableDefinitionToModelMetadata(tableDef stormPars
 			Name:   field.Name,
 			DBName: field.DBName,
 			Type:   field.Type,
+			DBDef:  field.DBDef,
+			Tags:   map[string]string{"json": field.JSONTag},
 		}
 
 		fieldMeta.IsPointer = field.IsPointer
@@ -122,6 +172,10 @@ func (g *CodeGenerator) convertTableDefinitionToModelMetadata(tableDef stormPars
 			}
 		}
 
+		if field.DBName == "-" {
+			continue
+		}
+
 		if _, isPK := field.DBDef["primary_key"]; isPK {
 			fieldMeta.IsPrimaryKey = true
 			metadata.PrimaryKeys = append(metadata.PrimaryKeys, field.DBName)
@@ -131,6 +185,32 @@ func (g *CodeGenerator) convertTableDefinitionToModelMetadata(tableDef stormPars
 			fieldMeta.IsUnique = true
 		}
 
+		if _, isImmutable := field.DBDef["immutable"]; isImmutable {
+			fieldMeta.IsImmutable = true
+		}
+
+		if _, isIgnored := field.DBDef["json_ignore"]; isIgnored || jsonName(field.JSONTag) == "-" {
+			fieldMeta.IsJSONIgnored = true
+		}
+
+		if mode, isEncrypted := field.DBDef["encrypted"]; isEncrypted {
+			fieldMeta.Encrypted = encryptionMode(mode)
+		}
+
+		if _, isMasked := field.DBDef["masked"]; isMasked {
+			fieldMeta.IsMasked = true
+		}
+
+		if _, isSensitive := field.DBDef["sensitive"]; isSensitive {
+			fieldMeta.IsSensitive = true
+		}
+
+		applyAutoTimestamps(&fieldMeta, field.DBDef)
+
+		if computed, isComputed := field.DBDef["computed"]; isComputed {
+			fieldMeta.Computed = computed
+		}
+
 		if defaultVal, hasDefault := field.DBDef["default"]; hasDefault {
 			fieldMeta.DefaultValue = defaultVal
 			if isAutoGeneratedDefault(defaultVal) || field.DBDef["type"] == "serial" {
@@ -141,10 +221,20 @@ func (g *CodeGenerator) convertTableDefinitionToModelMetadata(tableDef stormPars
 		if dbType, hasType := field.DBDef["type"]; hasType {
 			fieldMeta.DBType = dbType
 		}
+		fieldMeta.Interval = field.Type == "time.Duration" && !field.IsArray &&
+			(fieldMeta.DBType == "" || strings.EqualFold(fieldMeta.DBType, "interval"))
 
 		metadata.Columns = append(metadata.Columns, fieldMeta)
 	}
 
+	linkCountFields(metadata, tableDef.Fields)
+	metadata.Scopes = parseScopes(tableDef.TableLevel)
+	metadata.Indexes = parseIndexes(tableDef.TableLevel)
+	metadata.Finders = buildFinders(metadata)
+	metadata.Keysets = buildKeysets(metadata)
+	metadata.ShardKey = tableDef.TableLevel["shard_key"]
+	applyReadModel(metadata, tableDef.TableLevel)
+
 	return metadata
 }
 
@@ -230,41 +320,57 @@ func (g *CodeGenerator) GenerateAll() error {
 		return fmt.Errorf("failed to generate Storm: %w", err)
 	}
 
+	if err := g.generateQueries(); err != nil {
+		return fmt.Errorf("failed to generate queries: %w", err)
+	}
+
+	if err := g.generateValidators(); err != nil {
+		return fmt.Errorf("failed to generate validators: %w", err)
+	}
+
+	if g.includeMocks {
+		if err := g.generateMocks(); err != nil {
+			return fmt.Errorf("failed to generate mocks: %w", err)
+		}
+	}
+
+	if g.includeTests {
+		if err := g.generateFactories(); err != nil {
+			return fmt.Errorf("failed to generate factories: %w", err)
+		}
+	}
+
+	if g.includeHandlers {
+		if err := g.generateHandlers(); err != nil {
+			return fmt.Errorf("failed to generate HTTP handlers: %w", err)
+		}
+	}
+
+	if g.includeDTOs {
+		if err := g.generateDTOs(); err != nil {
+			return fmt.Errorf("failed to generate DTOs: %w", err)
+		}
+	}
+
+	if err := g.generateCustomTemplates(); err != nil {
+		return fmt.Errorf("failed to generate custom templates: %w", err)
+	}
+
 	return nil
 }
 
 func (g *CodeGenerator) loadTemplates() error {
-	funcMap := template.FuncMap{
-		"lower":          strings.ToLower,
-		"upper":          strings.ToUpper,
-		"title":          strings.Title,
-		"camel":          toCamelCase,
-		"pascal":         toPascalCase,
-		"snake":          toSnakeCase,
-		"plural":         pluralize,
-		"singular":       singularize,
-		"goType":         g.mapDBTypeToGo,
-		"dbType":         g.mapGoTypeToPostgreSQL,
-		"join":           strings.Join,
-		"hasPrefix":      strings.HasPrefix,
-		"hasSuffix":      strings.HasSuffix,
-		"contains":       strings.Contains,
-		"replace":        strings.ReplaceAll,
-		"now":            time.Now,
-		"sanitizeGoName": sanitizeGoName,
-	}
-
-	g.templates["metadata"] = template.Must(template.New("metadata").Funcs(funcMap).Parse(metadataTemplate))
-	g.templates["columns"] = template.Must(template.New("columns").Funcs(funcMap).Parse(columnTemplate))
-	g.templates["repository"] = template.Must(template.New("repository").Funcs(funcMap).Parse(repositoryTemplate))
-	g.templates["relationships"] = template.Must(template.New("relationships").Funcs(funcMap).Parse(relationshipsTemplate))
-	g.templates["storm"] = template.Must(template.New("storm").Funcs(funcMap).Parse(stormTemplate))
+	funcMap := g.templateFuncs()
 
-	return nil
+	for name, source := range builtinTemplates {
+		g.templates[name] = template.Must(template.New(name).Funcs(funcMap).Parse(source))
+	}
+
+	return g.loadCustomTemplates(funcMap)
 }
 
 func (g *CodeGenerator) generateMetadata() error {
-	for _, model := range g.models {
+	for _, model := range g.sortedModels() {
 		hasTimeFields := false
 		for _, col := range model.Columns {
 			if col.Type == "time.Time" {
@@ -278,18 +384,54 @@ func (g *CodeGenerator) generateMetadata() error {
 			modelTableMap[name] = m.TableName
 		}
 
+		// Autosave and dependent destroy walk the metadata of their targets
+		var linked []FieldMetadata
+		for _, rel := range model.Relationships {
+			if rel.Relationship != nil && (rel.Relationship.Autosave || rel.Relationship.Dependent == "destroy") {
+				linked = append(linked, rel)
+			}
+		}
+
+		// Loads and counts of a relationship apply its target's default scopes
+		targetScopes := make(map[string][]string)
+		for _, rel := range model.Relationships {
+			if rel.Relationship == nil || g.models[rel.Relationship.Target] == nil {
+				continue
+			}
+			for _, scope := range g.models[rel.Relationship.Target].Scopes {
+				if scope.IsDefault {
+					targetScopes[rel.Name] = append(targetScopes[rel.Name], scope.Condition)
+				}
+			}
+		}
+
+		loads := make(map[string]*RelationshipKeys)
+		formatsKeys := false
+		for _, rel := range model.Relationships {
+			if keys := g.relationshipKeys(model, rel); keys != nil {
+				loads[rel.Name] = keys
+				formatsKeys = formatsKeys || keys.OwnerText
+			}
+		}
+
 		data := struct {
 			Package       string
 			Model         *ModelMetadata
 			HasTimeFields bool
-			Now           time.Time
 			ModelTableMap map[string]string
+			Linked        []FieldMetadata
+			TargetScopes  map[string][]string
+			Loads         map[string]*RelationshipKeys
+			FormatsKeys   bool
 		}{
 			Package:       g.packageName,
 			Model:         model,
 			HasTimeFields: hasTimeFields,
-			Now:           time.Now(),
 			ModelTableMap: modelTableMap,
+			Linked:        linked,
+			TargetScopes:  targetScopes,
+			Loads:         loads,
+			FormatsKeys:   formatsKeys,
 		}
 
 		filename := fmt.Sprintf("%s_metadata.go", strings.ToLower(model.Name))
@@ -304,26 +446,30 @@ func (g *CodeGenerator) generateColumnConstants() error {
 	data := struct {
 		Package string
 		Models  map[string]*ModelMetadata
-		Now     time.Time
 	}{
 		Package: g.packageName,
 		Models:  g.models,
-		Now:     time.Now(),
 	}
 
 	return g.executeTemplate("columns", "columns.go", data)
 }
 
 func (g *CodeGenerator) generateRepositories() error {
-	for _, model := range g.models {
+	for _, model := range g.sortedModels() {
+		key := treeKey(model)
 		data := struct {
 			Package string
 			Model   *ModelMetadata
-			Now     time.Time
+			Imports []DTOImport
+			TreeKey string
 		}{
 			Package: g.packageName,
 			Model:   model,
-			Now:     time.Now(),
+			Imports: g.paramImports(append(finderParams(model), append(keysetParams(model), key)...)...),
+			TreeKey: "interface{}",
+		}
+		if len(key) > 0 {
+			data.TreeKey = key[0].Type
 		}
 
 		filename := fmt.Sprintf("%s_repository.go", toSnakeCase(model.Name))
@@ -334,15 +480,113 @@ func (g *CodeGenerator) generateRepositories() error {
 	return nil
 }
 
+// paramImports returns the imports that the finder and keyset arguments in
+// paramLists need besides storm, which generated repositories always import.
+// Arguments whose type names a package the models package does not import
+// fall back to interface{}.
+func (g *CodeGenerator) paramImports(paramLists ...[]FinderParam) []DTOImport {
+	packages := make(map[string]bool)
+	for _, params := range paramLists {
+		for i, param := range params {
+			var names []string
+			for _, match := range qualifiedType.FindAllStringSubmatch(param.Type, -1) {
+				if match[1] == "storm" {
+					continue
+				}
+				if _, ok := g.importPath(match[1]); !ok {
+					params[i].Type = "interface{}"
+					names = nil
+					break
+				}
+				names = append(names, match[1])
+			}
+			for _, name := range names {
+				packages[name] = true
+			}
+		}
+	}
+
+	std, thirdParty := g.dtoImports(packages)
+	return append(std, thirdParty...)
+}
+
+// treeKey returns the argument of the generated tree navigation methods: the
+// key column the self-referential relationships of model link to, or nil when
+// model has none
+func treeKey(model *ModelMetadata) []FinderParam {
+	for _, rel := range model.Relationships {
+		tag := rel.Relationship
+		if tag == nil || tag.Target != model.Name {
+			continue
+		}
+
+		var key string
+		switch tag.Type {
+		case "belongs_to":
+			key = tag.TargetKey
+		case "has_one", "has_many":
+			key = tag.SourceKey
+		default:
+			continue
+		}
+		if key == "" {
+			key = "id"
+		}
+
+		for _, col := range model.Columns {
+			if col.DBName == key {
+				return []FinderParam{{Name: "id", Type: strings.TrimPrefix(finderParamType(col), "*"), DBName: key}}
+			}
+		}
+	}
+	return nil
+}
+
+// keysetParams returns the argument lists of the keysets of model
+func keysetParams(model *ModelMetadata) [][]FinderParam {
+	params := make([][]FinderParam, 0, len(model.Keysets))
+	for _, keyset := range model.Keysets {
+		params = append(params, keyset.Params)
+	}
+	return params
+}
+
+// finderParams returns the argument lists of the finders of model
+func finderParams(model *ModelMetadata) [][]FinderParam {
+	params := make([][]FinderParam, 0, len(model.Finders))
+	for _, finder := range model.Finders {
+		params = append(params, finder.Params)
+	}
+	return params
+}
+
+func (g *CodeGenerator) generateMocks() error {
+	for _, model := range g.sortedModels() {
+		data := struct {
+			Package string
+			Model   *ModelMetadata
+			Imports []DTOImport
+		}{
+			Package: g.packageName,
+			Model:   model,
+			Imports: g.paramImports(finderParams(model)...),
+		}
+
+		filename := fmt.Sprintf("%s_mock_test.go", toSnakeCase(model.Name))
+		if err := g.executeTemplate("mock", filename, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (g *CodeGenerator) generateRelationships() error {
 	data := struct {
 		Package string
 		Models  map[string]*ModelMetadata
-		Now     time.Time
 	}{
 		Package: g.packageName,
 		Models:  g.models,
-		Now:     time.Now(),
 	}
 
 	return g.executeTemplate("relationships", "relationships.go", data)
@@ -350,13 +594,13 @@ func (g *CodeGenerator) generateRelationships() error {
 
 func (g *CodeGenerator) generateStorm() error {
 	data := struct {
-		Package string
-		Models  map[string]*ModelMetadata
-		Now     time.Time
+		Package  string
+		Database string
+		Models   map[string]*ModelMetadata
 	}{
-		Package: g.packageName,
-		Models:  g.models,
-		Now:     time.Now(),
+		Package:  g.packageName,
+		Database: g.database,
+		Models:   g.models,
 	}
 
 	return g.executeTemplate("storm", "storm.go", data)
@@ -368,7 +612,13 @@ func (g *CodeGenerator) executeTemplate(templateName, filename string, data inte
 		return fmt.Errorf("template %s not found", templateName)
 	}
 
+	header, err := g.fileHeaderFor(filename)
+	if err != nil {
+		return err
+	}
+
 	var buf bytes.Buffer
+	buf.WriteString(header)
 	if err := tmpl.Execute(&buf, data); err != nil {
 		return fmt.Errorf("failed to execute template %s: %w", templateName, err)
 	}
@@ -470,6 +720,16 @@ func (g *CodeGenerator) GetModelNames() []string {
 	return names
 }
 
+// sortedModels returns the models ordered by name, so files are generated and
+// problems reported in the same order on every run
+func (g *CodeGenerator) sortedModels() []*ModelMetadata {
+	models := make([]*ModelMetadata, 0, len(g.models))
+	for _, name := range g.GetModelNames() {
+		models = append(models, g.models[name])
+	}
+	return models
+}
+
 func (g *CodeGenerator) GetModel(name string) (*ModelMetadata, bool) {
 	model, exists := g.models[name]
 	return model, exists
@@ -484,19 +744,36 @@ func (g *CodeGenerator) GetModelsByTable() map[string]*ModelMetadata {
 }
 
 func (g *CodeGenerator) ValidateModels() error {
-	for name, model := range g.models {
+	for _, model := range g.sortedModels() {
 		if err := g.validateModel(model); err != nil {
-			return fmt.Errorf("model %s validation failed: %w", name, err)
+			return fmt.Errorf("model %s validation failed: %w", model.Name, err)
 		}
 	}
 	return nil
 }
 
+// columnsMethods are the methods of the generated <Model>Columns types, which
+// no column field may share a name with
+var columnsMethods = map[string]bool{"As": true, "Table": true}
+
 func (g *CodeGenerator) validateModel(model *ModelMetadata) error {
 	if len(model.PrimaryKeys) == 0 {
 		return fmt.Errorf("model %s has no primary key", model.Name)
 	}
 
+	for _, col := range model.Columns {
+		if err := validateProtectedField(col); err != nil {
+			return err
+		}
+		if columnsMethods[sanitizeGoName(col.Name)] {
+			return fmt.Errorf("field %s clashes with the generated %sColumns.%s method, rename the field", col.Name, model.Name, sanitizeGoName(col.Name))
+		}
+	}
+
+	if model.ShardKey != "" && !g.hasColumn(model, model.ShardKey) {
+		return fmt.Errorf("shard key %s is not a column of model %s", model.ShardKey, model.Name)
+	}
+
 	for _, rel := range model.Relationships {
 		if err := g.validateRelationship(model, rel); err != nil {
 			return fmt.Errorf("relationship %s validation failed: %w", rel.Name, err)
@@ -524,6 +801,9 @@ func (g *CodeGenerator) validateRelationship(model *ModelMetadata, rel FieldMeta
 		if !g.hasColumn(targetModel, rel.Relationship.TargetKey) {
 			return fmt.Errorf("target key column %s not found in target model %s", rel.Relationship.TargetKey, targetModel.Name)
 		}
+		if typeColumn := polymorphicTypeColumn(rel.Relationship); typeColumn != "" && !g.hasColumn(model, typeColumn) {
+			return fmt.Errorf("polymorphic type column %s not found in model %s", typeColumn, model.Name)
+		}
 
 	case "has_one", "has_many":
 		if !g.hasColumn(targetModel, rel.Relationship.ForeignKey) {
@@ -532,10 +812,111 @@ func (g *CodeGenerator) validateRelationship(model *ModelMetadata, rel FieldMeta
 		if !g.hasColumn(model, rel.Relationship.SourceKey) {
 			return fmt.Errorf("source key column %s not found in model %s", rel.Relationship.SourceKey, model.Name)
 		}
+		if typeColumn := polymorphicTypeColumn(rel.Relationship); typeColumn != "" && !g.hasColumn(targetModel, typeColumn) {
+			return fmt.Errorf("polymorphic type column %s not found in target model %s", typeColumn, targetModel.Name)
+		}
 
 	case "has_many_through":
+		if rel.Relationship.Autosave {
+			return fmt.Errorf("autosave is not supported for has_many_through relationships")
+		}
+	}
+
+	if rel.Relationship.Autosave && !rel.IsPointer && !rel.IsArray {
+		return fmt.Errorf("autosave relationship %s must be a pointer or slice", rel.Name)
+	}
+
+	if rel.Relationship.Count {
+		if rel.Relationship.Type == "belongs_to" {
+			return fmt.Errorf("count is not supported for belongs_to relationships")
+		}
+		if rel.CountField == "" {
+			return fmt.Errorf("counted relationship %s needs a field %sCount int64 `db:\"-\"` on %s to receive the count", rel.Name, rel.Name, model.Name)
+		}
+	}
+
+	return nil
+}
+
+// polymorphicTypeColumn returns the type column of a polymorphic relationship, or "" if it is not polymorphic
+func polymorphicTypeColumn(rel *ParsedORMTag) string {
+	if rel.Polymorphic == "" {
+		return ""
+	}
+	return rel.Polymorphic + "_type"
+}
+
+// RelationshipKeys names the fields a generated Load function matches on to
+// hand each related row to the model that owns it
+type RelationshipKeys struct {
+	OwnerField   string // Key field on the owning model
+	OwnerPointer bool   // Whether OwnerField is a pointer
+	OwnerType    string // Go type of OwnerField, without the pointer
+	RowField     string // Key field on the loaded rows
+	RowPointer   bool   // Whether RowField is a pointer
+
+	// OwnerText matches has_many_through rows on the text form of the owner
+	// key, for key types from other packages the metadata file cannot name
+	OwnerText bool
+}
+
+// relationshipKeys resolves the key fields of rel, or returns nil when one of
+// its key columns is missing and the relationship cannot be loaded
+func (g *CodeGenerator) relationshipKeys(model *ModelMetadata, rel FieldMetadata) *RelationshipKeys {
+	if rel.Relationship == nil {
+		return nil
+	}
+	targetModel, exists := g.models[rel.Relationship.Target]
+	if !exists {
+		return nil
+	}
+
+	ownerKey, rowKey := defaultKey(rel.Relationship.SourceKey), rel.Relationship.ForeignKey
+	rowModel := targetModel
+	if rel.Relationship.Type == "belongs_to" {
+		ownerKey, rowKey = rel.Relationship.ForeignKey, defaultKey(rel.Relationship.TargetKey)
+	}
+
+	owner := g.column(model, ownerKey)
+	if owner == nil {
+		return nil
+	}
+	keys := &RelationshipKeys{
+		OwnerField:   owner.Name,
+		OwnerPointer: owner.IsPointer,
+		OwnerType:    strings.TrimPrefix(owner.Type, "*"),
+	}
+
+	if rel.Relationship.Type == "has_many_through" {
+		keys.RowField = "StormOwner"
+		keys.OwnerText = strings.Contains(keys.OwnerType, ".")
+		return keys
+	}
+
+	row := g.column(rowModel, rowKey)
+	if row == nil {
+		return nil
+	}
+	keys.RowField = row.Name
+	keys.RowPointer = row.IsPointer
+	return keys
+}
+
+// defaultKey returns key, or the conventional "id" when it is empty
+func defaultKey(key string) string {
+	if key == "" {
+		return "id"
 	}
+	return key
+}
 
+// column returns the column of model stored as columnName
+func (g *CodeGenerator) column(model *ModelMetadata, columnName string) *FieldMetadata {
+	for i := range model.Columns {
+		if model.Columns[i].DBName == columnName {
+			return &model.Columns[i]
+		}
+	}
 	return nil
 }
 
@@ -561,11 +942,9 @@ func (g *CodeGenerator) GenerateForModel(modelName string) error {
 	data := struct {
 		Package string
 		Model   *ModelMetadata
-		Now     time.Time
 	}{
 		Package: g.packageName,
 		Model:   model,
-		Now:     time.Now(),
 	}
 
 	filename := fmt.Sprintf("%s_repository.go", toSnakeCase(model.Name))
@@ -3,8 +3,11 @@ package orm_generator
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	stormParser "github.com/eleven-am/storm/internal/parser"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -202,3 +205,910 @@ func TestGenerateForModel(t *testing.T) {
 
 	t.Logf("GenerateForModel completed, output directory exists: %v", fileExists(outputDir))
 }
+
+func TestImmutableFieldMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	tableDef := stormParser.TableDefinition{
+		StructName: "Account",
+		TableName:  "accounts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Slug", DBName: "slug", Type: "string", DBDef: map[string]string{"immutable": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+		},
+	}
+
+	model := generator.convertTableDefinitionToModelMetadata(tableDef)
+	assert.False(t, model.Columns[0].IsImmutable)
+	assert.True(t, model.Columns[1].IsImmutable)
+	assert.False(t, model.Columns[2].IsImmutable)
+
+	generator.models[model.Name] = model
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "account_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "IsImmutable:")
+	assert.Equal(t, 1, strings.Count(string(content), "IsImmutable:"))
+
+	columns, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	assert.NoError(t, err)
+	assert.Regexp(t, `Slug\s+storm\.StringColumn`, string(columns), "immutable columns share the column types; writes are rejected through the metadata")
+}
+
+func TestAutoTimestampMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	tableDef := stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "CreatedAt", DBName: "created_at", Type: "time.Time", DBDef: map[string]string{}},
+			{Name: "UpdatedAt", DBName: "updated_at", Type: "*time.Time", DBDef: map[string]string{}},
+			{Name: "PublishedAt", DBName: "published_at", Type: "time.Time", DBDef: map[string]string{"auto_create_time": ""}},
+			{Name: "EditedAt", DBName: "edited_at", Type: "time.Time", DBDef: map[string]string{"auto_update_time": ""}},
+		},
+	}
+
+	model := generator.convertTableDefinitionToModelMetadata(tableDef)
+	assert.False(t, model.Columns[0].AutoCreateTime || model.Columns[0].AutoUpdateTime)
+	assert.True(t, model.Columns[1].AutoCreateTime)
+	assert.True(t, model.Columns[2].AutoUpdateTime)
+	assert.True(t, model.Columns[3].AutoCreateTime)
+	assert.True(t, model.Columns[4].AutoUpdateTime)
+
+	generator.models[model.Name] = model
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "AutoCreateTime:  true,"))
+	assert.Equal(t, 2, strings.Count(string(content), "AutoUpdateTime:  true,"))
+}
+
+func TestComputedFieldMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	tableDef := stormParser.TableDefinition{
+		StructName: "Person",
+		TableName:  "people",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": "", "type": "uuid"}},
+			{Name: "FullName", DBName: "full_name", Type: "string", DBDef: map[string]string{"computed": "first_name || ' ' || last_name"}},
+		},
+	}
+
+	model := generator.convertTableDefinitionToModelMetadata(tableDef)
+	assert.Empty(t, model.Columns[0].Computed)
+	assert.Equal(t, "first_name || ' ' || last_name", model.Columns[1].Computed)
+
+	generator.models[model.Name] = model
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "person_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `Computed:        "first_name || ' ' || last_name",`)
+	assert.Contains(t, string(content), `DBType:          "uuid",`)
+	assert.Regexp(t, `ColumnOrder: \[\]string\{\s*"ID",\s*"FullName",\s*\}`, string(content))
+	assert.Regexp(t, `ScanColumns: \[\]string\{\s*"id",\s*"full_name",\s*\}`, string(content))
+	assert.Regexp(t, `return \[\]interface\{\}\{\s*&m.ID,\s*&m.FullName,\s*\}`, string(content))
+}
+
+func TestEncryptedFieldGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+		IncludeDTOs: true,
+	})
+	generator.imports["storm"] = "github.com/eleven-am/storm/pkg/storm-orm"
+
+	model := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Customer",
+		TableName:  "customers",
+		TableLevel: map[string]string{"index": "idx_customers_note,note"},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"masked": ""}},
+			{Name: "SSN", DBName: "ssn", Type: "storm.DeterministicString", DBDef: map[string]string{"encrypted": "deterministic", "unique": "", "not_null": ""}},
+			{Name: "Note", DBName: "note", Type: "storm.EncryptedString", DBDef: map[string]string{"encrypted": "randomized", "not_null": ""}},
+			{Name: "Phone", DBName: "phone", Type: "string", DBDef: map[string]string{"sensitive": ""}},
+		},
+	})
+	assert.True(t, model.Columns[1].IsMasked)
+	assert.True(t, model.Columns[4].IsSensitive)
+	assert.Equal(t, "deterministic", model.Columns[2].Encrypted)
+	assert.Equal(t, []FinderMetadata{
+		{Name: "SSN", Unique: true, Params: []FinderParam{{Name: "ssn", Type: "storm.DeterministicString", DBName: "ssn"}}},
+	}, model.Finders, "randomized encrypted columns get no finders")
+
+	generator.models[model.Name] = model
+	assert.NoError(t, generator.ValidateModels())
+	assert.NoError(t, generator.GenerateAll())
+
+	columns, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(columns), `SSN: storm.Column[storm.DeterministicString]{Name: "ssn", Table: table},`)
+	assert.Contains(t, string(columns), `Note: storm.EncryptedColumn{Name: "note", Table: table},`)
+
+	dto, err := os.ReadFile(filepath.Join(outputDir, "customer_dto.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(dto), "\t\tEmail: storm.Mask(m.Email),\n")
+	assert.Contains(t, string(dto), "\t\tNote:  m.Note,\n")
+	assert.Contains(t, string(dto), `storm "github.com/eleven-am/storm/pkg/storm-orm"`)
+
+	metadata, err := os.ReadFile(filepath.Join(outputDir, "customer_metadata.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(metadata), "IsSensitive:     true"), "masked, encrypted and sensitive columns are redacted from logs")
+
+	t.Run("type must match the encryption mode", func(t *testing.T) {
+		model.Columns[3].Type = "string"
+		defer func() { model.Columns[3].Type = "storm.EncryptedString" }()
+		assert.ErrorContains(t, generator.ValidateModels(), "must have type storm.EncryptedString")
+	})
+
+	t.Run("nullable columns must be pointers", func(t *testing.T) {
+		delete(model.Columns[3].DBDef, "not_null")
+		assert.ErrorContains(t, generator.ValidateModels(), "encrypted field Note is nullable")
+
+		model.Columns[3].IsPointer = true
+		assert.NoError(t, generator.ValidateModels())
+	})
+}
+
+func TestPolymorphicRelationshipMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Title", DBName: "title", Type: "string", DBDef: map[string]string{}},
+			{Name: "Comments", Type: "[]Comment", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Comment;polymorphic:commentable"},
+		},
+	})
+	comment := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Comment",
+		TableName:  "comments",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "CommentableID", DBName: "commentable_id", Type: "string", DBDef: map[string]string{}},
+			{Name: "CommentableType", DBName: "commentable_type", Type: "string", DBDef: map[string]string{}},
+			{Name: "Post", Type: "*Post", IsPointer: true, IsRelationship: true, StormTag: "relation:belongs_to:Post;polymorphic:commentable"},
+		},
+	})
+	generator.models[post.Name] = post
+	generator.models[comment.Name] = comment
+
+	assert.NoError(t, generator.GenerateAll())
+
+	postContent, err := os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	assert.NoError(t, err)
+	assert.Regexp(t, `PolymorphicType:\s+"commentable_type",`, string(postContent))
+	assert.Regexp(t, `PolymorphicValue:\s+"Post",`, string(postContent))
+	assert.Regexp(t, `ForeignKey:\s+"commentable_id",`, string(postContent))
+
+	commentContent, err := os.ReadFile(filepath.Join(outputDir, "comment_metadata.go"))
+	assert.NoError(t, err)
+	assert.Regexp(t, `PolymorphicValue:\s+"Post",`, string(commentContent))
+}
+
+func TestSelfReferentialTreeMethods(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	category := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Category",
+		TableName:  "categories",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "ParentID", DBName: "parent_id", Type: "string", IsPointer: true, DBDef: map[string]string{}},
+			{Name: "Parent", Type: "*Category", IsPointer: true, IsRelationship: true, StormTag: "relation:belongs_to:Category;foreign_key:parent_id"},
+			{Name: "Children", Type: "[]Category", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Category;foreign_key:parent_id"},
+		},
+	})
+	generator.models[category.Name] = category
+
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "category_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "func (q *CategoryQuery) WithoutParent() *CategoryQuery")
+	assert.Contains(t, string(content), "func (q *CategoryQuery) ChildrenOf(id string) *CategoryQuery")
+	assert.Contains(t, string(content), "func (q *CategoryQuery) Descendants(id string) ([]Category, error)")
+	assert.Contains(t, string(content), "func (q *CategoryQuery) Ancestors(id string) ([]Category, error)")
+}
+
+func TestRelationshipCountField(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	author := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Author",
+		TableName:  "authors",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+			{Name: "PostsCount", DBName: "-", Type: "int64", DBDef: map[string]string{}},
+			{Name: "Posts", Type: "[]Post", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Post;foreign_key:author_id;conditions:published = true;count"},
+			{Name: "Drafts", Type: "[]Post", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Post;foreign_key:author_id"},
+		},
+	})
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		TableLevel: map[string]string{"default_scope": "live,deleted_at IS NULL"},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", DBDef: map[string]string{}},
+			{Name: "Published", DBName: "published", Type: "bool", DBDef: map[string]string{}},
+		},
+	})
+
+	assert.Len(t, author.Columns, 2)
+	assert.Equal(t, "PostsCount", author.Relationships[0].CountField)
+	assert.Empty(t, author.Relationships[1].CountField, "only relationships tagged count are counted")
+
+	generator.models[author.Name] = author
+	generator.models[post.Name] = post
+	assert.NoError(t, generator.ValidateModels())
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "author_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "model.(*Author).PostsCount = int64(count)")
+
+	repoContent, err := os.ReadFile(filepath.Join(outputDir, "author_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(repoContent), "func (q *AuthorQuery) IncludePostsCount() *AuthorQuery")
+	assert.NotContains(t, string(repoContent), "IncludeDraftsCount")
+	assert.Contains(t, string(content), `TargetScopes: []string{"deleted_at IS NULL"},`, "counts and loads apply the target's default scopes")
+
+	t.Run("counted relationships need a count field", func(t *testing.T) {
+		author.Relationships[1].Relationship.Count = true
+		defer func() { author.Relationships[1].Relationship.Count = false }()
+		assert.ErrorContains(t, generator.ValidateModels(), "counted relationship Drafts needs a field DraftsCount int64 `db:\"-\"` on Author")
+	})
+}
+
+func TestDependentDestroyMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	author := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Author",
+		TableName:  "authors",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+			{Name: "Posts", Type: "[]Post", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Post;foreign_key:author_id;dependent:destroy"},
+			{Name: "Drafts", Type: "[]Draft", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Draft;foreign_key:author_id;dependent:delete"},
+		},
+	})
+
+	generator.models[author.Name] = author
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "author_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `AuthorMetadata.Relationships["Posts"].TargetMetadata = PostMetadata`)
+	assert.NotContains(t, string(content), `AuthorMetadata.Relationships["Drafts"].TargetMetadata`)
+}
+
+func TestAutosaveRelationshipMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	author := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Author",
+		TableName:  "authors",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+			{Name: "Posts", Type: "[]Post", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Post;foreign_key:author_id;autosave"},
+		},
+	})
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", DBDef: map[string]string{}},
+			{Name: "Author", Type: "*Author", IsPointer: true, IsRelationship: true, StormTag: "relation:belongs_to:Author;foreign_key:author_id;autosave"},
+		},
+	})
+
+	generator.models[author.Name] = author
+	generator.models[post.Name] = post
+	assert.NoError(t, generator.ValidateModels())
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "author_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "related[i] = &m.Posts[i]")
+	assert.Contains(t, string(content), `AuthorMetadata.Relationships["Posts"].TargetMetadata = PostMetadata`)
+
+	content, err = os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "return []interface{}{m.Author}")
+	assert.Contains(t, string(content), `PostMetadata.Relationships["Author"].TargetMetadata = AuthorMetadata`)
+
+	author.Relationships[0].IsArray = false
+	assert.ErrorContains(t, generator.ValidateModels(), "must be a pointer or slice")
+}
+
+func TestRelationshipLoadMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	author := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Author",
+		TableName:  "authors",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+			{Name: "Posts", Type: "[]Post", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Post;foreign_key:author_id"},
+			{Name: "Tags", Type: "[]Tag", IsArray: true, IsRelationship: true, StormTag: "relation:has_many_through:Tag;join_table:author_tags;source_fk:author_id;target_fk:tag_id"},
+		},
+	})
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", IsPointer: true, DBDef: map[string]string{}},
+			{Name: "Author", Type: "*Author", IsPointer: true, IsRelationship: true, StormTag: "relation:belongs_to:Author;foreign_key:author_id"},
+		},
+	})
+	tag := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Tag",
+		TableName:  "tags",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Label", DBName: "label", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+
+	generator.models[author.Name] = author
+	generator.models[post.Name] = post
+	generator.models[tag.Name] = tag
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "author_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "related := make(map[interface{}][]Post, len(rows))")
+	assert.Contains(t, string(content), "if row.AuthorID == nil {")
+	assert.Contains(t, string(content), "m.Posts = []Post{}")
+	assert.Contains(t, string(content), `StormOwner string `+"`"+`db:"storm_owner"`+"`")
+	assert.Contains(t, string(content), "related[key] = append(related[key], row.Tag)")
+	assert.Contains(t, string(content), `Through:     "author_tags",`)
+	assert.NotContains(t, string(content), "ScanToModel")
+
+	content, err = os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "m.Author = nil")
+	assert.Contains(t, string(content), "key := *m.AuthorID")
+	assert.Contains(t, string(content), "m.Author = &found")
+
+	content, err = os.ReadFile(filepath.Join(outputDir, "author_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "func (q *AuthorQuery) JoinPosts() *AuthorQuery {")
+	assert.Contains(t, string(content), `q.Query = q.Query.JoinRelationship("Posts", storm.InnerJoin)`)
+	assert.Contains(t, string(content), "func (q *AuthorQuery) LeftJoinTags() *AuthorQuery {")
+	assert.Contains(t, string(content), `q.Query = q.Query.JoinRelationship("Tags", storm.LeftJoin)`)
+	assert.Contains(t, string(content), "func (q *AuthorQuery) IncludeStrategy(strategy storm.IncludeStrategy) *AuthorQuery {")
+}
+
+func TestRelationshipScopeMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	author := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Author",
+		TableName:  "authors",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+			{Name: "Posts", Type: "[]Post", IsArray: true, IsRelationship: true, StormTag: "relation:has_many:Post;foreign_key:author_id;conditions:published = true,deleted_at IS NULL;order_by:created_at DESC"},
+		},
+	})
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+	generator.models[author.Name] = author
+	generator.models[post.Name] = post
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "author_metadata.go"))
+	assert.NoError(t, err)
+	assert.Regexp(t, `Conditions:\s+\[\]string\{"published = true", "deleted_at IS NULL"\},`, string(content))
+	assert.Regexp(t, `OrderBy:\s+"created_at DESC",`, string(content))
+}
+
+func TestScopeGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	user := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "User",
+		TableName:  "users",
+		TableLevel: map[string]string{
+			"scope":         "active,is_active = true",
+			"default_scope": "visible,deleted_at IS NULL",
+		},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "IsActive", DBName: "is_active", Type: "bool", DBDef: map[string]string{}},
+		},
+	})
+	assert.Equal(t, []ScopeMetadata{
+		{Name: "active", Condition: "is_active = true"},
+		{Name: "visible", Condition: "deleted_at IS NULL", IsDefault: true},
+	}, user.Scopes)
+
+	generator.models[user.Name] = user
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "user_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `Scope("active", storm.SQLScope[User]("is_active = true"))`)
+	assert.Contains(t, string(content), `DefaultScope("visible", storm.SQLScope[User]("deleted_at IS NULL"))`)
+	assert.Contains(t, string(content), "baseRepo = withUserScopes(baseRepo)")
+	assert.Contains(t, string(content), "func (q *UserQuery) Active() *UserQuery")
+	assert.NotContains(t, string(content), "func (q *UserQuery) Visible() *UserQuery")
+}
+
+func TestFinderGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		TableLevel: map[string]string{
+			"index":  "idx_posts_author,author_id;idx_posts_published,published_at DESC where:published_at IS NOT NULL;idx_posts_created,created_at,id",
+			"unique": "uq_posts_tenant_slug,tenant_id,slug",
+		},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"unique": ""}},
+			{Name: "ExternalID", DBName: "external_id", Type: "uuid.UUID", DBDef: map[string]string{"unique": ""}},
+			{Name: "LegacyRef", DBName: "legacy_ref", Type: "legacy.Ref", DBDef: map[string]string{"unique": ""}},
+			{Name: "TenantID", DBName: "tenant_id", Type: "int64", DBDef: map[string]string{}},
+			{Name: "Slug", DBName: "slug", Type: "string", DBDef: map[string]string{}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", DBDef: map[string]string{}},
+			{Name: "PublishedAt", DBName: "published_at", Type: "time.Time", DBDef: map[string]string{}},
+			{Name: "CreatedAt", DBName: "created_at", Type: "time.Time", DBDef: map[string]string{}},
+		},
+	})
+	assert.Equal(t, []FinderMetadata{
+		{Name: "Email", Unique: true, Params: []FinderParam{{Name: "email", Type: "string", DBName: "email"}}},
+		{Name: "ExternalID", Unique: true, Params: []FinderParam{{Name: "externalID", Type: "uuid.UUID", DBName: "external_id"}}},
+		{Name: "LegacyRef", Unique: true, Params: []FinderParam{{Name: "legacyRef", Type: "legacy.Ref", DBName: "legacy_ref"}}},
+		{Name: "AuthorID", Params: []FinderParam{{Name: "authorID", Type: "string", DBName: "author_id"}}},
+		{Name: "CreatedAtAndID", Params: []FinderParam{
+			{Name: "createdAt", Type: "time.Time", DBName: "created_at"},
+			{Name: "id", Type: "string", DBName: "id"},
+		}},
+		{Name: "TenantIDAndSlug", Unique: true, Params: []FinderParam{
+			{Name: "tenantID", Type: "int64", DBName: "tenant_id"},
+			{Name: "slug", Type: "string", DBName: "slug"},
+		}},
+	}, post.Finders)
+
+	generator.imports["uuid"] = "github.com/google/uuid"
+	generator.models[post.Name] = post
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "post_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "\t\"time\"\n")
+	assert.Contains(t, string(content), "\t\"github.com/google/uuid\"\n")
+	assert.Contains(t, string(content), `func (r *PostRepository) FindAllByCreatedAtAndID(ctx context.Context, createdAt time.Time, id string) ([]Post, error) {`)
+	assert.Contains(t, string(content), `func (r *PostRepository) FindByExternalID(ctx context.Context, externalID uuid.UUID) (*Post, error) {`)
+	assert.Contains(t, string(content), `func (r *PostRepository) FindByLegacyRef(ctx context.Context, legacyRef interface{}) (*Post, error) {`)
+	assert.Contains(t, string(content), `func (r *PostRepository) FindByEmail(ctx context.Context, email string) (*Post, error) {
+	return r.FindOneBy(ctx, []string{"email"}, email)
+}`)
+	assert.Contains(t, string(content), `func (r *PostRepository) FindByTenantIDAndSlug(ctx context.Context, tenantID int64, slug string) (*Post, error) {
+	return r.FindOneBy(ctx, []string{"tenant_id", "slug"}, tenantID, slug)
+}`)
+	assert.Contains(t, string(content), `func (r *PostRepository) FindAllByAuthorID(ctx context.Context, authorID string) ([]Post, error) {`)
+	assert.Contains(t, string(content), "type PostStore interface {")
+	assert.Contains(t, string(content), "\tFindByTenantIDAndSlug(ctx context.Context, tenantID int64, slug string) (*Post, error)\n")
+	assert.Contains(t, string(content), "\tQuery(ctx context.Context) *PostQuery\n")
+	assert.Contains(t, string(content), "var _ PostStore = (*PostRepository)(nil)")
+	assert.NotContains(t, string(content), "func (r *PostRepository) FindByID(")
+	assert.NotContains(t, string(content), "PublishedAt(ctx")
+}
+
+func TestKeysetGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		TableLevel: map[string]string{
+			"index":  "idx_posts_published,published_at DESC;idx_posts_archived,archived_at;idx_posts_id_author,id,author_id",
+			"unique": "uq_posts_slug,slug",
+		},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Slug", DBName: "slug", Type: "string", DBDef: map[string]string{}},
+			{Name: "AuthorID", DBName: "author_id", Type: "string", DBDef: map[string]string{}},
+			{Name: "PublishedAt", DBName: "published_at", Type: "time.Time", DBDef: map[string]string{}},
+			{Name: "ArchivedAt", DBName: "archived_at", Type: "time.Time", IsPointer: true, DBDef: map[string]string{}},
+		},
+	})
+	assert.Equal(t, []KeysetMetadata{
+		{Name: "PublishedAtID", Params: []FinderParam{
+			{Name: "publishedAt", Type: "time.Time", DBName: "published_at"},
+			{Name: "id", Type: "int64", DBName: "id"},
+		}},
+		{Name: "IDAuthorID", Params: []FinderParam{
+			{Name: "id", Type: "int64", DBName: "id"},
+			{Name: "authorID", Type: "string", DBName: "author_id"},
+		}},
+		{Name: "Slug", Params: []FinderParam{{Name: "slug", Type: "string", DBName: "slug"}}},
+	}, post.Keysets)
+
+	generator.models[post.Name] = post
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "post_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "\t\"time\"\n")
+	assert.Contains(t, string(content), `func (q *PostQuery) AfterPublishedAtID(publishedAt time.Time, id int64) *PostQuery {
+	q.Query = q.Query.After([]string{"published_at", "id"}, publishedAt, id)
+	return q
+}`)
+	assert.Contains(t, string(content), `func (q *PostQuery) BeforeSlug(slug string) *PostQuery {`)
+	assert.NotContains(t, string(content), "AfterArchivedAt")
+}
+
+func TestMockGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName:  "testmodels",
+		OutputDir:    outputDir,
+		IncludeMocks: true,
+	})
+
+	user := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "User",
+		TableName:  "users",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"unique": ""}},
+		},
+	})
+	generator.models[user.Name] = user
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "user_mock_test.go"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "UserRepositoryInterface")
+	assert.Contains(t, string(content), "var _ UserStore = (*MockUserRepository)(nil)")
+	assert.Contains(t, string(content), "func (m *MockUserRepository) Query(ctx context.Context) *UserQuery {")
+	assert.Contains(t, string(content), "func (m *MockUserRepository) CreateManyPartial(ctx context.Context, records []User, opts storm.BatchOptions) (*storm.BatchResult, error) {")
+	assert.Contains(t, string(content), `func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	args := m.Called(ctx, email)
+	return m.record(args), args.Error(1)
+}`)
+
+	t.Run("not generated by default", func(t *testing.T) {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: outputDir})
+		generator.models[user.Name] = user
+		assert.NoError(t, generator.GenerateAll())
+
+		_, err := os.Stat(filepath.Join(outputDir, "user_mock_test.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestDatabaseTargetAccessor(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "analytics",
+		OutputDir:   outputDir,
+		Database:    "analytics",
+	})
+
+	event := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Event",
+		TableName:  "events",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+	generator.models[event.Name] = event
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "storm.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `const DatabaseName = "analytics"`)
+	assert.Contains(t, string(content), "func NewAnalyticsStorm(db *sqlx.DB, logger ...storm.QueryLogger) *Storm {")
+
+	t.Run("not generated without a target", func(t *testing.T) {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "analytics", OutputDir: outputDir})
+		generator.models[event.Name] = event
+		assert.NoError(t, generator.GenerateAll())
+
+		content, err := os.ReadFile(filepath.Join(outputDir, "storm.go"))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(content), "DatabaseName")
+	})
+}
+
+func TestShardKeyMetadata(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	order := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Order",
+		TableName:  "orders",
+		TableLevel: map[string]string{"shard_key": "tenant_id"},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "TenantID", DBName: "tenant_id", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+	assert.Equal(t, "tenant_id", order.ShardKey)
+
+	generator.models[order.Name] = order
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "order_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `ShardKey: "tenant_id",`)
+
+	t.Run("rejects an unknown column", func(t *testing.T) {
+		order.ShardKey = "region"
+		assert.ErrorContains(t, generator.validateModel(order), "shard key region")
+	})
+}
+
+func TestReadModelGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName:  "testmodels",
+		OutputDir:    outputDir,
+		IncludeMocks: true,
+	})
+
+	fields := []stormParser.FieldDefinition{
+		{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": ""}},
+		{Name: "Total", DBName: "total", Type: "int64", DBDef: map[string]string{}},
+	}
+	view := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "ActiveUser",
+		TableName:  "active_users",
+		TableLevel: map[string]string{"readonly": "", "source": "reporting.active_users_view"},
+		Fields:     fields,
+	})
+	assert.True(t, view.ReadOnly)
+	assert.Equal(t, "reporting.active_users_view", view.TableName)
+	assert.Empty(t, view.Source)
+
+	stats := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "AuthorStats",
+		TableName:  "author_stats",
+		TableLevel: map[string]string{"readonly": "", "source": "SELECT author_id AS id, count(*) AS total FROM posts GROUP BY author_id"},
+		Fields:     fields,
+	})
+	assert.Equal(t, "author_stats", stats.TableName)
+	assert.Equal(t, "SELECT author_id AS id, count(*) AS total FROM posts GROUP BY author_id", stats.Source)
+
+	generator.models[stats.Name] = stats
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "authorstats_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "ReadOnly: true,")
+	assert.Contains(t, string(content), `Source:   "SELECT author_id AS id, count(*) AS total FROM posts GROUP BY author_id",`)
+
+	content, err = os.ReadFile(filepath.Join(outputDir, "author_stats_repository.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "*storm.ReadOnlyRepository[AuthorStats]")
+	assert.NotContains(t, string(content), "Create(ctx context.Context")
+	assert.NotContains(t, string(content), "func (q *AuthorStatsQuery) Delete()")
+
+	content, err = os.ReadFile(filepath.Join(outputDir, "author_stats_mock_test.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "func (m *MockAuthorStatsRepository) FindByID(")
+	assert.NotContains(t, string(content), "func (m *MockAuthorStatsRepository) Update(")
+}
+
+func TestValidateModelColumnsMethodClash(t *testing.T) {
+	generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: t.TempDir()})
+
+	booking := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Booking",
+		TableName:  "bookings",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Table", DBName: "table_number", Type: "int", DBDef: map[string]string{}},
+		},
+	})
+	assert.ErrorContains(t, generator.validateModel(booking), "field Table clashes with the generated BookingColumns.Table method")
+}
+
+func TestCivilColumnGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	booking := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Booking",
+		TableName:  "bookings",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "CheckIn", DBName: "check_in", Type: "storm.Date", DBDef: map[string]string{}},
+			{Name: "OpensAt", DBName: "opens_at", Type: "storm.TimeOfDay", DBDef: map[string]string{}},
+			{Name: "Stay", DBName: "stay", Type: "time.Duration", DBDef: map[string]string{}},
+			{Name: "Grace", DBName: "grace", Type: "time.Duration", IsPointer: true, DBDef: map[string]string{}},
+			{Name: "Window", DBName: "window", Type: "time.Duration", DBDef: map[string]string{"type": "bigint"}},
+		},
+	})
+
+	generator.models[booking.Name] = booking
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `CheckIn: storm.DateColumn{Column: storm.Column[storm.Date]{Name: "check_in", Table: table}},`)
+	assert.Contains(t, string(content), `OpensAt: storm.Column[storm.TimeOfDay]{Name: "opens_at", Table: table},`)
+	assert.Contains(t, string(content), `Stay: storm.IntervalColumn{Column: storm.Column[time.Duration]{Name: "stay", Table: table}},`)
+	assert.Contains(t, string(content), `Window: storm.NumericColumn[time.Duration]{`)
+
+	metadata, err := os.ReadFile(filepath.Join(outputDir, "booking_metadata.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(metadata), "return storm.Interval(m.Stay)")
+	assert.Contains(t, string(metadata), "return storm.Interval(*m.Grace)")
+	assert.Contains(t, string(metadata), "(*storm.Interval)(&m.Stay),")
+	assert.Contains(t, string(metadata), "storm.IntervalPointer(&m.Grace),")
+	assert.Contains(t, string(metadata), "&m.Window,")
+}
+
+func TestGenerateAllIsReproducible(t *testing.T) {
+	generate := func() map[string]string {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{
+			PackageName: "testmodels",
+			OutputDir:   outputDir,
+		})
+		for _, tableDef := range []stormParser.TableDefinition{
+			{
+				StructName: "User",
+				TableName:  "users",
+				Fields: []stormParser.FieldDefinition{
+					{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+					{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"unique": ""}},
+					{Name: "CreatedAt", DBName: "created_at", Type: "time.Time", DBDef: map[string]string{"auto_create_time": ""}},
+				},
+			},
+			{
+				StructName: "Post",
+				TableName:  "posts",
+				Fields: []stormParser.FieldDefinition{
+					{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": ""}},
+					{Name: "UserID", DBName: "user_id", Type: "string", DBDef: map[string]string{"foreign_key": "users.id"}},
+					{Name: "Title", DBName: "title", Type: "string", DBDef: map[string]string{}},
+				},
+			},
+		} {
+			model := generator.convertTableDefinitionToModelMetadata(tableDef)
+			generator.models[model.Name] = model
+		}
+		if err := generator.GenerateAll(); err != nil {
+			t.Fatalf("GenerateAll failed: %v", err)
+		}
+
+		files := make(map[string]string)
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			content, err := os.ReadFile(filepath.Join(outputDir, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[entry.Name()] = string(content)
+		}
+		return files
+	}
+
+	first, second := generate(), generate()
+	if len(first) == 0 || len(first) != len(second) {
+		t.Fatalf("expected the same files from both runs, got %d and %d", len(first), len(second))
+	}
+	today := time.Now().Format("2006-01-02")
+	for name, content := range first {
+		if second[name] != content {
+			t.Errorf("%s differs between runs", name)
+		}
+		if strings.Contains(content, today) {
+			t.Errorf("%s contains the date it was generated on", name)
+		}
+	}
+}
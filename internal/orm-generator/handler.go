@@ -0,0 +1,121 @@
+package orm_generator
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// handlerParamTypes lists the Go types storm.ParseParam can decode from a URL
+var handlerParamTypes = map[string]bool{
+	"string": true, "bool": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+func (g *CodeGenerator) generateHandlers() error {
+	for _, model := range g.sortedModels() {
+		primaryKey, ok := handlerPrimaryKey(model)
+		if !ok {
+			fmt.Printf("Skipping HTTP handler for %s: requires a single primary key of a basic type\n", model.Name)
+			continue
+		}
+
+		data := struct {
+			Package        string
+			Model          *ModelMetadata
+			PrimaryKey     FieldMetadata
+			Filters        []FieldMetadata
+			DTOs           bool       // Whether Request and Response are the generated DTOs
+			Request        string     // Type request bodies are decoded into
+			Response       string     // Type records are written as
+			RequestFields  []DTOField // Fields of Request, when the handler declares it
+			ResponseFields []DTOField // Fields of Response, when the handler declares it
+			StdImports     []DTOImport
+			Imports        []DTOImport
+		}{
+			Package:    g.packageName,
+			Model:      model,
+			PrimaryKey: primaryKey,
+			Filters:    handlerFilters(model),
+			DTOs:       g.includeDTOs,
+		}
+
+		if g.includeDTOs {
+			data.Request, data.Response = model.Name+"Request", model.Name+"Response"
+		} else {
+			requiredImports := make(map[string]bool)
+			if !model.ReadOnly {
+				data.RequestFields = g.buildDTOFields(model, true, requiredImports)
+			}
+			for _, field := range g.buildDTOFields(model, false, requiredImports) {
+				if !field.Masked {
+					data.ResponseFields = append(data.ResponseFields, field)
+				}
+			}
+			data.StdImports, data.Imports = g.dtoImports(requiredImports)
+
+			name := unexportedName(model.Name)
+			data.Request, data.Response = name+"Request", name+"Response"
+		}
+
+		filename := fmt.Sprintf("%s_handler.go", toSnakeCase(model.Name))
+		if err := g.executeTemplate("handler", filename, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlerPrimaryKey returns the primary key column when it can be parsed from a URL path
+func handlerPrimaryKey(model *ModelMetadata) (FieldMetadata, bool) {
+	if len(model.PrimaryKeys) != 1 {
+		return FieldMetadata{}, false
+	}
+	for _, col := range model.Columns {
+		if col.DBName == model.PrimaryKeys[0] {
+			return col, handlerParamTypes[col.Type] && !col.IsPointer && !col.IsArray
+		}
+	}
+	return FieldMetadata{}, false
+}
+
+// handlerFilters returns the columns list requests may filter on: the primary
+// key plus every unique or indexed column whose type can be parsed from a query string
+func handlerFilters(model *ModelMetadata) []FieldMetadata {
+	indexed := make(map[string]bool)
+	for _, key := range model.PrimaryKeys {
+		indexed[key] = true
+	}
+	for _, index := range model.Indexes {
+		if index.Partial == "" {
+			for _, column := range index.Columns {
+				indexed[column] = true
+			}
+		}
+	}
+
+	var filters []FieldMetadata
+	for _, col := range model.Columns {
+		if !indexed[col.DBName] && !col.IsUnique {
+			continue
+		}
+		if handlerParamTypes[col.Type] && !col.IsArray {
+			filters = append(filters, col)
+		}
+	}
+	return filters
+}
+
+// unexportedName lowers the leading capitals of name, keeping the last one of
+// an initialism that starts a word: User becomes user, APIKey becomes apiKey
+func unexportedName(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,166 @@
+package orm_generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName:     "testmodels",
+		OutputDir:       outputDir,
+		IncludeHandlers: true,
+	})
+
+	user := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "User",
+		TableName:  "users",
+		TableLevel: map[string]string{"index": "idx_users_team,team_id"},
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"unique": ""}},
+			{Name: "TeamID", DBName: "team_id", Type: "int64", DBDef: map[string]string{}},
+			{Name: "Bio", DBName: "bio", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+	tag := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Tag",
+		TableName:  "tags",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "uuid.UUID", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Name", DBName: "name", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+	generator.models[user.Name] = user
+	generator.models[tag.Name] = tag
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "user_handler.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `func NewUserHandler(repo *UserRepository) *UserHandler {`)
+	assert.Contains(t, string(content), `mux.HandleFunc("GET "+prefix+"/{id}", h.Get)`)
+	assert.Contains(t, string(content), `id, err := storm.ParseParam[int64](r.PathValue("id"))`)
+	assert.Contains(t, string(content), `params.Get("team_id")`)
+	assert.Contains(t, string(content), `params.Get("email")`)
+	assert.NotContains(t, string(content), `params.Get("bio")`)
+	assert.Contains(t, string(content), `columns := []string{"id", "email", "team_id"}`)
+	assert.Contains(t, string(content), `query = query.Filter(spec, columns...).Sort(sortFields, columns...)`)
+	assert.Contains(t, string(content), "type userRequest struct {\n\tEmail  string `json:\"email\"`")
+	assert.Contains(t, string(content), `storm.WriteJSON(w, http.StatusOK, storm.MapPage(page, userResponseFromModel))`)
+	assert.Contains(t, string(content), `record, err := h.repo.FindByID(r.Context(), id)`)
+
+	t.Run("with DTOs", func(t *testing.T) {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: outputDir, IncludeHandlers: true, IncludeDTOs: true})
+		generator.models[user.Name] = user
+		assert.NoError(t, generator.GenerateAll())
+
+		content, err := os.ReadFile(filepath.Join(outputDir, "user_handler.go"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), `var request UserRequest`)
+		assert.Contains(t, string(content), `storm.WriteJSON(w, http.StatusOK, storm.MapPage(page, UserResponseFromModel))`)
+		assert.Contains(t, string(content), `storm.WriteJSON(w, http.StatusCreated, UserResponseFromModel(created))`)
+		assert.NotContains(t, string(content), `userRequest`)
+		assert.NotContains(t, string(content), `userResponse`)
+	})
+
+	_, err = os.Stat(filepath.Join(outputDir, "tag_handler.go"))
+	assert.True(t, os.IsNotExist(err), "models with unparseable primary keys are skipped")
+}
+
+// TestGeneratedHandlerBodies builds a generated handler with its model and
+// serves requests through it, checking what clients can read and write
+func TestGeneratedHandlerBodies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+
+	t.Run("own types", func(t *testing.T) { testGeneratedHandlerBodies(t, false) })
+	t.Run("DTOs", func(t *testing.T) { testGeneratedHandlerBodies(t, true) })
+}
+
+func testGeneratedHandlerBodies(t *testing.T, includeDTOs bool) {
+	// Inside the module, so the generated code imports storm from this tree
+	dir, err := os.MkdirTemp(".", "_handler")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	model := "package models\n\nimport \"time\"\n\n" +
+		"type User struct {\n" +
+		"\t_ struct{} `storm:\"table:users\"`\n\n" +
+		"\tID           int64     `db:\"id\" storm:\"column:id;type:bigserial;primary_key\"`\n" +
+		"\tEmail        string    `db:\"email\" storm:\"column:email;type:text;not_null\"`\n" +
+		"\tPasswordHash string    `db:\"password_hash\" storm:\"column:password_hash;type:text;not_null;json_ignore\"`\n" +
+		"\tRole         string    `db:\"role\" storm:\"column:role;type:text;not_null;immutable\"`\n" +
+		"\tCreatedAt    time.Time `db:\"created_at\" storm:\"column:created_at;type:timestamptz;auto_create_time\"`\n" +
+		"}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(model), 0644))
+
+	generator := NewCodeGenerator(GenerationConfig{OutputDir: dir, IncludeHandlers: true, IncludeDTOs: includeDTOs})
+	require.NoError(t, generator.DiscoverModels(dir))
+	require.NoError(t, generator.GenerateAll())
+
+	serve := `package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestUserHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewUserHandler(NewStorm(sqlx.NewDb(db, "postgres")).Users).Register(mux, "/users")
+
+	columns := []string{"id", "email", "password_hash", "role", "created_at"}
+	mock.ExpectQuery("SELECT .* FROM users WHERE").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "ada@example.com", "$2a$10$secret", "admin", time.Now()))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/users/1", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "ada@example.com") {
+		t.Fatalf("expected the user, got %d %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "password_hash") || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("json_ignore column in the response: %s", rec.Body)
+	}
+
+	for _, body := range []string{
+		` + "`" + `{"email": "ada@example.com", "id": 7}` + "`" + `,
+		` + "`" + `{"email": "ada@example.com", "role": "admin"}` + "`" + `,
+		` + "`" + `{"email": "ada@example.com", "password_hash": "x"}` + "`" + `,
+		` + "`" + `{"email": "ada@example.com", "created_at": "2020-01-01T00:00:00Z"}` + "`" + `,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/users", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected the field to be rejected, got %d %s", body, rec.Code, rec.Body)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "serve_test.go"), []byte(serve), 0644))
+
+	output, err := exec.Command("go", "test", "./"+dir).CombinedOutput()
+	assert.NoError(t, err, string(output))
+}
@@ -0,0 +1,349 @@
+package orm_generator
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueriesDir is the directory, inside the models package, holding .sql files
+// of named queries
+const QueriesDir = "queries"
+
+// NamedQuery is a statement read from a .sql file in the queries directory,
+// generated as a method of Storm:
+//
+//	-- name: TopAuthors :many
+//	-- param: since time.Time
+//	-- column: author_id int64
+//	-- column: posts int64
+//	SELECT author_id, count(*) AS posts FROM posts
+//	WHERE created_at > :since GROUP BY author_id
+type NamedQuery struct {
+	Name    string       // Go method name
+	Kind    string       // one, many or exec
+	File    string       // Source file, relative to the models package
+	SQL     string       // Statement with :param placeholders
+	Params  []QueryField // Parameters, in the order they are declared
+	Columns []QueryField // Result columns, generating a <Name>Row struct
+	Returns string       // Model returned instead of a generated row
+}
+
+// QueryField is a parameter or result column of a named query
+type QueryField struct {
+	Name   string // Go field name
+	Type   string // Go type
+	DBName string // Parameter or column name in the SQL
+}
+
+// RowType returns the Go type of a row the query returns
+func (q NamedQuery) RowType() string {
+	if q.Returns != "" {
+		return q.Returns
+	}
+	return q.Name + "Row"
+}
+
+// ConstName returns the name of the unexported constant holding the statement
+func (q NamedQuery) ConstName() string {
+	return strings.ToLower(q.Name[:1]) + q.Name[1:] + "SQL"
+}
+
+// Literal returns the statement as a Go string literal
+func (q NamedQuery) Literal() string {
+	if strings.Contains(q.SQL, "`") {
+		return strconv.Quote(q.SQL)
+	}
+	return "`" + q.SQL + "`"
+}
+
+var (
+	queryNameLine   = regexp.MustCompile(`^--\s*name:\s*(\S+)\s*:(\w+)\s*$`)
+	queryAnnotation = regexp.MustCompile(`^--\s*(param|column|returns):\s*(.*?)\s*$`)
+	queryFieldDef   = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s+(\S+)$`)
+)
+
+// loadQueries reads the named queries of every .sql file in dir. A missing
+// directory holds no queries.
+func (g *CodeGenerator) loadQueries(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list queries in %s: %w", dir, err)
+	}
+	sort.Strings(files)
+
+	seen := make(map[string]string)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		rel := filepath.ToSlash(filepath.Join(QueriesDir, filepath.Base(file)))
+		queries, err := parseQueryFile(rel, string(content))
+		if err != nil {
+			return err
+		}
+		for _, query := range queries {
+			if previous, ok := seen[query.Name]; ok {
+				return fmt.Errorf("%s: query %s is already defined in %s", rel, query.Name, previous)
+			}
+			seen[query.Name] = rel
+			g.queries = append(g.queries, query)
+		}
+	}
+	return nil
+}
+
+// parseQueryFile splits a .sql file into the queries started by its
+// "-- name: <Name> :<kind>" lines
+func parseQueryFile(file, content string) ([]NamedQuery, error) {
+	var queries []NamedQuery
+	var current *NamedQuery
+	var body []string
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		current.SQL = strings.TrimSuffix(strings.TrimSpace(strings.Join(body, "\n")), ";")
+		if err := validateQuery(current); err != nil {
+			return fmt.Errorf("%s: query %s: %w", file, current.Name, err)
+		}
+		queries = append(queries, *current)
+		return nil
+	}
+
+	for number, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if match := queryNameLine.FindStringSubmatch(trimmed); match != nil {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			current = &NamedQuery{Name: match[1], Kind: match[2], File: file}
+			body = nil
+			continue
+		}
+		if current == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("%s:%d: statement before the first -- name: line", file, number+1)
+			}
+			continue
+		}
+
+		if match := queryAnnotation.FindStringSubmatch(trimmed); match != nil {
+			if match[1] == "returns" {
+				current.Returns = match[2]
+				continue
+			}
+			def := queryFieldDef.FindStringSubmatch(match[2])
+			if def == nil {
+				return nil, fmt.Errorf("%s:%d: %s must be in the form <name> <type>", file, number+1, match[1])
+			}
+			field := QueryField{Name: queryFieldName(def[1]), Type: def[2], DBName: def[1]}
+			if match[1] == "param" {
+				current.Params = append(current.Params, field)
+			} else {
+				current.Columns = append(current.Columns, field)
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// validateQuery checks a query's name and kind, that its result is declared
+// once, and that its declared parameters match the placeholders of its SQL
+func validateQuery(query *NamedQuery) error {
+	if !token.IsIdentifier(query.Name) || !token.IsExported(query.Name) {
+		return fmt.Errorf("name must be an exported Go identifier")
+	}
+	if query.SQL == "" {
+		return fmt.Errorf("statement is empty")
+	}
+
+	switch query.Kind {
+	case "exec":
+		if query.Returns != "" || len(query.Columns) > 0 {
+			return fmt.Errorf(":exec queries return no rows, remove their columns")
+		}
+	case "one", "many":
+		if query.Returns == "" && len(query.Columns) == 0 {
+			return fmt.Errorf("declare the result with -- column: lines or -- returns: <Model>")
+		}
+		if query.Returns != "" && len(query.Columns) > 0 {
+			return fmt.Errorf("-- returns: and -- column: cannot be combined")
+		}
+	default:
+		return fmt.Errorf("unknown kind :%s, use :one, :many or :exec", query.Kind)
+	}
+
+	for _, fields := range [][]QueryField{query.Params, query.Columns} {
+		names := make(map[string]bool)
+		for _, field := range fields {
+			if names[field.Name] {
+				return fmt.Errorf("%s is declared twice", field.DBName)
+			}
+			names[field.Name] = true
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, param := range query.Params {
+		declared[param.DBName] = true
+	}
+	used := make(map[string]bool)
+	for _, name := range sqlParams(query.SQL) {
+		if !declared[name] {
+			return fmt.Errorf(":%s needs a -- param: declaration", name)
+		}
+		used[name] = true
+	}
+	for _, param := range query.Params {
+		if !used[param.DBName] {
+			return fmt.Errorf("param %s is not used", param.DBName)
+		}
+	}
+	return nil
+}
+
+// sqlParams returns the :name placeholders of query outside quotes and
+// comments, skipping PostgreSQL casts such as ::text, as storm.RawQuery binds
+// them
+func sqlParams(query string) []string {
+	var params []string
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return params
+			}
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return params
+			}
+			i += end
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			i++
+		case c == ':' && i+1 < len(query) && isQueryParamStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isQueryParamChar(query[end]) {
+				end++
+			}
+			params = append(params, query[i+1:end])
+			i = end - 1
+		}
+	}
+	return params
+}
+
+func isQueryParamStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isQueryParamChar(c byte) bool {
+	return isQueryParamStart(c) || c >= '0' && c <= '9' || c == '.'
+}
+
+var queryInitialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "url": "URL", "uuid": "UUID", "api": "API",
+	"http": "HTTP", "ip": "IP", "json": "JSON", "sql": "SQL", "uri": "URI",
+}
+
+// queryFieldName converts a snake_case parameter or column name to a Go
+// field name, upper-casing common initialisms: author_id = AuthorID
+func queryFieldName(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		if initialism, ok := queryInitialisms[strings.ToLower(part)]; ok {
+			parts[i] = initialism
+		} else if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// queryImports checks the types of the loaded queries against the models and
+// the imports of the models package, and returns the imports they need
+func (g *CodeGenerator) queryImports() (std, thirdParty []DTOImport, err error) {
+	reserved := map[string]bool{"WithTransaction": true, "WithTransactionOptions": true, "DryRun": true}
+	for _, model := range g.models {
+		reserved[pluralize(model.Name)] = true
+	}
+
+	packages := make(map[string]bool)
+	usesStorm := false
+	for _, query := range g.queries {
+		if reserved[query.Name] {
+			return nil, nil, fmt.Errorf("%s: query %s clashes with a generated Storm field or method", query.File, query.Name)
+		}
+		if query.Returns != "" {
+			if _, ok := g.models[query.Returns]; !ok {
+				return nil, nil, fmt.Errorf("%s: query %s returns unknown model %s", query.File, query.Name, query.Returns)
+			}
+		}
+
+		for _, fields := range [][]QueryField{query.Params, query.Columns} {
+			for _, field := range fields {
+				for _, match := range qualifiedType.FindAllStringSubmatch(field.Type, -1) {
+					if match[1] == "storm" {
+						usesStorm = true
+						continue
+					}
+					if _, ok := g.importPath(match[1]); !ok {
+						return nil, nil, fmt.Errorf("%s: query %s: package %s of %s is not imported by the models package", query.File, query.Name, match[1], field.Type)
+					}
+					packages[match[1]] = true
+				}
+			}
+		}
+	}
+
+	std, thirdParty = g.dtoImports(packages)
+	std = addDTOImport(std, DTOImport{Path: "context"})
+	if usesStorm {
+		thirdParty = addDTOImport(thirdParty, DTOImport{Name: "storm", Path: ormImportPath})
+	}
+	return std, thirdParty, nil
+}
+
+// generateQueries writes queries.go with a Storm method for every named query
+func (g *CodeGenerator) generateQueries() error {
+	if len(g.queries) == 0 {
+		return nil
+	}
+
+	std, thirdParty, err := g.queryImports()
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Package    string
+		Queries    []NamedQuery
+		StdImports []DTOImport
+		Imports    []DTOImport
+	}{
+		Package:    g.packageName,
+		Queries:    g.queries,
+		StdImports: std,
+		Imports:    thirdParty,
+	}
+	return g.executeTemplate("queries", "queries.go", data)
+}
@@ -0,0 +1,141 @@
+package orm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueryFile(t *testing.T) {
+	queries, err := parseQueryFile("queries/report.sql", `-- Reports
+
+-- name: TopAuthors :many
+-- param: since time.Time
+-- column: author_id int64
+-- column: posts int64
+SELECT author_id, count(*) AS posts FROM posts
+WHERE created_at > :since AND title <> ':draft' AND id::text <> ''
+GROUP BY author_id;
+
+-- name: PurgeDrafts :exec
+DELETE FROM posts WHERE published_at IS NULL;
+`)
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+
+	assert.Equal(t, NamedQuery{
+		Name: "TopAuthors",
+		Kind: "many",
+		File: "queries/report.sql",
+		SQL: `SELECT author_id, count(*) AS posts FROM posts
+WHERE created_at > :since AND title <> ':draft' AND id::text <> ''
+GROUP BY author_id`,
+		Params: []QueryField{{Name: "Since", Type: "time.Time", DBName: "since"}},
+		Columns: []QueryField{
+			{Name: "AuthorID", Type: "int64", DBName: "author_id"},
+			{Name: "Posts", Type: "int64", DBName: "posts"},
+		},
+	}, queries[0])
+	assert.Equal(t, "exec", queries[1].Kind)
+	assert.Equal(t, "DELETE FROM posts WHERE published_at IS NULL", queries[1].SQL)
+	assert.Equal(t, "purgeDraftsSQL", queries[1].ConstName())
+
+	invalid := map[string]string{
+		"unknown kind":     "-- name: A :all\n-- column: id int64\nSELECT id FROM posts",
+		"unexported name":  "-- name: topAuthors :many\n-- column: id int64\nSELECT id FROM posts",
+		"no result":        "-- name: A :many\nSELECT id FROM posts",
+		"exec with result": "-- name: A :exec\n-- column: id int64\nDELETE FROM posts",
+		"both results":     "-- name: A :one\n-- returns: Post\n-- column: id int64\nSELECT * FROM posts",
+		"undeclared param": "-- name: A :exec\nDELETE FROM posts WHERE id = :id",
+		"unused param":     "-- name: A :exec\n-- param: id int64\nDELETE FROM posts",
+		"malformed param":  "-- name: A :exec\n-- param: id\nDELETE FROM posts WHERE id = :id",
+		"duplicate column": "-- name: A :many\n-- column: id int64\n-- column: id int64\nSELECT id FROM posts",
+		"empty statement":  "-- name: A :exec\n",
+		"leading sql":      "SELECT 1;\n-- name: A :exec\nDELETE FROM posts",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseQueryFile("queries/report.sql", content)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQueriesGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+	queriesDir := filepath.Join(t.TempDir(), QueriesDir)
+	require.NoError(t, os.Mkdir(queriesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(queriesDir, "report.sql"), []byte(`
+-- name: TopAuthors :many
+-- param: since time.Time
+-- param: limit_to int
+-- column: author_id int64
+-- column: tags pq.StringArray
+SELECT author_id, array_agg(tag) AS tags FROM posts
+WHERE created_at > :since GROUP BY author_id LIMIT :limit_to;
+
+-- name: LatestPost :one
+-- returns: Post
+SELECT * FROM posts ORDER BY created_at DESC LIMIT 1;
+
+-- name: PurgeDrafts :exec
+DELETE FROM posts WHERE published_at IS NULL;
+`), 0o644))
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+	generator.imports["pq"] = "github.com/lib/pq"
+	post := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Post",
+		TableName:  "posts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Title", DBName: "title", Type: "string", DBDef: map[string]string{}},
+		},
+	})
+	generator.models[post.Name] = post
+
+	require.NoError(t, generator.loadQueries(queriesDir))
+	require.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "queries.go"))
+	require.NoError(t, err)
+	generated := string(content)
+
+	assert.Contains(t, generated, "\t\"context\"\n\t\"time\"\n\n\t\"github.com/lib/pq\"\n")
+	assert.Contains(t, generated, "\tLimitTo int       `db:\"limit_to\"`\n")
+	assert.Contains(t, generated, "\tTags     pq.StringArray `db:\"tags\" json:\"tags\"`\n")
+	assert.Contains(t, generated, `func (s *Storm) TopAuthors(ctx context.Context, params TopAuthorsParams) ([]TopAuthorsRow, error) {
+	rows := []TopAuthorsRow{}
+	if err := s.Storm.QueryNamed(ctx, "TopAuthors", topAuthorsSQL, params, &rows); err != nil {`)
+	assert.Contains(t, generated, "func (s *Storm) LatestPost(ctx context.Context) (*Post, error) {")
+	assert.Contains(t, generated, `return s.Storm.ExecNamed(ctx, "PurgeDrafts", purgeDraftsSQL, nil)`)
+	assert.NotContains(t, generated, "PurgeDraftsParams")
+}
+
+func TestQueriesGenerationRejects(t *testing.T) {
+	tests := map[string]string{
+		"unknown model":      "-- name: LatestPost :one\n-- returns: Article\nSELECT * FROM articles",
+		"unimported package": "-- name: Ids :many\n-- column: id uuid.UUID\nSELECT id FROM posts",
+		"repository clash":   "-- name: Posts :many\n-- returns: Post\nSELECT * FROM posts",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: t.TempDir()})
+			generator.models["Post"] = &ModelMetadata{Name: "Post", TableName: "posts", PrimaryKeys: []string{"id"}}
+
+			queries, err := parseQueryFile("queries/report.sql", content)
+			require.NoError(t, err)
+			generator.queries = queries
+
+			assert.Error(t, generator.generateQueries())
+		})
+	}
+}
@@ -2,8 +2,11 @@ package orm_generator
 
 import (
 	"fmt"
+	"go/token"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/eleven-am/storm/internal/parser"
 )
@@ -11,10 +14,12 @@ import (
 // StormTagParser is an alias for the parser package's StormTagParser
 type StormTagParser = parser.StormTagParser
 
-// ORMTagParser handles parsing of ORM-specific tags for code generation
+// ORMTagParser handles parsing of ORM-specific tags for code generation. It is
+// safe for concurrent use; parsed tags are cached and shared, so callers must
+// not modify them.
 type ORMTagParser struct {
 	// Cache for parsed tags
-	tagCache map[string]*ParsedORMTag
+	tagCache sync.Map
 	// Storm tag parser for unified tags
 	stormParser *StormTagParser
 }
@@ -37,13 +42,13 @@ type ParsedORMTag struct {
 	Through     string   // Through association
 	Validate    bool     // Whether to validate association
 	Autosave    bool     // Whether to autosave association
+	Count       bool     // Whether IncludeCount fills a <Name>Count field
 	Counter     string   // Counter cache column
 	Raw         string   // Raw tag value
 }
 
 func NewORMTagParser() *ORMTagParser {
 	return &ORMTagParser{
-		tagCache:    make(map[string]*ParsedORMTag),
 		stormParser: parser.NewStormTagParser(),
 	}
 }
@@ -53,8 +58,8 @@ func (p *ORMTagParser) ParseORMTag(tag string) (*ParsedORMTag, error) {
 		return nil, fmt.Errorf("empty ORM tag")
 	}
 
-	if cached, exists := p.tagCache[tag]; exists {
-		return cached, nil
+	if cached, exists := p.tagCache.Load(tag); exists {
+		return cached.(*ParsedORMTag), nil
 	}
 
 	parsed := &ParsedORMTag{
@@ -87,8 +92,8 @@ func (p *ORMTagParser) ParseORMTag(tag string) (*ParsedORMTag, error) {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
-	p.tagCache[tag] = parsed
-	return parsed, nil
+	actual, _ := p.tagCache.LoadOrStore(tag, parsed)
+	return actual.(*ParsedORMTag), nil
 }
 
 func (p *ORMTagParser) parseMainRelationship(main string, parsed *ParsedORMTag) error {
@@ -129,6 +134,9 @@ func (p *ORMTagParser) parseRelationshipOption(option string, parsed *ParsedORMT
 	case "no_autosave":
 		parsed.Autosave = false
 		return nil
+	case "count":
+		parsed.Count = true
+		return nil
 	}
 
 	if strings.Contains(option, ":") {
@@ -170,6 +178,9 @@ func (p *ORMTagParser) parseRelationshipOption(option string, parsed *ParsedORMT
 			parsed.Counter = value
 		case "conditions":
 			parsed.Conditions = strings.Split(value, ",")
+			for i, v := range parsed.Conditions {
+				parsed.Conditions[i] = strings.TrimSpace(v)
+			}
 		default:
 			return fmt.Errorf("unknown option: %s", key)
 		}
@@ -181,8 +192,15 @@ func (p *ORMTagParser) parseRelationshipOption(option string, parsed *ParsedORMT
 }
 
 func (p *ORMTagParser) validateAndSetDefaults(parsed *ParsedORMTag) error {
+	if parsed.Polymorphic != "" && parsed.Type == "has_many_through" {
+		return fmt.Errorf("polymorphic is not supported for has_many_through relationships")
+	}
+
 	switch parsed.Type {
 	case "belongs_to":
+		if parsed.ForeignKey == "" && parsed.Polymorphic != "" {
+			parsed.ForeignKey = parsed.Polymorphic + "_id"
+		}
 		if parsed.ForeignKey == "" {
 			parsed.ForeignKey = toSnakeCase(parsed.Target) + "_id"
 		}
@@ -191,6 +209,9 @@ func (p *ORMTagParser) validateAndSetDefaults(parsed *ParsedORMTag) error {
 		}
 
 	case "has_one", "has_many":
+		if parsed.ForeignKey == "" && parsed.Polymorphic != "" {
+			parsed.ForeignKey = parsed.Polymorphic + "_id"
+		}
 		if parsed.ForeignKey == "" {
 			return fmt.Errorf("foreign_key is required for %s relationships", parsed.Type)
 		}
@@ -241,10 +262,21 @@ type FieldMetadata struct {
 	IsUnique        bool              // Whether it has unique constraint
 	IsRequired      bool              // Whether it's required (not null)
 	IsAutoGenerated bool              // Whether it's auto-generated (serial, default:now(), etc)
+	IsImmutable     bool              // Whether it can only be set on insert
+	IsJSONIgnored   bool              // Whether it is left out of generated DTOs
+	IsMasked        bool              // Whether generated API responses mask it
+	IsSensitive     bool              // Whether query logs redact its values
+	AutoCreateTime  bool              // Whether it is set to NOW() on insert
+	AutoUpdateTime  bool              // Whether it is set to NOW() on insert and every update
+	Encrypted       string            // Encryption mode (randomized or deterministic), empty when stored in plain
+	Interval        bool              // Whether it is a time.Duration stored in an INTERVAL column
+	Computed        string            // SQL expression for read-only computed fields
 	DefaultValue    string            // Default value
 	Tags            map[string]string // All struct tags
 	DBDef           map[string]string // Parsed dbdef tags
 	Relationship    *ParsedORMTag     // Parsed ORM relationship tag
+	CountField      string            // Non-column field receiving the relationship's row count
+	CountType       string            // Go type of CountField
 }
 
 // ModelMetadata represents metadata about a model for code generation
@@ -258,6 +290,39 @@ type ModelMetadata struct {
 	PrimaryKeys   []string             // Primary key column names
 	Indexes       []IndexMetadata      // Index definitions
 	Constraints   []ConstraintMetadata // Constraint definitions
+	Scopes        []ScopeMetadata      // Query scopes declared on the table
+	Finders       []FinderMetadata     // Lookup methods generated for unique and indexed columns
+	Keysets       []KeysetMetadata     // Keyset pagination methods generated for indexes
+	ShardKey      string               // Column routing rows to shards, if the model is sharded
+	ReadOnly      bool                 // Read model over a view or query, generated without writes
+	Source        string               // SELECT statement a read model maps, empty for a view
+}
+
+// FinderMetadata represents a generated FindBy/FindAllBy lookup method
+type FinderMetadata struct {
+	Name   string        // Method name suffix, e.g. Email or TenantIDAndSlug
+	Unique bool          // Whether the lookup returns a single record
+	Params []FinderParam // Lookup arguments, in column order
+}
+
+// FinderParam represents a column argument of a generated finder
+type FinderParam struct {
+	Name   string // Go parameter name
+	Type   string // Go parameter type
+	DBName string // Database column name
+}
+
+// KeysetMetadata represents generated After/Before keyset pagination methods
+type KeysetMetadata struct {
+	Name   string        // Method name suffix, e.g. CreatedAtID
+	Params []FinderParam // Cursor values, in column order
+}
+
+// ScopeMetadata represents a named query scope
+type ScopeMetadata struct {
+	Name      string // Scope name
+	Condition string // SQL condition applied by the scope
+	IsDefault bool   // Whether the scope is applied to every query
 }
 
 // IndexMetadata represents index metadata
@@ -298,7 +363,7 @@ func (p *ORMTagParser) ParseModelFromTable(table parser.TableDefinition) (*Model
 
 		if fieldMeta.Relationship != nil {
 			metadata.Relationships = append(metadata.Relationships, fieldMeta)
-		} else {
+		} else if field.DBName != "-" {
 			metadata.Columns = append(metadata.Columns, fieldMeta)
 
 			if fieldMeta.IsPrimaryKey {
@@ -307,9 +372,319 @@ func (p *ORMTagParser) ParseModelFromTable(table parser.TableDefinition) (*Model
 		}
 	}
 
+	linkCountFields(metadata, table.Fields)
+	metadata.Scopes = parseScopes(table.TableLevel)
+	metadata.Indexes = parseIndexes(table.TableLevel)
+	metadata.Finders = buildFinders(metadata)
+	metadata.Keysets = buildKeysets(metadata)
+	applyReadModel(metadata, table.TableLevel)
+
 	return metadata, nil
 }
 
+var viewName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// applyReadModel marks a model declared storm:"readonly" as a read model. A
+// source naming a view becomes its table name; any other source is a SELECT
+// statement queried under the model's table name.
+func applyReadModel(metadata *ModelMetadata, tableLevel map[string]string) {
+	if _, readOnly := tableLevel["readonly"]; !readOnly {
+		return
+	}
+	metadata.ReadOnly = true
+
+	source := strings.TrimSpace(tableLevel["source"])
+	if viewName.MatchString(source) {
+		metadata.TableName = source
+	} else {
+		metadata.Source = source
+	}
+}
+
+// parseScopes reads the scope and default_scope table attributes, each holding
+// ";"-separated "name,condition" pairs
+func parseScopes(tableLevel map[string]string) []ScopeMetadata {
+	var scopes []ScopeMetadata
+	for _, attr := range []string{"scope", "default_scope"} {
+		value, ok := tableLevel[attr]
+		if !ok {
+			continue
+		}
+		for _, def := range strings.Split(value, ";") {
+			parts := strings.SplitN(def, ",", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			scopes = append(scopes, ScopeMetadata{
+				Name:      strings.TrimSpace(parts[0]),
+				Condition: strings.TrimSpace(parts[1]),
+				IsDefault: attr == "default_scope",
+			})
+		}
+	}
+	return scopes
+}
+
+// parseIndexes reads the index and unique table attributes. Index columns keep
+// only their name, dropping any ASC/DESC ordering.
+func parseIndexes(tableLevel map[string]string) []IndexMetadata {
+	var indexes []IndexMetadata
+	for _, attr := range []string{"index", "unique"} {
+		value, ok := tableLevel[attr]
+		if !ok {
+			continue
+		}
+		for _, def := range strings.Split(value, ";") {
+			def = strings.TrimSpace(def)
+			if def == "" {
+				continue
+			}
+
+			index := IndexMetadata{Unique: attr == "unique"}
+			if whereIdx := strings.Index(def, "where:"); whereIdx != -1 {
+				index.Partial = strings.TrimSpace(def[whereIdx+6:])
+				def = def[:whereIdx]
+			}
+			for _, option := range []string{" using:", " include:", " fillfactor:", " tablespace:"} {
+				if optionIdx := strings.Index(def, option); optionIdx != -1 {
+					def = def[:optionIdx]
+				}
+			}
+
+			parts := strings.Split(def, ",")
+			index.Name = strings.TrimSpace(parts[0])
+			for _, part := range parts[1:] {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				if strings.EqualFold(part, "unique") {
+					index.Unique = true
+					continue
+				}
+				index.Columns = append(index.Columns, strings.Fields(part)[0])
+			}
+
+			if len(index.Columns) > 0 {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	return indexes
+}
+
+// buildFinders derives FindBy methods from unique columns and unique indexes,
+// and FindAllBy methods from plain indexes. Lookups on the primary key, partial
+// indexes, expression indexes and randomized encrypted columns are skipped.
+func buildFinders(metadata *ModelMetadata) []FinderMetadata {
+	columns := make(map[string]FieldMetadata, len(metadata.Columns))
+	for _, col := range metadata.Columns {
+		columns[col.DBName] = col
+	}
+
+	var finders []FinderMetadata
+	seen := make(map[string]bool)
+	add := func(unique bool, dbNames []string) {
+		if unique && len(dbNames) == 1 && columns[dbNames[0]].IsPrimaryKey {
+			return
+		}
+
+		finder := FinderMetadata{Unique: unique}
+		names := make([]string, 0, len(dbNames))
+		for _, dbName := range dbNames {
+			col, ok := columns[dbName]
+			if !ok || col.IsArray || col.Encrypted == "randomized" {
+				return
+			}
+			names = append(names, col.Name)
+			finder.Params = append(finder.Params, FinderParam{
+				Name:   finderParamName(dbName),
+				Type:   finderParamType(col),
+				DBName: dbName,
+			})
+		}
+		finder.Name = strings.Join(names, "And")
+
+		key := fmt.Sprintf("%t:%s", unique, finder.Name)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		finders = append(finders, finder)
+	}
+
+	for _, col := range metadata.Columns {
+		if col.IsUnique {
+			add(true, []string{col.DBName})
+		}
+	}
+	for _, index := range metadata.Indexes {
+		if index.Partial == "" {
+			add(index.Unique, index.Columns)
+		}
+	}
+	return finders
+}
+
+// buildKeysets derives After/Before keyset methods from indexes. Indexes that
+// are not unique get the primary key appended so the cursor identifies a
+// single row. Indexes on nullable, array, JSON or encrypted columns are
+// skipped, since their rows cannot be ordered reliably by a row comparison.
+func buildKeysets(metadata *ModelMetadata) []KeysetMetadata {
+	columns := make(map[string]FieldMetadata, len(metadata.Columns))
+	for _, col := range metadata.Columns {
+		columns[col.DBName] = col
+	}
+
+	var keysets []KeysetMetadata
+	seen := make(map[string]bool)
+	for _, index := range metadata.Indexes {
+		dbNames := append([]string(nil), index.Columns...)
+		if !index.Unique {
+			if len(metadata.PrimaryKeys) == 0 {
+				continue
+			}
+			included := make(map[string]bool, len(dbNames))
+			for _, dbName := range dbNames {
+				included[dbName] = true
+			}
+			for _, pk := range metadata.PrimaryKeys {
+				if !included[pk] {
+					dbNames = append(dbNames, pk)
+				}
+			}
+		}
+
+		var keyset KeysetMetadata
+		names := make([]string, 0, len(dbNames))
+		for _, dbName := range dbNames {
+			col, ok := columns[dbName]
+			if !ok || !keysetColumn(col) {
+				keyset.Params = nil
+				break
+			}
+			names = append(names, col.Name)
+			keyset.Params = append(keyset.Params, FinderParam{
+				Name:   finderParamName(dbName),
+				Type:   finderParamType(col),
+				DBName: dbName,
+			})
+		}
+		keyset.Name = strings.Join(names, "")
+		if len(keyset.Params) == 0 || seen[keyset.Name] {
+			continue
+		}
+		seen[keyset.Name] = true
+		keysets = append(keysets, keyset)
+	}
+	return keysets
+}
+
+func keysetColumn(col FieldMetadata) bool {
+	return !col.IsPointer && !col.IsArray && col.Encrypted == "" && col.Type != "" &&
+		col.Type != "json.RawMessage" && col.Type != "storm.JSONData" && !strings.HasPrefix(col.Type, "JSONField[")
+}
+
+// encryptionMode normalises the encrypted attribute, which defaults to randomized
+func encryptionMode(mode string) string {
+	if mode == "" {
+		return "randomized"
+	}
+	return mode
+}
+
+// applyAutoTimestamps marks fields tagged auto_create_time or auto_update_time,
+// and time.Time fields named CreatedAt or UpdatedAt, for automatic timestamps
+func applyAutoTimestamps(fieldMeta *FieldMetadata, dbDef map[string]string) {
+	isTime := strings.TrimPrefix(fieldMeta.Type, "*") == "time.Time"
+	if _, ok := dbDef["auto_create_time"]; ok || isTime && fieldMeta.Name == "CreatedAt" {
+		fieldMeta.AutoCreateTime = true
+	}
+	if _, ok := dbDef["auto_update_time"]; ok || isTime && fieldMeta.Name == "UpdatedAt" {
+		fieldMeta.AutoUpdateTime = true
+	}
+}
+
+// validateProtectedField checks that encrypted columns use the orm type that
+// performs their encryption, as a pointer when the column is nullable, and
+// that masked columns are plain string values
+func validateProtectedField(field FieldMetadata) error {
+	switch field.Encrypted {
+	case "randomized":
+		if !strings.HasSuffix(field.Type, ".EncryptedString") || field.IsArray {
+			return fmt.Errorf("encrypted field %s must have type storm.EncryptedString, got %s", field.Name, field.Type)
+		}
+	case "deterministic":
+		if !strings.HasSuffix(field.Type, ".DeterministicString") || field.IsArray {
+			return fmt.Errorf("encrypted:deterministic field %s must have type storm.DeterministicString, got %s", field.Name, field.Type)
+		}
+	}
+
+	if field.Encrypted != "" && !field.IsPointer && !field.IsPrimaryKey {
+		if _, notNull := field.DBDef["not_null"]; !notNull {
+			return fmt.Errorf("encrypted field %s is nullable: make it a pointer, which scans NULL as nil, or mark it not_null", field.Name)
+		}
+	}
+
+	if field.IsMasked && (field.IsPointer || field.IsArray) {
+		return fmt.Errorf("masked field %s must be a string value, not a pointer or slice", field.Name)
+	}
+	return nil
+}
+
+// finderParamName converts a column name to a Go parameter name, upper-casing
+// common initialisms after the first word: user_id = userID
+func finderParamName(dbName string) string {
+	first, rest, _ := strings.Cut(dbName, "_")
+	name := strings.ToLower(first) + queryFieldName(rest)
+	if token.IsKeyword(name) || name == "ctx" || name == "r" {
+		return name + "Value"
+	}
+	return name
+}
+
+// finderParamType returns the Go type of a finder or keyset argument. The
+// generator imports the packages these types name, or falls back to
+// interface{} for packages the models package does not import; deterministic
+// encrypted columns take storm.DeterministicString.
+func finderParamType(col FieldMetadata) string {
+	if col.Encrypted == "deterministic" {
+		return "storm.DeterministicString"
+	}
+	if col.Type == "" {
+		return "interface{}"
+	}
+	return col.Type
+}
+
+// linkCountFields attaches the non-column integer field named
+// <Relationship>Count (e.g. PostsCount with db:"-") of each relationship
+// tagged count, so IncludeCount can fill it. validateRelationship rejects
+// counted relationships left without one.
+func linkCountFields(metadata *ModelMetadata, fields []parser.FieldDefinition) {
+	countFields := make(map[string]string)
+	for _, field := range fields {
+		if field.DBName != "-" || field.IsPointer || field.IsArray {
+			continue
+		}
+		switch field.Type {
+		case "int", "int32", "int64", "uint", "uint32", "uint64":
+			countFields[field.Name] = field.Type
+		}
+	}
+
+	for i := range metadata.Relationships {
+		rel := &metadata.Relationships[i]
+		if rel.Relationship == nil || !rel.Relationship.Count || rel.Relationship.Type == "belongs_to" {
+			continue
+		}
+		if countType, ok := countFields[rel.Name+"Count"]; ok {
+			rel.CountField = rel.Name + "Count"
+			rel.CountType = countType
+		}
+	}
+}
+
 func (p *ORMTagParser) ParseFieldFromAST(field parser.FieldDefinition) (FieldMetadata, error) {
 	return p.parseFieldFromAST(field)
 }
@@ -338,6 +713,32 @@ func (p *ORMTagParser) parseFieldFromAST(field parser.FieldDefinition) (FieldMet
 		fieldMeta.IsUnique = true
 	}
 
+	if _, isImmutable := field.DBDef["immutable"]; isImmutable {
+		fieldMeta.IsImmutable = true
+	}
+
+	if _, isIgnored := field.DBDef["json_ignore"]; isIgnored || jsonName(field.JSONTag) == "-" {
+		fieldMeta.IsJSONIgnored = true
+	}
+
+	if mode, isEncrypted := field.DBDef["encrypted"]; isEncrypted {
+		fieldMeta.Encrypted = encryptionMode(mode)
+	}
+
+	if _, isMasked := field.DBDef["masked"]; isMasked {
+		fieldMeta.IsMasked = true
+	}
+
+	if _, isSensitive := field.DBDef["sensitive"]; isSensitive {
+		fieldMeta.IsSensitive = true
+	}
+
+	applyAutoTimestamps(&fieldMeta, field.DBDef)
+
+	if computed, isComputed := field.DBDef["computed"]; isComputed {
+		fieldMeta.Computed = computed
+	}
+
 	if defaultVal, hasDefault := field.DBDef["default"]; hasDefault {
 		fieldMeta.DefaultValue = defaultVal
 		if isAutoGeneratedDefault(defaultVal) || field.DBDef["type"] == "serial" {
@@ -345,8 +746,12 @@ func (p *ORMTagParser) parseFieldFromAST(field parser.FieldDefinition) (FieldMet
 		}
 	}
 
+	if err := validateProtectedField(fieldMeta); err != nil {
+		return fieldMeta, err
+	}
+
 	if field.StormTag != "" {
-		isRelationshipField := field.IsArray || field.IsPointer
+		isRelationshipField := (field.IsArray || field.IsPointer) && parser.IsRelationshipTag(field.StormTag)
 		parsed, err := p.stormParser.ParseStormTag(field.StormTag, isRelationshipField)
 		if err != nil {
 			return fieldMeta, fmt.Errorf("invalid storm tag: %w", err)
@@ -370,6 +775,7 @@ func (p *ORMTagParser) parseFieldFromAST(field parser.FieldDefinition) (FieldMet
 				Through:     parsed.Through,
 				Validate:    parsed.Validate,
 				Autosave:    parsed.Autosave,
+				Count:       parsed.Count,
 				Counter:     parsed.Counter,
 				Raw:         parsed.Raw,
 			}
@@ -11,7 +11,6 @@ const metadataTemplate = `//go:build !exclude_generated
 //
 // Source package: {{ .Package }}
 // Model: {{ .Model.Name }}
-// Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -25,6 +24,9 @@ import (
 	{{- if .Model.Relationships }}
 	"context"
 	{{- end }}
+	{{- if .FormatsKeys }}
+	"fmt"
+	{{- end }}
 	storm "github.com/eleven-am/storm/pkg/storm-orm"
 )
 
@@ -39,20 +41,38 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 			FieldName:       "{{ .Name }}",
 			DBName:          "{{ .DBName }}",
 			GoType:          "{{ .Type }}",
+			{{- if .DBType }}
+			DBType:          {{ printf "%q" .DBType }},
+			{{- end }}
 			IsPointer:       {{ .IsPointer }},
 			IsPrimaryKey:    {{ .IsPrimaryKey }},
 			IsAutoGenerated: {{ .IsAutoGenerated }},
+			{{- if .IsImmutable }}
+			IsImmutable:     true,
+			{{- end }}
+			{{- if or .IsSensitive .IsMasked .Encrypted }}
+			IsSensitive:     true,
+			{{- end }}
+			{{- if .AutoCreateTime }}
+			AutoCreateTime:  true,
+			{{- end }}
+			{{- if .AutoUpdateTime }}
+			AutoUpdateTime:  true,
+			{{- end }}
+			{{- if .Computed }}
+			Computed:        {{ printf "%q" .Computed }},
+			{{- end }}
 			
 			// Generated accessor functions for zero-reflection field access
 			GetValue: func(model interface{}) interface{} {
 				m := model.({{ $.Model.Name }})
 				{{- if .IsPointer }}
 				if m.{{ .Name }} != nil {
-					return *m.{{ .Name }}
+					return {{ if .Interval }}storm.Interval(*m.{{ .Name }}){{ else }}*m.{{ .Name }}{{ end }}
 				}
 				return nil
 				{{- else }}
-				return m.{{ .Name }}
+				return {{ if .Interval }}storm.Interval(m.{{ .Name }}){{ else }}m.{{ .Name }}{{ end }}
 				{{- end }}
 			},
 			{{- if .IsPointer }}
@@ -76,11 +96,49 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 		{{- end }}
 	},
 	
+	ColumnOrder: []string{
+		{{- range .Model.Columns }}
+		"{{ .Name }}",
+		{{- end }}
+	},
+	
+	// Zero-reflection row scanning, in ColumnOrder
+	ScanColumns: []string{
+		{{- range .Model.Columns }}
+		"{{ .DBName }}",
+		{{- end }}
+	},
+	ScanFields: func(model interface{}) []interface{} {
+		m := model.(*{{ .Model.Name }})
+		return []interface{}{
+			{{- range .Model.Columns }}
+			{{- if and .Interval .IsPointer }}
+			storm.IntervalPointer(&m.{{ .Name }}),
+			{{- else if .Interval }}
+			(*storm.Interval)(&m.{{ .Name }}),
+			{{- else }}
+			&m.{{ .Name }},
+			{{- end }}
+			{{- end }}
+		}
+	},
+	
 	PrimaryKeys: []string{
 		{{- range .Model.PrimaryKeys }}
 		"{{ . }}",
 		{{- end }}
 	},
+	{{- if .Model.ShardKey }}
+
+	ShardKey: "{{ .Model.ShardKey }}",
+	{{- end }}
+	{{- if .Model.ReadOnly }}
+
+	ReadOnly: true,
+	{{- if .Model.Source }}
+	Source:   {{ quote .Model.Source }},
+	{{- end }}
+	{{- end }}
 	
 	Relationships: map[string]*storm.RelationshipMetadata{
 		{{- range .Model.Relationships }}
@@ -100,8 +158,8 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 			{{- if .Relationship.TargetKey }}
 			TargetKey: "{{ .Relationship.TargetKey }}",
 			{{- end }}
-			{{- if .Relationship.Through }}
-			Through: "{{ .Relationship.Through }}",
+			{{- with or .Relationship.JoinTable .Relationship.Through }}
+			Through: "{{ . }}",
 			{{- end }}
 			{{- if .Relationship.SourceFK }}
 			ThroughFK: "{{ .Relationship.SourceFK }}",
@@ -109,34 +167,142 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 			{{- if .Relationship.TargetFK }}
 			ThroughTK: "{{ .Relationship.TargetFK }}",
 			{{- end }}
+			{{- if .Relationship.Dependent }}
+			Dependent: "{{ .Relationship.Dependent }}",
+			{{- end }}
+			{{- if .Relationship.Conditions }}
+			Conditions: []string{ {{- range $i, $c := .Relationship.Conditions }}{{ if $i }}, {{ end }}{{ printf "%q" $c }}{{ end -}} },
+			{{- end }}
+			{{- if .Relationship.OrderBy }}
+			OrderBy: {{ printf "%q" .Relationship.OrderBy }},
+			{{- end }}
+			{{- with index $.TargetScopes .Name }}
+			TargetScopes: []string{ {{- range $i, $c := . }}{{ if $i }}, {{ end }}{{ printf "%q" $c }}{{ end -}} },
+			{{- end }}
+			{{- if .Relationship.Polymorphic }}
+			PolymorphicType: "{{ .Relationship.Polymorphic }}_type",
+			{{- if eq .Relationship.Type "belongs_to" }}
+			PolymorphicValue: "{{ .Relationship.Target }}",
+			{{- else }}
+			PolymorphicValue: "{{ $.Model.Name }}",
+			{{- end }}
+			{{- end }}
 			
-			// Zero-reflection relationship scanning - directly scan and set on model
-			ScanToModel: func(ctx context.Context, exec storm.DBExecutor, query string, args []interface{}, model interface{}) error {
-				{{- if or (eq .Relationship.Type "has_many") (eq .Relationship.Type "has_many_through") }}
-				var {{ lower .Name }} []{{ .Relationship.Target }}
-				err := exec.SelectContext(ctx, &{{ lower .Name }}, query, args...)
-				if err != nil {
-					return err
+			{{- if .CountField }}
+
+			// Receives the row count loaded by IncludeCount
+			SetCount: func(model interface{}, count int64) {
+				model.(*{{ $.Model.Name }}).{{ .CountField }} = {{ .CountType }}(count)
+			},
+			{{- end }}
+
+			{{- if .Relationship.Autosave }}
+
+			// Records persisted with the model by Create
+			Autosave: true,
+			Related: func(model interface{}) []interface{} {
+				m := model.(*{{ $.Model.Name }})
+				{{- if .IsArray }}
+				related := make([]interface{}, len(m.{{ .Name }}))
+				for i := range m.{{ .Name }} {
+					related[i] = &m.{{ .Name }}[i]
 				}
-				model.(*{{ $.Model.Name }}).{{ .Name }} = {{ lower .Name }}
-				{{- else if or (eq .Relationship.Type "has_one") (eq .Relationship.Type "belongs_to") }}
-				var {{ lower .Name }} {{ .Relationship.Target }}
-				err := exec.GetContext(ctx, &{{ lower .Name }}, query, args...)
-				if err != nil {
-					return err
+				return related
+				{{- else }}
+				if m.{{ .Name }} == nil {
+					return nil
+				}
+				return []interface{}{m.{{ .Name }}}
+				{{- end }}
+			},
+			{{- end }}
+
+			{{- $rel := . }}
+			{{- $many := or (eq .Relationship.Type "has_many") (eq .Relationship.Type "has_many_through") }}
+			{{- with index $.Loads .Name }}
+
+			// Assigns the rows of one batched query to every model, without reflection
+			Load: func(ctx context.Context, exec storm.DBExecutor, query string, args []interface{}, models []interface{}) error {
+				{{- if eq $rel.Relationship.Type "has_many_through" }}
+				var rows []struct {
+					{{ $rel.Relationship.Target }}
+					StormOwner {{ if .OwnerText }}string{{ else }}{{ .OwnerType }}{{ end }} ` + "`db:\"storm_owner\"`" + `
 				}
-				{{- if .IsPointer }}
-				model.(*{{ $.Model.Name }}).{{ .Name }} = &{{ lower .Name }}
 				{{- else }}
-				model.(*{{ $.Model.Name }}).{{ .Name }} = {{ lower .Name }}
+				var rows []{{ $rel.Relationship.Target }}
 				{{- end }}
+				if query != "" {
+					if err := exec.SelectContext(ctx, &rows, query, args...); err != nil {
+						return err
+					}
+				}
+
+				{{- if $many }}
+				related := make(map[interface{}][]{{ $rel.Relationship.Target }}, len(rows))
+				{{- else }}
+				related := make(map[interface{}]{{ $rel.Relationship.Target }}, len(rows))
 				{{- end }}
+				for _, row := range rows {
+					{{- if .RowPointer }}
+					if row.{{ .RowField }} == nil {
+						continue
+					}
+					key := *row.{{ .RowField }}
+					{{- else }}
+					key := row.{{ .RowField }}
+					{{- end }}
+					{{- if eq $rel.Relationship.Type "has_many_through" }}
+					related[key] = append(related[key], row.{{ $rel.Relationship.Target }})
+					{{- else if $many }}
+					related[key] = append(related[key], row)
+					{{- else }}
+					if _, ok := related[key]; !ok {
+						related[key] = row
+					}
+					{{- end }}
+				}
+
+				for _, model := range models {
+					m := model.(*{{ $.Model.Name }})
+					{{- if $many }}
+					m.{{ $rel.Name }} = []{{ $rel.Relationship.Target }}{}
+					{{- else if $rel.IsPointer }}
+					m.{{ $rel.Name }} = nil
+					{{- else }}
+					m.{{ $rel.Name }} = {{ $rel.Relationship.Target }}{}
+					{{- end }}
+					{{- if .OwnerPointer }}
+					if m.{{ .OwnerField }} == nil {
+						continue
+					}
+					{{- end }}
+					{{- if .OwnerText }}
+					key := fmt.Sprint({{ if .OwnerPointer }}*{{ end }}m.{{ .OwnerField }})
+					{{- else }}
+					key := {{ if .OwnerPointer }}*{{ end }}m.{{ .OwnerField }}
+					{{- end }}
+					if found, ok := related[key]; ok {
+						m.{{ $rel.Name }} = {{ if and (not $many) $rel.IsPointer }}&{{ end }}found
+					}
+				}
 				return nil
 			},
+			{{- end }}
 		},
 		{{- end }}
 	},
 }
+{{- if .Linked }}
+
+// Autosave and destroy targets are linked here rather than in the declaration
+// above, which would form an initialization cycle between models that refer to
+// each other
+func init() {
+	{{- range .Linked }}
+	{{ $.Model.Name }}Metadata.Relationships["{{ .Name }}"].TargetMetadata = {{ .Relationship.Target }}Metadata
+	{{- end }}
+}
+{{- end }}
 `
 
 // columnTemplate generates type-safe column constants
@@ -150,7 +316,6 @@ const columnTemplate = `//go:build !exclude_generated
 //
 // Source package: {{ .Package }}
 // Models found: {{ len .Models }}
-// Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -166,15 +331,35 @@ import (
 )
 
 {{range $modelName, $model := .Models}}
-// {{ $model.Name }}s provides type-safe column references for {{ $model.Name }}
-var {{ $model.Name }}s = struct {
+// {{ $model.Name }}Columns provides type-safe column references for {{ $model.Name }}
+type {{ $model.Name }}Columns struct {
+	table string
 	{{range $model.Columns}}
-	{{ sanitizeGoName .Name }} {{ if eq .Type "string" }}storm.StringColumn{{ else if eq .Type "int" }}storm.NumericColumn[int]{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{{ else if eq .Type "bool" }}storm.BoolColumn{{ else if eq .Type "time.Time" }}storm.TimeColumn{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{{ else if eq .Type "" }}storm.StringColumn{{ else }}storm.Column[interface{}]{{ end }} ` + "`json:\"{{ .DBName }}\"`" + `
+	{{ sanitizeGoName .Name }} {{ if eq .Encrypted "randomized" }}storm.EncryptedColumn{{ else if eq .Encrypted "deterministic" }}storm.Column[storm.DeterministicString]{{ else if eq .Type "string" }}storm.StringColumn{{ else if eq .Type "int" }}storm.NumericColumn[int]{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{{ else if eq .Type "bool" }}storm.BoolColumn{{ else if eq .Type "time.Time" }}storm.TimeColumn{{ else if .Interval }}storm.IntervalColumn{{ else if eq .Type "time.Duration" }}storm.NumericColumn[time.Duration]{{ else if eq .Type "storm.Date" }}storm.DateColumn{{ else if eq .Type "storm.TimeOfDay" }}storm.Column[storm.TimeOfDay]{{ else if eq .Type "storm.DateTime" }}storm.Column[storm.DateTime]{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{{ else if eq .Type "" }}storm.StringColumn{{ else }}storm.Column[interface{}]{{ end }} ` + "`json:\"{{ .DBName }}\"`" + `
 	{{end}}
-}{
+}
+
+// {{ $model.Name }}s provides type-safe column references for {{ $model.Name }}
+var {{ $model.Name }}s = new{{ $model.Name }}Columns("{{ $model.TableName }}")
+
+// As returns the column references qualified by alias, for queries that
+// alias the table with Query.As
+func ({{ $model.Name }}Columns) As(alias string) {{ $model.Name }}Columns {
+	return new{{ $model.Name }}Columns(alias)
+}
+
+// Table returns the quoted table name, or alias, the columns are qualified by
+func (c {{ $model.Name }}Columns) Table() string {
+	return storm.QuoteIdent(c.table)
+}
+
+func new{{ $model.Name }}Columns(table string) {{ $model.Name }}Columns {
+	return {{ $model.Name }}Columns{
+	table: table,
 	{{range $model.Columns}}
-	{{ sanitizeGoName .Name }}: {{ if eq .Type "string" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "int" }}storm.NumericColumn[int]{ComparableColumn: storm.ComparableColumn[int]{Column: storm.Column[int]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{ComparableColumn: storm.ComparableColumn[int32]{Column: storm.Column[int32]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{ComparableColumn: storm.ComparableColumn[int64]{Column: storm.Column[int64]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{ComparableColumn: storm.ComparableColumn[float32]{Column: storm.Column[float32]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{ComparableColumn: storm.ComparableColumn[float64]{Column: storm.Column[float64]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "bool" }}storm.BoolColumn{Column: storm.Column[bool]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "time.Time" }}storm.TimeColumn{ComparableColumn: storm.ComparableColumn[time.Time]{Column: storm.Column[time.Time]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{Column: storm.Column[[]string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{Column: storm.Column[{{ .Type }}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else }}storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}{{ end }},
+	{{ sanitizeGoName .Name }}: {{ if eq .Encrypted "randomized" }}storm.EncryptedColumn{Name: "{{ .DBName }}", Table: table}{{ else if eq .Encrypted "deterministic" }}storm.Column[storm.DeterministicString]{Name: "{{ .DBName }}", Table: table}{{ else if eq .Type "string" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "int" }}storm.NumericColumn[int]{ComparableColumn: storm.ComparableColumn[int]{Column: storm.Column[int]{Name: "{{ .DBName }}", Table: table}}}{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{ComparableColumn: storm.ComparableColumn[int32]{Column: storm.Column[int32]{Name: "{{ .DBName }}", Table: table}}}{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{ComparableColumn: storm.ComparableColumn[int64]{Column: storm.Column[int64]{Name: "{{ .DBName }}", Table: table}}}{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{ComparableColumn: storm.ComparableColumn[float32]{Column: storm.Column[float32]{Name: "{{ .DBName }}", Table: table}}}{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{ComparableColumn: storm.ComparableColumn[float64]{Column: storm.Column[float64]{Name: "{{ .DBName }}", Table: table}}}{{ else if eq .Type "bool" }}storm.BoolColumn{Column: storm.Column[bool]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "time.Time" }}storm.TimeColumn{ComparableColumn: storm.ComparableColumn[time.Time]{Column: storm.Column[time.Time]{Name: "{{ .DBName }}", Table: table}}}{{ else if .Interval }}storm.IntervalColumn{Column: storm.Column[time.Duration]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "time.Duration" }}storm.NumericColumn[time.Duration]{ComparableColumn: storm.ComparableColumn[time.Duration]{Column: storm.Column[time.Duration]{Name: "{{ .DBName }}", Table: table}}}{{ else if eq .Type "storm.Date" }}storm.DateColumn{Column: storm.Column[storm.Date]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "storm.TimeOfDay" }}storm.Column[storm.TimeOfDay]{Name: "{{ .DBName }}", Table: table}{{ else if eq .Type "storm.DateTime" }}storm.Column[storm.DateTime]{Name: "{{ .DBName }}", Table: table}{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{Column: storm.Column[[]string]{Name: "{{ .DBName }}", Table: table}}{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{Column: storm.Column[{{ .Type }}]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: table}}{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: table}}{{ else if eq .Type "" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: table}}{{ else }}storm.Column[interface{}]{Name: "{{ .DBName }}", Table: table}{{ end }},
 	{{end}}
+	}
 }
 
 // {{ $model.Name }}Table provides table-level operations for {{ $model.Name }}
@@ -197,7 +382,6 @@ const repositoryTemplate = `//go:build !exclude_generated
 //
 // Source package: {{ .Package }}
 // Model: {{ .Model.Name }}
-// Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -210,10 +394,25 @@ package {{ .Package }}
 import (
 	"context"
 	"fmt"
+	{{- range .Imports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+	{{- end }}
 	storm "github.com/eleven-am/storm/pkg/storm-orm"
 	"github.com/jmoiron/sqlx"
 )
 
+{{- if .Model.ReadOnly }}
+// {{ .Model.Name }}Repository provides type-safe reads of {{ .Model.Name }}, a read model over
+{{- if .Model.Source }} a query{{ else }} the {{ .Model.TableName }} view{{ end }}.
+// It has no write methods; reads come from storm.ReadOnlyRepository:
+//   - FindByID(ctx, id) - Find record by primary key
+//   - FindOneBy(ctx, columns, values...) - Find record by column values
+//   - FindAllBy(ctx, columns, values...) - Find all records by column values
+//   - Query(ctx) - Create new query builder for complex queries
+type {{ .Model.Name }}Repository struct {
+	*storm.ReadOnlyRepository[{{ .Model.Name }}]
+}
+{{- else }}
 // {{ .Model.Name }}Repository provides type-safe operations for {{ .Model.Name }}
 //
 // The repository inherits these operations from storm.Repository:
@@ -221,6 +420,8 @@ import (
 // Single Record Operations:
 //   - Create(ctx, record) - Insert single record, returns saved record
 //   - FindByID(ctx, id) - Find record by primary key
+//   - FindOneBy(ctx, columns, values...) - Find record by column values
+//   - FindAllBy(ctx, columns, values...) - Find all records by column values
 //   - Update(ctx, record) - Update single record by primary key, returns updated record
 //   - Delete(ctx, id) - Delete record by primary key ID, returns deleted record
 //   - DeleteRecord(ctx, record) - Delete record using the record instance, returns deleted record
@@ -230,6 +431,7 @@ import (
 //   - BulkUpdate(ctx, records, opts) - Update multiple records with bulk operation
 //   - Upsert(ctx, record, opts) - Insert or update single record on conflict
 //   - UpsertMany(ctx, records, opts) - Insert or update multiple records on conflict
+//   - CreateManyPartial, UpsertManyPartial - Write the rows that succeed and report the rest
 //
 // Query Building:
 //   - Query(ctx) - Create new query builder for complex queries
@@ -252,15 +454,56 @@ import (
 type {{ .Model.Name }}Repository struct {
 	*storm.Repository[{{ .Model.Name }}]
 }
+{{- end }}
+
+// {{ .Model.Name }}Store is the public surface of {{ .Model.Name }}Repository. Depend on it in
+// application code so a mock or an in-memory fake can stand in for the database.
+type {{ .Model.Name }}Store interface {
+{{- if not .Model.ReadOnly }}
+	Create(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error)
+{{- end }}
+	FindByID(ctx context.Context, id interface{}) (*{{ .Model.Name }}, error)
+	FindOneBy(ctx context.Context, columns []string, values ...interface{}) (*{{ .Model.Name }}, error)
+	FindAllBy(ctx context.Context, columns []string, values ...interface{}) ([]{{ .Model.Name }}, error)
+{{- if not .Model.ReadOnly }}
+	Update(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error)
+	UpdateFields(ctx context.Context, id interface{}, updates map[string]interface{}) (*{{ .Model.Name }}, error)
+	UpdateMany(ctx context.Context, records []{{ .Model.Name }}) (int64, error)
+	Delete(ctx context.Context, id interface{}) (*{{ .Model.Name }}, error)
+	DeleteRecord(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error)
+	CreateMany(ctx context.Context, records []{{ .Model.Name }}) error
+	Upsert(ctx context.Context, record *{{ .Model.Name }}, opts storm.UpsertOptions) error
+	UpsertMany(ctx context.Context, records []{{ .Model.Name }}, opts storm.UpsertOptions) error
+	CreateManyPartial(ctx context.Context, records []{{ .Model.Name }}, opts storm.BatchOptions) (*storm.BatchResult, error)
+	UpsertManyPartial(ctx context.Context, records []{{ .Model.Name }}, upsert storm.UpsertOptions, opts storm.BatchOptions) (*storm.BatchResult, error)
+{{- end }}
+	Query(ctx context.Context) *{{ .Model.Name }}Query
+{{- range .Model.Finders }}
+{{- if .Unique }}
+	FindBy{{ .Name }}(ctx context.Context{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}) (*{{ $.Model.Name }}, error)
+{{- else }}
+	FindAllBy{{ .Name }}(ctx context.Context{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}) ([]{{ $.Model.Name }}, error)
+{{- end }}
+{{- end }}
+}
+
+var _ {{ .Model.Name }}Store = (*{{ .Model.Name }}Repository)(nil)
 
 func new{{ .Model.Name }}Repository(db *sqlx.DB) (*{{ .Model.Name }}Repository, error) {
 	baseRepo, err := storm.NewRepository[{{ .Model.Name }}](db, {{ .Model.Name }}Metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base repository: %w", err)
 	}
+{{- if .Model.Scopes }}
+	baseRepo = with{{ .Model.Name }}Scopes(baseRepo)
+{{- end }}
 
 	return &{{ .Model.Name }}Repository{
+{{- if .Model.ReadOnly }}
+		ReadOnlyRepository: storm.ReadOnly(baseRepo),
+{{- else }}
 		Repository: baseRepo,
+{{- end }}
 	}, nil
 }
 
@@ -269,11 +512,28 @@ func new{{ .Model.Name }}RepositoryWithTx(tx *sqlx.Tx) (*{{ .Model.Name }}Reposi
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base repository with transaction: %w", err)
 	}
+{{- if .Model.Scopes }}
+	baseRepo = with{{ .Model.Name }}Scopes(baseRepo)
+{{- end }}
 
 	return &{{ .Model.Name }}Repository{
+{{- if .Model.ReadOnly }}
+		ReadOnlyRepository: storm.ReadOnly(baseRepo),
+{{- else }}
 		Repository: baseRepo,
+{{- end }}
 	}, nil
 }
+{{- if .Model.Scopes }}
+
+// with{{ .Model.Name }}Scopes registers the scopes declared on the {{ .Model.Name }} struct
+func with{{ .Model.Name }}Scopes(repo *storm.Repository[{{ .Model.Name }}]) *storm.Repository[{{ .Model.Name }}] {
+	return repo.
+{{- range $i, $scope := .Model.Scopes }}{{ if $i }}.{{ end }}
+		{{ if $scope.IsDefault }}DefaultScope{{ else }}Scope{{ end }}({{ printf "%q" $scope.Name }}, storm.SQLScope[{{ $.Model.Name }}]({{ printf "%q" $scope.Condition }}))
+{{- end }}
+}
+{{- end }}
 
 // Query returns a type-safe query builder for {{ .Model.Name }}
 //
@@ -306,7 +566,7 @@ func new{{ .Model.Name }}RepositoryWithTx(tx *sqlx.Tx) (*{{ .Model.Name }}Reposi
 {{- end }}
 func (r *{{ .Model.Name }}Repository) Query(ctx context.Context) *{{ .Model.Name }}Query {
 	return &{{ .Model.Name }}Query{
-		Query: r.Repository.Query(ctx),
+		Query: r.{{ if .Model.ReadOnly }}ReadOnlyRepository{{ else }}Repository{{ end }}.Query(ctx),
 		repo:  r,
 	}
 }
@@ -331,13 +591,29 @@ func (r *{{ .Model.Name }}Repository) Authorize(fn func(ctx context.Context, que
 	}
 	
 	// Call the base Repository.Authorize with the converted function
-	baseRepo := r.Repository.Authorize(genericFn)
+	baseRepo := r.{{ if .Model.ReadOnly }}ReadOnlyRepository{{ else }}Repository{{ end }}.Authorize(genericFn)
 	
 	// Return a new {{ .Model.Name }}Repository wrapping the authorized base repository
 	return &{{ .Model.Name }}Repository{
-		Repository: baseRepo,
+		{{ if .Model.ReadOnly }}ReadOnlyRepository{{ else }}Repository{{ end }}: baseRepo,
 	}
 }
+{{- range .Model.Finders }}
+{{- if .Unique }}
+
+// FindBy{{ .Name }} returns the {{ $.Model.Name }} with the given {{ range $i, $p := .Params }}{{ if $i }} and {{ end }}{{ $p.DBName }}{{ end }}.
+// A *storm.NotFoundError is returned when no record matches.
+func (r *{{ $.Model.Name }}Repository) FindBy{{ .Name }}(ctx context.Context{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}) (*{{ $.Model.Name }}, error) {
+	return r.FindOneBy(ctx, []string{ {{- range $i, $p := .Params }}{{ if $i }}, {{ end }}"{{ $p.DBName }}"{{ end -}} }{{ range .Params }}, {{ .Name }}{{ end }})
+}
+{{- else }}
+
+// FindAllBy{{ .Name }} returns every {{ $.Model.Name }} with the given {{ range $i, $p := .Params }}{{ if $i }} and {{ end }}{{ $p.DBName }}{{ end }}
+func (r *{{ $.Model.Name }}Repository) FindAllBy{{ .Name }}(ctx context.Context{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}) ([]{{ $.Model.Name }}, error) {
+	return r.FindAllBy(ctx, []string{ {{- range $i, $p := .Params }}{{ if $i }}, {{ end }}"{{ $p.DBName }}"{{ end -}} }{{ range .Params }}, {{ .Name }}{{ end }})
+}
+{{- end }}
+{{- end }}
 
 // {{ .Model.Name }}Query provides type-safe query building for {{ .Model.Name }}
 //
@@ -351,8 +627,12 @@ func (r *{{ .Model.Name }}Repository) Authorize(fn func(ctx context.Context, que
 //   - LeftJoin(table, condition) - Left join
 //   - RightJoin(table, condition) - Right join
 //   - FullJoin(table, condition) - Full outer join
+{{- range .Model.Relationships }}
+//   - Join{{ .Name }}(), LeftJoin{{ .Name }}() - Join the {{ .Name }} relationship
+{{- end }}
 //   - Include(relationships...) - Load relationships
 //   - IncludeWhere(relationship, conditions...) - Load relationships with conditions
+//   - IncludeStrategy(strategy) - Choose how included relationships are loaded
 //   - WithTx(tx) - Execute within transaction
 //
 // Execution Methods:
@@ -360,7 +640,9 @@ func (r *{{ .Model.Name }}Repository) Authorize(fn func(ctx context.Context, que
 //   - First() - Execute query and return first record
 //   - Count() - Execute count query
 //   - Exists() - Check if any records exist
+{{- if not .Model.ReadOnly }}
 //   - Delete() - Execute DELETE query
+{{- end }}
 //   - ExecuteRaw(query, args...) - Execute raw SQL
 //
 // Example usage:
@@ -426,6 +708,37 @@ func (q *{{ .Model.Name }}Query) Where(condition storm.Condition) *{{ .Model.Nam
 	return q
 }
 
+// Filter applies a FilterSpec, such as one read from request parameters with
+// storm.ParseFilterQuery. When fields are given only those columns may be filtered on.
+func (q *{{ .Model.Name }}Query) Filter(spec storm.FilterSpec, fields ...string) *{{ .Model.Name }}Query {
+	q.Query = q.Query.Filter(spec, fields...)
+	return q
+}
+
+// Sort orders results by fields, such as those read from a request with
+// storm.ParseSort. When allowed is given only those columns may be sorted on.
+func (q *{{ .Model.Name }}Query) Sort(fields []storm.SortField, allowed ...string) *{{ .Model.Name }}Query {
+	q.Query = q.Query.Sort(fields, allowed...)
+	return q
+}
+
+{{- range .Model.Keysets }}
+
+// After{{ .Name }} continues past the row with the given {{ range $i, $p := .Params }}{{ if $i }}, {{ end }}{{ $p.DBName }}{{ end }},
+// ordering by those columns ascending
+func (q *{{ $.Model.Name }}Query) After{{ .Name }}({{ range $i, $p := .Params }}{{ if $i }}, {{ end }}{{ $p.Name }} {{ $p.Type }}{{ end }}) *{{ $.Model.Name }}Query {
+	q.Query = q.Query.After([]string{ {{- range $i, $p := .Params }}{{ if $i }}, {{ end }}"{{ $p.DBName }}"{{ end -}} }{{ range .Params }}, {{ .Name }}{{ end }})
+	return q
+}
+
+// Before{{ .Name }} continues before the row with the given {{ range $i, $p := .Params }}{{ if $i }}, {{ end }}{{ $p.DBName }}{{ end }},
+// ordering by those columns descending
+func (q *{{ $.Model.Name }}Query) Before{{ .Name }}({{ range $i, $p := .Params }}{{ if $i }}, {{ end }}{{ $p.Name }} {{ $p.Type }}{{ end }}) *{{ $.Model.Name }}Query {
+	q.Query = q.Query.Before([]string{ {{- range $i, $p := .Params }}{{ if $i }}, {{ end }}"{{ $p.DBName }}"{{ end -}} }{{ range .Params }}, {{ .Name }}{{ end }})
+	return q
+}
+{{- end }}
+
 // OrderBy specifies the order of results using column names or expressions.
 // Use DESC suffix for descending order, ASC (or no suffix) for ascending.
 //
@@ -447,6 +760,28 @@ func (q *{{ .Model.Name }}Query) OrderBy(expressions ...string) *{{ .Model.Name
 	return q
 }
 
+// Scoped applies scopes registered on the repository by name
+func (q *{{ .Model.Name }}Query) Scoped(names ...string) *{{ .Model.Name }}Query {
+	q.Query = q.Query.Scoped(names...)
+	return q
+}
+
+// Unscoped disables the repository's default scopes for this query
+func (q *{{ .Model.Name }}Query) Unscoped() *{{ .Model.Name }}Query {
+	q.Query = q.Query.Unscoped()
+	return q
+}
+{{- range .Model.Scopes }}
+{{- if not .IsDefault }}
+
+// {{ pascal .Name }} applies the {{ .Name }} scope ({{ .Condition }})
+func (q *{{ $.Model.Name }}Query) {{ pascal .Name }}() *{{ $.Model.Name }}Query {
+	q.Query = q.Query.Scoped({{ printf "%q" .Name }})
+	return q
+}
+{{- end }}
+{{- end }}
+
 // Limit restricts the number of results returned.
 // Useful for pagination and preventing large result sets.
 //
@@ -546,6 +881,8 @@ func (q *{{ .Model.Name }}Query) Exists() (bool, error) {
 	return q.Query.Exists()
 }
 
+{{- if not .Model.ReadOnly }}
+
 // Delete removes all {{ .Model.Name }} records matching the query conditions.
 // Returns the number of records deleted.
 // WARNING: This is a bulk operation that cannot be undone.
@@ -564,6 +901,52 @@ func (q *{{ .Model.Name }}Query) Exists() (bool, error) {
 func (q *{{ .Model.Name }}Query) Delete() (int64, error) {
 	return q.Query.Delete()
 }
+{{- end }}
+{{- $isTree := false }}
+{{- range .Model.Relationships }}
+{{- if eq .Relationship.Target $.Model.Name }}
+{{- if eq .Relationship.Type "belongs_to" }}
+{{- $isTree = true }}
+
+// Without{{ .Name }} restricts the query to {{ lower $.Model.Name }}s that have no {{ .Name }} (the roots of the tree)
+func (q *{{ $.Model.Name }}Query) Without{{ .Name }}() *{{ $.Model.Name }}Query {
+	q.Query = q.Query.Where(storm.Column[interface{}]{Name: "{{ .Relationship.ForeignKey }}", Table: "{{ $.Model.TableName }}"}.IsNull())
+	return q
+}
+{{- else if or (eq .Relationship.Type "has_many") (eq .Relationship.Type "has_one") }}
+{{- $isTree = true }}
+
+// {{ .Name }}Of restricts the query to the direct {{ .Name }} of the {{ lower $.Model.Name }} with the given key
+func (q *{{ $.Model.Name }}Query) {{ .Name }}Of(id {{ $.TreeKey }}) *{{ $.Model.Name }}Query {
+	q.Query = q.Query.Where(storm.Column[interface{}]{Name: "{{ .Relationship.ForeignKey }}", Table: "{{ $.Model.TableName }}"}.Eq(id))
+	return q
+}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $isTree }}
+
+// Descendants returns every {{ .Model.Name }} below id in the tree using a recursive query
+func (q *{{ .Model.Name }}Query) Descendants(id {{ .TreeKey }}) ([]{{ .Model.Name }}, error) {
+	return q.Query.Descendants(id)
+}
+
+// Ancestors returns every {{ .Model.Name }} above id in the tree, from its parent up to the root
+func (q *{{ .Model.Name }}Query) Ancestors(id {{ .TreeKey }}) ([]{{ .Model.Name }}, error) {
+	return q.Query.Ancestors(id)
+}
+{{- end }}
+
+{{- if .Model.Relationships }}
+
+// IncludeStrategy sets how included relationships are loaded. storm.JoinJSON
+// loads them, nested includes such as "Posts.Comments" too, in the same query
+// as the {{ lower .Model.Name }}s.
+func (q *{{ .Model.Name }}Query) IncludeStrategy(strategy storm.IncludeStrategy) *{{ .Model.Name }}Query {
+	q.Query = q.Query.IncludeStrategy(strategy)
+	return q
+}
+{{- end }}
 
 {{range .Model.Relationships}}
 // Include{{ .Name }} includes the {{ .Name }} relationship in the query
@@ -586,6 +969,27 @@ func (q *{{ $.Model.Name }}Query) Include{{ .Name }}() *{{ $.Model.Name }}Query
 	q.Query = q.Query.Include("{{ .Name }}")
 	return q
 }
+
+// Join{{ .Name }} inner joins {{ .Name }} so conditions can filter on its columns,
+// keeping only {{ lower $.Model.Name }}s that have {{ if or (eq .Relationship.Type "belongs_to") (eq .Relationship.Type "has_one") }}one{{ else }}at least one{{ end }}
+func (q *{{ $.Model.Name }}Query) Join{{ .Name }}() *{{ $.Model.Name }}Query {
+	q.Query = q.Query.JoinRelationship("{{ .Name }}", storm.InnerJoin)
+	return q
+}
+
+// LeftJoin{{ .Name }} left joins {{ .Name }}, keeping {{ lower $.Model.Name }}s without {{ if or (eq .Relationship.Type "belongs_to") (eq .Relationship.Type "has_one") }}one{{ else }}any{{ end }}
+func (q *{{ $.Model.Name }}Query) LeftJoin{{ .Name }}() *{{ $.Model.Name }}Query {
+	q.Query = q.Query.JoinRelationship("{{ .Name }}", storm.LeftJoin)
+	return q
+}
+{{- if .CountField }}
+
+// Include{{ .Name }}Count loads the number of {{ .Name }} into {{ .CountField }} without loading the records
+func (q *{{ $.Model.Name }}Query) Include{{ .Name }}Count() *{{ $.Model.Name }}Query {
+	q.Query = q.Query.IncludeCount("{{ .Name }}")
+	return q
+}
+{{- end }}
 {{end}}
 
 `
@@ -603,7 +1007,6 @@ const relationshipsTemplate = `//go:build !exclude_generated
 // Any changes made to this file will be lost when regenerating.
 //
 // Source package: {{ .Package }}
-// Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -626,7 +1029,6 @@ const stormTemplate = `//go:build !exclude_generated
 //
 // Source package: {{ .Package }}
 // Models found: {{ len .Models }}
-// Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -639,6 +1041,8 @@ package {{ .Package }}
 import (
 	"context"
 	"fmt"
+	"sync"
+
 	storm "github.com/eleven-am/storm/pkg/storm-orm"
 	"github.com/jmoiron/sqlx"
 )
@@ -664,6 +1068,7 @@ import (
 //   - BulkUpdate(ctx, records, opts) - Update multiple records with bulk operation
 //   - Upsert(ctx, record, opts) - Insert or update single record on conflict
 //   - UpsertMany(ctx, records, opts) - Insert or update multiple records on conflict
+//   - CreateManyPartial, UpsertManyPartial - Write the rows that succeed and report the rest
 //
 // Transaction support:
 //   err := storm.WithTransaction(ctx, func(txStorm *Storm) error {
@@ -686,8 +1091,16 @@ type Storm struct {
 }
 
 func NewStorm(db *sqlx.DB, logger ...storm.QueryLogger) *Storm {
-	baseStorm := storm.NewStorm(db, logger...)
+	return newStorm(storm.NewStorm(db, logger...))
+}
+
+// NewStormWithOptions returns a Storm configured by options, e.g.
+// storm.WithDialect(storm.DialectCockroachDB)
+func NewStormWithOptions(db *sqlx.DB, options ...storm.Option) *Storm {
+	return newStorm(storm.NewStormWithOptions(db, options...))
+}
 
+func newStorm(baseStorm *storm.Storm) *Storm {
 	storm := &Storm{
 		Storm: baseStorm,
 	}
@@ -696,6 +1109,17 @@ func NewStorm(db *sqlx.DB, logger ...storm.QueryLogger) *Storm {
 
 	return storm
 }
+{{- if .Database }}
+
+// DatabaseName is the storm.yaml database these models belong to
+const DatabaseName = {{ quote .Database }}
+
+// New{{ pascal .Database }}Storm returns the Storm for the {{ .Database }} database; db must be
+// connected to it
+func New{{ pascal .Database }}Storm(db *sqlx.DB, logger ...storm.QueryLogger) *Storm {
+	return NewStorm(db, logger...)
+}
+{{- end }}
 
 func (s *Storm) WithTransaction(ctx context.Context, fn func(*Storm) error) error {
 	return s.Storm.WithTransaction(ctx, func(baseStorm *storm.Storm) error {
@@ -717,17 +1141,879 @@ func (s *Storm) WithTransactionOptions(ctx context.Context, opts *storm.Transact
 	})
 }
 
+// DryRun runs fn with a Storm whose writes are recorded instead of run, and
+// returns the statements they would have run
+func (s *Storm) DryRun(ctx context.Context, fn func(*Storm) error) ([]storm.DryRunStatement, error) {
+	return s.Storm.DryRun(ctx, func(baseStorm *storm.Storm) error {
+		dryStorm := &Storm{
+			Storm: baseStorm,
+		}
+		dryStorm.initializeRepositories()
+		return fn(dryStorm)
+	})
+}
+
+// PreloadMetadata validates the metadata of every model and computes the
+// lookups repositories derive from it. Call it once at startup, before serving
+// requests, to fail fast on broken metadata.
+func PreloadMetadata() error {
+	return storm.PreloadMetadata(
+		{{- range $modelName, $model := .Models }}
+		{{ $model.Name }}Metadata,
+		{{- end }}
+	)
+}
+
+// SensitiveColumns returns the table.column names of every column whose
+// values query logs should redact, for storm.RedactionRules
+func SensitiveColumns() []string {
+	return storm.SensitiveColumns(
+		{{- range $modelName, $model := .Models }}
+		{{ $model.Name }}Metadata,
+		{{- end }}
+	)
+}
+
 func (s *Storm) initializeRepositories() {
-	executor := s.GetExecutor()
-	
 	{{range $modelName, $model := .Models}}
-	if baseRepo, err := storm.NewRepositoryWithExecutor[{{ $model.Name }}](executor, {{ $model.Name }}Metadata); err == nil {
-		s.{{ plural $model.Name }} = &{{ $model.Name }}Repository{
-			Repository: baseRepo,
-		}
+	if repo, err := new{{ $model.Name }}RepositoryForStorm(s.Storm); err == nil {
+		s.{{ plural $model.Name }} = repo
 	} else {
 		panic(fmt.Errorf("failed to initialize {{ $model.Name }} repository: %w", err))
 	}
 	{{end}}
 }
-`
+
+// Repositories returns a container for the repositories of this Storm, sharing
+// its connection or transaction and its options
+func (s *Storm) Repositories() *Repositories {
+	return &Repositories{executor: s.GetExecutor(), storm: s.Storm}
+}
+
+// Repositories gives access to every typed repository through one value that
+// can be passed to constructors instead of wiring each repository by hand.
+// Repositories are created on first use and share a single executor.
+//
+// With dependency injection frameworks, register the constructor as a provider:
+//   fx.Provide(NewRepositories)
+//   wire.Build(NewRepositories, ...)
+type Repositories struct {
+	executor storm.DBExecutor
+	storm    *storm.Storm // Set when created by Storm.Repositories
+	{{range $modelName, $model := .Models}}
+	{{ lower (plural $model.Name) }}Once sync.Once
+	{{ lower (plural $model.Name) }}     *{{ $model.Name }}Repository
+	{{end}}
+}
+
+// NewRepositories returns a Repositories container using db
+func NewRepositories(db *sqlx.DB) (*Repositories, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	return &Repositories{executor: db}, nil
+}
+
+// NewRepositoriesWithExecutor returns a Repositories container using executor,
+// which may be a database or a transaction
+func NewRepositoriesWithExecutor(executor storm.DBExecutor) (*Repositories, error) {
+	if executor == nil {
+		return nil, fmt.Errorf("executor cannot be nil")
+	}
+	return &Repositories{executor: executor}, nil
+}
+{{range $modelName, $model := .Models}}
+// {{ plural $model.Name }} returns the {{ $model.Name }} repository, creating it on first use
+func (r *Repositories) {{ plural $model.Name }}() *{{ $model.Name }}Repository {
+	r.{{ lower (plural $model.Name) }}Once.Do(func() {
+		var repo *{{ $model.Name }}Repository
+		var err error
+		if r.storm != nil {
+			repo, err = new{{ $model.Name }}RepositoryForStorm(r.storm)
+		} else {
+			repo, err = new{{ $model.Name }}RepositoryWithExecutor(r.executor)
+		}
+		if err != nil {
+			panic(fmt.Errorf("failed to initialize {{ $model.Name }} repository: %w", err))
+		}
+		r.{{ lower (plural $model.Name) }} = repo
+	})
+	return r.{{ lower (plural $model.Name) }}
+}
+{{end}}
+{{- range $modelName, $model := .Models}}
+func new{{ $model.Name }}RepositoryWithExecutor(executor storm.DBExecutor) (*{{ $model.Name }}Repository, error) {
+	return wrap{{ $model.Name }}Repository(storm.NewRepositoryWithExecutor[{{ $model.Name }}](executor, {{ $model.Name }}Metadata))
+}
+
+func new{{ $model.Name }}RepositoryForStorm(base *storm.Storm) (*{{ $model.Name }}Repository, error) {
+	return wrap{{ $model.Name }}Repository(storm.NewRepositoryForStorm[{{ $model.Name }}](base, {{ $model.Name }}Metadata))
+}
+
+func wrap{{ $model.Name }}Repository(baseRepo *storm.Repository[{{ $model.Name }}], err error) (*{{ $model.Name }}Repository, error) {
+	if err != nil {
+		return nil, err
+	}
+	{{- if $model.Scopes }}
+	baseRepo = with{{ $model.Name }}Scopes(baseRepo)
+	{{- end }}
+	return &{{ $model.Name }}Repository{
+	{{- if $model.ReadOnly }}
+		ReadOnlyRepository: storm.ReadOnly(baseRepo),
+	{{- else }}
+		Repository: baseRepo,
+	{{- end }}
+	}, nil
+}
+{{end}}`
+
+// mockTemplate generates a testify mock implementing the repository's Store interface.
+// It is written to a _test.go file so the models package never imports testify.
+const mockTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from Go struct definitions.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Model: {{ .Model.Name }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+//
+// For more information, see:
+//   https://github.com/eleven-am/storm
+
+package {{ .Package }}
+
+import (
+	"context"
+{{- range .Imports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+{{ if not .Model.ReadOnly }}
+	storm "github.com/eleven-am/storm/pkg/storm-orm"
+{{- end }}
+	"github.com/stretchr/testify/mock"
+)
+
+var _ {{ .Model.Name }}Store = (*Mock{{ .Model.Name }}Repository)(nil)
+
+// Mock{{ .Model.Name }}Repository is a testify mock implementing {{ .Model.Name }}Store
+//
+// Example:
+//   repo := NewMock{{ .Model.Name }}Repository(t)
+//   repo.On("FindByID", mock.Anything, 1).Return(&{{ .Model.Name }}{}, nil)
+type Mock{{ .Model.Name }}Repository struct {
+	mock.Mock
+}
+
+// NewMock{{ .Model.Name }}Repository creates a mock whose expectations are asserted when t finishes
+func NewMock{{ .Model.Name }}Repository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Mock{{ .Model.Name }}Repository {
+	m := &Mock{{ .Model.Name }}Repository{}
+	m.Mock.Test(t)
+	t.Cleanup(func() { m.AssertExpectations(t) })
+	return m
+}
+{{- if not .Model.ReadOnly }}
+
+func (m *Mock{{ .Model.Name }}Repository) Create(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, record)
+	return m.record(args), args.Error(1)
+}
+{{- end }}
+
+func (m *Mock{{ .Model.Name }}Repository) FindByID(ctx context.Context, id interface{}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, id)
+	return m.record(args), args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) FindOneBy(ctx context.Context, columns []string, values ...interface{}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, columns, values)
+	return m.record(args), args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) FindAllBy(ctx context.Context, columns []string, values ...interface{}) ([]{{ .Model.Name }}, error) {
+	args := m.Called(ctx, columns, values)
+	return m.records(args), args.Error(1)
+}
+{{- if not .Model.ReadOnly }}
+
+func (m *Mock{{ .Model.Name }}Repository) Update(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, record)
+	return m.record(args), args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) UpdateFields(ctx context.Context, id interface{}, updates map[string]interface{}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, id, updates)
+	return m.record(args), args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) UpdateMany(ctx context.Context, records []{{ .Model.Name }}) (int64, error) {
+	args := m.Called(ctx, records)
+	affected, _ := args.Get(0).(int64)
+	return affected, args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) Delete(ctx context.Context, id interface{}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, id)
+	return m.record(args), args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) DeleteRecord(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error) {
+	args := m.Called(ctx, record)
+	return m.record(args), args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) CreateMany(ctx context.Context, records []{{ .Model.Name }}) error {
+	return m.Called(ctx, records).Error(0)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) Upsert(ctx context.Context, record *{{ .Model.Name }}, opts storm.UpsertOptions) error {
+	return m.Called(ctx, record, opts).Error(0)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) UpsertMany(ctx context.Context, records []{{ .Model.Name }}, opts storm.UpsertOptions) error {
+	return m.Called(ctx, records, opts).Error(0)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) CreateManyPartial(ctx context.Context, records []{{ .Model.Name }}, opts storm.BatchOptions) (*storm.BatchResult, error) {
+	args := m.Called(ctx, records, opts)
+	result, _ := args.Get(0).(*storm.BatchResult)
+	return result, args.Error(1)
+}
+
+func (m *Mock{{ .Model.Name }}Repository) UpsertManyPartial(ctx context.Context, records []{{ .Model.Name }}, upsert storm.UpsertOptions, opts storm.BatchOptions) (*storm.BatchResult, error) {
+	args := m.Called(ctx, records, upsert, opts)
+	result, _ := args.Get(0).(*storm.BatchResult)
+	return result, args.Error(1)
+}
+{{- end }}
+
+// Query returns the *{{ .Model.Name }}Query configured with Return, or nil
+func (m *Mock{{ .Model.Name }}Repository) Query(ctx context.Context) *{{ .Model.Name }}Query {
+	query, _ := m.Called(ctx).Get(0).(*{{ .Model.Name }}Query)
+	return query
+}
+{{- range .Model.Finders }}
+{{- if .Unique }}
+
+func (m *Mock{{ $.Model.Name }}Repository) FindBy{{ .Name }}(ctx context.Context{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}) (*{{ $.Model.Name }}, error) {
+	args := m.Called(ctx{{ range .Params }}, {{ .Name }}{{ end }})
+	return m.record(args), args.Error(1)
+}
+{{- else }}
+
+func (m *Mock{{ $.Model.Name }}Repository) FindAllBy{{ .Name }}(ctx context.Context{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}) ([]{{ $.Model.Name }}, error) {
+	args := m.Called(ctx{{ range .Params }}, {{ .Name }}{{ end }})
+	return m.records(args), args.Error(1)
+}
+{{- end }}
+{{- end }}
+
+// record returns the first return value as a *{{ .Model.Name }}, allowing nil
+func (m *Mock{{ .Model.Name }}Repository) record(args mock.Arguments) *{{ .Model.Name }} {
+	record, _ := args.Get(0).(*{{ .Model.Name }})
+	return record
+}
+
+// records returns the first return value as a []{{ .Model.Name }}, allowing nil
+func (m *Mock{{ .Model.Name }}Repository) records(args mock.Arguments) []{{ .Model.Name }} {
+	records, _ := args.Get(0).([]{{ .Model.Name }})
+	return records
+}
+`
+
+// factoryTemplate generates test data factories with fake defaults
+const factoryTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from Go struct definitions.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Model: {{ .Model.Name }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+//
+// For more information, see:
+//   https://github.com/eleven-am/storm
+
+package {{ .Package }}
+
+import (
+	"context"
+{{- if .NeedsFmt }}
+	"fmt"
+{{- end }}
+{{- if .UsesSeq }}
+	"sync/atomic"
+{{- end }}
+{{- if .NeedsTime }}
+	"time"
+{{- end }}
+)
+{{- if .UsesSeq }}
+
+// {{ lower .Model.Name }}FactorySequence numbers factory records so unique columns stay distinct
+var {{ lower .Model.Name }}FactorySequence atomic.Int64
+{{- end }}
+
+// {{ .Model.Name }}Factory builds {{ .Model.Name }} records with fake defaults for tests
+//
+// Example:
+//   {{ lower .Model.Name }}, err := New{{ .Model.Name }}Factory().
+{{- range .Fields }}{{ if and .Type .Default }}
+//       With{{ .Name }}(...).
+{{- break }}{{ end }}{{ end }}
+//       Create(ctx, repo)
+type {{ .Model.Name }}Factory struct {
+	record {{ .Model.Name }}
+}
+
+// New{{ .Model.Name }}Factory returns a factory pre-filled with defaults derived from the column types
+func New{{ .Model.Name }}Factory() *{{ .Model.Name }}Factory {
+{{- if .UsesSeq }}
+	seq := {{ lower .Model.Name }}FactorySequence.Add(1)
+{{- end }}
+	f := &{{ .Model.Name }}Factory{}
+{{- range .Fields }}
+{{- if .Default }}
+	f.record.{{ .Name }} = {{ .Default }}
+{{- end }}
+{{- end }}
+	return f
+}
+{{- range .Fields }}
+{{- if .Type }}
+
+// With{{ .Name }} sets {{ .Name }}
+func (f *{{ $.Model.Name }}Factory) With{{ .Name }}(value {{ .Type }}) *{{ $.Model.Name }}Factory {
+	f.record.{{ .Name }} = value
+	return f
+}
+{{- end }}
+{{- end }}
+
+// With applies fn to the record being built, for fields without a typed setter
+func (f *{{ .Model.Name }}Factory) With(fn func(record *{{ .Model.Name }})) *{{ .Model.Name }}Factory {
+	fn(&f.record)
+	return f
+}
+
+// Build returns a copy of the record without saving it
+func (f *{{ .Model.Name }}Factory) Build() *{{ .Model.Name }} {
+	record := f.record
+	return &record
+}
+
+// Create saves the built record through repo, which may be a {{ .Model.Name }}Repository or a mock
+func (f *{{ .Model.Name }}Factory) Create(ctx context.Context, repo interface {
+	Create(ctx context.Context, record *{{ .Model.Name }}) (*{{ .Model.Name }}, error)
+}) (*{{ .Model.Name }}, error) {
+	return repo.Create(ctx, f.Build())
+}
+`
+
+// handlerTemplate generates net/http CRUD handlers backed by a repository
+const handlerTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from Go struct definitions.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Model: {{ .Model.Name }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+//
+// For more information, see:
+//   https://github.com/eleven-am/storm
+
+package {{ .Package }}
+
+import (
+	"net/http"
+{{- range .StdImports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+
+	storm "github.com/eleven-am/storm/pkg/storm-orm"
+{{- range .Imports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+)
+
+// {{ .Model.Name }}Handler serves JSON {{ if .Model.ReadOnly }}read-only{{ else }}CRUD{{ end }} endpoints for {{ .Model.Name }}
+//
+// Routes (relative to the prefix passed to Register):
+//   GET    /      - List records, paginated with ?limit=&offset= or ?page[size]=&page[number]=
+{{- range .Filters }}
+//                   filter: ?{{ .DBName }}= or ?filter[{{ .DBName }}][op]=
+{{- end }}
+{{- if .Filters }}
+//                   sort:   ?sort=-{{ (index .Filters 0).DBName }} on the filter columns
+{{- end }}
+{{- if .Model.ReadOnly }}
+//   GET    /{id}  - Fetch a record
+{{- else }}
+//   POST   /      - Create a record
+//   GET    /{id}  - Fetch a record
+//   PUT    /{id}  - Replace a record
+//   DELETE /{id}  - Delete a record
+{{- end }}
+//
+// Request bodies are read through {{ .Request }} and records written as {{ .Response }}.
+//
+// Example:
+//   mux := http.NewServeMux()
+//   New{{ .Model.Name }}Handler(repo).Register(mux, "/{{ snake (plural .Model.Name) }}")
+type {{ .Model.Name }}Handler struct {
+	repo *{{ .Model.Name }}Repository
+
+	// DefaultLimit is the page size used when ?limit= is absent
+	DefaultLimit uint64
+	// MaxLimit caps the page size a client can request
+	MaxLimit uint64
+}
+
+// New{{ .Model.Name }}Handler creates a handler serving records from repo
+func New{{ .Model.Name }}Handler(repo *{{ .Model.Name }}Repository) *{{ .Model.Name }}Handler {
+	return &{{ .Model.Name }}Handler{
+		repo:         repo,
+		DefaultLimit: 50,
+		MaxLimit:     500,
+	}
+}
+
+{{- if not .DTOs }}
+{{- if not .Model.ReadOnly }}
+// {{ .Request }} is the body Create and Update accept. Primary keys, immutable,
+// computed and database-maintained columns are left out so clients cannot set them.
+type {{ .Request }} struct {
+{{- range .RequestFields }}
+	{{ .Name }} {{ .Type }} ` + "`json:\"{{ .JSONName }}\"`" + `
+{{- end }}
+}
+
+// ApplyTo copies the request fields onto m
+func (r {{ .Request }}) ApplyTo(m *{{ .Model.Name }}) {
+{{- range .RequestFields }}
+	m.{{ .Name }} = r.{{ .Name }}
+{{- end }}
+}
+
+{{ end -}}
+// {{ .Response }} is a {{ .Model.Name }} as the handler writes it, without its
+// json_ignore and masked columns
+type {{ .Response }} struct {
+{{- range .ResponseFields }}
+	{{ .Name }} {{ .Type }} ` + "`json:\"{{ .JSONName }}\"`" + `
+{{- end }}
+}
+
+// {{ .Response }}FromModel maps m to the handler's response
+func {{ .Response }}FromModel(m *{{ .Model.Name }}) {{ .Response }} {
+	return {{ .Response }}{
+{{- range .ResponseFields }}
+		{{ .Name }}: m.{{ .Name }},
+{{- end }}
+	}
+}
+
+{{ end -}}
+// Register mounts the handler's routes on mux under prefix, e.g. "/{{ snake (plural .Model.Name) }}"
+func (h *{{ .Model.Name }}Handler) Register(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc("GET "+prefix, h.List)
+{{- if .Model.ReadOnly }}
+	mux.HandleFunc("GET "+prefix+"/{id}", h.Get)
+{{- else }}
+	mux.HandleFunc("POST "+prefix, h.Create)
+	mux.HandleFunc("GET "+prefix+"/{id}", h.Get)
+	mux.HandleFunc("PUT "+prefix+"/{id}", h.Update)
+	mux.HandleFunc("DELETE "+prefix+"/{id}", h.Delete)
+{{- end }}
+}
+
+// List returns a page of records matching the filter query parameters
+func (h *{{ .Model.Name }}Handler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := storm.ParsePagination(r, h.DefaultLimit, h.MaxLimit)
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	var conditions []storm.Condition
+{{- if .Filters }}
+	params := r.URL.Query()
+{{- end }}
+{{- range .Filters }}
+	if value := params.Get("{{ .DBName }}"); value != "" {
+		parsed, err := storm.ParseParam[{{ .Type }}](value)
+		if err != nil {
+			storm.WriteError(w, err)
+			return
+		}
+		conditions = append(conditions, storm.Column[{{ .Type }}]{Name: "{{ .DBName }}", Table: "{{ $.Model.TableName }}"}.Eq(parsed))
+	}
+{{- end }}
+{{- if .Filters }}
+	spec, err := storm.ParseFilterQuery(params)
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	sortFields, err := storm.ParseSort(params.Get("sort"))
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	columns := []string{ {{- range $i, $f := .Filters }}{{ if $i }}, {{ end }}"{{ $f.DBName }}"{{ end -}} }
+{{- end }}
+
+	query := h.repo.Query(r.Context())
+	for _, condition := range conditions {
+		query = query.Where(condition)
+	}
+{{- if .Filters }}
+	query = query.Filter(spec, columns...).Sort(sortFields, columns...)
+{{- end }}
+	page, err := query.
+		OrderBy("{{ .Model.TableName }}.{{ .PrimaryKey.DBName }}").
+		Limit(limit).
+		Offset(offset).
+		FindPage()
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	storm.WriteJSON(w, http.StatusOK, storm.MapPage(page, {{ .Response }}FromModel))
+}
+
+// Get returns the record identified by the {id} path parameter
+func (h *{{ .Model.Name }}Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := storm.ParseParam[{{ .PrimaryKey.Type }}](r.PathValue("id"))
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	record, err := h.repo.FindByID(r.Context(), id)
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	storm.WriteJSON(w, http.StatusOK, {{ .Response }}FromModel(record))
+}
+{{- if not .Model.ReadOnly }}
+
+// Create inserts the record decoded from the request body
+func (h *{{ .Model.Name }}Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var record {{ .Model.Name }}
+	if err := h.decode(r, &record); err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	created, err := h.repo.Create(r.Context(), &record)
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	storm.WriteJSON(w, http.StatusCreated, {{ .Response }}FromModel(created))
+}
+
+// Update replaces the fields the request body carries on the record identified
+// by the {id} path parameter
+func (h *{{ .Model.Name }}Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := storm.ParseParam[{{ .PrimaryKey.Type }}](r.PathValue("id"))
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	record, err := h.repo.FindByID(r.Context(), id)
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	if err := h.decode(r, record); err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	updated, err := h.repo.Update(r.Context(), record)
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	storm.WriteJSON(w, http.StatusOK, {{ .Response }}FromModel(updated))
+}
+
+// Delete removes the record identified by the {id} path parameter
+func (h *{{ .Model.Name }}Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := storm.ParseParam[{{ .PrimaryKey.Type }}](r.PathValue("id"))
+	if err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+
+	if _, err := h.repo.Delete(r.Context(), id); err != nil {
+		storm.WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decode reads the request body onto record through {{ .Request }} and runs
+// the record's Validate method when it has one
+func (h *{{ .Model.Name }}Handler) decode(r *http.Request, record *{{ .Model.Name }}) error {
+	var request {{ .Request }}
+	if err := storm.DecodeJSON(r, &request); err != nil {
+		return err
+	}
+	request.ApplyTo(record)
+	if validator, ok := interface{}(record).(interface{ Validate() error }); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+{{- end }}
+`
+
+const validateTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from Go struct definitions.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Model: {{ .Model.Name }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+//
+// For more information, see:
+//   https://github.com/eleven-am/storm
+
+package {{ .Package }}
+
+import (
+{{- if .NeedsUTF8 }}
+	"unicode/utf8"
+{{ end }}
+	storm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+
+// Validate checks {{ .Model.Name }} against the constraints declared in its
+// storm tags. Repositories call it before writing, so invalid records fail
+// with field-level errors instead of database constraint violations.
+func (m *{{ .Model.Name }}) Validate() error {
+	var errs storm.ValidationErrors
+{{ range .Fields }}
+{{- if .IsPointer }}
+{{- if .Required }}
+	if m.{{ .Name }} == nil {
+		errs = append(errs, storm.ValidationError{Field: "{{ .DBName }}", Message: "is required"})
+	}
+{{- end }}
+{{- if .Rules }}
+	if m.{{ .Name }} != nil {
+{{- $field := . }}
+{{- range .Rules }}
+		if v := *m.{{ $field.Name }}; {{ .Invalid }} {
+			errs = append(errs, storm.ValidationError{Field: "{{ $field.DBName }}", Message: {{ printf "%q" .Message }}})
+		}
+{{- end }}
+	}
+{{- end }}
+{{- else }}
+{{- $field := . }}
+{{- range .Rules }}
+	if v := m.{{ $field.Name }}; {{ .Invalid }} {
+		errs = append(errs, storm.ValidationError{Field: "{{ $field.DBName }}", Message: {{ printf "%q" .Message }}})
+	}
+{{- end }}
+{{- end }}
+{{ end }}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+`
+
+const dtoTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from Go struct definitions.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Model: {{ .Model.Name }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+//
+// For more information, see:
+//   https://github.com/eleven-am/storm
+
+package {{ .Package }}
+{{ if or .StdImports .Imports }}
+import (
+{{- range .StdImports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+{{- if and .StdImports .Imports }}
+{{ end }}
+{{- range .Imports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+)
+{{ end }}
+{{- if not .Model.ReadOnly }}
+// {{ .Model.Name }}Request is the API payload for creating or updating a {{ .Model.Name }}.
+// Primary keys, database-generated, immutable and json_ignore columns are not accepted.
+type {{ .Model.Name }}Request struct {
+{{- range .RequestFields }}
+	{{ .Name }} {{ .Type }} ` + "`json:\"{{ .JSONName }}\"`" + `
+{{- end }}
+}
+
+// ToModel builds a new {{ .Model.Name }} from the request
+func (r {{ .Model.Name }}Request) ToModel() *{{ .Model.Name }} {
+	m := &{{ .Model.Name }}{}
+	r.ApplyTo(m)
+	return m
+}
+
+// ApplyTo copies the request fields onto an existing {{ .Model.Name }}, leaving
+// the fields the request does not carry untouched
+func (r {{ .Model.Name }}Request) ApplyTo(m *{{ .Model.Name }}) {
+{{- range .RequestFields }}
+	m.{{ .Name }} = r.{{ .Name }}
+{{- end }}
+}
+{{- end }}
+
+// {{ .Model.Name }}Response is the API representation of a {{ .Model.Name }}.
+// Columns marked json_ignore are never exposed and masked columns show only
+// their last four characters.
+type {{ .Model.Name }}Response struct {
+{{- range .ResponseFields }}
+	{{ .Name }} {{ .Type }} ` + "`json:\"{{ .JSONName }}\"`" + `
+{{- end }}
+}
+
+// {{ .Model.Name }}ResponseFromModel maps a {{ .Model.Name }} to its API representation
+func {{ .Model.Name }}ResponseFromModel(m *{{ .Model.Name }}) {{ .Model.Name }}Response {
+	return {{ .Model.Name }}Response{
+{{- range .ResponseFields }}
+		{{ .Name }}: {{ if .Masked }}storm.Mask(m.{{ .Name }}){{ else }}m.{{ .Name }}{{ end }},
+{{- end }}
+	}
+}
+
+// {{ .Model.Name }}ResponsesFromModels maps a slice of {{ .Model.Name }} to API representations
+func {{ .Model.Name }}ResponsesFromModels(models []{{ .Model.Name }}) []{{ .Model.Name }}Response {
+	responses := make([]{{ .Model.Name }}Response, len(models))
+	for i := range models {
+		responses[i] = {{ .Model.Name }}ResponseFromModel(&models[i])
+	}
+	return responses
+}
+`
+
+// queriesTemplate generates a Storm method with typed parameters and rows for
+// every named query in the queries directory
+const queriesTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from the SQL files in queries/.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Queries found: {{ len .Queries }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+//
+// For more information, see:
+//   https://github.com/eleven-am/storm
+
+package {{ .Package }}
+
+import (
+{{- range .StdImports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+{{- if .Imports }}
+{{ end }}
+{{- range .Imports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+)
+{{ range .Queries }}
+// {{ .ConstName }} is the {{ .Name }} statement of {{ .File }}
+const {{ .ConstName }} = {{ .Literal }}
+{{ if .Params }}
+// {{ .Name }}Params holds the parameters of {{ .Name }}
+type {{ .Name }}Params struct {
+{{- range .Params }}
+	{{ .Name }} {{ .Type }} ` + "`db:\"{{ .DBName }}\"`" + `
+{{- end }}
+}
+{{ end }}
+{{- if .Columns }}
+// {{ .Name }}Row is a row returned by {{ .Name }}
+type {{ .Name }}Row struct {
+{{- range .Columns }}
+	{{ .Name }} {{ .Type }} ` + "`db:\"{{ .DBName }}\" json:\"{{ .DBName }}\"`" + `
+{{- end }}
+}
+{{ end }}
+{{- if eq .Kind "many" }}
+// {{ .Name }} runs the {{ .Name }} query of {{ .File }} and returns its rows
+func (s *Storm) {{ .Name }}(ctx context.Context{{ if .Params }}, params {{ .Name }}Params{{ end }}) ([]{{ .RowType }}, error) {
+	rows := []{{ .RowType }}{}
+	if err := s.Storm.QueryNamed(ctx, {{ quote .Name }}, {{ .ConstName }}, {{ if .Params }}params{{ else }}nil{{ end }}, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+{{- else if eq .Kind "one" }}
+// {{ .Name }} runs the {{ .Name }} query of {{ .File }} and returns its first
+// row, or an error wrapping storm.ErrNotFound when there is none
+func (s *Storm) {{ .Name }}(ctx context.Context{{ if .Params }}, params {{ .Name }}Params{{ end }}) (*{{ .RowType }}, error) {
+	var row {{ .RowType }}
+	if err := s.Storm.QueryNamed(ctx, {{ quote .Name }}, {{ .ConstName }}, {{ if .Params }}params{{ else }}nil{{ end }}, &row); err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+{{- else }}
+// {{ .Name }} runs the {{ .Name }} statement of {{ .File }} and returns the
+// number of rows it affected
+func (s *Storm) {{ .Name }}(ctx context.Context{{ if .Params }}, params {{ .Name }}Params{{ end }}) (int64, error) {
+	return s.Storm.ExecNamed(ctx, {{ quote .Name }}, {{ .ConstName }}, {{ if .Params }}params{{ else }}nil{{ end }})
+}
+{{- end }}
+{{ end }}`
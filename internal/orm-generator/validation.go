@@ -0,0 +1,331 @@
+package orm_generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationField groups the generated checks for one column
+type ValidationField struct {
+	Name      string           // Go field name
+	DBName    string           // Column name reported in validation errors
+	IsPointer bool             // Whether the field is a pointer, checked only when set
+	Required  bool             // Whether a nil pointer is rejected
+	Rules     []ValidationRule // Value checks
+}
+
+// ValidationRule is a single generated value check
+type ValidationRule struct {
+	Invalid string // Go expression over v that is true when the value is invalid
+	Message string // Validation error message
+}
+
+var (
+	checkComparison = regexp.MustCompile(`^"?(\w+)"?\s*(>=|<=|<>|!=|=|>|<)\s*(-?\d+(?:\.\d+)?)$`)
+	checkLength     = regexp.MustCompile(`(?i)^(?:length|char_length|character_length)\(\s*"?(\w+)"?\s*\)\s*(>=|<=|<>|!=|=|>|<)\s*(\d+)$`)
+	checkBetween    = regexp.MustCompile(`(?i)^"?(\w+)"?\s+BETWEEN\s+(-?\d+(?:\.\d+)?)\s+AND\s+(-?\d+(?:\.\d+)?)$`)
+	checkIn         = regexp.MustCompile(`(?i)^"?(\w+)"?\s+IN\s*\((.+)\)$`)
+	checkString     = regexp.MustCompile(`^"?(\w+)"?\s*(<>|!=|=)\s*'([^']*)'$`)
+	checkNotNull    = regexp.MustCompile(`(?i)^"?(\w+)"?\s+IS\s+NOT\s+NULL$`)
+	checkAnd        = regexp.MustCompile(`(?i)\s+AND\s+`)
+	checkOr         = regexp.MustCompile(`(?i)\sOR\s`)
+)
+
+// invalidOperators maps a SQL comparison to the Go operator that detects a
+// violation, and the message describing the constraint
+var invalidOperators = map[string]struct{ op, message string }{
+	">":  {"<=", "must be greater than %s"},
+	">=": {"<", "must be at least %s"},
+	"<":  {">=", "must be less than %s"},
+	"<=": {">", "must be at most %s"},
+	"=":  {"!=", "must equal %s"},
+	"<>": {"==", "must not equal %s"},
+	"!=": {"==", "must not equal %s"},
+}
+
+func (g *CodeGenerator) generateValidators() error {
+	for _, model := range g.sortedModels() {
+		if g.customValidators[model.Name] {
+			continue
+		}
+
+		fields := buildValidationFields(model)
+		if len(fields) == 0 {
+			continue
+		}
+
+		data := struct {
+			Package   string
+			Model     *ModelMetadata
+			Fields    []ValidationField
+			NeedsUTF8 bool
+			Now       time.Time
+		}{
+			Package: g.packageName,
+			Model:   model,
+			Fields:  fields,
+			Now:     time.Now(),
+		}
+		for _, field := range fields {
+			for _, rule := range field.Rules {
+				data.NeedsUTF8 = data.NeedsUTF8 || strings.Contains(rule.Invalid, "utf8.")
+			}
+		}
+
+		filename := fmt.Sprintf("%s_validate.go", toSnakeCase(model.Name))
+		if err := g.executeTemplate("validate", filename, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildValidationFields derives checks from not_null, varchar/char lengths,
+// enum values and the parts of check expressions that compare the column
+// against constants. Anything else is left for the database to enforce.
+func buildValidationFields(model *ModelMetadata) []ValidationField {
+	var fields []ValidationField
+	for _, col := range model.Columns {
+		if col.IsArray || col.Computed != "" || col.IsAutoGenerated {
+			continue
+		}
+
+		field := ValidationField{
+			Name:      col.Name,
+			DBName:    col.DBName,
+			IsPointer: col.IsPointer,
+		}
+
+		if _, notNull := col.DBDef["not_null"]; notNull && col.IsPointer && col.DefaultValue == "" {
+			field.Required = true
+		}
+
+		if col.Type == "string" {
+			if limit := varcharLength(strings.ToLower(col.DBDef["type"])); limit > 0 {
+				field.Rules = append(field.Rules, ValidationRule{
+					Invalid: fmt.Sprintf("utf8.RuneCountInString(v) > %d", limit),
+					Message: fmt.Sprintf("must be at most %d characters", limit),
+				})
+			}
+			if enum := col.DBDef["enum"]; enum != "" {
+				field.Rules = append(field.Rules, enumRule(strings.Split(enum, ",")))
+			}
+		}
+
+		if check := col.DBDef["check"]; check != "" {
+			rules, required := checkRules(col, check)
+			field.Rules = append(field.Rules, rules...)
+			field.Required = field.Required || (required && col.IsPointer)
+		}
+
+		if field.Required || len(field.Rules) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func enumRule(values []string) ValidationRule {
+	quoted := make([]string, 0, len(values))
+	for i, value := range values {
+		values[i] = strings.Trim(strings.TrimSpace(value), "'")
+		quoted = append(quoted, strconv.Quote(values[i]))
+	}
+
+	var conditions []string
+	for _, value := range quoted {
+		conditions = append(conditions, "v != "+value)
+	}
+	return ValidationRule{
+		Invalid: strings.Join(conditions, " && "),
+		Message: "must be one of: " + strings.Join(values, ", "),
+	}
+}
+
+// checkRules converts the statically analyzable conjuncts of a check
+// expression on col into rules. Expressions containing OR are skipped
+// entirely. The second result reports an IS NOT NULL conjunct.
+func checkRules(col FieldMetadata, check string) ([]ValidationRule, bool) {
+	expr := trimParens(strings.TrimSpace(check))
+	if checkOr.MatchString(expr) {
+		return nil, false
+	}
+
+	if m := checkBetween.FindStringSubmatch(expr); m != nil {
+		return betweenRule(col, m[1], m[2], m[3]), false
+	}
+
+	var rules []ValidationRule
+	required := false
+	for _, term := range checkAnd.Split(expr, -1) {
+		term = trimParens(strings.TrimSpace(term))
+
+		switch {
+		case checkNotNull.MatchString(term):
+			m := checkNotNull.FindStringSubmatch(term)
+			required = required || m[1] == col.DBName
+		case checkLength.MatchString(term):
+			m := checkLength.FindStringSubmatch(term)
+			if m[1] == col.DBName && col.Type == "string" {
+				op := invalidOperators[m[2]]
+				rules = append(rules, ValidationRule{
+					Invalid: fmt.Sprintf("utf8.RuneCountInString(v) %s %s", op.op, m[3]),
+					Message: fmt.Sprintf("length "+op.message, m[3]),
+				})
+			}
+		case checkComparison.MatchString(term):
+			m := checkComparison.FindStringSubmatch(term)
+			if m[1] == col.DBName && numericLiteralFits(col.Type, m[3]) {
+				op := invalidOperators[m[2]]
+				rules = append(rules, ValidationRule{
+					Invalid: fmt.Sprintf("v %s %s", op.op, m[3]),
+					Message: fmt.Sprintf(op.message, m[3]),
+				})
+			}
+		case checkString.MatchString(term):
+			m := checkString.FindStringSubmatch(term)
+			if m[1] == col.DBName && col.Type == "string" {
+				op := invalidOperators[m[2]]
+				rules = append(rules, ValidationRule{
+					Invalid: fmt.Sprintf("v %s %s", op.op, strconv.Quote(m[3])),
+					Message: fmt.Sprintf(op.message, strconv.Quote(m[3])),
+				})
+			}
+		case checkIn.MatchString(term):
+			m := checkIn.FindStringSubmatch(term)
+			if rule, ok := inRule(col, m[1], m[2]); ok {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules, required
+}
+
+func betweenRule(col FieldMetadata, column, low, high string) []ValidationRule {
+	if column != col.DBName || !numericLiteralFits(col.Type, low) || !numericLiteralFits(col.Type, high) {
+		return nil
+	}
+	return []ValidationRule{{
+		Invalid: fmt.Sprintf("v < %s || v > %s", low, high),
+		Message: fmt.Sprintf("must be between %s and %s", low, high),
+	}}
+}
+
+func inRule(col FieldMetadata, column, list string) (ValidationRule, bool) {
+	if column != col.DBName {
+		return ValidationRule{}, false
+	}
+
+	var values []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case col.Type == "string" && len(item) >= 2 && strings.HasPrefix(item, "'") && strings.HasSuffix(item, "'"):
+			values = append(values, item[1:len(item)-1])
+		case col.Type != "string" && numericLiteralFits(col.Type, item):
+			values = append(values, item)
+		default:
+			return ValidationRule{}, false
+		}
+	}
+
+	if col.Type == "string" {
+		return enumRule(values), true
+	}
+
+	conditions := make([]string, 0, len(values))
+	for _, value := range values {
+		conditions = append(conditions, "v != "+value)
+	}
+	return ValidationRule{
+		Invalid: strings.Join(conditions, " && "),
+		Message: "must be one of: " + strings.Join(values, ", "),
+	}, true
+}
+
+// numericLiteralFits reports whether literal is a valid Go constant for goType
+func numericLiteralFits(goType, literal string) bool {
+	switch goType {
+	case "int", "int8", "int16", "int32", "int64":
+		_, err := strconv.ParseInt(literal, 10, 64)
+		return err == nil
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		_, err := strconv.ParseUint(literal, 10, 64)
+		return err == nil
+	case "float32", "float64":
+		_, err := strconv.ParseFloat(literal, 64)
+		return err == nil
+	}
+	return false
+}
+
+// trimParens removes parentheses wrapping the whole expression
+func trimParens(expr string) string {
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		depth := 0
+		wrapped := true
+		for i, r := range expr {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(expr)-1 {
+				wrapped = false
+				break
+			}
+		}
+		if !wrapped {
+			return expr
+		}
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	return expr
+}
+
+// findCustomValidators returns the types in packagePath that declare their own
+// Validate method outside generated files
+func findCustomValidators(packagePath string) map[string]bool {
+	validators := make(map[string]bool)
+
+	matches, err := filepath.Glob(filepath.Join(packagePath, "*.go"))
+	if err != nil {
+		return validators
+	}
+
+	fileSet := token.NewFileSet()
+	for _, file := range matches {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		src, err := parser.ParseFile(fileSet, file, nil, parser.ParseComments)
+		if err != nil || ast.IsGenerated(src) {
+			continue
+		}
+
+		for _, decl := range src.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "Validate" || len(fn.Recv.List) == 0 {
+				continue
+			}
+
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				validators[ident.Name] = true
+			}
+		}
+	}
+	return validators
+}
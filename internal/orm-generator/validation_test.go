@@ -0,0 +1,155 @@
+package orm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		col      FieldMetadata
+		check    string
+		expected []ValidationRule
+	}{
+		{
+			name:     "range",
+			col:      FieldMetadata{DBName: "age", Type: "int"},
+			check:    "age >= 0 AND age <= 150",
+			expected: []ValidationRule{{"v < 0", "must be at least 0"}, {"v > 150", "must be at most 150"}},
+		},
+		{
+			name:     "between",
+			col:      FieldMetadata{DBName: "score", Type: "float64"},
+			check:    "(score BETWEEN 0 AND 100)",
+			expected: []ValidationRule{{"v < 0 || v > 100", "must be between 0 and 100"}},
+		},
+		{
+			name:     "length",
+			col:      FieldMetadata{DBName: "nick", Type: "string"},
+			check:    "char_length(nick) > 2",
+			expected: []ValidationRule{{"utf8.RuneCountInString(v) <= 2", "length must be greater than 2"}},
+		},
+		{
+			name:     "string in list",
+			col:      FieldMetadata{DBName: "role", Type: "string"},
+			check:    "role IN ('admin', 'user')",
+			expected: []ValidationRule{{`v != "admin" && v != "user"`, "must be one of: admin, user"}},
+		},
+		{
+			name:     "not empty",
+			col:      FieldMetadata{DBName: "name", Type: "string"},
+			check:    "name <> ''",
+			expected: []ValidationRule{{`v == ""`, `must not equal ""`}},
+		},
+		{
+			name:  "other column",
+			col:   FieldMetadata{DBName: "age", Type: "int"},
+			check: "other > 0",
+		},
+		{
+			name:  "or is skipped",
+			col:   FieldMetadata{DBName: "age", Type: "int"},
+			check: "age > 0 OR age = -1",
+		},
+		{
+			name:  "float literal on integer",
+			col:   FieldMetadata{DBName: "age", Type: "int"},
+			check: "age > 0.5",
+		},
+		{
+			name:  "negative literal on unsigned",
+			col:   FieldMetadata{DBName: "count", Type: "uint32"},
+			check: "count > -1",
+		},
+		{
+			name:  "function call",
+			col:   FieldMetadata{DBName: "email", Type: "string"},
+			check: "email ~* '^.+@.+$'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, _ := checkRules(tt.col, tt.check)
+			assert.Equal(t, tt.expected, rules)
+		})
+	}
+}
+
+func TestValidationGeneration(t *testing.T) {
+	outputDir := t.TempDir()
+
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "testmodels",
+		OutputDir:   outputDir,
+	})
+
+	account := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Account",
+		TableName:  "accounts",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "string", DBDef: map[string]string{"primary_key": "", "type": "uuid", "default": "gen_random_uuid()"}},
+			{Name: "Email", DBName: "email", Type: "string", DBDef: map[string]string{"type": "varchar(255)", "not_null": ""}},
+			{Name: "Status", DBName: "status", Type: "string", DBDef: map[string]string{"type": "text", "enum": "active,inactive"}},
+			{Name: "Bio", DBName: "bio", Type: "string", IsPointer: true, DBDef: map[string]string{"type": "varchar(20)", "not_null": ""}},
+			{Name: "Balance", DBName: "balance", Type: "int64", DBDef: map[string]string{"type": "bigint", "check": "balance >= 0"}},
+		},
+	})
+	generator.models[account.Name] = account
+
+	plain := generator.convertTableDefinitionToModelMetadata(stormParser.TableDefinition{
+		StructName: "Tag",
+		TableName:  "tags",
+		Fields: []stormParser.FieldDefinition{
+			{Name: "ID", DBName: "id", Type: "int64", DBDef: map[string]string{"primary_key": "", "type": "bigserial"}},
+			{Name: "Label", DBName: "label", Type: "string", DBDef: map[string]string{"type": "text"}},
+		},
+	})
+	generator.models[plain.Name] = plain
+
+	assert.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "account_validate.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"unicode/utf8"`)
+	assert.Contains(t, string(content), "func (m *Account) Validate() error {")
+	assert.Contains(t, string(content), "if v := m.Email; utf8.RuneCountInString(v) > 255 {")
+	assert.Contains(t, string(content), `if v := m.Status; v != "active" && v != "inactive" {`)
+	assert.Contains(t, string(content), "if m.Bio == nil {")
+	assert.Contains(t, string(content), "if v := *m.Bio; utf8.RuneCountInString(v) > 20 {")
+	assert.Contains(t, string(content), `storm.ValidationError{Field: "balance", Message: "must be at least 0"}`)
+	assert.NotContains(t, string(content), "m.ID")
+
+	_, err = os.Stat(filepath.Join(outputDir, "tag_validate.go"))
+	assert.True(t, os.IsNotExist(err), "models without constraints get no Validate method")
+
+	t.Run("skips models with a hand-written Validate", func(t *testing.T) {
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "testmodels", OutputDir: outputDir})
+		generator.models[account.Name] = account
+		generator.customValidators[account.Name] = true
+		assert.NoError(t, generator.GenerateAll())
+
+		_, err := os.Stat(filepath.Join(outputDir, "account_validate.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestFindCustomValidators(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	write("models.go", "package models\n\ntype User struct{}\n\nfunc (u *User) Validate() error { return nil }\n")
+	write("order_validate.go", "// Code generated by storm orm generate-orm; DO NOT EDIT.\n\npackage models\n\ntype Order struct{}\n\nfunc (m *Order) Validate() error { return nil }\n")
+
+	validators := findCustomValidators(dir)
+	assert.True(t, validators["User"])
+	assert.False(t, validators["Order"])
+}
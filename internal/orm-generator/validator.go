@@ -3,6 +3,7 @@ package orm_generator
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/eleven-am/storm/internal/parser"
@@ -78,8 +79,8 @@ func (v *ModelValidator) ValidateModel(modelType reflect.Type) ValidationResult
 func (v *ModelValidator) ValidateModels(models map[string]reflect.Type) ValidationResult {
 	result := ValidationResult{Valid: true}
 
-	for _, modelType := range models {
-		modelResult := v.ValidateModel(modelType)
+	for _, name := range sortedModelNames(models) {
+		modelResult := v.ValidateModel(models[name])
 		if !modelResult.Valid {
 			result.Valid = false
 			result.Errors = append(result.Errors, modelResult.Errors...)
@@ -215,7 +216,8 @@ func (v *ModelValidator) validateFieldType(fieldType reflect.Type) error {
 func (v *ModelValidator) validateRelationships(models map[string]reflect.Type) []ModelValidationError {
 	var errors []ModelValidationError
 
-	for modelName, modelType := range models {
+	for _, modelName := range sortedModelNames(models) {
+		modelType := models[modelName]
 		for i := 0; i < modelType.NumField(); i++ {
 			field := modelType.Field(i)
 			ormTag := field.Tag.Get("orm")
@@ -279,6 +281,17 @@ func (v *ModelValidator) validateRelationships(models map[string]reflect.Type) [
 	return errors
 }
 
+// sortedModelNames returns the keys of models in ascending order, so errors are
+// reported in the same order on every run
+func sortedModelNames(models map[string]reflect.Type) []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (v *ModelValidator) hasField(structType reflect.Type, fieldName string) bool {
 	for i := 0; i < structType.NumField(); i++ {
 		field := structType.Field(i)
@@ -318,7 +331,9 @@ func ValidateModelsFromDirectory(packagePath string) ValidationResult {
 
 	var dbModels []parser.TableDefinition
 	for _, table := range tables {
-		if _, hasExplicitTable := table.TableLevel["table"]; hasExplicitTable {
+		_, hasExplicitTable := table.TableLevel["table"]
+		_, readOnly := table.TableLevel["readonly"]
+		if hasExplicitTable || readOnly {
 			dbModels = append(dbModels, table)
 		}
 	}
@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// NamingConvention controls how struct and field names become table and column names
+type NamingConvention string
+
+const (
+	// SnakeCase names tables and columns like user_profiles and created_at
+	SnakeCase NamingConvention = "snake_case"
+	// CamelCase names tables and columns like userProfiles and createdAt
+	CamelCase NamingConvention = "camelCase"
+)
+
+// ParseNamingConvention parses a naming convention, defaulting to snake_case when empty
+func ParseNamingConvention(value string) (NamingConvention, error) {
+	switch NamingConvention(value) {
+	case "", SnakeCase:
+		return SnakeCase, nil
+	case CamelCase:
+		return CamelCase, nil
+	}
+	return "", fmt.Errorf("unknown naming convention %q (expected snake_case or camelCase)", value)
+}
+
+var defaultIrregularPlurals = map[string]string{
+	"analysis":  "analyses",
+	"axis":      "axes",
+	"basis":     "bases",
+	"child":     "children",
+	"criterion": "criteria",
+	"crisis":    "crises",
+	"datum":     "data",
+	"foot":      "feet",
+	"goose":     "geese",
+	"half":      "halves",
+	"hero":      "heroes",
+	"index":     "indexes",
+	"knife":     "knives",
+	"leaf":      "leaves",
+	"life":      "lives",
+	"man":       "men",
+	"matrix":    "matrices",
+	"mouse":     "mice",
+	"ox":        "oxen",
+	"potato":    "potatoes",
+	"quiz":      "quizzes",
+	"shelf":     "shelves",
+	"status":    "statuses",
+	"thesis":    "theses",
+	"tomato":    "tomatoes",
+	"tooth":     "teeth",
+	"vertex":    "vertices",
+	"wife":      "wives",
+	"wolf":      "wolves",
+	"woman":     "women",
+}
+
+var defaultUncountables = []string{
+	"equipment", "feedback", "fish", "information", "metadata",
+	"news", "series", "sheep", "software", "species",
+}
+
+// Inflector pluralizes and singularizes English nouns. Irregular forms and
+// uncountable words take precedence over the suffix rules.
+type Inflector struct {
+	plurals     map[string]string
+	singulars   map[string]string
+	uncountable map[string]bool
+}
+
+// NewInflector returns an inflector with the built-in irregular and uncountable words
+func NewInflector() *Inflector {
+	inflector := &Inflector{
+		plurals:     make(map[string]string),
+		singulars:   make(map[string]string),
+		uncountable: make(map[string]bool),
+	}
+	for singular, plural := range defaultIrregularPlurals {
+		inflector.AddIrregular(singular, plural)
+	}
+	for _, word := range defaultUncountables {
+		inflector.AddUncountable(word)
+	}
+	return inflector
+}
+
+// AddIrregular registers an irregular plural, replacing any existing rule for singular.
+// Registering a word as its own plural makes it uncountable.
+func (i *Inflector) AddIrregular(singular, plural string) {
+	singular, plural = strings.ToLower(singular), strings.ToLower(plural)
+	if singular == plural {
+		i.AddUncountable(singular)
+		return
+	}
+	delete(i.uncountable, singular)
+	i.plurals[singular] = plural
+	i.singulars[plural] = singular
+}
+
+// AddUncountable registers a word whose plural is the same as its singular
+func (i *Inflector) AddUncountable(word string) {
+	i.uncountable[strings.ToLower(word)] = true
+}
+
+// Pluralize returns the plural of word, keeping its capitalization
+func (i *Inflector) Pluralize(word string) string {
+	lower := strings.ToLower(word)
+	if lower == "" || i.uncountable[lower] {
+		return word
+	}
+	if plural, ok := i.plurals[lower]; ok {
+		return matchCase(word, plural)
+	}
+	if _, ok := i.singulars[lower]; ok {
+		return word
+	}
+
+	var plural string
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !isVowel(lower[len(lower)-2]):
+		plural = lower[:len(lower)-1] + "ies"
+	case strings.HasSuffix(lower, "sis"):
+		plural = lower[:len(lower)-2] + "es"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		plural = lower + "es"
+	default:
+		plural = lower + "s"
+	}
+	return matchCase(word, plural)
+}
+
+// Singularize returns the singular of word, keeping its capitalization
+func (i *Inflector) Singularize(word string) string {
+	lower := strings.ToLower(word)
+	if lower == "" || i.uncountable[lower] {
+		return word
+	}
+	if singular, ok := i.singulars[lower]; ok {
+		return matchCase(word, singular)
+	}
+	if _, ok := i.plurals[lower]; ok {
+		return word
+	}
+
+	var singular string
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		singular = lower[:len(lower)-3] + "y"
+	case strings.HasSuffix(lower, "ses") && (strings.HasSuffix(lower, "sses") || strings.HasSuffix(lower, "uses")),
+		strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		singular = lower[:len(lower)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		singular = lower
+	case strings.HasSuffix(lower, "s"):
+		singular = lower[:len(lower)-1]
+	default:
+		singular = lower
+	}
+	return matchCase(word, singular)
+}
+
+// Naming turns struct and field names into table and column names using a
+// convention, an inflector for table plurals and per-model table overrides
+type Naming struct {
+	Convention NamingConvention
+	Inflector  *Inflector
+	// Tables maps struct names to table names, taking precedence over derived names
+	Tables map[string]string
+	// Include limits the structs that become models to those matching one of
+	// these names or path.Match patterns; every struct qualifies when empty
+	Include []string
+	// Exclude lists struct names or patterns that never become models
+	Exclude []string
+}
+
+// NewNaming returns a naming strategy for convention with the default inflector
+func NewNaming(convention NamingConvention) *Naming {
+	if convention == "" {
+		convention = SnakeCase
+	}
+	return &Naming{
+		Convention: convention,
+		Inflector:  NewInflector(),
+		Tables:     make(map[string]string),
+	}
+}
+
+// BuildNaming returns the naming strategy for a convention with extra irregular
+// plurals (singular to plural) and per-model table names (struct to table)
+func BuildNaming(convention string, plurals, tables map[string]string) (*Naming, error) {
+	parsed, err := ParseNamingConvention(convention)
+	if err != nil {
+		return nil, err
+	}
+
+	naming := NewNaming(parsed)
+	for singular, plural := range plurals {
+		naming.Inflector.AddIrregular(singular, plural)
+	}
+	for model, table := range tables {
+		naming.Tables[model] = table
+	}
+	return naming, nil
+}
+
+// DefaultNaming returns the snake_case naming strategy
+func DefaultNaming() *Naming {
+	return NewNaming(SnakeCase)
+}
+
+// TableName derives the table name for a struct, pluralizing its last word
+func (n *Naming) TableName(structName string) string {
+	if table, ok := n.Tables[structName]; ok && table != "" {
+		return table
+	}
+
+	words := splitWords(structName)
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] = n.Inflector.Pluralize(words[len(words)-1])
+	return n.join(words)
+}
+
+// IsModel reports whether the include and exclude lists let a struct become a model
+func (n *Naming) IsModel(structName string) bool {
+	if len(n.Include) > 0 && !matchesAny(n.Include, structName) {
+		return false
+	}
+	return !matchesAny(n.Exclude, structName)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ColumnName derives the column name for a struct field
+func (n *Naming) ColumnName(fieldName string) string {
+	return n.join(splitWords(fieldName))
+}
+
+func (n *Naming) join(words []string) string {
+	if n.Convention != CamelCase {
+		return strings.Join(words, "_")
+	}
+
+	var result strings.Builder
+	for i, word := range words {
+		if i > 0 && word != "" {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		result.WriteString(word)
+	}
+	return result.String()
+}
+
+// splitWords splits a Go identifier into lower-case words, keeping acronyms and
+// ordinals such as "1st" together
+func splitWords(s string) []string {
+	snake := snakeCase(s)
+	if snake == "" {
+		return nil
+	}
+	return strings.Split(snake, "_")
+}
+
+func snakeCase(s string) string {
+	edgeCases := map[string]string{
+		"OAuth2Token": "oauth2_token",
+		"OAuth2":      "oauth2",
+		"OAuth":       "oauth",
+	}
+	if result, ok := edgeCases[s]; ok {
+		return result
+	}
+
+	var result strings.Builder
+
+	for i, r := range s {
+		isUpper := r >= 'A' && r <= 'Z'
+
+		if i > 0 {
+			prevIsLower := s[i-1] >= 'a' && s[i-1] <= 'z'
+			prevIsDigit := s[i-1] >= '0' && s[i-1] <= '9'
+			prevIsUpper := s[i-1] >= 'A' && s[i-1] <= 'Z'
+
+			if isUpper && (prevIsLower || prevIsDigit) {
+				result.WriteRune('_')
+			} else if isUpper && prevIsUpper && i+1 < len(s) {
+
+				nextIsLower := s[i+1] >= 'a' && s[i+1] <= 'z'
+				if nextIsLower {
+					result.WriteRune('_')
+				}
+			} else if (r >= 'a' && r <= 'z') && prevIsDigit {
+
+				if i >= 2 {
+					prevPrevIsDigit := s[i-2] >= '0' && s[i-2] <= '9'
+					if !prevPrevIsDigit || !isOrdinalSuffix(s[i-1:]) {
+						result.WriteRune('_')
+					}
+				} else {
+					result.WriteRune('_')
+				}
+			}
+		}
+
+		if isUpper {
+			result.WriteRune(r - 'A' + 'a')
+		} else {
+			result.WriteRune(r)
+		}
+	}
+
+	return result.String()
+}
+
+func isOrdinalSuffix(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	suffix := s[:2]
+	return suffix == "st" || suffix == "nd" || suffix == "rd" || suffix == "th"
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
+
+// matchCase applies the capitalization of original to word
+func matchCase(original, word string) string {
+	if original == "" || word == "" {
+		return word
+	}
+	if strings.ToUpper(original) == original && len(original) > 1 {
+		return strings.ToUpper(word)
+	}
+	if unicode.IsUpper(rune(original[0])) {
+		return strings.ToUpper(word[:1]) + word[1:]
+	}
+	return word
+}
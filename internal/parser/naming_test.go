@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInflectorPluralize(t *testing.T) {
+	inflector := NewInflector()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"user", "users"},
+		{"category", "categories"},
+		{"day", "days"},
+		{"process", "processes"},
+		{"box", "boxes"},
+		{"branch", "branches"},
+		{"analysis", "analyses"},
+		{"diagnosis", "diagnoses"},
+		{"child", "children"},
+		{"person", "persons"},
+		{"status", "statuses"},
+		{"news", "news"},
+		{"metadata", "metadata"},
+		{"children", "children"},
+		{"Category", "Categories"},
+		{"Child", "Children"},
+		{"API", "APIS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := inflector.Pluralize(tt.input); got != tt.expected {
+				t.Errorf("Pluralize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInflectorSingularize(t *testing.T) {
+	inflector := NewInflector()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"users", "user"},
+		{"categories", "category"},
+		{"processes", "process"},
+		{"boxes", "box"},
+		{"branches", "branch"},
+		{"statuses", "status"},
+		{"children", "child"},
+		{"analyses", "analysis"},
+		{"news", "news"},
+		{"address", "address"},
+		{"user", "user"},
+		{"Categories", "Category"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := inflector.Singularize(tt.input); got != tt.expected {
+				t.Errorf("Singularize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInflectorAddIrregular(t *testing.T) {
+	inflector := NewInflector()
+	inflector.AddIrregular("person", "people")
+	inflector.AddIrregular("Cactus", "Cacti")
+	inflector.AddIrregular("equipment", "equipment")
+
+	tests := map[string]string{
+		"person":    "people",
+		"cactus":    "cacti",
+		"equipment": "equipment",
+	}
+	for singular, plural := range tests {
+		if got := inflector.Pluralize(singular); got != plural {
+			t.Errorf("Pluralize(%q) = %q, want %q", singular, got, plural)
+		}
+		if got := inflector.Singularize(plural); got != singular {
+			t.Errorf("Singularize(%q) = %q, want %q", plural, got, singular)
+		}
+	}
+}
+
+func TestNaming(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention NamingConvention
+		input      string
+		table      string
+		column     string
+	}{
+		{"snake simple", SnakeCase, "User", "users", "user"},
+		{"snake compound", SnakeCase, "UserProfile", "user_profiles", "user_profile"},
+		{"snake acronym", SnakeCase, "APIKey", "api_keys", "api_key"},
+		{"snake irregular last word", SnakeCase, "FamilyChild", "family_children", "family_child"},
+		{"camel simple", CamelCase, "User", "users", "user"},
+		{"camel compound", CamelCase, "UserProfile", "userProfiles", "userProfile"},
+		{"camel acronym", CamelCase, "UserID", "userIds", "userId"},
+		{"camel category", CamelCase, "ProductCategory", "productCategories", "productCategory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			naming := NewNaming(tt.convention)
+			if got := naming.TableName(tt.input); got != tt.table {
+				t.Errorf("TableName(%q) = %q, want %q", tt.input, got, tt.table)
+			}
+			if got := naming.ColumnName(tt.input); got != tt.column {
+				t.Errorf("ColumnName(%q) = %q, want %q", tt.input, got, tt.column)
+			}
+		})
+	}
+}
+
+func TestBuildNaming(t *testing.T) {
+	naming, err := BuildNaming("camelCase",
+		map[string]string{"person": "people"},
+		map[string]string{"AuditEntry": "audit_log"})
+	if err != nil {
+		t.Fatalf("BuildNaming failed: %v", err)
+	}
+
+	if got := naming.TableName("SalesPerson"); got != "salesPeople" {
+		t.Errorf("expected custom plural, got %q", got)
+	}
+	if got := naming.TableName("AuditEntry"); got != "audit_log" {
+		t.Errorf("expected table override, got %q", got)
+	}
+
+	if _, err := BuildNaming("kebab-case", nil, nil); err == nil {
+		t.Error("expected an error for an unknown convention")
+	}
+	if naming, err := BuildNaming("", nil, nil); err != nil || naming.Convention != SnakeCase {
+		t.Errorf("expected snake_case by default, got %v, %v", naming, err)
+	}
+}
+
+func TestStructParser_Naming(t *testing.T) {
+	dir := t.TempDir()
+	writeModelFile(t, dir, "models.go", `package models
+
+type UserProfile struct {
+	_         struct{} `+"`"+`storm:"index:idx_profile_user,user_id"`+"`"+`
+	ID        string   `+"`"+`storm:"type:uuid;primary_key"`+"`"+`
+	UserID    string   `+"`"+`storm:"type:uuid;not_null"`+"`"+`
+	CreatedAt string   `+"`"+`storm:"type:timestamptz"`+"`"+`
+	User      *User    `+"`"+`storm:"relation:belongs_to:User"`+"`"+`
+}
+
+type Person struct {
+	ID string `+"`"+`storm:"type:uuid;primary_key"`+"`"+`
+}
+`)
+
+	naming, err := BuildNaming("camelCase", map[string]string{"person": "people"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := NewStructParserWithNaming(naming).ParseDirectory(filepath.Clean(dir))
+	if err != nil {
+		t.Fatalf("ParseDirectory failed: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(tables))
+	}
+
+	profile := tables[0]
+	if profile.TableName != "userProfiles" {
+		t.Errorf("expected table userProfiles, got %s", profile.TableName)
+	}
+	columns := map[string]string{}
+	for _, field := range profile.Fields {
+		columns[field.Name] = field.DBName
+	}
+	if columns["UserID"] != "userId" || columns["CreatedAt"] != "createdAt" {
+		t.Errorf("expected camelCase columns, got %v", columns)
+	}
+
+	parsed, err := NewStormTagParserWithNaming(naming).ParseStormTag("relation:belongs_to:User", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.RelationForeignKey != "userId" {
+		t.Errorf("expected default foreign key userId, got %s", parsed.RelationForeignKey)
+	}
+
+	if tables[1].TableName != "people" {
+		t.Errorf("expected configured plural people, got %s", tables[1].TableName)
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SplitPackageSpec splits a models package specification holding several
+// entries separated by os.PathListSeparator
+func SplitPackageSpec(spec string) []string {
+	var entries []string
+	for _, entry := range filepath.SplitList(spec) {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// JoinPackageSpec combines several models package entries into one specification
+func JoinPackageSpec(entries []string) string {
+	return strings.Join(entries, string(os.PathListSeparator))
+}
+
+// ResolvePackageDirs expands a models package specification into directories.
+// An entry ending in "/..." includes every directory below it that contains Go
+// files, and an entry with glob characters matches directories by pattern.
+// Plain entries are returned as they are.
+func ResolvePackageDirs(spec string) ([]string, error) {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(dir string) {
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	for _, entry := range SplitPackageSpec(spec) {
+		switch {
+		case entry == "..." || strings.HasSuffix(entry, "/..."):
+			root := strings.TrimSuffix(strings.TrimSuffix(entry, "..."), "/")
+			if root == "" {
+				root = "."
+			}
+			matches, err := goPackageDirs(root)
+			if err != nil {
+				return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no Go packages found under %s", root)
+			}
+			for _, dir := range matches {
+				add(dir)
+			}
+
+		case strings.ContainsAny(entry, "*?["):
+			matches, err := filepath.Glob(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid package pattern %s: %w", entry, err)
+			}
+			found := false
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && info.IsDir() {
+					add(match)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("no directories match %s", entry)
+			}
+
+		default:
+			add(entry)
+		}
+	}
+
+	return dirs, nil
+}
+
+// goPackageDirs lists root and the directories below it that contain non-test Go
+// files, skipping hidden, vendor and testdata directories
+func goPackageDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+			return filepath.SkipDir
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") {
+				dirs = append(dirs, path)
+				break
+			}
+		}
+		return nil
+	})
+	return dirs, err
+}
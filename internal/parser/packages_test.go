@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeModelFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolvePackageDirs(t *testing.T) {
+	root := t.TempDir()
+	billing := filepath.Join(root, "domains", "billing", "models")
+	users := filepath.Join(root, "domains", "users", "models")
+	writeModelFile(t, billing, "invoice.go", "package models\n")
+	writeModelFile(t, users, "user.go", "package models\n")
+	writeModelFile(t, filepath.Join(root, "domains", "users", "testdata"), "fixture.go", "package testdata\n")
+	writeModelFile(t, filepath.Join(root, "domains", "docs"), "README.md", "docs\n")
+	writeModelFile(t, filepath.Join(root, "domains", "only_tests"), "x_test.go", "package x\n")
+
+	tests := []struct {
+		name     string
+		spec     string
+		expected []string
+	}{
+		{"single directory", users, []string{users}},
+		{"list of directories", JoinPackageSpec([]string{users, billing, users}), []string{users, billing}},
+		{"glob pattern", filepath.Join(root, "domains", "*", "models"), []string{billing, users}},
+		{"recursive pattern", filepath.Join(root, "domains") + "/...", []string{billing, users}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs, err := ResolvePackageDirs(tt.spec)
+			if err != nil {
+				t.Fatalf("ResolvePackageDirs failed: %v", err)
+			}
+			if !reflect.DeepEqual(dirs, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, dirs)
+			}
+		})
+	}
+
+	t.Run("pattern without matches", func(t *testing.T) {
+		_, err := ResolvePackageDirs(filepath.Join(root, "missing", "*"))
+		if err == nil || !strings.Contains(err.Error(), "no directories match") {
+			t.Errorf("expected no match error, got %v", err)
+		}
+	})
+}
+
+func TestStructParser_ParseDirectory_MultiplePackages(t *testing.T) {
+	root := t.TempDir()
+	writeModelFile(t, filepath.Join(root, "users"), "user.go", `package users
+
+type User struct {
+	_  struct{} `+"`"+`storm:"table:users"`+"`"+`
+	ID string   `+"`"+`db:"id" storm:"column:id;type:uuid;primary_key"`+"`"+`
+}
+`)
+	writeModelFile(t, filepath.Join(root, "billing"), "invoice.go", `package billing
+
+type Invoice struct {
+	_      struct{} `+"`"+`storm:"table:invoices"`+"`"+`
+	ID     string   `+"`"+`db:"id" storm:"column:id;type:uuid;primary_key"`+"`"+`
+	UserID string   `+"`"+`db:"user_id" storm:"column:user_id;type:uuid;foreign_key:users.id"`+"`"+`
+}
+`)
+
+	tables, err := NewStructParser().ParseDirectory(filepath.Join(root, "*"))
+	if err != nil {
+		t.Fatalf("ParseDirectory failed: %v", err)
+	}
+
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.TableName)
+	}
+	if !reflect.DeepEqual(names, []string{"invoices", "users"}) {
+		t.Errorf("expected models from both packages, got %v", names)
+	}
+}
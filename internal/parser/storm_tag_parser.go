@@ -3,12 +3,16 @@ package parser
 import (
 	"fmt"
 	"strings"
+	"sync"
 )
 
-// StormTagParser handles parsing of unified storm tags
+// StormTagParser handles parsing of unified storm tags. It is safe for
+// concurrent use; parsed tags are cached and shared, so callers must not
+// modify them.
 type StormTagParser struct {
-	// Cache for parsed tags
-	tagCache map[string]*ParsedStormTag
+	// Cache for parsed tags, keyed by tag and field kind
+	tagCache sync.Map
+	naming   *Naming
 }
 
 // ParsedStormTag represents a parsed storm tag that can contain both column and relationship attributes
@@ -26,6 +30,7 @@ type ParsedStormTag struct {
 	Constraint string
 	Prev       string
 	Enum       []string
+	EnumMode   string // "native" (default) or "check"
 	ArrayType  string
 
 	// Relationship attributes (from previous orm)
@@ -45,18 +50,36 @@ type ParsedStormTag struct {
 	Through            string   // Through association
 	Validate           bool     // Whether to validate association
 	Autosave           bool     // Whether to autosave association
+	Count              bool     // Whether IncludeCount fills a <Name>Count field
 	Counter            string   // Counter cache column
 
 	// Special attributes
-	Column    string // Database column name (replaces db tag for relationships)
-	Ignore    bool   // Exclude from database operations
-	Computed  string // Computed/derived field
-	Immutable bool   // Immutable field (create-only)
+	Column     string // Database column name (replaces db tag for relationships)
+	Ignore     bool   // Exclude from database operations
+	Computed   string // Computed/derived field
+	Immutable  bool   // Immutable field (create-only)
+	JSONIgnore bool   // Internal field left out of generated DTOs
+	Encrypted  string // Encryption mode: randomized or deterministic
+	Masked     bool   // Value masked in generated API responses
+	Sensitive  bool   // Value redacted from query logs
+	Anonymize  string // Mask applied by storm clone --anonymize: null, email, hash, keep or fixed:<value>
+	CIIndex    string // Case insensitive search index: lower or unaccent
+
+	AutoCreateTime bool // Set to NOW() on insert
+	AutoUpdateTime bool // Set to NOW() on insert and every update
 
 	// Table-level attributes (for _ struct{} fields)
 	Table         string   // Table name
 	Indexes       []string // Index definitions
 	UniqueIndexes []string // Unique constraints
+	Scopes        []string // Named query scopes (name,condition)
+	DefaultScopes []string // Query scopes applied by default (name,condition)
+	ShardKey      string   // Column that picks the shard holding a row
+	ForeignKeys   []string // Foreign keys, including composite ones declared on the table
+	EnableRLS     bool     // Enable row level security on the table
+	Policies      []string // Row level security policies (name,clause)
+	ReadOnly      bool     // Read model over a view or query, never migrated or written
+	Source        string   // View or SELECT statement a read model maps
 
 	// Raw tag value
 	Raw string
@@ -66,8 +89,17 @@ type ParsedStormTag struct {
 }
 
 func NewStormTagParser() *StormTagParser {
+	return NewStormTagParserWithNaming(nil)
+}
+
+// NewStormTagParserWithNaming returns a parser that names default foreign key
+// columns with naming, or in snake_case when nil
+func NewStormTagParserWithNaming(naming *Naming) *StormTagParser {
+	if naming == nil {
+		naming = DefaultNaming()
+	}
 	return &StormTagParser{
-		tagCache: make(map[string]*ParsedStormTag),
+		naming: naming,
 	}
 }
 
@@ -77,8 +109,8 @@ func (p *StormTagParser) ParseStormTag(tag string, isRelationshipField bool) (*P
 	}
 
 	cacheKey := fmt.Sprintf("%s:%t", tag, isRelationshipField)
-	if cached, exists := p.tagCache[cacheKey]; exists {
-		return cached, nil
+	if cached, exists := p.tagCache.Load(cacheKey); exists {
+		return cached.(*ParsedStormTag), nil
 	}
 
 	parsed := &ParsedStormTag{
@@ -103,8 +135,19 @@ func (p *StormTagParser) ParseStormTag(tag string, isRelationshipField bool) (*P
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
-	p.tagCache[cacheKey] = parsed
-	return parsed, nil
+	actual, _ := p.tagCache.LoadOrStore(cacheKey, parsed)
+	return actual.(*ParsedStormTag), nil
+}
+
+// IsRelationshipTag reports whether a storm tag declares a relationship.
+// Pointer and slice fields without one are nullable or array columns.
+func IsRelationshipTag(tag string) bool {
+	for _, attr := range strings.Split(tag, ";") {
+		if strings.HasPrefix(strings.TrimSpace(attr), "relation:") {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *StormTagParser) parseAttribute(attr string, parsed *ParsedStormTag) error {
@@ -135,6 +178,20 @@ func (p *StormTagParser) parseFlagAttribute(flag string, parsed *ParsedStormTag)
 		parsed.Ignore = true
 	case "immutable":
 		parsed.Immutable = true
+	case "json_ignore":
+		parsed.JSONIgnore = true
+	case "encrypted":
+		parsed.Encrypted = "randomized"
+	case "masked":
+		parsed.Masked = true
+	case "sensitive":
+		parsed.Sensitive = true
+	case "ci_index":
+		parsed.CIIndex = "lower"
+	case "auto_create_time":
+		parsed.AutoCreateTime = true
+	case "auto_update_time":
+		parsed.AutoUpdateTime = true
 	case "validate":
 		parsed.Validate = true
 	case "no_validate":
@@ -143,6 +200,12 @@ func (p *StormTagParser) parseFlagAttribute(flag string, parsed *ParsedStormTag)
 		parsed.Autosave = true
 	case "no_autosave":
 		parsed.Autosave = false
+	case "count":
+		parsed.Count = true
+	case "enable_rls":
+		parsed.EnableRLS = true
+	case "readonly":
+		parsed.ReadOnly = true
 	default:
 		return fmt.Errorf("unknown flag attribute: %s", flag)
 	}
@@ -166,6 +229,7 @@ func (p *StormTagParser) parseKeyValueAttribute(key, value string, parsed *Parse
 	case "foreign_key":
 		parsed.ForeignKey = value
 		parsed.RelationForeignKey = value
+		parsed.ForeignKeys = append(parsed.ForeignKeys, value)
 	case "on_delete":
 		parsed.OnDelete = value
 	case "on_update":
@@ -179,10 +243,30 @@ func (p *StormTagParser) parseKeyValueAttribute(key, value string, parsed *Parse
 		for i, v := range parsed.Enum {
 			parsed.Enum[i] = strings.TrimSpace(v)
 		}
+	case "enum_mode":
+		if value != "native" && value != "check" {
+			return fmt.Errorf("invalid enum mode: %s (expected native or check)", value)
+		}
+		parsed.EnumMode = value
 	case "array_type":
 		parsed.ArrayType = value
 	case "computed":
 		parsed.Computed = value
+	case "encrypted":
+		if value != "randomized" && value != "deterministic" {
+			return fmt.Errorf("invalid encryption mode: %s (expected randomized or deterministic)", value)
+		}
+		parsed.Encrypted = value
+	case "ci_index":
+		if value != "lower" && value != "unaccent" {
+			return fmt.Errorf("invalid ci_index mode: %s (expected lower or unaccent)", value)
+		}
+		parsed.CIIndex = value
+	case "anonymize":
+		if !isValidAnonymizeRule(value) {
+			return fmt.Errorf("invalid anonymize rule: %s (expected null, email, hash, keep or fixed:<value>)", value)
+		}
+		parsed.Anonymize = value
 
 	case "table":
 		parsed.Table = value
@@ -190,6 +274,27 @@ func (p *StormTagParser) parseKeyValueAttribute(key, value string, parsed *Parse
 		parsed.Indexes = append(parsed.Indexes, value)
 	case "unique":
 		parsed.UniqueIndexes = append(parsed.UniqueIndexes, value)
+	case "scope", "default_scope":
+		parts := strings.SplitN(value, ",", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("%s must be in the form name,condition: %s", key, value)
+		}
+		if key == "scope" {
+			parsed.Scopes = append(parsed.Scopes, value)
+		} else {
+			parsed.DefaultScopes = append(parsed.DefaultScopes, value)
+		}
+
+	case "shard_key":
+		parsed.ShardKey = value
+	case "source":
+		parsed.Source = value
+	case "policy":
+		parts := strings.SplitN(value, ",", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("policy must be in the form name,clause: %s", value)
+		}
+		parsed.Policies = append(parsed.Policies, value)
 
 	case "relation":
 		return p.parseRelationAttribute(value, parsed)
@@ -267,16 +372,26 @@ func (p *StormTagParser) validateRelationship(parsed *ParsedStormTag) error {
 		return fmt.Errorf("relationships must specify relation:type:target")
 	}
 
+	if parsed.Polymorphic != "" && parsed.RelationType == "has_many_through" {
+		return fmt.Errorf("polymorphic is not supported for has_many_through relationships")
+	}
+
 	switch parsed.RelationType {
 	case "belongs_to":
+		if parsed.RelationForeignKey == "" && parsed.Polymorphic != "" {
+			parsed.RelationForeignKey = parsed.Polymorphic + "_id"
+		}
 		if parsed.RelationForeignKey == "" {
-			parsed.RelationForeignKey = toSnakeCase(parsed.RelationTarget) + "_id"
+			parsed.RelationForeignKey = p.naming.ColumnName(parsed.RelationTarget + "ID")
 		}
 		if parsed.RelationTargetKey == "" {
 			parsed.RelationTargetKey = "id"
 		}
 
 	case "has_one", "has_many":
+		if parsed.RelationForeignKey == "" && parsed.Polymorphic != "" {
+			parsed.RelationForeignKey = parsed.Polymorphic + "_id"
+		}
 		if parsed.RelationForeignKey == "" {
 			return fmt.Errorf("foreign_key is required for %s relationships", parsed.RelationType)
 		}
@@ -326,9 +441,9 @@ func (p *StormTagParser) validateColumn(parsed *ParsedStormTag) error {
 		}
 	}
 
-	if parsed.ForeignKey != "" {
-		if err := p.validateForeignKey(parsed.ForeignKey); err != nil {
-			return fmt.Errorf("invalid foreign key '%s': %w", parsed.ForeignKey, err)
+	for _, fk := range parsed.ForeignKeys {
+		if err := p.validateForeignKey(fk); err != nil {
+			return fmt.Errorf("invalid foreign key '%s': %w", fk, err)
 		}
 	}
 
@@ -344,6 +459,14 @@ func (p *StormTagParser) validateColumn(parsed *ParsedStormTag) error {
 		}
 	}
 
+	if parsed.EnumMode != "" && len(parsed.Enum) == 0 {
+		return fmt.Errorf("enum_mode requires enum values")
+	}
+
+	if parsed.Encrypted == "randomized" && (parsed.PrimaryKey || parsed.Unique) {
+		return fmt.Errorf("randomized encrypted columns cannot be compared; use encrypted:deterministic for keys and unique columns")
+	}
+
 	return nil
 }
 
@@ -383,6 +506,15 @@ func isValidDependentAction(action string) bool {
 	return false
 }
 
+// isValidAnonymizeRule reports whether rule names a mask storm clone knows
+func isValidAnonymizeRule(rule string) bool {
+	switch rule {
+	case "null", "email", "hash", "keep":
+		return true
+	}
+	return strings.HasPrefix(rule, "fixed:")
+}
+
 func toSnakeCase(s string) string {
 	var result strings.Builder
 	for i, r := range s {
@@ -437,9 +569,42 @@ func (p *ParsedStormTag) ToDBDefAttributes() map[string]string {
 	if len(p.Enum) > 0 {
 		attrs["enum"] = strings.Join(p.Enum, ",")
 	}
+	if p.EnumMode != "" {
+		attrs["enum_mode"] = p.EnumMode
+	}
 	if p.ArrayType != "" {
 		attrs["array_type"] = p.ArrayType
 	}
+	if p.Immutable {
+		attrs["immutable"] = ""
+	}
+	if p.JSONIgnore {
+		attrs["json_ignore"] = ""
+	}
+	if p.Encrypted != "" {
+		attrs["encrypted"] = p.Encrypted
+	}
+	if p.Masked {
+		attrs["masked"] = ""
+	}
+	if p.Sensitive {
+		attrs["sensitive"] = ""
+	}
+	if p.Anonymize != "" {
+		attrs["anonymize"] = p.Anonymize
+	}
+	if p.CIIndex != "" {
+		attrs["ci_index"] = p.CIIndex
+	}
+	if p.AutoCreateTime {
+		attrs["auto_create_time"] = ""
+	}
+	if p.AutoUpdateTime {
+		attrs["auto_update_time"] = ""
+	}
+	if p.Computed != "" {
+		attrs["computed"] = p.Computed
+	}
 
 	return attrs
 }
@@ -464,6 +629,30 @@ func (p *ParsedStormTag) ToTableLevelAttributes() map[string]string {
 			attrs["unique"] = unique
 		}
 	}
+	if len(p.Scopes) > 0 {
+		attrs["scope"] = strings.Join(p.Scopes, ";")
+	}
+	if len(p.DefaultScopes) > 0 {
+		attrs["default_scope"] = strings.Join(p.DefaultScopes, ";")
+	}
+	if p.ShardKey != "" {
+		attrs["shard_key"] = p.ShardKey
+	}
+	if len(p.ForeignKeys) > 0 {
+		attrs["foreign_key"] = strings.Join(p.ForeignKeys, ";")
+	}
+	if p.EnableRLS {
+		attrs["enable_rls"] = ""
+	}
+	if len(p.Policies) > 0 {
+		attrs["policy"] = strings.Join(p.Policies, ";")
+	}
+	if p.ReadOnly {
+		attrs["readonly"] = ""
+	}
+	if p.Source != "" {
+		attrs["source"] = p.Source
+	}
 
 	return attrs
 }
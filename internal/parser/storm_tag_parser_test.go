@@ -2,6 +2,7 @@ package parser
 
 import (
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -136,6 +137,176 @@ func TestStormTagParser_ToDBDefAttributes(t *testing.T) {
 	}
 }
 
+func TestStormTagParser_ToDBDefAttributesImmutable(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("column:slug;type:varchar(64);immutable", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToDBDefAttributes()
+	if _, exists := attrs["immutable"]; !exists {
+		t.Errorf("expected immutable attribute, got %v", attrs)
+	}
+}
+
+func TestStormTagParser_ToDBDefAttributesAutoTime(t *testing.T) {
+	parser := NewStormTagParser()
+
+	for _, flag := range []string{"auto_create_time", "auto_update_time"} {
+		parsed, err := parser.ParseStormTag("type:timestamptz;"+flag, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", flag, err)
+		}
+
+		attrs := parsed.ToDBDefAttributes()
+		if _, exists := attrs[flag]; !exists {
+			t.Errorf("expected %s attribute, got %v", flag, attrs)
+		}
+	}
+}
+
+func TestStormTagParser_ToDBDefAttributesJSONIgnore(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("column:password_hash;type:text;not_null;json_ignore", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToDBDefAttributes()
+	if _, exists := attrs["json_ignore"]; !exists {
+		t.Errorf("expected json_ignore attribute, got %v", attrs)
+	}
+}
+
+func TestStormTagParser_ToDBDefAttributesComputed(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("column:full_name;type:text;computed:first_name || ' ' || last_name", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToDBDefAttributes()
+	if attrs["computed"] != "first_name || ' ' || last_name" {
+		t.Errorf("expected computed attribute, got %v", attrs)
+	}
+}
+
+func TestStormTagParser_Encrypted(t *testing.T) {
+	parser := NewStormTagParser()
+
+	tests := []struct {
+		tag       string
+		encrypted string
+		masked    bool
+	}{
+		{"encrypted", "randomized", false},
+		{"encrypted:deterministic;unique", "deterministic", false},
+		{"type:text;masked", "", true},
+	}
+
+	for _, tt := range tests {
+		parsed, err := parser.ParseStormTag(tt.tag, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.tag, err)
+		}
+		attrs := parsed.ToDBDefAttributes()
+		if got, ok := attrs["encrypted"]; got != tt.encrypted || ok != (tt.encrypted != "") {
+			t.Errorf("%s: expected encrypted %q, got %v", tt.tag, tt.encrypted, attrs)
+		}
+		if _, ok := attrs["masked"]; ok != tt.masked {
+			t.Errorf("%s: expected masked %t, got %v", tt.tag, tt.masked, attrs)
+		}
+	}
+
+	for _, tag := range []string{"encrypted:sometimes", "encrypted;unique", "encrypted;primary_key"} {
+		if _, err := parser.ParseStormTag(tag, false); err == nil {
+			t.Errorf("%s: expected an error", tag)
+		}
+	}
+}
+
+func TestStormTagParser_Sensitive(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("type:text;sensitive", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !parsed.Sensitive {
+		t.Error("expected sensitive to be set")
+	}
+	if _, ok := parsed.ToDBDefAttributes()["sensitive"]; !ok {
+		t.Errorf("expected sensitive attribute, got %v", parsed.ToDBDefAttributes())
+	}
+}
+
+func TestStormTagParser_Anonymize(t *testing.T) {
+	parser := NewStormTagParser()
+
+	for _, rule := range []string{"email", "null", "fixed:redacted@example.com"} {
+		parsed, err := parser.ParseStormTag("type:text;anonymize:"+rule, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", rule, err)
+		}
+		if got := parsed.ToDBDefAttributes()["anonymize"]; got != rule {
+			t.Errorf("%s: expected anonymize %q, got %q", rule, rule, got)
+		}
+	}
+
+	if _, err := parser.ParseStormTag("type:text;anonymize:scramble", false); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+}
+
+func TestStormTagParser_CIIndex(t *testing.T) {
+	parser := NewStormTagParser()
+
+	for tag, want := range map[string]string{"ci_index": "lower", "ci_index:unaccent": "unaccent"} {
+		parsed, err := parser.ParseStormTag("type:text;"+tag, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tag, err)
+		}
+		if got := parsed.ToDBDefAttributes()["ci_index"]; got != want {
+			t.Errorf("%s: expected ci_index %q, got %q", tag, want, got)
+		}
+	}
+
+	if _, err := parser.ParseStormTag("ci_index:upper", false); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestStormTagParser_EnumMode(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("enum:draft,published;enum_mode:check", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := parsed.ToDBDefAttributes()["enum_mode"]; got != "check" {
+		t.Errorf("expected enum_mode check, got %q", got)
+	}
+
+	for _, tag := range []string{"enum:draft;enum_mode:lookup", "type:text;enum_mode:check"} {
+		if _, err := parser.ParseStormTag(tag, false); err == nil {
+			t.Errorf("%s: expected an error", tag)
+		}
+	}
+}
+
+func TestIsRelationshipTag(t *testing.T) {
+	if !IsRelationshipTag("relation:belongs_to:User;foreign_key:user_id") {
+		t.Error("expected a relationship tag")
+	}
+	if IsRelationshipTag("type:timestamptz;encrypted") {
+		t.Error("expected a column tag")
+	}
+}
+
 func TestStormTagParser_ValidationErrors(t *testing.T) {
 	parser := NewStormTagParser()
 
@@ -358,3 +529,135 @@ func TestStormTagParser_ToTableLevelAttributes(t *testing.T) {
 		t.Errorf("expected index attribute 'idx_user_id', got '%s'", attrs["index"])
 	}
 }
+
+func TestStormTagParser_Scopes(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("table:users;scope:active,is_active = true;scope:admins,role = 'admin';default_scope:visible,deleted_at IS NULL", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToTableLevelAttributes()
+	if attrs["scope"] != "active,is_active = true;admins,role = 'admin'" {
+		t.Errorf("unexpected scope attribute: %q", attrs["scope"])
+	}
+	if attrs["default_scope"] != "visible,deleted_at IS NULL" {
+		t.Errorf("unexpected default_scope attribute: %q", attrs["default_scope"])
+	}
+
+	if _, err := parser.ParseStormTag("table:users;scope:active", false); err == nil {
+		t.Error("expected error for scope without condition")
+	}
+}
+
+func TestStormTagParser_TableForeignKeys(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("table:order_items;foreign_key:orders(tenant_id,order_id);foreign_key:fk_product,product_id REFERENCES products(id) ON DELETE CASCADE", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToTableLevelAttributes()
+	if attrs["foreign_key"] != "orders(tenant_id,order_id);fk_product,product_id REFERENCES products(id) ON DELETE CASCADE" {
+		t.Errorf("unexpected foreign_key attribute: %q", attrs["foreign_key"])
+	}
+
+	for _, tag := range []string{"table:order_items;foreign_key:orders(tenant_id", "table:order_items;foreign_key:orders() ON DELETE CASCADE"} {
+		if _, err := parser.ParseStormTag(tag, false); err == nil {
+			t.Errorf("%s: expected an error", tag)
+		}
+	}
+}
+
+func TestStormTagParser_RowSecurity(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("table:documents;enable_rls;policy:tenant_isolation,USING tenant_id = current_setting('app.tenant')::uuid;policy:owner_write,FOR UPDATE USING owner_id = current_user", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToTableLevelAttributes()
+	if _, ok := attrs["enable_rls"]; !ok {
+		t.Error("expected enable_rls attribute")
+	}
+	if attrs["policy"] != "tenant_isolation,USING tenant_id = current_setting('app.tenant')::uuid;owner_write,FOR UPDATE USING owner_id = current_user" {
+		t.Errorf("unexpected policy attribute: %q", attrs["policy"])
+	}
+
+	if _, err := parser.ParseStormTag("table:documents;policy:tenant_isolation", false); err == nil {
+		t.Error("expected error for policy without a clause")
+	}
+}
+
+func TestStormTagParser_ReadModel(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("readonly;source:SELECT author_id, count(*) AS posts FROM posts GROUP BY author_id", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToTableLevelAttributes()
+	if _, ok := attrs["readonly"]; !ok {
+		t.Error("expected readonly attribute")
+	}
+	if attrs["source"] != "SELECT author_id, count(*) AS posts FROM posts GROUP BY author_id" {
+		t.Errorf("unexpected source attribute: %q", attrs["source"])
+	}
+}
+
+func TestStormTagParser_Polymorphic(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("relation:has_many:Comment;polymorphic:commentable", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.RelationForeignKey != "commentable_id" {
+		t.Errorf("expected foreign key commentable_id, got %s", parsed.RelationForeignKey)
+	}
+
+	parsed, err = parser.ParseStormTag("relation:belongs_to:Post;polymorphic:commentable", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.RelationForeignKey != "commentable_id" {
+		t.Errorf("expected foreign key commentable_id, got %s", parsed.RelationForeignKey)
+	}
+
+	if _, err := parser.ParseStormTag("relation:has_many_through:Tag;join_table:t;source_fk:a;target_fk:b;polymorphic:taggable", true); err == nil {
+		t.Error("expected error for polymorphic has_many_through")
+	}
+}
+
+func TestStormTagParser_ConcurrentParse(t *testing.T) {
+	parser := NewStormTagParser()
+	tags := []string{"type:uuid;primary_key", "type:text;not_null", "relation:belongs_to:User;foreign_key:user_id"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tag := tags[i%len(tags)]
+			parsed, err := parser.ParseStormTag(tag, strings.HasPrefix(tag, "relation:"))
+			if err != nil {
+				t.Errorf("unexpected error for %q: %v", tag, err)
+				return
+			}
+			if parsed.Raw != tag {
+				t.Errorf("expected raw tag %q, got %q", tag, parsed.Raw)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	first, _ := parser.ParseStormTag(tags[0], false)
+	second, _ := parser.ParseStormTag(tags[0], false)
+	if first != second {
+		t.Error("expected repeated parses to return the cached tag")
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
 	"path/filepath"
@@ -24,6 +25,11 @@ type FieldDefinition struct {
 	JSONTag        string
 	ORMTag         string // Deprecated: use StormTag instead
 	StormTag       string // New unified tag
+	// ResolvedType is what a named field type is defined as, e.g. string for
+	// a type UserID string, when ParseDirectory could resolve it
+	ResolvedType string
+	// ResolveError explains why a named field type could not be resolved
+	ResolveError string
 }
 
 // TableDefinition represents a complete table structure
@@ -32,6 +38,16 @@ type TableDefinition struct {
 	TableName  string
 	Fields     []FieldDefinition
 	TableLevel map[string]string
+	TableTag   string // Raw storm tag of the table-level _ field
+
+	embeds []embeddedStruct
+}
+
+// embeddedStruct is a struct embedded in a model. Its fields are flattened
+// into the model once every struct of the package has been parsed.
+type embeddedStruct struct {
+	position int // Index in Fields where the embedded fields belong
+	typeName string
 }
 
 // StructParser handles parsing Go struct definitions
@@ -39,48 +55,107 @@ type StructParser struct {
 	fileSet        *token.FileSet
 	tagParser      *TagParser
 	stormTagParser *StormTagParser
+	naming         *Naming
 }
 
 func NewStructParser() *StructParser {
+	return NewStructParserWithNaming(nil)
+}
+
+// NewStructParserWithNaming returns a parser that derives table and column
+// names with naming, or with the default snake_case naming when nil
+func NewStructParserWithNaming(naming *Naming) *StructParser {
+	if naming == nil {
+		naming = DefaultNaming()
+	}
 	return &StructParser{
 		fileSet:        token.NewFileSet(),
 		tagParser:      NewTagParser(),
-		stormTagParser: NewStormTagParser(),
+		stormTagParser: NewStormTagParserWithNaming(naming),
+		naming:         naming,
 	}
 }
 
+// ParseDirectory parses the models in dir. dir may also be a package
+// specification naming several directories or patterns (see ResolvePackageDirs),
+// in which case the models of all of them are returned together.
 func (p *StructParser) ParseDirectory(dir string) ([]TableDefinition, error) {
+	dirs, err := ResolvePackageDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		dirs = []string{dir}
+	}
+
+	var allTables []TableDefinition
+	for _, packageDir := range dirs {
+		tables, err := p.parsePackageDir(packageDir)
+		if err != nil {
+			return nil, err
+		}
+		allTables = append(allTables, tables...)
+	}
+
+	return allTables, nil
+}
+
+func (p *StructParser) parsePackageDir(dir string) ([]TableDefinition, error) {
 	pattern := filepath.Join(dir, "*.go")
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to glob directory %s: %w", dir, err)
 	}
 
-	var allTables []TableDefinition
+	var structs []TableDefinition
+	var files []*ast.File
 
 	for _, file := range matches {
 		if strings.HasSuffix(file, "_test.go") {
 			continue
 		}
 
-		tables, err := p.ParseFile(file)
+		// Files excluded by build constraints, e.g. //go:build ignore, hold no models
+		if matched, err := build.Default.MatchFile(dir, filepath.Base(file)); err == nil && !matched {
+			continue
+		}
+
+		src, fileStructs, err := p.parseFileStructs(file)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse file %s: %w", file, err)
 		}
 
-		allTables = append(allTables, tables...)
+		files = append(files, src)
+		structs = append(structs, fileStructs...)
 	}
 
-	return allTables, nil
+	tables, err := p.resolveTables(structs)
+	if err != nil {
+		return nil, err
+	}
+
+	p.resolveFieldTypes(dir, files, tables)
+	return tables, nil
 }
 
+// ParseFile parses the models in a single file. Embedded structs are only
+// flattened when they are declared in the same file and named field types are
+// not resolved; use ParseDirectory for whole packages.
 func (p *StructParser) ParseFile(filename string) ([]TableDefinition, error) {
+	_, structs, err := p.parseFileStructs(filename)
+	if err != nil {
+		return nil, err
+	}
+	return p.resolveTables(structs)
+}
+
+func (p *StructParser) parseFileStructs(filename string) (*ast.File, []TableDefinition, error) {
 	src, err := parser.ParseFile(p.fileSet, filename, nil, parser.ParseComments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	var tables []TableDefinition
+	var structs []TableDefinition
 
 	ast.Inspect(src, func(n ast.Node) bool {
 		switch node := n.(type) {
@@ -92,17 +167,104 @@ func (p *StructParser) ParseFile(filename string) ([]TableDefinition, error) {
 					return true
 				}
 
-				if p.isDatabaseStruct(table) {
-					tables = append(tables, table)
-				}
+				structs = append(structs, table)
 			}
 		}
 		return true
 	})
 
+	return src, structs, nil
+}
+
+// resolveTables flattens embedded structs into the structs embedding them and
+// returns the database structs. A struct that is only embedded, such as a
+// BaseModel holding shared columns, is not a table of its own unless it
+// declares table-level attributes. Structs marked with _ struct{} `storm:"-"`
+// or left out by the naming's include and exclude lists are skipped too, but
+// can still be embedded.
+func (p *StructParser) resolveTables(structs []TableDefinition) ([]TableDefinition, error) {
+	byName := make(map[string]*TableDefinition, len(structs))
+	embedded := make(map[string]bool)
+	for i := range structs {
+		byName[structs[i].StructName] = &structs[i]
+		for _, embed := range structs[i].embeds {
+			embedded[embed.typeName] = true
+		}
+	}
+
+	var tables []TableDefinition
+	for _, table := range structs {
+		fields, err := p.flattenFields(table, byName, make(map[string]bool))
+		if err != nil {
+			return nil, fmt.Errorf("struct %s: %w", table.StructName, err)
+		}
+		table.Fields = fields
+		table.embeds = nil
+
+		if table.TableTag == "-" || !p.naming.IsModel(table.StructName) {
+			continue
+		}
+		if embedded[table.StructName] && len(table.TableLevel) == 0 && table.TableTag == "" {
+			continue
+		}
+		if p.isDatabaseStruct(table) {
+			tables = append(tables, table)
+		}
+	}
+
 	return tables, nil
 }
 
+// flattenFields returns the fields of table with the fields of its embedded
+// structs in place of the embedding. As in Go, a field declared on the struct
+// itself hides a promoted field of the same name.
+func (p *StructParser) flattenFields(table TableDefinition, structs map[string]*TableDefinition, visiting map[string]bool) ([]FieldDefinition, error) {
+	if len(table.embeds) == 0 {
+		return table.Fields, nil
+	}
+	if visiting[table.StructName] {
+		return nil, fmt.Errorf("struct %s embeds itself", table.StructName)
+	}
+	visiting[table.StructName] = true
+	defer delete(visiting, table.StructName)
+
+	own := make(map[string]bool, len(table.Fields))
+	for _, field := range table.Fields {
+		own[field.Name] = true
+	}
+
+	promotedFrom := make(map[string]string)
+	fields := make([]FieldDefinition, 0, len(table.Fields))
+	next := 0
+	for _, embed := range table.embeds {
+		fields = append(fields, table.Fields[next:embed.position]...)
+		next = embed.position
+
+		inner, ok := structs[embed.typeName]
+		if !ok {
+			continue
+		}
+
+		innerFields, err := p.flattenFields(*inner, structs, visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range innerFields {
+			if own[field.Name] {
+				continue
+			}
+			if from, exists := promotedFrom[field.Name]; exists {
+				return nil, fmt.Errorf("field %s is promoted from both %s and %s", field.Name, from, embed.typeName)
+			}
+			promotedFrom[field.Name] = embed.typeName
+			fields = append(fields, field)
+		}
+	}
+
+	return append(fields, table.Fields[next:]...), nil
+}
+
 func (p *StructParser) parseStruct(structName string, structType *ast.StructType) (TableDefinition, error) {
 	table := TableDefinition{
 		StructName: structName,
@@ -112,6 +274,16 @@ func (p *StructParser) parseStruct(structName string, structType *ast.StructType
 	}
 
 	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			if ident, ok := field.Type.(*ast.Ident); ok {
+				table.embeds = append(table.embeds, embeddedStruct{position: len(table.Fields), typeName: ident.Name})
+			}
+		}
+
+		if len(field.Names) == 1 && field.Names[0].Name == "_" && field.Tag != nil {
+			table.TableTag = p.extractTag(strings.Trim(field.Tag.Value, "`"), "storm")
+		}
+
 		fieldDefs, tableLevelAttrs, err := p.parseField(field)
 		if err != nil {
 			return table, fmt.Errorf("failed to parse field: %w", err)
@@ -209,19 +381,19 @@ func (p *StructParser) parseField(field *ast.Field) ([]FieldDefinition, map[stri
 			if fieldDef.DBTag != "" {
 				fieldDef.DBName = fieldDef.DBTag
 			} else if fieldDef.StormTag != "" {
-				isRelationshipField := fieldDef.IsArray || fieldDef.IsPointer
+				isRelationshipField := (fieldDef.IsArray || fieldDef.IsPointer) && IsRelationshipTag(fieldDef.StormTag)
 				parsed, err := p.stormTagParser.ParseStormTag(fieldDef.StormTag, isRelationshipField)
 				if err == nil && parsed.Column != "" {
 					fieldDef.DBName = parsed.Column
 				} else {
-					fieldDef.DBName = p.toSnakeCase(fieldDef.Name)
+					fieldDef.DBName = p.naming.ColumnName(fieldDef.Name)
 				}
 			} else {
-				fieldDef.DBName = p.toSnakeCase(fieldDef.Name)
+				fieldDef.DBName = p.naming.ColumnName(fieldDef.Name)
 			}
 
 			if fieldDef.StormTag != "" {
-				isRelationshipField := fieldDef.IsArray || fieldDef.IsPointer
+				isRelationshipField := (fieldDef.IsArray || fieldDef.IsPointer) && IsRelationshipTag(fieldDef.StormTag)
 				parsed, err := p.stormTagParser.ParseStormTag(fieldDef.StormTag, isRelationshipField)
 				if err == nil {
 					fieldDef.IsRelationship = parsed.IsRelationship
@@ -239,7 +411,7 @@ func (p *StructParser) parseField(field *ast.Field) ([]FieldDefinition, map[stri
 				fieldDef.DBDef = make(map[string]string)
 			}
 		} else {
-			fieldDef.DBName = p.toSnakeCase(fieldDef.Name)
+			fieldDef.DBName = p.naming.ColumnName(fieldDef.Name)
 			fieldDef.DBDef = make(map[string]string)
 		}
 
@@ -287,7 +459,7 @@ func (p *StructParser) isDatabaseStruct(table TableDefinition) bool {
 		}
 	}
 
-	if len(table.TableLevel) > 0 {
+	if len(table.TableLevel) > 0 || table.TableTag != "" {
 		return true
 	}
 
@@ -295,100 +467,11 @@ func (p *StructParser) isDatabaseStruct(table TableDefinition) bool {
 }
 
 func (p *StructParser) deriveTableName(structName string) string {
-
-	snake := p.toSnakeCase(structName)
-
-	irregularPlurals := map[string]string{
-		"analysis": "analyses",
-		"basis":    "bases",
-		"datum":    "data",
-		"index":    "indexes",
-		"matrix":   "matrices",
-		"vertex":   "vertices",
-		"axis":     "axes",
-		"crisis":   "crises",
-
-		"child": "children",
-		"foot":  "feet",
-		"tooth": "teeth",
-		"goose": "geese",
-		"man":   "men",
-		"woman": "women",
-		"mouse": "mice",
-	}
-
-	if plural, ok := irregularPlurals[snake]; ok {
-		return plural
-	}
-
-	if strings.HasSuffix(snake, "y") && !strings.HasSuffix(snake, "ey") && !strings.HasSuffix(snake, "ay") && !strings.HasSuffix(snake, "oy") && !strings.HasSuffix(snake, "uy") {
-
-		return snake[:len(snake)-1] + "ies"
-	}
-	if strings.HasSuffix(snake, "s") || strings.HasSuffix(snake, "sh") || strings.HasSuffix(snake, "ch") || strings.HasSuffix(snake, "x") || strings.HasSuffix(snake, "z") {
-		return snake + "es"
-	}
-	return snake + "s"
+	return p.naming.TableName(structName)
 }
 
 func (p *StructParser) toSnakeCase(s string) string {
-
-	edgeCases := map[string]string{
-		"OAuth2Token": "oauth2_token",
-		"OAuth2":      "oauth2",
-		"OAuth":       "oauth",
-	}
-	if result, ok := edgeCases[s]; ok {
-		return result
-	}
-
-	var result strings.Builder
-
-	for i, r := range s {
-		isUpper := r >= 'A' && r <= 'Z'
-
-		if i > 0 {
-			prevIsLower := s[i-1] >= 'a' && s[i-1] <= 'z'
-			prevIsDigit := s[i-1] >= '0' && s[i-1] <= '9'
-			prevIsUpper := s[i-1] >= 'A' && s[i-1] <= 'Z'
-
-			if isUpper && (prevIsLower || prevIsDigit) {
-				result.WriteRune('_')
-			} else if isUpper && prevIsUpper && i+1 < len(s) {
-
-				nextIsLower := s[i+1] >= 'a' && s[i+1] <= 'z'
-				if nextIsLower {
-					result.WriteRune('_')
-				}
-			} else if (r >= 'a' && r <= 'z') && prevIsDigit {
-
-				if i >= 2 {
-					prevPrevIsDigit := s[i-2] >= '0' && s[i-2] <= '9'
-					if !prevPrevIsDigit || !isOrdinalSuffix(s[i-1:]) {
-						result.WriteRune('_')
-					}
-				} else {
-					result.WriteRune('_')
-				}
-			}
-		}
-
-		if isUpper {
-			result.WriteRune(r - 'A' + 'a')
-		} else {
-			result.WriteRune(r)
-		}
-	}
-
-	return result.String()
-}
-
-func isOrdinalSuffix(s string) bool {
-	if len(s) < 2 {
-		return false
-	}
-	suffix := s[:2]
-	return suffix == "st" || suffix == "nd" || suffix == "rd" || suffix == "th"
+	return snakeCase(s)
 }
 
 func (p *StructParser) exprToString(expr ast.Expr) string {
@@ -3,6 +3,7 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -229,6 +230,215 @@ type Team struct {
 	}
 }
 
+func TestStructParser_TableTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test_model.go")
+
+	testCode := `
+package models
+
+type Post struct {
+	_ struct{} ` + "`" + `storm:"table:posts;colour:blue"` + "`" + `
+
+	ID string ` + "`" + `db:"id" storm:"column:id;type:uuid;primary_key"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(tables))
+	}
+
+	if tables[0].TableTag != "table:posts;colour:blue" {
+		t.Errorf("Expected raw table tag to be kept, got '%s'", tables[0].TableTag)
+	}
+	if _, hasTable := tables[0].TableLevel["table"]; hasTable {
+		t.Error("Invalid table tag should not produce table-level attributes")
+	}
+}
+
+func TestStructParser_PointerColumnTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test_model.go")
+
+	testCode := `
+package models
+
+type Customer struct {
+	_ struct{} ` + "`" + `storm:"table:customers"` + "`" + `
+
+	ID    string                 ` + "`" + `db:"id" storm:"type:uuid;primary_key"` + "`" + `
+	Phone *storm.EncryptedString ` + "`" + `db:"phone" storm:"encrypted"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	phone := findField(tables[0].Fields, "Phone")
+	if phone == nil {
+		t.Fatal("Phone field not found")
+	}
+	if phone.IsRelationship || phone.DBDef["encrypted"] != "randomized" {
+		t.Errorf("Expected a nullable encrypted column, got %+v", phone)
+	}
+}
+
+func TestStructParser_EmbeddedStruct(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	baseCode := `
+package models
+
+import "time"
+
+type BaseModel struct {
+	ID        string    ` + "`" + `db:"id" storm:"type:uuid;primary_key"` + "`" + `
+	CreatedAt time.Time ` + "`" + `db:"created_at" storm:"default:now()"` + "`" + `
+	UpdatedAt time.Time ` + "`" + `db:"updated_at" storm:"default:now()"` + "`" + `
+}
+`
+
+	userCode := `
+package models
+
+type User struct {
+	_ struct{} ` + "`" + `storm:"table:users"` + "`" + `
+
+	Email string ` + "`" + `db:"email" storm:"type:text;not_null"` + "`" + `
+	BaseModel
+	UpdatedAt string ` + "`" + `db:"modified_at" storm:"type:text"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "base.go"), []byte(baseCode), 0644); err != nil {
+		t.Fatalf("Failed to write base file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "user.go"), []byte(userCode), 0644); err != nil {
+		t.Fatalf("Failed to write user file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseDirectory(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to parse directory: %v", err)
+	}
+	if len(tables) != 1 || tables[0].StructName != "User" {
+		t.Fatalf("Expected only the User table, got %+v", tables)
+	}
+
+	var columns []string
+	for _, field := range tables[0].Fields {
+		columns = append(columns, field.DBName)
+	}
+	expected := []string{"email", "id", "created_at", "modified_at"}
+	if strings.Join(columns, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected columns %v, got %v", expected, columns)
+	}
+
+	if id := findField(tables[0].Fields, "ID"); id == nil || id.DBDef["type"] != "uuid" {
+		t.Errorf("Expected the embedded column definition, got %+v", id)
+	} else if _, ok := id.DBDef["primary_key"]; !ok {
+		t.Errorf("Expected the embedded primary key, got %+v", id)
+	}
+
+	t.Run("reports ambiguous fields", func(t *testing.T) {
+		auditCode := `
+package models
+
+type Audit struct {
+	CreatedAt string ` + "`" + `db:"created_at"` + "`" + `
+}
+
+type Post struct {
+	BaseModel
+	Audit
+}
+`
+		if err := os.WriteFile(filepath.Join(tmpDir, "post.go"), []byte(auditCode), 0644); err != nil {
+			t.Fatalf("Failed to write post file: %v", err)
+		}
+
+		_, err := NewStructParser().ParseDirectory(tmpDir)
+		if err == nil || !strings.Contains(err.Error(), "field CreatedAt is promoted from both BaseModel and Audit") {
+			t.Errorf("Expected ambiguous field error, got %v", err)
+		}
+	})
+}
+
+func TestStructParser_ModelSelection(t *testing.T) {
+	dir := t.TempDir()
+	writeModelFile(t, dir, "models.go", `package models
+
+type User struct {
+	ID string `+"`"+`db:"id" storm:"type:uuid;primary_key"`+"`"+`
+}
+
+type UserFilter struct {
+	_    struct{} `+"`"+`storm:"-"`+"`"+`
+	Name string   `+"`"+`db:"name"`+"`"+`
+}
+
+type AuditDTO struct {
+	Action string `+"`"+`db:"action"`+"`"+`
+}
+
+type Audited struct {
+	_ struct{} `+"`"+`storm:"table:audited"`+"`"+`
+	UserFilter
+}
+`)
+	writeModelFile(t, dir, "scratch.go", `//go:build ignore
+
+package models
+
+type Scratch struct {
+	ID string `+"`"+`db:"id" storm:"type:uuid;primary_key"`+"`"+`
+}
+`)
+
+	tableNames := func(naming *Naming) []string {
+		tables, err := NewStructParserWithNaming(naming).ParseDirectory(dir)
+		if err != nil {
+			t.Fatalf("ParseDirectory failed: %v", err)
+		}
+		var names []string
+		for _, table := range tables {
+			names = append(names, table.StructName)
+		}
+		return names
+	}
+
+	if got := tableNames(nil); strings.Join(got, ",") != "User,AuditDTO,Audited" {
+		t.Errorf("expected ignored and build-excluded structs to be skipped, got %v", got)
+	}
+
+	naming := DefaultNaming()
+	naming.Exclude = []string{"*DTO"}
+	if got := tableNames(naming); strings.Join(got, ",") != "User,Audited" {
+		t.Errorf("expected excluded structs to be skipped, got %v", got)
+	}
+
+	naming = DefaultNaming()
+	naming.Include = []string{"Audited"}
+	got := tableNames(naming)
+	if strings.Join(got, ",") != "Audited" {
+		t.Fatalf("expected only included structs, got %v", got)
+	}
+}
+
 func findField(fields []FieldDefinition, name string) *FieldDefinition {
 	for _, f := range fields {
 		if f.Name == name {
@@ -237,3 +447,60 @@ func findField(fields []FieldDefinition, name string) *FieldDefinition {
 	}
 	return nil
 }
+
+func TestStructParser_ResolvesNamedTypes(t *testing.T) {
+	dir := t.TempDir()
+	writeModelFile(t, dir, "go.mod", "module example.com/app\n\ngo 1.24\n")
+	if err := os.MkdirAll(filepath.Join(dir, "common"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeModelFile(t, filepath.Join(dir, "common"), "types.go", `package common
+
+type Priority int16
+
+type Tags []string
+`)
+	if err := os.MkdirAll(filepath.Join(dir, "models"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeModelFile(t, filepath.Join(dir, "models"), "models.go", `package models
+
+import (
+	"time"
+
+	"example.com/app/common"
+	"example.com/app/missing"
+)
+
+type Email string
+
+type Task struct {
+	ID        string          `+"`"+`db:"id" storm:"type:uuid;primary_key"`+"`"+`
+	Email     *Email          `+"`"+`db:"email"`+"`"+`
+	Priority  common.Priority `+"`"+`db:"priority"`+"`"+`
+	Tags      common.Tags     `+"`"+`db:"tags"`+"`"+`
+	Owner     missing.ID      `+"`"+`db:"owner"`+"`"+`
+	CreatedAt time.Time       `+"`"+`db:"created_at"`+"`"+`
+}
+`)
+
+	tables, err := NewStructParser().ParseDirectory(filepath.Join(dir, "models"))
+	if err != nil {
+		t.Fatalf("ParseDirectory failed: %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(tables))
+	}
+
+	for name, expected := range map[string]string{"Email": "string", "Priority": "int16", "Tags": "[]string", "CreatedAt": ""} {
+		field := findField(tables[0].Fields, name)
+		if field == nil || field.ResolvedType != expected || field.ResolveError != "" {
+			t.Errorf("expected %s to resolve to %q, got %+v", name, expected, field)
+		}
+	}
+
+	owner := findField(tables[0].Fields, "Owner")
+	if owner == nil || owner.ResolvedType != "" || !strings.Contains(owner.ResolveError, "missing") {
+		t.Errorf("expected a resolution error for Owner, got %+v", owner)
+	}
+}
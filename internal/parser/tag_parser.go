@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -76,7 +77,8 @@ func (p *TagParser) ValidateDBDefTag(tagValue string) error {
 			if err := p.validatePrev(value); err != nil {
 				return fmt.Errorf("invalid prev hint '%s': %w", value, err)
 			}
-		case "primary_key", "not_null", "unique", "auto_increment":
+		case "primary_key", "not_null", "unique", "auto_increment", "immutable", "json_ignore", "masked", "sensitive",
+			"auto_create_time", "auto_update_time":
 			if value != "" {
 				return fmt.Errorf("flag attribute '%s' should not have a value", key)
 			}
@@ -88,12 +90,29 @@ func (p *TagParser) ValidateDBDefTag(tagValue string) error {
 			if err := p.validateEnum(value); err != nil {
 				return fmt.Errorf("invalid enum '%s': %w", value, err)
 			}
+		case "enum_mode":
+			if value != "native" && value != "check" {
+				return fmt.Errorf("invalid enum mode '%s': expected native or check", value)
+			}
 		case "array", "array_type":
 			if err := p.validateArrayType(value); err != nil {
 				return fmt.Errorf("invalid array type '%s': %w", value, err)
 			}
+		case "encrypted":
+			if value != "" && value != "randomized" && value != "deterministic" {
+				return fmt.Errorf("invalid encryption mode '%s': expected randomized or deterministic", value)
+			}
+		case "ci_index":
+			if value != "" && value != "lower" && value != "unaccent" {
+				return fmt.Errorf("invalid ci_index mode '%s': expected lower or unaccent", value)
+			}
+		case "anonymize":
+			if !isValidAnonymizeRule(value) {
+				return fmt.Errorf("invalid anonymize rule '%s': expected null, email, hash, keep or fixed:<value>", value)
+			}
+		case "computed", "constraint":
 		default:
-			fmt.Printf("Warning: unknown dbdef attribute '%s'\n", key)
+			return fmt.Errorf("unknown dbdef attribute '%s'", key)
 		}
 	}
 
@@ -145,44 +164,32 @@ func (p *TagParser) validateType(typeValue string) error {
 	return nil
 }
 
+// validateDefault only rejects an empty default. Defaults are checked against
+// the column type when the schema is generated, where the type is known.
 func (p *TagParser) validateDefault(defaultValue string) error {
 	if defaultValue == "" {
 		return fmt.Errorf("default value cannot be empty")
 	}
-
-	commonDefaults := []string{
-		"now()", "current_timestamp", "current_date", "current_time",
-		"gen_random_uuid()", "uuid_generate_v4()",
-		"true", "false", "null",
-	}
-
-	lowerDefault := strings.ToLower(defaultValue)
-
-	for _, common := range commonDefaults {
-		if lowerDefault == common {
-			return nil
-		}
-	}
-
-	if (strings.HasPrefix(defaultValue, "'") && strings.HasSuffix(defaultValue, "'")) ||
-		(strings.HasPrefix(defaultValue, "\"") && strings.HasSuffix(defaultValue, "\"")) {
-		return nil
-	}
-
-	if strings.ContainsAny(defaultValue, "0123456789") &&
-		!strings.ContainsAny(defaultValue, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
-		return nil
-	}
-
-	fmt.Printf("Warning: complex default expression '%s' - please verify manually\n", defaultValue)
 	return nil
 }
 
+// foreignKeyReference matches the parenthesized foreign key forms:
+// orders(id), orders(tenant_id,order_id) and
+// fk_name,tenant_id,order_id REFERENCES orders(tenant_id,id) ON DELETE CASCADE
+var foreignKeyReference = regexp.MustCompile(`(?i)^\s*(?:[\w.]+(?:\s*,\s*\w+)*\s+REFERENCES\s+)?[\w.]+\s*\(\s*\w+(?:\s*,\s*\w+)*\s*\)(?:\s+ON\s+(?:DELETE|UPDATE)\s+(?:CASCADE|RESTRICT|NO\s+ACTION|SET\s+NULL|SET\s+DEFAULT))*\s*$`)
+
 func (p *TagParser) validateForeignKey(fkValue string) error {
 	if fkValue == "" {
 		return fmt.Errorf("foreign key reference cannot be empty")
 	}
 
+	if strings.Contains(fkValue, "(") {
+		if !foreignKeyReference.MatchString(fkValue) {
+			return fmt.Errorf("foreign key must be in format 'table(column, ...)' or 'name,column, ... REFERENCES table(column, ...)', got: %s", fkValue)
+		}
+		return nil
+	}
+
 	parts := strings.Split(fkValue, ".")
 	if len(parts) != 2 {
 		return fmt.Errorf("foreign key must be in format 'table.column', got: %s", fkValue)
@@ -433,6 +440,15 @@ func (p *TagParser) GetEnum(attributes map[string]string) []string {
 	return nil
 }
 
+// GetEnumMode returns how an enum column is stored: "native" for a
+// PostgreSQL enum type, or "check" for a text column with a CHECK constraint
+func (p *TagParser) GetEnumMode(attributes map[string]string) string {
+	if mode, exists := attributes["enum_mode"]; exists && mode != "" {
+		return mode
+	}
+	return "native"
+}
+
 func (p *TagParser) GetPrevName(attributes map[string]string) string {
 	if prevVal, exists := attributes["prev"]; exists {
 		return prevVal
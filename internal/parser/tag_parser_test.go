@@ -2,6 +2,7 @@ package parser
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -165,6 +166,46 @@ func TestTagParser_HasFlag(t *testing.T) {
 	}
 }
 
+func TestTagParser_ValidateDBDefTag(t *testing.T) {
+	parser := NewTagParser()
+
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr string
+	}{
+		{
+			name: "valid tag",
+			tag:  "type:uuid;primary_key;not_null;immutable",
+		},
+		{
+			name:    "unknown attribute",
+			tag:     "type:uuid;primary_kye",
+			wantErr: "unknown dbdef attribute 'primary_kye'",
+		},
+		{
+			name:    "flag with value",
+			tag:     "unique:true",
+			wantErr: "flag attribute 'unique' should not have a value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parser.ValidateDBDefTag(tt.tag)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateDBDefTag() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateDBDefTag() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestTagParser_GetType(t *testing.T) {
 	parser := NewTagParser()
 
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// resolveFieldTypes type-checks the package in dir and records in
+// ResolvedType what the named field types of the models are defined as, so a
+// type UserID string or a shared enum from another package maps like string.
+// Imported packages are read from the export data `go list -export` builds.
+// Fields whose type cannot be resolved get a ResolveError instead.
+func (p *StructParser) resolveFieldTypes(dir string, files []*ast.File, tables []TableDefinition) {
+	stdNames := standardImportNames(files)
+
+	pending := false
+	for _, table := range tables {
+		for _, field := range table.Fields {
+			if needsTypeResolution(field, stdNames) {
+				pending = true
+			}
+		}
+	}
+	if !pending || len(files) == 0 {
+		return
+	}
+
+	exports := exportData(dir, importPaths(files))
+	lookup := func(importPath string) (io.ReadCloser, error) {
+		file, ok := exports[importPath]
+		if !ok || file == "" {
+			return nil, fmt.Errorf("no export data for %s", importPath)
+		}
+		return os.Open(file)
+	}
+
+	var typeErrors []string
+	config := types.Config{
+		Importer: importer.ForCompiler(p.fileSet, "gc", lookup),
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				typeErrors = append(typeErrors, typeErr.Msg)
+				return
+			}
+			typeErrors = append(typeErrors, err.Error())
+		},
+	}
+	pkg, _ := config.Check(files[0].Name.Name, p.fileSet, files, nil)
+	if pkg == nil {
+		return
+	}
+
+	for i := range tables {
+		obj, _ := pkg.Scope().Lookup(tables[i].StructName).(*types.TypeName)
+		for j := range tables[i].Fields {
+			field := &tables[i].Fields[j]
+			if !needsTypeResolution(*field, stdNames) {
+				continue
+			}
+
+			resolved, ok := "", false
+			if obj != nil {
+				resolved, ok = resolveFieldType(obj.Type(), pkg, *field)
+			}
+			if ok {
+				field.ResolvedType = resolved
+			} else {
+				field.ResolveError = resolveErrorFor(field.Type, typeErrors)
+			}
+		}
+	}
+}
+
+// needsTypeResolution reports whether a column's Go type is a named type
+// declared in the models package or in a package outside the standard library
+func needsTypeResolution(field FieldDefinition, stdNames map[string]bool) bool {
+	if field.IsRelationship || field.DBName == "-" || field.Type == "" || strings.Contains(field.Type, "[") {
+		return false
+	}
+	if _, hasType := field.DBDef["type"]; hasType {
+		return false
+	}
+	if _, encrypted := field.DBDef["encrypted"]; encrypted {
+		return false
+	}
+	if _, isEnum := field.DBDef["enum"]; isEnum {
+		return false
+	}
+
+	if pkgName, _, qualified := strings.Cut(field.Type, "."); qualified {
+		return !stdNames[pkgName]
+	}
+	_, predeclared := types.Universe.Lookup(field.Type).(*types.TypeName)
+	return !predeclared
+}
+
+// resolveFieldType returns the type a field of structType is defined as,
+// following pointers and, for array fields, the element type
+func resolveFieldType(structType types.Type, pkg *types.Package, field FieldDefinition) (string, bool) {
+	obj, _, _ := types.LookupFieldOrMethod(structType, true, pkg, field.Name)
+	v, ok := obj.(*types.Var)
+	if !ok {
+		return "", false
+	}
+
+	t := v.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if slice, ok := t.Underlying().(*types.Slice); ok && field.IsArray {
+		t = slice.Elem()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+	}
+
+	switch underlying := t.Underlying().(type) {
+	case *types.Basic:
+		if underlying.Kind() == types.Invalid {
+			return "", false
+		}
+		return underlying.Name(), true
+	case *types.Slice:
+		if elem, ok := underlying.Elem().Underlying().(*types.Basic); ok && elem.Kind() != types.Invalid {
+			if elem.Kind() == types.Byte {
+				return "[]byte", true
+			}
+			return "[]" + elem.Name(), true
+		}
+	}
+
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg().Name() + "." + named.Obj().Name(), true
+	}
+	return types.TypeString(t, (*types.Package).Name), true
+}
+
+// resolveErrorFor picks the type-checking error that explains why goType did
+// not resolve, preferring one naming its package or the type itself
+func resolveErrorFor(goType string, typeErrors []string) string {
+	name, _, _ := strings.Cut(goType, ".")
+	for _, msg := range typeErrors {
+		if strings.Contains(msg, name) {
+			return msg
+		}
+	}
+	if len(typeErrors) > 0 {
+		return typeErrors[0]
+	}
+	return fmt.Sprintf("type %s not found", goType)
+}
+
+// standardImportNames returns the names the files import standard library
+// packages under. Those types are mapped by name and need no resolution.
+func standardImportNames(files []*ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || strings.Contains(strings.Split(importPath, "/")[0], ".") {
+				continue
+			}
+			name := path.Base(importPath)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func importPaths(files []*ast.File) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || importPath == "C" || seen[importPath] {
+				continue
+			}
+			seen[importPath] = true
+			paths = append(paths, importPath)
+		}
+	}
+	return paths
+}
+
+// exportData maps the packages imported from dir, and their dependencies, to
+// their export data files. Packages that fail to build are left out.
+func exportData(dir string, imports []string) map[string]string {
+	exports := make(map[string]string)
+	if len(imports) == 0 {
+		return exports
+	}
+
+	args := append([]string{"list", "-e", "-export", "-deps", "-f", "{{.ImportPath}}={{.Export}}"}, imports...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		return exports
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if importPath, file, ok := strings.Cut(scanner.Text(), "="); ok {
+			exports[importPath] = file
+		}
+	}
+	return exports
+}
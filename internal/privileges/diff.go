@@ -0,0 +1,271 @@
+package privileges
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// State is the roles and grants found in a database
+type State struct {
+	// Roles maps role names to whether they can log in
+	Roles map[string]bool
+	// Memberships maps role names to the roles they are members of
+	Memberships map[string][]string
+	// Privileges are the schema and table privileges held by roles other
+	// than the owner of the object
+	Privileges []Privilege
+	// Tables maps schema names to the tables and views they contain
+	Tables map[string][]string
+}
+
+// Privilege is one privilege held by a role. Table is empty for USAGE on a
+// schema.
+type Privilege struct {
+	Role      string `json:"role"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table,omitempty"`
+	Privilege string `json:"privilege"`
+}
+
+func (p Privilege) String() string {
+	if p.Table == "" {
+		return fmt.Sprintf("%s ON SCHEMA %s TO %s", p.Privilege, p.Schema, p.Role)
+	}
+	return fmt.Sprintf("%s ON %s.%s TO %s", p.Privilege, p.Schema, p.Table, p.Role)
+}
+
+// Membership is a role being a member of another
+type Membership struct {
+	Role   string `json:"role"`
+	Parent string `json:"member_of"`
+}
+
+// Drift is the difference between the declared privileges and a database
+type Drift struct {
+	MissingRoles       []string     `json:"missing_roles,omitempty"`
+	LoginMismatches    []string     `json:"login_mismatches,omitempty"`
+	MissingMemberships []Membership `json:"missing_memberships,omitempty"`
+	MissingPrivileges  []Privilege  `json:"missing_privileges,omitempty"`
+	ExtraPrivileges    []Privilege  `json:"extra_privileges,omitempty"`
+}
+
+// IsEmpty reports whether the database matches the declarations
+func (d Drift) IsEmpty() bool {
+	return len(d.MissingRoles) == 0 && len(d.LoginMismatches) == 0 && len(d.MissingMemberships) == 0 &&
+		len(d.MissingPrivileges) == 0 && len(d.ExtraPrivileges) == 0
+}
+
+// Diff compares config against state. Only the roles config mentions are
+// compared, so privileges of other roles are never reported as extra.
+func Diff(config *Config, state *State) Drift {
+	var drift Drift
+
+	for _, role := range config.Roles {
+		login, exists := state.Roles[role.Name]
+		if !exists {
+			drift.MissingRoles = append(drift.MissingRoles, role.Name)
+			continue
+		}
+		if login != role.Login {
+			drift.LoginMismatches = append(drift.LoginMismatches,
+				fmt.Sprintf("%s should be %s", role.Name, loginOption(role.Login)))
+		}
+		for _, parent := range role.MemberOf {
+			if !containsString(state.Memberships[role.Name], parent) {
+				drift.MissingMemberships = append(drift.MissingMemberships, Membership{Role: role.Name, Parent: parent})
+			}
+		}
+	}
+
+	declared := declaredPrivileges(config, state)
+	managed := make(map[string]bool)
+	for _, role := range config.Roles {
+		managed[role.Name] = true
+	}
+	for _, grant := range config.Grants {
+		managed[grant.Role] = true
+	}
+
+	held := make(map[Privilege]bool)
+	for _, p := range state.Privileges {
+		held[p] = true
+		if managed[p.Role] && !declared[p] {
+			drift.ExtraPrivileges = append(drift.ExtraPrivileges, p)
+		}
+	}
+	for p := range declared {
+		if !held[p] {
+			drift.MissingPrivileges = append(drift.MissingPrivileges, p)
+		}
+	}
+
+	sortPrivileges(drift.MissingPrivileges)
+	sortPrivileges(drift.ExtraPrivileges)
+	return drift
+}
+
+// declaredPrivileges expands the grants into the privileges they give, with
+// "*" standing for every table state lists in the schema
+func declaredPrivileges(config *Config, state *State) map[Privilege]bool {
+	declared := make(map[Privilege]bool)
+	for _, grant := range config.Grants {
+		schema := grant.SchemaName()
+		declared[Privilege{Role: grant.Role, Schema: schema, Privilege: "USAGE"}] = true
+
+		var tables []string
+		for _, table := range grant.Tables {
+			if table == AllTables {
+				tables = append(tables, state.Tables[schema]...)
+				continue
+			}
+			tables = append(tables, table)
+		}
+
+		for _, table := range tables {
+			for _, privilege := range grant.expandedPrivileges() {
+				declared[Privilege{Role: grant.Role, Schema: schema, Table: table, Privilege: privilege}] = true
+			}
+		}
+	}
+	return declared
+}
+
+// Lines describes the drift, one difference per line
+func (d Drift) Lines() []string {
+	var lines []string
+	for _, role := range d.MissingRoles {
+		lines = append(lines, "missing role "+role)
+	}
+	for _, mismatch := range d.LoginMismatches {
+		lines = append(lines, "role "+mismatch)
+	}
+	for _, m := range d.MissingMemberships {
+		lines = append(lines, fmt.Sprintf("missing membership: %s is not a member of %s", m.Role, m.Parent))
+	}
+	for _, p := range d.MissingPrivileges {
+		lines = append(lines, "missing grant "+p.String())
+	}
+	for _, p := range d.ExtraPrivileges {
+		lines = append(lines, "unexpected grant "+p.String())
+	}
+	return lines
+}
+
+// Inspect reads the roles, memberships and privileges of db in the schemas
+// config grants on
+func Inspect(ctx context.Context, db *sql.DB, config *Config) (*State, error) {
+	state := &State{
+		Roles:       make(map[string]bool),
+		Memberships: make(map[string][]string),
+		Tables:      make(map[string][]string),
+	}
+	schemas := make(map[string]bool)
+	for _, schema := range config.schemas() {
+		schemas[schema] = true
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT rolname, rolcanlogin FROM pg_roles`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		var login bool
+		if err := rows.Scan(&name, &login); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		state.Roles[name] = login
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT member.rolname, parent.rolname
+		FROM pg_auth_members m
+		JOIN pg_roles member ON member.oid = m.member
+		JOIN pg_roles parent ON parent.oid = m.roleid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query role memberships: %w", err)
+	}
+	for rows.Next() {
+		var role, parent string
+		if err := rows.Scan(&role, &parent); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan role membership: %w", err)
+		}
+		state.Memberships[role] = append(state.Memberships[role], parent)
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		ORDER BY n.nspname, c.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		if schemas[schema] {
+			state.Tables[schema] = append(state.Tables[schema], table)
+		}
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT r.rolname, n.nspname, '', a.privilege_type
+		FROM pg_namespace n
+		CROSS JOIN LATERAL aclexplode(n.nspacl) a
+		JOIN pg_roles r ON r.oid = a.grantee
+		WHERE a.grantee <> n.nspowner AND a.privilege_type = 'USAGE'
+		UNION ALL
+		SELECT r.rolname, n.nspname, c.relname, a.privilege_type
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL aclexplode(c.relacl) a
+		JOIN pg_roles r ON r.oid = a.grantee
+		WHERE a.grantee <> c.relowner AND c.relkind IN ('r', 'p', 'v', 'm', 'f')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query privileges: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Privilege
+		if err := rows.Scan(&p.Role, &p.Schema, &p.Table, &p.Privilege); err != nil {
+			return nil, fmt.Errorf("failed to scan privilege: %w", err)
+		}
+		if schemas[p.Schema] {
+			state.Privileges = append(state.Privileges, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read privileges: %w", err)
+	}
+	return state, nil
+}
+
+func sortPrivileges(privileges []Privilege) {
+	sort.Slice(privileges, func(i, j int) bool {
+		a, b := privileges[i], privileges[j]
+		return strings.Join([]string{a.Role, a.Schema, a.Table, a.Privilege}, "\x00") <
+			strings.Join([]string{b.Role, b.Schema, b.Table, b.Privilege}, "\x00")
+	})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+// Package privileges declares database roles and table grants and turns them
+// into idempotent migrations, or compares them against a live database.
+package privileges
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/dialect"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the privileges section of storm.yaml, or a privileges.yaml file
+type Config struct {
+	Roles  []Role  `yaml:"roles,omitempty"`
+	Grants []Grant `yaml:"grants,omitempty"`
+}
+
+// Role is a role to create. Passwords are not declared here; set them on
+// login roles outside of migrations.
+type Role struct {
+	Name     string   `yaml:"name"`
+	Login    bool     `yaml:"login,omitempty"`
+	MemberOf []string `yaml:"member_of,omitempty"`
+}
+
+// Grant gives a role privileges on tables of a schema. A table of "*" grants
+// on every table in the schema, including tables created later.
+type Grant struct {
+	Role       string   `yaml:"role"`
+	Privileges []string `yaml:"privileges"`
+	Schema     string   `yaml:"schema,omitempty"`
+	Tables     []string `yaml:"tables"`
+}
+
+// AllTables is the table name that grants on every table in a schema
+const AllTables = "*"
+
+// tablePrivileges are the privileges PostgreSQL grants on tables, in the
+// order ALL expands to
+var tablePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// Load reads a privileges.yaml file, rejecting unknown keys
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read privileges file: %w", err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse privileges file %s: %w", path, err)
+	}
+	return &config, config.Validate()
+}
+
+// IsEmpty reports whether nothing is declared
+func (c *Config) IsEmpty() bool {
+	return c == nil || (len(c.Roles) == 0 && len(c.Grants) == 0)
+}
+
+// Validate checks names and privileges and reports every problem found
+func (c *Config) Validate() error {
+	var problems []string
+	seen := make(map[string]bool)
+	for i, role := range c.Roles {
+		if !namePattern.MatchString(role.Name) {
+			problems = append(problems, fmt.Sprintf("roles[%d]: %q is not a valid role name", i, role.Name))
+			continue
+		}
+		if seen[role.Name] {
+			problems = append(problems, fmt.Sprintf("roles[%d]: role %s is declared twice", i, role.Name))
+		}
+		seen[role.Name] = true
+		for _, parent := range role.MemberOf {
+			if !namePattern.MatchString(parent) {
+				problems = append(problems, fmt.Sprintf("roles[%d]: %q is not a valid role name", i, parent))
+			} else if parent == role.Name {
+				problems = append(problems, fmt.Sprintf("roles[%d]: role %s cannot be a member of itself", i, role.Name))
+			}
+		}
+	}
+
+	for i, grant := range c.Grants {
+		if !namePattern.MatchString(grant.Role) {
+			problems = append(problems, fmt.Sprintf("grants[%d]: %q is not a valid role name", i, grant.Role))
+		}
+		if grant.Schema != "" && !namePattern.MatchString(grant.Schema) {
+			problems = append(problems, fmt.Sprintf("grants[%d]: %q is not a valid schema name", i, grant.Schema))
+		}
+		if len(grant.Privileges) == 0 {
+			problems = append(problems, fmt.Sprintf("grants[%d]: privileges are required", i))
+		}
+		for _, privilege := range grant.Privileges {
+			if _, err := normalizePrivilege(privilege); err != nil {
+				problems = append(problems, fmt.Sprintf("grants[%d]: %v", i, err))
+			}
+		}
+		if len(grant.Tables) == 0 {
+			problems = append(problems, fmt.Sprintf("grants[%d]: tables are required (use \"*\" for every table)", i))
+		}
+		for _, table := range grant.Tables {
+			if table != AllTables && !namePattern.MatchString(table) {
+				problems = append(problems, fmt.Sprintf("grants[%d]: %q is not a valid table name", i, table))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid privileges:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// SchemaName returns the schema of the grant, public when not set
+func (g Grant) SchemaName() string {
+	if g.Schema == "" {
+		return "public"
+	}
+	return g.Schema
+}
+
+// expandedPrivileges returns the grant's privileges in canonical order, with
+// ALL expanded
+func (g Grant) expandedPrivileges() []string {
+	set := make(map[string]bool)
+	for _, privilege := range g.Privileges {
+		name, err := normalizePrivilege(privilege)
+		if err != nil {
+			continue
+		}
+		if name == "ALL" {
+			for _, p := range tablePrivileges {
+				set[p] = true
+			}
+			continue
+		}
+		set[name] = true
+	}
+
+	var expanded []string
+	for _, p := range tablePrivileges {
+		if set[p] {
+			expanded = append(expanded, p)
+		}
+	}
+	return expanded
+}
+
+func normalizePrivilege(privilege string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(privilege))
+	if name == "ALL PRIVILEGES" {
+		name = "ALL"
+	}
+	if name == "ALL" {
+		return name, nil
+	}
+	for _, p := range tablePrivileges {
+		if p == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported privilege %q (expected one of: ALL, %s)", privilege, strings.Join(tablePrivileges, ", "))
+}
+
+// UpSQL returns statements creating the roles and granting the privileges.
+// Every statement can be run again without error: roles are only created when
+// missing, and PostgreSQL ignores grants that are already held.
+func (c *Config) UpSQL() string {
+	var b strings.Builder
+	for _, role := range c.Roles {
+		name := dialect.QuoteIdentifierIfNeeded(role.Name)
+		fmt.Fprintf(&b, "DO $$\nBEGIN\n    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = %s) THEN\n        CREATE ROLE %s %s;\n    END IF;\nEND\n$$;\n",
+			quoteLiteral(role.Name), name, loginOption(role.Login))
+		fmt.Fprintf(&b, "ALTER ROLE %s %s;\n", name, loginOption(role.Login))
+		for _, parent := range role.MemberOf {
+			fmt.Fprintf(&b, "GRANT %s TO %s;\n", dialect.QuoteIdentifierIfNeeded(parent), name)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, grant := range c.Grants {
+		role := dialect.QuoteIdentifierIfNeeded(grant.Role)
+		schema := dialect.QuoteIdentifierIfNeeded(grant.SchemaName())
+		privileges := strings.Join(grant.expandedPrivileges(), ", ")
+
+		fmt.Fprintf(&b, "GRANT USAGE ON SCHEMA %s TO %s;\n", schema, role)
+		for _, table := range grant.Tables {
+			if table == AllTables {
+				fmt.Fprintf(&b, "GRANT %s ON ALL TABLES IN SCHEMA %s TO %s;\n", privileges, schema, role)
+				fmt.Fprintf(&b, "ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT %s ON TABLES TO %s;\n", schema, privileges, role)
+				continue
+			}
+			fmt.Fprintf(&b, "GRANT %s ON TABLE %s.%s TO %s;\n", privileges, schema, dialect.QuoteIdentifierIfNeeded(table), role)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// DownSQL returns statements revoking the grants and dropping the roles, in
+// the reverse order UpSQL created them
+func (c *Config) DownSQL() string {
+	var b strings.Builder
+	for i := len(c.Grants) - 1; i >= 0; i-- {
+		grant := c.Grants[i]
+		role := dialect.QuoteIdentifierIfNeeded(grant.Role)
+		schema := dialect.QuoteIdentifierIfNeeded(grant.SchemaName())
+		privileges := strings.Join(grant.expandedPrivileges(), ", ")
+
+		for _, table := range grant.Tables {
+			if table == AllTables {
+				fmt.Fprintf(&b, "ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE %s ON TABLES FROM %s;\n", schema, privileges, role)
+				fmt.Fprintf(&b, "REVOKE %s ON ALL TABLES IN SCHEMA %s FROM %s;\n", privileges, schema, role)
+				continue
+			}
+			fmt.Fprintf(&b, "REVOKE %s ON TABLE %s.%s FROM %s;\n", privileges, schema, dialect.QuoteIdentifierIfNeeded(table), role)
+		}
+		fmt.Fprintf(&b, "REVOKE USAGE ON SCHEMA %s FROM %s;\n\n", schema, role)
+	}
+
+	for i := len(c.Roles) - 1; i >= 0; i-- {
+		name := dialect.QuoteIdentifierIfNeeded(c.Roles[i].Name)
+		for _, parent := range c.Roles[i].MemberOf {
+			fmt.Fprintf(&b, "REVOKE %s FROM %s;\n", dialect.QuoteIdentifierIfNeeded(parent), name)
+		}
+		fmt.Fprintf(&b, "DROP ROLE IF EXISTS %s;\n\n", name)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// schemas returns the schemas the grants refer to, sorted
+func (c *Config) schemas() []string {
+	seen := make(map[string]bool)
+	var schemas []string
+	for _, grant := range c.Grants {
+		if !seen[grant.SchemaName()] {
+			seen[grant.SchemaName()] = true
+			schemas = append(schemas, grant.SchemaName())
+		}
+	}
+	sort.Strings(schemas)
+	return schemas
+}
+
+func loginOption(login bool) string {
+	if login {
+		return "LOGIN"
+	}
+	return "NOLOGIN"
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,199 @@
+package privileges
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testConfig() *Config {
+	return &Config{
+		Roles: []Role{
+			{Name: "app_readonly"},
+			{Name: "reporting", Login: true, MemberOf: []string{"app_readonly"}},
+		},
+		Grants: []Grant{
+			{Role: "app_readonly", Privileges: []string{"select"}, Tables: []string{"*"}},
+			{Role: "reporting", Privileges: []string{"INSERT", "UPDATE"}, Schema: "audit", Tables: []string{"events"}},
+		},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("accepts valid declarations", func(t *testing.T) {
+		if err := testConfig().Validate(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		config := &Config{
+			Roles: []Role{
+				{Name: "reader"},
+				{Name: "reader", MemberOf: []string{"reader"}},
+				{Name: "bad name"},
+			},
+			Grants: []Grant{
+				{Role: "reader", Privileges: []string{"EXECUTE"}, Tables: []string{"users; DROP"}},
+				{Role: "reader"},
+			},
+		}
+		err := config.Validate()
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		for _, want := range []string{
+			"roles[1]: role reader is declared twice",
+			"roles[1]: role reader cannot be a member of itself",
+			`roles[2]: "bad name" is not a valid role name`,
+			`grants[0]: unsupported privilege "EXECUTE"`,
+			`grants[0]: "users; DROP" is not a valid table name`,
+			"grants[1]: privileges are required",
+			"grants[1]: tables are required",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got %v", want, err)
+			}
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("reads a privileges file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "privileges.yaml")
+		content := `roles:
+  - name: reader
+grants:
+  - role: reader
+    privileges: [ALL]
+    tables: [users]
+`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := Load(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(config.Roles) != 1 || len(config.Grants) != 1 || config.Grants[0].SchemaName() != "public" {
+			t.Errorf("unexpected config %+v", config)
+		}
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "privileges.yaml")
+		if err := os.WriteFile(path, []byte("roles:\n  - name: reader\n    password: secret\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "password") {
+			t.Errorf("expected unknown key error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_UpSQL(t *testing.T) {
+	sql := testConfig().UpSQL()
+
+	for _, want := range []string{
+		"IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'app_readonly') THEN\n        CREATE ROLE app_readonly NOLOGIN;",
+		"CREATE ROLE reporting LOGIN;",
+		"ALTER ROLE reporting LOGIN;",
+		"GRANT app_readonly TO reporting;",
+		"GRANT USAGE ON SCHEMA public TO app_readonly;",
+		"GRANT SELECT ON ALL TABLES IN SCHEMA public TO app_readonly;",
+		"ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT ON TABLES TO app_readonly;",
+		"GRANT USAGE ON SCHEMA audit TO reporting;",
+		"GRANT INSERT, UPDATE ON TABLE audit.events TO reporting;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected SQL to contain %q, got:\n%s", want, sql)
+		}
+	}
+	if strings.Index(sql, "CREATE ROLE app_readonly") > strings.Index(sql, "GRANT app_readonly TO reporting") {
+		t.Error("expected roles to be created before memberships are granted")
+	}
+}
+
+func TestConfig_DownSQL(t *testing.T) {
+	sql := testConfig().DownSQL()
+
+	expected := []string{
+		"REVOKE INSERT, UPDATE ON TABLE audit.events FROM reporting;",
+		"ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT ON TABLES FROM app_readonly;",
+		"REVOKE SELECT ON ALL TABLES IN SCHEMA public FROM app_readonly;",
+		"REVOKE app_readonly FROM reporting;",
+		"DROP ROLE IF EXISTS reporting;",
+		"DROP ROLE IF EXISTS app_readonly;",
+	}
+	last := -1
+	for _, want := range expected {
+		index := strings.Index(sql, want)
+		if index < 0 {
+			t.Errorf("expected SQL to contain %q, got:\n%s", want, sql)
+			continue
+		}
+		if index < last {
+			t.Errorf("expected %q to come later, got:\n%s", want, sql)
+		}
+		last = index
+	}
+}
+
+func TestDiff(t *testing.T) {
+	state := func() *State {
+		return &State{
+			Roles:       map[string]bool{"app_readonly": false, "reporting": true, "postgres": true},
+			Memberships: map[string][]string{"reporting": {"app_readonly"}},
+			Tables:      map[string][]string{"public": {"posts", "users"}, "audit": {"events"}},
+			Privileges: []Privilege{
+				{Role: "app_readonly", Schema: "public", Privilege: "USAGE"},
+				{Role: "app_readonly", Schema: "public", Table: "posts", Privilege: "SELECT"},
+				{Role: "app_readonly", Schema: "public", Table: "users", Privilege: "SELECT"},
+				{Role: "reporting", Schema: "audit", Privilege: "USAGE"},
+				{Role: "reporting", Schema: "audit", Table: "events", Privilege: "INSERT"},
+				{Role: "reporting", Schema: "audit", Table: "events", Privilege: "UPDATE"},
+				{Role: "analyst", Schema: "public", Table: "users", Privilege: "DELETE"},
+			},
+		}
+	}
+
+	t.Run("reports no drift when the database matches", func(t *testing.T) {
+		drift := Diff(testConfig(), state())
+		if !drift.IsEmpty() {
+			t.Errorf("expected no drift, got %v", drift.Lines())
+		}
+	})
+
+	t.Run("reports missing and extra grants", func(t *testing.T) {
+		s := state()
+		delete(s.Roles, "app_readonly")
+		s.Roles["reporting"] = false
+		s.Privileges = append(s.Privileges[:2], s.Privileges[3:]...)
+		s.Privileges = append(s.Privileges, Privilege{Role: "reporting", Schema: "audit", Table: "events", Privilege: "DELETE"})
+
+		drift := Diff(testConfig(), s)
+		expected := []string{
+			"missing role app_readonly",
+			"role reporting should be LOGIN",
+			"missing grant SELECT ON public.users TO app_readonly",
+			"unexpected grant DELETE ON audit.events TO reporting",
+		}
+		if !reflect.DeepEqual(drift.Lines(), expected) {
+			t.Errorf("expected %v, got %v", expected, drift.Lines())
+		}
+	})
+
+	t.Run("reports missing memberships of existing roles", func(t *testing.T) {
+		s := state()
+		s.Memberships = map[string][]string{}
+
+		drift := Diff(testConfig(), s)
+		expected := []Membership{{Role: "reporting", Parent: "app_readonly"}}
+		if !reflect.DeepEqual(drift.MissingMemberships, expected) {
+			t.Errorf("expected %v, got %v", expected, drift.MissingMemberships)
+		}
+	})
+}
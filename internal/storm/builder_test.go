@@ -117,3 +117,13 @@ func (l *TestLogger) Debug(msg string, fields ...interface{}) {}
 func (l *TestLogger) Info(msg string, fields ...interface{})  {}
 func (l *TestLogger) Warn(msg string, fields ...interface{})  {}
 func (l *TestLogger) Error(msg string, fields ...interface{}) {}
+
+// recordingLogger keeps the warnings logged, for tests that expect one
+type recordingLogger struct {
+	TestLogger
+	warnings []string
+}
+
+func (l *recordingLogger) Warn(msg string, fields ...interface{}) {
+	l.warnings = append(l.warnings, msg)
+}
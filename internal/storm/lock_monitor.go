@@ -0,0 +1,107 @@
+package storm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/jmoiron/sqlx"
+)
+
+// lockPollInterval caps how often the lock monitor polls pg_stat_activity
+var lockPollInterval = time.Second
+
+// blockedSessionsQuery lists the sessions waiting on locks held by the
+// backend $1, with how long they have waited and the blocking statement
+const blockedSessionsQuery = `
+	SELECT blocked.pid, blocked.query,
+		EXTRACT(EPOCH FROM clock_timestamp() - blocked.query_start) AS waiting,
+		COALESCE(blocker.query, '') AS blocking_query
+	FROM pg_stat_activity blocked
+	LEFT JOIN pg_stat_activity blocker ON blocker.pid = $1
+	WHERE $1 = ANY(pg_blocking_pids(blocked.pid))
+`
+
+type blockedSession struct {
+	PID           int     `db:"pid"`
+	Query         string  `db:"query"`
+	Waiting       float64 `db:"waiting"`
+	BlockingQuery string  `db:"blocking_query"`
+}
+
+// monitorLocks watches the sessions blocked by the connection conn while a
+// migration runs, when a lock threshold is configured. Statements of the
+// migration run with the returned context, which is cancelled when the
+// abort policy applies; stop ends the watch and returns the abort error.
+func (m *MigratorImpl) monitorLocks(ctx context.Context, name string, conn sqlx.QueryerContext) (context.Context, func() error, error) {
+	threshold := m.config.MigrationLockThreshold
+	if threshold <= 0 || m.config.IsCockroachDB() {
+		return ctx, func() error { return nil }, nil
+	}
+
+	var pid int
+	if err := sqlx.GetContext(ctx, conn, &pid, "SELECT pg_backend_pid()"); err != nil {
+		return nil, nil, fmt.Errorf("failed to get migration backend: %w", err)
+	}
+
+	interval := threshold / 2
+	if interval > lockPollInterval {
+		interval = lockPollInterval
+	}
+
+	monitorCtx, cancel := context.WithCancel(ctx)
+	var (
+		wg       sync.WaitGroup
+		abortErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		warned := make(map[int]bool)
+		for {
+			select {
+			case <-monitorCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var sessions []blockedSession
+			if err := m.db.SelectContext(monitorCtx, &sessions, blockedSessionsQuery, pid); err != nil {
+				if monitorCtx.Err() == nil {
+					m.logger.Warn("Failed to check migration lock contention", "name", name, "error", err)
+				}
+				continue
+			}
+
+			for _, session := range sessions {
+				waiting := time.Duration(session.Waiting * float64(time.Second))
+				if waiting < threshold || warned[session.PID] {
+					continue
+				}
+				warned[session.PID] = true
+				m.logger.Warn("Migration is blocking another session",
+					"name", name, "blocked_pid", session.PID, "waiting", waiting.Round(time.Millisecond),
+					"blocked_query", session.Query, "blocking_query", session.BlockingQuery)
+
+				if m.config.MigrationLockPolicy == storm.MigrationLockAbort {
+					abortErr = fmt.Errorf("migration %s aborted: session %d waited %s for its locks (query: %s)",
+						name, session.PID, waiting.Round(time.Millisecond), session.Query)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	stop := func() error {
+		cancel()
+		wg.Wait()
+		return abortErr
+	}
+	return monitorCtx, stop, nil
+}
@@ -0,0 +1,174 @@
+package storm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/pkg/storm"
+)
+
+// noTransactionDirective on a line of an up migration applies it statement
+// by statement outside a transaction, for statements such as CREATE INDEX
+// CONCURRENTLY that cannot run inside one
+const noTransactionDirective = "-- storm:no-transaction"
+
+func isNonTransactional(migration *storm.Migration) bool {
+	for _, line := range strings.Split(migration.UpSQL, "\n") {
+		if strings.TrimSpace(line) == noTransactionDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// applyWithJournal applies a non-transactional migration, recording each
+// statement that succeeds in the journal table. A run interrupted by a
+// failing statement resumes after the last recorded one; the migration is
+// recorded as applied and its journal entry removed once all have run.
+func (m *MigratorImpl) applyWithJournal(ctx context.Context, migration *storm.Migration) error {
+	if err := m.createJournalTable(ctx); err != nil {
+		return fmt.Errorf("failed to create migration journal table: %w", err)
+	}
+
+	done, err := m.journalPosition(ctx, migration)
+	if err != nil {
+		return err
+	}
+
+	statements, err := m.migrationStatements(migration)
+	if err != nil {
+		return err
+	}
+	if done > len(statements) {
+		return fmt.Errorf("migration %s journal records %d statements but the migration has %d", migration.Name, done, len(statements))
+	}
+
+	resumed := done > 0
+	if resumed {
+		m.logger.Info("Resuming migration", "name", migration.Name, "statement", done+1, "total", len(statements))
+	}
+
+	started := time.Now()
+	if err := m.executeWithJournal(ctx, migration, statements, done, started); err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	var rollback = func() { tx.Rollback() }
+	defer func() {
+		if rollback != nil {
+			rollback()
+		}
+	}()
+
+	if err := m.recordMigration(ctx, tx, migration, time.Since(started)); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s_journal WHERE name = $1`, m.config.MigrationsTable)
+	if _, err := tx.ExecContext(ctx, query, migration.Name); err != nil {
+		return fmt.Errorf("failed to clear migration journal: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	rollback = nil
+
+	m.logger.Info("Migration applied successfully", "name", migration.Name)
+	return nil
+}
+
+// executeWithJournal runs the statements after the first done, one at a time
+// on a connection of their own, recording each in the journal
+func (m *MigratorImpl) executeWithJournal(ctx context.Context, migration *storm.Migration, statements []string, done int, started time.Time) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.useTenantSchema(ctx, conn, false); err != nil {
+		return err
+	}
+	if m.schema != "" {
+		defer conn.ExecContext(context.Background(), "RESET search_path")
+	}
+
+	execCtx, stopMonitor, err := m.monitorLocks(ctx, migration.Name, conn)
+	if err != nil {
+		return err
+	}
+	defer stopMonitor()
+
+	for i := done; i < len(statements); i++ {
+		if _, err := conn.ExecContext(execCtx, statements[i]); err != nil {
+			if abortErr := stopMonitor(); abortErr != nil {
+				return abortErr
+			}
+			return fmt.Errorf("failed to execute statement %d of %d: %s: %w", i+1, len(statements), statements[i], err)
+		}
+		if err := m.recordJournal(ctx, migration, i+1); err != nil {
+			return fmt.Errorf("failed to record statement %d of migration %s in the journal: %w", i+1, migration.Name, err)
+		}
+		m.reportProgress(migration.Name, i+1, len(statements), started, done > 0)
+	}
+	return nil
+}
+
+func (m *MigratorImpl) createJournalTable(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s_journal (
+			name VARCHAR(255) PRIMARY KEY,
+			checksum VARCHAR(64) NOT NULL,
+			statements INTEGER NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`, m.config.MigrationsTable)
+
+	_, err := m.db.ExecContext(ctx, query)
+	return err
+}
+
+// journalPosition returns how many statements of the migration an earlier
+// run applied. Resuming is refused when the migration changed since.
+func (m *MigratorImpl) journalPosition(ctx context.Context, migration *storm.Migration) (int, error) {
+	query := fmt.Sprintf(`
+		SELECT statements, checksum FROM %s_journal WHERE name = $1
+	`, m.config.MigrationsTable)
+
+	var entry struct {
+		Statements int    `db:"statements"`
+		Checksum   string `db:"checksum"`
+	}
+	err := m.db.GetContext(ctx, &entry, query, migration.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration journal: %w", err)
+	}
+
+	if entry.Checksum != migration.Checksum {
+		return 0, fmt.Errorf("migration %s changed after %d of its statements were applied; restore it or remove its row from %s_journal", migration.Name, entry.Statements, m.config.MigrationsTable)
+	}
+	return entry.Statements, nil
+}
+
+func (m *MigratorImpl) recordJournal(ctx context.Context, migration *storm.Migration, statements int) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s_journal (name, checksum, statements, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE SET statements = EXCLUDED.statements, updated_at = EXCLUDED.updated_at
+	`, m.config.MigrationsTable)
+
+	_, err := m.db.ExecContext(ctx, query, migration.Name, migration.Checksum, statements)
+	return err
+}
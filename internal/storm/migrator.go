@@ -2,9 +2,13 @@ package storm
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -20,6 +24,7 @@ type MigratorImpl struct {
 	db     *sqlx.DB
 	config *storm.Config
 	logger storm.Logger
+	schema string // Tenant schema, set by forSchema
 }
 
 func NewMigrator(db *sqlx.DB, config *storm.Config, logger storm.Logger) *MigratorImpl {
@@ -55,7 +60,7 @@ func (m *MigratorImpl) Generate(ctx context.Context, opts storm.MigrateOptions)
 		return nil, fmt.Errorf("failed to get desired schema: %w", err)
 	}
 
-	migration, err := m.generateMigration(currentSchema, desiredSchema, opts)
+	migration, err := m.generateMigration(ctx, currentSchema, desiredSchema, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate migration: %w", err)
 	}
@@ -80,6 +85,10 @@ func (m *MigratorImpl) Apply(ctx context.Context, migration *storm.Migration) er
 		return nil
 	}
 
+	if isNonTransactional(migration) {
+		return m.applyWithJournal(ctx, migration)
+	}
+
 	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -91,11 +100,25 @@ func (m *MigratorImpl) Apply(ctx context.Context, migration *storm.Migration) er
 		}
 	}()
 
-	if err := m.executeMigration(ctx, tx, migration); err != nil {
+	if err := m.useTenantSchema(ctx, tx, true); err != nil {
+		return err
+	}
+
+	execCtx, stopMonitor, err := m.monitorLocks(ctx, migration.Name, tx)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	err = m.executeMigration(execCtx, tx, migration)
+	if abortErr := stopMonitor(); abortErr != nil {
+		return abortErr
+	}
+	if err != nil {
 		return fmt.Errorf("failed to execute migration: %w", err)
 	}
 
-	if err := m.recordMigration(ctx, tx, migration); err != nil {
+	if err := m.recordMigration(ctx, tx, migration, time.Since(started)); err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
 
@@ -154,22 +177,74 @@ func (m *MigratorImpl) Status(ctx context.Context) (*storm.MigrationStatus, erro
 		return nil, fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	applied, err := m.getAppliedMigrations(ctx)
+	records, err := m.getAppliedRecords(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
-	pending, err := m.getPendingMigrations(ctx)
+	files, err := m.migrationFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pending migrations: %w", err)
+		return nil, err
 	}
 
-	return &storm.MigrationStatus{
-		Applied:   len(applied),
-		Pending:   len(pending),
-		Available: len(applied) + len(pending),
-		Current:   "",
-	}, nil
+	status := &storm.MigrationStatus{}
+	recorded := make(map[string]bool, len(records))
+
+	for _, record := range records {
+		recorded[record.name] = true
+		appliedAt := record.appliedAt
+		info := &storm.MigrationInfo{
+			Name:         record.name,
+			State:        storm.MigrationStateApplied,
+			AppliedAt:    &appliedAt,
+			Checksum:     record.checksum,
+			Duration:     record.duration,
+			AppliedBy:    record.appliedBy,
+			StormVersion: record.stormVersion,
+			SQLHash:      record.sqlHash,
+		}
+
+		if file, ok := files[record.name]; ok {
+			migration, err := m.loadMigration(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load migration %s: %w", record.name, err)
+			}
+			if record.sqlHash != "" {
+				info.ChecksumValid = sqlHash(migration.UpSQL) == record.sqlHash
+			} else {
+				info.ChecksumValid = migration.Checksum == record.checksum
+			}
+			status.Applied++
+		} else {
+			info.State = storm.MigrationStateMissing
+			status.Missing++
+		}
+
+		status.Migrations = append(status.Migrations, info)
+		status.Current = record.name
+	}
+
+	for name, file := range files {
+		if recorded[name] {
+			continue
+		}
+		migration, err := m.loadMigration(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load migration %s: %w", name, err)
+		}
+		status.Migrations = append(status.Migrations, &storm.MigrationInfo{
+			Name:          name,
+			State:         storm.MigrationStatePending,
+			Checksum:      migration.Checksum,
+			ChecksumValid: true,
+		})
+		status.Pending++
+	}
+
+	status.Available = status.Applied + status.Pending
+	status.Migrations = status.List(storm.MigrationListOptions{})
+
+	return status, nil
 }
 
 func (m *MigratorImpl) History(ctx context.Context) ([]*storm.MigrationRecord, error) {
@@ -177,29 +252,25 @@ func (m *MigratorImpl) History(ctx context.Context) ([]*storm.MigrationRecord, e
 		return nil, fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	query := fmt.Sprintf(`
-		SELECT name, applied_at, checksum
-		FROM %s
-		ORDER BY applied_at DESC
-	`, m.config.MigrationsTable)
-
-	rows, err := m.db.QueryContext(ctx, query)
+	applied, err := m.getAppliedRecords(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query migration history: %w", err)
 	}
-	defer rows.Close()
 
-	var records []*storm.MigrationRecord
-	for rows.Next() {
-		var record storm.MigrationRecord
-		var name, checksum string
-		if err := rows.Scan(&name, &record.AppliedAt, &checksum); err != nil {
-			return nil, fmt.Errorf("failed to scan migration record: %w", err)
-		}
-		record.ID = name
-		record.Version = name
-		record.Success = true
-		records = append(records, &record)
+	records := make([]*storm.MigrationRecord, 0, len(applied))
+	for i := len(applied) - 1; i >= 0; i-- {
+		record := applied[i]
+		records = append(records, &storm.MigrationRecord{
+			ID:           record.name,
+			Version:      record.name,
+			AppliedAt:    record.appliedAt,
+			AppliedBy:    record.appliedBy,
+			Duration:     record.duration,
+			Checksum:     record.checksum,
+			SQLHash:      record.sqlHash,
+			StormVersion: record.stormVersion,
+			Success:      true,
+		})
 	}
 
 	return records, nil
@@ -221,13 +292,26 @@ func (m *MigratorImpl) AutoMigrate(ctx context.Context, opts storm.AutoMigrateOp
 	defer cancel()
 
 	const lockID = 8675309
-	if err := m.acquireAdvisoryLock(lockCtx, lockID); err != nil {
-		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	if m.config.IsCockroachDB() {
+		if err := m.acquireTableLock(lockCtx, lockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer m.releaseTableLock(ctx, lockID)
+		defer m.refreshTableLock(lockID)()
+	} else {
+		if err := m.acquireAdvisoryLock(lockCtx, lockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer m.releaseAdvisoryLock(ctx, lockID)
 	}
-	defer m.releaseAdvisoryLock(ctx, lockID)
 
 	m.logger.Info("Acquired migration lock, proceeding with auto-migration")
 
+	naming, err := NamingFromConfig(m.config)
+	if err != nil {
+		return err
+	}
+
 	atlasMigrator := NewAtlasMigrator(m.config.DatabaseURL)
 
 	migrationOpts := MigrationOptions{
@@ -237,6 +321,11 @@ func (m *MigratorImpl) AutoMigrate(ctx context.Context, opts storm.AutoMigrateOp
 		AllowDestructive:    opts.AllowDestructive,
 		PushToDB:            true,
 		CreateDBIfNotExists: opts.CreateDBIfNotExists,
+		Naming:              naming,
+	}
+
+	if opts.PlanFile != "" || opts.ApprovalFile != "" {
+		return m.autoMigrateWithPlan(ctx, atlasMigrator, migrationOpts, opts)
 	}
 
 	result, err := atlasMigrator.GenerateMigration(ctx, m.db.DB, migrationOpts)
@@ -253,6 +342,56 @@ func (m *MigratorImpl) AutoMigrate(ctx context.Context, opts storm.AutoMigrateOp
 	return nil
 }
 
+// autoMigrateWithPlan either writes the pending changes to opts.PlanFile, or
+// applies them when they match the plan approved in opts.ApprovalFile
+func (m *MigratorImpl) autoMigrateWithPlan(ctx context.Context, atlasMigrator *migrator.AtlasMigrator, migrationOpts MigrationOptions, opts storm.AutoMigrateOptions) error {
+	migrationOpts.DryRun = false
+	migrationOpts.PushToDB = false
+	migrationOpts.AllowDestructive = true
+
+	result, err := atlasMigrator.GenerateMigration(ctx, m.db.DB, migrationOpts)
+	if err != nil {
+		return fmt.Errorf("auto-migration failed: %w", err)
+	}
+	plan := storm.NewMigrationPlan(result.Statements, result.DestructiveOps)
+
+	if opts.PlanFile != "" {
+		if err := plan.Save(opts.PlanFile); err != nil {
+			return err
+		}
+		m.logger.Info("Migration plan written", "file", opts.PlanFile, "hash", plan.Hash, "statements", len(plan.Statements))
+		return nil
+	}
+
+	approved, err := storm.ReadApprovedPlanHash(opts.ApprovalFile)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Statements) == 0 {
+		m.logger.Info("No schema changes detected, database is up to date")
+		return nil
+	}
+	if plan.Hash != approved {
+		return fmt.Errorf("%w: pending changes have hash %s, %s approves %s",
+			storm.ErrPlanNotApproved, plan.Hash, opts.ApprovalFile, approved)
+	}
+	if len(plan.Destructive) > 0 && !opts.AllowDestructive {
+		return fmt.Errorf("%w: %s", storm.ErrDestructiveChange, strings.Join(plan.Destructive, ", "))
+	}
+	if opts.DryRun {
+		m.logger.Info("Dry run - approved plan would be applied", "hash", plan.Hash, "statements", len(plan.Statements))
+		return nil
+	}
+
+	if err := migrator.ExecuteStatements(ctx, m.db.DB, plan.Statements); err != nil {
+		return fmt.Errorf("auto-migration failed: %w", err)
+	}
+
+	m.logger.Info("Approved migration plan applied", "hash", plan.Hash, "statements", len(plan.Statements))
+	return nil
+}
+
 func (m *MigratorImpl) acquireAdvisoryLock(ctx context.Context, lockID int64) error {
 	_, err := m.db.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID)
 	return err
@@ -262,16 +401,119 @@ func (m *MigratorImpl) releaseAdvisoryLock(ctx context.Context, lockID int64) {
 	_, _ = m.db.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID)
 }
 
+// tableLockExpiry is how long a lock row may go without being refreshed before
+// another process takes the lock over, assuming its holder died. Holders
+// refresh the row several times within it.
+const tableLockExpiry = 2 * time.Minute
+
+// acquireTableLock emulates an advisory lock with a lock row, since CockroachDB
+// does not support pg_advisory_lock. A row whose locked_at is older than
+// tableLockExpiry is left by a holder that died and is removed.
+func (m *MigratorImpl) acquireTableLock(ctx context.Context, lockID int64) error {
+	createQuery := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s_lock (
+			id BIGINT PRIMARY KEY,
+			locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`, m.config.MigrationsTable)
+
+	if _, err := m.db.ExecContext(ctx, createQuery); err != nil {
+		return fmt.Errorf("failed to create lock table: %w", err)
+	}
+
+	staleQuery := fmt.Sprintf(`
+		DELETE FROM %s_lock WHERE id = $1 AND locked_at < NOW() - $2 * INTERVAL '1 second'
+	`, m.config.MigrationsTable)
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s_lock (id) VALUES ($1) ON CONFLICT (id) DO NOTHING
+	`, m.config.MigrationsTable)
+
+	for {
+		result, err := m.db.ExecContext(ctx, staleQuery, lockID, int64(tableLockExpiry/time.Second))
+		if err != nil {
+			return fmt.Errorf("failed to remove stale lock: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+			m.logger.Warn("Removed stale migration lock", "table", m.config.MigrationsTable+"_lock", "expiry", tableLockExpiry)
+		}
+
+		result, err = m.db.ExecContext(ctx, insertQuery, lockID)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// refreshTableLock keeps the lock row from expiring while the migration runs,
+// until the returned function is called
+func (m *MigratorImpl) refreshTableLock(lockID int64) func() {
+	query := fmt.Sprintf(`UPDATE %s_lock SET locked_at = NOW() WHERE id = $1`, m.config.MigrationsTable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(tableLockExpiry / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := m.db.ExecContext(ctx, query, lockID); err != nil && ctx.Err() == nil {
+					m.logger.Warn("Failed to refresh migration lock", "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (m *MigratorImpl) releaseTableLock(ctx context.Context, lockID int64) {
+	query := fmt.Sprintf(`DELETE FROM %s_lock WHERE id = $1`, m.config.MigrationsTable)
+	_, _ = m.db.ExecContext(ctx, query, lockID)
+}
+
 func (m *MigratorImpl) createMigrationsTable(ctx context.Context) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
 			name VARCHAR(255) PRIMARY KEY,
 			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-			checksum VARCHAR(64) NOT NULL
+			checksum VARCHAR(64) NOT NULL,
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			applied_by VARCHAR(255) NOT NULL DEFAULT '',
+			storm_version VARCHAR(64) NOT NULL DEFAULT '',
+			sql_hash VARCHAR(64) NOT NULL DEFAULT ''
 		)
 	`, m.config.MigrationsTable)
 
-	_, err := m.db.ExecContext(ctx, query)
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	// Tables created by earlier versions lack the later columns
+	alter := fmt.Sprintf(`
+		ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS duration_ms BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS applied_by VARCHAR(255) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS storm_version VARCHAR(64) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS sql_hash VARCHAR(64) NOT NULL DEFAULT ''
+	`, m.config.MigrationsTable)
+
+	_, err := m.db.ExecContext(ctx, alter)
 	return err
 }
 
@@ -295,15 +537,70 @@ func (m *MigratorImpl) getAppliedMigrations(ctx context.Context) ([]string, erro
 	return names, err
 }
 
+// appliedRecord is a row of the migrations table
+type appliedRecord struct {
+	name         string
+	appliedAt    time.Time
+	checksum     string
+	duration     time.Duration
+	appliedBy    string
+	stormVersion string
+	sqlHash      string
+}
+
+func (m *MigratorImpl) getAppliedRecords(ctx context.Context) ([]appliedRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT name, applied_at, checksum, duration_ms, applied_by, storm_version, sql_hash
+		FROM %s ORDER BY applied_at, name
+	`, m.config.MigrationsTable)
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []appliedRecord
+	for rows.Next() {
+		var record appliedRecord
+		var durationMS int64
+		if err := rows.Scan(&record.name, &record.appliedAt, &record.checksum, &durationMS,
+			&record.appliedBy, &record.stormVersion, &record.sqlHash); err != nil {
+			return nil, fmt.Errorf("failed to scan migration record: %w", err)
+		}
+		record.duration = time.Duration(durationMS) * time.Millisecond
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// migrationFiles maps migration names to their up files in the migrations
+// directory and its subdirectories
+func (m *MigratorImpl) migrationFiles() (map[string]string, error) {
+	files, err := migrator.ListMigrationFiles(m.config.MigrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(files))
+	for _, file := range files {
+		result[file.Name] = file.UpPath
+	}
+	return result, nil
+}
+
+// getPendingMigrations returns the migrations not yet applied, generated and
+// hand-written alike, in version order
 func (m *MigratorImpl) getPendingMigrations(ctx context.Context) ([]*storm.Migration, error) {
 
 	if err := m.createMigrationsTable(ctx); err != nil {
 		return nil, fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	files, err := filepath.Glob(filepath.Join(m.config.MigrationsDir, "*.up.sql"))
+	files, err := migrator.ListMigrationFiles(m.config.MigrationsDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to glob migration files: %w", err)
+		return nil, err
 	}
 
 	applied, err := m.getAppliedMigrations(ctx)
@@ -318,13 +615,10 @@ func (m *MigratorImpl) getPendingMigrations(ctx context.Context) ([]*storm.Migra
 
 	var pending []*storm.Migration
 	for _, file := range files {
-		name := filepath.Base(file)
-		name = strings.TrimSuffix(name, ".up.sql")
-
-		if !appliedMap[name] {
-			migration, err := m.loadMigration(file)
+		if !appliedMap[file.Name] {
+			migration, err := m.loadMigration(file.UpPath)
 			if err != nil {
-				return nil, fmt.Errorf("failed to load migration %s: %w", name, err)
+				return nil, fmt.Errorf("failed to load migration %s: %w", file.Name, err)
 			}
 			pending = append(pending, migration)
 		}
@@ -359,12 +653,31 @@ func (m *MigratorImpl) loadMigration(upFile string) (*storm.Migration, error) {
 }
 
 func (m *MigratorImpl) executeMigration(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
+	statements, err := m.migrationStatements(migration)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	for i, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement %d of %d: %s: %w", i+1, len(statements), stmt, err)
+		}
+		m.reportProgress(migration.Name, i+1, len(statements), started, false)
+	}
+
+	return nil
+}
+
+// migrationStatements returns the statements of the migration to run on this
+// database, leaving out CREATE DATABASE. On CockroachDB a migration with
+// statements it does not support fails as a whole, before any of them runs.
+func (m *MigratorImpl) migrationStatements(migration *storm.Migration) ([]string, error) {
 	if migration.UpSQL == "" {
-		return nil
+		return nil, nil
 	}
 
-	statements := m.splitSQLStatements(migration.UpSQL)
-	for _, stmt := range statements {
+	var statements, unsupported []string
+	for _, stmt := range m.splitSQLStatements(migration.UpSQL) {
 		stmt = strings.TrimSpace(stmt)
 		if stmt == "" {
 			continue
@@ -375,12 +688,38 @@ func (m *MigratorImpl) executeMigration(ctx context.Context, tx *sqlx.Tx, migrat
 			continue
 		}
 
-		if _, err := tx.ExecContext(ctx, stmt); err != nil {
-			return fmt.Errorf("failed to execute statement: %s: %w", stmt, err)
+		if m.config.IsCockroachDB() {
+			if feature := unsupportedByCockroach(stmt); feature != "" {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%s)", stmt, feature))
+				continue
+			}
 		}
+
+		statements = append(statements, stmt)
 	}
 
-	return nil
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("migration %s has statements CockroachDB does not support: %s",
+			migration.Name, strings.Join(unsupported, "; "))
+	}
+	return statements, nil
+}
+
+// reportProgress logs that statement of total has run and passes it to the
+// configured progress callback
+func (m *MigratorImpl) reportProgress(name string, statement, total int, started time.Time, resumed bool) {
+	progress := storm.MigrationProgress{
+		Migration: name,
+		Schema:    m.schema,
+		Statement: statement,
+		Total:     total,
+		Elapsed:   time.Since(started),
+		Resumed:   resumed,
+	}
+	m.logger.Info("Migration progress", "name", name, "statement", statement, "total", total, "elapsed", progress.Elapsed)
+	if m.config.OnMigrationProgress != nil {
+		m.config.OnMigrationProgress(progress)
+	}
 }
 
 // splitSQLStatements properly splits PostgreSQL statements, handling dollar-quoted strings
@@ -439,6 +778,39 @@ func (m *MigratorImpl) splitSQLStatements(sql string) []string {
 	return statements
 }
 
+// cockroachUnsupported lists PostgreSQL features CockroachDB does not
+// provide, matched against statements with their whitespace collapsed
+var cockroachUnsupported = []struct {
+	pattern *regexp.Regexp
+	feature string
+}{
+	{regexp.MustCompile(`^(CREATE|DROP|ALTER) EXTENSION\b`), "extensions"},
+	{regexp.MustCompile(`^CLUSTER\b`), "CLUSTER"},
+	{regexp.MustCompile(`^VACUUM\b`), "VACUUM"},
+	{regexp.MustCompile(`\bPG_ADVISORY_`), "advisory locks"},
+	{regexp.MustCompile(`^ALTER TABLE .*\bSET TABLESPACE\b`), "tablespaces"},
+	{regexp.MustCompile(`^ALTER TABLE .*\b(CLUSTER ON|SET WITHOUT CLUSTER)\b`), "clustering"},
+	{regexp.MustCompile(`^ALTER TABLE .*\bSET (LOGGED|UNLOGGED)\b`), "unlogged tables"},
+	{regexp.MustCompile(`^ALTER TABLE .*\b(NO )?INHERIT\b`), "table inheritance"},
+	{regexp.MustCompile(`^ALTER TABLE .*\bREPLICA IDENTITY\b`), "replica identity"},
+	{regexp.MustCompile(`^ALTER TABLE .*\b(ENABLE|DISABLE)( ALWAYS| REPLICA)? (TRIGGER|RULE)\b`), "enabling or disabling triggers and rules"},
+	{regexp.MustCompile(`^ALTER TABLE .*\bALTER (COLUMN )?\S+ SET (STORAGE|STATISTICS|COMPRESSION)\b`), "column storage settings"},
+	{regexp.MustCompile(`^ALTER TABLE .*\bADD (CONSTRAINT \S+ )?EXCLUDE\b`), "exclusion constraints"},
+	{regexp.MustCompile(`^ALTER TABLE .*\bALTER (COLUMN )?\S+ (SET DATA )?TYPE\b.*\bUSING\b`), "column type changes that rewrite data"},
+}
+
+// unsupportedByCockroach returns the PostgreSQL feature a statement relies
+// on that CockroachDB does not provide, or "" when it has none
+func unsupportedByCockroach(stmt string) string {
+	upper := strings.Join(strings.Fields(strings.ToUpper(stmt)), " ")
+	for _, unsupported := range cockroachUnsupported {
+		if unsupported.pattern.MatchString(upper) {
+			return unsupported.feature
+		}
+	}
+	return ""
+}
+
 // isOnlyComments checks if a statement contains only comments and whitespace
 func isOnlyComments(stmt string) bool {
 	lines := strings.Split(stmt, "\n")
@@ -452,7 +824,10 @@ func isOnlyComments(stmt string) bool {
 }
 
 func (m *MigratorImpl) executeRollback(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
-	if migration.DownSQL == "" {
+	if reasons := migrator.IrreversibleReasons(migration.DownSQL); len(reasons) > 0 {
+		return fmt.Errorf("migration %s is irreversible: %s", migration.Name, strings.Join(reasons, "; "))
+	}
+	if isOnlyComments(migration.DownSQL) {
 		return fmt.Errorf("no rollback script available for migration %s", migration.Name)
 	}
 
@@ -471,16 +846,35 @@ func (m *MigratorImpl) executeRollback(ctx context.Context, tx *sqlx.Tx, migrati
 	return nil
 }
 
-func (m *MigratorImpl) recordMigration(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
+func (m *MigratorImpl) recordMigration(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration, duration time.Duration) error {
 	query := fmt.Sprintf(`
-		INSERT INTO %s (name, applied_at, checksum)
-		VALUES ($1, $2, $3)
+		INSERT INTO %s (name, applied_at, checksum, duration_ms, applied_by, storm_version, sql_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`, m.config.MigrationsTable)
 
-	_, err := tx.ExecContext(ctx, query, migration.Name, time.Now(), migration.Checksum)
+	_, err := tx.ExecContext(ctx, query, migration.Name, time.Now(), migration.Checksum, duration.Milliseconds(),
+		appliedBy(), storm.Version, sqlHash(migration.UpSQL))
 	return err
 }
 
+// appliedBy identifies who applies a migration as user@host
+func appliedBy() string {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return name + "@" + host
+	}
+	return name
+}
+
+// sqlHash is the SHA-256 of the SQL of a migration, recorded to detect later edits
+func sqlHash(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
 func (m *MigratorImpl) removeMigrationRecord(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
 	query := fmt.Sprintf(`
 		DELETE FROM %s WHERE name = $1
@@ -511,8 +905,18 @@ func (m *MigratorImpl) getCurrentSchemaOrEmpty(ctx context.Context) (*storm.Sche
 	return currentSchema, nil
 }
 
+// DesiredSchema parses the configured models package into a schema
+func (m *MigratorImpl) DesiredSchema(ctx context.Context) (*storm.Schema, error) {
+	return m.getDesiredSchema(m.config.ModelsPackage)
+}
+
 func (m *MigratorImpl) getDesiredSchema(packagePath string) (*storm.Schema, error) {
-	structParser := NewStructParser()
+	naming, err := NamingFromConfig(m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	structParser := parser.NewStructParserWithNaming(naming)
 	models, err := structParser.ParseDirectory(packagePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse structs: %w", err)
@@ -527,7 +931,12 @@ func (m *MigratorImpl) getDesiredSchema(packagePath string) (*storm.Schema, erro
 	return m.convertGeneratorSchemaToStorm(schema), nil
 }
 
-func (m *MigratorImpl) generateMigration(current, desired *storm.Schema, migrateOpts storm.MigrateOptions) (*storm.Migration, error) {
+func (m *MigratorImpl) generateMigration(ctx context.Context, current, desired *storm.Schema, migrateOpts storm.MigrateOptions) (*storm.Migration, error) {
+	naming, err := NamingFromConfig(m.config)
+	if err != nil {
+		return nil, err
+	}
+
 	atlasMigrator := NewAtlasMigrator(m.config.DatabaseURL)
 
 	opts := MigrationOptions{
@@ -537,16 +946,22 @@ func (m *MigratorImpl) generateMigration(current, desired *storm.Schema, migrate
 		AllowDestructive:    migrateOpts.AllowDestructive,
 		PushToDB:            false,
 		CreateDBIfNotExists: migrateOpts.CreateDBIfNotExists,
+		Naming:              naming,
+		Files: migrator.FileLayout{
+			Naming: m.config.MigrationFileNaming,
+			Header: m.config.MigrationHeader,
+		},
 	}
 
-	ctx := context.Background()
 	result, err := atlasMigrator.GenerateMigration(ctx, m.db.DB, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate migration: %w", err)
 	}
 
-	timestamp := time.Now().Format("20060102150405")
-	name := fmt.Sprintf("%s_auto_migration", timestamp)
+	name := fmt.Sprintf("%s_auto_migration", time.Now().Format("20060102150405"))
+	if result.UpFilePath != "" {
+		name = strings.TrimSuffix(filepath.Base(result.UpFilePath), ".up.sql")
+	}
 
 	return &storm.Migration{
 		Name:      name,
@@ -567,6 +982,18 @@ func NewStructParser() *parser.StructParser {
 	return parser.NewStructParser()
 }
 
+// NamingFromConfig builds the table and column naming strategy configured for
+// models, along with the structs included in or excluded from the models
+func NamingFromConfig(config *storm.Config) (*parser.Naming, error) {
+	naming, err := parser.BuildNaming(config.NamingConvention, config.Plurals, config.TableNames)
+	if err != nil {
+		return nil, err
+	}
+	naming.Include = config.IncludeModels
+	naming.Exclude = config.ExcludeModels
+	return naming, nil
+}
+
 func NewSchemaGenerator() *generator.SchemaGenerator {
 	return generator.NewSchemaGenerator()
 }
@@ -0,0 +1,516 @@
+package storm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestUnsupportedByCockroach(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		feature string
+	}{
+		{`CREATE EXTENSION IF NOT EXISTS "pgcrypto";`, "extensions"},
+		{"SELECT pg_advisory_lock(1);", "advisory locks"},
+		{"VACUUM ANALYZE users;", "VACUUM"},
+		{"ALTER TABLE users\n  SET TABLESPACE fast;", "tablespaces"},
+		{"ALTER TABLE users REPLICA IDENTITY FULL;", "replica identity"},
+		{"ALTER TABLE users DISABLE TRIGGER ALL;", "enabling or disabling triggers and rules"},
+		{"ALTER TABLE users ALTER COLUMN bio SET STORAGE EXTERNAL;", "column storage settings"},
+		{"ALTER TABLE bookings ADD CONSTRAINT no_overlap EXCLUDE USING gist (room WITH =, during WITH &&);", "exclusion constraints"},
+		{"ALTER TABLE users ALTER COLUMN age TYPE integer USING age::integer;", "column type changes that rewrite data"},
+		{"CREATE TABLE users (id UUID PRIMARY KEY);", ""},
+		{"ALTER TABLE users ADD COLUMN name TEXT;", ""},
+		{"ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(255);", ""},
+	}
+
+	for _, tt := range tests {
+		if got := unsupportedByCockroach(tt.stmt); got != tt.feature {
+			t.Errorf("unsupportedByCockroach(%q) = %q, want %q", tt.stmt, got, tt.feature)
+		}
+	}
+}
+
+func TestMigrationStatementsOnCockroach(t *testing.T) {
+	config := storm.NewConfig()
+	config.Dialect = storm.DialectCockroachDB
+	m := NewMigrator(nil, config, &TestLogger{})
+
+	migration := &storm.Migration{
+		Name:  "20240101000000_search",
+		UpSQL: "CREATE EXTENSION IF NOT EXISTS pg_trgm;\nALTER TABLE users ADD COLUMN bio TEXT;\nVACUUM users;",
+	}
+	_, err := m.migrationStatements(migration)
+	if err == nil {
+		t.Fatal("expected a migration with unsupported statements to fail")
+	}
+	for _, part := range []string{"20240101000000_search", "CREATE EXTENSION IF NOT EXISTS pg_trgm; (extensions)", "VACUUM users; (VACUUM)"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected error to mention %q, got %v", part, err)
+		}
+	}
+
+	config.Dialect = storm.DialectPostgres
+	statements, err := m.migrationStatements(migration)
+	if err != nil || len(statements) != 3 {
+		t.Errorf("expected every statement on PostgreSQL, got %v (%v)", statements, err)
+	}
+}
+
+func TestAcquireTableLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	config := storm.NewConfig()
+	config.Dialect = storm.DialectCockroachDB
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{})
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations_lock").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations_lock WHERE id = $1 AND locked_at < NOW() - $2 * INTERVAL '1 second'")).
+		WithArgs(int64(42), int64(120)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations_lock").
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations_lock WHERE id = $1")).
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := m.acquireTableLock(context.Background(), 42); err != nil {
+		t.Fatalf("expected lock to be acquired, got %v", err)
+	}
+	m.releaseTableLock(context.Background(), 42)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestAcquireTableLockTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	config := storm.NewConfig()
+	config.Dialect = storm.DialectCockroachDB
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{})
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations_lock").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations_lock").
+		WithArgs(int64(42), int64(120)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations_lock").
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := m.acquireTableLock(ctx, 42); err == nil {
+		t.Fatal("expected lock acquisition to time out")
+	}
+}
+
+func TestAcquireTableLockStale(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	config := storm.NewConfig()
+	config.Dialect = storm.DialectCockroachDB
+	logger := &recordingLogger{}
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), config, logger)
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations_lock").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations_lock WHERE id = \\$1 AND locked_at <").
+		WithArgs(int64(42), int64(120)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO schema_migrations_lock").
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := m.acquireTableLock(context.Background(), 42); err != nil {
+		t.Fatalf("expected the stale lock to be taken over, got %v", err)
+	}
+	if len(logger.warnings) != 1 || logger.warnings[0] != "Removed stale migration lock" {
+		t.Errorf("expected a stale lock warning, got %v", logger.warnings)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_create_users.up.sql": "CREATE TABLE users (id UUID);",
+		"002_add_email.up.sql":    "ALTER TABLE users ADD COLUMN email TEXT NOT NULL;",
+		"004_add_posts.up.sql":    "CREATE TABLE posts (id UUID);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := storm.NewConfig()
+	config.MigrationsDir = dir
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{})
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS duration_ms").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name, applied_at, checksum, duration_ms, applied_by, storm_version, sql_hash FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "applied_at", "checksum", "duration_ms", "applied_by", "storm_version", "sql_hash"}).
+			AddRow("001_create_users", first, "0", 12, "ada@ci", "1.0.0", sqlHash(files["001_create_users.up.sql"])).
+			AddRow("002_add_email", first.Add(time.Minute), "0", 3, "", "", "").
+			AddRow("003_drop_legacy", first.Add(2*time.Minute), "1c", 0, "", "", ""))
+
+	status, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+
+	if status.Applied != 2 || status.Pending != 1 || status.Missing != 1 || status.Available != 3 {
+		t.Errorf("unexpected counts: %+v", status)
+	}
+	if info := status.Migrations[0]; info.AppliedBy != "ada@ci" || info.StormVersion != "1.0.0" || info.SQLHash == "" {
+		t.Errorf("expected the execution record of 001_create_users, got %+v", info)
+	}
+	if status.Current != "003_drop_legacy" {
+		t.Errorf("expected current migration 003_drop_legacy, got %s", status.Current)
+	}
+
+	expected := []struct {
+		name          string
+		state         storm.MigrationState
+		checksumValid bool
+		duration      time.Duration
+	}{
+		{"001_create_users", storm.MigrationStateApplied, true, 12 * time.Millisecond},
+		{"002_add_email", storm.MigrationStateApplied, false, 3 * time.Millisecond},
+		{"003_drop_legacy", storm.MigrationStateMissing, false, 0},
+		{"004_add_posts", storm.MigrationStatePending, true, 0},
+	}
+	if len(status.Migrations) != len(expected) {
+		t.Fatalf("expected %d migrations, got %d", len(expected), len(status.Migrations))
+	}
+	for i, want := range expected {
+		got := status.Migrations[i]
+		if got.Name != want.name || got.State != want.state || got.ChecksumValid != want.checksumValid || got.Duration != want.duration {
+			t.Errorf("migration %d: expected %+v, got %+v", i, want, got)
+		}
+		if (got.State == storm.MigrationStatePending) != (got.AppliedAt == nil) {
+			t.Errorf("migration %s: unexpected applied time %v", got.Name, got.AppliedAt)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorRollbackIrreversible(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), storm.NewConfig(), &TestLogger{})
+
+	mock.ExpectQuery("SELECT COUNT").WithArgs("002_drop_legacy").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err = m.Rollback(context.Background(), &storm.Migration{
+		Name:    "002_drop_legacy",
+		DownSQL: "-- storm:irreversible statement 1: Cannot reverse DROP TABLE without original schema\n",
+	})
+	if err == nil || !strings.Contains(err.Error(), "migration 002_drop_legacy is irreversible: statement 1: Cannot reverse DROP TABLE") {
+		t.Errorf("expected an irreversible migration error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorApplyResumesFromJournal(t *testing.T) {
+	upSQL := "-- storm:no-transaction\n" +
+		"CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\n" +
+		"CREATE INDEX CONCURRENTLY idx_users_name ON users (name);\n" +
+		"CREATE INDEX CONCURRENTLY idx_posts_title ON posts (title);\n"
+
+	setup := func(t *testing.T) (*MigratorImpl, sqlmock.Sqlmock, *[]storm.MigrationProgress) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		var progress []storm.MigrationProgress
+		config := storm.NewConfig()
+		config.OnMigrationProgress = func(p storm.MigrationProgress) { progress = append(progress, p) }
+		m := NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{})
+
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS duration_ms").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COUNT").WithArgs("003_add_indexes").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations_journal").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		return m, mock, &progress
+	}
+
+	t.Run("continues after the last recorded statement", func(t *testing.T) {
+		m, mock, progress := setup(t)
+		migration := &storm.Migration{Name: "003_add_indexes", UpSQL: upSQL, Checksum: m.calculateChecksum(upSQL)}
+
+		mock.ExpectQuery("SELECT statements, checksum FROM schema_migrations_journal").WithArgs("003_add_indexes").
+			WillReturnRows(sqlmock.NewRows([]string{"statements", "checksum"}).AddRow(1, migration.Checksum))
+		mock.ExpectExec("CREATE INDEX CONCURRENTLY idx_users_name").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations_journal").WithArgs("003_add_indexes", migration.Checksum, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("CREATE INDEX CONCURRENTLY idx_posts_title").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations_journal").WithArgs("003_add_indexes", migration.Checksum, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM schema_migrations_journal").WithArgs("003_add_indexes").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := m.Apply(context.Background(), migration); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		if len(*progress) != 2 {
+			t.Fatalf("expected progress for 2 statements, got %+v", *progress)
+		}
+		for i, p := range *progress {
+			if p.Migration != "003_add_indexes" || p.Statement != i+2 || p.Total != 3 || !p.Resumed {
+				t.Errorf("unexpected progress %+v", p)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("refuses to resume a changed migration", func(t *testing.T) {
+		m, mock, _ := setup(t)
+		migration := &storm.Migration{Name: "003_add_indexes", UpSQL: upSQL, Checksum: m.calculateChecksum(upSQL)}
+
+		mock.ExpectQuery("SELECT statements, checksum FROM schema_migrations_journal").WithArgs("003_add_indexes").
+			WillReturnRows(sqlmock.NewRows([]string{"statements", "checksum"}).AddRow(1, "0"))
+
+		err := m.Apply(context.Background(), migration)
+		if err == nil || !strings.Contains(err.Error(), "changed after 1 of its statements were applied") {
+			t.Errorf("expected a changed migration error, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+}
+
+func TestMigratorApplyAbortsOnLockContention(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	origInterval := lockPollInterval
+	lockPollInterval = 10 * time.Millisecond
+	defer func() { lockPollInterval = origInterval }()
+
+	config := storm.NewConfig()
+	config.MigrationLockThreshold = 20 * time.Millisecond
+	config.MigrationLockPolicy = storm.MigrationLockAbort
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{})
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS duration_ms").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("004_add_email").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_backend_pid").
+		WillReturnRows(sqlmock.NewRows([]string{"pg_backend_pid"}).AddRow(42))
+	mock.ExpectExec("ALTER TABLE users ADD COLUMN email").
+		WillDelayFor(5 * time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("pg_blocking_pids").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"pid", "query", "waiting", "blocking_query"}).
+			AddRow(77, "SELECT * FROM users", 0.5, "ALTER TABLE users ADD COLUMN email TEXT;"))
+	mock.ExpectRollback()
+
+	err = m.Apply(context.Background(), &storm.Migration{
+		Name:  "004_add_email",
+		UpSQL: "ALTER TABLE users ADD COLUMN email TEXT;",
+	})
+	if err == nil || !strings.Contains(err.Error(), "migration 004_add_email aborted: session 77 waited 500ms") {
+		t.Errorf("expected a lock contention abort, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), storm.NewConfig(), &TestLogger{})
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS duration_ms").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name, applied_at, checksum, duration_ms, applied_by, storm_version, sql_hash FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "applied_at", "checksum", "duration_ms", "applied_by", "storm_version", "sql_hash"}).
+			AddRow("001_create_users", first, "1d", 12, "ada@ci", "1.0.0", "abc").
+			AddRow("002_add_email", first.Add(time.Minute), "2a", 3, "grace@laptop", "1.1.0", "def"))
+
+	records, err := m.History(context.Background())
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	latest := records[0]
+	if latest.ID != "002_add_email" || latest.AppliedBy != "grace@laptop" || latest.StormVersion != "1.1.0" ||
+		latest.SQLHash != "def" || latest.Checksum != "2a" || latest.Duration != 3*time.Millisecond {
+		t.Errorf("unexpected latest record %+v", latest)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorMigrateTenants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"001_create_notes.up.sql": "CREATE TABLE notes (id SERIAL PRIMARY KEY);",
+		"002_add_title.up.sql":    "ALTER TABLE notes ADD COLUMN title TEXT;",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write migration: %v", err)
+		}
+	}
+
+	config := storm.NewConfig()
+	config.MigrationsDir = dir
+	m := NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{})
+
+	mock.ExpectQuery("SELECT nspname FROM pg_namespace").
+		WillReturnRows(sqlmock.NewRows([]string{"nspname"}).AddRow("tenant_acme").AddRow("tenant_globex"))
+
+	expectTenant := func(schema string, fail bool) {
+		table := regexp.QuoteMeta(`"` + schema + `".schema_migrations`)
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS " + table).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE " + table + " ADD COLUMN IF NOT EXISTS duration_ms").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT name FROM " + table).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS " + table).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE " + table + " ADD COLUMN IF NOT EXISTS duration_ms").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COUNT").WithArgs("001_create_notes").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config\\('search_path'").WithArgs(`"`+schema+`"`, true).WillReturnResult(sqlmock.NewResult(0, 0))
+		if fail {
+			mock.ExpectExec("CREATE TABLE notes").WillReturnError(fmt.Errorf("permission denied for schema %s", schema))
+			mock.ExpectRollback()
+			return
+		}
+		mock.ExpectExec("CREATE TABLE notes").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO " + table).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS " + table).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE " + table + " ADD COLUMN IF NOT EXISTS duration_ms").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COUNT").WithArgs("002_add_title").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config\\('search_path'").WithArgs(`"`+schema+`"`, true).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE notes ADD COLUMN title").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO " + table).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectTenant("tenant_acme", false)
+	expectTenant("tenant_globex", true)
+
+	report, err := m.MigrateTenants(context.Background(), storm.TenantMigrateOptions{
+		SchemaQuery: "SELECT nspname FROM pg_namespace WHERE nspname LIKE 'tenant_%'",
+	})
+	if err != nil {
+		t.Fatalf("MigrateTenants failed: %v", err)
+	}
+
+	if len(report.Tenants) != 2 {
+		t.Fatalf("expected 2 tenants, got %+v", report.Tenants)
+	}
+	acme, globex := report.Tenants[0], report.Tenants[1]
+	if acme.Schema != "tenant_acme" || acme.Err != nil || strings.Join(acme.Applied, ",") != "001_create_notes,002_add_title" {
+		t.Errorf("unexpected result for tenant_acme: %+v", acme)
+	}
+	if globex.Schema != "tenant_globex" || len(globex.Applied) != 0 || globex.Pending != 2 {
+		t.Errorf("unexpected result for tenant_globex: %+v", globex)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "1 of 2 tenants failed to migrate: tenant_globex: migration 001_create_notes") {
+		t.Errorf("expected a report error naming tenant_globex, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -25,11 +25,22 @@ func NewORM(config *storm.Config, logger storm.Logger) *ORMImpl {
 func (o *ORMImpl) Generate(ctx context.Context, opts storm.GenerateOptions) error {
 	o.logger.Info("Generating ORM code...", "package", opts.PackagePath)
 
+	naming, err := NamingFromConfig(o.config)
+	if err != nil {
+		return err
+	}
+
 	config := orm_generator.GenerationConfig{
-		PackageName:  filepath.Base(opts.PackagePath),
-		OutputDir:    opts.OutputDir,
-		IncludeTests: opts.IncludeTests,
-		IncludeDocs:  true,
+		PackageName:     filepath.Base(opts.PackagePath),
+		OutputDir:       opts.OutputDir,
+		IncludeTests:    opts.IncludeTests,
+		IncludeMocks:    opts.IncludeMocks,
+		IncludeHandlers: opts.IncludeHandlers,
+		IncludeDTOs:     opts.IncludeDTOs,
+		TemplateDir:     opts.TemplatesDir,
+		IncludeDocs:     true,
+		Database:        opts.Database,
+		Naming:          naming,
 	}
 
 	generator := orm_generator.NewCodeGenerator(config)
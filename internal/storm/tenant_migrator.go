@@ -0,0 +1,123 @@
+package storm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/jmoiron/sqlx"
+)
+
+// MigrateTenants applies the pending migrations to each tenant schema, with
+// at most opts.Parallelism tenants at once. Every tenant keeps its own
+// migrations table inside its schema, and its migrations run with the schema
+// first on the search_path. A failing migration stops that tenant only; the
+// report records what was applied and what is left for each of them.
+func (m *MigratorImpl) MigrateTenants(ctx context.Context, opts storm.TenantMigrateOptions) (*storm.TenantMigrationReport, error) {
+	schemas, err := m.tenantSchemas(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	m.logger.Info("Migrating tenants...", "tenants", len(schemas), "parallelism", parallelism)
+
+	report := &storm.TenantMigrationReport{Tenants: make([]storm.TenantMigrationResult, len(schemas))}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, schema := range schemas {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, schema string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Tenants[i] = m.forSchema(schema).migrateTenant(ctx)
+		}(i, schema)
+	}
+	wg.Wait()
+
+	m.logger.Info("Tenant migration finished", "tenants", len(schemas), "failed", len(report.Failed()))
+	return report, nil
+}
+
+// tenantSchemas returns the configured schemas, or those listed by the
+// schema query when none are
+func (m *MigratorImpl) tenantSchemas(ctx context.Context, opts storm.TenantMigrateOptions) ([]string, error) {
+	if len(opts.Schemas) > 0 {
+		return opts.Schemas, nil
+	}
+	if strings.TrimSpace(opts.SchemaQuery) == "" {
+		return nil, fmt.Errorf("tenant schemas or a schema query are required")
+	}
+
+	var schemas []string
+	if err := m.db.SelectContext(ctx, &schemas, opts.SchemaQuery); err != nil {
+		return nil, fmt.Errorf("failed to list tenant schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// forSchema returns a migrator that tracks its migrations in a table of the
+// tenant schema and applies them within it
+func (m *MigratorImpl) forSchema(schema string) *MigratorImpl {
+	table := m.config.MigrationsTable
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+
+	config := m.config.Clone()
+	config.MigrationsTable = dialect.QuoteIdentifier(schema) + "." + table
+	return &MigratorImpl{
+		db:     m.db,
+		config: config,
+		logger: m.logger,
+		schema: schema,
+	}
+}
+
+// migrateTenant applies the pending migrations of the tenant in order,
+// stopping at the first that fails
+func (m *MigratorImpl) migrateTenant(ctx context.Context) storm.TenantMigrationResult {
+	started := time.Now()
+	result := storm.TenantMigrationResult{Schema: m.schema}
+
+	pending, err := m.getPendingMigrations(ctx)
+	if err != nil {
+		result.Err = err
+		result.Duration = time.Since(started)
+		return result
+	}
+
+	for i, migration := range pending {
+		if err := m.Apply(ctx, migration); err != nil {
+			m.logger.Error("Tenant migration failed", "schema", m.schema, "name", migration.Name, "error", err)
+			result.Err = fmt.Errorf("migration %s: %w", migration.Name, err)
+			result.Pending = len(pending) - i
+			break
+		}
+		result.Applied = append(result.Applied, migration.Name)
+	}
+	result.Duration = time.Since(started)
+	return result
+}
+
+// useTenantSchema puts the tenant schema first on the search_path of the
+// transaction or connection, for migrators created by forSchema. local limits
+// the setting to the current transaction.
+func (m *MigratorImpl) useTenantSchema(ctx context.Context, exec sqlx.ExecerContext, local bool) error {
+	if m.schema == "" {
+		return nil
+	}
+	query := `SELECT set_config('search_path', $1 || ', ' || current_setting('search_path'), $2)`
+	if _, err := exec.ExecContext(ctx, query, dialect.QuoteIdentifier(m.schema), local); err != nil {
+		return fmt.Errorf("failed to set search_path to tenant schema %s: %w", m.schema, err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+)
+
+// Masks a clone applies to column values
+const (
+	MaskNull  = "null"  // Replace the value with NULL
+	MaskEmail = "email" // Replace the value with a fake address derived from its hash
+	MaskHash  = "hash"  // Replace the value with its salted hash
+	MaskFixed = "fixed" // Replace the value with a fixed one
+	MaskKeep  = "keep"  // Copy the value as it is
+)
+
+// Mask is how a clone replaces the values of a column. NULL stays NULL under
+// every mask.
+type Mask struct {
+	Kind  string
+	Value string // Replacement of a fixed mask
+}
+
+// ParseMask reads a mask written as null, email, hash, keep or fixed:<value>
+func ParseMask(rule string) (Mask, error) {
+	rule = strings.TrimSpace(rule)
+	if value, ok := strings.CutPrefix(rule, MaskFixed+":"); ok {
+		return Mask{Kind: MaskFixed, Value: value}, nil
+	}
+	switch rule {
+	case MaskNull, MaskEmail, MaskHash, MaskKeep:
+		return Mask{Kind: rule}, nil
+	}
+	return Mask{}, fmt.Errorf("unknown mask %q: expected null, email, hash, keep or fixed:<value>", rule)
+}
+
+func (m Mask) String() string {
+	if m.Kind == MaskFixed {
+		return MaskFixed + ":" + m.Value
+	}
+	return m.Kind
+}
+
+// apply returns the masked value. Hashes are salted and deterministic within
+// a clone, so equal values stay equal and unique values stay unique.
+func (m Mask) apply(value *string, salt string) interface{} {
+	if value == nil || m.Kind == MaskNull {
+		return nil
+	}
+	switch m.Kind {
+	case MaskEmail:
+		return "user_" + saltedHash(salt, *value)[:12] + "@example.com"
+	case MaskHash:
+		return saltedHash(salt, *value)[:16]
+	case MaskFixed:
+		return m.Value
+	}
+	return *value
+}
+
+func saltedHash(salt, value string) string {
+	sum := sha256.Sum256([]byte(salt + "\x00" + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// CloneTable is a table a clone copies, with the masks of its columns
+type CloneTable struct {
+	Name    string          // Optionally schema-qualified
+	Columns []ImportColumn  // Columns of the model of the table
+	Key     []string        // Primary key, the order rows are copied in
+	Serial  []string        // Columns whose sequences are moved past the copied values
+	Masks   map[string]Mask // Column to mask; other columns are copied as they are
+}
+
+// Clone copies the rows of tables from one database to another, masking
+// column values on the way. Target tables are emptied first, and the whole
+// copy runs in one transaction on the target.
+type Clone struct {
+	Tables    []CloneTable // Referenced tables before the tables referencing them
+	Salt      string       // Salt of hashed values, random when empty
+	BatchSize int          // Rows per COPY, 1000 when zero
+}
+
+// CloneProgress reports the rows copied into a table after each batch
+type CloneProgress struct {
+	Table   string
+	Rows    int64
+	Elapsed time.Duration
+}
+
+// Validate checks that every mask names a column of its table that can hold
+// the masked values
+func (c Clone) Validate() error {
+	if len(c.Tables) == 0 {
+		return fmt.Errorf("clone has no tables")
+	}
+	if c.BatchSize < 0 {
+		return fmt.Errorf("clone batch size cannot be negative")
+	}
+	for _, table := range c.Tables {
+		columns := make(map[string]ImportColumn, len(table.Columns))
+		for _, column := range table.Columns {
+			columns[column.Name] = column
+		}
+		for name, mask := range table.Masks {
+			column, ok := columns[name]
+			if !ok {
+				return fmt.Errorf("mask %s: %s is not a column of %s", mask, name, table.Name)
+			}
+			switch mask.Kind {
+			case MaskNull:
+				if !column.Nullable {
+					return fmt.Errorf("mask null: column %s.%s is NOT NULL", table.Name, name)
+				}
+			case MaskEmail, MaskHash:
+				if !isTextType(column.Type) {
+					return fmt.Errorf("mask %s: column %s.%s is %s, not text", mask, table.Name, name, column.Type)
+				}
+			case MaskFixed, MaskKeep:
+			default:
+				return fmt.Errorf("column %s.%s has an unknown mask %q", table.Name, name, mask.Kind)
+			}
+		}
+	}
+	return nil
+}
+
+func isTextType(sqlType string) bool {
+	sqlType = strings.ToLower(strings.TrimSpace(sqlType))
+	for _, prefix := range []string{"text", "varchar", "character varying", "char", "character", "citext"} {
+		if sqlType == prefix || strings.HasPrefix(sqlType, prefix+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// Run copies the tables from source to target, calling progress, when set,
+// after every batch, and returns how many rows were copied
+func (c Clone) Run(ctx context.Context, source, target *sql.DB, progress func(CloneProgress)) (int64, error) {
+	if err := c.Validate(); err != nil {
+		return 0, err
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 1000
+	}
+	if c.Salt == "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return 0, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		c.Salt = hex.EncodeToString(salt)
+	}
+
+	tx, err := target.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	names := make([]string, len(c.Tables))
+	for i, table := range c.Tables {
+		names[i] = dialect.QuoteQualifiedIfNeeded(table.Name)
+	}
+	if _, err := tx.ExecContext(ctx, "TRUNCATE "+strings.Join(names, ", ")); err != nil {
+		return 0, fmt.Errorf("failed to empty target tables: %w", err)
+	}
+
+	var total int64
+	for _, table := range c.Tables {
+		count, err := c.copyTable(ctx, source, tx, table, progress)
+		if err != nil {
+			return 0, fmt.Errorf("failed to clone %s: %w", table.Name, err)
+		}
+		total += count
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit clone: %w", err)
+	}
+	return total, nil
+}
+
+// copyTable streams the rows of table from source into the target, masked,
+// and moves its sequences past the copied values
+func (c Clone) copyTable(ctx context.Context, source *sql.DB, tx *sql.Tx, table CloneTable, progress func(CloneProgress)) (int64, error) {
+	rows, err := source.QueryContext(ctx, table.query())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, len(table.Columns))
+	masks := make([]Mask, len(table.Columns))
+	for i, column := range table.Columns {
+		names[i] = column.Name
+		masks[i] = table.Masks[column.Name]
+	}
+
+	started := time.Now()
+	copied := CloneProgress{Table: table.Name}
+	batch := make([][]interface{}, 0, c.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := copyRows(ctx, tx, table.Name, names, batch); err != nil {
+			return fmt.Errorf("failed to load rows %d-%d: %w", copied.Rows+1, copied.Rows+int64(len(batch)), err)
+		}
+		copied.Rows += int64(len(batch))
+		copied.Elapsed = time.Since(started)
+		if progress != nil {
+			progress(copied)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	values := make([]sql.NullString, len(names))
+	targets := make([]interface{}, len(names))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return 0, fmt.Errorf("failed to read row: %w", err)
+		}
+		row := make([]interface{}, len(values))
+		for i, value := range values {
+			var text *string
+			if value.Valid {
+				text = &value.String
+			}
+			row[i] = masks[i].apply(text, c.Salt)
+		}
+		batch = append(batch, row)
+
+		if len(batch) == c.BatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	for _, column := range table.Serial {
+		quoted := dialect.QuoteIdentifierIfNeeded(column)
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			quoted, dialect.QuoteQualifiedIfNeeded(table.Name))
+		if _, err := tx.ExecContext(ctx, query, dialect.QuoteQualifiedIfNeeded(table.Name), column); err != nil {
+			return 0, fmt.Errorf("failed to reset the sequence of %s: %w", column, err)
+		}
+	}
+	return copied.Rows, nil
+}
+
+// query reads every column of the table as text, which COPY turns back into
+// the same values, ordered by the primary key so rows referencing earlier
+// rows of the table load after them
+func (t CloneTable) query() string {
+	columns := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		columns[i] = dialect.QuoteIdentifierIfNeeded(column.Name) + "::text"
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), dialect.QuoteQualifiedIfNeeded(t.Name))
+	if len(t.Key) > 0 {
+		keys := make([]string, len(t.Key))
+		for i, key := range t.Key {
+			keys[i] = dialect.QuoteIdentifierIfNeeded(key)
+		}
+		query += " ORDER BY " + strings.Join(keys, ", ")
+	}
+	return query
+}
@@ -0,0 +1,138 @@
+package transfer
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCloneRun(t *testing.T) {
+	source, sourceMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer source.Close()
+	target, targetMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer target.Close()
+
+	users := CloneTable{
+		Name:    "users",
+		Columns: []ImportColumn{{Name: "id", Type: "serial"}, {Name: "email", Type: "varchar(255)"}, {Name: "name", Type: "text"}, {Name: "api_token", Type: "text", Nullable: true}},
+		Key:     []string{"id"},
+		Serial:  []string{"id"},
+		Masks: map[string]Mask{
+			"email":     {Kind: MaskEmail},
+			"name":      {Kind: MaskHash},
+			"api_token": {Kind: MaskNull},
+		},
+	}
+	posts := CloneTable{
+		Name:    "posts",
+		Columns: []ImportColumn{{Name: "id", Type: "uuid"}, {Name: "user_id", Type: "integer"}, {Name: "title", Type: "text"}},
+		Key:     []string{"id"},
+	}
+
+	const salt = "staging"
+	adaEmail := "user_" + saltedHash(salt, "ada@example.org")[:12] + "@example.com"
+	adaName := saltedHash(salt, "Ada")[:16]
+
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(regexp.QuoteMeta("TRUNCATE users, posts")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT id::text, email::text, name::text, api_token::text FROM users ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "api_token"}).
+			AddRow("1", "ada@example.org", "Ada", "secret").
+			AddRow("2", "grace@example.org", "Grace", nil).
+			AddRow("3", "linus@example.org", "Linus", "token"))
+	copyUsers := regexp.QuoteMeta(`COPY "users" ("id", "email", "name", "api_token") FROM STDIN`)
+	targetMock.ExpectPrepare(copyUsers)
+	targetMock.ExpectExec(copyUsers).WithArgs("1", adaEmail, adaName, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(copyUsers).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(copyUsers).WillReturnResult(sqlmock.NewResult(0, 2))
+	targetMock.ExpectPrepare(copyUsers)
+	targetMock.ExpectExec(copyUsers).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(copyUsers).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(regexp.QuoteMeta("SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(id), 0) + 1, false) FROM users")).
+		WithArgs("users", "id").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT id::text, user_id::text, title::text FROM posts ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title"}))
+	targetMock.ExpectCommit()
+
+	clone := Clone{Tables: []CloneTable{users, posts}, Salt: salt, BatchSize: 2}
+	var batches []CloneProgress
+	count, err := clone.Run(context.Background(), source, target, func(p CloneProgress) {
+		batches = append(batches, p)
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if count != 3 || len(batches) != 2 || batches[1].Rows != 3 {
+		t.Errorf("expected 3 rows in 2 batches, got %d rows and %+v", count, batches)
+	}
+	if err := sourceMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet source expectations: %v", err)
+	}
+	if err := targetMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet target expectations: %v", err)
+	}
+}
+
+func TestCloneValidate(t *testing.T) {
+	table := CloneTable{
+		Name:    "users",
+		Columns: []ImportColumn{{Name: "email", Type: "varchar(255)"}, {Name: "age", Type: "integer", Nullable: true}},
+	}
+
+	tests := []struct {
+		masks map[string]Mask
+		err   string
+	}{
+		{masks: map[string]Mask{"email": {Kind: MaskEmail}, "age": {Kind: MaskNull}}},
+		{masks: map[string]Mask{"email": {Kind: MaskNull}}, err: "column users.email is NOT NULL"},
+		{masks: map[string]Mask{"age": {Kind: MaskHash}}, err: "column users.age is integer, not text"},
+		{masks: map[string]Mask{"phone": {Kind: MaskKeep}}, err: "phone is not a column of users"},
+	}
+
+	for _, tt := range tests {
+		table.Masks = tt.masks
+		err := Clone{Tables: []CloneTable{table}}.Validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%v: expected a valid clone, got %v", tt.masks, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%v: expected error %q, got %v", tt.masks, tt.err, err)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	mask, err := ParseMask("fixed:redacted")
+	if err != nil || mask != (Mask{Kind: MaskFixed, Value: "redacted"}) || mask.String() != "fixed:redacted" {
+		t.Errorf("expected a fixed mask, got %+v (%v)", mask, err)
+	}
+	if _, err := ParseMask("scramble"); err == nil || !strings.Contains(err.Error(), `unknown mask "scramble"`) {
+		t.Errorf("expected an unknown mask error, got %v", err)
+	}
+
+	value := "ada@example.org"
+	email := Mask{Kind: MaskEmail}
+	if first, second := email.apply(&value, "a"), email.apply(&value, "a"); first != second {
+		t.Errorf("expected equal values to mask alike, got %v and %v", first, second)
+	}
+	if first, second := email.apply(&value, "a"), email.apply(&value, "b"); first == second {
+		t.Errorf("expected the salt to change the masked value, got %v twice", first)
+	}
+	if got := (Mask{Kind: MaskHash}).apply(nil, "a"); got != nil {
+		t.Errorf("expected NULL to stay NULL, got %v", got)
+	}
+	if got := (Mask{Kind: MaskKeep}).apply(&value, "a"); got != value {
+		t.Errorf("expected the value kept, got %v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// Export streams the rows of a table, optionally filtered, to a writer as
+// CSV, JSON lines or Parquet
+type Export struct {
+	Table   string   // Optionally schema-qualified
+	Columns []string // All columns when empty
+	Where   string   // SQL condition the rows must match
+	OrderBy string   // SQL ORDER BY expression
+	Limit   int      // No limit when zero
+	Format  string   // csv when empty
+}
+
+// Validate checks that the export names a table and a known format
+func (e Export) Validate() error {
+	switch {
+	case strings.TrimSpace(e.Table) == "":
+		return fmt.Errorf("export table is required")
+	case e.Limit < 0:
+		return fmt.Errorf("export limit cannot be negative")
+	}
+	switch e.format() {
+	case FormatCSV, FormatJSONL, FormatParquet:
+		return nil
+	}
+	return fmt.Errorf("export format must be 'csv', 'jsonl' or 'parquet'")
+}
+
+func (e Export) format() string {
+	if e.Format == "" {
+		return FormatCSV
+	}
+	return strings.ToLower(e.Format)
+}
+
+// Query returns the SELECT statement reading the exported rows
+func (e Export) Query() string {
+	columns := "*"
+	if len(e.Columns) > 0 {
+		quoted := make([]string, len(e.Columns))
+		for i, column := range e.Columns {
+			quoted[i] = dialect.QuoteIdentifierIfNeeded(strings.TrimSpace(column))
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", columns, dialect.QuoteQualifiedIfNeeded(e.Table))
+	if strings.TrimSpace(e.Where) != "" {
+		query += " WHERE " + e.Where
+	}
+	if strings.TrimSpace(e.OrderBy) != "" {
+		query += " ORDER BY " + e.OrderBy
+	}
+	if e.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(e.Limit)
+	}
+	return query
+}
+
+// Run writes the rows to w as they are read and returns how many were
+// exported. JSON lines are built by the database with row_to_json; CSV and
+// Parquet are encoded here.
+func (e Export) Run(ctx context.Context, db *sql.DB, w io.Writer) (int64, error) {
+	if err := e.Validate(); err != nil {
+		return 0, err
+	}
+
+	switch e.format() {
+	case FormatJSONL:
+		return e.runJSONL(ctx, db, w)
+	case FormatParquet:
+		return e.runParquet(ctx, db, w)
+	}
+	return e.runCSV(ctx, db, w)
+}
+
+func (e Export) runJSONL(ctx context.Context, db *sql.DB, w io.Writer) (int64, error) {
+	query := fmt.Sprintf("SELECT row_to_json(export_row)::text FROM (%s) export_row", e.Query())
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", e.Table, err)
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var line []byte
+		if err := rows.Scan(&line); err != nil {
+			return count, fmt.Errorf("failed to read row: %w", err)
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+func (e Export) runCSV(ctx context.Context, db *sql.DB, w io.Writer) (int64, error) {
+	rows, err := db.QueryContext(ctx, e.Query())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", e.Table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	record := make([]string, len(columns))
+	err = scanRows(rows, len(columns), func(values []interface{}) error {
+		for i, value := range values {
+			record[i] = csvValue(value)
+		}
+		count++
+		return out.Write(record)
+	})
+	out.Flush()
+	if err != nil {
+		return count, err
+	}
+	return count, out.Error()
+}
+
+func (e Export) runParquet(ctx context.Context, db *sql.DB, w io.Writer) (int64, error) {
+	rows, err := db.QueryContext(ctx, e.Query())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", e.Table, err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]ParquetColumn, len(types))
+	for i, t := range types {
+		columns[i] = ParquetColumnFor(t.Name(), t.DatabaseTypeName())
+	}
+
+	out := NewParquetWriter(w, columns)
+	var count int64
+	if err := scanRows(rows, len(columns), func(values []interface{}) error {
+		count++
+		return out.WriteRow(values)
+	}); err != nil {
+		return count, err
+	}
+	return count, out.Close()
+}
+
+// CopyCSV writes the rows as CSV with a COPY run by psql, which is faster
+// than reading them through the driver. lib/pq does not support COPY TO, so
+// the CLI uses this when psql is installed.
+func (e Export) CopyCSV(ctx context.Context, databaseURL string, w io.Writer) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	statement := fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT csv, HEADER)", e.Query())
+	cmd := exec.CommandContext(ctx, "psql", "--no-psqlrc", "--quiet", "--set", "ON_ERROR_STOP=1", "--dbname", databaseURL, "--command", statement)
+	cmd.Stdout = w
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("psql COPY failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// scanRows calls row with the values of each row
+func scanRows(rows *sql.Rows, columns int, row func(values []interface{}) error) error {
+	values := make([]interface{}, columns)
+	targets := make([]interface{}, columns)
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+		if err := row(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// csvValue renders a CSV field, with NULL as an empty field and booleans as
+// t and f like COPY
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return string(toBytes(value))
+}
@@ -0,0 +1,94 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExportQuery(t *testing.T) {
+	export := Export{
+		Table:   "audit.events",
+		Columns: []string{"id", "user"},
+		Where:   "created_at > now() - interval '1 day'",
+		OrderBy: "id",
+		Limit:   10,
+	}
+
+	expected := `SELECT id, "user" FROM audit.events WHERE created_at > now() - interval '1 day' ORDER BY id LIMIT 10`
+	if got := export.Query(); got != expected {
+		t.Errorf("expected query %s, got %s", expected, got)
+	}
+	if got := (Export{Table: "users"}).Query(); got != "SELECT * FROM users" {
+		t.Errorf("expected a query of all columns, got %s", got)
+	}
+}
+
+func TestExportValidate(t *testing.T) {
+	if err := (Export{Table: "users", Format: "JSONL"}).Validate(); err != nil {
+		t.Errorf("expected a valid export, got %v", err)
+	}
+	if err := (Export{Table: "users", Format: "xml"}).Validate(); err == nil || !strings.Contains(err.Error(), "must be 'csv', 'jsonl' or 'parquet'") {
+		t.Errorf("expected a format error, got %v", err)
+	}
+	if err := (Export{}).Validate(); err == nil || !strings.Contains(err.Error(), "table is required") {
+		t.Errorf("expected a missing table error, got %v", err)
+	}
+}
+
+func TestExportRun(t *testing.T) {
+	t.Run("csv", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		created := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM users WHERE active")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "active", "created_at"}).
+				AddRow(int64(1), "Ada, Countess", true, created).
+				AddRow(int64(2), nil, false, created))
+
+		var out bytes.Buffer
+		count, err := Export{Table: "users", Where: "active"}.Run(context.Background(), db, &out)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		expected := "id,name,active,created_at\n" +
+			"1,\"Ada, Countess\",t,2024-03-04T05:06:07Z\n" +
+			"2,,f,2024-03-04T05:06:07Z\n"
+		if out.String() != expected {
+			t.Errorf("expected CSV:\n%s\ngot:\n%s", expected, out.String())
+		}
+		if count != 2 {
+			t.Errorf("expected 2 rows, got %d", count)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT row_to_json(export_row)::text FROM (SELECT id FROM users LIMIT 2) export_row")).
+			WillReturnRows(sqlmock.NewRows([]string{"row_to_json"}).AddRow(`{"id":1}`).AddRow(`{"id":2}`))
+
+		var out bytes.Buffer
+		count, err := Export{Table: "users", Columns: []string{"id"}, Limit: 2, Format: FormatJSONL}.Run(context.Background(), db, &out)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if out.String() != "{\"id\":1}\n{\"id\":2}\n" || count != 2 {
+			t.Errorf("unexpected JSON lines (%d rows):\n%s", count, out.String())
+		}
+	})
+}
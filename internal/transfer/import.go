@@ -0,0 +1,545 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+	"github.com/lib/pq"
+)
+
+// ImportColumn is a column of the table an import loads, as declared by its
+// model
+type ImportColumn struct {
+	Name       string
+	Type       string // SQL type, e.g. varchar(255) or timestamptz
+	Nullable   bool
+	HasDefault bool // Filled by the database when the input leaves it out
+}
+
+// Import loads CSV or JSON lines into a table with COPY, a batch at a time,
+// checking every value against the type of its column first. With Upsert
+// set, each batch is copied into a temporary table and merged with
+// INSERT ... ON CONFLICT on those columns. The import runs in one
+// transaction, so an invalid row leaves the table as it was.
+type Import struct {
+	Table      string            // Optionally schema-qualified
+	Format     string            // csv or jsonl
+	Columns    []ImportColumn    // Columns of the model of the table
+	UniqueKeys [][]string        // Primary and unique keys of the model
+	Mapping    map[string]string // Input field to column, for fields named differently
+	Ignore     []string          // Input fields to skip
+	Upsert     []string          // Conflict columns, a unique key of the model; insert only when empty
+	BatchSize  int               // Rows per COPY, 1000 when zero
+}
+
+// ImportProgress reports the rows loaded after each batch
+type ImportProgress struct {
+	Batches int
+	Rows    int64
+	Elapsed time.Duration
+}
+
+// Validate checks that the import names a table, its columns and a known
+// format, and that the upsert columns form a unique key
+func (im Import) Validate() error {
+	switch {
+	case strings.TrimSpace(im.Table) == "":
+		return fmt.Errorf("import table is required")
+	case len(im.Columns) == 0:
+		return fmt.Errorf("import table %s has no model columns", im.Table)
+	case im.BatchSize < 0:
+		return fmt.Errorf("import batch size cannot be negative")
+	}
+	switch strings.ToLower(im.Format) {
+	case FormatCSV, FormatJSONL:
+	default:
+		return fmt.Errorf("import format must be 'csv' or 'jsonl'")
+	}
+
+	if len(im.Upsert) > 0 && !im.isUniqueKey(im.Upsert) {
+		return fmt.Errorf("upsert columns (%s) are not a primary or unique key of %s", strings.Join(im.Upsert, ", "), im.Table)
+	}
+	return nil
+}
+
+func (im Import) isUniqueKey(columns []string) bool {
+	for _, key := range im.UniqueKeys {
+		if len(key) != len(columns) {
+			continue
+		}
+		matched := true
+		for _, column := range columns {
+			found := false
+			for _, keyColumn := range key {
+				found = found || keyColumn == column
+			}
+			matched = matched && found
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// importSource reads the records of the input, each as a map of field to
+// value with nil for NULL
+type importSource interface {
+	fields() []string
+	next() (map[string]interface{}, error)
+}
+
+// Run loads the rows read from r, calling progress, when set, after every
+// batch, and returns how many rows were loaded
+func (im Import) Run(ctx context.Context, db *sql.DB, r io.Reader, progress func(ImportProgress)) (int64, error) {
+	if err := im.Validate(); err != nil {
+		return 0, err
+	}
+	if im.BatchSize == 0 {
+		im.BatchSize = 1000
+	}
+
+	source, err := newImportSource(strings.ToLower(im.Format), r)
+	if err != nil {
+		return 0, err
+	}
+	columns, err := im.targetColumns(source)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	copyTable, err := im.prepare(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	started := time.Now()
+	loaded := ImportProgress{}
+	batch := make([][]interface{}, 0, im.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.load(ctx, tx, copyTable, columns, batch); err != nil {
+			return fmt.Errorf("failed to load rows %d-%d: %w", loaded.Rows+1, loaded.Rows+int64(len(batch)), err)
+		}
+		loaded.Batches++
+		loaded.Rows += int64(len(batch))
+		loaded.Elapsed = time.Since(started)
+		if progress != nil {
+			progress(loaded)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for row := 1; ; row++ {
+		record, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			value, err := convertImportValue(column.column, record[column.field])
+			if err != nil {
+				return 0, fmt.Errorf("row %d: column %s: %w", row, column.column.Name, err)
+			}
+			values[i] = value
+		}
+		batch = append(batch, values)
+
+		if len(batch) == im.BatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return loaded.Rows, nil
+}
+
+// importTarget pairs an input field with the column it loads
+type importTarget struct {
+	field  string
+	column ImportColumn
+}
+
+// targetColumns maps the input fields to model columns, refusing fields
+// that are not columns and required columns the input leaves out
+func (im Import) targetColumns(source importSource) ([]importTarget, error) {
+	byName := make(map[string]ImportColumn, len(im.Columns))
+	for _, column := range im.Columns {
+		byName[column.Name] = column
+	}
+	ignored := make(map[string]bool, len(im.Ignore))
+	for _, field := range im.Ignore {
+		ignored[field] = true
+	}
+
+	var targets []importTarget
+	mapped := make(map[string]bool)
+	for _, field := range source.fields() {
+		if ignored[field] {
+			continue
+		}
+		name := field
+		if column, ok := im.Mapping[field]; ok {
+			name = column
+		}
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("input field %s is not a column of %s; map it with --map or skip it with --ignore", field, im.Table)
+		}
+		if mapped[name] {
+			return nil, fmt.Errorf("column %s is loaded from more than one input field", name)
+		}
+		mapped[name] = true
+		targets = append(targets, importTarget{field: field, column: column})
+	}
+
+	for _, column := range im.Columns {
+		if !mapped[column.Name] && !column.Nullable && !column.HasDefault {
+			return nil, fmt.Errorf("column %s is NOT NULL without a default but is missing from the input", column.Name)
+		}
+	}
+	for _, column := range im.Upsert {
+		if !mapped[column] {
+			return nil, fmt.Errorf("upsert column %s is missing from the input", column)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("the input has no fields to load")
+	}
+	return targets, nil
+}
+
+// prepare returns the table batches are copied into: the table itself, or
+// for an upsert a temporary table shaped like it
+func (im Import) prepare(ctx context.Context, tx *sql.Tx) (string, error) {
+	if len(im.Upsert) == 0 {
+		return im.Table, nil
+	}
+	staging := "storm_import_" + strings.NewReplacer(".", "_", `"`, "").Replace(im.Table)
+	query := fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP",
+		dialect.QuoteIdentifier(staging), dialect.QuoteQualifiedIfNeeded(im.Table))
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return "", fmt.Errorf("failed to create staging table: %w", err)
+	}
+	return staging, nil
+}
+
+// load copies a batch into copyTable and, for an upsert, merges it into the
+// table
+func (im Import) load(ctx context.Context, tx *sql.Tx, copyTable string, columns []importTarget, batch [][]interface{}) error {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.column.Name
+	}
+
+	if err := copyRows(ctx, tx, copyTable, names, batch); err != nil {
+		return err
+	}
+
+	if len(im.Upsert) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, im.upsertQuery(copyTable, names)); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "TRUNCATE "+dialect.QuoteIdentifier(copyTable))
+	return err
+}
+
+// copyRows loads rows into the columns of table, optionally
+// schema-qualified, with a single COPY
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]interface{}) error {
+	statement := pq.CopyIn(table, columns...)
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		statement = pq.CopyInSchema(strings.Trim(schema, `"`), strings.Trim(name, `"`), columns...)
+	}
+	stmt, err := tx.PrepareContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	for _, values := range rows {
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// upsertQuery merges the staging table into the table, updating the rows
+// whose upsert columns already exist
+func (im Import) upsertQuery(staging string, names []string) string {
+	quoted := make([]string, len(names))
+	var updates []string
+	for i, name := range names {
+		quoted[i] = dialect.QuoteIdentifierIfNeeded(name)
+		isKey := false
+		for _, key := range im.Upsert {
+			isKey = isKey || key == name
+		}
+		if !isKey {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted[i], quoted[i]))
+		}
+	}
+	keys := make([]string, len(im.Upsert))
+	for i, key := range im.Upsert {
+		keys[i] = dialect.QuoteIdentifierIfNeeded(key)
+	}
+
+	action := "DO NOTHING"
+	if len(updates) > 0 {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	columns := strings.Join(quoted, ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT (%s) %s",
+		dialect.QuoteQualifiedIfNeeded(im.Table), columns, columns, dialect.QuoteIdentifier(staging), strings.Join(keys, ", "), action)
+}
+
+func newImportSource(format string, r io.Reader) (importSource, error) {
+	if format == FormatJSONL {
+		return newJSONLSource(r)
+	}
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	return &csvSource{reader: reader, header: append([]string(nil), header...)}, nil
+}
+
+// csvSource reads CSV with a header row. Empty fields are NULL, as with COPY.
+type csvSource struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (s *csvSource) fields() []string { return s.header }
+
+func (s *csvSource) next() (map[string]interface{}, error) {
+	fields, err := s.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	record := make(map[string]interface{}, len(fields))
+	for i, field := range fields {
+		if field != "" {
+			record[s.header[i]] = field
+		}
+	}
+	return record, nil
+}
+
+// jsonlSource reads one JSON object per line. Its fields are those of the
+// first object; fields missing from a later object are NULL.
+type jsonlSource struct {
+	decoder *json.Decoder
+	first   map[string]interface{}
+	keys    []string
+}
+
+func newJSONLSource(r io.Reader) (*jsonlSource, error) {
+	s := &jsonlSource{decoder: json.NewDecoder(r)}
+	s.decoder.UseNumber()
+
+	var raw json.RawMessage
+	if err := s.decoder.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read the first JSON line: %w", err)
+	}
+	first, keys, err := decodeJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+	s.first, s.keys = first, keys
+	return s, nil
+}
+
+func (s *jsonlSource) fields() []string { return s.keys }
+
+func (s *jsonlSource) next() (map[string]interface{}, error) {
+	if s.first != nil {
+		record := s.first
+		s.first = nil
+		return record, nil
+	}
+	var raw json.RawMessage
+	if err := s.decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	record, _, err := decodeJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+	for key := range record {
+		if !contains(s.keys, key) {
+			return nil, fmt.Errorf("field %s is not in the first line", key)
+		}
+	}
+	return record, nil
+}
+
+// decodeJSONObject decodes an object and its keys in the order written
+func decodeJSONObject(raw json.RawMessage) (map[string]interface{}, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected a JSON object")
+	}
+
+	record := make(map[string]interface{})
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := token.(string)
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		if value != nil {
+			record[key] = value
+		}
+	}
+	return record, keys, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var uuidPattern = regexp.MustCompile(`^(?i)[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$`)
+
+// timeLayouts are the layouts accepted for date and timestamp columns
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// convertImportValue checks a value against the type of its column and
+// returns it as it is sent to COPY
+func convertImportValue(column ImportColumn, value interface{}) (interface{}, error) {
+	if value == nil {
+		if !column.Nullable {
+			return nil, fmt.Errorf("NULL in a NOT NULL column")
+		}
+		return nil, nil
+	}
+
+	sqlType := strings.ToLower(strings.TrimSpace(column.Type))
+	if strings.HasSuffix(sqlType, "[]") {
+		if list, ok := value.([]interface{}); ok {
+			return pq.GenericArray{A: list}.Value()
+		}
+		return fmt.Sprint(value), nil
+	}
+	if i := strings.IndexByte(sqlType, '('); i >= 0 {
+		sqlType = strings.TrimSpace(sqlType[:i])
+	}
+
+	text, isText := value.(string)
+	switch sqlType {
+	case "smallint", "integer", "int", "int2", "int4", "bigint", "int8", "serial", "smallserial", "bigserial":
+		n, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v is not an integer", value)
+		}
+		return n, nil
+	case "numeric", "decimal", "real", "double precision", "float4", "float8":
+		s := fmt.Sprint(value)
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("%v is not a number", value)
+		}
+		return s, nil
+	case "boolean", "bool":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		b, err := toBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%v is not a boolean", value)
+		}
+		return b, nil
+	case "uuid":
+		if !isText || !uuidPattern.MatchString(text) {
+			return nil, fmt.Errorf("%v is not a UUID", value)
+		}
+		return text, nil
+	case "date", "timestamp", "timestamptz", "timestamp with time zone", "timestamp without time zone":
+		if isText {
+			for _, layout := range timeLayouts {
+				if _, err := time.Parse(layout, text); err == nil {
+					return text, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%v is not a date or time", value)
+	case "json", "jsonb":
+		if isText && json.Valid([]byte(text)) {
+			return text, nil
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(encoded), nil
+	}
+
+	if !isText {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("a JSON %T cannot be loaded into a %s column", value, column.Type)
+		}
+		return fmt.Sprint(value), nil
+	}
+	return text, nil
+}
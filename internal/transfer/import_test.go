@@ -0,0 +1,185 @@
+package transfer
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var importUserColumns = []ImportColumn{
+	{Name: "id", Type: "serial", HasDefault: true},
+	{Name: "email", Type: "varchar(255)"},
+	{Name: "name", Type: "text", Nullable: true},
+	{Name: "age", Type: "integer", Nullable: true},
+	{Name: "settings", Type: "jsonb", Nullable: true},
+}
+
+func TestImportRun(t *testing.T) {
+	t.Run("copies csv in batches", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		input := "email_address,name,age,notes\n" +
+			"ada@example.com,Ada,36,first\n" +
+			"grace@example.com,,85,\n" +
+			"linus@example.com,Linus,,\n"
+
+		copyIn := regexp.QuoteMeta(`COPY "users" ("email", "name", "age") FROM STDIN`)
+		mock.ExpectBegin()
+		mock.ExpectPrepare(copyIn)
+		mock.ExpectExec(copyIn).WithArgs("ada@example.com", "Ada", int64(36)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(copyIn).WithArgs("grace@example.com", nil, int64(85)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(copyIn).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectPrepare(copyIn)
+		mock.ExpectExec(copyIn).WithArgs("linus@example.com", "Linus", nil).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(copyIn).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		im := Import{
+			Table:     "users",
+			Format:    FormatCSV,
+			Columns:   importUserColumns,
+			Mapping:   map[string]string{"email_address": "email"},
+			Ignore:    []string{"notes"},
+			BatchSize: 2,
+		}
+		var batches []ImportProgress
+		count, err := im.Run(context.Background(), db, strings.NewReader(input), func(p ImportProgress) {
+			batches = append(batches, p)
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if count != 3 || len(batches) != 2 || batches[1].Rows != 3 {
+			t.Errorf("expected 3 rows in 2 batches, got %d rows and %+v", count, batches)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("upserts jsonl through a staging table", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		input := `{"email": "ada@example.com", "name": "Ada", "settings": {"theme": "dark"}}` + "\n" +
+			`{"email": "grace@example.com", "name": null}` + "\n"
+
+		copyIn := regexp.QuoteMeta(`COPY "storm_import_users" ("email", "name", "settings") FROM STDIN`)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TEMP TABLE "storm_import_users" (LIKE users INCLUDING DEFAULTS) ON COMMIT DROP`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(copyIn)
+		mock.ExpectExec(copyIn).WithArgs("ada@example.com", "Ada", `{"theme":"dark"}`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(copyIn).WithArgs("grace@example.com", nil, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(copyIn).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users (email, name, settings) SELECT email, name, settings FROM "storm_import_users" ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, settings = EXCLUDED.settings`)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`TRUNCATE "storm_import_users"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		im := Import{
+			Table:      "users",
+			Format:     FormatJSONL,
+			Columns:    importUserColumns,
+			UniqueKeys: [][]string{{"id"}, {"email"}},
+			Upsert:     []string{"email"},
+		}
+		count, err := im.Run(context.Background(), db, strings.NewReader(input), nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("expected 2 rows, got %d", count)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("rolls back on an invalid value", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		input := "email,age\nada@example.com,36\ngrace@example.com,eighty\n"
+		im := Import{Table: "users", Format: FormatCSV, Columns: importUserColumns}
+		_, err = im.Run(context.Background(), db, strings.NewReader(input), nil)
+		if err == nil || !strings.Contains(err.Error(), "row 2: column age: eighty is not an integer") {
+			t.Errorf("expected an invalid integer error, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+}
+
+func TestImportValidate(t *testing.T) {
+	im := Import{Table: "users", Format: FormatCSV, Columns: importUserColumns, UniqueKeys: [][]string{{"id"}, {"email"}}, Upsert: []string{"name"}}
+	if err := im.Validate(); err == nil || !strings.Contains(err.Error(), "not a primary or unique key") {
+		t.Errorf("expected an upsert key error, got %v", err)
+	}
+
+	im.Upsert = []string{"email"}
+	if err := im.Validate(); err != nil {
+		t.Errorf("expected a valid import, got %v", err)
+	}
+
+	source, err := newImportSource(FormatCSV, strings.NewReader("name,nickname\n"))
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if _, err := im.targetColumns(source); err == nil || !strings.Contains(err.Error(), "input field nickname is not a column of users") {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+
+	im.Ignore = []string{"nickname"}
+	if _, err := im.targetColumns(source); err == nil || !strings.Contains(err.Error(), "column email is NOT NULL without a default") {
+		t.Errorf("expected a missing column error, got %v", err)
+	}
+}
+
+func TestConvertImportValue(t *testing.T) {
+	tests := []struct {
+		column   ImportColumn
+		value    interface{}
+		expected interface{}
+		err      string
+	}{
+		{column: ImportColumn{Type: "bigint"}, value: "42", expected: int64(42)},
+		{column: ImportColumn{Type: "numeric(10,2)"}, value: "19.99", expected: "19.99"},
+		{column: ImportColumn{Type: "boolean"}, value: "t", expected: true},
+		{column: ImportColumn{Type: "uuid"}, value: "not-a-uuid", err: "is not a UUID"},
+		{column: ImportColumn{Type: "timestamptz"}, value: "2024-03-04 05:06:07+00", expected: "2024-03-04 05:06:07+00"},
+		{column: ImportColumn{Type: "date"}, value: "04/03/2024", err: "is not a date or time"},
+		{column: ImportColumn{Type: "text[]"}, value: []interface{}{"a", "b"}, expected: `{"a","b"}`},
+		{column: ImportColumn{Type: "text"}, value: nil, err: "NULL in a NOT NULL column"},
+	}
+
+	for _, tt := range tests {
+		got, err := convertImportValue(tt.column, tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("convert %v to %s: expected error %q, got %v", tt.value, tt.column.Type, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("convert %v to %s: expected %v, got %v (%v)", tt.value, tt.column.Type, tt.expected, got, err)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet converted types, -1 for none
+const (
+	parquetNoConversion    = -1
+	parquetUTF8            = 0
+	parquetDate            = 6
+	parquetTimestampMicros = 10
+)
+
+const (
+	parquetMagic       = "PAR1"
+	parquetEncPlain    = 0
+	parquetEncRLE      = 3
+	parquetOptional    = 1
+	parquetDataPage    = 0
+	parquetCreatedBy   = "storm export"
+	parquetRowGroupLen = 10000
+)
+
+// ParquetColumn describes a column of a Parquet file. Every column is
+// optional, holding NULLs through definition levels.
+type ParquetColumn struct {
+	Name      string
+	Type      int
+	Converted int
+}
+
+// ParquetColumnFor maps a PostgreSQL type name, as reported by
+// sql.ColumnType.DatabaseTypeName, to a Parquet column. Types without a
+// Parquet counterpart, numeric included to keep its precision, are written
+// as UTF-8 strings.
+func ParquetColumnFor(name, databaseType string) ParquetColumn {
+	column := ParquetColumn{Name: name, Type: parquetByteArray, Converted: parquetUTF8}
+	switch strings.ToUpper(databaseType) {
+	case "BOOL":
+		column.Type, column.Converted = parquetBoolean, parquetNoConversion
+	case "INT2", "INT4":
+		column.Type, column.Converted = parquetInt32, parquetNoConversion
+	case "INT8":
+		column.Type, column.Converted = parquetInt64, parquetNoConversion
+	case "FLOAT4", "FLOAT8":
+		column.Type, column.Converted = parquetDouble, parquetNoConversion
+	case "DATE":
+		column.Type, column.Converted = parquetInt32, parquetDate
+	case "TIMESTAMP", "TIMESTAMPTZ":
+		column.Type, column.Converted = parquetInt64, parquetTimestampMicros
+	case "BYTEA":
+		column.Converted = parquetNoConversion
+	}
+	return column
+}
+
+// ParquetWriter streams rows into an uncompressed Parquet file, writing a
+// row group every parquetRowGroupLen rows so memory stays bounded
+type ParquetWriter struct {
+	w         io.Writer
+	columns   []ParquetColumn
+	buffered  []parquetColumnBuffer
+	rows      int
+	offset    int64
+	totalRows int64
+	groups    []parquetRowGroup
+	started   bool
+}
+
+type parquetColumnBuffer struct {
+	defined []bool
+	values  bytes.Buffer
+	bits    int // Booleans packed into the last byte of values
+}
+
+type parquetRowGroup struct {
+	rows   int64
+	size   int64
+	chunks []parquetChunk
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+func NewParquetWriter(w io.Writer, columns []ParquetColumn) *ParquetWriter {
+	return &ParquetWriter{
+		w:        w,
+		columns:  columns,
+		buffered: make([]parquetColumnBuffer, len(columns)),
+	}
+}
+
+// WriteRow adds a row holding one value per column, nil for NULL
+func (p *ParquetWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(p.columns) {
+		return fmt.Errorf("parquet row has %d values for %d columns", len(values), len(p.columns))
+	}
+	for i, value := range values {
+		if err := p.buffered[i].add(p.columns[i], value); err != nil {
+			return fmt.Errorf("column %s: %w", p.columns[i].Name, err)
+		}
+	}
+	p.rows++
+	if p.rows >= parquetRowGroupLen {
+		return p.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer
+func (p *ParquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	footer := p.footer()
+	var trailer [4]byte
+	binary.LittleEndian.PutUint32(trailer[:], uint32(len(footer)))
+	footer = append(footer, trailer[:]...)
+	footer = append(footer, parquetMagic...)
+	_, err := p.w.Write(footer)
+	return err
+}
+
+func (p *ParquetWriter) start() error {
+	if p.started {
+		return nil
+	}
+	p.started = true
+	p.offset = int64(len(parquetMagic))
+	_, err := io.WriteString(p.w, parquetMagic)
+	return err
+}
+
+// flush writes the buffered rows as a row group of one data page per column
+func (p *ParquetWriter) flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	group := parquetRowGroup{rows: int64(p.rows)}
+	for i := range p.buffered {
+		page := p.buffered[i].page()
+		chunk := parquetChunk{offset: p.offset, size: int64(len(page)), values: int64(p.rows)}
+		if _, err := p.w.Write(page); err != nil {
+			return err
+		}
+		p.offset += chunk.size
+		group.size += chunk.size
+		group.chunks = append(group.chunks, chunk)
+		p.buffered[i] = parquetColumnBuffer{}
+	}
+
+	p.groups = append(p.groups, group)
+	p.totalRows += int64(p.rows)
+	p.rows = 0
+	return nil
+}
+
+func (b *parquetColumnBuffer) add(column ParquetColumn, value interface{}) error {
+	if value == nil {
+		b.defined = append(b.defined, false)
+		return nil
+	}
+
+	switch column.Type {
+	case parquetBoolean:
+		v, err := toBool(value)
+		if err != nil {
+			return err
+		}
+		if b.bits%8 == 0 {
+			b.values.WriteByte(0)
+		}
+		if v {
+			raw := b.values.Bytes()
+			raw[len(raw)-1] |= 1 << (b.bits % 8)
+		}
+		b.bits++
+	case parquetInt32:
+		var v int64
+		var err error
+		if column.Converted == parquetDate {
+			var t time.Time
+			t, err = toTime(value)
+			v = int64(math.Floor(float64(t.Unix()) / 86400))
+		} else {
+			v, err = toInt(value)
+		}
+		if err != nil {
+			return err
+		}
+		binary.Write(&b.values, binary.LittleEndian, int32(v))
+	case parquetInt64:
+		var v int64
+		var err error
+		if column.Converted == parquetTimestampMicros {
+			var t time.Time
+			t, err = toTime(value)
+			v = t.UnixMicro()
+		} else {
+			v, err = toInt(value)
+		}
+		if err != nil {
+			return err
+		}
+		binary.Write(&b.values, binary.LittleEndian, v)
+	case parquetDouble:
+		v, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+		binary.Write(&b.values, binary.LittleEndian, math.Float64bits(v))
+	default:
+		raw := toBytes(value)
+		binary.Write(&b.values, binary.LittleEndian, uint32(len(raw)))
+		b.values.Write(raw)
+	}
+	b.defined = append(b.defined, true)
+	return nil
+}
+
+// page encodes the buffered values as a data page: the definition levels,
+// RLE encoded with a bit width of 1, followed by the PLAIN encoded values
+func (b *parquetColumnBuffer) page() []byte {
+	var levels bytes.Buffer
+	for i := 0; i < len(b.defined); {
+		run := 1
+		for i+run < len(b.defined) && b.defined[i+run] == b.defined[i] {
+			run++
+		}
+		levels.Write(binary.AppendUvarint(nil, uint64(run)<<1))
+		if b.defined[i] {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		i += run
+	}
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, uint32(levels.Len()))
+	data.Write(levels.Bytes())
+	data.Write(b.values.Bytes())
+
+	var header thriftWriter
+	header.i32(1, parquetDataPage)
+	header.i32(2, int32(data.Len()))
+	header.i32(3, int32(data.Len()))
+	header.structBegin(5)
+	header.i32(1, int32(len(b.defined)))
+	header.i32(2, parquetEncPlain)
+	header.i32(3, parquetEncRLE)
+	header.i32(4, parquetEncRLE)
+	header.structEnd()
+	header.stop()
+
+	return append(header.buf.Bytes(), data.Bytes()...)
+}
+
+// footer encodes the FileMetaData of the file
+func (p *ParquetWriter) footer() []byte {
+	var t thriftWriter
+	t.i32(1, 1)
+
+	t.listBegin(2, thriftStruct, len(p.columns)+1)
+	t.elemBegin()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(p.columns)))
+	t.elemEnd()
+	for _, column := range p.columns {
+		t.elemBegin()
+		t.i32(1, int32(column.Type))
+		t.i32(3, parquetOptional)
+		t.binary(4, []byte(column.Name))
+		if column.Converted != parquetNoConversion {
+			t.i32(6, int32(column.Converted))
+		}
+		t.elemEnd()
+	}
+
+	t.i64(3, p.totalRows)
+
+	t.listBegin(4, thriftStruct, len(p.groups))
+	for _, group := range p.groups {
+		t.elemBegin()
+		t.listBegin(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			t.elemBegin()
+			t.i64(2, chunk.offset)
+			t.structBegin(3)
+			t.i32(1, int32(p.columns[i].Type))
+			t.listBegin(2, thriftI32, 2)
+			t.varint(zigzag(parquetEncPlain))
+			t.varint(zigzag(parquetEncRLE))
+			t.listBegin(3, thriftBinary, 1)
+			t.varint(uint64(len(p.columns[i].Name)))
+			t.buf.WriteString(p.columns[i].Name)
+			t.i32(4, 0)
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.structEnd()
+			t.elemEnd()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.rows)
+		t.elemEnd()
+	}
+
+	t.binary(6, []byte(parquetCreatedBy))
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the structs of Parquet metadata with the Thrift
+// compact protocol
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+func (t *thriftWriter) listBegin(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elem)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) structEnd() {
+	t.elemEnd()
+}
+
+// elemBegin starts a struct nested in a list or field
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case []byte:
+		return toBool(string(v))
+	case string:
+		switch strings.ToLower(v) {
+		case "t", "true", "1":
+			return true, nil
+		case "f", "false", "0":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("cannot convert %T %v to a boolean", value, value)
+}
+
+func toInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T %v to an integer", value, value)
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T %v to a float", value, value)
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	if t, ok := value.(time.Time); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %T %v to a time", value, value)
+}
+
+// toBytes renders a value as text, times in RFC 3339
+func toBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case time.Time:
+		return []byte(v.Format(time.RFC3339Nano))
+	}
+	return []byte(fmt.Sprint(value))
+}
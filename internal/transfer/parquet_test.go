@@ -0,0 +1,58 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestParquetWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewParquetWriter(&out, []ParquetColumn{ParquetColumnFor("id", "INT8"), ParquetColumnFor("name", "TEXT")})
+	for _, row := range [][]interface{}{{int64(1), "ada"}, {int64(2), nil}} {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data := out.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("expected the file to start and end with PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	for _, want := range []string{"schema", "id", "name", "storm export"} {
+		if !bytes.Contains(footer, []byte(want)) {
+			t.Errorf("expected the footer to contain %q", want)
+		}
+	}
+
+	if err := w.WriteRow([]interface{}{int64(1)}); err == nil {
+		t.Errorf("expected an error for a row with too few values")
+	}
+}
+
+func TestParquetColumnBufferPage(t *testing.T) {
+	var b parquetColumnBuffer
+	column := ParquetColumnFor("active", "BOOL")
+	for _, value := range []interface{}{true, true, nil, false, "t"} {
+		if err := b.add(column, value); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+
+	page := b.page()
+	// Definition levels: runs of 2 defined, 1 null and 2 defined, then the
+	// four booleans packed LSB first
+	levels := []byte{6, 0, 0, 0, 4, 1, 2, 0, 4, 1, 0x0b}
+	if !bytes.HasSuffix(page, levels) {
+		t.Errorf("expected the page to end with % x, got % x", levels, page)
+	}
+
+	if err := b.add(column, 3.5); err == nil {
+		t.Errorf("expected an error converting a float to a boolean")
+	}
+}
@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config holds the queue settings
+type Config struct {
+	// Table is the jobs table, optionally schema-qualified
+	Table string
+	// VisibilityTimeout is how long a claimed job stays hidden from other workers
+	VisibilityTimeout time.Duration
+	// MaxAttempts is the attempt limit for jobs enqueued without one
+	MaxAttempts int
+	// PollInterval is how long Poll waits when the queue is empty
+	PollInterval time.Duration
+	// BatchSize is the number of jobs Poll claims at once
+	BatchSize int
+	// Backoff returns the delay before a failed job is retried, given the attempts so far
+	Backoff func(attempt int) time.Duration
+}
+
+// Option configures a Queue
+type Option func(*Config) error
+
+func defaultConfig() Config {
+	return Config{
+		Table:             DefaultTable,
+		VisibilityTimeout: 5 * time.Minute,
+		MaxAttempts:       5,
+		PollInterval:      time.Second,
+		BatchSize:         10,
+		Backoff:           ExponentialBackoff(time.Second, time.Hour),
+	}
+}
+
+// WithTable sets the jobs table
+func WithTable(table string) Option {
+	return func(c *Config) error {
+		if table == "" {
+			return fmt.Errorf("table cannot be empty")
+		}
+		c.Table = table
+		return nil
+	}
+}
+
+// WithVisibilityTimeout sets how long a claimed job stays locked
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(c *Config) error {
+		if d <= 0 {
+			return fmt.Errorf("visibility timeout must be positive")
+		}
+		c.VisibilityTimeout = d
+		return nil
+	}
+}
+
+// WithMaxAttempts sets the default attempt limit
+func WithMaxAttempts(max int) Option {
+	return func(c *Config) error {
+		if max <= 0 {
+			return fmt.Errorf("max attempts must be positive")
+		}
+		c.MaxAttempts = max
+		return nil
+	}
+}
+
+// WithPollInterval sets how long Poll sleeps when no job is due
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Config) error {
+		if d <= 0 {
+			return fmt.Errorf("poll interval must be positive")
+		}
+		c.PollInterval = d
+		return nil
+	}
+}
+
+// WithBatchSize sets how many jobs Poll claims at once
+func WithBatchSize(size int) Option {
+	return func(c *Config) error {
+		if size <= 0 {
+			return fmt.Errorf("batch size must be positive")
+		}
+		c.BatchSize = size
+		return nil
+	}
+}
+
+// WithBackoff sets the retry delay policy
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(c *Config) error {
+		if backoff == nil {
+			return fmt.Errorf("backoff cannot be nil")
+		}
+		c.Backoff = backoff
+		return nil
+	}
+}
+
+// ExponentialBackoff doubles the delay after every attempt, starting at base
+// and never exceeding max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt; i++ {
+			delay *= 2
+			if delay >= max {
+				return max
+			}
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
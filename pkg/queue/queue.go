@@ -0,0 +1,233 @@
+// Package queue is a PostgreSQL-backed job queue. Workers claim jobs with
+// SELECT ... FOR UPDATE SKIP LOCKED, so any number of them can poll the same
+// table without blocking each other.
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+
+const (
+	// DefaultTable is the name of the jobs table
+	DefaultTable = "storm_jobs"
+	// DefaultQueue is the queue jobs go to when none is set
+	DefaultQueue = "default"
+)
+
+// ErrJobNotFound is returned when a job no longer exists or is not held by the caller
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is a row of the jobs table
+type Job struct {
+	ID          int64           `db:"id" json:"id"`
+	Queue       string          `db:"queue" json:"queue"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	MaxAttempts int             `db:"max_attempts" json:"max_attempts"`
+	RunAt       time.Time       `db:"run_at" json:"run_at"`
+	LockedUntil *time.Time      `db:"locked_until" json:"locked_until,omitempty"`
+	LastError   *string         `db:"last_error" json:"last_error,omitempty"`
+	FailedAt    *time.Time      `db:"failed_at" json:"failed_at,omitempty"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+}
+
+// NewJob returns a job for queueName carrying payload encoded as JSON
+func NewJob(queueName string, payload interface{}) (Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	return Job{Queue: queueName, Payload: data}, nil
+}
+
+// Decode unmarshals the job payload into v
+func (j Job) Decode(v interface{}) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode payload of job %d: %w", j.ID, err)
+	}
+	return nil
+}
+
+// Queue enqueues and claims jobs stored in a single table
+type Queue struct {
+	db     orm.DBExecutor
+	config Config
+	table  string
+}
+
+// New returns a queue backed by db
+func New(db orm.DBExecutor, opts ...Option) (*Queue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	config := defaultConfig()
+	for _, opt := range opts {
+		if err := opt(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Queue{
+		db:     db,
+		config: config,
+		table:  dialect.QuoteQualifiedIfNeeded(config.Table),
+	}, nil
+}
+
+// WithExecutor returns a copy of the queue that runs its statements on executor,
+// typically a transaction so jobs are enqueued atomically with other writes
+func (q *Queue) WithExecutor(executor orm.DBExecutor) *Queue {
+	clone := *q
+	clone.db = executor
+	return &clone
+}
+
+// Schema returns the statements that create the jobs table and its polling index
+func (q *Queue) Schema() string {
+	index := dialect.QuoteIdentifierIfNeeded(indexName(q.config.Table))
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id BIGSERIAL PRIMARY KEY,
+	queue TEXT NOT NULL DEFAULT '%[3]s',
+	payload JSONB NOT NULL DEFAULT '{}',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT %[4]d,
+	run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMPTZ,
+	last_error TEXT,
+	failed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (queue, run_at) WHERE failed_at IS NULL;`,
+		q.table, index, DefaultQueue, q.config.MaxAttempts)
+}
+
+// EnsureTable creates the jobs table when it does not exist yet
+func (q *Queue) EnsureTable(ctx context.Context) error {
+	for _, statement := range strings.SplitAfter(q.Schema(), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := q.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create jobs table %s: %w", q.config.Table, err)
+		}
+	}
+	return nil
+}
+
+// Enqueue inserts job and returns it as stored. An empty queue, payload or
+// attempt limit falls back to the defaults, and a zero RunAt runs it immediately.
+func (q *Queue) Enqueue(ctx context.Context, job Job) (*Job, error) {
+	if job.Queue == "" {
+		job.Queue = DefaultQueue
+	}
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage("{}")
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = q.config.MaxAttempts
+	}
+
+	var runAt interface{}
+	if !job.RunAt.IsZero() {
+		runAt = job.RunAt
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (queue, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, COALESCE($4, NOW()))
+RETURNING %s`, q.table, jobColumns)
+
+	var stored Job
+	if err := q.db.GetContext(ctx, &stored, query, job.Queue, []byte(job.Payload), job.MaxAttempts, runAt); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job on %s: %w", job.Queue, err)
+	}
+	return &stored, nil
+}
+
+// Claim locks up to limit due jobs from queueName for the visibility timeout and
+// counts the attempt. Jobs that are not completed or failed before the timeout
+// runs out become visible to other workers again.
+func (q *Queue) Claim(ctx context.Context, queueName string, limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	query := fmt.Sprintf(`UPDATE %[1]s SET locked_until = NOW() + $3 * INTERVAL '1 second', attempts = attempts + 1
+WHERE id IN (
+	SELECT id FROM %[1]s
+	WHERE queue = $1 AND failed_at IS NULL AND run_at <= NOW()
+		AND (locked_until IS NULL OR locked_until < NOW())
+	ORDER BY run_at, id
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING %[2]s`, q.table, jobColumns)
+
+	var jobs []Job
+	if err := q.db.SelectContext(ctx, &jobs, query, queueName, limit, q.config.VisibilityTimeout.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim jobs from %s: %w", queueName, err)
+	}
+	return jobs, nil
+}
+
+// Complete removes a claimed job from the table
+func (q *Queue) Complete(ctx context.Context, job Job) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", q.table)
+	return q.expectRow(q.db.ExecContext(ctx, query, job.ID))
+}
+
+// Fail records cause against a claimed job. The job is retried after the
+// backoff delay, or marked as failed once it has used all of its attempts.
+func (q *Queue) Fail(ctx context.Context, job Job, cause error) error {
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		query := fmt.Sprintf("UPDATE %s SET locked_until = NULL, last_error = $2, failed_at = NOW() WHERE id = $1", q.table)
+		return q.expectRow(q.db.ExecContext(ctx, query, job.ID, message))
+	}
+
+	delay := q.config.Backoff(job.Attempts)
+	query := fmt.Sprintf("UPDATE %s SET locked_until = NULL, last_error = $2, run_at = NOW() + $3 * INTERVAL '1 second' WHERE id = $1", q.table)
+	return q.expectRow(q.db.ExecContext(ctx, query, job.ID, message, delay.Seconds()))
+}
+
+// Retry makes a failed job runnable again with a fresh set of attempts
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	query := fmt.Sprintf("UPDATE %s SET attempts = 0, failed_at = NULL, locked_until = NULL, run_at = NOW() WHERE id = $1", q.table)
+	return q.expectRow(q.db.ExecContext(ctx, query, id))
+}
+
+func (q *Queue) expectRow(result sql.Result, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	if affected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+const jobColumns = "id, queue, payload, attempts, max_attempts, run_at, locked_until, last_error, failed_at, created_at"
+
+func indexName(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	return "idx_" + table + "_poll"
+}
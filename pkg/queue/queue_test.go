@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testColumns = []string{"id", "queue", "payload", "attempts", "max_attempts", "run_at", "locked_until", "last_error", "failed_at", "created_at"}
+
+func newTestQueue(t *testing.T, opts ...Option) (*Queue, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	q, err := New(sqlx.NewDb(db, "postgres"), opts...)
+	require.NoError(t, err)
+	return q, mock
+}
+
+func jobRow(id int64, attempts, maxAttempts int) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(testColumns).
+		AddRow(id, "emails", []byte(`{"to":"a@example.com"}`), attempts, maxAttempts, now, now, nil, nil, now)
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	_, err := New(nil)
+	assert.Error(t, err)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = New(sqlx.NewDb(db, "postgres"), WithVisibilityTimeout(0))
+	assert.Error(t, err)
+	_, err = New(sqlx.NewDb(db, "postgres"), WithTable(""))
+	assert.Error(t, err)
+}
+
+func TestSchema(t *testing.T) {
+	q, _ := newTestQueue(t, WithTable("jobs.order"), WithMaxAttempts(3))
+
+	schema := q.Schema()
+	assert.Contains(t, schema, `CREATE TABLE IF NOT EXISTS jobs."order"`)
+	assert.Contains(t, schema, "max_attempts INTEGER NOT NULL DEFAULT 3")
+	assert.Contains(t, schema, `CREATE INDEX IF NOT EXISTS idx_order_poll ON jobs."order" (queue, run_at) WHERE failed_at IS NULL`)
+}
+
+func TestEnsureTable(t *testing.T) {
+	q, mock := newTestQueue(t)
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS storm_jobs").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS idx_storm_jobs_poll").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, q.EnsureTable(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnqueue(t *testing.T) {
+	q, mock := newTestQueue(t, WithMaxAttempts(7))
+
+	job, err := NewJob("emails", map[string]string{"to": "a@example.com"})
+	require.NoError(t, err)
+
+	mock.ExpectQuery("INSERT INTO storm_jobs \\(queue, payload, max_attempts, run_at\\)").
+		WithArgs("emails", []byte(`{"to":"a@example.com"}`), 7, nil).
+		WillReturnRows(jobRow(1, 0, 7))
+
+	stored, err := q.Enqueue(context.Background(), job)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored.ID)
+
+	var payload map[string]string
+	require.NoError(t, stored.Decode(&payload))
+	assert.Equal(t, "a@example.com", payload["to"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimUsesSkipLocked(t *testing.T) {
+	q, mock := newTestQueue(t, WithVisibilityTimeout(30*time.Second))
+
+	mock.ExpectQuery("(?s)UPDATE storm_jobs SET locked_until = NOW\\(\\) \\+ \\$3 \\* INTERVAL '1 second', attempts = attempts \\+ 1.*FOR UPDATE SKIP LOCKED").
+		WithArgs("emails", 5, float64(30)).
+		WillReturnRows(jobRow(1, 1, 5))
+
+	jobs, err := q.Claim(context.Background(), "emails", 5)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 1, jobs[0].Attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFail(t *testing.T) {
+	t.Run("schedules a retry with backoff", func(t *testing.T) {
+		q, mock := newTestQueue(t, WithBackoff(func(attempt int) time.Duration { return time.Duration(attempt) * time.Minute }))
+
+		mock.ExpectExec("UPDATE storm_jobs SET locked_until = NULL, last_error = \\$2, run_at = NOW\\(\\)").
+			WithArgs(int64(1), "boom", float64(120)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, q.Fail(context.Background(), Job{ID: 1, Attempts: 2, MaxAttempts: 5}, errors.New("boom")))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("marks the job failed after the last attempt", func(t *testing.T) {
+		q, mock := newTestQueue(t)
+
+		mock.ExpectExec("UPDATE storm_jobs SET locked_until = NULL, last_error = \\$2, failed_at = NOW\\(\\)").
+			WithArgs(int64(1), "boom").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, q.Fail(context.Background(), Job{ID: 1, Attempts: 5, MaxAttempts: 5}, errors.New("boom")))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports a missing job", func(t *testing.T) {
+		q, mock := newTestQueue(t)
+
+		mock.ExpectExec("DELETE FROM storm_jobs").WithArgs(int64(9)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, q.Complete(context.Background(), Job{ID: 9}), ErrJobNotFound)
+	})
+}
+
+func TestRunOnce(t *testing.T) {
+	q, mock := newTestQueue(t, WithBatchSize(2))
+
+	rows := jobRow(1, 1, 5).AddRow(int64(2), "emails", []byte(`{}`), 1, 5, time.Now(), time.Now(), nil, nil, time.Now())
+	mock.ExpectQuery("UPDATE storm_jobs SET locked_until").WithArgs("emails", 2, sqlmock.AnyArg()).WillReturnRows(rows)
+	mock.ExpectExec("DELETE FROM storm_jobs").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE storm_jobs SET locked_until = NULL, last_error = \\$2, run_at").
+		WithArgs(int64(2), "job 2 panicked: bad payload", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := q.RunOnce(context.Background(), "emails", func(ctx context.Context, job Job) error {
+		if job.ID == 2 {
+			panic("bad payload")
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPollStopsOnCancel(t *testing.T) {
+	q, mock := newTestQueue(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mock.ExpectQuery("UPDATE storm_jobs SET locked_until").WillReturnRows(jobRow(1, 1, 5))
+	mock.ExpectExec("DELETE FROM storm_jobs").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := q.Poll(ctx, "emails", func(ctx context.Context, job Job) error {
+		cancel()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 2*time.Second, backoff(2))
+	assert.Equal(t, 8*time.Second, backoff(4))
+	assert.Equal(t, 10*time.Second, backoff(10))
+}
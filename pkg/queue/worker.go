@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Handler processes a single job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job Job) error
+
+// Poll claims jobs from queueName and runs handler on each until ctx is
+// cancelled. Successful jobs are removed, failed ones are retried with backoff,
+// and a panicking handler counts as a failure. It returns ctx.Err() on shutdown.
+func (q *Queue) Poll(ctx context.Context, queueName string, handler Handler) error {
+	for {
+		processed, err := q.RunOnce(ctx, queueName, handler)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if processed > 0 {
+			continue
+		}
+
+		timer := time.NewTimer(q.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce claims one batch of jobs and processes it, returning how many jobs were claimed
+func (q *Queue) RunOnce(ctx context.Context, queueName string, handler Handler) (int, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	jobs, err := q.Claim(ctx, queueName, q.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			// Jobs left unprocessed become visible again once their lock expires
+			break
+		}
+		if err := q.process(ctx, job, handler); err != nil {
+			return len(jobs), err
+		}
+	}
+	return len(jobs), nil
+}
+
+// process runs handler and records the outcome. The outcome is written even if
+// ctx was cancelled while the handler ran, so finished work is not redone.
+func (q *Queue) process(ctx context.Context, job Job, handler Handler) error {
+	cause := runHandler(ctx, job, handler)
+	ctx = context.WithoutCancel(ctx)
+
+	if cause != nil {
+		if err := q.Fail(ctx, job, cause); err != nil && !errors.Is(err, ErrJobNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	if err := q.Complete(ctx, job); err != nil && !errors.Is(err, ErrJobNotFound) {
+		return err
+	}
+	return nil
+}
+
+func runHandler(ctx context.Context, job Job, handler Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job %d panicked: %v", job.ID, r)
+		}
+	}()
+	return handler(ctx, job)
+}
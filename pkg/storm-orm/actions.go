@@ -0,0 +1,97 @@
+package orm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Increment returns an action that atomically adds n to col
+func Increment[T Numeric](col NumericColumn[T], n T) Action {
+	return col.Increment(n)
+}
+
+// Decrement returns an action that atomically subtracts n from col
+func Decrement[T Numeric](col NumericColumn[T], n T) Action {
+	return col.Decrement(n)
+}
+
+// Append returns an action that appends value to the array in col
+func Append[T any](col ArrayColumn[T], value T) Action {
+	return col.Append(value)
+}
+
+// JSONBSet returns an action that sets the value at a dotted path such as
+// "address.city" inside col. The value is encoded as JSON, so strings,
+// numbers, maps and structs can be passed directly.
+func JSONBSet(col JSONBColumn, path string, value interface{}) Action {
+	action := Action{
+		column: col.String(),
+		target: col.quotedName(),
+		set:    "jsonb_set(" + col.quotedName() + ", ?::text[], ?::jsonb)",
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		action.err = fmt.Errorf("failed to encode value for %s: %w", path, err)
+		return action
+	}
+
+	action.value = []interface{}{"{" + strings.Join(strings.Split(path, "."), ",") + "}", string(encoded)}
+	return action
+}
+
+// NowOnUpdate returns an action that sets col to the current time
+func NowOnUpdate(col TimeColumn) Action {
+	return col.SetNow()
+}
+
+// NowOnUpdate returns a new Repository that sets the given columns to NOW()
+// on every Update, UpdateFields, UpdateMany and Query.Update, unless the
+// update already assigns them
+func (r *Repository[T]) NowOnUpdate(columns ...string) *Repository[T] {
+	clone := r.clone()
+	clone.nowOnUpdate = append(append([]string(nil), r.nowOnUpdate...), columns...)
+	return clone
+}
+
+// touchedColumns returns the NowOnUpdate columns not in assigned
+func (r *Repository[T]) touchedColumns(assigned map[string]bool) []string {
+	var columns []string
+	for _, column := range r.nowOnUpdate {
+		if !assigned[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// touchedAutoUpdateColumns returns the touched columns marked AutoUpdateTime,
+// which Update reads back into the record
+func (r *Repository[T]) touchedAutoUpdateColumns(assigned map[string]bool) []string {
+	var columns []string
+	for _, column := range r.touchedColumns(assigned) {
+		if col := r.columnByDBName(column); col != nil && col.AutoUpdateTime {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// touchUpdate adds the NowOnUpdate columns to an update builder
+func (r *Repository[T]) touchUpdate(query squirrel.UpdateBuilder, assigned map[string]bool) squirrel.UpdateBuilder {
+	for _, column := range r.touchedColumns(assigned) {
+		query = query.Set(quoteIdent(column), squirrel.Expr("NOW()"))
+	}
+	return query
+}
+
+// unqualifiedColumn strips the table and quotes from a column reference such as users."order"
+func unqualifiedColumn(column string) string {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.Trim(column, `"`)
+}
@@ -61,6 +61,20 @@ func TestActions(t *testing.T) {
 			expectedExpr:   "updated_at = NOW()",
 			hasValue:       false,
 		},
+		{
+			name:           "TimeColumn Add",
+			action:         updatedAtCol.Add(time.Hour),
+			expectedColumn: "users.updated_at",
+			expectedExpr:   "updated_at = updated_at + ?::interval",
+			hasValue:       true,
+		},
+		{
+			name:           "TimeColumn Subtract",
+			action:         updatedAtCol.Subtract(time.Hour),
+			expectedColumn: "users.updated_at",
+			expectedExpr:   "updated_at = updated_at - ?::interval",
+			hasValue:       true,
+		},
 		{
 			name:           "ArrayColumn Append",
 			action:         tagsCol.Append("new-tag"),
@@ -178,3 +192,48 @@ func TestJSONBColumnActions(t *testing.T) {
 		})
 	}
 }
+
+func TestTypedActions(t *testing.T) {
+	ageCol := NumericColumn[int]{ComparableColumn: ComparableColumn[int]{Column: Column[int]{Name: "age", Table: "users"}}}
+	tagsCol := ArrayColumn[string]{Column: Column[[]string]{Name: "tags", Table: "users"}}
+	seenCol := TimeColumn{ComparableColumn: ComparableColumn[time.Time]{Column: Column[time.Time]{Name: "seen_at", Table: "users"}}}
+
+	tests := []struct {
+		name         string
+		action       Action
+		expectedExpr string
+	}{
+		{"Increment", Increment(ageCol, 2), "age = age + ?"},
+		{"Decrement", Decrement(ageCol, 2), "age = age - ?"},
+		{"Append", Append(tagsCol, "new"), "tags = array_append(tags, ?)"},
+		{"NowOnUpdate", NowOnUpdate(seenCol), "seen_at = NOW()"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.action.Expression() != tt.expectedExpr {
+				t.Errorf("Expression() = %v, expected %v", tt.action.Expression(), tt.expectedExpr)
+			}
+		})
+	}
+}
+
+func TestJSONBSet(t *testing.T) {
+	metaCol := JSONBColumn{Column: Column[interface{}]{Name: "metadata", Table: "users"}}
+
+	action := JSONBSet(metaCol, "address.city", "Paris")
+	if action.Expression() != "metadata = jsonb_set(metadata, ?::text[], ?::jsonb)" {
+		t.Errorf("unexpected expression %q", action.Expression())
+	}
+	values, ok := action.Value().([]interface{})
+	if !ok || len(values) != 2 {
+		t.Fatalf("expected path and value, got %v", action.Value())
+	}
+	if values[0] != "{address,city}" || values[1] != `"Paris"` {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	if action := JSONBSet(metaCol, "bad", make(chan int)); action.err == nil {
+		t.Error("expected an encoding error")
+	}
+}
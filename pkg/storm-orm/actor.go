@@ -0,0 +1,139 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+type actorKey struct{}
+
+// actorColumns names the columns stamped with the actor of a write
+type actorColumns struct {
+	createdBy string
+	updatedBy string
+}
+
+// WithActor returns a context carrying the actor, usually a user ID, that
+// repositories configured with StampActor record on writes
+func WithActor(ctx context.Context, actor interface{}) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored in ctx by WithActor
+func ActorFromContext(ctx context.Context) (interface{}, bool) {
+	actor := ctx.Value(actorKey{})
+	return actor, actor != nil
+}
+
+// StampActor returns a new Repository that fills createdBy on insert and
+// updatedBy on insert and every update with the actor from the context.
+// Either name may be empty. Writes without an actor leave the columns as they
+// are; add RequireActor to reject them instead.
+func (r *Repository[T]) StampActor(createdBy, updatedBy string) *Repository[T] {
+	clone := r.clone()
+	clone.actor = actorColumns{createdBy: createdBy, updatedBy: updatedBy}
+	return clone
+}
+
+// RequireActor returns middleware that rejects every write whose context
+// carries no actor
+func RequireActor() QueryMiddleware {
+	return func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			if ctx.Operation == OpFind || ctx.Operation == OpQuery {
+				return next(ctx)
+			}
+			if _, ok := ActorFromContext(ctx.Context); !ok {
+				return &Error{
+					Op:    string(ctx.Operation),
+					Table: ctx.TableName,
+					Err:   ErrNoActor,
+				}
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// stampActor sets the actor columns of record from the actor in ctx, the
+// created-by column only when the record is being inserted
+func (r *Repository[T]) stampActor(ctx context.Context, op string, record *T, insert bool) error {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	columns := []string{r.actor.updatedBy}
+	if insert {
+		columns = append(columns, r.actor.createdBy)
+	}
+
+	for _, column := range columns {
+		if column == "" {
+			continue
+		}
+		if err := r.setActorField(record, column, actor); err != nil {
+			return &Error{
+				Op:     op,
+				Table:  r.metadata.TableName,
+				Column: column,
+				Err:    err,
+			}
+		}
+	}
+	return nil
+}
+
+// setActorField assigns actor to the field behind column
+func (r *Repository[T]) setActorField(record *T, column string, actor interface{}) error {
+	col := r.columnByDBName(column)
+	if col == nil {
+		return ErrUnknownColumn
+	}
+	return setField(record, col.FieldName, actor)
+}
+
+// setField assigns value to the named field of the struct record points to,
+// converting it to the field's type when both share a kind. A nil value
+// clears the field.
+func setField(record interface{}, fieldName string, value interface{}) error {
+	field := reflect.ValueOf(record).Elem().FieldByName(fieldName)
+	if !field.IsValid() || !field.CanSet() {
+		return ErrUnknownColumn
+	}
+
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	target := field.Type()
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	v := reflect.ValueOf(value)
+	if !v.Type().AssignableTo(target) && (v.Kind() != target.Kind() || !v.Type().ConvertibleTo(target)) {
+		return fmt.Errorf("value of type %T cannot be stored in a %s field", value, field.Type())
+	}
+	v = v.Convert(target)
+
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(target)
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	field.Set(v)
+	return nil
+}
+
+// actorUpdate returns the updated-by column and the actor from ctx, or false
+// when the column is not configured, already assigned, or ctx has no actor
+func (r *Repository[T]) actorUpdate(ctx context.Context, assigned map[string]bool) (string, interface{}, bool) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok || r.actor.updatedBy == "" || assigned[r.actor.updatedBy] {
+		return "", nil, false
+	}
+	return r.actor.updatedBy, actor, true
+}
@@ -0,0 +1,156 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUserID string
+
+type testAuditedPost struct {
+	ID        int        `db:"id"`
+	Title     string     `db:"title"`
+	CreatedBy testUserID `db:"created_by"`
+	UpdatedBy *string    `db:"updated_by"`
+}
+
+func createTestAuditedPostMetadata() *ModelMetadata {
+	return &ModelMetadata{
+		TableName:  "posts",
+		StructName: "AuditedPost",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue:        func(model interface{}) interface{} { return model.(testAuditedPost).ID },
+			},
+			"Title": {
+				FieldName: "Title",
+				DBName:    "title",
+				GetValue:  func(model interface{}) interface{} { return model.(testAuditedPost).Title },
+			},
+			"CreatedBy": {
+				FieldName: "CreatedBy",
+				DBName:    "created_by",
+				GetValue:  func(model interface{}) interface{} { return string(model.(testAuditedPost).CreatedBy) },
+			},
+			"UpdatedBy": {
+				FieldName: "UpdatedBy",
+				DBName:    "updated_by",
+				IsPointer: true,
+				GetValue: func(model interface{}) interface{} {
+					if p := model.(testAuditedPost).UpdatedBy; p != nil {
+						return *p
+					}
+					return nil
+				},
+				IsNil: func(model interface{}) bool { return model.(testAuditedPost).UpdatedBy == nil },
+			},
+		},
+		ReverseMap:  map[string]string{"id": "ID", "title": "Title", "created_by": "CreatedBy", "updated_by": "UpdatedBy"},
+		PrimaryKeys: []string{"id"},
+	}
+}
+
+func TestStampActor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[testAuditedPost](sqlx.NewDb(db, "postgres"), createTestAuditedPostMetadata())
+	require.NoError(t, err)
+	repo = repo.StampActor("created_by", "updated_by")
+
+	ctx := WithActor(context.Background(), "user-1")
+
+	t.Run("Create fills both columns", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO posts (created_by,title,updated_by) VALUES ($1,$2,$3) RETURNING id`)).
+			WithArgs("user-1", "Hello", "user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		post := &testAuditedPost{Title: "Hello"}
+		_, err := repo.Create(ctx, post)
+		require.NoError(t, err)
+		assert.Equal(t, testUserID("user-1"), post.CreatedBy)
+		require.NotNil(t, post.UpdatedBy)
+		assert.Equal(t, "user-1", *post.UpdatedBy)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update fills only updated_by", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET created_by = $1, title = $2, updated_by = $3 WHERE id = $4`)).
+			WithArgs("user-0", "Edited", "user-2", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		post := &testAuditedPost{ID: 1, Title: "Edited", CreatedBy: "user-0"}
+		_, err := repo.Update(WithActor(context.Background(), "user-2"), post)
+		require.NoError(t, err)
+		assert.Equal(t, testUserID("user-0"), post.CreatedBy)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query Update", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET title = $1, updated_by = $2 WHERE (posts.id = $3)`)).
+			WithArgs("Renamed", "user-1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		titleCol := Column[string]{Name: "title", Table: "posts"}
+		idCol := Column[int]{Name: "id", Table: "posts"}
+		_, err := repo.Query(ctx).Where(idCol.Eq(1)).Update(titleCol.Set("Renamed"))
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Upsert keeps created_by on conflict", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (title) DO UPDATE SET updated_by = EXCLUDED.updated_by`)).
+			WithArgs("user-1", "Hello", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		post := &testAuditedPost{Title: "Hello"}
+		require.NoError(t, repo.Upsert(ctx, post, UpsertOptions{ConflictColumns: []string{"title"}}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("writes without an actor are left alone", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO posts (created_by,title) VALUES ($1,$2) RETURNING id`)).
+			WithArgs("", "Anonymous").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+		_, err := repo.Create(context.Background(), &testAuditedPost{Title: "Anonymous"})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects an actor of another kind", func(t *testing.T) {
+		_, err := repo.Create(WithActor(context.Background(), 42), &testAuditedPost{Title: "Hello"})
+		assert.ErrorContains(t, err, "value of type int cannot be stored")
+	})
+}
+
+func TestRequireActor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[testAuditedPost](sqlx.NewDb(db, "postgres"), createTestAuditedPostMetadata())
+	require.NoError(t, err)
+	repo.AddMiddleware(RequireActor())
+
+	_, err = repo.Create(context.Background(), &testAuditedPost{Title: "Hello"})
+	assert.ErrorIs(t, err, ErrNoActor)
+
+	mock.ExpectQuery("SELECT .* FROM posts").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "created_by", "updated_by"}))
+
+	_, err = repo.Query(context.Background()).Find()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,298 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// autosaveState tracks a record while autosave persists its graph
+type autosaveState int
+
+const (
+	autosaveSaving autosaveState = iota + 1 // waiting on its belongs_to parents
+	autosaveSaved                           // inserted, or already stored
+)
+
+// autosaver persists the populated autosave relationships of a record graph
+// with a single executor, visiting each record once
+type autosaver struct {
+	ctx     context.Context
+	exec    DBExecutor
+	options *queryOptions
+	op      string
+	visited map[interface{}]autosaveState
+}
+
+// autosaveRelationships returns the autosave relationships of metadata that
+// can be persisted, ordered by name for deterministic execution
+func autosaveRelationships(metadata *ModelMetadata) []*RelationshipMetadata {
+	var relationships []*RelationshipMetadata
+	for _, rel := range metadata.Relationships {
+		if !rel.Autosave || rel.Related == nil || rel.TargetMetadata == nil {
+			continue
+		}
+		if rel.Type != "belongs_to" && rel.Type != "has_one" && rel.Type != "has_many" {
+			continue
+		}
+		relationships = append(relationships, rel)
+	}
+	sort.Slice(relationships, func(i, j int) bool {
+		return relationships[i].Name < relationships[j].Name
+	})
+	return relationships
+}
+
+// withAutosave runs create, which inserts record with the executor it is
+// given, after saving the record's belongs_to parents and before saving its
+// has_one and has_many children. When any autosave relationship is populated
+// the whole graph is written in one transaction.
+func (r *Repository[T]) withAutosave(ctx context.Context, op string, record *T, create func(exec DBExecutor) error) error {
+	populated := false
+	for _, rel := range autosaveRelationships(r.metadata) {
+		if len(rel.Related(record)) > 0 {
+			populated = true
+			break
+		}
+	}
+	if !populated {
+		return create(r.db)
+	}
+
+	return r.inTransaction(ctx, op, r.db, func(exec DBExecutor) error {
+		saver := &autosaver{
+			ctx:     ctx,
+			exec:    exec,
+			options: r.options,
+			op:      op,
+			visited: map[interface{}]autosaveState{record: autosaveSaving},
+		}
+
+		if err := saver.saveParents(r.metadata, record); err != nil {
+			return err
+		}
+		if err := create(exec); err != nil {
+			return err
+		}
+		saver.visited[record] = autosaveSaved
+		return saver.saveChildren(r.metadata, record)
+	})
+}
+
+// save persists record, a pointer to a struct described by metadata, with its
+// parents and children. Records with a primary key are treated as stored.
+func (s *autosaver) save(metadata *ModelMetadata, record interface{}) error {
+	s.visited[record] = autosaveSaving
+
+	if err := s.saveParents(metadata, record); err != nil {
+		return err
+	}
+	if isNewRecord(metadata, record) {
+		if err := s.insert(metadata, record); err != nil {
+			return err
+		}
+	}
+
+	s.visited[record] = autosaveSaved
+	return s.saveChildren(metadata, record)
+}
+
+// saveParents saves the belongs_to records of record and points its foreign
+// keys at them
+func (s *autosaver) saveParents(metadata *ModelMetadata, record interface{}) error {
+	for _, rel := range autosaveRelationships(metadata) {
+		if rel.Type != "belongs_to" {
+			continue
+		}
+
+		for _, parent := range rel.Related(record) {
+			switch s.visited[parent] {
+			case autosaveSaving:
+				return &Error{
+					Op:    s.op,
+					Table: metadata.TableName,
+					Err:   fmt.Errorf("%w through %s", ErrAutosaveCycle, rel.Name),
+				}
+			case 0:
+				if err := s.save(rel.TargetMetadata, parent); err != nil {
+					return err
+				}
+			}
+
+			targetKey := rel.TargetKey
+			if targetKey == "" {
+				targetKey = "id"
+			}
+			if err := s.setColumn(metadata, record, rel.ForeignKey, columnValue(rel.TargetMetadata, parent, targetKey)); err != nil {
+				return err
+			}
+			if rel.isPolymorphic() {
+				if err := s.setColumn(metadata, record, rel.PolymorphicType, rel.PolymorphicValue); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// saveChildren points the has_one and has_many records of record at it and
+// saves them. Children that are already stored have their foreign key updated.
+func (s *autosaver) saveChildren(metadata *ModelMetadata, record interface{}) error {
+	for _, rel := range autosaveRelationships(metadata) {
+		if rel.Type == "belongs_to" {
+			continue
+		}
+
+		sourceKey := rel.SourceKey
+		if sourceKey == "" {
+			sourceKey = "id"
+		}
+		key := columnValue(metadata, record, sourceKey)
+
+		for _, child := range rel.Related(record) {
+			if s.visited[child] != 0 {
+				continue
+			}
+
+			links := map[string]interface{}{rel.ForeignKey: key}
+			if rel.isPolymorphic() {
+				links[rel.PolymorphicType] = rel.PolymorphicValue
+			}
+			for column, value := range links {
+				if err := s.setColumn(rel.TargetMetadata, child, column, value); err != nil {
+					return err
+				}
+			}
+
+			if !isNewRecord(rel.TargetMetadata, child) {
+				if err := s.link(rel.TargetMetadata, child, links); err != nil {
+					return err
+				}
+			}
+			if err := s.save(rel.TargetMetadata, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insert writes record and reads the columns the database fills back into it
+func (s *autosaver) insert(metadata *ModelMetadata, record interface{}) error {
+	if validator, ok := record.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return &Error{
+				Op:    s.op,
+				Table: metadata.TableName,
+				Err:   err,
+			}
+		}
+	}
+
+	columns, values := insertFields(s.options, metadata, reflect.ValueOf(record).Elem().Interface())
+	if len(columns) == 0 {
+		return &Error{
+			Op:    s.op,
+			Table: metadata.TableName,
+			Err:   fmt.Errorf("no fields to insert"),
+		}
+	}
+
+	sqlQuery, args, err := metadata.derived().insertBuilder.
+		Columns(quoteIdents(columns)...).
+		Values(values...).
+		ToSql()
+	if err != nil {
+		return &Error{
+			Op:    s.op,
+			Table: metadata.TableName,
+			Err:   fmt.Errorf("failed to build query: %w", err),
+		}
+	}
+
+	if returning := returningClause(metadata); returning != "" {
+		sqlQuery += returning
+		err = getRecord(s.ctx, s.exec, metadata, record, sqlQuery, args...)
+	} else {
+		_, err = s.exec.ExecContext(s.ctx, sqlQuery, args...)
+	}
+	if err != nil {
+		return parsePostgreSQLError(err, s.op, metadata.TableName)
+	}
+	return nil
+}
+
+// link updates the foreign key columns of a stored child record
+func (s *autosaver) link(metadata *ModelMetadata, record interface{}, links map[string]interface{}) error {
+	query := squirrel.Update(quoteIdent(metadata.TableName)).
+		PlaceholderFormat(squirrel.Dollar)
+
+	columns := make([]string, 0, len(links))
+	for column := range links {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		query = query.Set(quoteIdent(column), links[column])
+	}
+	for _, pk := range metadata.PrimaryKeys {
+		query = query.Where(squirrel.Eq{quoteIdent(pk): columnValue(metadata, record, pk)})
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return &Error{
+			Op:    s.op,
+			Table: metadata.TableName,
+			Err:   fmt.Errorf("failed to build query: %w", err),
+		}
+	}
+
+	if _, err := s.exec.ExecContext(s.ctx, sqlQuery, args...); err != nil {
+		return parsePostgreSQLError(err, s.op, metadata.TableName)
+	}
+	return nil
+}
+
+// setColumn assigns value to the field behind column of record
+func (s *autosaver) setColumn(metadata *ModelMetadata, record interface{}, column string, value interface{}) error {
+	err := ErrUnknownColumn
+	if col, ok := metadata.Columns[metadata.ReverseMap[column]]; ok {
+		err = setField(record, col.FieldName, value)
+	}
+	if err != nil {
+		return &Error{
+			Op:     s.op,
+			Table:  metadata.TableName,
+			Column: column,
+			Err:    err,
+		}
+	}
+	return nil
+}
+
+// columnValue reads column from record, a pointer to a struct described by
+// metadata
+func columnValue(metadata *ModelMetadata, record interface{}, column string) interface{} {
+	col, ok := metadata.Columns[metadata.ReverseMap[column]]
+	if !ok || col.GetValue == nil {
+		return nil
+	}
+	return col.GetValue(reflect.ValueOf(record).Elem().Interface())
+}
+
+// isNewRecord reports whether record has yet to be stored, judged by its
+// primary key being unset
+func isNewRecord(metadata *ModelMetadata, record interface{}) bool {
+	for _, pk := range metadata.PrimaryKeys {
+		value := columnValue(metadata, record, pk)
+		if value != nil && !reflect.ValueOf(value).IsZero() {
+			return false
+		}
+	}
+	return true
+}
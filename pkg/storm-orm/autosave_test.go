@@ -0,0 +1,281 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testWriter struct {
+	ID    int        `db:"id"`
+	Name  string     `db:"name"`
+	Books []testBook `db:"-"`
+}
+
+type testBook struct {
+	ID       int         `db:"id"`
+	AuthorID int         `db:"author_id"`
+	Title    string      `db:"title"`
+	Author   *testWriter `db:"-"`
+}
+
+type testNode struct {
+	ID       int       `db:"id"`
+	ParentID *int      `db:"parent_id"`
+	Parent   *testNode `db:"-"`
+}
+
+func createAutosaveMetadata() (*ModelMetadata, *ModelMetadata) {
+	authors := &ModelMetadata{
+		TableName:  "authors",
+		StructName: "Author",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue:        func(model interface{}) interface{} { return model.(testWriter).ID },
+			},
+			"Name": {
+				FieldName: "Name",
+				DBName:    "name",
+				GetValue:  func(model interface{}) interface{} { return model.(testWriter).Name },
+			},
+		},
+		ReverseMap:  map[string]string{"id": "ID", "name": "Name"},
+		PrimaryKeys: []string{"id"},
+	}
+
+	books := &ModelMetadata{
+		TableName:  "books",
+		StructName: "Book",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue:        func(model interface{}) interface{} { return model.(testBook).ID },
+			},
+			"AuthorID": {
+				FieldName: "AuthorID",
+				DBName:    "author_id",
+				GetValue:  func(model interface{}) interface{} { return model.(testBook).AuthorID },
+			},
+			"Title": {
+				FieldName: "Title",
+				DBName:    "title",
+				GetValue:  func(model interface{}) interface{} { return model.(testBook).Title },
+			},
+		},
+		ReverseMap:  map[string]string{"id": "ID", "author_id": "AuthorID", "title": "Title"},
+		PrimaryKeys: []string{"id"},
+	}
+
+	authors.Relationships = map[string]*RelationshipMetadata{
+		"Books": {
+			Name:           "Books",
+			Type:           "has_many",
+			Target:         "Book",
+			TargetTable:    "books",
+			ForeignKey:     "author_id",
+			SourceKey:      "id",
+			Autosave:       true,
+			TargetMetadata: books,
+			Related: func(model interface{}) []interface{} {
+				m := model.(*testWriter)
+				related := make([]interface{}, len(m.Books))
+				for i := range m.Books {
+					related[i] = &m.Books[i]
+				}
+				return related
+			},
+		},
+	}
+
+	books.Relationships = map[string]*RelationshipMetadata{
+		"Author": {
+			Name:           "Author",
+			Type:           "belongs_to",
+			Target:         "Author",
+			TargetTable:    "authors",
+			ForeignKey:     "author_id",
+			TargetKey:      "id",
+			Autosave:       true,
+			TargetMetadata: authors,
+			Related: func(model interface{}) []interface{} {
+				if m := model.(*testBook); m.Author != nil {
+					return []interface{}{m.Author}
+				}
+				return nil
+			},
+		},
+	}
+
+	return authors, books
+}
+
+func TestAutosaveHasMany(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	authors, _ := createAutosaveMetadata()
+	repo, err := NewRepository[testWriter](sqlx.NewDb(db, "postgres"), authors)
+	require.NoError(t, err)
+
+	t.Run("inserts children with the parent key", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO authors (name) VALUES ($1) RETURNING id`)).
+			WithArgs("Ursula").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books (author_id,title) VALUES ($1,$2) RETURNING id`)).
+			WithArgs(7, "Earthsea").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books (author_id,title) VALUES ($1,$2) RETURNING id`)).
+			WithArgs(7, "The Dispossessed").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		author := &testWriter{Name: "Ursula", Books: []testBook{{Title: "Earthsea"}, {Title: "The Dispossessed"}}}
+		_, err := repo.Create(context.Background(), author)
+		require.NoError(t, err)
+		assert.Equal(t, 7, author.ID)
+		assert.Equal(t, []int{7, 7}, []int{author.Books[0].AuthorID, author.Books[1].AuthorID})
+		assert.Equal(t, []int{1, 2}, []int{author.Books[0].ID, author.Books[1].ID})
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("links stored children", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO authors (name) VALUES ($1) RETURNING id`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET author_id = $1 WHERE id = $2`)).
+			WithArgs(8, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		author := &testWriter{Name: "Octavia", Books: []testBook{{ID: 3, Title: "Kindred"}}}
+		_, err := repo.Create(context.Background(), author)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when a child fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(`INSERT INTO books`).
+			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		author := &testWriter{Name: "Iain", Books: []testBook{{Title: "Excession"}}}
+		_, err := repo.Create(context.Background(), author)
+		assert.Error(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skips the transaction without related records", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+
+		_, err := repo.Create(context.Background(), &testWriter{Name: "Alone"})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAutosaveBelongsTo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, books := createAutosaveMetadata()
+	repo, err := NewRepository[testBook](sqlx.NewDb(db, "postgres"), books)
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO authors (name) VALUES ($1) RETURNING id`)).
+		WithArgs("Ted").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books (author_id,title) VALUES ($1,$2) RETURNING id`)).
+		WithArgs(4, "Exhalation").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	book := &testBook{Title: "Exhalation", Author: &testWriter{Name: "Ted"}}
+	_, err = repo.Create(context.Background(), book)
+	require.NoError(t, err)
+	assert.Equal(t, 4, book.AuthorID)
+	assert.Equal(t, 4, book.Author.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAutosaveCycle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	nodes := &ModelMetadata{
+		TableName:  "nodes",
+		StructName: "Node",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue:        func(model interface{}) interface{} { return model.(testNode).ID },
+			},
+			"ParentID": {
+				FieldName: "ParentID",
+				DBName:    "parent_id",
+				IsPointer: true,
+				GetValue: func(model interface{}) interface{} {
+					if p := model.(testNode).ParentID; p != nil {
+						return *p
+					}
+					return nil
+				},
+				IsNil: func(model interface{}) bool { return model.(testNode).ParentID == nil },
+			},
+		},
+		ReverseMap:  map[string]string{"id": "ID", "parent_id": "ParentID"},
+		PrimaryKeys: []string{"id"},
+	}
+	nodes.Relationships = map[string]*RelationshipMetadata{
+		"Parent": {
+			Name:           "Parent",
+			Type:           "belongs_to",
+			Target:         "Node",
+			ForeignKey:     "parent_id",
+			TargetKey:      "id",
+			Autosave:       true,
+			TargetMetadata: nodes,
+			Related: func(model interface{}) []interface{} {
+				if m := model.(*testNode); m.Parent != nil {
+					return []interface{}{m.Parent}
+				}
+				return nil
+			},
+		},
+	}
+
+	repo, err := NewRepository[testNode](sqlx.NewDb(db, "postgres"), nodes)
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	a, b := &testNode{}, &testNode{}
+	a.Parent, b.Parent = b, a
+	_, err = repo.Create(context.Background(), a)
+	assert.ErrorIs(t, err, ErrAutosaveCycle)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,235 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// DefaultBatchChunkSize is the number of rows a partial batch inserts per
+// statement when BatchOptions.ChunkSize is not set
+const DefaultBatchChunkSize = 100
+
+// batchSavepoint names the savepoint each statement of a partial batch runs under
+const batchSavepoint = "storm_batch"
+
+// BatchOptions configures CreateManyPartial and UpsertManyPartial
+type BatchOptions struct {
+	ChunkSize int // Rows per statement; DefaultBatchChunkSize when zero
+}
+
+// BatchError is a row of a partial batch that was not written
+type BatchError struct {
+	Index int   // Position of the row in the records passed in
+	Err   error // Why the row was rejected
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchResult reports the outcome of a partial batch
+type BatchResult struct {
+	Written int          // Rows inserted or upserted
+	Failed  []BatchError // Rejected rows, ordered by index
+}
+
+// FailedIndexes returns the positions of the rows that were not written
+func (b *BatchResult) FailedIndexes() []int {
+	indexes := make([]int, len(b.Failed))
+	for i, failed := range b.Failed {
+		indexes[i] = failed.Index
+	}
+	return indexes
+}
+
+// savepointError marks a failure to manage the batch savepoint, which leaves
+// the transaction unusable and ends the batch
+type savepointError struct {
+	err error
+}
+
+func (e *savepointError) Error() string {
+	return e.err.Error()
+}
+
+func (e *savepointError) Unwrap() error {
+	return e.err
+}
+
+// CreateManyPartial inserts records like CreateMany, but a bad row does not
+// abort the batch. Rows are inserted in chunks, each under a savepoint; when a
+// chunk fails it is rolled back and its rows are retried one at a time. Rows
+// that fail validation or the retry are reported in the result and every other
+// row is written in one transaction. The error is only set when the batch
+// could not run at all.
+func (r *Repository[T]) CreateManyPartial(ctx context.Context, records []T, opts BatchOptions) (*BatchResult, error) {
+	return r.writePartial(ctx, "createMany", OpCreateMany, records, opts, func([]string) string {
+		return ""
+	})
+}
+
+// UpsertManyPartial upserts records like UpsertMany, collecting the rows that
+// fail instead of aborting the batch. See CreateManyPartial.
+func (r *Repository[T]) UpsertManyPartial(ctx context.Context, records []T, upsert UpsertOptions, opts BatchOptions) (*BatchResult, error) {
+	if len(upsert.ConflictColumns) == 0 {
+		return nil, &Error{
+			Op:    "upsertMany",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("conflict columns must be specified"),
+		}
+	}
+	if err := r.validateUpsertOptions("upsertMany", upsert); err != nil {
+		return nil, err
+	}
+
+	return r.writePartial(ctx, "upsertMany", OpUpsertMany, records, opts, func(columns []string) string {
+		return r.onConflictClause(columns, upsert)
+	})
+}
+
+// writePartial validates records and inserts the valid ones chunk by chunk,
+// appending the statement suffix built from the inserted columns
+func (r *Repository[T]) writePartial(ctx context.Context, op string, opType OperationType, records []T, opts BatchOptions, suffix func(columns []string) string) (*BatchResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := &BatchResult{}
+	var rows []int
+	for i := range records {
+		if err := r.stampActor(ctx, op, &records[i], true); err != nil {
+			return nil, err
+		}
+		if err := r.validateRecord(op, &records[i]); err != nil {
+			result.Failed = append(result.Failed, BatchError{Index: i, Err: err})
+			continue
+		}
+		rows = append(rows, i)
+	}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	columns, _ := r.getInsertFields(records[rows[0]])
+	if len(columns) == 0 {
+		return result, nil
+	}
+	tail := suffix(columns)
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultBatchChunkSize
+	}
+
+	err := r.inTransaction(ctx, op, r.db, func(exec DBExecutor) error {
+		for start := 0; start < len(rows); start += chunkSize {
+			chunk := rows[start:min(start+chunkSize, len(rows))]
+
+			err := r.insertUnderSavepoint(ctx, exec, op, opType, records, chunk, columns, tail)
+			if err == nil {
+				result.Written += len(chunk)
+				continue
+			}
+
+			var spErr *savepointError
+			if errors.As(err, &spErr) {
+				return spErr.err
+			}
+			if len(chunk) == 1 {
+				result.Failed = append(result.Failed, BatchError{Index: chunk[0], Err: err})
+				continue
+			}
+
+			for _, row := range chunk {
+				err := r.insertUnderSavepoint(ctx, exec, op, opType, records, []int{row}, columns, tail)
+				if errors.As(err, &spErr) {
+					return spErr.err
+				}
+				if err != nil {
+					result.Failed = append(result.Failed, BatchError{Index: row, Err: err})
+					continue
+				}
+				result.Written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result.Failed, func(i, j int) bool {
+		return result.Failed[i].Index < result.Failed[j].Index
+	})
+	return result, nil
+}
+
+// insertUnderSavepoint inserts the records at rows in one statement. When the
+// statement fails the transaction is rolled back to before it, so the batch
+// can carry on.
+func (r *Repository[T]) insertUnderSavepoint(ctx context.Context, exec DBExecutor, op string, opType OperationType, records []T, rows []int, columns []string, suffix string) error {
+	chunk := make([]T, len(rows))
+	query := squirrel.Insert(r.quotedTable()).
+		PlaceholderFormat(squirrel.Dollar).
+		Columns(quoteIdents(columns)...)
+	for i, row := range rows {
+		chunk[i] = records[row]
+		_, values := r.getInsertFields(records[row])
+		query = query.Values(values...)
+	}
+
+	if err := r.execSavepoint(ctx, exec, op, "SAVEPOINT "+batchSavepoint); err != nil {
+		return err
+	}
+
+	err := r.executeQueryMiddleware(opType, ctx, chunk, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    op,
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to build batch insert query: %w", err),
+			}
+		}
+		sqlQuery += suffix
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		if _, err := exec.ExecContext(ctx, sqlQuery, args...); err != nil {
+			return parsePostgreSQLError(err, op, r.metadata.TableName)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if spErr := r.execSavepoint(ctx, exec, op, "ROLLBACK TO SAVEPOINT "+batchSavepoint); spErr != nil {
+			return spErr
+		}
+	}
+	if spErr := r.execSavepoint(ctx, exec, op, "RELEASE SAVEPOINT "+batchSavepoint); spErr != nil {
+		return spErr
+	}
+	return err
+}
+
+// execSavepoint runs a savepoint statement, marking a failure as fatal to the batch
+func (r *Repository[T]) execSavepoint(ctx context.Context, exec DBExecutor, op, statement string) error {
+	if _, err := exec.ExecContext(ctx, statement); err != nil {
+		return &savepointError{err: &Error{
+			Op:    op,
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("failed to run %s: %w", statement, err),
+		}}
+	}
+	return nil
+}
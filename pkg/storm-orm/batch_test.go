@@ -0,0 +1,132 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchTestMetadata() *ModelMetadata {
+	return &ModelMetadata{
+		TableName:  "users",
+		StructName: "validatedUser",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue: func(model interface{}) interface{} {
+					return model.(validatedUser).ID
+				},
+			},
+			"Name": {
+				FieldName: "Name",
+				DBName:    "name",
+				GetValue: func(model interface{}) interface{} {
+					return model.(validatedUser).Name
+				},
+			},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "Name": "name"},
+		ReverseMap:  map[string]string{"id": "ID", "name": "Name"},
+		PrimaryKeys: []string{"id"},
+	}
+}
+
+func TestCreateManyPartial(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[validatedUser](sqlx.NewDb(db, "postgres"), batchTestMetadata())
+	require.NoError(t, err)
+
+	duplicate := &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}
+
+	t.Run("retries a failed chunk row by row", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users \(name\) VALUES \(\$1\),\(\$2\)`).
+			WithArgs("ada", "bob").
+			WillReturnError(duplicate)
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users \(name\) VALUES \(\$1\)`).WithArgs("ada").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users \(name\) VALUES \(\$1\)`).WithArgs("bob").WillReturnError(duplicate)
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users \(name\) VALUES \(\$1\)`).WithArgs("cy").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := repo.CreateManyPartial(context.Background(),
+			[]validatedUser{{Name: "ada"}, {}, {Name: "bob"}, {Name: "cy"}},
+			BatchOptions{ChunkSize: 2})
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Written)
+		assert.Equal(t, []int{1, 2}, result.FailedIndexes())
+
+		var validationErrs ValidationErrors
+		assert.ErrorAs(t, result.Failed[0], &validationErrs)
+		assert.ErrorIs(t, result.Failed[1], ErrDuplicateKey)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops when a savepoint cannot be created", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnError(errors.New("connection lost"))
+		mock.ExpectRollback()
+
+		result, err := repo.CreateManyPartial(context.Background(), []validatedUser{{Name: "ada"}}, BatchOptions{})
+		assert.Nil(t, result)
+		assert.ErrorContains(t, err, "failed to run SAVEPOINT storm_batch")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpsertManyPartial(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[validatedUser](sqlx.NewDb(db, "postgres"), batchTestMetadata())
+	require.NoError(t, err)
+
+	t.Run("appends the conflict clause", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users \(name\) VALUES \(\$1\),\(\$2\) ON CONFLICT \(name\) DO NOTHING`).
+			WithArgs("ada", "bob").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := repo.UpsertManyPartial(context.Background(),
+			[]validatedUser{{Name: "ada"}, {Name: "bob"}},
+			UpsertOptions{ConflictColumns: []string{"name"}}, BatchOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Written)
+		assert.Empty(t, result.Failed)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requires conflict columns", func(t *testing.T) {
+		_, err := repo.UpsertManyPartial(context.Background(), []validatedUser{{Name: "ada"}}, UpsertOptions{}, BatchOptions{})
+		assert.ErrorContains(t, err, "conflict columns must be specified")
+	})
+}
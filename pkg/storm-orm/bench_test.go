@@ -0,0 +1,133 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+// benchRowCount is the number of rows every benchConn query returns
+const benchRowCount = 100
+
+var registerBenchDriver sync.Once
+
+// openBenchDB returns a database whose queries all return the same
+// benchRowCount TestUser rows and whose writes always succeed, so benchmarks
+// measure the ORM rather than a database
+func openBenchDB(tb testing.TB) *sqlx.DB {
+	registerBenchDriver.Do(func() {
+		sql.Register("storm-bench", benchDriver{})
+	})
+
+	db, err := sqlx.Open("storm-bench", "")
+	require.NoError(tb, err)
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+type benchDriver struct{}
+
+func (benchDriver) Open(string) (driver.Conn, error) { return benchConn{}, nil }
+
+type benchConn struct{}
+
+func (benchConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (benchConn) Close() error { return nil }
+func (benchConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+func (benchConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &benchRows{}, nil
+}
+func (benchConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type benchRows struct{ next int }
+
+var benchTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (r *benchRows) Columns() []string { return scanTestColumns }
+func (r *benchRows) Close() error      { return nil }
+func (r *benchRows) Next(dest []driver.Value) error {
+	if r.next == benchRowCount {
+		return io.EOF
+	}
+	r.next++
+	dest[0] = int64(r.next)
+	dest[1] = "User"
+	dest[2] = "user@example.com"
+	dest[3] = true
+	dest[4] = benchTime
+	dest[5] = benchTime
+	return nil
+}
+
+func benchmarkFind(b *testing.B, metadata *ModelMetadata) {
+	repo, err := NewRepository[TestUser](openBenchDB(b), metadata)
+	require.NoError(b, err)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Query(ctx).Find(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFind_GeneratedScan(b *testing.B) {
+	benchmarkFind(b, withGeneratedScan(createTestUserMetadata()))
+}
+
+func BenchmarkFind_ReflectionScan(b *testing.B) {
+	benchmarkFind(b, createTestUserMetadata())
+}
+
+func BenchmarkQuery_Build(b *testing.B) {
+	repo, err := NewRepository[TestUser](openBenchDB(b), createTestUserMetadata())
+	require.NoError(b, err)
+	ctx := context.Background()
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	activeCol := Column[bool]{Name: "is_active", Table: "users"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := repo.Query(ctx).
+			Where(nameCol.Eq("Alice")).
+			Where(activeCol.Eq(true)).
+			OrderBy("name").
+			Limit(10).
+			buildQuery()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreate(b *testing.B) {
+	repo, err := NewRepository[TestUser](openBenchDB(b), createTestUserMetadata())
+	require.NoError(b, err)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		user := TestUser{Name: "Alice", Email: "alice@example.com", IsActive: true}
+		if _, err := repo.Create(ctx, &user); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Date is a calendar date without a time of day or time zone, stored in a
+// DATE column. Unlike a time.Time at midnight it never shifts a day when read
+// or written from a process in another time zone.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date t falls on in its own location
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date in YYYY-MM-DD form
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// String returns the date in YYYY-MM-DD form
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// IsZero reports whether d is the zero Date
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// In returns midnight at the start of d in loc, or in UTC when loc is nil
+func (d Date) In(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// AddDays returns the date n days after d
+func (d Date) AddDays(n int) Date {
+	return DateOf(d.In(time.UTC).AddDate(0, 0, n))
+}
+
+// Before reports whether d is before other
+func (d Date) Before(other Date) bool {
+	return d.In(time.UTC).Before(other.In(time.UTC))
+}
+
+// After reports whether d is after other
+func (d Date) After(other Date) bool {
+	return other.Before(d)
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = Date{}
+	case time.Time:
+		*d = DateOf(v)
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+	return nil
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(data []byte) error {
+	s := string(data)
+	if len(s) > len(time.DateOnly) {
+		s = s[:len(time.DateOnly)]
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// TimeOfDay is a wall clock time without a date or time zone, stored in a
+// TIME column
+type TimeOfDay struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+// TimeOfDayOf returns the wall clock time of t in its own location
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	return TimeOfDay{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second(), Nanosecond: t.Nanosecond()}
+}
+
+// ParseTimeOfDay parses a time in HH:MM:SS form with optional fractional seconds
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse(time.TimeOnly, s)
+	if err != nil {
+		return TimeOfDay{}, err
+	}
+	return TimeOfDayOf(t), nil
+}
+
+// String returns the time in HH:MM:SS form, with fractional seconds when set
+func (t TimeOfDay) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Nanosecond != 0 {
+		s += time.Date(0, 1, 1, 0, 0, 0, t.Nanosecond, time.UTC).Format(".999999999")
+	}
+	return s
+}
+
+func (t TimeOfDay) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+func (t *TimeOfDay) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*t = TimeOfDay{}
+	case time.Time:
+		*t = TimeOfDayOf(v)
+	case string:
+		return t.UnmarshalText([]byte(v))
+	case []byte:
+		return t.UnmarshalText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into TimeOfDay", value)
+	}
+	return nil
+}
+
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *TimeOfDay) UnmarshalText(data []byte) error {
+	parsed, err := ParseTimeOfDay(string(data))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// DateTime is a date and wall clock time without a time zone, stored in a
+// TIMESTAMP (without time zone) column
+type DateTime struct {
+	Date Date
+	Time TimeOfDay
+}
+
+// DateTimeOf returns the date and wall clock time of t in its own location
+func DateTimeOf(t time.Time) DateTime {
+	return DateTime{Date: DateOf(t), Time: TimeOfDayOf(t)}
+}
+
+// In returns the instant the date and time name in loc, or in UTC when loc is nil
+func (dt DateTime) In(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(dt.Date.Year, dt.Date.Month, dt.Date.Day,
+		dt.Time.Hour, dt.Time.Minute, dt.Time.Second, dt.Time.Nanosecond, loc)
+}
+
+// String returns the date and time in YYYY-MM-DDTHH:MM:SS form
+func (dt DateTime) String() string {
+	return dt.Date.String() + "T" + dt.Time.String()
+}
+
+func (dt DateTime) Value() (driver.Value, error) {
+	return dt.Date.String() + " " + dt.Time.String(), nil
+}
+
+func (dt *DateTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*dt = DateTime{}
+	case time.Time:
+		*dt = DateTimeOf(v)
+	case string:
+		return dt.UnmarshalText([]byte(v))
+	case []byte:
+		return dt.UnmarshalText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into DateTime", value)
+	}
+	return nil
+}
+
+func (dt DateTime) MarshalText() ([]byte, error) {
+	return []byte(dt.String()), nil
+}
+
+func (dt *DateTime) UnmarshalText(data []byte) error {
+	s := string(data)
+	if len(s) <= len(time.DateOnly) || (s[len(time.DateOnly)] != 'T' && s[len(time.DateOnly)] != ' ') {
+		return fmt.Errorf("invalid date and time %q", s)
+	}
+
+	date, err := ParseDate(s[:len(time.DateOnly)])
+	if err != nil {
+		return err
+	}
+	clock, err := ParseTimeOfDay(s[len(time.DateOnly)+1:])
+	if err != nil {
+		return err
+	}
+	*dt = DateTime{Date: date, Time: clock}
+	return nil
+}
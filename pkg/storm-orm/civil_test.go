@@ -0,0 +1,61 @@
+package orm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate(t *testing.T) {
+	d, err := ParseDate("2024-02-28")
+	require.NoError(t, err)
+	assert.Equal(t, Date{Year: 2024, Month: time.February, Day: 28}, d)
+	assert.Equal(t, "2024-02-29", d.AddDays(1).String())
+	assert.True(t, d.Before(d.AddDays(1)))
+	assert.True(t, d.AddDays(1).After(d))
+
+	value, err := d.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02-28", value)
+
+	var scanned Date
+	require.NoError(t, scanned.Scan(time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, d, scanned)
+	require.NoError(t, scanned.Scan([]byte("2024-02-28T00:00:00Z")))
+	assert.Equal(t, d, scanned)
+	require.NoError(t, scanned.Scan(nil))
+	assert.True(t, scanned.IsZero())
+	assert.Error(t, scanned.Scan(42))
+
+	data, err := json.Marshal(struct{ Due Date }{d})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Due":"2024-02-28"}`, string(data))
+}
+
+func TestTimeOfDay(t *testing.T) {
+	clock, err := ParseTimeOfDay("09:30:15.25")
+	require.NoError(t, err)
+	assert.Equal(t, TimeOfDay{Hour: 9, Minute: 30, Second: 15, Nanosecond: 250000000}, clock)
+	assert.Equal(t, "09:30:15.25", clock.String())
+
+	var scanned TimeOfDay
+	require.NoError(t, scanned.Scan("18:00:00"))
+	assert.Equal(t, TimeOfDay{Hour: 18}, scanned)
+}
+
+func TestDateTime(t *testing.T) {
+	var dt DateTime
+	require.NoError(t, dt.Scan("2024-03-10 02:30:00"))
+	assert.Equal(t, DateTime{Date: Date{Year: 2024, Month: time.March, Day: 10}, Time: TimeOfDay{Hour: 2, Minute: 30}}, dt)
+
+	value, err := dt.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-10 02:30:00", value)
+	assert.Equal(t, "2024-03-10T02:30:00", dt.String())
+	assert.Equal(t, time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC), dt.In(nil))
+
+	assert.Error(t, dt.Scan("2024-03-10"))
+}
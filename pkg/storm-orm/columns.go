@@ -2,9 +2,11 @@ package orm
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/eleven-am/storm/internal/dialect"
 )
 
 // Column represents a type-safe database column reference
@@ -15,33 +17,94 @@ type Column[T any] struct {
 
 func (c Column[T]) String() string {
 	if c.Table != "" {
-		return fmt.Sprintf("%s.%s", c.Table, c.Name)
+		return fmt.Sprintf("%s.%s", quoteIdent(c.Table), c.quotedName())
+	}
+	return c.quotedName()
+}
+
+// Qualified returns the column as a table.column reference with both parts
+// quoted where needed, for raw Join, OrderBy and Select fragments
+func (c Column[T]) Qualified() string {
+	if c.Table == "" {
+		return c.quotedName()
+	}
+	return quoteIdent(c.Table) + "." + dialect.QuoteIdentifierIfNeeded(c.Name)
+}
+
+// quotedName quotes reserved column names. Derived columns such as
+// ArrayColumn.Length carry an SQL expression as their name, so anything
+// other than a reserved keyword is left untouched.
+func (c Column[T]) quotedName() string {
+	if dialect.IsReservedKeyword(c.Name) {
+		return dialect.QuoteIdentifier(c.Name)
 	}
 	return c.Name
 }
 
 func (c Column[T]) Eq(value T) Condition {
-	return Condition{squirrel.Eq{c.String(): value}}
+	column := c.String()
+	return valueCondition(value, func(value interface{}) squirrel.Sqlizer {
+		return squirrel.Eq{column: value}
+	})
 }
 
 func (c Column[T]) NotEq(value T) Condition {
-	return Condition{squirrel.NotEq{c.String(): value}}
+	column := c.String()
+	return valueCondition(value, func(value interface{}) squirrel.Sqlizer {
+		return squirrel.NotEq{column: value}
+	})
 }
 
+// In matches any of values. Lists longer than the limit the querying Storm
+// was given with WithInListLimit are bound as a single array parameter.
 func (c Column[T]) In(values ...T) Condition {
-	interfaces := make([]interface{}, len(values))
-	for i, v := range values {
-		interfaces[i] = v
-	}
-	return Condition{squirrel.Eq{c.String(): interfaces}}
+	column := c.String()
+	return Condition{optionSqlizer(func(options *queryOptions) squirrel.Sqlizer {
+		if len(values) > 0 && isEncrypted(values[0]) {
+			return anyOf(options, column, bindAll(options, values))
+		}
+		return anyOf(options, column, values)
+	})}
 }
 
+// NotIn matches none of values, binding long lists like In
 func (c Column[T]) NotIn(values ...T) Condition {
-	interfaces := make([]interface{}, len(values))
-	for i, v := range values {
-		interfaces[i] = v
+	column := c.String()
+	return Condition{optionSqlizer(func(options *queryOptions) squirrel.Sqlizer {
+		if len(values) > 0 && isEncrypted(values[0]) {
+			return noneOf(options, column, bindAll(options, values))
+		}
+		return noneOf(options, column, values)
+	})}
+}
+
+// IsDistinctFrom is NotEq treating NULL as a comparable value, so it also
+// matches rows where the column is NULL
+func (c Column[T]) IsDistinctFrom(value T) Condition {
+	column := c.String()
+	return valueCondition(value, func(value interface{}) squirrel.Sqlizer {
+		return squirrel.Expr(column+" IS DISTINCT FROM ?", value)
+	})
+}
+
+// IsNotDistinctFrom is Eq treating NULL as a comparable value
+func (c Column[T]) IsNotDistinctFrom(value T) Condition {
+	column := c.String()
+	return valueCondition(value, func(value interface{}) squirrel.Sqlizer {
+		return squirrel.Expr(column+" IS NOT DISTINCT FROM ?", value)
+	})
+}
+
+// valueCondition builds a condition comparing against value. Encrypted values
+// are bound to the keys of the querying Storm, so the condition is deferred
+// to the query.
+func valueCondition(value interface{}, build func(value interface{}) squirrel.Sqlizer) Condition {
+	if !isEncrypted(value) {
+		return Condition{build(value)}
 	}
-	return Condition{squirrel.NotEq{c.String(): interfaces}}
+	return Condition{optionSqlizer(func(options *queryOptions) squirrel.Sqlizer {
+		return build(options.bind(value))
+	})}
 }
 
 func (c Column[T]) IsNull() Condition {
@@ -97,6 +160,11 @@ func (c ComparableColumn[T]) Between(min, max T) Condition {
 	}}
 }
 
+// NotBetween matches values below min or above max
+func (c ComparableColumn[T]) NotBetween(min, max T) Condition {
+	return Condition{squirrel.Expr(c.String()+" NOT BETWEEN ? AND ?", min, max)}
+}
+
 // StringColumn provides string-specific operations
 type StringColumn struct {
 	Column[string]
@@ -110,6 +178,42 @@ func (c StringColumn) ILike(pattern string) Condition {
 	return Condition{squirrel.ILike{c.String(): pattern}}
 }
 
+func (c StringColumn) NotLike(pattern string) Condition {
+	return Condition{squirrel.NotLike{c.String(): pattern}}
+}
+
+func (c StringColumn) NotILike(pattern string) Condition {
+	return Condition{squirrel.NotILike{c.String(): pattern}}
+}
+
+// SimilarTo matches an SQL regular expression, which like LIKE must match the
+// whole value
+func (c StringColumn) SimilarTo(pattern string) Condition {
+	return Condition{squirrel.Expr(c.String()+" SIMILAR TO ?", pattern)}
+}
+
+func (c StringColumn) NotSimilarTo(pattern string) Condition {
+	return Condition{squirrel.Expr(c.String()+" NOT SIMILAR TO ?", pattern)}
+}
+
+// IEq matches value ignoring case, as lower(column) = lower(value), which a
+// ci_index on the column serves
+func (c StringColumn) IEq(value string) Condition {
+	return Condition{squirrel.Expr(foldedExpr(c.String(), false)+" = "+foldedExpr("?", false), value)}
+}
+
+// ISearch matches a LIKE pattern ignoring case and, once SetUnaccent or
+// DetectUnaccent has enabled it on the Storm running the query, accents.
+// Patterns anchored at the start, such as "jose%", can use a ci_index on the
+// column.
+func (c StringColumn) ISearch(pattern string) Condition {
+	column := c.String()
+	return Condition{optionSqlizer(func(options *queryOptions) squirrel.Sqlizer {
+		unaccent := options.unaccentEnabled()
+		return squirrel.Expr(foldedExpr(column, unaccent)+" LIKE "+foldedExpr("?", unaccent), pattern)
+	})}
+}
+
 func (c StringColumn) StartsWith(prefix string) Condition {
 	return c.Like(prefix + "%")
 }
@@ -199,6 +303,120 @@ func (c TimeColumn) LastNDays(days int) Condition {
 	return c.Between(start, now)
 }
 
+// DateEq matches instants falling on date d in loc (UTC when nil). The
+// comparison is a range on the column, so it neither depends on the session
+// time zone the way a ::date cast does nor prevents index use.
+func (c TimeColumn) DateEq(d Date, loc *time.Location) Condition {
+	return c.DateBetween(d, d, loc)
+}
+
+// DateBetween matches instants from the start of from to the end of to,
+// both inclusive, in loc (UTC when nil)
+func (c TimeColumn) DateBetween(from, to Date, loc *time.Location) Condition {
+	return Condition{squirrel.And{
+		squirrel.GtOrEq{c.String(): from.In(loc)},
+		squirrel.Lt{c.String(): to.AddDays(1).In(loc)},
+	}}
+}
+
+// DateColumn provides operations for DATE columns holding a Date
+type DateColumn struct {
+	Column[Date]
+}
+
+func (c DateColumn) Before(d Date) Condition {
+	return Condition{squirrel.Lt{c.String(): d}}
+}
+
+func (c DateColumn) After(d Date) Condition {
+	return Condition{squirrel.Gt{c.String(): d}}
+}
+
+func (c DateColumn) OnOrBefore(d Date) Condition {
+	return Condition{squirrel.LtOrEq{c.String(): d}}
+}
+
+func (c DateColumn) OnOrAfter(d Date) Condition {
+	return Condition{squirrel.GtOrEq{c.String(): d}}
+}
+
+// Between matches dates from from to to, both inclusive
+func (c DateColumn) Between(from, to Date) Condition {
+	return Condition{squirrel.And{
+		squirrel.GtOrEq{c.String(): from},
+		squirrel.LtOrEq{c.String(): to},
+	}}
+}
+
+// NotBetween matches dates before from or after to
+func (c DateColumn) NotBetween(from, to Date) Condition {
+	return Condition{squirrel.Expr(c.String()+" NOT BETWEEN ? AND ?", from, to)}
+}
+
+// IntervalColumn provides operations for INTERVAL columns holding a
+// time.Duration. Durations are bound as Interval so PostgreSQL compares them
+// as intervals rather than as numbers.
+type IntervalColumn struct {
+	Column[time.Duration]
+}
+
+func (c IntervalColumn) Eq(d time.Duration) Condition {
+	return Condition{squirrel.Eq{c.String(): Interval(d)}}
+}
+
+func (c IntervalColumn) NotEq(d time.Duration) Condition {
+	return Condition{squirrel.NotEq{c.String(): Interval(d)}}
+}
+
+func (c IntervalColumn) In(values ...time.Duration) Condition {
+	column, list := c.String(), intervals(values)
+	return Condition{optionSqlizer(func(options *queryOptions) squirrel.Sqlizer {
+		return anyOf(options, column, list)
+	})}
+}
+
+func (c IntervalColumn) NotIn(values ...time.Duration) Condition {
+	column, list := c.String(), intervals(values)
+	return Condition{optionSqlizer(func(options *queryOptions) squirrel.Sqlizer {
+		return noneOf(options, column, list)
+	})}
+}
+
+func (c IntervalColumn) Gt(d time.Duration) Condition {
+	return Condition{squirrel.Gt{c.String(): Interval(d)}}
+}
+
+func (c IntervalColumn) Gte(d time.Duration) Condition {
+	return Condition{squirrel.GtOrEq{c.String(): Interval(d)}}
+}
+
+func (c IntervalColumn) Lt(d time.Duration) Condition {
+	return Condition{squirrel.Lt{c.String(): Interval(d)}}
+}
+
+func (c IntervalColumn) Lte(d time.Duration) Condition {
+	return Condition{squirrel.LtOrEq{c.String(): Interval(d)}}
+}
+
+func (c IntervalColumn) Between(min, max time.Duration) Condition {
+	return Condition{squirrel.And{
+		squirrel.GtOrEq{c.String(): Interval(min)},
+		squirrel.LtOrEq{c.String(): Interval(max)},
+	}}
+}
+
+func (c IntervalColumn) NotBetween(min, max time.Duration) Condition {
+	return Condition{squirrel.Expr(c.String()+" NOT BETWEEN ? AND ?", Interval(min), Interval(max))}
+}
+
+func intervals(values []time.Duration) []Interval {
+	converted := make([]Interval, len(values))
+	for i, d := range values {
+		converted[i] = Interval(d)
+	}
+	return converted
+}
+
 // BoolColumn provides boolean-specific operations
 type BoolColumn struct {
 	Column[bool]
@@ -221,6 +439,11 @@ func (c ArrayColumn[T]) Contains(value T) Condition {
 	return Condition{squirrel.Expr(c.String()+" @> ARRAY[?]", value)}
 }
 
+// Any matches rows whose array holds value, as "value = ANY(column)"
+func (c ArrayColumn[T]) Any(value T) Condition {
+	return Condition{squirrel.Expr("? = ANY("+c.String()+")", value)}
+}
+
 func (c ArrayColumn[T]) ContainedBy(values []T) Condition {
 	return Condition{squirrel.Expr(c.String()+" <@ ?", values)}
 }
@@ -291,32 +514,122 @@ func (c JSONBColumn) HasAllKeys(keys []string) Condition {
 	return Condition{squirrel.Expr(c.String()+" ?& ?", keys)}
 }
 
+// EncryptedColumn references a column holding EncryptedString values. Its
+// ciphertext changes on every write, so it supports only null checks and
+// assignment; use Column[DeterministicString] for columns that are searched.
+type EncryptedColumn struct {
+	Name  string
+	Table string
+}
+
+func (c EncryptedColumn) column() Column[EncryptedString] {
+	return Column[EncryptedString]{Name: c.Name, Table: c.Table}
+}
+
+func (c EncryptedColumn) String() string {
+	return c.column().String()
+}
+
+func (c EncryptedColumn) Qualified() string {
+	return c.column().Qualified()
+}
+
+func (c EncryptedColumn) IsNull() Condition {
+	return c.column().IsNull()
+}
+
+func (c EncryptedColumn) IsNotNull() Condition {
+	return c.column().IsNotNull()
+}
+
+func (c EncryptedColumn) Set(value EncryptedString) Action {
+	return c.column().Set(value)
+}
+
+func (c EncryptedColumn) SetNull() Action {
+	return c.column().SetNull()
+}
+
 // Condition wraps squirrel conditions for type safety
 type Condition struct {
 	condition squirrel.Sqlizer
 }
 
+// optionSqlizer is a condition whose SQL depends on the options of the Storm
+// running the query, such as whether ISearch ignores accents. Outside a query
+// it renders with the default options.
+type optionSqlizer func(options *queryOptions) squirrel.Sqlizer
+
+func (f optionSqlizer) ToSql() (string, []interface{}, error) {
+	return f(nil).ToSql()
+}
+
+// sqlizer renders c for a query run with options
+func (c Condition) sqlizer(options *queryOptions) squirrel.Sqlizer {
+	if render, ok := c.condition.(optionSqlizer); ok {
+		return render(options)
+	}
+	return c.condition
+}
+
+// joinConditions combines conditions with join, deferring the result to the
+// query when any of them depends on its options
+func joinConditions(conditions []Condition, join func([]squirrel.Sqlizer) squirrel.Sqlizer) Condition {
+	render := func(options *queryOptions) squirrel.Sqlizer {
+		sqlizers := make([]squirrel.Sqlizer, len(conditions))
+		for i, c := range conditions {
+			sqlizers[i] = c.sqlizer(options)
+		}
+		return join(sqlizers)
+	}
+	for _, c := range conditions {
+		if _, ok := c.condition.(optionSqlizer); ok {
+			return Condition{optionSqlizer(render)}
+		}
+	}
+	return Condition{render(nil)}
+}
+
 func (c Condition) And(other Condition) Condition {
-	return Condition{squirrel.And{c.condition, other.condition}}
+	return joinConditions([]Condition{c, other}, func(sqlizers []squirrel.Sqlizer) squirrel.Sqlizer {
+		return squirrel.And(sqlizers)
+	})
 }
 
 func (c Condition) Or(other Condition) Condition {
-	return Condition{squirrel.Or{c.condition, other.condition}}
+	return joinConditions([]Condition{c, other}, func(sqlizers []squirrel.Sqlizer) squirrel.Sqlizer {
+		return squirrel.Or(sqlizers)
+	})
 }
 
 func (c Condition) Not() Condition {
-	return Condition{squirrel.Expr("NOT (?)", c.condition)}
+	return joinConditions([]Condition{c}, func(sqlizers []squirrel.Sqlizer) squirrel.Sqlizer {
+		return squirrel.Expr("NOT (?)", sqlizers[0])
+	})
+}
+
+// AndNot matches c unless other also matches
+func (c Condition) AndNot(other Condition) Condition {
+	return c.And(other.Not())
+}
+
+// OrNot matches c or anything other does not match
+func (c Condition) OrNot(other Condition) Condition {
+	return c.Or(other.Not())
 }
 
 func (c Condition) ToSqlizer() squirrel.Sqlizer {
 	return c.condition
 }
 
-// Action represents a type-safe database update operation
+// Action represents a type-safe database update operation: target is the
+// assigned column and set the SQL it is assigned, with ? for each argument
 type Action struct {
-	column     string
-	expression string
-	value      interface{}
+	column string
+	target string
+	set    string
+	value  interface{}
+	err    error
 }
 
 func (a Action) Column() string {
@@ -324,7 +637,19 @@ func (a Action) Column() string {
 }
 
 func (a Action) Expression() string {
-	return a.expression
+	return a.target + " = " + a.set
+}
+
+// assignment returns the right-hand side of the action with its arguments
+// bound, so it can be added to an update builder of a Storm with options
+func (a Action) assignment(options *queryOptions) squirrel.Sqlizer {
+	if values, ok := a.value.([]interface{}); ok {
+		return squirrel.Expr(a.set, values...)
+	}
+	if strings.Contains(a.set, "?") {
+		return squirrel.Expr(a.set, options.bind(a.value))
+	}
+	return squirrel.Expr(a.set)
 }
 
 func (a Action) Value() interface{} {
@@ -334,157 +659,245 @@ func (a Action) Value() interface{} {
 // Column action methods
 func (c Column[T]) Set(value T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = ?",
-		value:      value,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "?",
+		value:  value,
 	}
 }
 
 func (c Column[T]) SetNull() Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = NULL",
-		value:      nil,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "NULL",
+		value:  nil,
 	}
 }
 
 func (c Column[T]) SetDefault() Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = DEFAULT",
-		value:      nil,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "DEFAULT",
+		value:  nil,
 	}
 }
 
 // NumericColumn action methods
 func (c NumericColumn[T]) Increment(amount T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " + ?",
-		value:      amount,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " + ?",
+		value:  amount,
 	}
 }
 
 func (c NumericColumn[T]) Decrement(amount T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " - ?",
-		value:      amount,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " - ?",
+		value:  amount,
 	}
 }
 
 func (c NumericColumn[T]) Multiply(factor T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " * ?",
-		value:      factor,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " * ?",
+		value:  factor,
 	}
 }
 
 // TimeColumn action methods
 func (c TimeColumn) SetNow() Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = NOW()",
-		value:      nil,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "NOW()",
+		value:  nil,
 	}
 }
 
 func (c TimeColumn) SetCurrentTimestamp() Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = CURRENT_TIMESTAMP",
-		value:      nil,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "CURRENT_TIMESTAMP",
+		value:  nil,
+	}
+}
+
+// Add returns an action that moves the timestamp forward by d
+func (c TimeColumn) Add(d time.Duration) Action {
+	return Action{
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " + ?::interval",
+		value:  Interval(d),
+	}
+}
+
+// Subtract returns an action that moves the timestamp back by d
+func (c TimeColumn) Subtract(d time.Duration) Action {
+	return Action{
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " - ?::interval",
+		value:  Interval(d),
+	}
+}
+
+// IntervalColumn action methods
+func (c IntervalColumn) Set(d time.Duration) Action {
+	return Action{
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "?",
+		value:  Interval(d),
+	}
+}
+
+func (c IntervalColumn) Add(d time.Duration) Action {
+	return Action{
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " + ?::interval",
+		value:  Interval(d),
+	}
+}
+
+func (c IntervalColumn) Subtract(d time.Duration) Action {
+	return Action{
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " - ?::interval",
+		value:  Interval(d),
 	}
 }
 
 // StringColumn action methods
 func (c StringColumn) Concat(suffix string) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " || ?",
-		value:      suffix,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " || ?",
+		value:  suffix,
 	}
 }
 
 func (c StringColumn) Prepend(prefix string) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = ? || " + c.Name,
-		value:      prefix,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "? || " + c.quotedName(),
+		value:  prefix,
 	}
 }
 
 func (c StringColumn) Upper() Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = UPPER(" + c.Name + ")",
-		value:      nil,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "UPPER(" + c.quotedName() + ")",
+		value:  nil,
 	}
 }
 
 func (c StringColumn) Lower() Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = LOWER(" + c.Name + ")",
-		value:      nil,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "LOWER(" + c.quotedName() + ")",
+		value:  nil,
 	}
 }
 
 // ArrayColumn action methods
 func (c ArrayColumn[T]) Append(value T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = array_append(" + c.Name + ", ?)",
-		value:      value,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "array_append(" + c.quotedName() + ", ?)",
+		value:  value,
 	}
 }
 
 func (c ArrayColumn[T]) Prepend(value T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = array_prepend(?, " + c.Name + ")",
-		value:      value,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "array_prepend(?, " + c.quotedName() + ")",
+		value:  value,
 	}
 }
 
 func (c ArrayColumn[T]) Remove(value T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = array_remove(" + c.Name + ", ?)",
-		value:      value,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "array_remove(" + c.quotedName() + ", ?)",
+		value:  value,
 	}
 }
 
 func (c ArrayColumn[T]) Concat(values []T) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " || ?",
-		value:      values,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " || ?",
+		value:  values,
 	}
 }
 
 // JSONBColumn action methods
 func (c JSONBColumn) SetPath(path string, value interface{}) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = jsonb_set(" + c.Name + ", ?, ?)",
-		value:      []interface{}{"{" + path + "}", value},
+		column: c.String(),
+		target: c.quotedName(),
+		set:    "jsonb_set(" + c.quotedName() + ", ?, ?)",
+		value:  []interface{}{"{" + path + "}", value},
 	}
 }
 
 func (c JSONBColumn) RemovePath(path string) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " - ?",
-		value:      path,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " - ?",
+		value:  path,
 	}
 }
 
 func (c JSONBColumn) Merge(jsonValue interface{}) Action {
 	return Action{
-		column:     c.String(),
-		expression: c.Name + " = " + c.Name + " || ?",
-		value:      jsonValue,
+		column: c.String(),
+		target: c.quotedName(),
+		set:    c.quotedName() + " || ?",
+		value:  jsonValue,
+	}
+}
+
+// ColumnRef is a column that can be written into raw SQL fragments
+type ColumnRef interface {
+	Qualified() string
+}
+
+// On returns the join condition "left = right" between two columns, such as
+// On(models.Posts.UserID, models.Users.ID), so join strings follow renames
+func On(left, right ColumnRef) string {
+	return left.Qualified() + " = " + right.Qualified()
+}
+
+// ColumnList returns a comma separated list of qualified columns, for Select
+// and GroupBy fragments
+func ColumnList(columns ...ColumnRef) string {
+	qualified := make([]string, len(columns))
+	for i, column := range columns {
+		qualified[i] = column.Qualified()
 	}
+	return strings.Join(qualified, ", ")
 }
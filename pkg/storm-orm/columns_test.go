@@ -1,10 +1,15 @@
 package orm
 
 import (
+	"context"
+	"database/sql/driver"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
 )
 
 func TestStringColumn(t *testing.T) {
@@ -204,6 +209,45 @@ func TestTimeColumn(t *testing.T) {
 	}
 }
 
+func TestDateConditions(t *testing.T) {
+	createdAt := TimeColumn{
+		ComparableColumn: ComparableColumn[time.Time]{
+			Column: Column[time.Time]{Name: "created_at", Table: "orders"},
+		},
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	day := Date{Year: 2024, Month: time.March, Day: 10}
+	sql, args, err := createdAt.DateEq(day, newYork).ToSqlizer().ToSql()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sql != "(orders.created_at >= ? AND orders.created_at < ?)" {
+		t.Errorf("unexpected SQL %q", sql)
+	}
+	if len(args) != 2 || !args[0].(time.Time).Equal(time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)) ||
+		!args[1].(time.Time).Equal(time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the New York day across the DST change, got %v", args)
+	}
+
+	_, args, _ = createdAt.DateBetween(day, day.AddDays(2), nil).ToSqlizer().ToSql()
+	if !args[1].(time.Time).Equal(time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the range to end after the last day, got %v", args[1])
+	}
+
+	dueOn := DateColumn{Column: Column[Date]{Name: "due_on", Table: "orders"}}
+	sql, args, err = dueOn.Between(day, day.AddDays(30)).ToSqlizer().ToSql()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sql != "(orders.due_on >= ? AND orders.due_on <= ?)" || args[1] != "2024-04-09" {
+		t.Errorf("unexpected date range %q %v", sql, args)
+	}
+}
+
 func TestBoolColumn(t *testing.T) {
 	col := BoolColumn{Column: Column[bool]{Name: "is_active", Table: "users"}}
 
@@ -451,3 +495,125 @@ func TestConditionSqlizer(t *testing.T) {
 		})
 	}
 }
+
+func TestInListLimit(t *testing.T) {
+	options := newQueryOptions()
+	options.inListLimit = 3
+
+	col := Column[int64]{Name: "id", Table: "users"}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		expected  string
+		args      int
+	}{
+		{"In at the limit", col.In(1, 2, 3), "users.id IN (?,?,?)", 3},
+		{"In over the limit", col.In(1, 2, 3, 4), "users.id = ANY(?)", 1},
+		{"NotIn over the limit", col.NotIn(1, 2, 3, 4), "users.id <> ALL(?)", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.condition.sqlizer(options).ToSql()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tt.expected {
+				t.Errorf("expected SQL %q, got %q", tt.expected, sql)
+			}
+			if len(args) != tt.args {
+				t.Errorf("expected %d args, got %d", tt.args, len(args))
+			}
+		})
+	}
+
+	t.Run("array parameter", func(t *testing.T) {
+		_, args, _ := col.In(1, 2, 3, 4).sqlizer(options).ToSql()
+		value, err := args[0].(driver.Valuer).Value()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if value != "{1,2,3,4}" {
+			t.Errorf("expected {1,2,3,4}, got %v", value)
+		}
+	})
+
+	t.Run("zero keeps lists inline", func(t *testing.T) {
+		sql, _, _ := col.In(1, 2, 3, 4).sqlizer(&queryOptions{}).ToSql()
+		if sql != "users.id IN (?,?,?,?)" {
+			t.Errorf("expected an inline list, got %q", sql)
+		}
+	})
+
+	t.Run("outside a Storm the default limit applies", func(t *testing.T) {
+		sql, _, _ := col.In(1, 2, 3, 4).ToSqlizer().ToSql()
+		if sql != "users.id IN (?,?,?,?)" {
+			t.Errorf("expected an inline list, got %q", sql)
+		}
+	})
+
+	t.Run("each Storm keeps its own limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer db.Close()
+		sqlxDB := sqlx.NewDb(db, "postgres")
+
+		limited, err := NewRepositoryForStorm[TestUser](NewStormWithOptions(sqlxDB, WithInListLimit(3)), createTestUserMetadata())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		unlimited, err := NewRepositoryForStorm[TestUser](NewStorm(sqlxDB), createTestUserMetadata())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE (users.id = ANY($1))")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE (users.id IN ($1,$2,$3,$4))")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		condition := col.In(1, 2, 3, 4)
+		if _, err := limited.Query(context.Background()).Where(condition).Find(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := unlimited.Query(context.Background()).Where(condition).Find(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestColumnFragments(t *testing.T) {
+	userID := StringColumn{Column: Column[string]{Name: "user_id", Table: "posts"}}
+	id := StringColumn{Column: Column[string]{Name: "id", Table: "user"}}
+	order := NumericColumn[int]{ComparableColumn: ComparableColumn[int]{Column: Column[int]{Name: "order", Table: "public.items"}}}
+	secret := EncryptedColumn{Name: "ssn", Table: "people"}
+
+	tests := []struct {
+		name     string
+		result   string
+		expected string
+	}{
+		{"Qualified", userID.Qualified(), "posts.user_id"},
+		{"Qualified reserved table", id.Qualified(), `"user".id`},
+		{"Qualified reserved column", order.Qualified(), `public.items."order"`},
+		{"Qualified encrypted", secret.Qualified(), "people.ssn"},
+		{"Qualified without table", Column[int]{Name: "total"}.Qualified(), "total"},
+		{"On", On(userID, id), `posts.user_id = "user".id`},
+		{"ColumnList", ColumnList(id, userID, order), `"user".id, posts.user_id, public.items."order"`},
+		{"QuoteIdent", QuoteIdent("group.select"), `"group"."select"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, tt.result)
+			}
+		})
+	}
+}
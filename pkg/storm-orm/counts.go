@@ -0,0 +1,179 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// IncludeCount loads the number of related rows for each relationship without
+// loading the rows themselves, counting the rows Include would load. Counts
+// are stored through the relationship's generated SetCount function, which
+// fills the <Relationship>Count field of relationships tagged count.
+func (q *Query[T]) IncludeCount(relationships ...string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.counts = append(q.counts, relationships...)
+	return q
+}
+
+func (q *Query[T]) findWithCounts() ([]T, error) {
+	counts := q.counts
+	q.counts = nil
+
+	records, err := q.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range counts {
+		if err := q.loadCount(records, name); err != nil {
+			return nil, fmt.Errorf("failed to count relationship %s: %w", name, err)
+		}
+	}
+
+	return records, nil
+}
+
+// loadCount counts the related rows of every record with one grouped query
+func (q *Query[T]) loadCount(records []T, name string) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	relationship := q.repo.getRelationship(name)
+	if relationship == nil {
+		return fmt.Errorf("relationship %s not found", name)
+	}
+	if relationship.SetCount == nil {
+		return fmt.Errorf("relationship %s does not have a SetCount function", name)
+	}
+
+	sourceKey := relationship.SourceKey
+	if sourceKey == "" {
+		sourceKey = "id"
+	}
+	sourceColumn := q.repo.columnByDBName(sourceKey)
+	if sourceColumn == nil {
+		return fmt.Errorf("source key column %s not found", sourceKey)
+	}
+
+	keys := make([]interface{}, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for i := range records {
+		value := sourceColumn.GetValue(records[i])
+		if value == nil || isZeroValue(value) || seen[fmt.Sprint(value)] {
+			continue
+		}
+		seen[fmt.Sprint(value)] = true
+		keys = append(keys, value)
+	}
+
+	counts := make(map[string]int64, len(keys))
+	if len(keys) > 0 {
+		query, err := q.buildCountQuery(relationship, keys)
+		if err != nil {
+			return err
+		}
+		if err := q.scanCounts(query, counts); err != nil {
+			return err
+		}
+	}
+
+	for i := range records {
+		count := counts[fmt.Sprint(sourceColumn.GetValue(records[i]))]
+		if relationship.Type == "has_one" && count > 1 {
+			// Include loads only the first of several matching rows
+			count = 1
+		}
+		relationship.SetCount(&records[i], count)
+	}
+
+	return nil
+}
+
+// buildCountQuery counts the related rows of each key, applying the same
+// conditions and default scopes as loading the relationship so the counts
+// agree with Include. Ordering does not change a count and is left out.
+func (q *Query[T]) buildCountQuery(relationship *RelationshipMetadata, keys []interface{}) (squirrel.SelectBuilder, error) {
+	tableName := relationship.TargetTable
+	if tableName == "" {
+		tableName = relationship.Target
+	}
+
+	var query squirrel.SelectBuilder
+	switch relationship.Type {
+	case "has_one", "has_many":
+		foreignKey := quoteIdent(relationship.ForeignKey)
+		query = squirrel.Select(foreignKey, "COUNT(*)").
+			From(quoteIdent(tableName)).
+			Where(anyOf(q.repo.options, foreignKey, keys)).
+			GroupBy(foreignKey)
+		if relationship.isPolymorphic() {
+			query = query.Where(polymorphicCondition(relationship))
+		}
+
+	case "has_many_through":
+		throughFK := "jt." + quoteIdent(relationship.ThroughFK)
+		query = squirrel.Select(throughFK, "COUNT(*)").
+			From(quoteIdent(tableName) + " t").
+			InnerJoin(fmt.Sprintf("%s jt ON t.%s = jt.%s",
+				quoteIdent(relationship.Through),
+				quoteIdent(relationship.TargetKey),
+				quoteIdent(relationship.ThroughTK))).
+			Where(anyOf(q.repo.options, throughFK, keys)).
+			GroupBy(throughFK)
+
+	default:
+		return squirrel.SelectBuilder{}, fmt.Errorf("cannot count %s relationship %s", relationship.Type, relationship.Name)
+	}
+
+	for _, condition := range relationship.scopeConditions() {
+		query = query.Where(squirrel.Expr(condition))
+	}
+	return query.PlaceholderFormat(squirrel.Dollar), nil
+}
+
+func (q *Query[T]) scanCounts(query squirrel.SelectBuilder, counts map[string]int64) error {
+	return q.repo.executeQueryMiddleware(OpQuery, q.ctx, nil, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "count",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build count query: %w", err),
+			}
+		}
+
+		var executor DBExecutor = q.repo.db
+		if q.tx != nil {
+			executor = q.tx
+		}
+
+		rows, err := executor.QueryContext(q.ctx, sqlQuery, args...)
+		if err != nil {
+			return parsePostgreSQLError(err, "count", q.repo.metadata.TableName)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key interface{}
+			var count int64
+			if err := rows.Scan(&key, &count); err != nil {
+				return &Error{
+					Op:    "count",
+					Table: q.repo.metadata.TableName,
+					Err:   fmt.Errorf("failed to scan count: %w", err),
+				}
+			}
+			if b, ok := key.([]byte); ok {
+				key = string(b)
+			}
+			counts[fmt.Sprint(key)] = count
+		}
+		return rows.Err()
+	})
+}
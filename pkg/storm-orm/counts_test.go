@@ -0,0 +1,128 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAuthor struct {
+	ID         int `db:"id"`
+	PostsCount int `db:"-"`
+	TagsCount  int `db:"-"`
+}
+
+func createTestAuthorMetadata() *ModelMetadata {
+	return &ModelMetadata{
+		TableName:  "authors",
+		StructName: "Author",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:    "ID",
+				DBName:       "id",
+				IsPrimaryKey: true,
+				GetValue:     func(model interface{}) interface{} { return model.(testAuthor).ID },
+			},
+		},
+		ReverseMap:  map[string]string{"id": "ID"},
+		PrimaryKeys: []string{"id"},
+		Relationships: map[string]*RelationshipMetadata{
+			"Posts": {
+				Name:        "Posts",
+				Type:        "has_many",
+				Target:      "Post",
+				TargetTable: "posts",
+				ForeignKey:   "author_id",
+				SourceKey:    "id",
+				Conditions:   []string{"published = true"},
+				OrderBy:      "created_at DESC",
+				TargetScopes: []string{"deleted_at IS NULL"},
+				SetCount: func(model interface{}, count int64) {
+					model.(*testAuthor).PostsCount = int(count)
+				},
+			},
+			"Tags": {
+				Name:         "Tags",
+				Type:         "has_many_through",
+				Target:       "Tag",
+				TargetTable:  "tags",
+				Through:      "author_tags",
+				ThroughFK:    "author_id",
+				ThroughTK:    "tag_id",
+				TargetKey:    "id",
+				SourceKey:    "id",
+				TargetScopes: []string{"archived = false"},
+				SetCount: func(model interface{}, count int64) {
+					model.(*testAuthor).TagsCount = int(count)
+				},
+			},
+			"Profile": {
+				Name:        "Profile",
+				Type:        "has_one",
+				Target:      "Profile",
+				TargetTable: "profiles",
+				ForeignKey:  "author_id",
+				SourceKey:   "id",
+			},
+		},
+	}
+}
+
+func TestIncludeCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[testAuthor](sqlx.NewDb(db, "postgres"), createTestAuthorMetadata())
+	require.NoError(t, err)
+
+	t.Run("counts related rows with one grouped query", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT id FROM authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+		mock.ExpectQuery(`SELECT author_id, COUNT\(\*\) FROM posts WHERE author_id IN \(\$1,\$2,\$3\) AND published = true AND deleted_at IS NULL GROUP BY author_id$`).
+			WithArgs(1, 2, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"author_id", "count"}).AddRow(int64(1), int64(4)).AddRow(int64(3), int64(1)))
+
+		authors, err := repo.Query(context.Background()).IncludeCount("Posts").Find()
+		require.NoError(t, err)
+		require.Len(t, authors, 3)
+		assert.Equal(t, 4, authors[0].PostsCount)
+		assert.Equal(t, 0, authors[1].PostsCount)
+		assert.Equal(t, 1, authors[2].PostsCount)
+	})
+
+	t.Run("counts the targets a through relationship loads", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT id FROM authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(`SELECT jt.author_id, COUNT\(\*\) FROM tags t INNER JOIN author_tags jt ON t.id = jt.tag_id WHERE jt.author_id IN \(\$1\) AND archived = false GROUP BY jt.author_id$`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"author_id", "count"}).AddRow(int64(1), int64(2)))
+
+		authors, err := repo.Query(context.Background()).IncludeCount("Tags").Find()
+		require.NoError(t, err)
+		require.Len(t, authors, 1)
+		assert.Equal(t, 2, authors[0].TagsCount)
+	})
+
+	t.Run("requires a count field", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT id FROM authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		_, err := repo.Query(context.Background()).IncludeCount("Profile").Find()
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown relationship", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT id FROM authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		_, err := repo.Query(context.Background()).IncludeCount("Missing").Find()
+		assert.Error(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
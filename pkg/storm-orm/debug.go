@@ -0,0 +1,140 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type debugLoggerKey struct{}
+
+// ToSQL returns the SELECT statement Find would run, with its arguments,
+// without executing it
+func (q *Query[T]) ToSQL() (string, []interface{}, error) {
+	q.applyDefaultScopes()
+	return q.buildQuery()
+}
+
+// ToUpdateSQL returns the UPDATE statement Update would run for actions, with
+// its arguments, without executing it
+func (q *Query[T]) ToUpdateSQL(actions ...Action) (string, []interface{}, error) {
+	query, err := q.updateBuilder(actions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, &Error{
+			Op:    "update",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("failed to build query: %w", err),
+		}
+	}
+	return sqlQuery, args, nil
+}
+
+// Debug logs every statement this query runs, with its arguments inlined, to
+// logger or, when none is given, to a SimpleQueryLogger. The inlined form is for
+// reading only; the statement itself still runs with bound parameters.
+func (q *Query[T]) Debug(logger ...QueryLogger) *Query[T] {
+	var l QueryLogger = &SimpleQueryLogger{}
+	if len(logger) > 0 && logger[0] != nil {
+		l = logger[0]
+	}
+	q.ctx = context.WithValue(q.ctx, debugLoggerKey{}, l)
+	return q
+}
+
+// debugLogged wraps finalFunc so the statement it runs is logged when the
+// context was marked by Debug
+func debugLogged(ctx context.Context, finalFunc QueryMiddlewareFunc) QueryMiddlewareFunc {
+	logger, ok := ctx.Value(debugLoggerKey{}).(QueryLogger)
+	if !ok {
+		return finalFunc
+	}
+
+	return func(middlewareCtx *MiddlewareContext) error {
+		start := time.Now()
+		err := finalFunc(middlewareCtx)
+		args := redactArgs(logger, middlewareCtx.Query, middlewareCtx.Args)
+		logger.LogQuery(InterpolateSQL(middlewareCtx.Query, args), nil, time.Since(start), err)
+		return err
+	}
+}
+
+// InterpolateSQL inlines args into the $n placeholders of query as SQL
+// literals, for logging. Strings are quoted and escaped, but the result is
+// meant to be read, not executed.
+func InterpolateSQL(query string, args []interface{}) string {
+	var out strings.Builder
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(query[i+1:], '\'')
+			if end < 0 {
+				out.WriteString(query[i:])
+				return out.String()
+			}
+			out.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			end := i + 1
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				end++
+			}
+			n, _ := strconv.Atoi(query[i+1 : end])
+			if n < 1 || n > len(args) {
+				out.WriteString(query[i:end])
+			} else {
+				out.WriteString(sqlLiteral(args[n-1]))
+			}
+			i = end - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String()
+}
+
+// sqlLiteral renders value as a PostgreSQL literal
+func sqlLiteral(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "'<" + err.Error() + ">'"
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case []byte:
+		return `'\x` + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return quoteLiteral(v.Format(time.RFC3339Nano))
+	case string:
+		return quoteLiteral(v)
+	default:
+		return quoteLiteral(fmt.Sprint(v))
+	}
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,146 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogger struct {
+	queries []string
+	errs    []error
+}
+
+func (l *recordingLogger) LogQuery(query string, args []interface{}, duration time.Duration, err error) {
+	l.queries = append(l.queries, query)
+	l.errs = append(l.errs, err)
+}
+
+func TestQueryToSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	idCol := Column[int]{Name: "id", Table: "users"}
+
+	t.Run("select", func(t *testing.T) {
+		query, args, err := repo.Query(context.Background()).
+			Where(nameCol.Eq("Ada")).
+			Limit(5).
+			ToSQL()
+
+		require.NoError(t, err)
+		assert.Contains(t, query, "FROM users WHERE (users.name = $1) LIMIT 5")
+		assert.Equal(t, []interface{}{"Ada"}, args)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		ageCol := NumericColumn[int]{ComparableColumn: ComparableColumn[int]{Column: Column[int]{Name: "age", Table: "users"}}}
+		query, args, err := repo.Query(context.Background()).
+			Where(idCol.In(1, 2, 3)).
+			ToUpdateSQL(nameCol.Set("Ada"), ageCol.Increment(1))
+
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET name = $1, age = age + $2 WHERE (users.id IN ($3,$4,$5))", query)
+		assert.Equal(t, []interface{}{"Ada", 1, 1, 2, 3}, args)
+	})
+
+	t.Run("update with more than nine arguments", func(t *testing.T) {
+		query, args, err := repo.Query(context.Background()).
+			Where(idCol.In(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)).
+			ToUpdateSQL(nameCol.Set("Ada"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET name = $1 WHERE (users.id IN ($2,$3,$4,$5,$6,$7,$8,$9,$10,$11))", query)
+		assert.Len(t, args, 11)
+	})
+
+	t.Run("update with a question mark in a LIKE pattern", func(t *testing.T) {
+		likeCol := StringColumn{Column: nameCol}
+		query, args, err := repo.Query(context.Background()).
+			Where(likeCol.Like("what?%")).
+			ToUpdateSQL(likeCol.Concat("?"), nameCol.Set("$1"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET name = name || $1, name = $2 WHERE (users.name LIKE $3)", query)
+		assert.Equal(t, []interface{}{"?", "$1", "what?%"}, args)
+	})
+
+	t.Run("update with a join", func(t *testing.T) {
+		titleCol := Column[string]{Name: "title", Table: "posts"}
+		query, args, err := repo.Query(context.Background()).
+			InnerJoin("posts", "posts.user_id = users.id").
+			Where(titleCol.Eq("hello")).
+			ToUpdateSQL(nameCol.Set("Ada"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET name = $1 FROM posts WHERE (posts.user_id = users.id AND posts.title = $2)", query)
+		assert.Equal(t, []interface{}{"Ada", "hello"}, args)
+	})
+
+	t.Run("update without actions", func(t *testing.T) {
+		_, _, err := repo.Query(context.Background()).ToUpdateSQL()
+		assert.ErrorContains(t, err, "no actions provided")
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryDebug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	logger := &recordingLogger{}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1 WHERE (users.name = $2)")).
+		WithArgs("O'Brien", "Ada").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = repo.Query(context.Background()).Debug(logger).Where(nameCol.Eq("Ada")).Update(nameCol.Set("O'Brien"))
+	require.NoError(t, err)
+
+	require.Len(t, logger.queries, 1)
+	assert.Equal(t, "UPDATE users SET name = 'O''Brien' WHERE (users.name = 'Ada')", logger.queries[0])
+	assert.NoError(t, logger.errs[0])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInterpolateSQL(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		args     []interface{}
+		expected string
+	}{
+		{"scalars", "SELECT $1, $2, $3, $4", []interface{}{1, 2.5, true, nil}, "SELECT 1, 2.5, TRUE, NULL"},
+		{"string escaping", "WHERE name = $1", []interface{}{"it's"}, "WHERE name = 'it''s'"},
+		{"two digit placeholders", "VALUES ($1, $10)", []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, "VALUES (1, 10)"},
+		{"placeholder inside literal", "WHERE note = '$1' AND id = $1", []interface{}{7}, "WHERE note = '$1' AND id = 7"},
+		{"time", "WHERE created_at > $1", []interface{}{created}, "WHERE created_at > '2024-01-02T03:04:05Z'"},
+		{"bytes", "WHERE data = $1", []interface{}{[]byte{0xde, 0xad}}, `WHERE data = '\xdead'`},
+		{"missing argument", "WHERE id = $2", []interface{}{1}, "WHERE id = $2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, InterpolateSQL(tt.query, tt.args))
+		})
+	}
+}
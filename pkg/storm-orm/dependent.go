@@ -0,0 +1,229 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// Dependent actions applied to child records when their parent is deleted
+const (
+	DependentDestroy  = "destroy"
+	DependentDelete   = "delete"
+	DependentNullify  = "nullify"
+	DependentRestrict = "restrict"
+)
+
+// dependentRelationships returns the has_one and has_many relationships that
+// declare a dependent action, ordered by name for deterministic execution
+func (r *Repository[T]) dependentRelationships() []*RelationshipMetadata {
+	return dependentRelationships(r.metadata)
+}
+
+func dependentRelationships(metadata *ModelMetadata) []*RelationshipMetadata {
+	var relationships []*RelationshipMetadata
+	for _, rel := range metadata.Relationships {
+		if rel.Dependent == "" {
+			continue
+		}
+		if rel.Type != "has_one" && rel.Type != "has_many" {
+			continue
+		}
+		relationships = append(relationships, rel)
+	}
+	sort.Slice(relationships, func(i, j int) bool {
+		return relationships[i].Name < relationships[j].Name
+	})
+	return relationships
+}
+
+// withDependents runs fn inside a transaction when the model declares dependent
+// relationships, so children and parent are removed atomically. exec is used
+// as-is when it is already a transaction.
+func (r *Repository[T]) withDependents(ctx context.Context, op string, exec DBExecutor, fn func(exec DBExecutor) error) error {
+	if len(r.dependentRelationships()) == 0 {
+		return fn(exec)
+	}
+	return r.inTransaction(ctx, op, exec, fn)
+}
+
+// inTransaction runs fn inside a new transaction, or with exec as-is when it
+// is already a transaction
+func (r *Repository[T]) inTransaction(ctx context.Context, op string, exec DBExecutor, fn func(exec DBExecutor) error) error {
+	db, ok := exec.(*sqlx.DB)
+	if !ok {
+		return fn(exec)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return &Error{
+			Op:    op,
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("failed to begin transaction: %w", err),
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &Error{
+			Op:    op,
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("failed to commit transaction: %w", err),
+		}
+	}
+	return nil
+}
+
+// applyDependents deletes or nullifies the children of every parent row matched
+// by where. destroy first applies the dependents of the child model to the
+// children, recursively, and then removes them; delete removes the child rows
+// alone. restrict fails with ErrForeignKey while any child exists.
+func (r *Repository[T]) applyDependents(ctx context.Context, exec DBExecutor, op string, where squirrel.Sqlizer) error {
+	path := map[*ModelMetadata]bool{r.metadata: true}
+	return applyDependents(ctx, exec, op, r.metadata, r.quotedTable(), where, path)
+}
+
+// applyDependents applies the dependents of metadata to the children of the
+// rows of table matched by where. path holds the models being destroyed
+// above, so a destroy that leads back to one of them fails instead of
+// recursing forever.
+func applyDependents(ctx context.Context, exec DBExecutor, op string, metadata *ModelMetadata, table string, where squirrel.Sqlizer, path map[*ModelMetadata]bool) error {
+	for _, rel := range dependentRelationships(metadata) {
+		sourceKey := rel.SourceKey
+		if sourceKey == "" {
+			sourceKey = "id"
+		}
+
+		tableName := rel.TargetTable
+		if tableName == "" {
+			tableName = rel.Target
+		}
+
+		parents := squirrel.Select(quoteIdent(sourceKey)).From(table)
+		if where != nil {
+			parents = parents.Where(where)
+		}
+
+		parentSQL, parentArgs, err := parents.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    op,
+				Table: metadata.TableName,
+				Err:   fmt.Errorf("failed to build dependent query for %s: %w", rel.Name, err),
+			}
+		}
+		var children squirrel.Sqlizer = squirrel.Expr(fmt.Sprintf("%s IN (%s)", quoteIdent(rel.ForeignKey), parentSQL), parentArgs...)
+		if rel.isPolymorphic() {
+			children = squirrel.And{children, polymorphicCondition(rel)}
+		}
+
+		var query squirrel.Sqlizer
+		switch rel.Dependent {
+		case DependentRestrict:
+			if err := checkRestricted(ctx, exec, op, metadata, rel, tableName, children); err != nil {
+				return err
+			}
+			continue
+		case DependentDestroy:
+			if err := destroyDependents(ctx, exec, op, metadata, rel, tableName, children, path); err != nil {
+				return err
+			}
+			query = squirrel.Delete(quoteIdent(tableName)).
+				Where(children).
+				PlaceholderFormat(squirrel.Dollar)
+		case DependentDelete:
+			query = squirrel.Delete(quoteIdent(tableName)).
+				Where(children).
+				PlaceholderFormat(squirrel.Dollar)
+		case DependentNullify:
+			query = squirrel.Update(quoteIdent(tableName)).
+				Set(quoteIdent(rel.ForeignKey), nil).
+				Where(children).
+				PlaceholderFormat(squirrel.Dollar)
+		default:
+			return &Error{
+				Op:    op,
+				Table: metadata.TableName,
+				Err:   fmt.Errorf("unsupported dependent action %q on relationship %s", rel.Dependent, rel.Name),
+			}
+		}
+
+		sqlQuery, args, err := query.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    op,
+				Table: tableName,
+				Err:   fmt.Errorf("failed to build dependent query for %s: %w", rel.Name, err),
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, sqlQuery, args...); err != nil {
+			return parsePostgreSQLError(err, op, tableName)
+		}
+	}
+	return nil
+}
+
+// destroyDependents applies the dependents of the target model of a destroy
+// relationship to the children it is about to remove
+func destroyDependents(ctx context.Context, exec DBExecutor, op string, metadata *ModelMetadata, rel *RelationshipMetadata, tableName string, children squirrel.Sqlizer, path map[*ModelMetadata]bool) error {
+	target := rel.TargetMetadata
+	if target == nil {
+		return &Error{
+			Op:    op,
+			Table: metadata.TableName,
+			Err:   fmt.Errorf("dependent destroy of %s needs the target metadata of %s", rel.Name, rel.Target),
+		}
+	}
+	if path[target] {
+		return &Error{
+			Op:    op,
+			Table: metadata.TableName,
+			Err:   fmt.Errorf("dependent destroy of %s leads back to %s; use delete on one of the relationships", rel.Name, target.TableName),
+		}
+	}
+
+	path[target] = true
+	defer delete(path, target)
+	return applyDependents(ctx, exec, op, target, quoteIdent(tableName), children, path)
+}
+
+// checkRestricted fails when any child row of a restrict relationship exists
+func checkRestricted(ctx context.Context, exec DBExecutor, op string, metadata *ModelMetadata, rel *RelationshipMetadata, tableName string, children squirrel.Sqlizer) error {
+	sqlQuery, args, err := squirrel.Select("1").
+		From(quoteIdent(tableName)).
+		Where(children).
+		Limit(1).
+		Prefix("SELECT EXISTS (").
+		Suffix(")").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return &Error{
+			Op:    op,
+			Table: tableName,
+			Err:   fmt.Errorf("failed to build dependent query for %s: %w", rel.Name, err),
+		}
+	}
+
+	var exists bool
+	if err := exec.GetContext(ctx, &exists, sqlQuery, args...); err != nil {
+		return parsePostgreSQLError(err, op, tableName)
+	}
+	if exists {
+		return &Error{
+			Op:    op,
+			Table: metadata.TableName,
+			Err:   fmt.Errorf("%w: %s still has dependent records", ErrForeignKey, rel.Name),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDependentTestRepo(t *testing.T, dependent string) (*Repository[TestUser], sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Posts": {
+			Name:        "Posts",
+			Type:        "has_many",
+			Target:      "Post",
+			TargetTable: "posts",
+			ForeignKey:  "user_id",
+			SourceKey:   "id",
+			Dependent:   dependent,
+			TargetMetadata: &ModelMetadata{
+				TableName:     "posts",
+				PrimaryKeys:   []string{"id"},
+				Relationships: map[string]*RelationshipMetadata{},
+			},
+		},
+		"Author": {
+			Name:       "Author",
+			Type:       "belongs_to",
+			Target:     "Author",
+			ForeignKey: "author_id",
+			Dependent:  DependentDestroy,
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestDependentRelationships(t *testing.T) {
+	repo, _ := newDependentTestRepo(t, DependentDestroy)
+
+	rels := repo.dependentRelationships()
+	require.Len(t, rels, 1)
+	assert.Equal(t, "Posts", rels[0].Name)
+}
+
+func TestDependentDestroy(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentDestroy)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM posts WHERE user_id IN \(SELECT id FROM users WHERE id = \$1\)`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := repo.DeleteRecord(context.Background(), &TestUser{ID: 1})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDependentDestroyNested(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentDestroy)
+	posts := repo.metadata.Relationships["Posts"].TargetMetadata
+	posts.Relationships = map[string]*RelationshipMetadata{
+		"Comments": {
+			Name:        "Comments",
+			Type:        "has_many",
+			Target:      "Comment",
+			TargetTable: "comments",
+			ForeignKey:  "post_id",
+			Dependent:   DependentDelete,
+		},
+		"Likes": {
+			Name:        "Likes",
+			Type:        "has_many",
+			Target:      "Like",
+			TargetTable: "likes",
+			ForeignKey:  "post_id",
+			Dependent:   DependentNullify,
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM comments WHERE post_id IN \(SELECT id FROM posts WHERE user_id IN \(SELECT id FROM users WHERE id = \$1\)\)`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(`UPDATE likes SET post_id = \$1 WHERE post_id IN \(SELECT id FROM posts WHERE user_id IN \(SELECT id FROM users WHERE id = \$2\)\)`).
+		WithArgs(nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE FROM posts WHERE user_id IN \(SELECT id FROM users WHERE id = \$1\)`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := repo.DeleteRecord(context.Background(), &TestUser{ID: 1})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDependentDestroyCycle(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentDestroy)
+	posts := repo.metadata.Relationships["Posts"].TargetMetadata
+	posts.Relationships = map[string]*RelationshipMetadata{
+		"Editors": {
+			Name:           "Editors",
+			Type:           "has_many",
+			Target:         "TestUser",
+			TargetTable:    "users",
+			ForeignKey:     "edited_post_id",
+			Dependent:      DependentDestroy,
+			TargetMetadata: repo.metadata,
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err := repo.DeleteRecord(context.Background(), &TestUser{ID: 1})
+	assert.ErrorContains(t, err, "dependent destroy of Editors leads back to users")
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	repo.metadata.Relationships["Posts"].TargetMetadata = nil
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err = repo.DeleteRecord(context.Background(), &TestUser{ID: 1})
+	assert.ErrorContains(t, err, "needs the target metadata of Post")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDependentNullify(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentNullify)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE posts SET user_id = \$1 WHERE user_id IN \(SELECT id FROM users WHERE \(users.is_active = \$2\)\)`).
+		WithArgs(nil, false).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE FROM users WHERE \(users.is_active = \$1\)`).
+		WithArgs(false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	isActive := Column[bool]{Name: "is_active", Table: "users"}
+	_, err := repo.Query(context.Background()).Where(isActive.Eq(false)).Delete()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDependentJoinedDelete(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentDelete)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM posts WHERE user_id IN \(SELECT id FROM users WHERE users.id IN \(SELECT users.id FROM users INNER JOIN teams ON teams.id = users.team_id WHERE \(teams.name = \$1\)\)\)`).
+		WithArgs("archived").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`DELETE FROM users USING teams WHERE \(teams.id = users.team_id AND teams.name = \$1\)`).
+		WithArgs("archived").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	teamName := Column[string]{Name: "name", Table: "teams"}
+	_, err := repo.Query(context.Background()).
+		InnerJoin("teams", "teams.id = users.team_id").
+		Where(teamName.Eq("archived")).
+		Delete()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDependentRollbackOnNotFound(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentDelete)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"}).
+			AddRow(1, "John", "john@example.com", true, now, now))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM posts WHERE user_id IN`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := repo.Delete(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDependentRestrict(t *testing.T) {
+	repo, mock := newDependentTestRepo(t, DependentRestrict)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT EXISTS \( SELECT 1 FROM posts WHERE user_id IN \(SELECT id FROM users WHERE id = \$1\) LIMIT 1 \)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err := repo.DeleteRecord(context.Background(), &TestUser{ID: 1})
+	assert.ErrorIs(t, err, ErrForeignKey)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
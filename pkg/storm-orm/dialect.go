@@ -0,0 +1,146 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/dialect"
+)
+
+// Dialect identifies the SQL dialect spoken by the database
+type Dialect string
+
+const (
+	DialectPostgres    Dialect = "postgres"
+	DialectCockroachDB Dialect = "cockroachdb"
+)
+
+// defaultCockroachRetries is the number of times a transaction is retried
+// when CockroachDB asks the client to restart it
+const defaultCockroachRetries = 3
+
+// ErrTransactionRetry indicates the database aborted the transaction and it should be retried
+var ErrTransactionRetry = errors.New("transaction must be retried")
+
+// quoteIdent quotes a table or column name, or a table.column reference, when
+// PostgreSQL would not accept it bare (reserved keywords such as user or order)
+func quoteIdent(name string) string {
+	return dialect.QuoteQualifiedIfNeeded(name)
+}
+
+// QuoteIdent quotes a table or column name, or a table.column reference, the
+// way generated queries do, for names spliced into raw SQL
+func QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+// quoteIdents quotes each name with quoteIdent
+func quoteIdents(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return quoted
+}
+
+// castType returns the type to cast a bound value to for a column declared as
+// dbType. Serial types are not real types and cast to their integer type.
+func castType(dbType string) string {
+	switch strings.ToLower(strings.TrimSpace(dbType)) {
+	case "":
+		return ""
+	case "serial", "serial4":
+		return "integer"
+	case "bigserial", "serial8":
+		return "bigint"
+	case "smallserial", "serial2":
+		return "smallint"
+	}
+	return dbType
+}
+
+// SetDialect configures the SQL dialect used by this Storm instance
+func (s *Storm) SetDialect(dialect Dialect) {
+	s.dialect = dialect
+}
+
+// Dialect returns the configured SQL dialect
+func (s *Storm) Dialect() Dialect {
+	if s.dialect == "" {
+		return DialectPostgres
+	}
+	return s.dialect
+}
+
+// IsCockroachDB reports whether this Storm instance targets CockroachDB
+func (s *Storm) IsCockroachDB() bool {
+	return s.dialect == DialectCockroachDB
+}
+
+// maxTransactionRetries returns how many times a failed transaction may be retried
+func (s *Storm) maxTransactionRetries(opts *TransactionOptions) int {
+	if opts != nil && opts.MaxRetries > 0 {
+		return opts.MaxRetries
+	}
+	if s.IsCockroachDB() {
+		return defaultCockroachRetries
+	}
+	return 0
+}
+
+// isTransactionRetryError reports whether err is a serialization failure
+// that can be resolved by re-running the transaction
+func isTransactionRetryError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrTransactionRetry) {
+		return true
+	}
+
+	errStr := err.Error()
+	return strings.Contains(errStr, "restart transaction") ||
+		strings.Contains(errStr, "TransactionRetryWithProtoRefreshError") ||
+		strings.Contains(errStr, "could not serialize access") ||
+		strings.Contains(errStr, "40001")
+}
+
+// AsOfSystemTime reads data as it existed at the given time (CockroachDB only)
+func (q *Query[T]) AsOfSystemTime(t time.Time) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.asOfSystemTime = fmt.Sprintf("'%s'", t.UTC().Format("2006-01-02 15:04:05.999999"))
+	return q
+}
+
+// AsOfSystemTimeAgo reads data as it existed d ago (CockroachDB only)
+func (q *Query[T]) AsOfSystemTimeAgo(d time.Duration) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	if d <= 0 {
+		q.err = fmt.Errorf("as of system time interval must be positive")
+		return q
+	}
+	q.asOfSystemTime = fmt.Sprintf("'-%dus'", d.Microseconds())
+	return q
+}
+
+// FollowerRead reads from the nearest replica using follower_read_timestamp() (CockroachDB only)
+func (q *Query[T]) FollowerRead() *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.asOfSystemTime = "follower_read_timestamp()"
+	return q
+}
+
+func (q *Query[T]) asOfSystemTimeClause() string {
+	if q.asOfSystemTime == "" {
+		return ""
+	}
+	return "AS OF SYSTEM TIME " + q.asOfSystemTime
+}
@@ -0,0 +1,166 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStormDialect(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	assert.Equal(t, DialectPostgres, storm.Dialect())
+	assert.False(t, storm.IsCockroachDB())
+	assert.Equal(t, 0, storm.maxTransactionRetries(nil))
+
+	storm.SetDialect(DialectCockroachDB)
+	assert.True(t, storm.IsCockroachDB())
+	assert.Equal(t, defaultCockroachRetries, storm.maxTransactionRetries(nil))
+	assert.Equal(t, 5, storm.maxTransactionRetries(&TransactionOptions{MaxRetries: 5}))
+}
+
+func TestStormWithDialectOption(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cockroach := NewStormWithOptions(sqlx.NewDb(db, "postgres"), WithDialect(DialectCockroachDB))
+	postgres := NewStorm(sqlx.NewDb(db, "postgres"))
+	assert.True(t, cockroach.IsCockroachDB())
+	assert.False(t, postgres.IsCockroachDB(), "the dialect of one Storm does not leak into another")
+}
+
+func TestIsTransactionRetryError(t *testing.T) {
+	assert.False(t, isTransactionRetryError(nil))
+	assert.False(t, isTransactionRetryError(errors.New("syntax error")))
+	assert.True(t, isTransactionRetryError(errors.New("pq: restart transaction: TransactionRetryWithProtoRefreshError")))
+	assert.True(t, isTransactionRetryError(errors.New("pq: could not serialize access due to concurrent update")))
+	assert.True(t, isTransactionRetryError(&Error{Op: "update", Err: ErrTransactionRetry}))
+
+	parsed := parsePostgreSQLError(errors.New("pq: restart transaction: RETRY_SERIALIZABLE"), "update", "users")
+	assert.True(t, errors.Is(parsed, ErrTransactionRetry))
+	assert.True(t, IsRetryable(parsed))
+}
+
+func TestStormWithTransactionRetriesOnCockroach(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	storm.SetDialect(DialectCockroachDB)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	attempts := 0
+	err = storm.WithTransaction(context.Background(), func(txStorm *Storm) error {
+		attempts++
+		assert.True(t, txStorm.IsCockroachDB())
+		if attempts == 1 {
+			return errors.New("pq: restart transaction: RETRY_SERIALIZABLE")
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStormWithTransactionNoRetryOnPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	attempts := 0
+	err = storm.WithTransaction(context.Background(), func(txStorm *Storm) error {
+		attempts++
+		return errors.New("pq: could not serialize access due to concurrent update")
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryAsOfSystemTime(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("interval", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).
+			AsOfSystemTimeAgo(10 * time.Second).
+			buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "FROM users AS OF SYSTEM TIME '-10000000us'")
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		sql, _, err := repo.Query(context.Background()).
+			AsOfSystemTime(ts).
+			buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "AS OF SYSTEM TIME '2024-01-02 03:04:05'")
+	})
+
+	t.Run("follower read after joins", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).
+			InnerJoin("posts", "posts.user_id = users.id").
+			FollowerRead().
+			Where(Column[int64]{Name: "id", Table: "users"}.Eq(1)).
+			buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "JOIN posts ON posts.user_id = users.id AS OF SYSTEM TIME follower_read_timestamp() WHERE")
+	})
+
+	t.Run("invalid interval", func(t *testing.T) {
+		_, _, err := repo.Query(context.Background()).
+			AsOfSystemTimeAgo(0).
+			buildQuery()
+		assert.Error(t, err)
+	})
+}
+
+func TestQueryQuotesReservedIdentifiers(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.TableName = "user"
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	order := Column[int]{Name: "order", Table: "user"}
+	sql, args, err := repo.Query(context.Background()).Where(order.Eq(1)).buildQuery()
+	require.NoError(t, err)
+	assert.Contains(t, sql, `FROM "user"`)
+	assert.Contains(t, sql, `"user"."order" = $1`)
+	assert.Equal(t, []interface{}{1}, args)
+
+	assert.Equal(t, `"order" = ?`, Column[int]{Name: "order"}.Set(1).Expression())
+	assert.Equal(t, "users.name", Column[string]{Name: "name", Table: "users"}.String())
+}
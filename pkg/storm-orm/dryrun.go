@@ -0,0 +1,222 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrDryRun marks a write a dry run recorded instead of running. Writes made
+// through the repository methods report success; an error wrapping ErrDryRun
+// only surfaces where an operation needed the result of a skipped statement.
+var ErrDryRun = errors.New("dry run: statement not executed")
+
+// DryRunStatement is a write a dry run skipped
+type DryRunStatement struct {
+	Query string
+	Args  []interface{}
+}
+
+// DryRunLog collects the statements skipped by a dry run. It is safe for
+// concurrent use.
+type DryRunLog struct {
+	mu         sync.Mutex
+	statements []DryRunStatement
+}
+
+// Statements returns the skipped statements in the order they were issued
+func (l *DryRunLog) Statements() []DryRunStatement {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]DryRunStatement(nil), l.statements...)
+}
+
+func (l *DryRunLog) record(query string, args []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.statements = append(l.statements, DryRunStatement{Query: query, Args: args})
+}
+
+// DryRun returns a copy of the repository whose writes go through the usual
+// middleware, SQL building and logging but are recorded in log instead of
+// run. Reads, such as the lookup Delete makes first, still query the
+// database. Values the database would fill in, like generated keys, are left
+// unset, and operations report zero rows affected.
+func (r *Repository[T]) DryRun(log *DryRunLog) *Repository[T] {
+	clone := r.clone()
+	clone.db = &dryRunExecutor{next: r.db, log: log}
+	return clone
+}
+
+// DryRun runs fn with a Storm whose writes are recorded instead of run, and
+// returns them. Transactions begun inside fn run fn directly, since nothing
+// is written. See Repository.DryRun.
+func (s *Storm) DryRun(ctx context.Context, fn func(*Storm) error) ([]DryRunStatement, error) {
+	db, ok := s.db.(*sqlx.DB)
+	if !ok {
+		return nil, fmt.Errorf("cannot start dry run: executor is not a database connection")
+	}
+
+	log := &DryRunLog{}
+	dryStorm := newStormWithExecutor(db, &dryRunExecutor{next: s.executor, log: log, logger: s.logger}, nil)
+	dryStorm.logger = s.logger
+	dryStorm.dialect = s.dialect
+	dryStorm.options = s.options
+	dryStorm.rawPolicy = s.rawPolicy
+	dryStorm.middlewareManager = s.middlewareManager
+	err := fn(dryStorm)
+	return log.Statements(), err
+}
+
+// isDryRun reports whether the Storm records writes instead of running them
+func (s *Storm) isDryRun() bool {
+	_, ok := s.executor.(*dryRunExecutor)
+	return ok
+}
+
+// skipDryRun treats writes a dry run skipped as successful
+func skipDryRun(finalFunc QueryMiddlewareFunc) QueryMiddlewareFunc {
+	return func(middlewareCtx *MiddlewareContext) error {
+		err := finalFunc(middlewareCtx)
+		if errors.Is(err, ErrDryRun) {
+			middlewareCtx.RowsAffected = 0
+			return nil
+		}
+		return err
+	}
+}
+
+var dataModifyingCTE = regexp.MustCompile(`(?i)\b(?:INSERT\s+INTO|DELETE\s+FROM|UPDATE\s+\S+\s+SET|MERGE\s+INTO)\b`)
+
+// isWriteStatement reports whether statement may change the database
+func isWriteStatement(statement string) bool {
+	switch statementKeyword(statement) {
+	case "SELECT", "VALUES", "SHOW", "EXPLAIN", "TABLE":
+		return false
+	case "WITH":
+		return dataModifyingCTE.MatchString(statement)
+	}
+	return true
+}
+
+// dryRunExecutor records writes in log instead of running them and passes
+// reads on to next
+type dryRunExecutor struct {
+	next   DBExecutor
+	log    *DryRunLog
+	logger QueryLogger
+}
+
+func (d *dryRunExecutor) skip(query string, args []interface{}) {
+	d.log.record(query, args)
+	if d.logger != nil {
+		d.logger.LogQuery(query, args, 0, nil)
+	}
+}
+
+// dryRunResult reports a skipped statement; its counts are unknown
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, ErrDryRun }
+func (dryRunResult) RowsAffected() (int64, error) { return 0, ErrDryRun }
+
+func (d *dryRunExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if !isWriteStatement(query) {
+		return d.next.ExecContext(ctx, query, args...)
+	}
+	d.skip(query, args)
+	return dryRunResult{}, nil
+}
+
+func (d *dryRunExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if isWriteStatement(query) {
+		d.skip(query, args)
+		return nil, ErrDryRun
+	}
+	return d.next.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a write as a query matching no rows, so scanning it
+// reports sql.ErrNoRows
+func (d *dryRunExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if isWriteStatement(query) {
+		d.skip(query, args)
+		return d.next.QueryRowContext(ctx, "SELECT NULL WHERE false")
+	}
+	return d.next.QueryRowContext(ctx, query, args...)
+}
+
+func (d *dryRunExecutor) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if isWriteStatement(query) {
+		d.skip(query, args)
+		return ErrDryRun
+	}
+	return d.next.GetContext(ctx, dest, query, args...)
+}
+
+func (d *dryRunExecutor) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if isWriteStatement(query) {
+		d.skip(query, args)
+		return ErrDryRun
+	}
+	return d.next.SelectContext(ctx, dest, query, args...)
+}
+
+func (d *dryRunExecutor) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if isWriteStatement(query) {
+		d.skip(query, args)
+		return nil, ErrDryRun
+	}
+	return d.next.QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext runs a write as a query matching no rows, so scanning it
+// reports sql.ErrNoRows
+func (d *dryRunExecutor) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	if isWriteStatement(query) {
+		d.skip(query, args)
+		return d.next.QueryRowxContext(ctx, "SELECT NULL WHERE false")
+	}
+	return d.next.QueryRowxContext(ctx, query, args...)
+}
+
+func (d *dryRunExecutor) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	if !isWriteStatement(query) {
+		return d.next.NamedExecContext(ctx, query, arg)
+	}
+	d.skip(query, []interface{}{arg})
+	return dryRunResult{}, nil
+}
+
+func (d *dryRunExecutor) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return d.next.BindNamed(query, arg)
+}
+
+func (d *dryRunExecutor) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	if isWriteStatement(query) {
+		return nil, ErrDryRun
+	}
+	return d.next.PreparexContext(ctx, query)
+}
+
+func (d *dryRunExecutor) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	if isWriteStatement(query) {
+		return nil, ErrDryRun
+	}
+	return d.next.PrepareNamedContext(ctx, query)
+}
+
+func (d *dryRunExecutor) Rebind(query string) string {
+	return d.next.Rebind(query)
+}
+
+func (d *dryRunExecutor) DriverName() string {
+	return d.next.DriverName()
+}
+
+var _ DBExecutor = (*dryRunExecutor)(nil)
@@ -0,0 +1,109 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryDryRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	repo, err := NewRepository[TestUser](sqlxDB, createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("create is recorded and not run", func(t *testing.T) {
+		log := &DryRunLog{}
+		var seen []string
+		dry := repo.DryRun(log)
+		dry.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+			return func(ctx *MiddlewareContext) error {
+				seen = append(seen, string(ctx.Operation))
+				return next(ctx)
+			}
+		})
+
+		user := &TestUser{Name: "Ann", Email: "ann@example.com"}
+		_, err := dry.Create(context.Background(), user)
+		require.NoError(t, err)
+
+		statements := log.Statements()
+		require.Len(t, statements, 1)
+		assert.Contains(t, statements[0].Query, "INSERT INTO users")
+		assert.Contains(t, statements[0].Args, "Ann")
+		assert.Equal(t, []string{string(OpCreate)}, seen)
+		assert.Zero(t, user.ID)
+	})
+
+	t.Run("update is recorded and not run", func(t *testing.T) {
+		log := &DryRunLog{}
+		_, err := repo.DryRun(log).Update(context.Background(), &TestUser{ID: 1, Name: "Ann"})
+		require.NoError(t, err)
+
+		statements := log.Statements()
+		require.Len(t, statements, 1)
+		assert.Contains(t, statements[0].Query, "UPDATE users SET")
+	})
+
+	t.Run("delete reads the record but does not remove it", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "Ann", "ann@example.com"))
+
+		log := &DryRunLog{}
+		user, err := repo.DryRun(log).Delete(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Ann", user.Name)
+
+		statements := log.Statements()
+		require.Len(t, statements, 1)
+		assert.Contains(t, statements[0].Query, "DELETE FROM users")
+		assert.Equal(t, []interface{}{1}, statements[0].Args)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStormDryRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	statements, err := s.DryRun(context.Background(), func(dry *Storm) error {
+		return dry.WithTransaction(context.Background(), func(tx *Storm) error {
+			_, err := tx.ExecRaw(context.Background(), "UPDATE users SET is_active = false WHERE id = :id", map[string]interface{}{"id": 7})
+			return err
+		})
+	})
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.Equal(t, "UPDATE users SET is_active = false WHERE id = $1", statements[0].Query)
+	assert.Equal(t, []interface{}{7}, statements[0].Args)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsWriteStatement(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM users": false,
+		"  select 1":          false,
+		"WITH recent AS (SELECT id FROM users) SELECT * FROM recent":       false,
+		"WITH gone AS (DELETE FROM users RETURNING id) SELECT * FROM gone": true,
+		"INSERT INTO users (name) VALUES ($1)":                             true,
+		"UPDATE users SET name = $1":                                       true,
+		"DELETE FROM users":                                                true,
+		"TRUNCATE users":                                                   true,
+	}
+	for statement, want := range tests {
+		assert.Equal(t, want, isWriteStatement(statement), statement)
+	}
+}
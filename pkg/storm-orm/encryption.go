@@ -0,0 +1,430 @@
+package orm
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// KeyProvider supplies the keys that encrypt EncryptedString and
+// DeterministicString columns. Keys must be 16, 24 or 32 bytes long,
+// selecting AES-128, AES-192 or AES-256. Every value records the id of the
+// key it was encrypted with, so values written before a rotation still
+// decrypt.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are encrypted with and its id,
+	// at most 255 bytes long
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with id, for decrypting stored values
+	Key(id string) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider with a single key, whose id is empty
+type StaticKey []byte
+
+func (k StaticKey) CurrentKey() (string, []byte, error) {
+	return "", k, nil
+}
+
+func (k StaticKey) Key(id string) ([]byte, error) {
+	if id != "" {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return k, nil
+}
+
+// Keyring is a KeyProvider that encrypts with the key named by Current and
+// decrypts with any of Keys, so keys can be rotated without rewriting rows.
+// Deterministic lookups only match values written under the current key.
+type Keyring struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+func (k Keyring) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+func (k Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+// WithKeyProvider sets the keys the repositories of the Storm encrypt and
+// decrypt columns with. Encrypted values cannot be written or read through a
+// Storm without one.
+func WithKeyProvider(provider KeyProvider) Option {
+	return func(s *Storm) {
+		s.options.keys = provider
+	}
+}
+
+// EncryptedString is a string stored encrypted with AES-GCM under a fresh
+// random nonce, so equal values produce different ciphertexts. It is
+// decrypted when scanned and cannot be searched; use DeterministicString
+// for columns compared with Eq or In.
+//
+// Values are encrypted and decrypted by the repositories of a Storm created
+// WithKeyProvider. NULL only scans into a *EncryptedString, as nil.
+type EncryptedString string
+
+// DeterministicString is a string stored encrypted with AES-GCM under a
+// nonce derived from the value, so equal values produce equal ciphertexts
+// and can be compared with Eq, In and unique constraints. This reveals which
+// rows share a value; prefer EncryptedString when that is not needed.
+type DeterministicString string
+
+const (
+	encryptionVersion      byte = 1 // version || nonce || ciphertext, under the key with id ""
+	encryptionVersionKeyID byte = 2 // version || id length || id || nonce || ciphertext
+)
+
+// errUnbound is returned by encrypted values handed to the driver without
+// the keys of a Storm
+var errUnbound = fmt.Errorf("%w: encrypted values are written through the repositories of a Storm created WithKeyProvider", ErrNoEncryptionKey)
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	return nil, errUnbound
+}
+
+// Scan keeps the ciphertext, which the repository reading the row decrypts
+func (s *EncryptedString) Scan(value interface{}) error {
+	data, err := ciphertext(value, "EncryptedString")
+	if err != nil {
+		return fmt.Errorf("cannot scan into EncryptedString: %w", err)
+	}
+	*s = EncryptedString(data)
+	return nil
+}
+
+func (s DeterministicString) Value() (driver.Value, error) {
+	return nil, errUnbound
+}
+
+// Scan keeps the ciphertext, which the repository reading the row decrypts
+func (s *DeterministicString) Scan(value interface{}) error {
+	data, err := ciphertext(value, "DeterministicString")
+	if err != nil {
+		return fmt.Errorf("cannot scan into DeterministicString: %w", err)
+	}
+	*s = DeterministicString(data)
+	return nil
+}
+
+// ciphertext returns the bytes of a scanned encrypted column. Rows decoded
+// from JSON carry bytea in its \x hex form.
+func ciphertext(value interface{}, typeName string) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("column is NULL; use *%s for nullable columns", typeName)
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case string:
+		if strings.HasPrefix(v, `\x`) {
+			return hex.DecodeString(v[2:])
+		}
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// boundSecret is an encrypted value bound to the keys of the Storm writing
+// it, encrypted when the driver reads it
+type boundSecret struct {
+	keys          KeyProvider
+	plaintext     string
+	deterministic bool
+}
+
+func (b boundSecret) Value() (driver.Value, error) {
+	return encryptString(b.keys, b.plaintext, b.deterministic)
+}
+
+// keyProvider returns the keys of options, nil for repositories created
+// without a Storm
+func (o *queryOptions) keyProvider() KeyProvider {
+	if o == nil {
+		return nil
+	}
+	return o.keys
+}
+
+// bind binds an EncryptedString or DeterministicString, or a pointer to one,
+// to the keys of options. Other values are returned as they are.
+func (o *queryOptions) bind(value interface{}) interface{} {
+	switch v := value.(type) {
+	case EncryptedString:
+		return boundSecret{keys: o.keyProvider(), plaintext: string(v)}
+	case DeterministicString:
+		return boundSecret{keys: o.keyProvider(), plaintext: string(v), deterministic: true}
+	case *EncryptedString:
+		if v == nil {
+			return nil
+		}
+		return o.bind(*v)
+	case *DeterministicString:
+		if v == nil {
+			return nil
+		}
+		return o.bind(*v)
+	}
+	return value
+}
+
+// bindAll binds each of values like bind
+func bindAll[V any](options *queryOptions, values []V) []interface{} {
+	bound := make([]interface{}, len(values))
+	for i, v := range values {
+		bound[i] = options.bind(v)
+	}
+	return bound
+}
+
+// isEncrypted reports whether value is bound by bind
+func isEncrypted(value interface{}) bool {
+	switch value.(type) {
+	case EncryptedString, DeterministicString, *EncryptedString, *DeterministicString:
+		return true
+	}
+	return false
+}
+
+// encryptString seals plaintext with the current key of keys as version ||
+// id length || id || nonce || ciphertext, authenticating the header
+func encryptString(keys KeyProvider, plaintext string, deterministic bool) ([]byte, error) {
+	if keys == nil {
+		return nil, ErrNoEncryptionKey
+	}
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("encryption key id %q is longer than 255 bytes", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if deterministic {
+		mac := hmac.New(sha256.New, nonceKey(key))
+		mac.Write([]byte(plaintext))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, 2+len(id)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, encryptionVersionKeyID, byte(len(id)))
+	out = append(out, id...)
+	header := append([]byte(nil), out...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, []byte(plaintext), header), nil
+}
+
+// decryptString opens a value written by encryptString, or by earlier
+// versions that had no key id, with the key it names
+func decryptString(keys KeyProvider, data []byte) (string, error) {
+	if keys == nil {
+		return "", ErrNoEncryptionKey
+	}
+
+	var id string
+	var header []byte
+	body := data
+	switch {
+	case len(data) > 0 && data[0] == encryptionVersion:
+		body = data[1:]
+	case len(data) > 1 && data[0] == encryptionVersionKeyID && len(data) >= 2+int(data[1]):
+		header = data[:2+int(data[1])]
+		id = string(header[2:])
+		body = data[len(header):]
+	default:
+		return "", fmt.Errorf("value is not an encrypted string")
+	}
+
+	key, err := keys.Key(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(body) < aead.NonceSize()+aead.Overhead() {
+		return "", fmt.Errorf("value is not an encrypted string")
+	}
+	nonce := body[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, body[aead.NonceSize():], header)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonceKey derives the key for deterministic nonces, so the encryption key
+// itself is never used as a MAC key
+func nonceKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("storm deterministic nonce"))
+	return mac.Sum(nil)
+}
+
+var (
+	encryptedStringType     = reflect.TypeOf(EncryptedString(""))
+	deterministicStringType = reflect.TypeOf(DeterministicString(""))
+
+	// Whether each type scanned by a repository holds encrypted fields
+	encryptedTypes sync.Map
+)
+
+// decrypt decrypts, in place, the encrypted fields of dest, a pointer to the
+// records, rows or values a query was scanned into. Scanning keeps the
+// ciphertext, so dest must not have been decrypted before.
+func (o *queryOptions) decrypt(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if !value.IsValid() || !containsEncrypted(value.Type()) {
+		return nil
+	}
+	if err := decryptFields(o.keyProvider(), value); err != nil {
+		return fmt.Errorf("failed to decrypt column: %w", err)
+	}
+	return nil
+}
+
+func decryptFields(keys KeyProvider, value reflect.Value) error {
+	if value.Type() == encryptedStringType || value.Type() == deterministicStringType {
+		// Columns left out of the query stay empty
+		if value.Len() == 0 || !value.CanSet() {
+			return nil
+		}
+		plaintext, err := decryptString(keys, []byte(value.String()))
+		if err != nil {
+			return err
+		}
+		value.SetString(plaintext)
+		return nil
+	}
+	if !containsEncrypted(value.Type()) {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			return decryptFields(keys, value.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := decryptFields(keys, value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if !value.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := decryptFields(keys, value.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// containsEncrypted reports whether values of t can hold an EncryptedString
+// or DeterministicString, through pointers, slices, arrays and the exported
+// fields of structs such as loaded relationships
+func containsEncrypted(t reflect.Type) bool {
+	if cached, ok := encryptedTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	contains := reachesEncrypted(t, map[reflect.Type]bool{})
+	encryptedTypes.Store(t, contains)
+	return contains
+}
+
+func reachesEncrypted(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == encryptedStringType || t == deterministicStringType {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return reachesEncrypted(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() && reachesEncrypted(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptingExecutor decrypts what SelectContext and GetContext scan, for
+// generated code that reads rows through the executor it is handed
+type decryptingExecutor struct {
+	DBExecutor
+	options *queryOptions
+}
+
+func (e decryptingExecutor) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := e.DBExecutor.GetContext(ctx, dest, query, args...); err != nil {
+		return err
+	}
+	return e.options.decrypt(dest)
+}
+
+func (e decryptingExecutor) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := e.DBExecutor.SelectContext(ctx, dest, query, args...); err != nil {
+		return err
+	}
+	return e.options.decrypt(dest)
+}
+
+// Mask hides all but the last four characters of s, for showing values such
+// as card or phone numbers. Values of four characters or fewer are hidden
+// entirely.
+func Mask[S ~string](s S) S {
+	runes := []rune(string(s))
+	visible := 0
+	if len(runes) > 4 {
+		visible = 4
+	}
+	for i := 0; i < len(runes)-visible; i++ {
+		runes[i] = '*'
+	}
+	return S(runes)
+}
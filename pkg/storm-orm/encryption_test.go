@@ -0,0 +1,326 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKey      = "0123456789abcdef0123456789abcdef"
+	testOtherKey = "fedcba9876543210fedcba9876543210"
+)
+
+func TestEncryptedString(t *testing.T) {
+	keys := StaticKey(testKey)
+	options := &queryOptions{keys: keys}
+
+	t.Run("round trip", func(t *testing.T) {
+		value, err := options.bind(EncryptedString("4111 1111 1111 1111")).(driver.Valuer).Value()
+		require.NoError(t, err)
+		assert.NotContains(t, string(value.([]byte)), "4111")
+
+		var scanned EncryptedString
+		require.NoError(t, scanned.Scan(value))
+		require.NoError(t, options.decrypt(&scanned))
+		assert.Equal(t, EncryptedString("4111 1111 1111 1111"), scanned)
+	})
+
+	t.Run("randomized", func(t *testing.T) {
+		first, err := encryptString(keys, "secret", false)
+		require.NoError(t, err)
+		second, err := encryptString(keys, "secret", false)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		first, err := encryptString(keys, "123-45-6789", true)
+		require.NoError(t, err)
+		second, err := encryptString(keys, "123-45-6789", true)
+		require.NoError(t, err)
+		other, err := encryptString(keys, "987-65-4321", true)
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, first, other)
+
+		var scanned DeterministicString
+		require.NoError(t, scanned.Scan(first))
+		require.NoError(t, options.decrypt(&scanned))
+		assert.Equal(t, DeterministicString("123-45-6789"), scanned)
+	})
+
+	t.Run("rotated keys", func(t *testing.T) {
+		before := Keyring{Current: "2025", Keys: map[string][]byte{"2025": []byte(testKey)}}
+		after := Keyring{Current: "2026", Keys: map[string][]byte{"2025": []byte(testKey), "2026": []byte(testOtherKey)}}
+
+		old, err := encryptString(before, "secret", false)
+		require.NoError(t, err)
+		plaintext, err := decryptString(after, old)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+
+		current, err := encryptString(after, "secret", false)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{encryptionVersionKeyID, 4, '2', '0', '2', '6'}, current[:6])
+		_, err = decryptString(before, current)
+		assert.ErrorContains(t, err, `unknown encryption key "2026"`)
+	})
+
+	t.Run("unversioned key", func(t *testing.T) {
+		aead, err := newAEAD(keys)
+		require.NoError(t, err)
+		nonce := make([]byte, aead.NonceSize())
+		legacy := aead.Seal(append([]byte{encryptionVersion}, nonce...), nonce, []byte("secret"), nil)
+
+		plaintext, err := decryptString(keys, legacy)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+	})
+
+	t.Run("loaded relationships", func(t *testing.T) {
+		type account struct {
+			Name      string
+			Customers []testCustomer
+			Parent    *account
+		}
+
+		sealed, err := encryptString(keys, "123-45-6789", true)
+		require.NoError(t, err)
+		accounts := []account{{
+			Name:      "plain",
+			Customers: []testCustomer{{SSN: DeterministicString(sealed)}, {}},
+			Parent:    &account{Customers: []testCustomer{{SSN: DeterministicString(sealed)}}},
+		}}
+
+		require.NoError(t, options.decrypt(&accounts))
+		assert.Equal(t, "plain", accounts[0].Name)
+		assert.Equal(t, DeterministicString("123-45-6789"), accounts[0].Customers[0].SSN)
+		assert.Empty(t, accounts[0].Customers[1].SSN, "columns left out of the query stay empty")
+		assert.Equal(t, DeterministicString("123-45-6789"), accounts[0].Parent.Customers[0].SSN)
+	})
+
+	t.Run("null", func(t *testing.T) {
+		var scanned EncryptedString
+		assert.ErrorContains(t, scanned.Scan(nil), "use *EncryptedString for nullable columns")
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		value, err := encryptString(keys, "secret", false)
+		require.NoError(t, err)
+
+		_, err = decryptString(StaticKey(testOtherKey), value)
+		assert.ErrorContains(t, err, "failed to decrypt value")
+	})
+
+	t.Run("not encrypted", func(t *testing.T) {
+		_, err := decryptString(keys, []byte("plain text"))
+		assert.ErrorContains(t, err, "not an encrypted string")
+	})
+}
+
+func TestEncryptedStringWithoutKey(t *testing.T) {
+	_, err := EncryptedString("secret").Value()
+	assert.True(t, errors.Is(err, ErrNoEncryptionKey), "values are only encrypted once bound to keys")
+
+	var options *queryOptions
+	_, err = options.bind(DeterministicString("secret")).(driver.Valuer).Value()
+	assert.True(t, errors.Is(err, ErrNoEncryptionKey))
+
+	_, err = encryptString(StaticKey("short"), "secret", false)
+	assert.ErrorContains(t, err, "invalid encryption key")
+}
+
+func TestEncryptedColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	keys := StaticKey(testKey)
+	repo, err := NewRepositoryForStorm[TestUser](NewStormWithOptions(sqlxDB, WithKeyProvider(keys)), createTestUserMetadata())
+	require.NoError(t, err)
+
+	ssn := Column[DeterministicString]{Name: "email", Table: "users"}
+	note := EncryptedColumn{Name: "name", Table: "users"}
+
+	ciphertext, err := encryptString(keys, "ada@example.com", true)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT .* FROM users WHERE \(\(users\.email = \$1 AND users\.name IS NOT NULL\)\)`).
+		WithArgs(ciphertext).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`UPDATE users SET name = \$1 WHERE \(users\.id = \$2\)`).
+		WithArgs(sealedArg{keys}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	users, err := repo.Query(context.Background()).Where(ssn.Eq("ada@example.com").And(note.IsNotNull())).Find()
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	idCol := Column[int]{Name: "id", Table: "users"}
+	_, err = repo.Query(context.Background()).Where(idCol.Eq(1)).Update(note.Set("private"))
+	require.NoError(t, err)
+
+	t.Run("each Storm uses its own keys", func(t *testing.T) {
+		other, err := NewRepositoryForStorm[TestUser](NewStormWithOptions(sqlxDB, WithKeyProvider(StaticKey(testOtherKey))), createTestUserMetadata())
+		require.NoError(t, err)
+
+		otherCiphertext, err := encryptString(StaticKey(testOtherKey), "ada@example.com", true)
+		require.NoError(t, err)
+		assert.NotEqual(t, ciphertext, otherCiphertext)
+
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(users\.email = \$1\)`).
+			WithArgs(otherCiphertext).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err = other.Query(context.Background()).Where(ssn.Eq("ada@example.com")).Find()
+		require.NoError(t, err)
+
+		unkeyed, err := NewRepositoryForStorm[TestUser](NewStormWithOptions(sqlxDB), createTestUserMetadata())
+		require.NoError(t, err)
+		_, err = unkeyed.Query(context.Background()).Where(ssn.Eq("ada@example.com")).Find()
+		assert.True(t, errors.Is(err, ErrNoEncryptionKey))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+type testCustomer struct {
+	ID    int                 `db:"id"`
+	SSN   DeterministicString `db:"ssn"`
+	Phone *EncryptedString    `db:"phone"`
+}
+
+func testCustomerMetadata() *ModelMetadata {
+	return &ModelMetadata{
+		TableName:  "customers",
+		StructName: "testCustomer",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				GoType:          "int",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue: func(model interface{}) interface{} {
+					return model.(testCustomer).ID
+				},
+			},
+			"SSN": {
+				FieldName: "SSN",
+				DBName:    "ssn",
+				GoType:    "DeterministicString",
+				GetValue: func(model interface{}) interface{} {
+					return model.(testCustomer).SSN
+				},
+			},
+			"Phone": {
+				FieldName: "Phone",
+				DBName:    "phone",
+				GoType:    "*EncryptedString",
+				IsPointer: true,
+				GetValue: func(model interface{}) interface{} {
+					return model.(testCustomer).Phone
+				},
+				IsNil: func(model interface{}) bool {
+					return model.(testCustomer).Phone == nil
+				},
+			},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "SSN": "ssn", "Phone": "phone"},
+		ReverseMap:  map[string]string{"id": "ID", "ssn": "SSN", "phone": "Phone"},
+		PrimaryKeys: []string{"id"},
+	}
+}
+
+func TestEncryptedRecords(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	keys := Keyring{Current: "b", Keys: map[string][]byte{"a": []byte(testOtherKey), "b": []byte(testKey)}}
+	s := NewStormWithOptions(sqlx.NewDb(db, "postgres"), WithKeyProvider(keys))
+	repo, err := NewRepositoryForStorm[testCustomer](s, testCustomerMetadata())
+	require.NoError(t, err)
+
+	t.Run("create", func(t *testing.T) {
+		phone := EncryptedString("555-0100")
+		mock.ExpectQuery(`INSERT INTO customers \(phone,ssn\) VALUES \(\$1,\$2\) RETURNING id`).
+			WithArgs(sealedArg{keys}, sealedArg{keys}).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		customer := &testCustomer{SSN: "123-45-6789", Phone: &phone}
+		_, err := repo.Create(context.Background(), customer)
+		require.NoError(t, err)
+		assert.Equal(t, 7, customer.ID)
+		assert.Equal(t, DeterministicString("123-45-6789"), customer.SSN, "RETURNING leaves the written values alone")
+	})
+
+	t.Run("find", func(t *testing.T) {
+		ssn, err := encryptString(keys, "123-45-6789", true)
+		require.NoError(t, err)
+		phone, err := encryptString(Keyring{Current: "a", Keys: keys.Keys}, "555-0100", false)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(`SELECT .* FROM customers`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "ssn", "phone"}).
+				AddRow(1, ssn, phone).
+				AddRow(2, ssn, nil))
+
+		customers, err := repo.Query(context.Background()).Find()
+		require.NoError(t, err)
+		require.Len(t, customers, 2)
+		assert.Equal(t, DeterministicString("123-45-6789"), customers[0].SSN)
+		require.NotNil(t, customers[0].Phone)
+		assert.Equal(t, EncryptedString("555-0100"), *customers[0].Phone, "values written under an older key still decrypt")
+		assert.Nil(t, customers[1].Phone, "NULL scans as nil")
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		ssn, err := encryptString(keys, "123-45-6789", true)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(`SELECT id, ssn, phone FROM customers WHERE ssn = \$1`).
+			WithArgs(ssn).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "ssn", "phone"}).AddRow(1, ssn, nil))
+
+		var customers []testCustomer
+		err = s.Raw(context.Background(), "SELECT id, ssn, phone FROM customers WHERE ssn = :ssn").
+			Bind("ssn", DeterministicString("123-45-6789")).
+			Scan(&customers)
+		require.NoError(t, err)
+		require.Len(t, customers, 1)
+		assert.Equal(t, DeterministicString("123-45-6789"), customers[0].SSN)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// sealedArg matches an argument that decrypts with keys
+type sealedArg struct {
+	keys KeyProvider
+}
+
+func (a sealedArg) Match(value driver.Value) bool {
+	data, ok := value.([]byte)
+	if !ok {
+		return false
+	}
+	_, err := decryptString(a.keys, data)
+	return err == nil
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "************1111", Mask("4111111111111111"))
+	assert.Equal(t, "****", Mask("1234"))
+	assert.Equal(t, "", Mask(""))
+	assert.Equal(t, "*******örld", Mask("héllo wörld"))
+	assert.Equal(t, EncryptedString("***5678"), Mask(EncryptedString("1235678")))
+}
@@ -19,8 +19,31 @@ var (
 	ErrConnectionFailed = errors.New("database connection failed")
 	ErrTimeout          = errors.New("operation timeout")
 	ErrCanceled         = errors.New("operation canceled")
+	ErrUnknownColumn    = errors.New("unknown column")
+	ErrImmutableColumn  = errors.New("column cannot be updated")
+	ErrNoEncryptionKey  = errors.New("no encryption key configured")
+	ErrNoActor          = errors.New("no actor in context")
+	ErrAutosaveCycle    = errors.New("autosave cycle")
+	ErrNoShardKey       = errors.New("no shard key")
+	ErrReadOnly         = errors.New("model is read-only")
 )
 
+// NotFoundError reports that no record matched a lookup by column values.
+// It matches ErrNotFound with errors.Is. The values looked up are left out,
+// since they are often personal data such as email addresses.
+type NotFoundError struct {
+	Table   string   // Table searched
+	Columns []string // Columns used in the lookup
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("orm: %s: %s by %s", e.Table, ErrNotFound, strings.Join(e.Columns, ", "))
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
 // Error provides detailed error information
 type Error struct {
 	Op         string        // Operation that failed
@@ -133,6 +156,15 @@ func parsePostgreSQLError(err error, op, table string) error {
 		}
 	}
 
+	if isTransactionRetryError(err) {
+		return &Error{
+			Op:        op,
+			Table:     table,
+			Err:       ErrTransactionRetry,
+			Retryable: true,
+		}
+	}
+
 	if strings.Contains(errStr, "context deadline exceeded") {
 		return &Error{
 			Op:        op,
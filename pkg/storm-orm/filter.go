@@ -0,0 +1,343 @@
+package orm
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// FilterOp is a comparison a Filter applies
+type FilterOp string
+
+const (
+	FilterEq     FilterOp = "eq"
+	FilterNe     FilterOp = "ne"
+	FilterGt     FilterOp = "gt"
+	FilterGte    FilterOp = "gte"
+	FilterLt     FilterOp = "lt"
+	FilterLte    FilterOp = "lte"
+	FilterIn     FilterOp = "in"
+	FilterNotIn  FilterOp = "not_in"
+	FilterLike   FilterOp = "like"
+	FilterILike  FilterOp = "ilike"
+	FilterIsNull FilterOp = "is_null"
+)
+
+// Filter compares a column, named as in the database, with a value. Values
+// are strings as read from a URL or the types encoding/json decodes to; in
+// and not_in take a list or a comma separated string, and is_null a boolean.
+type Filter struct {
+	Field string      `json:"field"`
+	Op    FilterOp    `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// FilterSpec is a set of filters that must all match, typically taken from
+// the query string of a list endpoint or a JSON search request
+type FilterSpec struct {
+	Filters []Filter `json:"filters"`
+}
+
+var filterKey = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// ParseFilterQuery reads filters written as filter[field]=value, which
+// compares with eq, or filter[field][op]=value. Other parameters are ignored.
+func ParseFilterQuery(values url.Values) (FilterSpec, error) {
+	var spec FilterSpec
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		match := filterKey.FindStringSubmatch(key)
+		if match == nil {
+			return FilterSpec{}, fmt.Errorf("%w: malformed filter parameter %q", ErrInvalidRequest, key)
+		}
+		op := FilterOp(match[2])
+		if op == "" {
+			op = FilterEq
+		}
+		for _, value := range values[key] {
+			spec.Filters = append(spec.Filters, Filter{Field: match[1], Op: op, Value: value})
+		}
+	}
+	return spec, nil
+}
+
+// filterKind is how a column's values are parsed and which ops apply to it
+type filterKind int
+
+const (
+	filterString filterKind = iota
+	filterInt
+	filterUint
+	filterFloat
+	filterBool
+	filterTime
+	filterDate
+)
+
+var filterKinds = map[string]filterKind{
+	"string": filterString,
+	"int":    filterInt, "int8": filterInt, "int16": filterInt, "int32": filterInt, "int64": filterInt,
+	"uint": filterUint, "uint8": filterUint, "uint16": filterUint, "uint32": filterUint, "uint64": filterUint,
+	"float32": filterFloat, "float64": filterFloat,
+	"bool":       filterBool,
+	"time.Time":  filterTime,
+	"storm.Date": filterDate,
+}
+
+// fieldSet returns the columns a client may name, or nil when fields is empty
+func fieldSet(fields []string) map[string]bool {
+	if len(fields) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		allowed[field] = true
+	}
+	return allowed
+}
+
+// requestColumn resolves a column named by a client. It must be in allowed
+// when that is set, and otherwise must not be sensitive.
+func (r *Repository[T]) requestColumn(field string, allowed map[string]bool) *ColumnMetadata {
+	col := r.columnByDBName(field)
+	if col == nil || (allowed != nil && !allowed[field]) || (allowed == nil && col.IsSensitive) {
+		return nil
+	}
+	return col
+}
+
+// filterColumn resolves field to a column that may be filtered
+func (r *Repository[T]) filterColumn(field string, allowed map[string]bool) (*ColumnMetadata, filterKind, error) {
+	col := r.requestColumn(field, allowed)
+	if col == nil {
+		return nil, 0, fmt.Errorf("%w: cannot filter on %q", ErrInvalidRequest, field)
+	}
+	if _, isEnum := col.Tags["enum"]; isEnum {
+		return col, filterString, nil
+	}
+	kind, ok := filterKinds[strings.TrimPrefix(col.GoType, "*")]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: cannot filter on %q", ErrInvalidRequest, field)
+	}
+	return col, kind, nil
+}
+
+// filterCondition builds the condition for one filter on the column named
+// column in the query
+func (r *Repository[T]) filterCondition(column string, filter Filter, allowed map[string]bool) (Condition, error) {
+	col, kind, err := r.filterColumn(filter.Field, allowed)
+	if err != nil {
+		return Condition{}, err
+	}
+
+	switch filter.Op {
+	case FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte:
+	case FilterIsNull:
+		isNull, err := filterBoolValue(filter.Value)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: %s is_null takes true or false", ErrInvalidRequest, filter.Field)
+		}
+		if isNull {
+			return Condition{squirrel.Eq{column: nil}}, nil
+		}
+		return Condition{squirrel.NotEq{column: nil}}, nil
+
+	case FilterIn, FilterNotIn:
+		raw, err := filterList(filter.Value)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: %s %s takes a list", ErrInvalidRequest, filter.Field, filter.Op)
+		}
+		values := make([]interface{}, len(raw))
+		for i, v := range raw {
+			if values[i], err = filterValue(col, kind, v); err != nil {
+				return Condition{}, err
+			}
+		}
+		if filter.Op == FilterIn {
+			return Condition{anyOf(r.options, column, values)}, nil
+		}
+		return Condition{noneOf(r.options, column, values)}, nil
+
+	case FilterLike, FilterILike:
+		pattern, ok := filter.Value.(string)
+		if !ok || kind != filterString || !isTextType(col.DBType) {
+			return Condition{}, fmt.Errorf("%w: %s does not support %s", ErrInvalidRequest, filter.Field, filter.Op)
+		}
+		if filter.Op == FilterLike {
+			return Condition{squirrel.Like{column: pattern}}, nil
+		}
+		return Condition{squirrel.ILike{column: pattern}}, nil
+	default:
+		return Condition{}, fmt.Errorf("%w: unknown filter operator %q", ErrInvalidRequest, filter.Op)
+	}
+
+	value, err := filterValue(col, kind, filter.Value)
+	if err != nil {
+		return Condition{}, err
+	}
+	switch filter.Op {
+	case FilterEq:
+		return Condition{squirrel.Eq{column: value}}, nil
+	case FilterNe:
+		return Condition{squirrel.NotEq{column: value}}, nil
+	}
+
+	if kind == filterBool {
+		return Condition{}, fmt.Errorf("%w: %s does not support %s", ErrInvalidRequest, filter.Field, filter.Op)
+	}
+	switch filter.Op {
+	case FilterGt:
+		return Condition{squirrel.Gt{column: value}}, nil
+	case FilterGte:
+		return Condition{squirrel.GtOrEq{column: value}}, nil
+	case FilterLt:
+		return Condition{squirrel.Lt{column: value}}, nil
+	default:
+		return Condition{squirrel.LtOrEq{column: value}}, nil
+	}
+}
+
+// filterValue converts a raw filter value to the type of col
+func filterValue(col *ColumnMetadata, kind filterKind, raw interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("%w: invalid value %v for %s", ErrInvalidRequest, raw, col.DBName)
+
+	switch v := raw.(type) {
+	case string:
+		switch kind {
+		case filterString:
+			if values, isEnum := col.Tags["enum"]; isEnum && !containsValue(strings.Split(values, ","), v) {
+				return nil, invalid
+			}
+			return v, nil
+		case filterInt:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return n, nil
+		case filterUint:
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return n, nil
+		case filterFloat:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return n, nil
+		case filterBool:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, invalid
+			}
+			return b, nil
+		case filterTime:
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, invalid
+			}
+			return t, nil
+		case filterDate:
+			d, err := ParseDate(v)
+			if err != nil {
+				return nil, invalid
+			}
+			return d, nil
+		}
+	case float64:
+		switch kind {
+		case filterFloat:
+			return v, nil
+		case filterInt:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), nil
+			}
+		case filterUint:
+			if v == math.Trunc(v) && v >= 0 && v < math.MaxUint64 {
+				return uint64(v), nil
+			}
+		}
+	case bool:
+		if kind == filterBool {
+			return v, nil
+		}
+	}
+	return nil, invalid
+}
+
+func filterBoolValue(raw interface{}) (bool, error) {
+	switch v := raw.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("not a boolean: %v", raw)
+}
+
+func filterList(raw interface{}) ([]interface{}, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		return v, nil
+	case []string:
+		return toInterfaces(v), nil
+	case string:
+		return toInterfaces(strings.Split(v, ",")), nil
+	}
+	return nil, fmt.Errorf("not a list: %v", raw)
+}
+
+// isTextType reports whether a column of dbType can be matched with LIKE
+func isTextType(dbType string) bool {
+	dbType = strings.ToLower(dbType)
+	return dbType == "" || strings.Contains(dbType, "text") || strings.Contains(dbType, "char")
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter adds the conditions of spec to the query. Fields are database column
+// names; when fields are given only those may be filtered on, otherwise any
+// column except sensitive ones. Unknown fields and operators, and values that
+// do not convert to the column's type, fail the query with an error wrapping
+// ErrInvalidRequest.
+func (q *Query[T]) Filter(spec FilterSpec, fields ...string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+
+	allowed := fieldSet(fields)
+	for _, filter := range spec.Filters {
+		column := quoteIdent(q.tableRef()) + "." + quoteIdent(filter.Field)
+		condition, err := q.repo.filterCondition(column, filter, allowed)
+		if err != nil {
+			q.err = err
+			return q
+		}
+		q.whereClause = append(q.whereClause, condition.sqlizer(q.repo.options))
+	}
+	return q
+}
@@ -0,0 +1,122 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilterQuery(t *testing.T) {
+	values, err := url.ParseQuery("filter[name]=Ann&filter[id][gte]=3&filter[id][in]=1,2&limit=10&name=Bob")
+	require.NoError(t, err)
+
+	spec, err := ParseFilterQuery(values)
+	require.NoError(t, err)
+	assert.Equal(t, []Filter{
+		{Field: "id", Op: FilterGte, Value: "3"},
+		{Field: "id", Op: FilterIn, Value: "1,2"},
+		{Field: "name", Op: FilterEq, Value: "Ann"},
+	}, spec.Filters)
+
+	_, err = ParseFilterQuery(url.Values{"filter[name": {"Ann"}})
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+}
+
+func TestQueryFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter Filter
+		where  string
+		args   []driver.Value
+	}{
+		{"eq string", Filter{"name", FilterEq, "Ann"}, "users.name = $1", []driver.Value{"Ann"}},
+		{"ne int from string", Filter{"id", FilterNe, "4"}, "users.id <> $1", []driver.Value{int64(4)}},
+		{"gt int from json", Filter{"id", FilterGt, float64(4)}, "users.id > $1", []driver.Value{int64(4)}},
+		{"lte time", Filter{"created_at", FilterLte, since.Format(time.RFC3339)}, "users.created_at <= $1", []driver.Value{since}},
+		{"bool", Filter{"is_active", FilterEq, "true"}, "users.is_active = $1", []driver.Value{true}},
+		{"in", Filter{"id", FilterIn, "1,2"}, "users.id IN ($1,$2)", []driver.Value{int64(1), int64(2)}},
+		{"not in json list", Filter{"id", FilterNotIn, []interface{}{float64(1)}}, "users.id NOT IN ($1)", []driver.Value{int64(1)}},
+		{"ilike", Filter{"email", FilterILike, "%@example.com"}, "users.email ILIKE $1", []driver.Value{"%@example.com"}},
+		{"is null", Filter{"name", FilterIsNull, "true"}, "users.name IS NULL", nil},
+		{"is not null", Filter{"name", FilterIsNull, false}, "users.name IS NOT NULL", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("WHERE (" + tt.where + ")")).
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			_, err := repo.Query(context.Background()).Filter(FilterSpec{Filters: []Filter{tt.filter}}).Find()
+			require.NoError(t, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryFilterRejects(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Columns["Email"].IsSensitive = true
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		fields []string
+	}{
+		{"unknown field", Filter{"password", FilterEq, "x"}, nil},
+		{"unknown op", Filter{"name", "regex", "x"}, nil},
+		{"bad int", Filter{"id", FilterEq, "four"}, nil},
+		{"fractional int", Filter{"id", FilterEq, 1.5}, nil},
+		{"bad time", Filter{"created_at", FilterGt, "yesterday"}, nil},
+		{"ordering bool", Filter{"is_active", FilterGt, "true"}, nil},
+		{"like on int", Filter{"id", FilterLike, "1%"}, nil},
+		{"in without list", Filter{"id", FilterIn, 3}, nil},
+		{"sensitive column", Filter{"email", FilterEq, "ann@example.com"}, nil},
+		{"outside allowed fields", Filter{"name", FilterEq, "Ann"}, []string{"id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.Query(context.Background()).Filter(FilterSpec{Filters: []Filter{tt.filter}}, tt.fields...).Find()
+			assert.True(t, errors.Is(err, ErrInvalidRequest), "got %v", err)
+		})
+	}
+
+	t.Run("allowed sensitive column", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE (users.email = $1)")).
+			WithArgs("ann@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var spec FilterSpec
+		require.NoError(t, json.Unmarshal([]byte(`{"filters":[{"field":"email","op":"eq","value":"ann@example.com"}]}`), &spec))
+		_, err := repo.Query(context.Background()).Filter(spec, "email").Find()
+		require.NoError(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
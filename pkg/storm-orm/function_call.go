@@ -0,0 +1,103 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var functionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// FunctionCall is a call of a database function, built by Storm.CallFunction
+// and run by one of its Scan, Select or Exec methods
+type FunctionCall struct {
+	storm *Storm
+	ctx   context.Context
+	name  string
+	args  []interface{}
+}
+
+// CallFunction calls the database function name, optionally schema-qualified,
+// with args bound in order. The call selects from the function, so a scalar
+// result is a single column named after it, OUT parameters and RETURNS TABLE
+// columns are columns named after them, and set-returning functions return a
+// row per element:
+//
+//	var total int64
+//	err := s.CallFunction(ctx, "count_posts", authorID).Scan(&total)
+func (s *Storm) CallFunction(ctx context.Context, name string, args ...interface{}) *FunctionCall {
+	return &FunctionCall{storm: s, ctx: ctx, name: name, args: args}
+}
+
+// Scan reads the first row into dest: a pointer to a scalar for a function
+// returning a single value, or to a struct whose db tags name the result
+// columns. ErrNotFound is returned when the function returns no rows.
+func (c *FunctionCall) Scan(dest interface{}) error {
+	if err := c.checkDest(dest); err != nil {
+		return err
+	}
+	return c.run(dest, func(middlewareCtx *MiddlewareContext) error {
+		if err := c.storm.executor.GetContext(c.ctx, dest, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+			return parsePostgreSQLError(err, c.name, "")
+		}
+		return nil
+	})
+}
+
+// Select reads every row of a set-returning function into dest, a pointer
+// to a slice of scalars or structs
+func (c *FunctionCall) Select(dest interface{}) error {
+	if err := c.checkDest(dest); err != nil {
+		return err
+	}
+	if reflect.ValueOf(dest).Elem().Kind() != reflect.Slice {
+		return &Error{Op: c.name, Err: fmt.Errorf("destination must be a pointer to a slice, got %T", dest)}
+	}
+	return c.run(dest, func(middlewareCtx *MiddlewareContext) error {
+		if err := c.storm.executor.SelectContext(c.ctx, dest, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+			return parsePostgreSQLError(err, c.name, "")
+		}
+		return nil
+	})
+}
+
+// Exec calls a function for its effects, such as one returning void
+func (c *FunctionCall) Exec() error {
+	return c.run(nil, func(middlewareCtx *MiddlewareContext) error {
+		if _, err := c.storm.executor.ExecContext(c.ctx, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+			return parsePostgreSQLError(err, c.name, "")
+		}
+		return nil
+	})
+}
+
+// SQL returns the statement the call runs
+func (c *FunctionCall) SQL() string {
+	placeholders := make([]string, len(c.args))
+	for i := range c.args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("SELECT * FROM %s(%s)", c.name, strings.Join(placeholders, ", "))
+}
+
+func (c *FunctionCall) checkDest(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return &Error{Op: c.name, Err: fmt.Errorf("destination must be a non-nil pointer, got %T", dest)}
+	}
+	return nil
+}
+
+// run validates the function name and runs finalFunc through the Storm's
+// middleware as OpFunctionCall
+func (c *FunctionCall) run(result interface{}, finalFunc QueryMiddlewareFunc) error {
+	if err := c.ctx.Err(); err != nil {
+		return &Error{Op: c.name, Err: err}
+	}
+	if !functionName.MatchString(c.name) {
+		return &Error{Op: c.name, Err: fmt.Errorf("invalid function name %q", c.name)}
+	}
+	return c.storm.executeStatement(c.ctx, OpFunctionCall, c.name, c.SQL(), c.args, result, finalFunc)
+}
@@ -0,0 +1,79 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type authorStatsResult struct {
+	Posts    int64 `db:"posts"`
+	Comments int64 `db:"comments"`
+}
+
+func TestStormCallFunction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	var seen []*MiddlewareContext
+	s.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			err := next(ctx)
+			seen = append(seen, ctx)
+			return err
+		}
+	})
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM count_posts($1)")).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"count_posts"}).AddRow(3))
+	var total int64
+	require.NoError(t, s.CallFunction(ctx, "count_posts", 7).Scan(&total))
+	assert.Equal(t, int64(3), total)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM reporting.author_stats($1, $2)")).
+		WithArgs(7, true).
+		WillReturnRows(sqlmock.NewRows([]string{"posts", "comments"}).AddRow(3, 10))
+	var stats authorStatsResult
+	require.NoError(t, s.CallFunction(ctx, "reporting.author_stats", 7, true).Scan(&stats))
+	assert.Equal(t, authorStatsResult{Posts: 3, Comments: 10}, stats)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM post_tags($1)")).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"post_tags"}).AddRow("go").AddRow("sql"))
+	var tags []string
+	require.NoError(t, s.CallFunction(ctx, "post_tags", 7).Select(&tags))
+	assert.Equal(t, []string{"go", "sql"}, tags)
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT * FROM refresh_stats()")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, s.CallFunction(ctx, "refresh_stats").Exec())
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM find_author($1)")).
+		WithArgs("nobody").
+		WillReturnRows(sqlmock.NewRows([]string{"posts", "comments"}))
+	assert.ErrorIs(t, s.CallFunction(ctx, "find_author", "nobody").Scan(&stats), ErrNotFound)
+
+	require.Len(t, seen, 5)
+	assert.Equal(t, OpFunctionCall, seen[1].Operation)
+	assert.Equal(t, "reporting.author_stats", seen[1].QueryName)
+	assert.Equal(t, []interface{}{7, true}, seen[1].Args)
+	assert.Equal(t, &stats, seen[1].Result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("rejects invalid calls", func(t *testing.T) {
+		assert.ErrorContains(t, s.CallFunction(ctx, "now(); DROP TABLE users; --").Exec(), "invalid function name")
+		assert.ErrorContains(t, s.CallFunction(ctx, "post_tags", 7).Select(&total), "pointer to a slice")
+		assert.ErrorContains(t, s.CallFunction(ctx, "count_posts", 7).Scan(total), "non-nil pointer")
+		assert.Len(t, seen, 5)
+	})
+}
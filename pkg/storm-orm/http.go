@@ -0,0 +1,208 @@
+package orm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ErrInvalidRequest marks malformed HTTP input such as bad JSON or query parameters
+var ErrInvalidRequest = errors.New("invalid request")
+
+// maxRequestBody caps the JSON body size accepted by DecodeJSON
+const maxRequestBody = 1 << 20
+
+// StatusCode maps an error returned by a repository to an HTTP status code
+func StatusCode(err error) int {
+	var validationErr ValidationError
+	var validationErrs ValidationErrors
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDuplicateKey):
+		return http.StatusConflict
+	case errors.As(err, &validationErr), errors.As(err, &validationErrs),
+		errors.Is(err, ErrForeignKey), errors.Is(err, ErrCheckConstraint),
+		errors.Is(err, ErrNotNull), errors.Is(err, ErrImmutableColumn), errors.Is(err, ErrUnknownColumn):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteJSON writes v as a JSON response with the given status code
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// WriteError writes err as a JSON error response. Validation errors list the
+// failing fields; internal errors are not exposed to the client.
+func WriteError(w http.ResponseWriter, err error) {
+	status := StatusCode(err)
+
+	body := map[string]interface{}{"error": err.Error()}
+	if status == http.StatusInternalServerError {
+		body["error"] = http.StatusText(status)
+	}
+
+	var validationErrs ValidationErrors
+	var validationErr ValidationError
+	if errors.As(err, &validationErrs) {
+		body["fields"] = validationFields(validationErrs)
+	} else if errors.As(err, &validationErr) {
+		body["fields"] = validationFields(ValidationErrors{validationErr})
+	}
+
+	WriteJSON(w, status, body)
+}
+
+func validationFields(errs ValidationErrors) map[string]string {
+	fields := make(map[string]string, len(errs))
+	for _, err := range errs {
+		fields[err.Field] = err.Message
+	}
+	return fields
+}
+
+// DecodeJSON decodes the request body into v, rejecting unknown fields
+func DecodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// ParsePagination reads the limit and offset query parameters, applying
+// defaultLimit when limit is absent and capping it at maxLimit. page[size]
+// and page[number], counting pages from 1, may be given instead.
+func ParsePagination(r *http.Request, defaultLimit, maxLimit uint64) (limit, offset uint64, err error) {
+	query := r.URL.Query()
+	if query.Has("page[size]") || query.Has("page[number]") {
+		return parsePageNumber(query, defaultLimit, maxLimit)
+	}
+
+	limit = defaultLimit
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("%w: limit must be a non-negative integer", ErrInvalidRequest)
+		}
+	}
+	if maxLimit > 0 && (limit == 0 || limit > maxLimit) {
+		limit = maxLimit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidRequest)
+		}
+	}
+	return limit, offset, nil
+}
+
+func parsePageNumber(query url.Values, defaultLimit, maxLimit uint64) (limit, offset uint64, err error) {
+	if query.Has("limit") || query.Has("offset") {
+		return 0, 0, fmt.Errorf("%w: page cannot be combined with limit or offset", ErrInvalidRequest)
+	}
+
+	limit = defaultLimit
+	if value := query.Get("page[size]"); value != "" {
+		if limit, err = strconv.ParseUint(value, 10, 64); err != nil || limit == 0 {
+			return 0, 0, fmt.Errorf("%w: page[size] must be a positive integer", ErrInvalidRequest)
+		}
+	}
+	if maxLimit > 0 && (limit == 0 || limit > maxLimit) {
+		limit = maxLimit
+	}
+
+	number := uint64(1)
+	if value := query.Get("page[number]"); value != "" {
+		if number, err = strconv.ParseUint(value, 10, 64); err != nil || number == 0 {
+			return 0, 0, fmt.Errorf("%w: page[number] must be a positive integer", ErrInvalidRequest)
+		}
+	}
+	if limit > 0 && number-1 > math.MaxUint64/limit {
+		return 0, 0, fmt.Errorf("%w: page[number] is too large", ErrInvalidRequest)
+	}
+	return limit, (number - 1) * limit, nil
+}
+
+// ParseParam converts a path or query parameter into T. Strings, booleans,
+// integers and floats are supported.
+func ParseParam[T any](value string) (T, error) {
+	var result T
+	var err error
+
+	switch target := any(&result).(type) {
+	case *string:
+		*target = value
+	case *bool:
+		*target, err = strconv.ParseBool(value)
+	case *int:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 0)
+		*target = int(n)
+	case *int8:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 8)
+		*target = int8(n)
+	case *int16:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 16)
+		*target = int16(n)
+	case *int32:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		*target = int32(n)
+	case *int64:
+		*target, err = strconv.ParseInt(value, 10, 64)
+	case *uint:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 0)
+		*target = uint(n)
+	case *uint8:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 8)
+		*target = uint8(n)
+	case *uint16:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 16)
+		*target = uint16(n)
+	case *uint32:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 32)
+		*target = uint32(n)
+	case *uint64:
+		*target, err = strconv.ParseUint(value, 10, 64)
+	case *float32:
+		var n float64
+		n, err = strconv.ParseFloat(value, 32)
+		*target = float32(n)
+	case *float64:
+		*target, err = strconv.ParseFloat(value, 64)
+	default:
+		return result, fmt.Errorf("%w: unsupported parameter type %T", ErrInvalidRequest, result)
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("%w: invalid value %q", ErrInvalidRequest, value)
+	}
+	return result, nil
+}
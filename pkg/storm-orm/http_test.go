@@ -0,0 +1,129 @@
+package orm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"invalid request", fmt.Errorf("%w: bad", ErrInvalidRequest), http.StatusBadRequest},
+		{"not found", &Error{Op: "findByID", Err: ErrNotFound}, http.StatusNotFound},
+		{"not found lookup", &NotFoundError{Table: "users"}, http.StatusNotFound},
+		{"duplicate", &Error{Op: "create", Err: ErrDuplicateKey}, http.StatusConflict},
+		{"foreign key", &Error{Op: "create", Err: ErrForeignKey}, http.StatusUnprocessableEntity},
+		{"validation", ValidationErrors{{Field: "email", Message: "is required"}}, http.StatusUnprocessableEntity},
+		{"timeout", &Error{Op: "find", Err: ErrTimeout}, http.StatusGatewayTimeout},
+		{"unknown", fmt.Errorf("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, StatusCode(tt.err))
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	t.Run("validation errors list fields", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteError(rec, ValidationErrors{{Field: "email", Message: "is required"}})
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, map[string]interface{}{"email": "is required"}, body["fields"])
+	})
+
+	t.Run("internal errors are hidden", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteError(rec, fmt.Errorf("connection string postgres://secret"))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "secret")
+	})
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var user TestUser
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"john"}`))
+	require.NoError(t, DecodeJSON(req, &user))
+	assert.Equal(t, "john", user.Name)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Unknown":1}`))
+	assert.ErrorIs(t, DecodeJSON(req, &user), ErrInvalidRequest)
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query          string
+		expectedLimit  uint64
+		expectedOffset uint64
+		expectError    bool
+	}{
+		{"", 50, 0, false},
+		{"limit=10&offset=20", 10, 20, false},
+		{"limit=1000", 500, 0, false},
+		{"limit=0", 500, 0, false},
+		{"limit=-1", 0, 0, true},
+		{"offset=abc", 0, 0, true},
+		{"page%5Bsize%5D=10&page%5Bnumber%5D=3", 10, 20, false},
+		{"page%5Bnumber%5D=2", 50, 50, false},
+		{"page%5Bsize%5D=1000&page%5Bnumber%5D=2", 500, 500, false},
+		{"page%5Bnumber%5D=0", 0, 0, true},
+		{"page%5Bsize%5D=0", 0, 0, true},
+		{"page%5Bnumber%5D=2&offset=10", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			limit, offset, err := ParsePagination(req, 50, 500)
+			if tt.expectError {
+				assert.ErrorIs(t, err, ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedLimit, limit)
+			assert.Equal(t, tt.expectedOffset, offset)
+		})
+	}
+}
+
+func TestParseParam(t *testing.T) {
+	s, err := ParseParam[string]("abc")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", s)
+
+	n, err := ParseParam[int64]("42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+
+	b, err := ParseParam[bool]("true")
+	require.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = ParseParam[int8]("300")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, err = ParseParam[int]("abc")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, err = ParseParam[[]string]("a")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
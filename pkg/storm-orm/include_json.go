@@ -0,0 +1,439 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// IncludeStrategy selects how a query loads the relationships named by Include
+type IncludeStrategy int
+
+const (
+	// SeparateQueries loads each included relationship with one further query
+	// covering every record. It is the default.
+	SeparateQueries IncludeStrategy = iota
+
+	// JoinJSON loads the records and their included relationships, nested ones
+	// such as "Posts.Comments" included, in a single query. Each relationship is
+	// aggregated into JSON by a LEFT JOIN LATERAL subquery.
+	JoinJSON
+)
+
+// includeDataColumn is the column each LATERAL subquery returns its JSON in
+const includeDataColumn = "storm_data"
+
+// IncludeStrategy sets how the query loads its included relationships
+func (q *Query[T]) IncludeStrategy(strategy IncludeStrategy) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.includeStrategy = strategy
+	return q
+}
+
+// includeTree groups dotted includes such as "Posts.Comments" under their
+// first relationship. Conditions stay with the last relationship named.
+func includeTree(includes []include) []include {
+	var tree []include
+	for _, inc := range includes {
+		tree = addIncludePath(tree, strings.Split(inc.name, "."), inc.conditions)
+	}
+	return tree
+}
+
+func addIncludePath(tree []include, path []string, conditions []Condition) []include {
+	i := 0
+	for i < len(tree) && tree[i].name != path[0] {
+		i++
+	}
+	if i == len(tree) {
+		tree = append(tree, include{name: path[0]})
+	}
+
+	if len(path) == 1 {
+		tree[i].conditions = append(tree[i].conditions, conditions...)
+	} else {
+		tree[i].nested = addIncludePath(tree[i].nested, path[1:], conditions)
+	}
+	return tree
+}
+
+// findWithJSONIncludes runs the query with a LEFT JOIN LATERAL per included
+// relationship and decodes the JSON each one returns into the records
+func (q *Query[T]) findWithJSONIncludes() ([]T, error) {
+	tree := includeTree(q.includes)
+
+	builder := q.selectBuilder()
+	for i, inc := range tree {
+		alias := fmt.Sprintf("storm_include_%d", i)
+		subquery, err := jsonIncludeQuery(q.repo.metadata, q.tableRef(), inc, alias)
+		if err != nil {
+			return nil, &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   err,
+			}
+		}
+		builder = builder.
+			Column(alias + "." + includeDataColumn).
+			JoinClause(squirrel.Expr("LEFT JOIN LATERAL (?) AS "+alias+" ON true", subquery))
+	}
+
+	var records []T
+	err := q.repo.executeResultMiddleware(OpQuery, q.ctx, nil, builder, &records, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		var executor DBExecutor = q.repo.db
+		if q.tx != nil {
+			executor = q.tx
+		}
+
+		var included [][][]byte
+		records, included, err = q.repo.selectRecordsWithData(q.ctx, executor, sqlQuery, args, len(tree))
+		if err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to execute query: %w", err),
+			}
+		}
+
+		for i := range records {
+			record := reflect.ValueOf(&records[i]).Elem()
+			for j, inc := range tree {
+				if err := decodeIncluded(q.repo.metadata, record, inc, included[i][j]); err != nil {
+					return &Error{
+						Op:    "load_relationship",
+						Table: q.repo.metadata.TableName,
+						Err:   fmt.Errorf("failed to load relationship %s: %w", inc.name, err),
+					}
+				}
+			}
+		}
+		if err := q.repo.options.decrypt(&records); err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   err,
+			}
+		}
+		return nil
+	})
+
+	return records, err
+}
+
+// jsonIncludeQuery builds the LATERAL subquery that returns the rows related
+// through inc to the row of metadata named parent, as a JSON array for the
+// to-many relationships and a JSON object or NULL otherwise. Nested includes
+// are embedded in each row under their relationship name.
+func jsonIncludeQuery(metadata *ModelMetadata, parent string, inc include, alias string) (squirrel.SelectBuilder, error) {
+	rel := metadata.Relationships[inc.name]
+	if rel == nil {
+		return squirrel.SelectBuilder{}, fmt.Errorf("relationship %s not found", inc.name)
+	}
+	if rel.TargetMetadata == nil {
+		return squirrel.SelectBuilder{}, fmt.Errorf("relationship %s has no target metadata", inc.name)
+	}
+
+	table := rel.TargetTable
+	if table == "" {
+		table = rel.Target
+	}
+	ref, from := table, quoteIdent(table)
+	if table == parent {
+		// A relationship back to the same table needs its own name
+		ref = alias + "_t"
+		from += " AS " + ref
+	}
+
+	sourceKey := rel.SourceKey
+	if sourceKey == "" {
+		sourceKey = "id"
+	}
+	targetKey := rel.TargetKey
+	if targetKey == "" {
+		targetKey = "id"
+	}
+	column := func(table, column string) string {
+		return quoteIdent(table + "." + column)
+	}
+
+	query := squirrel.Select().From(from)
+	switch rel.Type {
+	case "belongs_to":
+		query = query.Where(column(ref, targetKey) + " = " + column(parent, rel.ForeignKey))
+		if rel.isPolymorphic() {
+			query = query.Where(squirrel.Eq{column(parent, rel.PolymorphicType): rel.PolymorphicValue})
+		}
+	case "has_one", "has_many":
+		query = query.Where(column(ref, rel.ForeignKey) + " = " + column(parent, sourceKey))
+		if rel.isPolymorphic() {
+			query = query.Where(squirrel.Eq{column(ref, rel.PolymorphicType): rel.PolymorphicValue})
+		}
+	case "has_many_through":
+		through := alias + "_through"
+		query = query.
+			Join(fmt.Sprintf("%s AS %s ON %s = %s",
+				quoteIdent(rel.Through), through, column(ref, targetKey), column(through, rel.ThroughTK))).
+			Where(column(through, rel.ThroughFK) + " = " + column(parent, sourceKey))
+	default:
+		return squirrel.SelectBuilder{}, fmt.Errorf("unsupported relationship type: %s", rel.Type)
+	}
+
+	row := "to_jsonb(" + quoteIdent(ref) + ")"
+	for i, nested := range inc.nested {
+		nestedAlias := fmt.Sprintf("%s_%d", alias, i)
+		subquery, err := jsonIncludeQuery(rel.TargetMetadata, ref, nested, nestedAlias)
+		if err != nil {
+			return squirrel.SelectBuilder{}, err
+		}
+		query = query.JoinClause(squirrel.Expr("LEFT JOIN LATERAL (?) AS "+nestedAlias+" ON true", subquery))
+		row += fmt.Sprintf(" || jsonb_build_object('%s', %s.%s)", strings.ReplaceAll(nested.name, "'", "''"), nestedAlias, includeDataColumn)
+	}
+
+	for _, condition := range rel.scopeConditions() {
+		query = query.Where(squirrel.Expr(condition))
+	}
+	for _, condition := range inc.conditions {
+		query = query.Where(condition.ToSqlizer())
+	}
+
+	if rel.Type == "has_many" || rel.Type == "has_many_through" {
+		orderBy := ""
+		if rel.OrderBy != "" {
+			orderBy = " ORDER BY " + rel.OrderBy
+		}
+		return query.Column("COALESCE(jsonb_agg(" + row + orderBy + "), '[]'::jsonb) AS " + includeDataColumn), nil
+	}
+
+	if rel.OrderBy != "" {
+		query = query.OrderBy(rel.OrderBy)
+	}
+	return query.Column(row + " AS " + includeDataColumn).Limit(1), nil
+}
+
+// selectRecordsWithData runs query, whose last extra columns follow the
+// model's, and returns each row scanned into a T along with the raw values of
+// those extra columns. Encrypted columns are left for the caller to decrypt
+// once the included rows are decoded.
+func (r *Repository[T]) selectRecordsWithData(ctx context.Context, exec DBExecutor, query string, args []interface{}, extra int) ([]T, [][][]byte, error) {
+	rows, err := exec.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(columns) < extra {
+		return nil, nil, fmt.Errorf("expected %d included columns, got %d columns", extra, len(columns))
+	}
+	columns = columns[:len(columns)-extra]
+
+	var record, zero T
+	fields, ok := scanFields(r.metadata, &record, columns)
+	if !ok {
+		fields = nil
+		value := reflect.ValueOf(&record).Elem()
+		mapper := reflectx.NewMapperFunc("db", strings.ToLower)
+		for i, traversal := range mapper.TraversalsByName(value.Type(), columns) {
+			if len(traversal) == 0 {
+				return nil, nil, fmt.Errorf("missing destination name %s in %T", columns[i], record)
+			}
+			fields = append(fields, reflectx.FieldByIndexes(value, traversal).Addr().Interface())
+		}
+	}
+
+	data := make([][]byte, extra)
+	for i := range data {
+		fields = append(fields, &data[i])
+	}
+
+	var records []T
+	var included [][][]byte
+	for rows.Next() {
+		record = zero
+		for i := range data {
+			data[i] = nil
+		}
+		if err := rows.Scan(fields...); err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+		included = append(included, append([][]byte(nil), data...))
+	}
+	return records, included, rows.Err()
+}
+
+// decodeIncluded assigns the JSON returned for inc to its relationship field
+// on record, a struct described by metadata
+func decodeIncluded(metadata *ModelMetadata, record reflect.Value, inc include, data []byte) error {
+	rel := metadata.Relationships[inc.name]
+	field := record.FieldByName(rel.Name)
+	if !field.IsValid() || !field.CanSet() {
+		return fmt.Errorf("%s has no settable field %s", record.Type(), rel.Name)
+	}
+
+	if field.Kind() == reflect.Slice {
+		var rows []json.RawMessage
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &rows); err != nil {
+				return err
+			}
+		}
+		related := reflect.MakeSlice(field.Type(), len(rows), len(rows))
+		for i, row := range rows {
+			if err := decodeJSONRow(rel.TargetMetadata, related.Index(i), inc.nested, row); err != nil {
+				return err
+			}
+		}
+		field.Set(related)
+		return nil
+	}
+
+	field.Set(reflect.Zero(field.Type()))
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return decodeJSONRow(rel.TargetMetadata, field, inc.nested, data)
+}
+
+// decodeJSONRow decodes a row converted with to_jsonb into target, a struct
+// described by metadata or a pointer to one, matching keys to column names
+func decodeJSONRow(metadata *ModelMetadata, target reflect.Value, nested []include, data []byte) error {
+	if target.Kind() == reflect.Ptr {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+
+	for _, col := range metadata.OrderedColumns() {
+		value, ok := row[col.DBName]
+		if !ok {
+			continue
+		}
+		field := target.FieldByName(col.FieldName)
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+		if err := decodeJSONValue(field, value); err != nil {
+			return fmt.Errorf("column %s: %w", col.DBName, err)
+		}
+	}
+
+	for _, inc := range nested {
+		if err := decodeIncluded(metadata, target, inc, row[inc.name]); err != nil {
+			return fmt.Errorf("failed to load relationship %s: %w", inc.name, err)
+		}
+	}
+	return nil
+}
+
+// decodeJSONValue decodes one column of a to_jsonb row into field. Types that
+// read their value from the database through sql.Scanner get it in the form
+// the driver would use.
+func decodeJSONValue(field reflect.Value, value json.RawMessage) error {
+	if string(value) == "null" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		target := reflect.New(field.Type().Elem())
+		if err := decodeJSONValue(target.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(target)
+		return nil
+	}
+
+	switch target := field.Addr().Interface().(type) {
+	case *time.Time:
+		return decodeJSONTime(target, value)
+	case *[]byte:
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			return err
+		}
+		if strings.HasPrefix(text, `\x`) {
+			decoded, err := hex.DecodeString(text[2:])
+			*target = decoded
+			return err
+		}
+		*target = []byte(text)
+		return nil
+	case json.Unmarshaler:
+		return target.UnmarshalJSON(value)
+	case sql.Scanner:
+		return target.Scan(scannerValue(value))
+	default:
+		return json.Unmarshal(value, target)
+	}
+}
+
+// decodeJSONTime parses the timestamp, date and time forms to_jsonb writes
+func decodeJSONTime(target *time.Time, value json.RawMessage) error {
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return err
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05.999999999"} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			*target = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a time", text)
+}
+
+// scannerValue converts a JSON value into the type a driver would hand to
+// sql.Scanner: strings, int64 or float64 numbers, booleans, and the raw JSON
+// for objects and arrays
+func scannerValue(value json.RawMessage) interface{} {
+	decoder := json.NewDecoder(strings.NewReader(string(value)))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return []byte(value)
+	}
+	switch v := decoded.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case string, bool:
+		return v
+	default:
+		return []byte(value)
+	}
+}
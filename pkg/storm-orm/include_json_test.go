@@ -0,0 +1,240 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonAuthor struct {
+	ID      int64         `db:"id"`
+	Name    string        `db:"name"`
+	Posts   []jsonPost    `db:"-"`
+	Profile *jsonBio      `db:"-"`
+	Mentor  *jsonAuthor   `db:"-"`
+	Tags    []jsonPostTag `db:"-"`
+}
+
+type jsonPost struct {
+	ID        int64       `db:"id"`
+	AuthorID  int64       `db:"author_id"`
+	Title     string      `db:"title"`
+	Subtitle  *string     `db:"subtitle"`
+	CreatedAt time.Time   `db:"created_at"`
+	Author    *jsonAuthor `db:"-"`
+}
+
+type jsonBio struct {
+	AuthorID int64  `db:"author_id"`
+	Body     string `db:"body"`
+}
+
+type jsonPostTag struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+// jsonMetadata builds metadata for table with the given field to column pairs,
+// the first being the primary key
+func jsonMetadata(table string, fields ...string) *ModelMetadata {
+	metadata := &ModelMetadata{
+		TableName:   table,
+		Columns:     map[string]*ColumnMetadata{},
+		ReverseMap:  map[string]string{},
+		PrimaryKeys: []string{fields[1]},
+	}
+	for i := 0; i < len(fields); i += 2 {
+		metadata.Columns[fields[i]] = &ColumnMetadata{FieldName: fields[i], DBName: fields[i+1]}
+		metadata.ReverseMap[fields[i+1]] = fields[i]
+		metadata.ColumnOrder = append(metadata.ColumnOrder, fields[i])
+	}
+	return metadata
+}
+
+func jsonIncludeMetadata() *ModelMetadata {
+	authors := jsonMetadata("authors", "ID", "id", "Name", "name")
+	posts := jsonMetadata("posts", "ID", "id", "AuthorID", "author_id", "Title", "title", "Subtitle", "subtitle", "CreatedAt", "created_at")
+	bios := jsonMetadata("bios", "AuthorID", "author_id", "Body", "body")
+	tags := jsonMetadata("tags", "ID", "id", "Name", "name")
+
+	authors.Relationships = map[string]*RelationshipMetadata{
+		"Posts": {
+			Name: "Posts", Type: "has_many", Target: "jsonPost", TargetTable: "posts",
+			ForeignKey: "author_id", OrderBy: "created_at DESC", TargetMetadata: posts,
+		},
+		"Profile": {
+			Name: "Profile", Type: "has_one", Target: "jsonBio", TargetTable: "bios",
+			ForeignKey: "author_id", TargetMetadata: bios,
+		},
+		"Mentor": {
+			Name: "Mentor", Type: "belongs_to", Target: "jsonAuthor", TargetTable: "authors",
+			ForeignKey: "mentor_id", TargetMetadata: authors,
+		},
+		"Tags": {
+			Name: "Tags", Type: "has_many_through", Target: "jsonPostTag", TargetTable: "tags",
+			Through: "author_tags", ThroughFK: "author_id", ThroughTK: "tag_id", TargetMetadata: tags,
+		},
+	}
+	posts.Relationships = map[string]*RelationshipMetadata{
+		"Author": {
+			Name: "Author", Type: "belongs_to", Target: "jsonAuthor", TargetTable: "authors",
+			ForeignKey: "author_id", TargetMetadata: authors,
+		},
+	}
+	return authors
+}
+
+func TestIncludeTree(t *testing.T) {
+	titleCol := Column[string]{Name: "title", Table: "posts"}
+	tree := includeTree([]include{
+		{name: "Posts.Author"},
+		{name: "Profile"},
+		{name: "Posts", conditions: []Condition{titleCol.Eq("Go")}},
+	})
+
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Posts", tree[0].name)
+	assert.Len(t, tree[0].conditions, 1)
+	require.Len(t, tree[0].nested, 1)
+	assert.Equal(t, "Author", tree[0].nested[0].name)
+	assert.Equal(t, "Profile", tree[1].name)
+}
+
+func TestJSONIncludeQuery(t *testing.T) {
+	metadata := jsonIncludeMetadata()
+
+	tests := []struct {
+		name     string
+		include  include
+		expected string
+	}{
+		{
+			name:     "has many",
+			include:  include{name: "Posts"},
+			expected: `SELECT COALESCE(jsonb_agg(to_jsonb(posts) ORDER BY created_at DESC), '[]'::jsonb) AS storm_data FROM posts WHERE posts.author_id = authors.id`,
+		},
+		{
+			name:     "has one",
+			include:  include{name: "Profile"},
+			expected: `SELECT to_jsonb(bios) AS storm_data FROM bios WHERE bios.author_id = authors.id LIMIT 1`,
+		},
+		{
+			name:     "belongs to the same table",
+			include:  include{name: "Mentor"},
+			expected: `SELECT to_jsonb(inc_t) AS storm_data FROM authors AS inc_t WHERE inc_t.id = authors.mentor_id LIMIT 1`,
+		},
+		{
+			name:     "has many through",
+			include:  include{name: "Tags"},
+			expected: `SELECT COALESCE(jsonb_agg(to_jsonb(tags)), '[]'::jsonb) AS storm_data FROM tags JOIN author_tags AS inc_through ON tags.id = inc_through.tag_id WHERE inc_through.author_id = authors.id`,
+		},
+		{
+			name:    "nested",
+			include: include{name: "Posts", nested: []include{{name: "Author"}}},
+			expected: `SELECT COALESCE(jsonb_agg(to_jsonb(posts) || jsonb_build_object('Author', inc_0.storm_data) ORDER BY created_at DESC), '[]'::jsonb) AS storm_data FROM posts ` +
+				`LEFT JOIN LATERAL (SELECT to_jsonb(authors) AS storm_data FROM authors WHERE authors.id = posts.author_id LIMIT 1) AS inc_0 ON true WHERE posts.author_id = authors.id`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := jsonIncludeQuery(metadata, "authors", tt.include, "inc")
+			require.NoError(t, err)
+			sql, _, err := query.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sql)
+		})
+	}
+
+	t.Run("unknown relationship", func(t *testing.T) {
+		_, err := jsonIncludeQuery(metadata, "authors", include{name: "Missing"}, "inc")
+		assert.ErrorContains(t, err, "relationship Missing not found")
+	})
+}
+
+func TestQueryIncludeStrategyJoinJSON(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[jsonAuthor](sqlx.NewDb(db, "postgres"), jsonIncludeMetadata())
+	require.NoError(t, err)
+
+	t.Run("loads nested includes in one query", func(t *testing.T) {
+		titleCol := Column[string]{Name: "title", Table: "posts"}
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, storm_include_0.storm_data, storm_include_1.storm_data FROM authors `+
+			`LEFT JOIN LATERAL (SELECT COALESCE(jsonb_agg(to_jsonb(posts) || jsonb_build_object('Author', storm_include_0_0.storm_data) ORDER BY created_at DESC), '[]'::jsonb) AS storm_data FROM posts `+
+			`LEFT JOIN LATERAL (SELECT to_jsonb(authors) AS storm_data FROM authors WHERE authors.id = posts.author_id LIMIT 1) AS storm_include_0_0 ON true `+
+			`WHERE posts.author_id = authors.id AND posts.title <> $1) AS storm_include_0 ON true `+
+			`LEFT JOIN LATERAL (SELECT to_jsonb(bios) AS storm_data FROM bios WHERE bios.author_id = authors.id LIMIT 1) AS storm_include_1 ON true `+
+			`WHERE (authors.name = $2)`)).
+			WithArgs("Draft", "Ada").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "storm_data", "storm_data"}).
+				AddRow(1, "Ada",
+					`[{"id": 10, "author_id": 1, "title": "Engines", "subtitle": null, "created_at": "2024-03-01T09:30:00.5+00:00", "Author": {"id": 1, "name": "Ada"}}]`,
+					`{"author_id": 1, "body": "Mathematician"}`).
+				AddRow(2, "Alan", `[]`, nil))
+
+		authors, err := repo.Query(context.Background()).
+			IncludeStrategy(JoinJSON).
+			Include("Posts.Author", "Profile").
+			IncludeWhere("Posts", titleCol.NotEq("Draft")).
+			Where(Column[string]{Name: "name", Table: "authors"}.Eq("Ada")).
+			Find()
+		require.NoError(t, err)
+		require.Len(t, authors, 2)
+
+		require.Len(t, authors[0].Posts, 1)
+		post := authors[0].Posts[0]
+		assert.Equal(t, "Engines", post.Title)
+		assert.Nil(t, post.Subtitle)
+		assert.True(t, post.CreatedAt.Equal(time.Date(2024, 3, 1, 9, 30, 0, 500000000, time.UTC)))
+		require.NotNil(t, post.Author)
+		assert.Equal(t, "Ada", post.Author.Name)
+		require.NotNil(t, authors[0].Profile)
+		assert.Equal(t, "Mathematician", authors[0].Profile.Body)
+
+		assert.NotNil(t, authors[1].Posts)
+		assert.Empty(t, authors[1].Posts)
+		assert.Nil(t, authors[1].Profile)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports a relationship without target metadata", func(t *testing.T) {
+		metadata := jsonIncludeMetadata()
+		metadata.Relationships["Posts"].TargetMetadata = nil
+		repo, err := NewRepository[jsonAuthor](sqlx.NewDb(db, "postgres"), metadata)
+		require.NoError(t, err)
+
+		_, err = repo.Query(context.Background()).IncludeStrategy(JoinJSON).Include("Posts").Find()
+		assert.ErrorContains(t, err, "relationship Posts has no target metadata")
+	})
+}
+
+func TestDecodeJSONValue(t *testing.T) {
+	t.Run("timestamps without a zone", func(t *testing.T) {
+		var value time.Time
+		require.NoError(t, decodeJSONValue(reflect.ValueOf(&value).Elem(), []byte(`"2024-03-01T09:30:00"`)))
+		assert.Equal(t, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), value)
+	})
+
+	t.Run("bytea", func(t *testing.T) {
+		var value []byte
+		require.NoError(t, decodeJSONValue(reflect.ValueOf(&value).Elem(), []byte(`"\\x6869"`)))
+		assert.Equal(t, []byte("hi"), value)
+	})
+
+	t.Run("scanners get driver values", func(t *testing.T) {
+		var value sql.NullString
+		require.NoError(t, decodeJSONValue(reflect.ValueOf(&value).Elem(), []byte(`"text"`)))
+		assert.Equal(t, sql.NullString{String: "text", Valid: true}, value)
+	})
+}
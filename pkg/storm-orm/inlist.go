@@ -0,0 +1,57 @@
+package orm
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// DefaultInListLimit is the longest IN list written with one placeholder per
+// value before the list is bound as a single array
+const DefaultInListLimit = 1000
+
+// WithInListLimit sets how many values Column.In, Column.NotIn and the batch
+// queries that load relationships write as separate placeholders in the
+// queries of the Storm, DefaultInListLimit unless set. Longer lists are bound
+// as one array parameter, as "= ANY($1)", which keeps huge key sets clear of
+// PostgreSQL's 65535 parameter limit and gives the planner a single parameter
+// to work with. A limit of zero or less keeps every list inline.
+func WithInListLimit(limit int) Option {
+	return func(s *Storm) {
+		s.options.inListLimit = limit
+	}
+}
+
+// overInListLimit reports whether a list of n values is bound as an array
+// under options
+func overInListLimit(options *queryOptions, n int) bool {
+	limit := DefaultInListLimit
+	if options != nil {
+		limit = options.inListLimit
+	}
+	return limit > 0 && n > limit
+}
+
+// anyOf matches column against values: with IN for short lists and = ANY
+// over an array parameter for lists over the limit of options
+func anyOf[V any](options *queryOptions, column string, values []V) squirrel.Sqlizer {
+	if overInListLimit(options, len(values)) {
+		return squirrel.Expr(column+" = ANY(?)", pq.Array(values))
+	}
+	return squirrel.Eq{column: toInterfaces(values)}
+}
+
+// noneOf is the negation of anyOf, using NOT IN or <> ALL
+func noneOf[V any](options *queryOptions, column string, values []V) squirrel.Sqlizer {
+	if overInListLimit(options, len(values)) {
+		return squirrel.Expr(column+" <> ALL(?)", pq.Array(values))
+	}
+	return squirrel.NotEq{column: toInterfaces(values)}
+}
+
+func toInterfaces[V any](values []V) []interface{} {
+	interfaces := make([]interface{}, len(values))
+	for i, v := range values {
+		interfaces[i] = v
+	}
+	return interfaces
+}
@@ -0,0 +1,135 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// SelectSource is a query whose rows InsertFromQuery can copy. Every *Query
+// satisfies it, whatever its model.
+type SelectSource interface {
+	selectExpressions(expressions []string) (squirrel.SelectBuilder, error)
+}
+
+// selectExpressions returns the query's SELECT with its columns replaced by
+// expressions, keeping its joins, filters, ordering and limits
+func (q *Query[T]) selectExpressions(expressions []string) (squirrel.SelectBuilder, error) {
+	q.applyDefaultScopes()
+	if q.err != nil {
+		return squirrel.SelectBuilder{}, q.err
+	}
+
+	return q.selectBuilder().
+		RemoveColumns().
+		Columns(expressions...).
+		PlaceholderFormat(squirrel.Question), nil
+}
+
+// InsertFromQuery copies the rows of source into the repository's table in a
+// single INSERT INTO ... SELECT and returns the number of rows inserted.
+// columnMapping maps each target column to the expression selecting its value
+// from source, such as a column of the source table or "NOW()". Pass
+// UpsertOptions to add an ON CONFLICT clause, as for Upsert:
+//
+//	n, err := archiveRepo.InsertFromQuery(ctx,
+//	    orderRepo.Query(ctx).Where(models.Orders.CreatedAt.Before(cutoff)),
+//	    map[string]string{"order_id": "orders.id", "total": "orders.total", "archived_at": "NOW()"},
+//	    orm.UpsertOptions{ConflictColumns: []string{"order_id"}})
+//
+// Model hooks, validation and actor stamping do not run for the copied rows.
+func (r *Repository[T]) InsertFromQuery(ctx context.Context, source SelectSource, columnMapping map[string]string, opts ...UpsertOptions) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if len(columnMapping) == 0 {
+		return 0, &Error{
+			Op:    "insertFromQuery",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("column mapping cannot be empty"),
+		}
+	}
+	if len(opts) > 1 {
+		return 0, &Error{
+			Op:    "insertFromQuery",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("expected at most one UpsertOptions, got %d", len(opts)),
+		}
+	}
+
+	columns := make([]string, 0, len(columnMapping))
+	for column := range columnMapping {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	if err := r.validateColumns("insertFromQuery", columns); err != nil {
+		return 0, err
+	}
+
+	onConflict := ""
+	if len(opts) == 1 {
+		if len(opts[0].ConflictColumns) == 0 {
+			return 0, &Error{
+				Op:    "insertFromQuery",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("conflict columns must be specified"),
+			}
+		}
+		if err := r.validateUpsertOptions("insertFromQuery", opts[0]); err != nil {
+			return 0, err
+		}
+		onConflict = r.onConflictClause(columns, opts[0])
+	}
+
+	expressions := make([]string, len(columns))
+	for i, column := range columns {
+		expressions[i] = columnMapping[column]
+	}
+	selectQuery, err := source.selectExpressions(expressions)
+	if err != nil {
+		return 0, err
+	}
+
+	query := squirrel.Insert(r.quotedTable()).
+		PlaceholderFormat(squirrel.Dollar).
+		Columns(quoteIdents(columns)...).
+		Select(selectQuery)
+
+	var rowsAffected int64
+	err = r.executeQueryMiddleware(OpInsertFromQuery, ctx, nil, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "insertFromQuery",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+		sqlQuery += onConflict
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		result, err := r.db.ExecContext(ctx, sqlQuery, args...)
+		if err != nil {
+			return parsePostgreSQLError(err, "insertFromQuery", r.metadata.TableName)
+		}
+
+		rowsAffected, err = result.RowsAffected()
+		if err != nil {
+			return &Error{
+				Op:    "insertFromQuery",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to get rows affected: %w", err),
+			}
+		}
+		middlewareCtx.RowsAffected = rowsAffected
+		return nil
+	})
+
+	return rowsAffected, err
+}
@@ -0,0 +1,66 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertFromQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	users, err := NewRepository[TestUser](sqlxDB, createTestUserMetadata())
+	require.NoError(t, err)
+
+	archiveMetadata := createTestUserMetadata()
+	archiveMetadata.TableName = "archived_users"
+	archive, err := NewRepository[TestUser](sqlxDB, archiveMetadata)
+	require.NoError(t, err)
+
+	activeCol := Column[bool]{Name: "is_active", Table: "users"}
+	mapping := map[string]string{"email": "users.email", "name": "users.name", "created_at": "NOW()"}
+
+	t.Run("copies the selected rows", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO archived_users (created_at,email,name) SELECT NOW(), users.email, users.name FROM users WHERE (users.is_active = $1) LIMIT 50`)).
+			WithArgs(false).
+			WillReturnResult(sqlmock.NewResult(0, 12))
+
+		n, err := archive.InsertFromQuery(context.Background(),
+			users.Query(context.Background()).Where(activeCol.Eq(false)).Limit(50),
+			mapping)
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("adds the conflict clause", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO archived_users (created_at,email,name) SELECT NOW(), users.email, users.name FROM users WHERE (users.is_active = $1) ON CONFLICT (email) DO UPDATE SET created_at = EXCLUDED.created_at, name = EXCLUDED.name`)).
+			WithArgs(false).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		n, err := archive.InsertFromQuery(context.Background(),
+			users.Query(context.Background()).Where(activeCol.Eq(false)),
+			mapping, UpsertOptions{ConflictColumns: []string{"email"}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects unknown target columns", func(t *testing.T) {
+		_, err := archive.InsertFromQuery(context.Background(), users.Query(context.Background()), map[string]string{"nickname": "users.name"})
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+
+	t.Run("rejects an empty mapping", func(t *testing.T) {
+		_, err := archive.InsertFromQuery(context.Background(), users.Query(context.Background()), nil)
+		assert.ErrorContains(t, err, "column mapping cannot be empty")
+	})
+}
@@ -0,0 +1,149 @@
+package orm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is a time.Duration stored in an INTERVAL column. Generated
+// metadata converts time.Duration fields to it when reading and writing, so
+// models keep their time.Duration fields.
+//
+// PostgreSQL keeps intervals to the microsecond, so finer durations are
+// truncated. Months and years, which have no fixed length, are read as 30 and
+// 365.25 days, as EXTRACT(EPOCH FROM ...) counts them.
+type Interval time.Duration
+
+const (
+	intervalDay   = 24 * time.Hour
+	intervalMonth = 30 * intervalDay
+	intervalYear  = intervalDay * 36525 / 100
+)
+
+// Value returns the interval in microseconds, a form PostgreSQL reads
+// whatever its IntervalStyle
+func (i Interval) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d microseconds", time.Duration(i).Microseconds()), nil
+}
+
+// Scan reads an interval in PostgreSQL's default output style, such as
+// "1 day 02:30:00", or a number of nanoseconds from a BIGINT column
+func (i *Interval) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*i = 0
+	case int64:
+		*i = Interval(v)
+	case string:
+		return i.parse(v)
+	case []byte:
+		return i.parse(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into Interval", value)
+	}
+	return nil
+}
+
+func (i *Interval) parse(s string) error {
+	d, err := parseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = Interval(d)
+	return nil
+}
+
+// parseInterval parses PostgreSQL's postgres IntervalStyle, e.g.
+// "-1 years 2 mons 3 days -04:05:06.789"
+func parseInterval(s string) (time.Duration, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+
+	var total time.Duration
+	for i := 0; i < len(fields); i++ {
+		if strings.Contains(fields[i], ":") {
+			clock, err := parseIntervalClock(fields[i])
+			if err != nil {
+				return 0, fmt.Errorf("invalid interval %q: %w", s, err)
+			}
+			total += clock
+			continue
+		}
+
+		n, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil || i+1 == len(fields) {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		i++
+
+		switch strings.TrimSuffix(fields[i], "s") {
+		case "year":
+			total += time.Duration(n) * intervalYear
+		case "mon":
+			total += time.Duration(n) * intervalMonth
+		case "day":
+			total += time.Duration(n) * intervalDay
+		default:
+			return 0, fmt.Errorf("invalid interval %q: unknown unit %q", s, fields[i])
+		}
+	}
+	return total, nil
+}
+
+// parseIntervalClock parses the [-]HH:MM:SS[.ffffff] part of an interval.
+// Hours may exceed 23.
+func parseIntervalClock(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+	}
+	s = strings.TrimLeft(s, "+-")
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := time.ParseDuration(parts[2] + "s")
+	if err != nil {
+		return 0, err
+	}
+	return sign * (time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + seconds), nil
+}
+
+// IntervalPointer returns a scanner reading a nullable INTERVAL column into
+// a *time.Duration field, leaving it nil for NULL
+func IntervalPointer(dest **time.Duration) sql.Scanner {
+	return nullInterval{dest: dest}
+}
+
+type nullInterval struct {
+	dest **time.Duration
+}
+
+func (n nullInterval) Scan(value interface{}) error {
+	if value == nil {
+		*n.dest = nil
+		return nil
+	}
+	var i Interval
+	if err := i.Scan(value); err != nil {
+		return err
+	}
+	d := time.Duration(i)
+	*n.dest = &d
+	return nil
+}
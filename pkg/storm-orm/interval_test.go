@@ -0,0 +1,125 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterval(t *testing.T) {
+	value, err := Interval(90*time.Minute + 1500*time.Nanosecond).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "5400000001 microseconds", value)
+
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"00:00:00", 0},
+		{"01:30:00", 90 * time.Minute},
+		{"-00:00:01.5", -1500 * time.Millisecond},
+		{"36:00:00", 36 * time.Hour},
+		{"1 day 02:00:00", 26 * time.Hour},
+		{"-1 days +02:00:00", -22 * time.Hour},
+		{"3 days", 72 * time.Hour},
+		{"1 mon 1 day", 31 * 24 * time.Hour},
+		{"1 year", 8766 * time.Hour},
+	}
+	for _, tt := range tests {
+		var scanned Interval
+		require.NoError(t, scanned.Scan([]byte(tt.input)), tt.input)
+		assert.Equal(t, tt.expected, time.Duration(scanned), tt.input)
+	}
+
+	var scanned Interval
+	require.NoError(t, scanned.Scan(int64(time.Second)))
+	assert.Equal(t, time.Second, time.Duration(scanned))
+	require.NoError(t, scanned.Scan(nil))
+	assert.Zero(t, scanned)
+	assert.Error(t, scanned.Scan("P1D"))
+	assert.Error(t, scanned.Scan("2 fortnights"))
+	assert.Error(t, scanned.Scan(1.5))
+
+	t.Run("IntervalPointer", func(t *testing.T) {
+		var timeout *time.Duration
+		require.NoError(t, IntervalPointer(&timeout).Scan("00:00:30"))
+		require.NotNil(t, timeout)
+		assert.Equal(t, 30*time.Second, *timeout)
+		require.NoError(t, IntervalPointer(&timeout).Scan(nil))
+		assert.Nil(t, timeout)
+	})
+}
+
+func TestIntervalColumn(t *testing.T) {
+	timeout := IntervalColumn{Column: Column[time.Duration]{Name: "timeout", Table: "jobs"}}
+
+	sql, args, err := timeout.Gt(time.Minute).ToSqlizer().ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "jobs.timeout > ?", sql)
+	assert.Equal(t, []interface{}{"60000000 microseconds"}, args)
+
+	sql, args, err = timeout.Between(time.Second, time.Minute).ToSqlizer().ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(jobs.timeout >= ? AND jobs.timeout <= ?)", sql)
+	assert.Len(t, args, 2)
+
+	add := timeout.Add(time.Second)
+	assert.Equal(t, "timeout = timeout + ?::interval", add.Expression())
+	assert.Equal(t, Interval(time.Second), add.Value())
+	assert.Equal(t, "timeout = timeout - ?::interval", timeout.Subtract(time.Second).Expression())
+	assert.Equal(t, Interval(time.Minute), timeout.Set(time.Minute).Value())
+}
+
+type intervalJob struct {
+	ID      int           `db:"id"`
+	Timeout time.Duration `db:"timeout"`
+}
+
+func TestIntervalRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the metadata the generator emits for a time.Duration INTERVAL column
+	metadata := &ModelMetadata{
+		TableName:  "jobs",
+		StructName: "intervalJob",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {FieldName: "ID", DBName: "id", IsPrimaryKey: true, IsAutoGenerated: true,
+				GetValue: func(model interface{}) interface{} { return model.(intervalJob).ID }},
+			"Timeout": {FieldName: "Timeout", DBName: "timeout", GoType: "time.Duration",
+				GetValue: func(model interface{}) interface{} { return Interval(model.(intervalJob).Timeout) }},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "Timeout": "timeout"},
+		ReverseMap:  map[string]string{"id": "ID", "timeout": "Timeout"},
+		ColumnOrder: []string{"ID", "Timeout"},
+		ScanColumns: []string{"id", "timeout"},
+		ScanFields: func(model interface{}) []interface{} {
+			m := model.(*intervalJob)
+			return []interface{}{&m.ID, (*Interval)(&m.Timeout)}
+		},
+		PrimaryKeys: []string{"id"},
+	}
+	repo, err := NewRepository[intervalJob](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`INSERT INTO jobs \(timeout\) VALUES \(\$1\) RETURNING id`).
+		WithArgs("90000000 microseconds").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	created, err := repo.Create(context.Background(), &intervalJob{Timeout: 90 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.ID)
+
+	mock.ExpectQuery(`SELECT id, timeout FROM jobs`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timeout"}).AddRow(1, "00:01:30"))
+	jobs, err := repo.Query(context.Background()).Find()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 90*time.Second, jobs[0].Timeout)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -23,6 +23,14 @@ type join struct {
 	Args      []interface{}
 }
 
+// source returns the joined table with its alias, if any
+func (j join) source() string {
+	if j.Alias != "" {
+		return j.Table + " " + j.Alias
+	}
+	return j.Table
+}
+
 // joinBuilder provides a fluent interface for building joins (internal use only)
 type joinBuilder struct {
 	joins []join
@@ -85,6 +93,12 @@ func (jb *joinBuilder) Build() []join {
 // Note: JoinQuery has been merged into the main Query type
 // Join functionality is now available directly on Query[T]
 
+// JoinRelationship joins the table of a declared relationship with joinType,
+// deriving the ON condition from the relationship's keys. has_many_through
+// relationships join the through table and then the target. Joins through
+// has_many and has_many_through select each row once: Find and Count match
+// the rows by primary key against a subquery holding the joins and the
+// conditions, so the joined tables can be filtered on but not ordered by.
 func (q *Query[T]) JoinRelationship(relationshipName string, joinType JoinType) *Query[T] {
 	repo := q.repo
 
@@ -94,29 +108,49 @@ func (q *Query[T]) JoinRelationship(relationshipName string, joinType JoinType)
 		return q
 	}
 
+	target := rel.TargetTable
+	if target == "" {
+		target = rel.Target
+	}
+	sourceKey, targetKey := rel.SourceKey, rel.TargetKey
+	if sourceKey == "" {
+		sourceKey = "id"
+	}
+	if targetKey == "" {
+		targetKey = "id"
+	}
+
 	switch rel.Type {
 	case "belongs_to":
-		condition := fmt.Sprintf("%s.%s = %s.%s",
-			repo.metadata.TableName, rel.ForeignKey,
-			rel.Target, rel.TargetKey)
-		q.Join(InnerJoin, rel.Target, condition)
+		condition := fmt.Sprintf("%s = %s",
+			quoteIdent(q.tableRef()+"."+rel.ForeignKey),
+			quoteIdent(target+"."+targetKey))
+		if rel.isPolymorphic() {
+			condition += polymorphicJoinCondition(rel, q.tableRef())
+		}
+		q.Join(joinType, quoteIdent(target), condition)
 
 	case "has_one", "has_many":
-		condition := fmt.Sprintf("%s.%s = %s.%s",
-			repo.metadata.TableName, rel.SourceKey,
-			rel.Target, rel.ForeignKey)
-		q.Join(InnerJoin, rel.Target, condition)
+		condition := fmt.Sprintf("%s = %s",
+			quoteIdent(q.tableRef()+"."+sourceKey),
+			quoteIdent(target+"."+rel.ForeignKey))
+		if rel.isPolymorphic() {
+			condition += polymorphicJoinCondition(rel, target)
+		}
+		q.Join(joinType, quoteIdent(target), condition)
+		q.joinsMany = q.joinsMany || rel.Type == "has_many"
 
 	case "has_many_through":
-		condition1 := fmt.Sprintf("%s.%s = %s.%s",
-			repo.metadata.TableName, rel.SourceKey,
-			rel.Through, rel.ThroughFK)
-		q.Join(InnerJoin, rel.Through, condition1)
+		condition1 := fmt.Sprintf("%s = %s",
+			quoteIdent(q.tableRef()+"."+sourceKey),
+			quoteIdent(rel.Through+"."+rel.ThroughFK))
+		q.Join(joinType, quoteIdent(rel.Through), condition1)
 
-		condition2 := fmt.Sprintf("%s.%s = %s.%s",
-			rel.Through, rel.ThroughTK,
-			rel.Target, rel.TargetKey)
-		q.Join(InnerJoin, rel.Target, condition2)
+		condition2 := fmt.Sprintf("%s = %s",
+			quoteIdent(rel.Through+"."+rel.ThroughTK),
+			quoteIdent(target+"."+targetKey))
+		q.Join(joinType, quoteIdent(target), condition2)
+		q.joinsMany = true
 
 	default:
 		q.err = fmt.Errorf("unsupported relationship type for join: %s", rel.Type)
@@ -134,5 +168,6 @@ func (q *Query[T]) RawJoin(joinClause string, args ...interface{}) *Query[T] {
 	}
 
 	q.joins = append(q.joins, join)
+	q.qualifyColumns()
 	return q
 }
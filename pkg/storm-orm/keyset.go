@@ -0,0 +1,55 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// After restricts the query to rows sorting after values in the order of
+// columns, and orders it by those columns ascending. The columns are compared
+// as a row, so a tie on a leading column falls through to the next one; they
+// should be not null and end in a unique key for pages to neither skip nor
+// repeat rows. Generated repositories wrap it per index, e.g. AfterCreatedAtID.
+func (q *Query[T]) After(columns []string, values ...interface{}) *Query[T] {
+	return q.keyset("after", ">", "", columns, values)
+}
+
+// Before restricts the query to rows sorting before values in the order of
+// columns, and orders it by those columns descending, the nearest rows first
+func (q *Query[T]) Before(columns []string, values ...interface{}) *Query[T] {
+	return q.keyset("before", "<", " DESC", columns, values)
+}
+
+func (q *Query[T]) keyset(op, comparison, direction string, columns []string, values []interface{}) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	if len(columns) == 0 || len(columns) != len(values) {
+		q.err = &Error{
+			Op:    op,
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("keyset needs one value per column, got %d columns and %d values", len(columns), len(values)),
+		}
+		return q
+	}
+	if err := q.repo.validateColumns(op, columns); err != nil {
+		q.err = err
+		return q
+	}
+
+	qualified := make([]string, len(columns))
+	for i, column := range columns {
+		qualified[i] = quoteIdent(q.tableRef()) + "." + quoteIdent(column)
+		q.orderBy = append(q.orderBy, qualified[i]+direction)
+	}
+
+	left, right := qualified[0], "?"
+	if len(columns) > 1 {
+		left = "(" + strings.Join(qualified, ", ") + ")"
+		right = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	}
+	q.whereClause = append(q.whereClause, squirrel.Expr(left+" "+comparison+" "+right, values...))
+	return q
+}
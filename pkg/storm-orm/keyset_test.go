@@ -0,0 +1,56 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryKeyset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("after compares the columns as a row", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE ((users.created_at, users.id) > ($1, $2)) ORDER BY users.created_at, users.id LIMIT 20")).
+			WithArgs(createdAt, 42).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.Query(context.Background()).
+			After([]string{"created_at", "id"}, createdAt, 42).
+			Limit(20).
+			Find()
+		require.NoError(t, err)
+	})
+
+	t.Run("before orders descending", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE (users.id < $1) ORDER BY users.id DESC")).
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.Query(context.Background()).Before([]string{"id"}, 42).Find()
+		require.NoError(t, err)
+	})
+
+	t.Run("values must match the columns", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).After([]string{"created_at", "id"}, createdAt).Find()
+		assert.Error(t, err)
+
+		_, err = repo.Query(context.Background()).After([]string{"missing"}, 1).Find()
+		assert.True(t, errors.Is(err, ErrUnknownColumn))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
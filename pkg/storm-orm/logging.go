@@ -0,0 +1,321 @@
+package orm
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRedactionMask replaces redacted values when RedactionRules.Mask is empty
+const DefaultRedactionMask = "[REDACTED]"
+
+// RedactionRules picks the query arguments masked before they are logged.
+// Values of EncryptedString and DeterministicString are always masked.
+type RedactionRules struct {
+	// Columns whose values are masked, either a bare column name matching
+	// any table or table.column
+	Columns []string
+	// Mask replaces each redacted value; DefaultRedactionMask when empty
+	Mask string
+}
+
+// SensitiveColumns returns the table.column names of the columns marked
+// sensitive, masked or encrypted in metadata
+func SensitiveColumns(metadata ...*ModelMetadata) []string {
+	var columns []string
+	for _, m := range metadata {
+		if m == nil {
+			continue
+		}
+		for _, col := range m.OrderedColumns() {
+			if col.IsSensitive {
+				columns = append(columns, m.TableName+"."+col.DBName)
+			}
+		}
+	}
+	return columns
+}
+
+// Redact returns a copy of args with the values bound to redacted columns
+// replaced by the mask. Arguments are matched to columns through the column
+// list of an INSERT and comparisons such as col = $1 or col IN ($1, $2);
+// arguments used any other way are left as they are.
+func (r RedactionRules) Redact(query string, args []interface{}) []interface{} {
+	if len(args) == 0 {
+		return args
+	}
+
+	mask := r.Mask
+	if mask == "" {
+		mask = DefaultRedactionMask
+	}
+
+	redacted := make([]interface{}, len(args))
+	copy(redacted, args)
+
+	var columns map[int]string
+	if len(r.Columns) > 0 {
+		columns = placeholderColumns(query)
+	}
+
+	for i, arg := range args {
+		if isEncryptedValue(arg) || r.matches(columns[i+1]) {
+			redacted[i] = mask
+		}
+	}
+
+	if len(args) == 1 && len(r.Columns) > 0 && !strings.Contains(query, "$") {
+		redacted[0] = r.redactNamed(query, args[0], mask)
+	}
+	return redacted
+}
+
+// matches reports whether column, a table.column or bare column name, is
+// one of the redacted columns
+func (r RedactionRules) matches(column string) bool {
+	if column == "" {
+		return false
+	}
+	table, name, qualified := strings.Cut(column, ".")
+	if !qualified {
+		table, name = "", column
+	}
+	for _, rule := range r.Columns {
+		ruleTable, ruleName, ruleQualified := strings.Cut(rule, ".")
+		if !ruleQualified {
+			if strings.EqualFold(rule, name) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(ruleName, name) && (table == "" || strings.EqualFold(ruleTable, table)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactNamed masks the argument of a named query such as those run by
+// NamedExecContext. Map keys are masked one by one; any other argument is
+// masked whole when the query names a redacted column.
+func (r RedactionRules) redactNamed(query string, arg interface{}, mask string) interface{} {
+	if values, ok := arg.(map[string]interface{}); ok {
+		redacted := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			if r.matches(key) || isEncryptedValue(value) {
+				value = mask
+			}
+			redacted[key] = value
+		}
+		return redacted
+	}
+	for _, match := range namedParam.FindAllStringSubmatch(query, -1) {
+		if r.matches(match[1]) {
+			return mask
+		}
+	}
+	return arg
+}
+
+func isEncryptedValue(arg interface{}) bool {
+	switch arg.(type) {
+	case EncryptedString, *EncryptedString, DeterministicString, *DeterministicString:
+		return true
+	}
+	return false
+}
+
+const sqlIdent = `(?:"[^"]+"|[A-Za-z_][A-Za-z0-9_]*)`
+
+var (
+	mainTable     = regexp.MustCompile(`(?i)\b(?:INSERT\s+INTO|UPDATE|FROM)\s+(` + sqlIdent + `(?:\.` + sqlIdent + `)?)`)
+	insertColumns = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES\s*`)
+	comparedParam = regexp.MustCompile(`(?i)(?:(` + sqlIdent + `)\.)?(` + sqlIdent + `)\s*(?:=|<>|!=|<=|>=|<|>|(?:NOT\s+)?I?LIKE|IS\s+(?:NOT\s+)?DISTINCT\s+FROM)\s*(?:(?:ANY|ALL)\s*\(\s*)?\$(\d+)`)
+	inListParams  = regexp.MustCompile(`(?i)(?:(` + sqlIdent + `)\.)?(` + sqlIdent + `)\s+(?:NOT\s+)?IN\s*\(([^)]*)\)`)
+	placeholder   = regexp.MustCompile(`\$(\d+)`)
+	namedParam    = regexp.MustCompile(`:(` + sqlIdent + `)`)
+)
+
+// placeholderColumns maps the $n placeholders of query to the table.column
+// they are bound to, where that can be told from the text of the query
+func placeholderColumns(query string) map[int]string {
+	columns := make(map[int]string)
+
+	table := ""
+	if match := mainTable.FindStringSubmatch(query); match != nil {
+		parts := strings.Split(match[1], ".")
+		table = unquoteIdent(parts[len(parts)-1])
+	}
+	qualify := func(qualifier, column string) string {
+		if qualifier == "" {
+			qualifier = table
+		}
+		if qualifier == "" {
+			return unquoteIdent(column)
+		}
+		return unquoteIdent(qualifier) + "." + unquoteIdent(column)
+	}
+
+	if loc := insertColumns.FindStringSubmatchIndex(query); loc != nil {
+		var names []string
+		for _, name := range strings.Split(query[loc[2]:loc[3]], ",") {
+			names = append(names, qualify("", strings.TrimSpace(name)))
+		}
+		mapValueTuples(query[loc[1]:], names, columns)
+	}
+
+	for _, match := range comparedParam.FindAllStringSubmatch(query, -1) {
+		if n, err := strconv.Atoi(match[3]); err == nil {
+			columns[n] = qualify(match[1], match[2])
+		}
+	}
+	for _, match := range inListParams.FindAllStringSubmatch(query, -1) {
+		for _, param := range placeholder.FindAllStringSubmatch(match[3], -1) {
+			if n, err := strconv.Atoi(param[1]); err == nil {
+				columns[n] = qualify(match[1], match[2])
+			}
+		}
+	}
+	return columns
+}
+
+// mapValueTuples maps the placeholders in the VALUES tuples at the start of
+// values to the column in the same position
+func mapValueTuples(values string, names []string, columns map[int]string) {
+	depth, position := 0, 0
+	for i := 0; i < len(values); i++ {
+		switch c := values[i]; c {
+		case '(':
+			depth++
+			if depth == 1 {
+				position = 0
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return
+			}
+		case ',':
+			if depth == 1 {
+				position++
+			}
+		case '$':
+			if depth < 1 || position >= len(names) {
+				continue
+			}
+			end := i + 1
+			for end < len(values) && values[end] >= '0' && values[end] <= '9' {
+				end++
+			}
+			if n, err := strconv.Atoi(values[i+1 : end]); err == nil {
+				columns[n] = names[position]
+			}
+		case ' ', '\t', '\n', '\r':
+		default:
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+func unquoteIdent(name string) string {
+	return strings.Trim(name, `"`)
+}
+
+// RedactingLogger masks sensitive arguments before passing queries on to
+// another QueryLogger
+type RedactingLogger struct {
+	next  QueryLogger
+	rules RedactionRules
+}
+
+// NewRedactingLogger returns a QueryLogger that applies rules to the
+// arguments of every query before logging it with next
+func NewRedactingLogger(next QueryLogger, rules RedactionRules) *RedactingLogger {
+	return &RedactingLogger{next: next, rules: rules}
+}
+
+func (l *RedactingLogger) LogQuery(query string, args []interface{}, duration time.Duration, err error) {
+	l.next.LogQuery(query, l.rules.Redact(query, args), duration, err)
+}
+
+// RedactArgs lets Debug redact arguments before inlining them into the query
+func (l *RedactingLogger) RedactArgs(query string, args []interface{}) []interface{} {
+	return l.rules.Redact(query, args)
+}
+
+// SampledLogger passes on a fraction of successful queries to another
+// QueryLogger. Failed queries are always passed on.
+type SampledLogger struct {
+	next QueryLogger
+	rate float64
+}
+
+// NewSampledLogger returns a QueryLogger logging each successful query with
+// probability rate, between 0 and 1
+func NewSampledLogger(next QueryLogger, rate float64) *SampledLogger {
+	return &SampledLogger{next: next, rate: min(max(rate, 0), 1)}
+}
+
+func (l *SampledLogger) LogQuery(query string, args []interface{}, duration time.Duration, err error) {
+	if err == nil && (l.rate == 0 || (l.rate < 1 && rand.Float64() >= l.rate)) {
+		return
+	}
+	l.next.LogQuery(query, args, duration, err)
+}
+
+// RedactArgs forwards to the wrapped logger when it redacts arguments
+func (l *SampledLogger) RedactArgs(query string, args []interface{}) []interface{} {
+	return redactArgs(l.next, query, args)
+}
+
+// SlogLogger writes queries to a slog.Logger as structured records with sql,
+// args, duration and, for failed queries, error attributes
+type SlogLogger struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogLogger returns a QueryLogger writing successful queries at level
+// and failed ones at slog.LevelError
+func NewSlogLogger(logger *slog.Logger, level slog.Level) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger, level: level}
+}
+
+func (l *SlogLogger) LogQuery(query string, args []interface{}, duration time.Duration, err error) {
+	attrs := []slog.Attr{
+		slog.String("sql", query),
+		slog.Duration("duration", duration),
+	}
+	if len(args) > 0 {
+		attrs = append(attrs, slog.Any("args", args))
+	}
+
+	level := l.level
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.logger.LogAttrs(context.Background(), level, "query", attrs...)
+}
+
+// argRedactor is implemented by loggers that mask arguments, so Debug can
+// redact them before inlining them into the logged statement
+type argRedactor interface {
+	RedactArgs(query string, args []interface{}) []interface{}
+}
+
+func redactArgs(logger QueryLogger, query string, args []interface{}) []interface{} {
+	if redactor, ok := logger.(argRedactor); ok {
+		return redactor.RedactArgs(query, args)
+	}
+	return args
+}
@@ -0,0 +1,166 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type argsLogger struct {
+	args [][]interface{}
+}
+
+func (l *argsLogger) LogQuery(query string, args []interface{}, duration time.Duration, err error) {
+	l.args = append(l.args, args)
+}
+
+func TestRedactionRules_Redact(t *testing.T) {
+	rules := RedactionRules{Columns: []string{"users.email", "ssn"}}
+
+	tests := []struct {
+		name     string
+		query    string
+		args     []interface{}
+		expected []interface{}
+	}{
+		{
+			name:     "insert values",
+			query:    `INSERT INTO "users" ("name", "email") VALUES ($1, $2), ($3, $4) RETURNING id`,
+			args:     []interface{}{"Ada", "ada@example.com", "Bob", "bob@example.com"},
+			expected: []interface{}{"Ada", DefaultRedactionMask, "Bob", DefaultRedactionMask},
+		},
+		{
+			name:     "update and where comparisons",
+			query:    `UPDATE users SET name = $1, "email" = $2 WHERE users.id = $3 AND ssn <> $4`,
+			args:     []interface{}{"Ada", "ada@example.com", 7, "123-45-6789"},
+			expected: []interface{}{"Ada", DefaultRedactionMask, 7, DefaultRedactionMask},
+		},
+		{
+			name:     "in list",
+			query:    `SELECT id FROM users WHERE email IN ($1,$2) AND id > $3`,
+			args:     []interface{}{"a@example.com", "b@example.com", 1},
+			expected: []interface{}{DefaultRedactionMask, DefaultRedactionMask, 1},
+		},
+		{
+			name:     "qualified rule ignores other tables",
+			query:    `SELECT id FROM contacts WHERE email = $1`,
+			args:     []interface{}{"a@example.com"},
+			expected: []interface{}{"a@example.com"},
+		},
+		{
+			name:     "encrypted values are always masked",
+			query:    `UPDATE accounts SET notes = $1 WHERE id = $2`,
+			args:     []interface{}{EncryptedString("secret"), 3},
+			expected: []interface{}{DefaultRedactionMask, 3},
+		},
+		{
+			name:     "named map arguments",
+			query:    `INSERT INTO users (email) VALUES (:email)`,
+			args:     []interface{}{map[string]interface{}{"email": "a@example.com", "name": "Ada"}},
+			expected: []interface{}{map[string]interface{}{"email": DefaultRedactionMask, "name": "Ada"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append([]interface{}(nil), tt.args...)
+			assert.Equal(t, tt.expected, rules.Redact(tt.query, tt.args))
+			assert.Equal(t, original, tt.args, "arguments must not be modified in place")
+		})
+	}
+
+	t.Run("custom mask", func(t *testing.T) {
+		rules := RedactionRules{Columns: []string{"email"}, Mask: "***"}
+		assert.Equal(t, []interface{}{"***"}, rules.Redact(`SELECT * FROM contacts WHERE email = $1`, []interface{}{"a@example.com"}))
+	})
+}
+
+func TestSensitiveColumns(t *testing.T) {
+	metadata := createTestUserMetadata()
+	metadata.Columns["Email"].IsSensitive = true
+
+	assert.Equal(t, []string{"users.email"}, SensitiveColumns(metadata, nil))
+}
+
+func TestRedactingLogger(t *testing.T) {
+	next := &argsLogger{}
+	logger := NewRedactingLogger(next, RedactionRules{Columns: []string{"email"}})
+
+	logger.LogQuery(`SELECT * FROM users WHERE email = $1`, []interface{}{"a@example.com"}, time.Millisecond, nil)
+	require.Len(t, next.args, 1)
+	assert.Equal(t, []interface{}{DefaultRedactionMask}, next.args[0])
+
+	t.Run("debug inlines redacted arguments", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET email = $1 WHERE (users.id = $2)`)).
+			WithArgs("new@example.com", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		recorder := &recordingLogger{}
+		debug := NewSampledLogger(NewRedactingLogger(recorder, RedactionRules{Columns: []string{"users.email"}}), 1)
+		emailCol := Column[string]{Name: "email", Table: "users"}
+		idCol := Column[int]{Name: "id", Table: "users"}
+
+		_, err = repo.Query(context.Background()).Debug(debug).Where(idCol.Eq(1)).Update(emailCol.Set("new@example.com"))
+		require.NoError(t, err)
+		require.Len(t, recorder.queries, 1)
+		assert.Equal(t, `UPDATE users SET email = '[REDACTED]' WHERE (users.id = 1)`, recorder.queries[0])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSampledLogger(t *testing.T) {
+	next := &argsLogger{}
+
+	none := NewSampledLogger(next, 0)
+	none.LogQuery("SELECT 1", nil, time.Millisecond, nil)
+	assert.Empty(t, next.args)
+
+	none.LogQuery("SELECT 1", nil, time.Millisecond, errors.New("boom"))
+	assert.Len(t, next.args, 1, "failed queries are always logged")
+
+	all := NewSampledLogger(next, 2)
+	all.LogQuery("SELECT 1", nil, time.Millisecond, nil)
+	assert.Len(t, next.args, 2)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)), slog.LevelInfo)
+
+	logger.LogQuery("SELECT * FROM users WHERE id = $1", []interface{}{1}, 2*time.Millisecond, nil)
+	logger.LogQuery("SELECT 1", nil, time.Millisecond, errors.New("boom"))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var ok, failed map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &ok))
+	require.NoError(t, json.Unmarshal(lines[1], &failed))
+
+	assert.Equal(t, "INFO", ok["level"])
+	assert.Equal(t, "query", ok["msg"])
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1", ok["sql"])
+	assert.Equal(t, []interface{}{float64(1)}, ok["args"])
+	assert.NotContains(t, ok, "error")
+
+	assert.Equal(t, "ERROR", failed["level"])
+	assert.Equal(t, "boom", failed["error"])
+	assert.NotContains(t, failed, "args")
+}
@@ -2,6 +2,12 @@ package orm
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/squirrel"
 )
 
 // ModelMetadata contains all the metadata needed for ORM operations
@@ -16,13 +22,178 @@ type ModelMetadata struct {
 	ColumnMap  map[string]string          // Go field -> DB column
 	ReverseMap map[string]string          // DB column -> Go field
 
+	// Go field names in struct declaration order; fixes the column order of
+	// generated SQL
+	ColumnOrder []string
+
 	// Primary keys only - other column lists are determined dynamically
 	PrimaryKeys []string // DB column names
 
+	// Column whose value picks the shard holding a row, see ShardedRepository
+	ShardKey string
+
+	// Read models map a view, or the SELECT statement in Source selected from
+	// as TableName, and cannot be written
+	ReadOnly bool
+	Source   string
+
+	// Generated function - zero reflection. Returns pointers to the fields of
+	// model, a pointer to the struct, in ScanColumns order for rows.Scan.
+	// Reads fall back to sqlx when it is nil or the result columns differ.
+	ScanColumns []string // DB column names
+	ScanFields  func(model interface{}) []interface{}
+
 	// Relationships
 	Relationships map[string]*RelationshipMetadata
 }
 
+// derivedMetadata holds lookups and SQL fragments computed once from a
+// ModelMetadata, so hot paths do not rebuild them on every operation
+type derivedMetadata struct {
+	columns  []*ColumnMetadata
+	byDBName map[string]*ColumnMetadata
+
+	quotedTable   string
+	from          string                 // Table as written in FROM, see relation
+	selectColumns []string               // Quoted select list, computed columns expanded
+	selectBuilder squirrel.SelectBuilder // SELECT <selectColumns> FROM <from>
+	insertBuilder squirrel.InsertBuilder // INSERT INTO <quotedTable>
+	autoGenerated []string               // Columns read back after an insert
+	returning     string                 // RETURNING clause for autoGenerated, or ""
+	scanIndex     map[string]int         // Position of each of ScanColumns in ScanFields
+}
+
+// metadataCache maps each *ModelMetadata to its derivedMetadata. Metadata is
+// treated as read-only once it has been used.
+var metadataCache sync.Map
+
+// PreloadMetadata validates the given metadata and computes the lookups
+// repositories derive from it. Calling it once at server startup, before
+// requests are served, surfaces broken metadata early and keeps that work
+// off the first queries. Metadata must not be changed after it is loaded.
+func PreloadMetadata(metadata ...*ModelMetadata) error {
+	for _, m := range metadata {
+		if err := m.validate(); err != nil {
+			return err
+		}
+		m.derived()
+	}
+	return nil
+}
+
+// validate checks the metadata is usable by a repository
+func (m *ModelMetadata) validate() error {
+	if m == nil {
+		return &Error{Op: "preload", Err: fmt.Errorf("metadata cannot be nil")}
+	}
+	if len(m.PrimaryKeys) == 0 {
+		return &Error{Op: "preload", Table: m.TableName, Err: ErrNoPrimaryKey}
+	}
+	for _, pk := range m.PrimaryKeys {
+		if _, ok := m.ReverseMap[pk]; !ok {
+			return &Error{Op: "preload", Table: m.TableName, Column: pk, Err: ErrUnknownColumn}
+		}
+	}
+	for _, name := range m.ColumnOrder {
+		if _, ok := m.Columns[name]; !ok {
+			return &Error{Op: "preload", Table: m.TableName, Column: name, Err: ErrUnknownColumn}
+		}
+	}
+	return nil
+}
+
+// derived returns the cached lookups for m, computing them on first use. It is
+// safe for concurrent use.
+func (m *ModelMetadata) derived() *derivedMetadata {
+	if cached, ok := metadataCache.Load(m); ok {
+		return cached.(*derivedMetadata)
+	}
+
+	d := &derivedMetadata{
+		columns:  m.orderColumns(),
+		byDBName: make(map[string]*ColumnMetadata, len(m.Columns)),
+	}
+	for _, col := range d.columns {
+		if _, exists := d.byDBName[col.DBName]; !exists {
+			d.byDBName[col.DBName] = col
+		}
+	}
+
+	d.quotedTable = quoteIdent(m.TableName)
+	d.from = m.relation("")
+	d.selectColumns = make([]string, 0, len(d.columns))
+	for _, col := range d.columns {
+		if col.IsAutoGenerated || col.AutoCreateTime || col.AutoUpdateTime {
+			d.autoGenerated = append(d.autoGenerated, col.DBName)
+		}
+		if col.Computed != "" {
+			d.selectColumns = append(d.selectColumns, fmt.Sprintf("(%s) AS %s", col.Computed, quoteIdent(col.DBName)))
+			continue
+		}
+		d.selectColumns = append(d.selectColumns, quoteIdent(col.DBName))
+	}
+	d.selectBuilder = squirrel.Select(d.selectColumns...).
+		From(d.from).
+		PlaceholderFormat(squirrel.Dollar)
+	d.insertBuilder = squirrel.Insert(d.quotedTable).
+		PlaceholderFormat(squirrel.Dollar)
+	if len(d.autoGenerated) > 0 {
+		d.returning = " RETURNING " + strings.Join(quoteIdents(d.autoGenerated), ", ")
+	}
+	d.scanIndex = make(map[string]int, len(m.ScanColumns))
+	for i, name := range m.ScanColumns {
+		d.scanIndex[name] = i
+	}
+
+	actual, _ := metadataCache.LoadOrStore(m, d)
+	return actual.(*derivedMetadata)
+}
+
+// relation returns the model's table as written in FROM, named alias when one
+// is given. A read model's Source is selected from as a subquery.
+func (m *ModelMetadata) relation(alias string) string {
+	if m.Source != "" {
+		if alias == "" {
+			alias = m.TableName
+		}
+		return "(" + m.Source + ") AS " + quoteIdent(alias)
+	}
+	if alias != "" {
+		return quoteIdent(m.TableName) + " AS " + quoteIdent(alias)
+	}
+	return quoteIdent(m.TableName)
+}
+
+// OrderedColumns returns the columns in ColumnOrder, followed by any columns it
+// does not list ordered by field name, so generated SQL is the same on every run.
+// The slice is shared and must not be modified.
+func (m *ModelMetadata) OrderedColumns() []*ColumnMetadata {
+	return m.derived().columns
+}
+
+func (m *ModelMetadata) orderColumns() []*ColumnMetadata {
+	columns := make([]*ColumnMetadata, 0, len(m.Columns))
+	listed := make(map[string]bool, len(m.ColumnOrder))
+	for _, name := range m.ColumnOrder {
+		if col, ok := m.Columns[name]; ok && !listed[name] {
+			columns = append(columns, col)
+			listed[name] = true
+		}
+	}
+
+	var rest []string
+	for name := range m.Columns {
+		if !listed[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		columns = append(columns, m.Columns[name])
+	}
+	return columns
+}
+
 // ColumnMetadata contains metadata for a single column
 type ColumnMetadata struct {
 	FieldName       string              // Go struct field name
@@ -34,6 +205,11 @@ type ColumnMetadata struct {
 	IsNullable      bool                // Can this be NULL?
 	IsUnique        bool                // Has unique constraint?
 	IsPointer       bool                // Is this a pointer field in Go struct?
+	IsImmutable     bool                // Can only be set on insert?
+	IsSensitive     bool                // Are its values redacted from query logs?
+	AutoCreateTime  bool                // Set to NOW() on insert when left zero?
+	AutoUpdateTime  bool                // Set to NOW() on insert when left zero and on every update?
+	Computed        string              // SQL expression for read-only computed columns
 	Default         string              // Default value
 	Tags            map[string]string   // All dbdef tags
 	Constraints     []string            // Check constraints
@@ -52,6 +228,10 @@ type ForeignKeyMetadata struct {
 	OnUpdate         string
 }
 
+// RelationshipOwnerColumn is the column has_many_through loads alias to the
+// join table's foreign key, telling Load which model each row belongs to
+const RelationshipOwnerColumn = "storm_owner"
+
 // RelationshipMetadata contains relationship information
 type RelationshipMetadata struct {
 	Name        string
@@ -64,8 +244,32 @@ type RelationshipMetadata struct {
 	Through     string // Through model (for has_many_through)
 	ThroughFK   string // Through foreign key
 	ThroughTK   string // Through target key
+	Dependent   string // Action applied to children on delete: destroy, delete, nullify
+
+	// Default scope applied whenever the relationship is loaded or counted
+	Conditions   []string // Raw SQL conditions (e.g. "published = true")
+	OrderBy      string   // ORDER BY expression (e.g. "created_at DESC")
+	TargetScopes []string // Conditions of the target model's default scopes
+
+	// Polymorphic associations store the owner's model name alongside the foreign key
+	PolymorphicType  string // Type column on the polymorphic side (e.g. commentable_type)
+	PolymorphicValue string // Value identifying the owner model in the type column
+
+	// Generated function - zero reflection. Runs query, which selects the
+	// related rows of every model in models, and assigns each model its rows.
+	// has_many fields receive a non-nil slice, empty when nothing matched;
+	// belongs_to and has_one pointers stay nil without a match. An empty query
+	// means no model has a key to match and only those empty values are set.
+	Load func(ctx context.Context, exec DBExecutor, query string, args []interface{}, models []interface{}) error
+
+	// Generated function - stores the row count loaded by IncludeCount on the
+	// model's <Relationship>Count field, for relationships tagged count
+	SetCount func(model interface{}, count int64)
 
-	// Generated function - zero reflection, atomic operation
-	// Scans database results directly into the model's relationship field
-	ScanToModel func(ctx context.Context, exec DBExecutor, query string, args []interface{}, model interface{}) error
+	// Autosave relationships are persisted with the model by Create. Their
+	// generated Related function returns pointers to the records held by the
+	// relationship field, and TargetMetadata describes those records.
+	Autosave       bool
+	TargetMetadata *ModelMetadata
+	Related        func(model interface{}) []interface{}
 }
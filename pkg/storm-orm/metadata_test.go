@@ -1,9 +1,11 @@
 package orm
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestModelMetadata tests the ModelMetadata structure and methods
@@ -131,6 +133,83 @@ func TestModelMetadata(t *testing.T) {
 		assert.Empty(t, metadata.Columns)
 		assert.Empty(t, metadata.PrimaryKeys)
 	})
+
+	t.Run("Ordered columns", func(t *testing.T) {
+		metadata := &ModelMetadata{
+			Columns: map[string]*ColumnMetadata{
+				"ID":    {FieldName: "ID"},
+				"Name":  {FieldName: "Name"},
+				"Email": {FieldName: "Email"},
+				"Age":   {FieldName: "Age"},
+			},
+			ColumnOrder: []string{"Name", "ID", "Missing"},
+		}
+
+		for i := 0; i < 20; i++ {
+			var names []string
+			for _, col := range metadata.OrderedColumns() {
+				names = append(names, col.FieldName)
+			}
+			assert.Equal(t, []string{"Name", "ID", "Age", "Email"}, names)
+		}
+	})
+}
+
+// TestPreloadMetadata tests metadata validation and concurrent access to the derived lookups
+func TestPreloadMetadata(t *testing.T) {
+	t.Run("Valid metadata", func(t *testing.T) {
+		metadata := createTestUserMetadata()
+		require.NoError(t, PreloadMetadata(metadata))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Len(t, metadata.OrderedColumns(), len(metadata.Columns))
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Nil metadata", func(t *testing.T) {
+		assert.Error(t, PreloadMetadata(nil))
+	})
+
+	t.Run("Missing primary key", func(t *testing.T) {
+		metadata := createTestUserMetadata()
+		metadata.PrimaryKeys = nil
+		assert.ErrorIs(t, PreloadMetadata(metadata), ErrNoPrimaryKey)
+	})
+
+	t.Run("Unknown column", func(t *testing.T) {
+		metadata := createTestUserMetadata()
+		metadata.ColumnOrder = []string{"ID", "Nickname"}
+		err := PreloadMetadata(metadata)
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+		assert.ErrorContains(t, err, "Nickname")
+	})
+}
+
+func TestDerivedFragments(t *testing.T) {
+	metadata := createTestUserMetadata()
+	metadata.Columns["Name"].Computed = "upper(name)"
+	require.NoError(t, PreloadMetadata(metadata))
+
+	sql, _, err := metadata.derived().selectBuilder.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT created_at, email, id, is_active, (upper(name)) AS name, updated_at FROM users", sql)
+	assert.Equal(t, " RETURNING created_at, id, updated_at", returningClause(metadata))
+
+	t.Run("column access does not allocate", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = metadata.OrderedColumns()
+			_ = metadata.derived().byDBName["email"]
+			_ = metadata.derived().selectColumns
+			_ = returningClause(metadata)
+		})
+		assert.Zero(t, allocs)
+	})
 }
 
 // TestColumnMetadata tests the ColumnMetadata structure
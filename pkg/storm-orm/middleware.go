@@ -9,27 +9,48 @@ import (
 type OperationType string
 
 const (
-	OpCreate     OperationType = "create"
-	OpCreateMany OperationType = "create_many"
-	OpUpdate     OperationType = "update"
-	OpUpdateMany OperationType = "update_many"
-	OpDelete     OperationType = "delete"
-	OpUpsert     OperationType = "upsert"
-	OpUpsertMany OperationType = "upsert_many"
-	OpBulkUpdate OperationType = "bulk_update"
-	OpFind       OperationType = "find"
-	OpQuery      OperationType = "query"
+	OpCreate          OperationType = "create"
+	OpCreateMany      OperationType = "create_many"
+	OpUpdate          OperationType = "update"
+	OpUpdateMany      OperationType = "update_many"
+	OpDelete          OperationType = "delete"
+	OpUpsert          OperationType = "upsert"
+	OpUpsertMany      OperationType = "upsert_many"
+	OpBulkUpdate      OperationType = "bulk_update"
+	OpFind            OperationType = "find"
+	OpQuery           OperationType = "query"
+	OpInsertFromQuery OperationType = "insert_from_query"
+	OpNamedQuery      OperationType = "named_query"
+	OpFunctionCall    OperationType = "function_call"
+	OpProcedureCall   OperationType = "procedure_call"
 )
 
-// MiddlewareContext contains information passed to middleware
+// writes reports whether the operation changes rows
+func (op OperationType) writes() bool {
+	switch op {
+	case OpFind, OpQuery, OpNamedQuery:
+		return false
+	}
+	return true
+}
+
+// MiddlewareContext contains information passed to middleware. Fields above
+// Query are set before next is called; Query, Args, Result, RowsAffected,
+// Error and Duration describe the execution once next returns.
 type MiddlewareContext struct {
 	Operation    OperationType
+	QueryName    string // statement name of an OpNamedQuery, or routine of an OpFunctionCall or OpProcedureCall, whose Query and Args are set before next
 	TableName    string
 	Record       interface{}
 	Records      interface{}
 	QueryBuilder interface{} // squirrel.SelectBuilder, squirrel.InsertBuilder, etc.
 	Query        string
 	Args         []interface{}
+	// Result points at the value a read fills: *[]T for Find and *int64 for
+	// Count, nil for writes. Middleware may fill it and skip next to serve a
+	// cached result, or inspect and modify it after next returns.
+	Result       interface{}
+	RowsAffected int64
 	Error        error
 	StartTime    time.Time
 	Duration     time.Duration
@@ -71,31 +92,59 @@ func (mm *middlewareManager) ExecuteMiddleware(ctx *MiddlewareContext, finalFunc
 // Repository middleware integration
 
 func (r *Repository[T]) executeQueryMiddleware(op OperationType, ctx context.Context, record interface{}, queryBuilder interface{}, finalFunc QueryMiddlewareFunc) error {
-	if r.middlewareManager == nil {
-		return finalFunc(&MiddlewareContext{
-			Operation:    op,
-			TableName:    r.metadata.TableName,
-			Record:       record,
-			QueryBuilder: queryBuilder,
-			Context:      ctx,
-			StartTime:    time.Now(),
-			Metadata:     make(map[string]interface{}),
-		})
+	return r.executeResultMiddleware(op, ctx, record, queryBuilder, nil, finalFunc)
+}
+
+// executeResultMiddleware runs finalFunc through the middleware chain with
+// result exposed as MiddlewareContext.Result
+func (r *Repository[T]) executeResultMiddleware(op OperationType, ctx context.Context, record interface{}, queryBuilder interface{}, result interface{}, finalFunc QueryMiddlewareFunc) error {
+	if err := ctx.Err(); err != nil {
+		return &Error{
+			Op:    string(op),
+			Table: r.metadata.TableName,
+			Err:   err,
+		}
+	}
+
+	if r.metadata.ReadOnly && op.writes() {
+		return &Error{
+			Op:    string(op),
+			Table: r.metadata.TableName,
+			Err:   ErrReadOnly,
+		}
 	}
 
+	finalFunc = recordOutcome(debugLogged(ctx, skipDryRun(finalFunc)))
+
 	middlewareCtx := &MiddlewareContext{
 		Operation:    op,
 		TableName:    r.metadata.TableName,
 		Record:       record,
 		QueryBuilder: queryBuilder,
+		Result:       result,
 		Context:      ctx,
 		StartTime:    time.Now(),
 		Metadata:     make(map[string]interface{}),
 	}
 
+	if r.middlewareManager == nil {
+		return finalFunc(middlewareCtx)
+	}
+
 	return r.middlewareManager.ExecuteMiddleware(middlewareCtx, finalFunc)
 }
 
+// recordOutcome stores the error and duration of finalFunc on the context so
+// middleware sees them once next returns
+func recordOutcome(finalFunc QueryMiddlewareFunc) QueryMiddlewareFunc {
+	return func(middlewareCtx *MiddlewareContext) error {
+		err := finalFunc(middlewareCtx)
+		middlewareCtx.Error = err
+		middlewareCtx.Duration = time.Since(middlewareCtx.StartTime)
+		return err
+	}
+}
+
 func (r *Repository[T]) AddMiddleware(middleware QueryMiddleware) {
 	if r.middlewareManager == nil {
 		r.middlewareManager = newMiddlewareManager()
@@ -3,6 +3,7 @@ package orm
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"testing"
 	"time"
 
@@ -293,3 +294,118 @@ func TestMiddlewareUpdateMany(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+// TestMiddlewareUpdateManyBuilder tests that UpdateMany middleware can extend
+// the update builder before it is rendered
+func TestMiddlewareUpdateManyBuilder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	repo.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			builder, ok := ctx.QueryBuilder.(squirrel.UpdateBuilder)
+			require.True(t, ok, "UpdateMany should pass an UpdateBuilder")
+			ctx.QueryBuilder = builder.Where(squirrel.Eq{"tenant_id": 7})
+			return next(ctx)
+		}
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET is_active = $1 WHERE (users.name = $2) AND tenant_id = $3`)).
+		WithArgs(false, "Ada", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	isActiveCol := Column[bool]{Name: "is_active", Table: "users"}
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	rowsAffected, err := repo.Query(context.Background()).
+		Where(nameCol.Eq("Ada")).
+		Update(isActiveCol.Set(false))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMiddlewarePostExecution tests that middleware sees results after next returns
+func TestMiddlewarePostExecution(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	var seen []*MiddlewareContext
+	repo.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			err := next(ctx)
+			if users, ok := ctx.Result.(*[]TestUser); ok {
+				for i := range *users {
+					(*users)[i].Email = "***"
+				}
+			}
+			seen = append(seen, ctx)
+			return err
+		}
+	})
+
+	mock.ExpectQuery("SELECT .* FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).
+			AddRow(1, "Ada", "ada@example.com").
+			AddRow(2, "Linus", "linus@example.com"))
+	mock.ExpectExec("UPDATE users SET is_active").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectQuery("SELECT COUNT").
+		WillReturnError(fmt.Errorf("connection reset"))
+
+	users, err := repo.Query(context.Background()).Find()
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "***", users[0].Email)
+
+	isActiveCol := Column[bool]{Name: "is_active", Table: "users"}
+	_, err = repo.Query(context.Background()).Update(isActiveCol.Set(false))
+	require.NoError(t, err)
+
+	_, err = repo.Query(context.Background()).Count()
+	require.Error(t, err)
+
+	require.Len(t, seen, 3)
+	assert.Contains(t, seen[0].Query, "FROM users")
+	assert.NoError(t, seen[0].Error)
+	assert.Positive(t, seen[0].Duration)
+	assert.Equal(t, int64(4), seen[1].RowsAffected)
+	assert.IsType(t, new(int64), seen[2].Result)
+	assert.Equal(t, err, seen[2].Error)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMiddlewareServesCachedResult tests that middleware can fill the result and skip execution
+func TestMiddlewareServesCachedResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	repo.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			if users, ok := ctx.Result.(*[]TestUser); ok {
+				*users = []TestUser{{ID: 7, Name: "Cached"}}
+				return nil
+			}
+			return next(ctx)
+		}
+	})
+
+	users, err := repo.Query(context.Background()).Find()
+	require.NoError(t, err)
+	assert.Equal(t, []TestUser{{ID: 7, Name: "Cached"}}, users)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,109 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// AddMiddleware adds middleware run around the named queries of this Storm
+// and of the transactions and dry runs begun from it. Repositories keep
+// their own middleware, added with Repository.AddMiddleware.
+func (s *Storm) AddMiddleware(middleware QueryMiddleware) {
+	if s.middlewareManager == nil {
+		s.middlewareManager = newMiddlewareManager()
+	}
+	s.middlewareManager.AddMiddleware(middleware)
+}
+
+// QueryNamed runs name, a statement generated from a queries/ SQL file with
+// :param placeholders bound from the db tags of params, which may be nil. A
+// pointer to a slice receives every row; any other pointer receives the first
+// row and ErrNotFound is returned when there is none. The statement runs
+// through the Storm's middleware as OpNamedQuery, with dest as the result.
+func (s *Storm) QueryNamed(ctx context.Context, name, query string, params interface{}, dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return &Error{Op: name, Err: fmt.Errorf("destination must be a non-nil pointer, got %T", dest)}
+	}
+
+	return s.executeNamed(ctx, name, query, params, dest, func(middlewareCtx *MiddlewareContext) error {
+		if value.Elem().Kind() == reflect.Slice && value.Elem().Type().Elem().Kind() != reflect.Uint8 {
+			if err := s.executor.SelectContext(ctx, dest, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+				return parsePostgreSQLError(err, name, "")
+			}
+			return nil
+		}
+		if err := s.executor.GetContext(ctx, dest, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+			return parsePostgreSQLError(err, name, "")
+		}
+		return nil
+	})
+}
+
+// ExecNamed runs name, a generated statement that returns no rows, and
+// returns the number of rows it affected. See QueryNamed.
+func (s *Storm) ExecNamed(ctx context.Context, name, query string, params interface{}) (int64, error) {
+	var affected int64
+	err := s.executeNamed(ctx, name, query, params, nil, func(middlewareCtx *MiddlewareContext) error {
+		result, err := s.executor.ExecContext(ctx, middlewareCtx.Query, middlewareCtx.Args...)
+		if err != nil {
+			return parsePostgreSQLError(err, name, "")
+		}
+		if affected, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		middlewareCtx.RowsAffected = affected
+		return nil
+	})
+	return affected, err
+}
+
+// namedRaw returns the raw query binding a named query's parameters. Named
+// queries are written by the application, not assembled at runtime, so the
+// raw policy does not apply to them.
+func (s *Storm) namedRaw(ctx context.Context, query string, params interface{}) *RawQuery {
+	raw := s.Raw(ctx, query)
+	raw.policy = nil
+	if params != nil {
+		raw.BindStruct(params)
+	}
+	return raw
+}
+
+// executeNamed compiles a named query and runs finalFunc through the
+// middleware chain with the compiled statement on the context
+func (s *Storm) executeNamed(ctx context.Context, name, query string, params interface{}, result interface{}, finalFunc QueryMiddlewareFunc) error {
+	if err := ctx.Err(); err != nil {
+		return &Error{Op: name, Err: err}
+	}
+
+	sqlQuery, args, err := s.namedRaw(ctx, query, params).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	return s.executeStatement(ctx, OpNamedQuery, name, sqlQuery, args, result, finalFunc)
+}
+
+// executeStatement runs finalFunc through the middleware chain for a
+// statement the Storm runs itself rather than through a repository
+func (s *Storm) executeStatement(ctx context.Context, op OperationType, name, query string, args []interface{}, result interface{}, finalFunc QueryMiddlewareFunc) error {
+	middlewareCtx := &MiddlewareContext{
+		Operation: op,
+		QueryName: name,
+		Query:     query,
+		Args:      args,
+		Result:    result,
+		Context:   ctx,
+		StartTime: time.Now(),
+		Metadata:  make(map[string]interface{}),
+	}
+
+	finalFunc = recordOutcome(debugLogged(ctx, skipDryRun(finalFunc)))
+	if s.middlewareManager == nil {
+		return finalFunc(middlewareCtx)
+	}
+	return s.middlewareManager.ExecuteMiddleware(middlewareCtx, finalFunc)
+}
@@ -0,0 +1,107 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type activeUsersParams struct {
+	Active bool `db:"active"`
+}
+
+type activeUsersRow struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestStormQueryNamed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	var seen []*MiddlewareContext
+	s.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			err := next(ctx)
+			seen = append(seen, ctx)
+			return err
+		}
+	})
+
+	const query = "SELECT id, name FROM users WHERE is_active = :active AND name <> ':x'"
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM users WHERE is_active = $1 AND name <> ':x'")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ada").AddRow(2, "Linus"))
+	mock.ExpectQuery("SELECT id, name FROM users").
+		WithArgs(false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	var rows []activeUsersRow
+	err = s.QueryNamed(context.Background(), "ActiveUsers", query, activeUsersParams{Active: true}, &rows)
+	require.NoError(t, err)
+	assert.Equal(t, []activeUsersRow{{ID: 1, Name: "Ada"}, {ID: 2, Name: "Linus"}}, rows)
+
+	var row activeUsersRow
+	err = s.QueryNamed(context.Background(), "ActiveUser", query, activeUsersParams{}, &row)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.Len(t, seen, 2)
+	assert.Equal(t, OpNamedQuery, seen[0].Operation)
+	assert.Equal(t, "ActiveUsers", seen[0].QueryName)
+	assert.Equal(t, []interface{}{true}, seen[0].Args)
+	assert.Equal(t, &rows, seen[0].Result)
+	assert.Equal(t, "ActiveUser", seen[1].QueryName)
+	assert.ErrorIs(t, seen[1].Error, ErrNotFound)
+
+	err = s.QueryNamed(context.Background(), "ActiveUser", query, nil, row)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStormExecNamed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorm(sqlx.NewDb(db, "postgres"))
+	var operations []OperationType
+	s.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			operations = append(operations, ctx.Operation)
+			return next(ctx)
+		}
+	})
+
+	const query = "DELETE FROM users WHERE is_active = :active"
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE is_active = $1")).
+		WithArgs(false).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err = s.WithTransaction(context.Background(), func(tx *Storm) error {
+		affected, err := tx.ExecNamed(context.Background(), "PurgeInactive", query, activeUsersParams{})
+		assert.Equal(t, int64(3), affected)
+		return err
+	})
+	require.NoError(t, err)
+
+	statements, err := s.DryRun(context.Background(), func(dry *Storm) error {
+		_, err := dry.ExecNamed(context.Background(), "PurgeInactive", query, activeUsersParams{})
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.Equal(t, "DELETE FROM users WHERE is_active = $1", statements[0].Query)
+
+	assert.Equal(t, []OperationType{OpNamedQuery, OpNamedQuery}, operations)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
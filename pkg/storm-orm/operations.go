@@ -2,7 +2,10 @@ package orm
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Masterminds/squirrel"
@@ -14,9 +17,42 @@ type UpsertOptions struct {
 	ConflictColumns []string          // Columns that define conflicts (ON CONFLICT)
 	UpdateColumns   []string          // Columns to update on conflict (if empty, updates all non-conflict columns)
 	UpdateExpr      map[string]string // Custom update expressions (column -> expression)
+
+	// ConflictWhere is the predicate of a partial unique index used as the
+	// conflict target, e.g. "deleted_at IS NULL"
+	ConflictWhere string
+
+	// UpdateWhere limits the update to conflicting rows it matches, e.g.
+	// "EXCLUDED.updated_at > users.updated_at". Rows it rejects are left as
+	// they are. It has no effect when nothing is updated (DO NOTHING).
+	UpdateWhere string
+}
+
+// Validator is implemented by models that check their own constraints.
+// Create, Update, CreateMany, Upsert and UpsertMany call Validate before writing.
+type Validator interface {
+	Validate() error
+}
+
+func (r *Repository[T]) validateRecord(op string, record *T) error {
+	validator, ok := any(record).(Validator)
+	if !ok {
+		return nil
+	}
+	if err := validator.Validate(); err != nil {
+		return &Error{
+			Op:    op,
+			Table: r.metadata.TableName,
+			Err:   err,
+		}
+	}
+	return nil
 }
 
 func (r *Repository[T]) Create(ctx context.Context, record *T) (*T, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if record == nil {
 		return nil, &Error{
 			Op:    "create",
@@ -25,24 +61,45 @@ func (r *Repository[T]) Create(ctx context.Context, record *T) (*T, error) {
 		}
 	}
 
+	if err := r.stampActor(ctx, "create", record, true); err != nil {
+		return nil, err
+	}
+
+	if err := r.validateRecord("create", record); err != nil {
+		return nil, err
+	}
+
+	err := r.withAutosave(ctx, "create", record, func(exec DBExecutor) error {
+		return r.create(ctx, exec, record)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// create inserts record with exec and reads the columns the database fills
+// back into it
+func (r *Repository[T]) create(ctx context.Context, exec DBExecutor, record *T) error {
 	columns, values := r.getInsertFields(*record)
 	if len(columns) == 0 {
-		return nil, &Error{
+		return &Error{
 			Op:    "create",
 			Table: r.metadata.TableName,
 			Err:   fmt.Errorf("no fields to insert"),
 		}
 	}
 
-	query := squirrel.Insert(r.metadata.TableName).
-		PlaceholderFormat(squirrel.Dollar).
-		Columns(columns...).
+	query := r.metadata.derived().insertBuilder.
+		Columns(quoteIdents(columns)...).
 		Values(values...)
 
-	err := r.executeQueryMiddleware(OpCreate, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+	return r.executeQueryMiddleware(OpCreate, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
 
-		returningCols := r.getAutoGeneratedColumns()
+		returning := returningClause(r.metadata)
 
 		sqlQuery, args, err := finalQuery.ToSql()
 		if err != nil {
@@ -52,21 +109,18 @@ func (r *Repository[T]) Create(ctx context.Context, record *T) (*T, error) {
 				Err:   fmt.Errorf("failed to build query: %w", err),
 			}
 		}
-
-		if len(returningCols) > 0 {
-			sqlQuery += " RETURNING " + strings.Join(returningCols, ", ")
-		}
+		sqlQuery += returning
 
 		middlewareCtx.Query = sqlQuery
 		middlewareCtx.Args = args
 
 		var execErr error
-		if len(returningCols) > 0 {
-			if err := r.db.GetContext(ctx, record, sqlQuery, args...); err != nil {
+		if returning != "" {
+			if err := getRecord(ctx, exec, r.metadata, record, sqlQuery, args...); err != nil {
 				execErr = err
 			}
 		} else {
-			if _, err := r.db.ExecContext(ctx, sqlQuery, args...); err != nil {
+			if _, err := exec.ExecContext(ctx, sqlQuery, args...); err != nil {
 				execErr = err
 			}
 		}
@@ -77,15 +131,12 @@ func (r *Repository[T]) Create(ctx context.Context, record *T) (*T, error) {
 
 		return nil
 	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return record, nil
 }
 
 func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if len(r.metadata.PrimaryKeys) != 1 {
 		return nil, &Error{
 			Op:    "findByID",
@@ -94,15 +145,8 @@ func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error
 		}
 	}
 
-	selectColumns := make([]string, 0, len(r.metadata.Columns))
-	for _, col := range r.metadata.Columns {
-		selectColumns = append(selectColumns, col.DBName)
-	}
-
-	query := squirrel.Select(selectColumns...).
-		From(r.metadata.TableName).
-		Where(squirrel.Eq{r.metadata.PrimaryKeys[0]: id}).
-		PlaceholderFormat(squirrel.Dollar).
+	query := r.metadata.derived().selectBuilder.
+		Where(squirrel.Eq{quoteIdent(r.metadata.PrimaryKeys[0]): r.options.bind(id)}).
 		Limit(1)
 
 	sqlQuery, args, err := query.ToSql()
@@ -114,16 +158,76 @@ func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error
 		}
 	}
 
-	var record T
-	err = r.db.GetContext(ctx, &record, sqlQuery, args...)
+	records, err := r.selectRecords(ctx, r.db, sqlQuery, args...)
+	if err == nil && len(records) == 0 {
+		err = sql.ErrNoRows
+	}
 	if err != nil {
 		return nil, parsePostgreSQLError(err, "findByID", r.metadata.TableName)
 	}
 
-	return &record, nil
+	return &records[0], nil
+}
+
+// FindOneBy returns the record whose columns equal values, paired by position.
+// A *NotFoundError is returned when no record matches.
+func (r *Repository[T]) FindOneBy(ctx context.Context, columns []string, values ...interface{}) (*T, error) {
+	query, err := r.findByQuery(ctx, "findOneBy", columns, values)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := query.First()
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, &NotFoundError{
+				Table:   r.metadata.TableName,
+				Columns: columns,
+			}
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// FindAllBy returns every record whose columns equal values, paired by position
+func (r *Repository[T]) FindAllBy(ctx context.Context, columns []string, values ...interface{}) ([]T, error) {
+	query, err := r.findByQuery(ctx, "findAllBy", columns, values)
+	if err != nil {
+		return nil, err
+	}
+	return query.Find()
+}
+
+func (r *Repository[T]) findByQuery(ctx context.Context, op string, columns []string, values []interface{}) (*Query[T], error) {
+	if len(columns) == 0 || len(columns) != len(values) {
+		return nil, &Error{
+			Op:    op,
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("expected %d values for columns %v, got %d", len(columns), columns, len(values)),
+		}
+	}
+
+	eq := squirrel.Eq{}
+	for i, column := range columns {
+		if _, ok := r.metadata.ReverseMap[column]; !ok {
+			return nil, &Error{
+				Op:     op,
+				Table:  r.metadata.TableName,
+				Column: column,
+				Err:    ErrUnknownColumn,
+			}
+		}
+		eq[quoteIdent(r.metadata.TableName+"."+column)] = r.options.bind(values[i])
+	}
+
+	return r.Query(ctx).Where(Condition{eq}), nil
 }
 
 func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if record == nil {
 		return nil, &Error{
 			Op:    "update",
@@ -132,17 +236,35 @@ func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 		}
 	}
 
-	query := squirrel.Update(r.metadata.TableName).
+	if err := r.stampActor(ctx, "update", record, false); err != nil {
+		return nil, err
+	}
+
+	if err := r.validateRecord("update", record); err != nil {
+		return nil, err
+	}
+
+	query := squirrel.Update(r.quotedTable()).
 		PlaceholderFormat(squirrel.Dollar)
 
 	updateFields := r.getUpdateFields(*record)
-	for column, value := range updateFields {
-		query = query.Set(column, value)
+	assigned := make(map[string]bool, len(updateFields))
+	for _, col := range r.metadata.OrderedColumns() {
+		value, ok := updateFields[col.DBName]
+		if !ok {
+			continue
+		}
+		query = query.Set(quoteIdent(col.DBName), value)
+		assigned[col.DBName] = true
 	}
+	query = r.touchUpdate(query, assigned)
+	returningCols := r.touchedAutoUpdateColumns(assigned)
 
 	pkValues := r.getPrimaryKeyValues(*record)
-	for pkCol, value := range pkValues {
-		query = query.Where(squirrel.Eq{pkCol: value})
+	for _, pkCol := range r.metadata.PrimaryKeys {
+		if value, ok := pkValues[pkCol]; ok {
+			query = query.Where(squirrel.Eq{quoteIdent(pkCol): value})
+		}
 	}
 
 	err := r.executeQueryMiddleware(OpUpdate, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
@@ -157,9 +279,24 @@ func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 			}
 		}
 
+		if len(returningCols) > 0 {
+			sqlQuery += " RETURNING " + strings.Join(quoteIdents(returningCols), ", ")
+		}
+
 		middlewareCtx.Query = sqlQuery
 		middlewareCtx.Args = args
 
+		if len(returningCols) > 0 {
+			if err := getRecord(ctx, r.db, r.metadata, record, sqlQuery, args...); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrNotFound
+				}
+				return parsePostgreSQLError(err, "update", r.metadata.TableName)
+			}
+			middlewareCtx.RowsAffected = 1
+			return nil
+		}
+
 		result, err := r.db.ExecContext(ctx, sqlQuery, args...)
 		if err != nil {
 			return parsePostgreSQLError(err, "update", r.metadata.TableName)
@@ -174,6 +311,8 @@ func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 			}
 		}
 
+		middlewareCtx.RowsAffected = rowsAffected
+
 		if rowsAffected == 0 {
 			return ErrNotFound
 		}
@@ -190,6 +329,9 @@ func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 
 // UpdateFields updates specific fields of a single record by primary key
 func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, updates map[string]interface{}) (*T, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if len(r.metadata.PrimaryKeys) != 1 {
 		return nil, &Error{
 			Op:    "updateFields",
@@ -206,12 +348,28 @@ func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, update
 		}
 	}
 
-	query := squirrel.Update(r.metadata.TableName).
+	columns := make([]string, 0, len(updates))
+	for column := range updates {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	if err := r.validateUpdateColumns("updateFields", columns); err != nil {
+		return nil, err
+	}
+
+	query := squirrel.Update(r.quotedTable()).
 		PlaceholderFormat(squirrel.Dollar).
-		Where(squirrel.Eq{r.metadata.PrimaryKeys[0]: id})
+		Where(squirrel.Eq{quoteIdent(r.metadata.PrimaryKeys[0]): r.options.bind(id)})
 
-	for column, value := range updates {
-		query = query.Set(column, value)
+	assigned := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		query = query.Set(quoteIdent(column), r.options.bind(updates[column]))
+		assigned[column] = true
+	}
+	query = r.touchUpdate(query, assigned)
+	if column, actor, ok := r.actorUpdate(ctx, assigned); ok {
+		query = query.Set(quoteIdent(column), actor)
 	}
 
 	var record *T
@@ -252,6 +410,8 @@ func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, update
 			}
 		}
 
+		middlewareCtx.RowsAffected = rowsAffected
+
 		if rowsAffected == 0 {
 			return ErrNotFound
 		}
@@ -271,7 +431,157 @@ func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, update
 	return record, nil
 }
 
+// UpdateMany updates every record by primary key in a single
+// UPDATE ... FROM (VALUES ...) statement and returns the number of rows updated.
+// Records whose primary key does not exist are skipped; when a key appears more
+// than once, the last record wins.
+func (r *Repository[T]) UpdateMany(ctx context.Context, records []T) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	if len(r.metadata.PrimaryKeys) == 0 {
+		return 0, &Error{
+			Op:    "updateMany",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("model has no primary key"),
+		}
+	}
+
+	for i := range records {
+		if err := r.stampActor(ctx, "updateMany", &records[i], false); err != nil {
+			return 0, err
+		}
+		if err := r.validateRecord("updateMany", &records[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	keys := append([]string(nil), r.metadata.PrimaryKeys...)
+	var columns []string
+	for column := range r.getUpdateFields(records[0]) {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	rows := make([][]interface{}, 0, len(records))
+	positions := make(map[string]int, len(records))
+	for _, record := range records {
+		pkValues := r.getPrimaryKeyValues(record)
+		fields := r.getUpdateFields(record)
+
+		row := make([]interface{}, 0, len(keys)+len(columns))
+		for _, key := range keys {
+			row = append(row, pkValues[key])
+		}
+		for _, column := range columns {
+			row = append(row, fields[column])
+		}
+
+		identity := fmt.Sprint(row[:len(keys)]...)
+		if i, seen := positions[identity]; seen {
+			rows[i] = row
+			continue
+		}
+		positions[identity] = len(rows)
+		rows = append(rows, row)
+	}
+
+	allColumns := append(append([]string(nil), keys...), columns...)
+	sqlQuery, args := r.buildUpdateManyQuery(keys, columns, allColumns, rows)
+
+	var rowsAffected int64
+	err := r.executeQueryMiddleware(OpBulkUpdate, ctx, records, sqlQuery, func(middlewareCtx *MiddlewareContext) error {
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		result, err := r.db.ExecContext(ctx, sqlQuery, args...)
+		if err != nil {
+			return parsePostgreSQLError(err, "updateMany", r.metadata.TableName)
+		}
+
+		rowsAffected, err = result.RowsAffected()
+		if err != nil {
+			return &Error{
+				Op:    "updateMany",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to get rows affected: %w", err),
+			}
+		}
+
+		middlewareCtx.RowsAffected = rowsAffected
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, nil
+}
+
+// buildUpdateManyQuery renders the UPDATE ... FROM (VALUES ...) statement.
+// Values are cast to the column types when the metadata knows them, because
+// PostgreSQL otherwise types every VALUES parameter as text.
+func (r *Repository[T]) buildUpdateManyQuery(keys, columns, allColumns []string, rows [][]interface{}) (string, []interface{}) {
+	casts := make([]string, len(allColumns))
+	for i, column := range allColumns {
+		if col := r.columnByDBName(column); col != nil {
+			casts[i] = castType(col.DBType)
+		}
+	}
+
+	args := make([]interface{}, 0, len(rows)*len(allColumns))
+	valueRows := make([]string, len(rows))
+	for i, row := range rows {
+		placeholders := make([]string, len(row))
+		for j, value := range row {
+			args = append(args, value)
+			placeholders[j] = fmt.Sprintf("$%d", len(args))
+			if casts[j] != "" {
+				placeholders[j] += "::" + casts[j]
+			}
+		}
+		valueRows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	setParts := make([]string, 0, len(columns))
+	assigned := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		setParts = append(setParts, fmt.Sprintf("%s = v.%s", quoteIdent(column), quoteIdent(column)))
+		assigned[column] = true
+	}
+	for _, column := range r.touchedColumns(assigned) {
+		setParts = append(setParts, quoteIdent(column)+" = NOW()")
+	}
+
+	conditions := make([]string, len(keys))
+	for i, key := range keys {
+		conditions[i] = fmt.Sprintf("t.%s = v.%s", quoteIdent(key), quoteIdent(key))
+	}
+
+	sqlQuery := fmt.Sprintf("UPDATE %s AS t SET %s FROM (VALUES %s) AS v(%s) WHERE %s",
+		r.quotedTable(),
+		strings.Join(setParts, ", "),
+		strings.Join(valueRows, ", "),
+		strings.Join(quoteIdents(allColumns), ", "),
+		strings.Join(conditions, " AND "))
+
+	return sqlQuery, args
+}
+
 func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if len(r.metadata.PrimaryKeys) != 1 {
 		return nil, &Error{
 			Op:    "delete",
@@ -280,8 +590,8 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error)
 		}
 	}
 
-	query := squirrel.Delete(r.metadata.TableName).
-		Where(squirrel.Eq{r.metadata.PrimaryKeys[0]: id}).
+	query := squirrel.Delete(r.quotedTable()).
+		Where(squirrel.Eq{quoteIdent(r.metadata.PrimaryKeys[0]): r.options.bind(id)}).
 		PlaceholderFormat(squirrel.Dollar)
 
 	var record *T
@@ -308,25 +618,33 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error)
 		middlewareCtx.Query = sqlQuery
 		middlewareCtx.Args = args
 
-		result, err := r.db.ExecContext(ctx, sqlQuery, args...)
-		if err != nil {
-			return parsePostgreSQLError(err, "delete", r.metadata.TableName)
-		}
+		return r.withDependents(ctx, "delete", r.db, func(exec DBExecutor) error {
+			if err := r.applyDependents(ctx, exec, "delete", squirrel.Eq{quoteIdent(r.metadata.PrimaryKeys[0]): r.options.bind(id)}); err != nil {
+				return err
+			}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return &Error{
-				Op:    "delete",
-				Table: r.metadata.TableName,
-				Err:   fmt.Errorf("failed to get rows affected: %w", err),
+			result, err := exec.ExecContext(ctx, sqlQuery, args...)
+			if err != nil {
+				return parsePostgreSQLError(err, "delete", r.metadata.TableName)
 			}
-		}
 
-		if rowsAffected == 0 {
-			return ErrNotFound
-		}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return &Error{
+					Op:    "delete",
+					Table: r.metadata.TableName,
+					Err:   fmt.Errorf("failed to get rows affected: %w", err),
+				}
+			}
 
-		return nil
+			middlewareCtx.RowsAffected = rowsAffected
+
+			if rowsAffected == 0 {
+				return ErrNotFound
+			}
+
+			return nil
+		})
 	})
 
 	if err != nil {
@@ -337,6 +655,9 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error)
 }
 
 func (r *Repository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if record == nil {
 		return nil, &Error{
 			Op:    "deleteRecord",
@@ -345,12 +666,14 @@ func (r *Repository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error)
 		}
 	}
 
-	query := squirrel.Delete(r.metadata.TableName).
+	query := squirrel.Delete(r.quotedTable()).
 		PlaceholderFormat(squirrel.Dollar)
 
 	pkValues := r.getPrimaryKeyValues(*record)
-	for pkCol, value := range pkValues {
-		query = query.Where(squirrel.Eq{pkCol: value})
+	for _, pkCol := range r.metadata.PrimaryKeys {
+		if value, ok := pkValues[pkCol]; ok {
+			query = query.Where(squirrel.Eq{quoteIdent(pkCol): value})
+		}
 	}
 
 	err := r.executeQueryMiddleware(OpDelete, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
@@ -368,25 +691,33 @@ func (r *Repository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error)
 		middlewareCtx.Query = sqlQuery
 		middlewareCtx.Args = args
 
-		result, err := r.db.ExecContext(ctx, sqlQuery, args...)
-		if err != nil {
-			return parsePostgreSQLError(err, "deleteRecord", r.metadata.TableName)
-		}
+		return r.withDependents(ctx, "deleteRecord", r.db, func(exec DBExecutor) error {
+			if err := r.applyDependents(ctx, exec, "deleteRecord", squirrel.Eq(pkValues)); err != nil {
+				return err
+			}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return &Error{
-				Op:    "deleteRecord",
-				Table: r.metadata.TableName,
-				Err:   fmt.Errorf("failed to get rows affected: %w", err),
+			result, err := exec.ExecContext(ctx, sqlQuery, args...)
+			if err != nil {
+				return parsePostgreSQLError(err, "deleteRecord", r.metadata.TableName)
 			}
-		}
 
-		if rowsAffected == 0 {
-			return ErrNotFound
-		}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return &Error{
+					Op:    "deleteRecord",
+					Table: r.metadata.TableName,
+					Err:   fmt.Errorf("failed to get rows affected: %w", err),
+				}
+			}
 
-		return nil
+			middlewareCtx.RowsAffected = rowsAffected
+
+			if rowsAffected == 0 {
+				return ErrNotFound
+			}
+
+			return nil
+		})
 	})
 
 	if err != nil {
@@ -397,10 +728,22 @@ func (r *Repository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error)
 }
 
 func (r *Repository[T]) CreateMany(ctx context.Context, records []T) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if len(records) == 0 {
 		return nil
 	}
 
+	for i := range records {
+		if err := r.stampActor(ctx, "createMany", &records[i], true); err != nil {
+			return err
+		}
+		if err := r.validateRecord("createMany", &records[i]); err != nil {
+			return err
+		}
+	}
+
 	var executor DBExecutor
 	needsCommit := false
 	var rollback func()
@@ -441,9 +784,9 @@ func (r *Repository[T]) CreateMany(ctx context.Context, records []T) error {
 		return nil
 	}
 
-	query := squirrel.Insert(r.metadata.TableName).
+	query := squirrel.Insert(r.quotedTable()).
 		PlaceholderFormat(squirrel.Dollar).
-		Columns(columns...)
+		Columns(quoteIdents(columns)...)
 
 	for _, record := range records {
 		_, values := r.getInsertFields(record)
@@ -487,6 +830,9 @@ func (r *Repository[T]) CreateMany(ctx context.Context, records []T) error {
 }
 
 func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOptions) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if record == nil {
 		return &Error{
 			Op:    "upsert",
@@ -495,6 +841,14 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 		}
 	}
 
+	if err := r.stampActor(ctx, "upsert", record, true); err != nil {
+		return err
+	}
+
+	if err := r.validateRecord("upsert", record); err != nil {
+		return err
+	}
+
 	if len(opts.ConflictColumns) == 0 {
 		return &Error{
 			Op:    "upsert",
@@ -503,6 +857,10 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 		}
 	}
 
+	if err := r.validateUpsertOptions("upsert", opts); err != nil {
+		return err
+	}
+
 	columns, values := r.getInsertFields(*record)
 	if len(columns) == 0 {
 		return &Error{
@@ -512,9 +870,9 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 		}
 	}
 
-	query := squirrel.Insert(r.metadata.TableName).
+	query := squirrel.Insert(r.quotedTable()).
 		PlaceholderFormat(squirrel.Dollar).
-		Columns(columns...).
+		Columns(quoteIdents(columns)...).
 		Values(values...)
 
 	return r.executeQueryMiddleware(OpUpsert, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
@@ -529,39 +887,7 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 			}
 		}
 
-		onConflict := fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(opts.ConflictColumns, ", "))
-
-		var updateColumns []string
-		if len(opts.UpdateColumns) > 0 {
-			updateColumns = opts.UpdateColumns
-		} else {
-			conflictSet := make(map[string]bool)
-			for _, col := range opts.ConflictColumns {
-				conflictSet[col] = true
-			}
-
-			for _, col := range columns {
-				if !conflictSet[col] {
-					updateColumns = append(updateColumns, col)
-				}
-			}
-		}
-
-		if len(updateColumns) > 0 {
-			var setParts []string
-			for _, col := range updateColumns {
-				if expr, hasCustom := opts.UpdateExpr[col]; hasCustom {
-					setParts = append(setParts, fmt.Sprintf("%s = %s", col, expr))
-				} else {
-					setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-				}
-			}
-			onConflict += " DO UPDATE SET " + strings.Join(setParts, ", ")
-		} else {
-			onConflict += " DO NOTHING"
-		}
-
-		finalSqlQuery := sqlQuery + onConflict
+		finalSqlQuery := sqlQuery + r.onConflictClause(columns, opts)
 
 		middlewareCtx.Query = finalSqlQuery
 		middlewareCtx.Args = args
@@ -576,6 +902,9 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 }
 
 func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts UpsertOptions) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if len(records) == 0 {
 		return nil
 	}
@@ -588,6 +917,19 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 		}
 	}
 
+	if err := r.validateUpsertOptions("upsertMany", opts); err != nil {
+		return err
+	}
+
+	for i := range records {
+		if err := r.stampActor(ctx, "upsertMany", &records[i], true); err != nil {
+			return err
+		}
+		if err := r.validateRecord("upsertMany", &records[i]); err != nil {
+			return err
+		}
+	}
+
 	var executor DBExecutor
 	needsCommit := false
 	var rollback func()
@@ -628,9 +970,9 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 		return nil
 	}
 
-	query := squirrel.Insert(r.metadata.TableName).
+	query := squirrel.Insert(r.quotedTable()).
 		PlaceholderFormat(squirrel.Dollar).
-		Columns(columns...)
+		Columns(quoteIdents(columns)...)
 
 	for _, record := range records {
 		_, values := r.getInsertFields(record)
@@ -649,38 +991,7 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 			}
 		}
 
-		onConflict := fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(opts.ConflictColumns, ", "))
-		var updateColumns []string
-		if len(opts.UpdateColumns) > 0 {
-			updateColumns = opts.UpdateColumns
-		} else {
-			conflictSet := make(map[string]bool)
-			for _, col := range opts.ConflictColumns {
-				conflictSet[col] = true
-			}
-
-			for _, col := range columns {
-				if !conflictSet[col] {
-					updateColumns = append(updateColumns, col)
-				}
-			}
-		}
-
-		if len(updateColumns) > 0 {
-			var setParts []string
-			for _, col := range updateColumns {
-				if expr, hasCustom := opts.UpdateExpr[col]; hasCustom {
-					setParts = append(setParts, fmt.Sprintf("%s = %s", col, expr))
-				} else {
-					setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-				}
-			}
-			onConflict += " DO UPDATE SET " + strings.Join(setParts, ", ")
-		} else {
-			onConflict += " DO NOTHING"
-		}
-
-		finalSqlQuery := sqlQuery + onConflict
+		finalSqlQuery := sqlQuery + r.onConflictClause(columns, opts)
 
 		middlewareCtx.Query = finalSqlQuery
 		middlewareCtx.Args = args
@@ -705,3 +1016,76 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 		return nil
 	})
 }
+
+// onConflictClause renders the ON CONFLICT clause of an upsert writing columns.
+// Without UpdateColumns every column but the conflict, immutable and creation
+// columns is updated.
+func (r *Repository[T]) onConflictClause(columns []string, opts UpsertOptions) string {
+	onConflict := fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(quoteIdents(opts.ConflictColumns), ", "))
+	if opts.ConflictWhere != "" {
+		onConflict += " WHERE " + opts.ConflictWhere
+	}
+
+	var updateColumns []string
+	if len(opts.UpdateColumns) > 0 {
+		updateColumns = opts.UpdateColumns
+	} else {
+		conflictSet := make(map[string]bool)
+		for _, col := range opts.ConflictColumns {
+			conflictSet[col] = true
+		}
+
+		for _, col := range columns {
+			if !conflictSet[col] && !r.isImmutableColumn(col) && !r.isCreationColumn(col) {
+				updateColumns = append(updateColumns, col)
+			}
+		}
+	}
+
+	if len(updateColumns) > 0 {
+		var setParts []string
+		for _, col := range updateColumns {
+			if expr, hasCustom := opts.UpdateExpr[col]; hasCustom {
+				setParts = append(setParts, fmt.Sprintf("%s = %s", quoteIdent(col), expr))
+			} else {
+				setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", quoteIdent(col), quoteIdent(col)))
+			}
+		}
+		onConflict += " DO UPDATE SET " + strings.Join(setParts, ", ")
+		if opts.UpdateWhere != "" {
+			onConflict += " WHERE " + opts.UpdateWhere
+		}
+	} else {
+		onConflict += " DO NOTHING"
+	}
+
+	return onConflict
+}
+
+// validateUpsertOptions ensures all columns referenced by opts exist on the model
+func (r *Repository[T]) validateUpsertOptions(op string, opts UpsertOptions) error {
+	if err := r.validateColumns(op, opts.ConflictColumns); err != nil {
+		return err
+	}
+	exprColumns := make([]string, 0, len(opts.UpdateExpr))
+	for col := range opts.UpdateExpr {
+		exprColumns = append(exprColumns, col)
+	}
+	sort.Strings(exprColumns)
+
+	updateColumns := append(append([]string{}, opts.UpdateColumns...), exprColumns...)
+	if err := r.validateColumns(op, updateColumns); err != nil {
+		return err
+	}
+	for _, col := range updateColumns {
+		if r.isImmutableColumn(col) {
+			return &Error{
+				Op:     op,
+				Table:  r.metadata.TableName,
+				Column: col,
+				Err:    ErrImmutableColumn,
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"testing"
 	"time"
 
@@ -59,6 +60,73 @@ func TestFindByID(t *testing.T) {
 	})
 }
 
+// TestFindOneBy tests lookups by column values
+func TestFindOneBy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("FindOneBy with existing record", func(t *testing.T) {
+		now := time.Now()
+
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(users\.email = \$1\) LIMIT 1`).
+			WithArgs("john@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"}).
+				AddRow(1, "John Doe", "john@example.com", true, now, now))
+
+		user, err := repo.FindOneBy(context.Background(), []string{"email"}, "john@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, 1, user.ID)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FindOneBy returns NotFoundError", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(users\.email = \$1 AND users\.name = \$2\) LIMIT 1`).
+			WithArgs("missing@example.com", "Nobody").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"}))
+
+		user, err := repo.FindOneBy(context.Background(), []string{"email", "name"}, "missing@example.com", "Nobody")
+		assert.Nil(t, user)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		var notFound *NotFoundError
+		require.ErrorAs(t, err, &notFound)
+		assert.Equal(t, "users", notFound.Table)
+		assert.Equal(t, []string{"email", "name"}, notFound.Columns)
+		assert.Equal(t, "orm: users: record not found by email, name", err.Error())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FindAllBy", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(users\.is_active = \$1\)`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"}).
+				AddRow(1, "John Doe", "john@example.com", true, time.Now(), time.Now()).
+				AddRow(2, "Jane Doe", "jane@example.com", true, time.Now(), time.Now()))
+
+		users, err := repo.FindAllBy(context.Background(), []string{"is_active"}, true)
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := repo.FindOneBy(context.Background(), []string{"nickname"}, "john")
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+
+	t.Run("mismatched values", func(t *testing.T) {
+		_, err := repo.FindAllBy(context.Background(), []string{"email", "name"}, "john@example.com")
+		assert.Error(t, err)
+	})
+}
+
 // TestDeleteRecord tests the DeleteRecord operation
 func TestDeleteRecord(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -107,6 +175,58 @@ func TestDeleteRecord(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("UpdateFields rejects unknown column", func(t *testing.T) {
+		updates := map[string]interface{}{
+			"name":          "Updated Name",
+			"name; DROP --": "x",
+		}
+
+		user, err := repo.UpdateFields(context.Background(), 1, updates)
+		require.Error(t, err)
+		assert.Nil(t, user)
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+		assert.Equal(t, "name; DROP --", GetColumnName(err))
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateFields rejects primary key", func(t *testing.T) {
+		user, err := repo.UpdateFields(context.Background(), 1, map[string]interface{}{"id": 2})
+		require.Error(t, err)
+		assert.Nil(t, user)
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpsertRejectsUnknownColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	user := &TestUser{Name: "John", Email: "john@example.com"}
+
+	err = repo.Upsert(context.Background(), user, UpsertOptions{ConflictColumns: []string{"mail"}})
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+
+	err = repo.Upsert(context.Background(), user, UpsertOptions{
+		ConflictColumns: []string{"email"},
+		UpdateExpr:      map[string]string{"nmae": "EXCLUDED.name"},
+	})
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+
+	err = repo.UpsertMany(context.Background(), []TestUser{*user}, UpsertOptions{
+		ConflictColumns: []string{"email"},
+		UpdateColumns:   []string{"unknown"},
+	})
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 // TestCreateMany tests the CreateMany operation
@@ -217,6 +337,32 @@ func TestUpsert(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("Upsert with a partial index target and a conditional update", func(t *testing.T) {
+		opts := UpsertOptions{
+			ConflictColumns: []string{"email"},
+			ConflictWhere:   "deleted_at IS NULL",
+			UpdateColumns:   []string{"name"},
+			UpdateWhere:     "EXCLUDED.updated_at > users.updated_at",
+		}
+
+		mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (email) WHERE deleted_at IS NULL DO UPDATE SET name = EXCLUDED.name WHERE EXCLUDED.updated_at > users.updated_at`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Upsert(context.Background(), &TestUser{Name: "John Doe", Email: "john@example.com"}, opts)
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateWhere is dropped when nothing is updated", func(t *testing.T) {
+		clause := repo.onConflictClause([]string{"email"}, UpsertOptions{
+			ConflictColumns: []string{"email"},
+			UpdateWhere:     "EXCLUDED.updated_at > users.updated_at",
+		})
+		assert.Equal(t, " ON CONFLICT (email) DO NOTHING", clause)
+	})
 }
 
 // TestUpsertMany tests the UpsertMany operation
@@ -293,8 +439,8 @@ func TestUpdateFields(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"}).
 				AddRow(userID, "Old Name", "old@example.com", true, now, now))
 
-		mock.ExpectExec(`UPDATE users SET`).
-			WithArgs("Updated Name", false, userID).
+		mock.ExpectExec(`UPDATE users SET is_active = \$1, name = \$2 WHERE id = \$3`).
+			WithArgs(false, "Updated Name", userID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		mock.ExpectQuery(`SELECT .* FROM users WHERE id = \$1`).
@@ -367,7 +513,204 @@ func TestUpdateFields(t *testing.T) {
 	})
 }
 
+func TestUpdateMany(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	metadata := createTestUserMetadata()
+	metadata.Columns["ID"].DBType = "serial"
+	metadata.Columns["IsActive"].DBType = "boolean"
+
+	repo, err := NewRepository[TestUser](sqlxDB, metadata)
+	require.NoError(t, err)
+
+	t.Run("UpdateMany uses a single statement", func(t *testing.T) {
+		users := []TestUser{
+			{ID: 1, Name: "User1", Email: "user1@example.com", IsActive: true},
+			{ID: 2, Name: "User2", Email: "user2@example.com", IsActive: false},
+		}
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users AS t SET email = v.email, is_active = v.is_active, name = v.name `+
+			`FROM (VALUES ($1::integer, $2, $3::boolean, $4), ($5::integer, $6, $7::boolean, $8)) AS v(id, email, is_active, name) `+
+			`WHERE t.id = v.id`)).
+			WithArgs(1, "user1@example.com", true, "User1", 2, "user2@example.com", false, "User2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		updated, err := repo.UpdateMany(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), updated)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateMany keeps the last record for a repeated key", func(t *testing.T) {
+		users := []TestUser{
+			{ID: 1, Name: "First", Email: "first@example.com"},
+			{ID: 1, Name: "Last", Email: "last@example.com"},
+		}
+
+		mock.ExpectExec(`UPDATE users AS t SET .* FROM \(VALUES \(\$1::integer, \$2, \$3::boolean, \$4\)\) AS v`).
+			WithArgs(1, "last@example.com", false, "Last").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		updated, err := repo.UpdateMany(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), updated)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateMany with empty slice", func(t *testing.T) {
+		updated, err := repo.UpdateMany(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Zero(t, updated)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateMany reports database errors", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users AS t`).WillReturnError(assert.AnError)
+
+		_, err := repo.UpdateMany(context.Background(), []TestUser{{ID: 1, Name: "User1"}})
+		assert.Error(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 // TestQueryUpdate tests the Query.Update operation
+func TestNowOnUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+	repo = repo.NowOnUpdate("updated_at")
+
+	t.Run("Update", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET .*updated_at = NOW\(\) WHERE id = \$4`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := repo.Update(context.Background(), &TestUser{ID: 1, Name: "User1"})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query Update", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = $1, updated_at = NOW() WHERE (users.id = $2)`)).
+			WithArgs("Renamed", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		nameCol := Column[string]{Name: "name", Table: "users"}
+		idCol := Column[int]{Name: "id", Table: "users"}
+		_, err := repo.Query(context.Background()).Where(idCol.Eq(1)).Update(nameCol.Set("Renamed"))
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("explicit assignment wins", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET updated_at = $1`) + "$").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		updatedAt := Column[time.Time]{Name: "updated_at", Table: "users"}
+		_, err := repo.Query(context.Background()).Update(updatedAt.Set(time.Now()))
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query Update surfaces action errors", func(t *testing.T) {
+		metaCol := JSONBColumn{Column: Column[interface{}]{Name: "metadata", Table: "users"}}
+		_, err := repo.Query(context.Background()).Update(JSONBSet(metaCol, "bad", make(chan int)))
+		assert.ErrorContains(t, err, "failed to encode value for bad")
+	})
+}
+
+func TestAutoTimestamps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Columns["CreatedAt"].IsAutoGenerated = false
+	metadata.Columns["CreatedAt"].AutoCreateTime = true
+	metadata.Columns["UpdatedAt"].IsAutoGenerated = false
+	metadata.Columns["UpdatedAt"].AutoUpdateTime = true
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Create sets unset timestamps", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (created_at,email,is_active,name,updated_at) VALUES (NOW(),$1,$2,$3,NOW()) RETURNING created_at, id, updated_at`)).
+			WithArgs("ada@example.com", true, "Ada").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+
+		user := &TestUser{Name: "Ada", Email: "ada@example.com", IsActive: true}
+		_, err := repo.Create(context.Background(), user)
+		require.NoError(t, err)
+		assert.Equal(t, now, user.CreatedAt)
+		assert.Equal(t, now, user.UpdatedAt)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Create keeps explicit timestamps", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`VALUES ($1,$2,$3,$4,NOW())`)).
+			WithArgs(now, "ada@example.com", true, "Ada").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+
+		user := &TestUser{Name: "Ada", Email: "ada@example.com", IsActive: true, CreatedAt: now}
+		_, err := repo.Create(context.Background(), user)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update refreshes updated_at", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET email = $1, is_active = $2, name = $3, updated_at = NOW() WHERE id = $4 RETURNING updated_at`)).
+			WithArgs("ada@example.com", true, "Ada", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+
+		user := &TestUser{ID: 1, Name: "Ada", Email: "ada@example.com", IsActive: true}
+		_, err := repo.Update(context.Background(), user)
+		require.NoError(t, err)
+		assert.Equal(t, now, user.UpdatedAt)
+		assert.True(t, user.CreatedAt.IsZero())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update of a missing row", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE users SET .* RETURNING updated_at`).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}))
+
+		_, err := repo.Update(context.Background(), &TestUser{ID: 9})
+		assert.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query Update", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = $1, updated_at = NOW() WHERE (users.id = $2)`)).
+			WithArgs("Renamed", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		nameCol := Column[string]{Name: "name", Table: "users"}
+		idCol := Column[int]{Name: "id", Table: "users"}
+		_, err := repo.Query(context.Background()).Where(idCol.Eq(1)).Update(nameCol.Set("Renamed"))
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Upsert leaves created_at alone", func(t *testing.T) {
+		mock.ExpectExec(`ON CONFLICT \(email\) DO UPDATE SET is_active = EXCLUDED.is_active, name = EXCLUDED.name, updated_at = EXCLUDED.updated_at$`).
+			WithArgs("ada@example.com", true, "Ada").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := &TestUser{Name: "Ada", Email: "ada@example.com", IsActive: true}
+		require.NoError(t, repo.Upsert(context.Background(), user, UpsertOptions{ConflictColumns: []string{"email"}}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestQueryUpdate(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -464,3 +807,181 @@ func TestQueryUpdate(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestImmutableColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Columns["Email"].IsImmutable = true
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	t.Run("Update skips immutable columns", func(t *testing.T) {
+		fields := repo.getUpdateFields(TestUser{ID: 1, Name: "John", Email: "john@example.com"})
+		assert.NotContains(t, fields, "email")
+		assert.Contains(t, fields, "name")
+	})
+
+	t.Run("UpdateFields rejects immutable columns", func(t *testing.T) {
+		_, err := repo.UpdateFields(context.Background(), 1, map[string]interface{}{"email": "new@example.com"})
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+	})
+
+	t.Run("Query Update rejects immutable columns", func(t *testing.T) {
+		emailCol := Column[string]{Name: "email", Table: "users"}
+		_, err := repo.Query(context.Background()).Update(emailCol.Set("new@example.com"))
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+
+		_, err = repo.Query(context.Background()).Update(StringColumn{Column: emailCol}.SetNull())
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+
+		aliased := Column[string]{Name: "email", Table: "u"}
+		_, err = repo.Query(context.Background()).As("u").Update(aliased.SetDefault())
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+	})
+
+	t.Run("Upsert rejects immutable update columns", func(t *testing.T) {
+		err := repo.Upsert(context.Background(), &TestUser{Name: "John", Email: "john@example.com"}, UpsertOptions{
+			ConflictColumns: []string{"id"},
+			UpdateColumns:   []string{"email"},
+		})
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComputedColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Columns["Name"].Computed = "upper(email)"
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	t.Run("Insert and update skip computed columns", func(t *testing.T) {
+		user := TestUser{ID: 1, Name: "John", Email: "john@example.com"}
+		columns, _ := repo.getInsertFields(user)
+		assert.NotContains(t, columns, "name")
+		assert.NotContains(t, repo.getUpdateFields(user), "name")
+	})
+
+	t.Run("FindByID selects the computed expression", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(`SELECT .*\(upper\(email\)\) AS name.* FROM users WHERE id = \$1`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"}).
+				AddRow(1, "JOHN@EXAMPLE.COM", "john@example.com", true, now, now))
+
+		user, err := repo.FindByID(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, "JOHN@EXAMPLE.COM", user.Name)
+	})
+
+	t.Run("UpdateFields rejects computed columns", func(t *testing.T) {
+		_, err := repo.UpdateFields(context.Background(), 1, map[string]interface{}{"name": "Jane"})
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+	})
+
+	t.Run("Query Update rejects computed columns", func(t *testing.T) {
+		nameCol := Column[string]{Name: "name", Table: "users"}
+		_, err := repo.Query(context.Background()).Update(nameCol.Set("Jane"))
+		assert.ErrorIs(t, err, ErrImmutableColumn)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+type validatedUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func (u *validatedUser) Validate() error {
+	if u.Name == "" {
+		return ValidationErrors{{Field: "name", Message: "is required"}}
+	}
+	return nil
+}
+
+// TestValidatorHook tests that models implementing Validator are checked before writing
+func TestValidatorHook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := &ModelMetadata{
+		TableName:  "users",
+		StructName: "validatedUser",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:       "ID",
+				DBName:          "id",
+				GoType:          "int",
+				IsPrimaryKey:    true,
+				IsAutoGenerated: true,
+				GetValue: func(model interface{}) interface{} {
+					return model.(validatedUser).ID
+				},
+			},
+			"Name": {
+				FieldName: "Name",
+				DBName:    "name",
+				GoType:    "string",
+				GetValue: func(model interface{}) interface{} {
+					return model.(validatedUser).Name
+				},
+			},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "Name": "name"},
+		ReverseMap:  map[string]string{"id": "ID", "name": "Name"},
+		PrimaryKeys: []string{"id"},
+	}
+
+	repo, err := NewRepository[validatedUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	assertInvalid := func(t *testing.T, err error) {
+		var validationErrs ValidationErrors
+		require.ErrorAs(t, err, &validationErrs)
+		assert.Equal(t, "name", validationErrs[0].Field)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+
+	t.Run("Create rejects invalid record", func(t *testing.T) {
+		_, err := repo.Create(context.Background(), &validatedUser{})
+		assertInvalid(t, err)
+	})
+
+	t.Run("Update rejects invalid record", func(t *testing.T) {
+		_, err := repo.Update(context.Background(), &validatedUser{ID: 1})
+		assertInvalid(t, err)
+	})
+
+	t.Run("CreateMany rejects any invalid record", func(t *testing.T) {
+		err := repo.CreateMany(context.Background(), []validatedUser{{Name: "ok"}, {}})
+		assertInvalid(t, err)
+	})
+
+	t.Run("Upsert rejects invalid record", func(t *testing.T) {
+		err := repo.Upsert(context.Background(), &validatedUser{}, UpsertOptions{ConflictColumns: []string{"id"}})
+		assertInvalid(t, err)
+	})
+
+	t.Run("Create writes valid record", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO users \(name\) VALUES \(\$1\) RETURNING id`).
+			WithArgs("john").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		created, err := repo.Create(context.Background(), &validatedUser{Name: "john"})
+		require.NoError(t, err)
+		assert.Equal(t, 7, created.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,61 @@
+package orm
+
+// Page is a page of records along with the number of records matching the
+// query, as returned by FindPage and generated list handlers
+type Page[T any] struct {
+	Data   []T    `json:"data"`
+	Total  int64  `json:"total"`
+	Limit  uint64 `json:"limit"`
+	Offset uint64 `json:"offset"`
+}
+
+// HasNext reports whether records follow this page
+func (p Page[T]) HasNext() bool {
+	return p.Total > 0 && p.Offset+uint64(len(p.Data)) < uint64(p.Total)
+}
+
+// HasPrev reports whether records precede this page
+func (p Page[T]) HasPrev() bool {
+	return p.Offset > 0 && p.Total > 0
+}
+
+// MapPage converts the records of page with convert, keeping its counts, e.g.
+// to write a page of records through their API representation
+func MapPage[T, R any](page Page[T], convert func(*T) R) Page[R] {
+	mapped := Page[R]{Data: make([]R, len(page.Data)), Total: page.Total, Limit: page.Limit, Offset: page.Offset}
+	for i := range page.Data {
+		mapped.Data[i] = convert(&page.Data[i])
+	}
+	return mapped
+}
+
+// FindPage runs the query along with a count of every record it matches,
+// ignoring its limit and offset. The count runs first, and the records are
+// only fetched when the page starts before the end of the results. Data is
+// empty rather than nil when the page has no records.
+func (q *Query[T]) FindPage() (Page[T], error) {
+	total, err := q.Count()
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	page := Page[T]{Data: []T{}, Total: total}
+	if q.limit != nil {
+		page.Limit = *q.limit
+	}
+	if q.offset != nil {
+		page.Offset = *q.offset
+	}
+	if total == 0 || page.Offset >= uint64(total) {
+		return page, nil
+	}
+
+	records, err := q.Find()
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if records != nil {
+		page.Data = records
+	}
+	return page, nil
+}
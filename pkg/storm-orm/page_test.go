@@ -0,0 +1,88 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFindPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("counts then fetches the page", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE (users.is_active = $1)")).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE (users.is_active = $1) ORDER BY users.id LIMIT 2 OFFSET 2")).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Cy").AddRow(4, "Di"))
+
+		page, err := repo.Query(context.Background()).
+			Where(Column[bool]{Name: "is_active", Table: "users"}.Eq(true)).
+			OrderBy("users.id").
+			Limit(2).
+			Offset(2).
+			FindPage()
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), page.Total)
+		assert.Equal(t, uint64(2), page.Limit)
+		assert.Equal(t, uint64(2), page.Offset)
+		require.Len(t, page.Data, 2)
+		assert.Equal(t, "Cy", page.Data[0].Name)
+		assert.True(t, page.HasNext())
+		assert.True(t, page.HasPrev())
+	})
+
+	t.Run("skips the fetch past the end", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		page, err := repo.Query(context.Background()).Limit(10).Offset(10).FindPage()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), page.Total)
+		assert.NotNil(t, page.Data)
+		assert.Empty(t, page.Data)
+		assert.False(t, page.HasNext())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPageNavigation(t *testing.T) {
+	tests := []struct {
+		name       string
+		page       Page[int]
+		next, prev bool
+	}{
+		{"first of several", Page[int]{Data: []int{1, 2}, Total: 5, Limit: 2}, true, false},
+		{"last", Page[int]{Data: []int{5}, Total: 5, Limit: 2, Offset: 4}, false, true},
+		{"only", Page[int]{Data: []int{1}, Total: 1, Limit: 2}, false, false},
+		{"empty", Page[int]{Data: []int{}, Limit: 2, Offset: 4}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.next, tt.page.HasNext())
+			assert.Equal(t, tt.prev, tt.page.HasPrev())
+		})
+	}
+}
+
+func TestMapPage(t *testing.T) {
+	page := Page[int]{Data: []int{1, 2}, Total: 5, Limit: 2, Offset: 2}
+	mapped := MapPage(page, func(n *int) string { return strconv.Itoa(*n * 10) })
+	assert.Equal(t, Page[string]{Data: []string{"10", "20"}, Total: 5, Limit: 2, Offset: 2}, mapped)
+
+	empty := MapPage(Page[int]{Data: []int{}}, func(n *int) string { return "" })
+	assert.NotNil(t, empty.Data)
+}
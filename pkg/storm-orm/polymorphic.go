@@ -0,0 +1,38 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// isPolymorphic reports whether the relationship is stored as a type + id pair
+func (rel *RelationshipMetadata) isPolymorphic() bool {
+	return rel.PolymorphicType != ""
+}
+
+// polymorphicCondition restricts the type column to the relationship's owner model
+func polymorphicCondition(rel *RelationshipMetadata) squirrel.Sqlizer {
+	return squirrel.Eq{quoteIdent(rel.PolymorphicType): rel.PolymorphicValue}
+}
+
+// polymorphicJoinCondition renders the type check for use in a JOIN clause
+func polymorphicJoinCondition(rel *RelationshipMetadata, table string) string {
+	return fmt.Sprintf(" AND %s.%s = '%s'", quoteIdent(table), quoteIdent(rel.PolymorphicType), strings.ReplaceAll(rel.PolymorphicValue, "'", "''"))
+}
+
+// matchesPolymorphicType reports whether a polymorphic belongs_to on record
+// points at the relationship's target model
+func (r *Repository[T]) matchesPolymorphicType(rel *RelationshipMetadata, record T) (bool, error) {
+	col := r.columnByDBName(rel.PolymorphicType)
+	if col == nil {
+		return false, fmt.Errorf("polymorphic type column %s not found", rel.PolymorphicType)
+	}
+
+	value := col.GetValue(record)
+	if value == nil {
+		return false, nil
+	}
+	return fmt.Sprint(value) == rel.PolymorphicValue, nil
+}
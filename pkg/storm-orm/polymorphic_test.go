@@ -0,0 +1,127 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testComment struct {
+	ID              int    `db:"id"`
+	CommentableID   int    `db:"commentable_id"`
+	CommentableType string `db:"commentable_type"`
+}
+
+func createTestCommentMetadata() *ModelMetadata {
+	return &ModelMetadata{
+		TableName:  "comments",
+		StructName: "Comment",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:    "ID",
+				DBName:       "id",
+				IsPrimaryKey: true,
+				GetValue:     func(model interface{}) interface{} { return model.(testComment).ID },
+			},
+			"CommentableID": {
+				FieldName: "CommentableID",
+				DBName:    "commentable_id",
+				GetValue:  func(model interface{}) interface{} { return model.(testComment).CommentableID },
+			},
+			"CommentableType": {
+				FieldName: "CommentableType",
+				DBName:    "commentable_type",
+				GetValue:  func(model interface{}) interface{} { return model.(testComment).CommentableType },
+			},
+		},
+		ReverseMap: map[string]string{
+			"id":               "ID",
+			"commentable_id":   "CommentableID",
+			"commentable_type": "CommentableType",
+		},
+		PrimaryKeys: []string{"id"},
+		Relationships: map[string]*RelationshipMetadata{
+			"Post": {
+				Name:             "Post",
+				Type:             "belongs_to",
+				Target:           "Post",
+				TargetTable:      "posts",
+				ForeignKey:       "commentable_id",
+				TargetKey:        "id",
+				PolymorphicType:  "commentable_type",
+				PolymorphicValue: "Post",
+			},
+		},
+	}
+}
+
+func TestPolymorphicHasMany(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Comments": {
+			Name:             "Comments",
+			Type:             "has_many",
+			Target:           "comments",
+			TargetTable:      "comments",
+			ForeignKey:       "commentable_id",
+			SourceKey:        "id",
+			PolymorphicType:  "commentable_type",
+			PolymorphicValue: "TestUser",
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	t.Run("Include filters on the type column", func(t *testing.T) {
+		q := repo.Query(context.Background())
+		sql, args, err := q.buildRelationshipQuery(metadata.Relationships["Comments"], []TestUser{{ID: 7}}, include{name: "Comments"})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM comments WHERE commentable_id IN ($1) AND commentable_type = $2", sql)
+		assert.Equal(t, []interface{}{7, "TestUser"}, args)
+	})
+
+	t.Run("JoinRelationship adds the type condition", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).JoinRelationship("Comments", InnerJoin).buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "users.id = comments.commentable_id AND comments.commentable_type = 'TestUser'")
+	})
+}
+
+func TestPolymorphicBelongsTo(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestCommentMetadata()
+	repo, err := NewRepository[testComment](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	rel := metadata.Relationships["Post"]
+
+	t.Run("loads the owner when the type matches", func(t *testing.T) {
+		q := repo.Query(context.Background())
+		sql, args, err := q.buildRelationshipQuery(rel, []testComment{
+			{ID: 1, CommentableID: 3, CommentableType: "Post"},
+			{ID: 2, CommentableID: 4, CommentableType: "Photo"},
+		}, include{name: "Post"})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM posts WHERE id IN ($1)", sql)
+		assert.Equal(t, []interface{}{3}, args)
+	})
+
+	t.Run("skips owners of another type", func(t *testing.T) {
+		q := repo.Query(context.Background())
+		sql, _, err := q.buildRelationshipQuery(rel, []testComment{{ID: 1, CommentableID: 3, CommentableType: "Photo"}}, include{name: "Post"})
+		require.NoError(t, err)
+		assert.Empty(t, sql)
+	})
+}
@@ -0,0 +1,98 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ProcedureCall is a CALL of a stored procedure, built by Storm.CallProcedure
+// and run by its Exec or Scan method
+type ProcedureCall struct {
+	storm              *Storm
+	ctx                context.Context
+	name               string
+	args               []interface{}
+	outsideTransaction bool
+}
+
+// CallProcedure calls the stored procedure name, optionally schema-qualified,
+// with args bound in order. Procedures need PostgreSQL 11 or later; pass nil
+// for OUT arguments, which PostgreSQL 14 added.
+//
+//	err := s.CallProcedure(ctx, "archive_posts", cutoff).Exec()
+func (s *Storm) CallProcedure(ctx context.Context, name string, args ...interface{}) *ProcedureCall {
+	return &ProcedureCall{storm: s, ctx: ctx, name: name, args: args}
+}
+
+// OutsideTransaction runs the call on its own connection instead of the
+// Storm's transaction. Procedures that COMMIT or ROLLBACK fail inside a
+// transaction block, so call them this way from WithTransaction; their work
+// is not undone when the surrounding transaction rolls back.
+func (c *ProcedureCall) OutsideTransaction() *ProcedureCall {
+	call := *c
+	call.outsideTransaction = true
+	return &call
+}
+
+// Exec calls the procedure
+func (c *ProcedureCall) Exec() error {
+	executor := c.executor()
+	return c.run(nil, func(middlewareCtx *MiddlewareContext) error {
+		if _, err := executor.ExecContext(c.ctx, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+			return parsePostgreSQLError(err, c.name, "")
+		}
+		return nil
+	})
+}
+
+// Scan calls the procedure and reads the values of its INOUT and OUT
+// arguments into dest: a pointer to a scalar for a single argument, or to a
+// struct whose db tags name them
+func (c *ProcedureCall) Scan(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return &Error{Op: c.name, Err: fmt.Errorf("destination must be a non-nil pointer, got %T", dest)}
+	}
+
+	executor := c.executor()
+	return c.run(dest, func(middlewareCtx *MiddlewareContext) error {
+		if err := executor.GetContext(c.ctx, dest, middlewareCtx.Query, middlewareCtx.Args...); err != nil {
+			return parsePostgreSQLError(err, c.name, "")
+		}
+		return nil
+	})
+}
+
+// SQL returns the statement the call runs
+func (c *ProcedureCall) SQL() string {
+	placeholders := make([]string, len(c.args))
+	for i := range c.args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("CALL %s(%s)", c.name, strings.Join(placeholders, ", "))
+}
+
+// executor returns the connection the call runs on
+func (c *ProcedureCall) executor() DBExecutor {
+	if !c.outsideTransaction || !c.storm.isInTransaction() {
+		return c.storm.executor
+	}
+	if c.storm.logger != nil {
+		return &loggingExecutor{executor: c.storm.db, logger: c.storm.logger}
+	}
+	return c.storm.db
+}
+
+// run validates the procedure name and runs finalFunc through the Storm's
+// middleware as OpProcedureCall
+func (c *ProcedureCall) run(result interface{}, finalFunc QueryMiddlewareFunc) error {
+	if err := c.ctx.Err(); err != nil {
+		return &Error{Op: c.name, Err: err}
+	}
+	if !functionName.MatchString(c.name) {
+		return &Error{Op: c.name, Err: fmt.Errorf("invalid procedure name %q", c.name)}
+	}
+	return c.storm.executeStatement(c.ctx, OpProcedureCall, c.name, c.SQL(), c.args, result, finalFunc)
+}
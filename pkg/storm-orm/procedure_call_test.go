@@ -0,0 +1,68 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStormCallProcedure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	var seen []*MiddlewareContext
+	s.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			err := next(ctx)
+			seen = append(seen, ctx)
+			return err
+		}
+	})
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta("CALL archive_posts($1)")).
+		WithArgs("2024-01-01").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, s.CallProcedure(ctx, "archive_posts", "2024-01-01").Exec())
+
+	mock.ExpectQuery(regexp.QuoteMeta("CALL billing.close_period($1, $2)")).
+		WithArgs(7, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"closed"}).AddRow(12))
+	var closed int
+	require.NoError(t, s.CallProcedure(ctx, "billing.close_period", 7, nil).Scan(&closed))
+	assert.Equal(t, 12, closed)
+
+	require.Len(t, seen, 2)
+	assert.Equal(t, OpProcedureCall, seen[1].Operation)
+	assert.Equal(t, "billing.close_period", seen[1].QueryName)
+	assert.Equal(t, &closed, seen[1].Result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("outside a transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("CALL refresh_stats()")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := s.WithTransaction(ctx, func(tx *Storm) error {
+			call := tx.CallProcedure(ctx, "refresh_stats")
+			assert.Equal(t, tx.executor, call.executor())
+			assert.Equal(t, tx.db, call.OutsideTransaction().executor())
+			return call.OutsideTransaction().Exec()
+		})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects invalid calls", func(t *testing.T) {
+		assert.ErrorContains(t, s.CallProcedure(ctx, "archive(); COMMIT").Exec(), "invalid procedure name")
+		assert.ErrorContains(t, s.CallProcedure(ctx, "close_period", 7).Scan(closed), "non-nil pointer")
+	})
+}
@@ -2,11 +2,12 @@ package orm
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"reflect"
 	"strings"
+	"time"
 )
 
 // Query provides a fluent interface for building database queries with all features integrated
@@ -21,25 +22,39 @@ type Query[T any] struct {
 	offset      *uint64
 	orderBy     []string
 	whereClause squirrel.And
+	alias       string
 
 	// Transaction support
 	tx *sqlx.Tx
 
 	// Join support
-	joins    []join
-	includes []include
+	joins           []join
+	joinsMany       bool // A join matches many rows of a joined table per row
+	includes        []include
+	includeStrategy IncludeStrategy
+	counts          []string
+	ancestry        squirrel.Sqlizer // Join ordering the rows of Ancestors by depth
+
+	// CockroachDB historical reads
+	asOfSystemTime string
+
+	// Execution timeout
+	timeout time.Duration
+
+	// Default scope state
+	unscoped      bool
+	defaultScoped bool
 }
 
 func (r *Repository[T]) Query(ctx context.Context) *Query[T] {
 	query := &Query[T]{
-		repo: r,
-		builder: squirrel.Select(r.Columns()...).
-			From(r.metadata.TableName).
-			PlaceholderFormat(squirrel.Dollar),
+		repo:        r,
+		builder:     r.metadata.derived().selectBuilder,
 		ctx:         ctx,
 		whereClause: squirrel.And{},
 		joins:       make([]join, 0),
 		includes:    make([]include, 0),
+		timeout:     r.timeout,
 	}
 
 	for _, authFunc := range r.authorizeFuncs {
@@ -58,7 +73,7 @@ func (q *Query[T]) Where(condition Condition) *Query[T] {
 	if q.err != nil {
 		return q
 	}
-	q.whereClause = append(q.whereClause, condition.ToSqlizer())
+	q.whereClause = append(q.whereClause, condition.sqlizer(q.repo.options))
 	return q
 }
 
@@ -86,6 +101,51 @@ func (q *Query[T]) Offset(offset uint64) *Query[T] {
 	return q
 }
 
+// As aliases the query's table, so self-joins and joins with clashing column
+// names can be written. Conditions on the table must then use the alias, as
+// the generated columns' As method does.
+func (q *Query[T]) As(alias string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.alias = alias
+	q.qualifyColumns()
+	return q
+}
+
+// qualifyColumns selects the query's columns qualified by tableRef, which
+// aliases and joined tables with the same column names require
+func (q *Query[T]) qualifyColumns() {
+	d := q.repo.metadata.derived()
+	columns := make([]string, len(d.columns))
+	for i, col := range d.columns {
+		columns[i] = d.selectColumns[i]
+		if col.Computed == "" {
+			columns[i] = quoteIdent(q.tableRef() + "." + col.DBName)
+		}
+	}
+	q.builder = squirrel.Select(columns...).
+		From(q.source()).
+		PlaceholderFormat(squirrel.Dollar)
+}
+
+// tableRef returns the name that qualifies the query's columns: its alias, or
+// the table name when it has none
+func (q *Query[T]) tableRef() string {
+	if q.alias != "" {
+		return q.alias
+	}
+	return q.repo.metadata.TableName
+}
+
+// source returns the query's table as written in FROM, with its alias
+func (q *Query[T]) source() string {
+	if q.alias != "" {
+		return q.repo.metadata.relation(q.alias)
+	}
+	return q.repo.metadata.derived().from
+}
+
 func (q *Query[T]) Join(joinType JoinType, table, condition string) *Query[T] {
 	if q.err != nil {
 		return q
@@ -95,6 +155,7 @@ func (q *Query[T]) Join(joinType JoinType, table, condition string) *Query[T] {
 		Table:     table,
 		Condition: condition,
 	})
+	q.qualifyColumns()
 	return q
 }
 
@@ -131,35 +192,103 @@ func (q *Query[T]) IncludeWhere(relationship string, conditions ...Condition) *Q
 	if q.err != nil {
 		return q
 	}
+	// Render the conditions with this query's options, as the included
+	// rows are loaded by the same Storm
+	bound := make([]Condition, len(conditions))
+	for i, condition := range conditions {
+		bound[i] = Condition{condition.sqlizer(q.repo.options)}
+	}
 	q.includes = append(q.includes, include{
 		name:       relationship,
-		conditions: conditions,
+		conditions: bound,
 	})
 	return q
 }
 
-func (q *Query[T]) buildQuery() (string, []interface{}, error) {
-	if q.err != nil {
-		return "", nil, q.err
+// applyJoins adds the query's joins and any AS OF SYSTEM TIME clause to the builder
+func (q *Query[T]) applyJoins(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	asOf := q.asOfSystemTimeClause()
+	if asOf != "" && len(q.joins) == 0 {
+		return builder.From(q.source() + " " + asOf)
 	}
 
-	builder := q.builder
+	for i, join := range q.joins {
+		clause := join.Condition
+		if join.Type != "" {
+			clause = fmt.Sprintf("%s ON %s", join.source(), join.Condition)
+		}
+		if asOf != "" && i == len(q.joins)-1 {
+			clause += " " + asOf
+		}
 
-	for _, join := range q.joins {
 		switch join.Type {
 		case InnerJoin:
-			builder = builder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
+			builder = builder.InnerJoin(clause, join.Args...)
 		case LeftJoin:
-			builder = builder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
+			builder = builder.LeftJoin(clause, join.Args...)
 		case RightJoin:
-			builder = builder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
+			builder = builder.RightJoin(clause, join.Args...)
 		case FullJoin:
-			builder = builder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition))
+			builder = builder.Join("FULL OUTER JOIN "+clause, join.Args...)
+		default:
+			builder = builder.JoinClause(clause, join.Args...)
 		}
 	}
 
-	if len(q.whereClause) > 0 {
-		builder = builder.Where(q.whereClause)
+	return builder
+}
+
+// buildWhere returns the conditions that select the query's rows, or nil when
+// it has none. Find, Count, Delete and Update all filter through it.
+func (q *Query[T]) buildWhere() squirrel.Sqlizer {
+	if len(q.whereClause) == 0 {
+		return nil
+	}
+	return q.whereClause
+}
+
+// applyFilters adds the query's joins and conditions to builder. A join to
+// many rows would repeat the query's rows once per joined row, so with one the
+// joins and conditions move into a filter on the primary key instead.
+func (q *Query[T]) applyFilters(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if !q.joinsMany {
+		return q.applyJoinedFilters(builder)
+	}
+	if asOf := q.asOfSystemTimeClause(); asOf != "" {
+		builder = builder.From(q.source() + " " + asOf)
+	}
+	return builder.Where(joinedRows[T]{query: q})
+}
+
+// joinedRows matches, by primary key, the rows of the query's table that its
+// joins and conditions select
+type joinedRows[T any] struct {
+	query *Query[T]
+}
+
+func (j joinedRows[T]) ToSql() (string, []interface{}, error) {
+	filter, err := j.query.joinedRowFilter("find", j.query.tableRef())
+	if err != nil {
+		return "", nil, err
+	}
+	return filter.ToSql()
+}
+
+// applyJoinedFilters adds the query's joins and conditions to builder as they
+// are
+func (q *Query[T]) applyJoinedFilters(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	builder = q.applyJoins(builder)
+	if where := q.buildWhere(); where != nil {
+		builder = builder.Where(where)
+	}
+	return builder
+}
+
+// selectBuilder returns the SELECT Find runs
+func (q *Query[T]) selectBuilder() squirrel.SelectBuilder {
+	builder := q.applyFilters(q.builder)
+	if q.ancestry != nil {
+		builder = builder.JoinClause(q.ancestry)
 	}
 
 	for _, orderBy := range q.orderBy {
@@ -174,52 +303,102 @@ func (q *Query[T]) buildQuery() (string, []interface{}, error) {
 		builder = builder.Offset(*q.offset)
 	}
 
-	baseSQL, baseArgs, err := builder.ToSql()
-	if err != nil {
-		return "", nil, err
+	return builder
+}
+
+// mutationFilter returns the tables a DELETE ... USING or UPDATE ... FROM
+// adds for the query's joins and the condition restricting it to the query's
+// rows. Inner joins become extra tables with their conditions in the filter;
+// outer and raw joins, which neither statement can express, restrict the rows
+// through joinedRowFilter instead.
+func (q *Query[T]) mutationFilter(op string) (string, squirrel.Sqlizer, error) {
+	if len(q.joins) == 0 {
+		return "", q.buildWhere(), nil
 	}
 
-	return baseSQL, baseArgs, nil
+	tables := make([]string, 0, len(q.joins))
+	where := make(squirrel.And, 0, len(q.joins)+len(q.whereClause))
+	for _, join := range q.joins {
+		if join.Type != InnerJoin {
+			filter, err := q.joinedRowFilter(op, q.tableRef())
+			return "", filter, err
+		}
+		tables = append(tables, join.source())
+		where = append(where, squirrel.Expr(join.Condition, join.Args...))
+	}
+	where = append(where, q.whereClause...)
+
+	return strings.Join(tables, ", "), where, nil
 }
 
-func (q *Query[T]) Find() ([]T, error) {
-	if len(q.includes) > 0 {
-		return q.findWithRelationships()
+// joinedRowFilter returns a condition matching, by primary key, the rows of
+// the query's table that its joins and conditions select. The keys are
+// qualified by outer, the name the enclosing statement knows the table by.
+func (q *Query[T]) joinedRowFilter(op, outer string) (squirrel.Sqlizer, error) {
+	primaryKeys := q.repo.metadata.PrimaryKeys
+	if len(primaryKeys) == 0 {
+		return nil, &Error{
+			Op:    op,
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("joined filters require a primary key"),
+		}
 	}
 
-	finalBuilder := q.builder
+	columns := make([]string, len(primaryKeys))
+	keys := make([]string, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		columns[i] = quoteIdent(q.tableRef() + "." + pk)
+		keys[i] = quoteIdent(outer + "." + pk)
+	}
 
-	for _, join := range q.joins {
-		switch join.Type {
-		case InnerJoin:
-			finalBuilder = finalBuilder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case LeftJoin:
-			finalBuilder = finalBuilder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case RightJoin:
-			finalBuilder = finalBuilder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case FullJoin:
-			finalBuilder = finalBuilder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition))
+	rowsSQL, rowsArgs, err := q.applyJoinedFilters(squirrel.Select(columns...).From(q.source())).ToSql()
+	if err != nil {
+		return nil, &Error{
+			Op:    op,
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("failed to build where clause: %w", err),
 		}
 	}
 
-	if len(q.whereClause) > 0 {
-		finalBuilder = finalBuilder.Where(q.whereClause)
+	key := keys[0]
+	if len(keys) > 1 {
+		key = "(" + strings.Join(keys, ", ") + ")"
 	}
+	return squirrel.Expr(fmt.Sprintf("%s IN (%s)", key, rowsSQL), rowsArgs...), nil
+}
 
-	for _, orderBy := range q.orderBy {
-		finalBuilder = finalBuilder.OrderBy(orderBy)
+func (q *Query[T]) buildQuery() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
 	}
 
-	if q.limit != nil {
-		finalBuilder = finalBuilder.Limit(*q.limit)
+	return q.selectBuilder().ToSql()
+}
+
+func (q *Query[T]) Find() ([]T, error) {
+	q.applyDefaultScopes()
+
+	if q.err != nil {
+		return nil, q.err
 	}
 
-	if q.offset != nil {
-		finalBuilder = finalBuilder.Offset(*q.offset)
+	defer q.withTimeout()()
+
+	if len(q.counts) > 0 {
+		return q.findWithCounts()
+	}
+
+	if len(q.includes) > 0 {
+		if q.includeStrategy == JoinJSON {
+			return q.findWithJSONIncludes()
+		}
+		return q.findWithRelationships()
 	}
 
+	finalBuilder := q.selectBuilder()
+
 	var records []T
-	err := q.repo.executeQueryMiddleware(OpQuery, q.ctx, nil, finalBuilder, func(middlewareCtx *MiddlewareContext) error {
+	err := q.repo.executeResultMiddleware(OpQuery, q.ctx, nil, finalBuilder, &records, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -231,13 +410,17 @@ func (q *Query[T]) Find() ([]T, error) {
 			}
 		}
 
-		var execErr error
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		var executor DBExecutor = q.repo.db
 		if q.tx != nil {
-			execErr = q.tx.SelectContext(q.ctx, &records, sqlQuery, args...)
-		} else {
-			execErr = q.repo.db.SelectContext(q.ctx, &records, sqlQuery, args...)
+			executor = q.tx
 		}
 
+		var execErr error
+		records, execErr = q.repo.selectRecords(q.ctx, executor, sqlQuery, args...)
+
 		if execErr != nil {
 			return &Error{
 				Op:    "find",
@@ -271,29 +454,20 @@ func (q *Query[T]) First() (*T, error) {
 }
 
 func (q *Query[T]) Count() (int64, error) {
-	countBuilder := squirrel.Select("COUNT(*)").
-		From(q.repo.metadata.TableName).
-		PlaceholderFormat(squirrel.Dollar)
+	q.applyDefaultScopes()
 
-	for _, join := range q.joins {
-		switch join.Type {
-		case InnerJoin:
-			countBuilder = countBuilder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case LeftJoin:
-			countBuilder = countBuilder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case RightJoin:
-			countBuilder = countBuilder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case FullJoin:
-			countBuilder = countBuilder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition))
-		}
+	if q.err != nil {
+		return 0, q.err
 	}
 
-	if len(q.whereClause) > 0 {
-		countBuilder = countBuilder.Where(q.whereClause)
-	}
+	defer q.withTimeout()()
+
+	countBuilder := q.applyFilters(squirrel.Select("COUNT(*)").
+		From(q.source()).
+		PlaceholderFormat(squirrel.Dollar))
 
 	var count int64
-	err := q.repo.executeQueryMiddleware(OpQuery, q.ctx, nil, countBuilder, func(middlewareCtx *MiddlewareContext) error {
+	err := q.repo.executeResultMiddleware(OpQuery, q.ctx, nil, countBuilder, &count, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -305,6 +479,9 @@ func (q *Query[T]) Count() (int64, error) {
 			}
 		}
 
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
 		var execErr error
 		if q.tx != nil {
 			execErr = q.tx.GetContext(q.ctx, &count, sqlQuery, args...)
@@ -335,15 +512,42 @@ func (q *Query[T]) Exists() (bool, error) {
 }
 
 func (q *Query[T]) Delete() (int64, error) {
-	deleteBuilder := squirrel.Delete(q.repo.metadata.TableName).
+	q.applyDefaultScopes()
+
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	defer q.withTimeout()()
+
+	using, where, err := q.mutationFilter("delete")
+	if err != nil {
+		return 0, err
+	}
+
+	// Dependents select the parent rows from the unaliased table alone, so
+	// joined and aliased filters reach them by primary key
+	dependentsWhere := where
+	if len(q.joins) > 0 || q.alias != "" {
+		if dependentsWhere, err = q.joinedRowFilter("delete", q.repo.metadata.TableName); err != nil {
+			return 0, err
+		}
+	}
+
+	table := q.source()
+	if using != "" {
+		table += " USING " + using
+	}
+
+	deleteBuilder := squirrel.Delete(table).
 		PlaceholderFormat(squirrel.Dollar)
 
-	if len(q.whereClause) > 0 {
-		deleteBuilder = deleteBuilder.Where(q.whereClause)
+	if where != nil {
+		deleteBuilder = deleteBuilder.Where(where)
 	}
 
 	var rowsAffected int64
-	err := q.repo.executeQueryMiddleware(OpDelete, q.ctx, nil, deleteBuilder, func(middlewareCtx *MiddlewareContext) error {
+	err = q.repo.executeQueryMiddleware(OpDelete, q.ctx, nil, deleteBuilder, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.DeleteBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -355,111 +559,132 @@ func (q *Query[T]) Delete() (int64, error) {
 			}
 		}
 
-		var result sql.Result
+		var executor DBExecutor = q.repo.db
 		if q.tx != nil {
-			result, err = q.tx.ExecContext(q.ctx, sqlQuery, args...)
-		} else {
-			result, err = q.repo.db.ExecContext(q.ctx, sqlQuery, args...)
+			executor = q.tx
 		}
 
-		if err != nil {
-			return parsePostgreSQLError(err, "delete", q.repo.metadata.TableName)
-		}
+		return q.repo.withDependents(q.ctx, "delete", executor, func(exec DBExecutor) error {
+			if err := q.repo.applyDependents(q.ctx, exec, "delete", dependentsWhere); err != nil {
+				return err
+			}
 
-		rowsAffected, err = result.RowsAffected()
-		if err != nil {
-			return &Error{
-				Op:    "delete",
-				Table: q.repo.metadata.TableName,
-				Err:   fmt.Errorf("failed to get rows affected: %w", err),
+			result, err := exec.ExecContext(q.ctx, sqlQuery, args...)
+			if err != nil {
+				return parsePostgreSQLError(err, "delete", q.repo.metadata.TableName)
 			}
-		}
 
-		return nil
+			rowsAffected, err = result.RowsAffected()
+			if err != nil {
+				return &Error{
+					Op:    "delete",
+					Table: q.repo.metadata.TableName,
+					Err:   fmt.Errorf("failed to get rows affected: %w", err),
+				}
+			}
+
+			middlewareCtx.RowsAffected = rowsAffected
+
+			return nil
+		})
 	})
 
 	return rowsAffected, err
 }
 
-// Update updates records using type-safe Action operations
-func (q *Query[T]) Update(actions ...Action) (int64, error) {
+// updateBuilder returns the UPDATE statement for actions, restricted by the
+// query's conditions and stamped with the NowOnUpdate and actor columns
+func (q *Query[T]) updateBuilder(actions []Action) (squirrel.UpdateBuilder, error) {
+	query := squirrel.Update(q.source()).
+		PlaceholderFormat(squirrel.Dollar)
+
 	if len(actions) == 0 {
-		return 0, &Error{
+		return query, &Error{
 			Op:    "update",
 			Table: q.repo.metadata.TableName,
 			Err:   fmt.Errorf("no actions provided"),
 		}
 	}
 
-	var setParts []string
-	var args []interface{}
-	argIndex := 1
+	q.applyDefaultScopes()
 
+	if q.err != nil {
+		return query, q.err
+	}
+
+	assigned := make(map[string]bool, len(actions))
 	for _, action := range actions {
-		expression := action.Expression()
-		value := action.Value()
+		if action.err != nil {
+			return query, &Error{
+				Op:     "update",
+				Table:  q.repo.metadata.TableName,
+				Column: action.Column(),
+				Err:    action.err,
+			}
+		}
+		if q.repo.isImmutableColumn(action.Column()) {
+			return query, &Error{
+				Op:     "update",
+				Table:  q.repo.metadata.TableName,
+				Column: action.Column(),
+				Err:    ErrImmutableColumn,
+			}
+		}
 
-		if value != nil {
+		query = query.Set(action.target, action.assignment(q.repo.options))
+		assigned[unqualifiedColumn(action.Column())] = true
+	}
 
-			if valueSlice, ok := value.([]interface{}); ok {
-				for _, v := range valueSlice {
-					expression = strings.Replace(expression, "?", fmt.Sprintf("$%d", argIndex), 1)
-					args = append(args, v)
-					argIndex++
-				}
-			} else {
+	query = q.repo.touchUpdate(query, assigned)
+	if column, actor, ok := q.repo.actorUpdate(q.ctx, assigned); ok {
+		query = query.Set(quoteIdent(column), actor)
+	}
 
-				for strings.Contains(expression, "?") {
-					expression = strings.Replace(expression, "?", fmt.Sprintf("$%d", argIndex), 1)
-					args = append(args, value)
-					argIndex++
-				}
-			}
-		}
-		setParts = append(setParts, expression)
+	from, where, err := q.mutationFilter("update")
+	if err != nil {
+		return query, err
+	}
+	if from != "" {
+		query = query.From(from)
 	}
+	if where != nil {
+		query = query.Where(where)
+	}
+	return query, nil
+}
 
-	baseSQL := fmt.Sprintf("UPDATE %s SET %s", q.repo.metadata.TableName, strings.Join(setParts, ", "))
+// Update updates records using type-safe Action operations. Middleware
+// receives the statement as a squirrel.UpdateBuilder.
+func (q *Query[T]) Update(actions ...Action) (int64, error) {
+	updateBuilder, err := q.updateBuilder(actions)
+	if err != nil {
+		return 0, err
+	}
 
-	if len(q.whereClause) > 0 {
-		whereBuilder := squirrel.Select("1").Where(q.whereClause).PlaceholderFormat(squirrel.Dollar)
-		_, whereArgs, err := whereBuilder.ToSql()
+	defer q.withTimeout()()
+
+	var rowsAffected int64
+	err = q.repo.executeQueryMiddleware(OpUpdateMany, q.ctx, actions, updateBuilder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.UpdateBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
 		if err != nil {
-			return 0, &Error{
+			return &Error{
 				Op:    "update",
 				Table: q.repo.metadata.TableName,
-				Err:   fmt.Errorf("failed to build where clause: %w", err),
-			}
-		}
-
-		dummySQL, _, _ := whereBuilder.ToSql()
-		whereStart := strings.Index(dummySQL, "WHERE")
-		if whereStart != -1 {
-			whereClause := dummySQL[whereStart:]
-
-			for i := range whereArgs {
-				placeholder := fmt.Sprintf("$%d", i+1)
-				newPlaceholder := fmt.Sprintf("$%d", argIndex+i)
-				whereClause = strings.Replace(whereClause, placeholder, newPlaceholder, -1)
+				Err:   fmt.Errorf("failed to build query: %w", err),
 			}
-			baseSQL += " " + whereClause
-			args = append(args, whereArgs...)
 		}
-	}
 
-	var rowsAffected int64
-	err := q.repo.executeQueryMiddleware(OpUpdateMany, q.ctx, actions, baseSQL, func(middlewareCtx *MiddlewareContext) error {
-		middlewareCtx.Query = baseSQL
+		middlewareCtx.Query = sqlQuery
 		middlewareCtx.Args = args
 
-		var result sql.Result
-		var err error
+		var executor DBExecutor = q.repo.db
 		if q.tx != nil {
-			result, err = q.tx.ExecContext(q.ctx, baseSQL, args...)
-		} else {
-			result, err = q.repo.db.ExecContext(q.ctx, baseSQL, args...)
+			executor = q.tx
 		}
 
+		result, err := executor.ExecContext(q.ctx, sqlQuery, args...)
 		if err != nil {
 			return parsePostgreSQLError(err, "update", q.repo.metadata.TableName)
 		}
@@ -473,6 +698,8 @@ func (q *Query[T]) Update(actions ...Action) (int64, error) {
 			}
 		}
 
+		middlewareCtx.RowsAffected = rowsAffected
+
 		return nil
 	})
 
@@ -502,6 +729,9 @@ func (q *Query[T]) findWithRelationships() ([]T, error) {
 	return records, nil
 }
 
+// loadRelationship loads an included relationship for every record with one
+// query and hands the rows to the relationship's generated Load function,
+// which assigns them to the typed relationship fields
 func (q *Query[T]) loadRelationship(records []T, include include) error {
 	if len(records) == 0 {
 		return nil
@@ -512,31 +742,25 @@ func (q *Query[T]) loadRelationship(records []T, include include) error {
 		return fmt.Errorf("relationship %s not found", include.name)
 	}
 
-	if relationship.ScanToModel == nil {
-		return fmt.Errorf("relationship %s does not have ScanToModel function", include.name)
+	if relationship.Load == nil {
+		return fmt.Errorf("relationship %s does not have a Load function", include.name)
 	}
 
-	for i := range records {
-
-		recordQuery, recordArgs, err := q.buildSingleRecordQuery(relationship, records[i], include)
-		if err != nil {
-			return err
-		}
-
-		if recordQuery != "" {
-			if err := q.executeSingleRelationshipQuery(relationship, recordQuery, recordArgs, &records[i]); err != nil {
-				return err
-			}
-		}
+	query, args, err := q.buildRelationshipQuery(relationship, records, include)
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
-
-func (q *Query[T]) executeSingleRelationshipQuery(relationship *RelationshipMetadata, query string, args []interface{}, record *T) error {
+	models := make([]interface{}, len(records))
+	for i := range records {
+		models[i] = &records[i]
+	}
 
-	return q.repo.executeQueryMiddleware(OpQuery, q.ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+	if query == "" {
+		return relationship.Load(q.ctx, nil, "", nil, models)
+	}
 
+	return q.repo.executeQueryMiddleware(OpQuery, q.ctx, records, query, func(middlewareCtx *MiddlewareContext) error {
 		var executor DBExecutor
 		if q.tx != nil {
 			executor = q.tx
@@ -544,7 +768,7 @@ func (q *Query[T]) executeSingleRelationshipQuery(relationship *RelationshipMeta
 			executor = q.repo.db
 		}
 
-		if err := relationship.ScanToModel(q.ctx, executor, query, args, record); err != nil {
+		if err := relationship.Load(q.ctx, decryptingExecutor{executor, q.repo.options}, query, args, models); err != nil {
 			return &Error{
 				Op:    "load_relationship",
 				Table: relationship.Target,
@@ -556,39 +780,12 @@ func (q *Query[T]) executeSingleRelationshipQuery(relationship *RelationshipMeta
 	})
 }
 
-func (q *Query[T]) buildSingleRecordQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
-	switch relationship.Type {
-	case "belongs_to":
-		return q.buildBelongsToSingleQuery(relationship, record, include)
-	case "has_one":
-		return q.buildHasOneSingleQuery(relationship, record, include)
-	case "has_many":
-		return q.buildHasManySingleQuery(relationship, record, include)
-	case "has_many_through":
-		return q.buildHasManyThroughSingleQuery(relationship, record, include)
-	default:
-		return "", nil, fmt.Errorf("unsupported relationship type: %s", relationship.Type)
-	}
-}
-
-func (q *Query[T]) buildBelongsToSingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
-
-	fkFieldName, ok := q.repo.metadata.ReverseMap[relationship.ForeignKey]
-	if !ok {
-		fkFieldName = relationship.ForeignKey
-		if _, exists := q.repo.metadata.Columns[fkFieldName]; !exists {
-			return "", nil, fmt.Errorf("foreign key %s not found", relationship.ForeignKey)
-		}
-	}
-
-	fkColumn := q.repo.metadata.Columns[fkFieldName]
-	if fkColumn == nil {
-		return "", nil, fmt.Errorf("foreign key column %s not found", fkFieldName)
-	}
-
-	fkValue := fkColumn.GetValue(record)
-	if fkValue == nil || isZeroValue(fkValue) {
-		return "", nil, nil
+// buildRelationshipQuery builds the query selecting the related rows of every
+// record. It returns an empty query when no record has a key to match.
+func (q *Query[T]) buildRelationshipQuery(relationship *RelationshipMetadata, records []T, include include) (string, []interface{}, error) {
+	keys, err := q.relationshipKeys(relationship, records)
+	if err != nil || len(keys) == 0 {
+		return "", nil, err
 	}
 
 	tableName := relationship.TargetTable
@@ -596,146 +793,113 @@ func (q *Query[T]) buildBelongsToSingleQuery(relationship *RelationshipMetadata,
 		tableName = relationship.Target
 	}
 
-	query := squirrel.Select("*").
-		From(tableName).
-		Where(squirrel.Eq{relationship.TargetKey: fkValue}).
-		PlaceholderFormat(squirrel.Dollar)
-
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
+	var query squirrel.SelectBuilder
+	switch relationship.Type {
+	case "belongs_to":
+		targetKey := relationship.TargetKey
+		if targetKey == "" {
+			targetKey = "id"
+		}
+		query = squirrel.Select("*").
+			From(quoteIdent(tableName)).
+			Where(anyOf(q.repo.options, quoteIdent(targetKey), keys))
+	case "has_one", "has_many":
+		query = squirrel.Select("*").
+			From(quoteIdent(tableName)).
+			Where(anyOf(q.repo.options, quoteIdent(relationship.ForeignKey), keys))
+		if relationship.isPolymorphic() {
+			query = query.Where(polymorphicCondition(relationship))
+		}
+	case "has_many_through":
+		query = squirrel.Select("t.*", "jt."+quoteIdent(relationship.ThroughFK)+" AS "+RelationshipOwnerColumn).
+			From(quoteIdent(tableName) + " t").
+			InnerJoin(fmt.Sprintf("%s jt ON t.%s = jt.%s",
+				quoteIdent(relationship.Through),
+				quoteIdent(relationship.TargetKey),
+				quoteIdent(relationship.ThroughTK))).
+			Where(anyOf(q.repo.options, "jt."+quoteIdent(relationship.ThroughFK), keys))
+	default:
+		return "", nil, fmt.Errorf("unsupported relationship type: %s", relationship.Type)
 	}
 
-	return query.ToSql()
+	return applyRelationshipScope(query.PlaceholderFormat(squirrel.Dollar), relationship, include).ToSql()
 }
 
-func (q *Query[T]) buildHasOneSingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
-
-	sourceKey := relationship.SourceKey
-	if sourceKey == "" {
-		sourceKey = "id"
+// relationshipKeys returns the distinct, non-zero values of the key that
+// records use to reach their related rows: the foreign key for belongs_to and
+// the source key otherwise
+func (q *Query[T]) relationshipKeys(relationship *RelationshipMetadata, records []T) ([]interface{}, error) {
+	key := relationship.SourceKey
+	if relationship.Type == "belongs_to" {
+		key = relationship.ForeignKey
+	} else if key == "" {
+		key = "id"
 	}
 
-	sourceFieldName, ok := q.repo.metadata.ReverseMap[sourceKey]
+	fieldName, ok := q.repo.metadata.ReverseMap[key]
 	if !ok {
-		sourceFieldName = sourceKey
-		if _, exists := q.repo.metadata.Columns[sourceFieldName]; !exists {
-			return "", nil, fmt.Errorf("source key %s not found", sourceKey)
-		}
-	}
-
-	sourceColumn := q.repo.metadata.Columns[sourceFieldName]
-	if sourceColumn == nil {
-		return "", nil, fmt.Errorf("source key column %s not found", sourceFieldName)
-	}
-
-	sourceValue := sourceColumn.GetValue(record)
-	if sourceValue == nil || isZeroValue(sourceValue) {
-		return "", nil, nil
+		fieldName = key
 	}
-
-	tableName := relationship.TargetTable
-	if tableName == "" {
-		tableName = relationship.Target
+	column := q.repo.metadata.Columns[fieldName]
+	if column == nil || column.GetValue == nil {
+		return nil, fmt.Errorf("key %s of relationship %s not found", key, relationship.Name)
 	}
 
-	query := squirrel.Select("*").
-		From(tableName).
-		Where(squirrel.Eq{relationship.ForeignKey: sourceValue}).
-		PlaceholderFormat(squirrel.Dollar)
-
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
-	}
-
-	return query.ToSql()
-}
-
-func (q *Query[T]) buildHasManySingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
-
-	sourceKey := relationship.SourceKey
-	if sourceKey == "" {
-		sourceKey = "id"
-	}
+	var keys []interface{}
+	seen := make(map[interface{}]bool)
+	for _, record := range records {
+		value := column.GetValue(record)
+		if value == nil || isZeroValue(value) {
+			continue
+		}
 
-	sourceFieldName, ok := q.repo.metadata.ReverseMap[sourceKey]
-	if !ok {
-		sourceFieldName = sourceKey
-		if _, exists := q.repo.metadata.Columns[sourceFieldName]; !exists {
-			return "", nil, fmt.Errorf("source key %s not found", sourceKey)
+		if relationship.Type == "belongs_to" && relationship.isPolymorphic() {
+			matches, err := q.repo.matchesPolymorphicType(relationship, record)
+			if err != nil {
+				return nil, err
+			}
+			if !matches {
+				continue
+			}
 		}
-	}
 
-	sourceColumn := q.repo.metadata.Columns[sourceFieldName]
-	if sourceColumn == nil {
-		return "", nil, fmt.Errorf("source key column %s not found", sourceFieldName)
+		if reflect.TypeOf(value).Comparable() {
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+		}
+		keys = append(keys, value)
 	}
 
-	sourceValue := sourceColumn.GetValue(record)
-	if sourceValue == nil || isZeroValue(sourceValue) {
-		return "", nil, nil
-	}
+	return keys, nil
+}
 
-	tableName := relationship.TargetTable
-	if tableName == "" {
-		tableName = relationship.Target
+// applyRelationshipScope adds the relationship's default conditions and ordering,
+// followed by any conditions supplied through IncludeWhere
+func applyRelationshipScope(query squirrel.SelectBuilder, relationship *RelationshipMetadata, include include) squirrel.SelectBuilder {
+	for _, condition := range relationship.scopeConditions() {
+		query = query.Where(squirrel.Expr(condition))
 	}
 
-	query := squirrel.Select("*").
-		From(tableName).
-		Where(squirrel.Eq{relationship.ForeignKey: sourceValue}).
-		PlaceholderFormat(squirrel.Dollar)
-
 	for _, condition := range include.conditions {
 		query = query.Where(condition.ToSqlizer())
 	}
 
-	return query.ToSql()
-}
-
-func (q *Query[T]) buildHasManyThroughSingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
-
-	sourceKey := relationship.SourceKey
-	if sourceKey == "" {
-		sourceKey = "id"
-	}
-
-	sourceFieldName, ok := q.repo.metadata.ReverseMap[sourceKey]
-	if !ok {
-		sourceFieldName = sourceKey
-		if _, exists := q.repo.metadata.Columns[sourceFieldName]; !exists {
-			return "", nil, fmt.Errorf("source key %s not found", sourceKey)
-		}
-	}
-
-	sourceColumn := q.repo.metadata.Columns[sourceFieldName]
-	if sourceColumn == nil {
-		return "", nil, fmt.Errorf("source key column %s not found", sourceFieldName)
-	}
-
-	sourceValue := sourceColumn.GetValue(record)
-	if sourceValue == nil || isZeroValue(sourceValue) {
-		return "", nil, nil
+	if relationship.OrderBy != "" {
+		query = query.OrderBy(relationship.OrderBy)
 	}
 
-	tableName := relationship.TargetTable
-	if tableName == "" {
-		tableName = relationship.Target
-	}
-
-	query := squirrel.Select("t.*").
-		From(tableName + " t").
-		InnerJoin(fmt.Sprintf("%s jt ON t.%s = jt.%s",
-			relationship.Through,
-			relationship.TargetKey,
-			relationship.ThroughTK)).
-		Where(squirrel.Eq{"jt." + relationship.ThroughFK: sourceValue}).
-		PlaceholderFormat(squirrel.Dollar)
+	return query
+}
 
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
+// scopeConditions returns the conditions every load and count of the
+// relationship applies: its own, then the default scopes of its target
+func (r *RelationshipMetadata) scopeConditions() []string {
+	if len(r.TargetScopes) == 0 {
+		return r.Conditions
 	}
-
-	return query.ToSql()
+	return append(append([]string(nil), r.Conditions...), r.TargetScopes...)
 }
 
 // isZeroValue checks if a value is the zero value for its type
@@ -783,6 +947,14 @@ func (q *Query[T]) ExecuteRaw(query string, args ...interface{}) ([]T, error) {
 		}
 	}
 
+	if err := q.repo.options.decrypt(&records); err != nil {
+		return nil, &Error{
+			Op:    "executeRaw",
+			Table: q.repo.metadata.TableName,
+			Err:   err,
+		}
+	}
+
 	return records, nil
 }
 
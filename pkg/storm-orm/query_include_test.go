@@ -38,3 +38,56 @@ func TestQueryIncludeWhere(t *testing.T) {
 
 	})
 }
+
+func TestRelationshipDefaultScope(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Posts": {
+			Name:        "Posts",
+			Type:        "has_many",
+			Target:      "Post",
+			TargetTable: "posts",
+			ForeignKey:  "user_id",
+			SourceKey:   "id",
+			Conditions:  []string{"published = true", "deleted_at IS NULL"},
+			OrderBy:     "created_at DESC",
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	rel := metadata.Relationships["Posts"]
+
+	t.Run("applies tag conditions and ordering", func(t *testing.T) {
+		sql, args, err := repo.Query(context.Background()).buildRelationshipQuery(rel, []TestUser{{ID: 1}, {ID: 2}, {ID: 1}}, include{name: "Posts"})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM posts WHERE user_id IN ($1,$2) AND published = true AND deleted_at IS NULL ORDER BY created_at DESC", sql)
+		assert.Equal(t, []interface{}{1, 2}, args)
+	})
+
+	t.Run("IncludeWhere adds to the defaults", func(t *testing.T) {
+		titleCol := Column[string]{Name: "title", Table: "posts"}
+		sql, args, err := repo.Query(context.Background()).buildRelationshipQuery(rel, []TestUser{{ID: 1}, {ID: 2}, {ID: 1}}, include{
+			name:       "Posts",
+			conditions: []Condition{titleCol.Eq("Hello")},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM posts WHERE user_id IN ($1,$2) AND published = true AND deleted_at IS NULL AND posts.title = $3 ORDER BY created_at DESC", sql)
+		assert.Equal(t, []interface{}{1, 2, "Hello"}, args)
+	})
+
+	t.Run("binds keys over the limit as an array", func(t *testing.T) {
+		limited, err := NewRepositoryForStorm[TestUser](NewStormWithOptions(sqlx.NewDb(db, "postgres"), WithInListLimit(1)), metadata)
+		require.NoError(t, err)
+
+		sql, args, err := limited.Query(context.Background()).buildRelationshipQuery(rel, []TestUser{{ID: 1}, {ID: 2}}, include{name: "Posts"})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM posts WHERE user_id = ANY($1) AND published = true AND deleted_at IS NULL ORDER BY created_at DESC", sql)
+		require.Len(t, args, 1)
+	})
+}
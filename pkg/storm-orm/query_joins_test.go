@@ -2,9 +2,11 @@ package orm
 
 import (
 	"context"
+	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,3 +37,85 @@ func TestQueryJoinRelationship(t *testing.T) {
 		assert.Equal(t, query, result)
 	})
 }
+
+func TestQueryJoinRelationshipConditions(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Posts": {
+			Name:        "Posts",
+			Type:        "has_many",
+			Target:      "Post",
+			TargetTable: "posts",
+			ForeignKey:  "user_id",
+		},
+		"Team": {
+			Name:        "Team",
+			Type:        "belongs_to",
+			Target:      "Team",
+			TargetTable: "teams",
+			ForeignKey:  "team_id",
+		},
+		"Roles": {
+			Name:        "Roles",
+			Type:        "has_many_through",
+			Target:      "Role",
+			TargetTable: "roles",
+			Through:     "user_roles",
+			ThroughFK:   "user_id",
+			ThroughTK:   "role_id",
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	const columns = "users.created_at, users.email, users.id, users.is_active, users.name, users.updated_at"
+	tests := []struct {
+		relationship string
+		joinType     JoinType
+		expected     string
+	}{
+		{"Posts", InnerJoin, "SELECT " + columns + " FROM users WHERE users.id IN (SELECT users.id FROM users INNER JOIN posts ON users.id = posts.user_id WHERE (posts.title = $1))"},
+		{"Team", LeftJoin, "SELECT " + columns + " FROM users LEFT JOIN teams ON users.team_id = teams.id WHERE (posts.title = $1)"},
+		{"Roles", LeftJoin, "SELECT " + columns + " FROM users WHERE users.id IN (SELECT users.id FROM users LEFT JOIN user_roles ON users.id = user_roles.user_id LEFT JOIN roles ON user_roles.role_id = roles.id WHERE (posts.title = $1))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relationship, func(t *testing.T) {
+			query, args, err := repo.Query(context.Background()).
+				JoinRelationship(tt.relationship, tt.joinType).
+				Where(Condition{squirrel.Eq{"posts.title": "Hello"}}).
+				ToSQL()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+			assert.Equal(t, []interface{}{"Hello"}, args)
+		})
+	}
+
+	t.Run("aliased", func(t *testing.T) {
+		query, _, err := repo.Query(context.Background()).As("u").JoinRelationship("Team", InnerJoin).ToSQL()
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT u.created_at, u.email, u.id, u.is_active, u.name, u.updated_at FROM users AS u INNER JOIN teams ON u.team_id = teams.id", query)
+	})
+
+	t.Run("counts each row once", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE users.id IN (SELECT users.id FROM users INNER JOIN posts ON users.id = posts.user_id)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		count, err := repo.Query(context.Background()).JoinRelationship("Posts", InnerJoin).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -3,6 +3,8 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"regexp"
 	"testing"
 	"time"
 
@@ -264,6 +266,37 @@ func TestQueryDelete(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	titleCol := Column[string]{Name: "title", Table: "posts"}
+
+	t.Run("Delete with an inner join", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users USING posts WHERE (posts.user_id = users.id AND posts.title = $1)`)).
+			WithArgs("spam").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		rowsAffected, err := repo.Query(context.Background()).
+			InnerJoin("posts", "posts.user_id = users.id").
+			Where(titleCol.Eq("spam")).
+			Delete()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), rowsAffected)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Delete with an outer join", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE users.id IN (SELECT users.id FROM users LEFT JOIN posts ON posts.user_id = users.id WHERE (posts.title IS NULL))`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rowsAffected, err := repo.Query(context.Background()).
+			LeftJoin("posts", "posts.user_id = users.id").
+			Where(titleCol.IsNull()).
+			Delete()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), rowsAffected)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 // TestQueryJoins tests join methods
@@ -311,6 +344,22 @@ func TestQueryJoins(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("Count with a join", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users INNER JOIN posts ON posts.user_id = users.id WHERE (posts.title = $1)`)).
+			WithArgs("hello").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		titleCol := Column[string]{Name: "title", Table: "posts"}
+		count, err := repo.Query(context.Background()).
+			InnerJoin("posts", "posts.user_id = users.id").
+			Where(titleCol.Eq("hello")).
+			Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("FullJoin", func(t *testing.T) {
 
 		mock.ExpectQuery(`SELECT .* FROM users JOIN FULL OUTER JOIN posts ON posts.user_id = users.id`).
@@ -323,6 +372,56 @@ func TestQueryJoins(t *testing.T) {
 	})
 }
 
+// TestQueryAs tests table aliases
+func TestQueryAs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	nameCol := Column[string]{Name: "name", Table: "m"}
+
+	t.Run("self join", func(t *testing.T) {
+		query, args, err := repo.Query(context.Background()).
+			As("u").
+			InnerJoin("users AS m", "m.id = u.manager_id").
+			Where(nameCol.Eq("Grace")).
+			ToSQL()
+
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT u.created_at, u.email, u.id, u.is_active, u.name, u.updated_at FROM users AS u INNER JOIN users AS m ON m.id = u.manager_id WHERE (m.name = $1)", query)
+		assert.Equal(t, []interface{}{"Grace"}, args)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users AS u WHERE (u.is_active = $1)`)).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		activeCol := Column[bool]{Name: "is_active", Table: "u"}
+		count, err := repo.Query(context.Background()).As("u").Where(activeCol.Eq(true)).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("delete with an outer join", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users AS u WHERE u.id IN (SELECT u.id FROM users AS u LEFT JOIN users AS m ON m.id = u.manager_id WHERE (m.name = $1))`)).
+			WithArgs("Grace").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := repo.Query(context.Background()).
+			As("u").
+			LeftJoin("users AS m", "m.id = u.manager_id").
+			Where(nameCol.Eq("Grace")).
+			Delete()
+		require.NoError(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 // TestQueryInclude tests Include functionality
 func TestQueryInclude(t *testing.T) {
 	db, _, err := sqlmock.New()
@@ -393,3 +492,104 @@ func TestQueryExecuteRaw(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+// TestQueryConditionPlaceholders runs each condition through a query and
+// checks the numbered placeholders and arguments it sends
+func TestQueryConditionPlaceholders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	id := NumericColumn[int]{ComparableColumn: ComparableColumn[int]{Column: Column[int]{Name: "id", Table: "users"}}}
+	name := StringColumn{Column: Column[string]{Name: "name", Table: "users"}}
+	active := BoolColumn{Column: Column[bool]{Name: "is_active", Table: "users"}}
+	tags := ArrayColumn[string]{Column: Column[[]string]{Name: "tags", Table: "users"}}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		where     string
+		args      []driver.Value
+	}{
+		{"In", id.In(1, 2, 3), "users.id IN ($1,$2,$3)", []driver.Value{1, 2, 3}},
+		{"NotIn", id.NotIn(1, 2), "users.id NOT IN ($1,$2)", []driver.Value{1, 2}},
+		{"empty In", id.In(), "(1=0)", nil},
+		{"Between", id.Between(1, 10), "(users.id >= $1 AND users.id <= $2)", []driver.Value{1, 10}},
+		{"NotBetween", id.NotBetween(1, 10), "users.id NOT BETWEEN $1 AND $2", []driver.Value{1, 10}},
+		{"ILike", name.ILike("%ann%"), "users.name ILIKE $1", []driver.Value{"%ann%"}},
+		{"NotILike", name.NotILike("%ann%"), "users.name NOT ILIKE $1", []driver.Value{"%ann%"}},
+		{"SimilarTo", name.SimilarTo("(Ann|Bob)%"), "users.name SIMILAR TO $1", []driver.Value{"(Ann|Bob)%"}},
+		{"NotSimilarTo", name.NotSimilarTo("A%"), "users.name NOT SIMILAR TO $1", []driver.Value{"A%"}},
+		{"IEq", name.IEq("ANN"), "lower(users.name) = lower($1)", []driver.Value{"ANN"}},
+		{"ISearch", name.ISearch("an%"), "lower(users.name) LIKE lower($1)", []driver.Value{"an%"}},
+		{"IsDistinctFrom", name.IsDistinctFrom("Ann"), "users.name IS DISTINCT FROM $1", []driver.Value{"Ann"}},
+		{"IsNotDistinctFrom", name.IsNotDistinctFrom("Ann"), "users.name IS NOT DISTINCT FROM $1", []driver.Value{"Ann"}},
+		{"Any", tags.Any("admin"), "$1 = ANY(users.tags)", []driver.Value{"admin"}},
+		{"AndNot", active.IsTrue().AndNot(name.Eq("Ann")), "(users.is_active = $1 AND NOT (users.name = $2))", []driver.Value{true, "Ann"}},
+		{"OrNot", id.Gt(5).OrNot(name.In("Ann", "Bob")), "(users.id > $1 OR NOT (users.name IN ($2,$3)))", []driver.Value{5, "Ann", "Bob"}},
+		{
+			"combined",
+			id.NotBetween(1, 10).And(name.ILike("a%")).AndNot(tags.Any("banned")),
+			"((users.id NOT BETWEEN $1 AND $2 AND users.name ILIKE $3) AND NOT ($4 = ANY(users.tags)))",
+			[]driver.Value{1, 10, "a%", "banned"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := mock.ExpectQuery(regexp.QuoteMeta("WHERE (" + tt.where + ")"))
+			if tt.args != nil {
+				expected.WithArgs(tt.args...)
+			} else {
+				expected.WithoutArgs()
+			}
+			expected.WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+			_, err := repo.Query(context.Background()).Where(tt.condition).Find()
+			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestQueryUnaccentSearch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	detected := NewStorm(sqlxDB)
+	repo, err := NewRepositoryForStorm[TestUser](detected, createTestUserMetadata())
+	require.NoError(t, err)
+	other, err := NewRepositoryForStorm[TestUser](NewStorm(sqlxDB), createTestUserMetadata())
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM pg_proc WHERE proname = $1)")).
+		WithArgs("storm_unaccent").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	enabled, err := detected.DetectUnaccent(context.Background())
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	name := StringColumn{Column: Column[string]{Name: "name", Table: "users"}}
+	search := name.ISearch("jose%").And(name.IsNotNull())
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE ((storm_unaccent(lower(users.name)) LIKE storm_unaccent(lower($1)) AND users.name IS NOT NULL))")).
+		WithArgs("jose%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	_, err = repo.Query(context.Background()).Where(search).Find()
+	require.NoError(t, err)
+
+	// Another Storm on the same database has not detected unaccent
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE ((lower(users.name) LIKE lower($1) AND users.name IS NOT NULL))")).
+		WithArgs("jose%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	_, err = other.Query(context.Background()).Where(search).Find()
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
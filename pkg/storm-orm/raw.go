@@ -0,0 +1,382 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// RawPolicy restricts the statements raw SQL may run. A statement is identified
+// by its first keyword, such as SELECT, WITH or DELETE.
+type RawPolicy struct {
+	// Allow lists the permitted statements; empty permits anything not denied
+	Allow []string
+	// Deny lists statements that are always rejected
+	Deny []string
+	// AllowMultiple permits several statements separated by semicolons
+	AllowMultiple bool
+}
+
+// StrictRawPolicy permits single data queries and data changes and rejects
+// schema changes and permission statements
+var StrictRawPolicy = RawPolicy{
+	Allow: []string{"SELECT", "WITH", "VALUES", "INSERT", "UPDATE", "DELETE"},
+	Deny:  []string{"DROP", "TRUNCATE", "ALTER", "CREATE", "GRANT", "REVOKE"},
+}
+
+// SetRawPolicy restricts the raw SQL run through Raw and ExecRaw. A nil policy
+// removes the restriction.
+func (s *Storm) SetRawPolicy(policy *RawPolicy) {
+	s.rawPolicy = policy
+}
+
+// RawQuery is a hand-written SQL statement using named parameters such as
+// :user_id. Parameters are rebound to the driver's placeholders before the
+// statement runs, and PostgreSQL casts like ::text are left alone.
+type RawQuery struct {
+	ctx      context.Context
+	executor DBExecutor
+	options  *queryOptions
+	query    string
+	params   map[string]interface{}
+	arg      interface{}
+	policy   *RawPolicy
+}
+
+// Raw starts a raw query on the current connection or transaction.
+// Encrypted parameters and scanned fields use the keys of the Storm.
+func (s *Storm) Raw(ctx context.Context, query string) *RawQuery {
+	return &RawQuery{
+		ctx:      ctx,
+		executor: decryptingExecutor{s.executor, s.options},
+		options:  s.options,
+		query:    query,
+		params:   make(map[string]interface{}),
+		policy:   s.rawPolicy,
+	}
+}
+
+// ExecRaw runs a statement that returns no rows, such as INSERT or UPDATE,
+// with named parameters taken from params
+func (s *Storm) ExecRaw(ctx context.Context, query string, params map[string]interface{}) (sql.Result, error) {
+	return s.Raw(ctx, query).BindMap(params).Exec()
+}
+
+// Bind sets the named parameter name
+func (r *RawQuery) Bind(name string, value interface{}) *RawQuery {
+	r.params[strings.TrimPrefix(name, ":")] = value
+	return r
+}
+
+// BindMap sets every parameter in params
+func (r *RawQuery) BindMap(params map[string]interface{}) *RawQuery {
+	for name, value := range params {
+		r.Bind(name, value)
+	}
+	return r
+}
+
+// BindStruct takes parameter values from the db tags of a struct. Parameters
+// set with Bind or BindMap take precedence.
+func (r *RawQuery) BindStruct(arg interface{}) *RawQuery {
+	r.arg = arg
+	return r
+}
+
+// Strict applies StrictRawPolicy to this query
+func (r *RawQuery) Strict() *RawQuery {
+	policy := StrictRawPolicy
+	r.policy = &policy
+	return r
+}
+
+// ToSQL returns the statement with positional placeholders and its arguments
+func (r *RawQuery) ToSQL() (string, []interface{}, error) {
+	if r.policy != nil {
+		if err := r.policy.check(r.query); err != nil {
+			return "", nil, err
+		}
+	}
+
+	params, err := r.namedParams()
+	if err != nil {
+		return "", nil, err
+	}
+
+	query, args, err := compileNamed(r.query, params, sqlx.BindType(r.executor.DriverName()))
+	if err != nil {
+		return "", nil, err
+	}
+	for i, arg := range args {
+		args[i] = r.options.bind(arg)
+	}
+	return query, args, nil
+}
+
+// Scan runs the query and scans the result into dest: a pointer to a slice
+// fills it with every row, any other pointer receives the first row and
+// returns ErrNotFound when there is none. Rows map to structs by db tags.
+func (r *RawQuery) Scan(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return &Error{Op: "raw", Err: fmt.Errorf("destination must be a non-nil pointer, got %T", dest)}
+	}
+
+	query, args, err := r.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	if maps, ok := dest.(*[]map[string]interface{}); ok {
+		*maps, err = r.scanMaps(query, args)
+		return err
+	}
+
+	if value.Elem().Kind() == reflect.Slice && value.Elem().Type().Elem().Kind() != reflect.Uint8 {
+		if err := r.executor.SelectContext(r.ctx, dest, query, args...); err != nil {
+			return parsePostgreSQLError(err, "raw", "")
+		}
+		return nil
+	}
+
+	if err := r.executor.GetContext(r.ctx, dest, query, args...); err != nil {
+		return parsePostgreSQLError(err, "raw", "")
+	}
+	return nil
+}
+
+// Maps runs the query and returns each row as a map of column name to value
+func (r *RawQuery) Maps() ([]map[string]interface{}, error) {
+	query, args, err := r.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	return r.scanMaps(query, args)
+}
+
+// Exec runs a statement that returns no rows
+func (r *RawQuery) Exec() (sql.Result, error) {
+	query, args, err := r.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.executor.ExecContext(r.ctx, query, args...)
+	if err != nil {
+		return nil, parsePostgreSQLError(err, "raw", "")
+	}
+	return result, nil
+}
+
+func (r *RawQuery) scanMaps(query string, args []interface{}) ([]map[string]interface{}, error) {
+	rows, err := r.executor.QueryxContext(r.ctx, query, args...)
+	if err != nil {
+		return nil, parsePostgreSQLError(err, "raw", "")
+	}
+	defer rows.Close()
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := rows.MapScan(row); err != nil {
+			return nil, &Error{Op: "raw", Err: fmt.Errorf("failed to scan row: %w", err)}
+		}
+		for column, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[column] = string(b)
+			}
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, parsePostgreSQLError(err, "raw", "")
+	}
+	return results, nil
+}
+
+// namedParams merges the struct argument with the explicitly bound parameters
+func (r *RawQuery) namedParams() (map[string]interface{}, error) {
+	if r.arg == nil {
+		return r.params, nil
+	}
+
+	params := make(map[string]interface{})
+	mapper := reflectx.NewMapperFunc("db", strings.ToLower)
+	value := reflect.Indirect(reflect.ValueOf(r.arg))
+	if value.Kind() != reflect.Struct {
+		return nil, &Error{Op: "raw", Err: fmt.Errorf("BindStruct needs a struct, got %T", r.arg)}
+	}
+	for name, field := range mapper.FieldMap(value) {
+		params[name] = field.Interface()
+	}
+	for name, v := range r.params {
+		params[name] = v
+	}
+	return params, nil
+}
+
+// compileNamed replaces each :name parameter outside quotes and comments with
+// a positional placeholder. PostgreSQL casts such as ::text are kept as they are.
+func compileNamed(query string, params map[string]interface{}, bindType int) (string, []interface{}, error) {
+	var out strings.Builder
+	var args []interface{}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				out.WriteString(query[i:])
+				i = len(query)
+				continue
+			}
+			out.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				out.WriteString(query[i:])
+				i = len(query)
+				continue
+			}
+			out.WriteString(query[i : i+end+1])
+			i += end
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			out.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(query) && isParamStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isParamChar(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			value, ok := params[name]
+			if !ok {
+				return "", nil, &Error{Op: "raw", Err: fmt.Errorf("failed to bind parameters: missing parameter :%s", name)}
+			}
+			args = append(args, value)
+			if bindType == sqlx.DOLLAR {
+				fmt.Fprintf(&out, "$%d", len(args))
+			} else {
+				out.WriteByte('?')
+			}
+			i = end - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String(), args, nil
+}
+
+func isParamStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isParamChar(c byte) bool {
+	return isParamStart(c) || c >= '0' && c <= '9' || c == '.'
+}
+
+// check rejects statements the policy does not permit
+func (p RawPolicy) check(query string) error {
+	statements := splitStatements(query)
+	if len(statements) == 0 {
+		return &Error{Op: "raw", Err: fmt.Errorf("empty statement")}
+	}
+	if len(statements) > 1 && !p.AllowMultiple {
+		return &Error{Op: "raw", Err: fmt.Errorf("multiple statements are not allowed")}
+	}
+
+	for _, statement := range statements {
+		keyword := statementKeyword(statement)
+		for _, denied := range p.Deny {
+			if strings.EqualFold(keyword, denied) {
+				return &Error{Op: "raw", Err: fmt.Errorf("%s statements are not allowed", keyword)}
+			}
+		}
+		if len(p.Allow) == 0 {
+			continue
+		}
+		allowed := false
+		for _, permitted := range p.Allow {
+			if strings.EqualFold(keyword, permitted) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &Error{Op: "raw", Err: fmt.Errorf("%s statements are not allowed", keyword)}
+		}
+	}
+	return nil
+}
+
+// splitStatements splits SQL on semicolons outside quotes and comments,
+// dropping statements that are empty once comments are removed
+func splitStatements(query string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statementKeyword(current.String()) != "" {
+			statements = append(statements, current.String())
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				current.WriteString(query[i:])
+				i = len(query)
+				continue
+			}
+			current.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+				continue
+			}
+			current.WriteByte(' ')
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+				continue
+			}
+			current.WriteByte(' ')
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// statementKeyword returns the upper-cased first word of a statement,
+// skipping any opening parentheses
+func statementKeyword(statement string) string {
+	statement = strings.TrimLeft(statement, " \t\r\n(")
+	end := strings.IndexFunc(statement, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_')
+	})
+	if end >= 0 {
+		statement = statement[:end]
+	}
+	return strings.ToUpper(statement)
+}
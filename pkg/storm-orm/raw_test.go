@@ -0,0 +1,164 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRawTestStorm(t *testing.T) (*Storm, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewStorm(sqlx.NewDb(db, "postgres")), mock
+}
+
+func TestRawQueryToSQL(t *testing.T) {
+	storm, _ := newRawTestStorm(t)
+
+	query, args, err := storm.Raw(context.Background(),
+		"SELECT id, name::text FROM users WHERE id = :user_id OR manager_id = :user_id AND team = :team").
+		Bind("user_id", 7).
+		Bind(":team", "core").
+		ToSQL()
+
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id, name::text FROM users WHERE id = $1 OR manager_id = $2 AND team = $3", query)
+	assert.Equal(t, []interface{}{7, 7, "core"}, args)
+
+	_, _, err = storm.Raw(context.Background(), "SELECT * FROM users WHERE id = :missing").ToSQL()
+	assert.ErrorContains(t, err, "failed to bind parameters")
+}
+
+func TestRawQueryBindStruct(t *testing.T) {
+	storm, _ := newRawTestStorm(t)
+
+	filter := struct {
+		Team   string `db:"team"`
+		Active bool   `db:"active"`
+	}{Team: "core", Active: true}
+
+	_, args, err := storm.Raw(context.Background(), "SELECT * FROM users WHERE team = :team AND active = :active").
+		BindStruct(filter).
+		Bind("active", false).
+		ToSQL()
+
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"core", false}, args)
+}
+
+func TestRawQueryScan(t *testing.T) {
+	storm, mock := newRawTestStorm(t)
+	ctx := context.Background()
+
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	t.Run("into a slice of structs", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM users WHERE team = $1")).
+			WithArgs("core").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ada").AddRow(2, "Linus"))
+
+		var rows []row
+		err := storm.Raw(ctx, "SELECT id, name FROM users WHERE team = :team").Bind("team", "core").Scan(&rows)
+		require.NoError(t, err)
+		assert.Equal(t, []row{{1, "Ada"}, {2, "Linus"}}, rows)
+	})
+
+	t.Run("into a single struct", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM users WHERE id = $1")).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ada"))
+
+		var one row
+		require.NoError(t, storm.Raw(ctx, "SELECT id, name FROM users WHERE id = :id").Bind("id", 1).Scan(&one))
+		assert.Equal(t, row{1, "Ada"}, one)
+	})
+
+	t.Run("reports a missing row", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, name FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		var one row
+		err := storm.Raw(ctx, "SELECT id, name FROM users WHERE id = :id").Bind("id", 9).Scan(&one)
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("into maps", func(t *testing.T) {
+		mock.ExpectQuery("SELECT team, COUNT").
+			WillReturnRows(sqlmock.NewRows([]string{"team", "total"}).AddRow([]byte("core"), int64(3)))
+
+		rows, err := storm.Raw(ctx, "SELECT team, COUNT(*) AS total FROM users GROUP BY team").Maps()
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]interface{}{{"team": "core", "total": int64(3)}}, rows)
+	})
+
+	t.Run("rejects a non-pointer destination", func(t *testing.T) {
+		var rows []row
+		assert.Error(t, storm.Raw(ctx, "SELECT 1").Scan(rows))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecRaw(t *testing.T) {
+	storm, mock := newRawTestStorm(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET active = $1 WHERE team = $2")).
+		WithArgs(false, "core").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	result, err := storm.ExecRaw(context.Background(), "UPDATE users SET active = :active WHERE team = :team",
+		map[string]interface{}{"active": false, "team": "core"})
+	require.NoError(t, err)
+
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), affected)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRawPolicy(t *testing.T) {
+	storm, _ := newRawTestStorm(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"select", "SELECT * FROM users", ""},
+		{"cte", "WITH recent AS (SELECT 1) SELECT * FROM recent", ""},
+		{"parenthesised", "(SELECT 1) UNION (SELECT 2)", ""},
+		{"leading comment", "-- find users\nSELECT * FROM users", ""},
+		{"semicolon in string", "SELECT * FROM users WHERE name = 'a;b';", ""},
+		{"drop", "DROP TABLE users", "DROP statements are not allowed"},
+		{"not allowed", "VACUUM users", "VACUUM statements are not allowed"},
+		{"stacked", "SELECT 1; DELETE FROM users", "multiple statements are not allowed"},
+		{"comment hides nothing", "SELECT 1 /* ; */", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := storm.Raw(ctx, tt.query).Strict().ToSQL()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	storm.SetRawPolicy(&RawPolicy{Deny: []string{"delete"}})
+	_, _, err := storm.Raw(ctx, "DELETE FROM users").ToSQL()
+	assert.ErrorContains(t, err, "DELETE statements are not allowed")
+	_, _, err = storm.Raw(ctx, "VACUUM users").ToSQL()
+	assert.NoError(t, err)
+}
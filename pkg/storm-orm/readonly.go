@@ -0,0 +1,61 @@
+package orm
+
+import (
+	"context"
+	"time"
+)
+
+// ReadOnlyRepository gives access to a read model, a struct marked
+// storm:"readonly" that maps a view or a query, through the reads of a
+// Repository. It has no write methods, and writes reaching the underlying
+// repository, such as a Query's Delete, fail with ErrReadOnly.
+type ReadOnlyRepository[T any] struct {
+	repo *Repository[T]
+}
+
+// ReadOnly wraps repo so only its reads are available
+func ReadOnly[T any](repo *Repository[T]) *ReadOnlyRepository[T] {
+	return &ReadOnlyRepository[T]{repo: repo}
+}
+
+func (r *ReadOnlyRepository[T]) FindByID(ctx context.Context, id interface{}) (*T, error) {
+	return r.repo.FindByID(ctx, id)
+}
+
+func (r *ReadOnlyRepository[T]) FindOneBy(ctx context.Context, columns []string, values ...interface{}) (*T, error) {
+	return r.repo.FindOneBy(ctx, columns, values...)
+}
+
+func (r *ReadOnlyRepository[T]) FindAllBy(ctx context.Context, columns []string, values ...interface{}) ([]T, error) {
+	return r.repo.FindAllBy(ctx, columns, values...)
+}
+
+func (r *ReadOnlyRepository[T]) Query(ctx context.Context) *Query[T] {
+	return r.repo.Query(ctx)
+}
+
+// Authorize returns a new ReadOnlyRepository whose queries also pass through fn
+func (r *ReadOnlyRepository[T]) Authorize(fn AuthorizeFunc[T]) *ReadOnlyRepository[T] {
+	return ReadOnly(r.repo.Authorize(fn))
+}
+
+// WithTimeout returns a new ReadOnlyRepository whose operations time out after d
+func (r *ReadOnlyRepository[T]) WithTimeout(d time.Duration) *ReadOnlyRepository[T] {
+	return ReadOnly(r.repo.WithTimeout(d))
+}
+
+func (r *ReadOnlyRepository[T]) AddMiddleware(middleware QueryMiddleware) {
+	r.repo.AddMiddleware(middleware)
+}
+
+func (r *ReadOnlyRepository[T]) TableName() string {
+	return r.repo.TableName()
+}
+
+func (r *ReadOnlyRepository[T]) PrimaryKeys() []string {
+	return r.repo.PrimaryKeys()
+}
+
+func (r *ReadOnlyRepository[T]) Columns() []string {
+	return r.repo.Columns()
+}
@@ -0,0 +1,57 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.TableName = "active_users"
+	metadata.ReadOnly = true
+	metadata.Source = "SELECT * FROM users WHERE is_active"
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+	readOnly := ReadOnly(repo)
+
+	t.Run("reads select from the source", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM (SELECT * FROM users WHERE is_active) AS active_users WHERE id = $1 LIMIT 1")).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "Ada"))
+
+		user, err := readOnly.FindByID(context.Background(), 7)
+		require.NoError(t, err)
+		assert.Equal(t, "Ada", user.Name)
+	})
+
+	t.Run("queries alias the source", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM (SELECT * FROM users WHERE is_active) AS u WHERE (u.name = $1)")).
+			WithArgs("Ada").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		name := Column[string]{Name: "name", Table: "u"}
+		_, err := readOnly.Query(context.Background()).As("u").Where(name.Eq("Ada")).Find()
+		require.NoError(t, err)
+	})
+
+	t.Run("writes are rejected", func(t *testing.T) {
+		_, err := repo.Create(context.Background(), &TestUser{Name: "Linus"})
+		assert.ErrorIs(t, err, ErrReadOnly)
+
+		_, err = readOnly.Query(context.Background()).Where(Column[int]{Name: "id", Table: "active_users"}.Eq(7)).Delete()
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
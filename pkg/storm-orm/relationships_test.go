@@ -2,8 +2,8 @@ package orm
 
 import (
 	"context"
-	"database/sql"
 	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
@@ -108,7 +108,7 @@ type RelTestPost struct {
 	User *RelTestUser `storm:"relation:belongs_to:RelTestUser;foreign_key:user_id;target_key:id"`
 }
 
-// Create test metadata with ScanToModel functions
+// Create test metadata with Load functions shaped like the generated ones
 var RelTestUserMetadata = &ModelMetadata{
 	TableName:  "users",
 	StructName: "RelTestUser",
@@ -156,16 +156,26 @@ var RelTestUserMetadata = &ModelMetadata{
 			Target:     "RelTestProfile",
 			ForeignKey: "UserID",
 			SourceKey:  "ID",
-			ScanToModel: func(ctx context.Context, exec DBExecutor, query string, args []interface{}, model interface{}) error {
-				var profile RelTestProfile
-				err := exec.GetContext(ctx, &profile, query, args...)
-				if err != nil {
-					if err == sql.ErrNoRows {
-						return nil // has_one can be nil
+			Load: func(ctx context.Context, exec DBExecutor, query string, args []interface{}, models []interface{}) error {
+				var rows []RelTestProfile
+				if query != "" {
+					if err := exec.SelectContext(ctx, &rows, query, args...); err != nil {
+						return err
+					}
+				}
+				related := make(map[interface{}]RelTestProfile, len(rows))
+				for _, row := range rows {
+					if _, ok := related[row.UserID]; !ok {
+						related[row.UserID] = row
+					}
+				}
+				for _, model := range models {
+					m := model.(*RelTestUser)
+					m.Profile = nil
+					if row, ok := related[m.ID]; ok {
+						m.Profile = &row
 					}
-					return err
 				}
-				model.(*RelTestUser).Profile = &profile
 				return nil
 			},
 		},
@@ -175,13 +185,24 @@ var RelTestUserMetadata = &ModelMetadata{
 			Target:     "RelTestPost",
 			ForeignKey: "UserID",
 			SourceKey:  "ID",
-			ScanToModel: func(ctx context.Context, exec DBExecutor, query string, args []interface{}, model interface{}) error {
-				var posts []RelTestPost
-				err := exec.SelectContext(ctx, &posts, query, args...)
-				if err != nil {
-					return err
+			Load: func(ctx context.Context, exec DBExecutor, query string, args []interface{}, models []interface{}) error {
+				var rows []RelTestPost
+				if query != "" {
+					if err := exec.SelectContext(ctx, &rows, query, args...); err != nil {
+						return err
+					}
+				}
+				related := make(map[interface{}][]RelTestPost, len(rows))
+				for _, row := range rows {
+					related[row.UserID] = append(related[row.UserID], row)
+				}
+				for _, model := range models {
+					m := model.(*RelTestUser)
+					m.Posts = []RelTestPost{}
+					if rows, ok := related[m.ID]; ok {
+						m.Posts = rows
+					}
 				}
-				model.(*RelTestUser).Posts = posts
 				return nil
 			},
 		},
@@ -227,13 +248,26 @@ var RelTestProfileMetadata = &ModelMetadata{
 			Target:     "RelTestUser",
 			ForeignKey: "UserID",
 			TargetKey:  "ID",
-			ScanToModel: func(ctx context.Context, exec DBExecutor, query string, args []interface{}, model interface{}) error {
-				var user RelTestUser
-				err := exec.GetContext(ctx, &user, query, args...)
-				if err != nil {
-					return err
+			Load: func(ctx context.Context, exec DBExecutor, query string, args []interface{}, models []interface{}) error {
+				var rows []RelTestUser
+				if query != "" {
+					if err := exec.SelectContext(ctx, &rows, query, args...); err != nil {
+						return err
+					}
+				}
+				related := make(map[interface{}]RelTestUser, len(rows))
+				for _, row := range rows {
+					if _, ok := related[row.ID]; !ok {
+						related[row.ID] = row
+					}
+				}
+				for _, model := range models {
+					m := model.(*RelTestProfile)
+					m.User = nil
+					if row, ok := related[m.UserID]; ok {
+						m.User = &row
+					}
 				}
-				model.(*RelTestProfile).User = &user
 				return nil
 			},
 		},
@@ -260,7 +294,7 @@ func TestRelationshipLoading_BelongsTo(t *testing.T) {
 	userRows := sqlmock.NewRows([]string{"id", "name", "email", "created_at"}).
 		AddRow(100, "John Doe", "john@example.com", time.Now())
 
-	mock.ExpectQuery("SELECT (.+) FROM RelTestUser WHERE ID = ?").
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM RelTestUser WHERE ID IN ($1)")).
 		WithArgs(100).
 		WillReturnRows(userRows)
 
@@ -301,7 +335,7 @@ func TestRelationshipLoading_HasOne(t *testing.T) {
 	profileRows := sqlmock.NewRows([]string{"id", "user_id", "bio"}).
 		AddRow(1, 100, "Software Engineer")
 
-	mock.ExpectQuery("SELECT (.+) FROM RelTestProfile WHERE UserID = ?").
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM RelTestProfile WHERE UserID IN ($1)")).
 		WithArgs(100).
 		WillReturnRows(profileRows)
 
@@ -344,7 +378,7 @@ func TestRelationshipLoading_HasMany(t *testing.T) {
 		AddRow(1, 100, "First Post", "This is my first post", now).
 		AddRow(2, 100, "Second Post", "This is my second post", now)
 
-	mock.ExpectQuery("SELECT (.+) FROM RelTestPost WHERE UserID = ?").
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM RelTestPost WHERE UserID IN ($1)")).
 		WithArgs(100).
 		WillReturnRows(postRows)
 
@@ -372,83 +406,86 @@ func TestRelationshipLoading_HasMany(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestScanToModel_BelongsTo(t *testing.T) {
+func TestRelationshipLoading_Batched(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
 
-	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	ctx := context.Background()
+	repo, err := NewRepository[RelTestUser](sqlx.NewDb(db, "sqlmock"), RelTestUserMetadata)
+	require.NoError(t, err)
 
-	profile := &RelTestProfile{
-		ID:     1,
-		UserID: 100,
-		Bio:    "Software Engineer",
-	}
+	ctx := context.Background()
+	now := time.Now()
 
-	userRows := sqlmock.NewRows([]string{"id", "name", "email", "created_at"}).
-		AddRow(100, "John Doe", "john@example.com", time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "created_at"}).
+			AddRow(100, "John Doe", "john@example.com", now).
+			AddRow(200, "Jane Doe", "jane@example.com", now).
+			AddRow(300, "Jim Doe", "jim@example.com", now))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM RelTestPost WHERE UserID IN ($1,$2,$3)")).
+		WithArgs(100, 200, 300).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "created_at"}).
+			AddRow(1, 100, "First Post", "", now).
+			AddRow(2, 300, "Other Post", "", now).
+			AddRow(3, 100, "Second Post", "", now))
 
-	mock.ExpectQuery("SELECT (.+) FROM RelTestUser WHERE ID = ?").
-		WithArgs(100).
-		WillReturnRows(userRows)
+	users, err := repo.Query(ctx).Include("Posts").Find()
+	require.NoError(t, err)
+	require.Len(t, users, 3)
 
-	userRelationship := RelTestProfileMetadata.Relationships["User"]
-	require.NotNil(t, userRelationship)
+	require.Len(t, users[0].Posts, 2)
+	assert.Equal(t, "First Post", users[0].Posts[0].Title)
+	assert.Equal(t, "Second Post", users[0].Posts[1].Title)
 
-	err = userRelationship.ScanToModel(ctx, sqlxDB, "SELECT id, name, email, created_at FROM RelTestUser WHERE ID = ?", []interface{}{100}, profile)
-	require.NoError(t, err)
+	assert.NotNil(t, users[1].Posts, "loaded has_many without rows is empty, not nil")
+	assert.Empty(t, users[1].Posts)
 
-	require.NotNil(t, profile.User)
-	assert.Equal(t, int64(100), profile.User.ID)
-	assert.Equal(t, "John Doe", profile.User.Name)
-	assert.Equal(t, "john@example.com", profile.User.Email)
+	require.Len(t, users[2].Posts, 1)
+	assert.Equal(t, int64(2), users[2].Posts[0].ID)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestScanToModel_HasMany(t *testing.T) {
+func TestRelationshipLoading_NoMatch(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
 
-	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	ctx := context.Background()
-
-	user := &RelTestUser{
-		ID:    100,
-		Name:  "John Doe",
-		Email: "john@example.com",
-	}
-
-	now := time.Now()
-	postRows := sqlmock.NewRows([]string{"id", "user_id", "title", "content", "created_at"}).
-		AddRow(1, 100, "First Post", "This is my first post", now).
-		AddRow(2, 100, "Second Post", "This is my second post", now)
-
-	mock.ExpectQuery("SELECT (.+) FROM RelTestPost WHERE UserID = ?").
-		WithArgs(100).
-		WillReturnRows(postRows)
-
-	postsRelationship := RelTestUserMetadata.Relationships["Posts"]
-	require.NotNil(t, postsRelationship)
-
-	err = postsRelationship.ScanToModel(ctx, sqlxDB, "SELECT id, user_id, title, content, created_at FROM RelTestPost WHERE UserID = ?", []interface{}{100}, user)
+	repo, err := NewRepository[RelTestProfile](sqlx.NewDb(db, "sqlmock"), RelTestProfileMetadata)
 	require.NoError(t, err)
 
-	require.Len(t, user.Posts, 2)
+	ctx := context.Background()
 
-	assert.Equal(t, int64(1), user.Posts[0].ID)
-	assert.Equal(t, int64(100), user.Posts[0].UserID)
-	assert.Equal(t, "First Post", user.Posts[0].Title)
-	assert.Equal(t, "This is my first post", user.Posts[0].Content)
+	t.Run("missing owner stays nil", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM profiles").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bio"}).
+				AddRow(1, 100, "Orphan").
+				AddRow(2, 100, "Another orphan"))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM RelTestUser WHERE ID IN ($1)")).
+			WithArgs(100).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "created_at"}))
+
+		profiles, err := repo.Query(ctx).Include("User").Find()
+		require.NoError(t, err)
+		require.Len(t, profiles, 2)
+		assert.Nil(t, profiles[0].User)
+		assert.Nil(t, profiles[1].User)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-	assert.Equal(t, int64(2), user.Posts[1].ID)
-	assert.Equal(t, int64(100), user.Posts[1].UserID)
-	assert.Equal(t, "Second Post", user.Posts[1].Title)
-	assert.Equal(t, "This is my second post", user.Posts[1].Content)
+	t.Run("records without keys skip the query", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM profiles").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bio"}).
+				AddRow(1, 0, "Unowned"))
 
-	assert.NoError(t, mock.ExpectationsWereMet())
+		profiles, err := repo.Query(ctx).Include("User").Find()
+		require.NoError(t, err)
+		require.Len(t, profiles, 1)
+		assert.Nil(t, profiles[0].User)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 // parseRelationshipTag is not exported, so we can't test it directly.
@@ -3,7 +3,9 @@ package orm
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -21,6 +23,21 @@ type Repository[T any] struct {
 
 	// Authorization functions
 	authorizeFuncs []AuthorizeFunc[T]
+
+	// Named and default query scopes
+	scopes []scope[T]
+
+	// Default timeout applied to operations and queries
+	timeout time.Duration
+
+	// Columns set to NOW() on every update
+	nowOnUpdate []string
+
+	// Columns stamped with the actor of each write
+	actor actorColumns
+
+	// Options of the Storm the repository was created from, nil otherwise
+	options *queryOptions
 }
 
 func NewRepository[T any](db *sqlx.DB, metadata *ModelMetadata) (*Repository[T], error) {
@@ -38,6 +55,24 @@ func NewRepositoryWithTx[T any](tx *sqlx.Tx, metadata *ModelMetadata) (*Reposito
 	return NewRepositoryWithExecutor[T](tx, metadata)
 }
 
+// NewRepositoryForStorm creates a repository on the connection or transaction
+// of s that follows the options set on s, such as SetUnaccent
+func NewRepositoryForStorm[T any](s *Storm, metadata *ModelMetadata) (*Repository[T], error) {
+	if s == nil {
+		return nil, &Error{
+			Op:  "initialize",
+			Err: fmt.Errorf("storm cannot be nil"),
+		}
+	}
+
+	repo, err := NewRepositoryWithExecutor[T](s.executor, metadata)
+	if err != nil {
+		return nil, err
+	}
+	repo.options = s.options
+	return repo, nil
+}
+
 func NewRepositoryWithExecutor[T any](executor DBExecutor, metadata *ModelMetadata) (*Repository[T], error) {
 	if executor == nil {
 		return nil, &Error{
@@ -83,6 +118,12 @@ func (r *Repository[T]) initialize() error {
 
 	r.middlewareManager = newMiddlewareManager()
 
+	for _, col := range r.metadata.OrderedColumns() {
+		if col.AutoUpdateTime {
+			r.nowOnUpdate = append(r.nowOnUpdate, col.DBName)
+		}
+	}
+
 	return nil
 }
 
@@ -96,12 +137,23 @@ func (r *Repository[T]) PrimaryKeys() []string {
 
 func (r *Repository[T]) Columns() []string {
 	columns := make([]string, 0, len(r.metadata.Columns))
-	for _, col := range r.metadata.Columns {
+	for _, col := range r.metadata.OrderedColumns() {
 		columns = append(columns, col.DBName)
 	}
 	return columns
 }
 
+// selectColumns returns the SELECT list, expanding computed columns into their
+// expressions. The slice is shared and must not be modified.
+func (r *Repository[T]) selectColumns() []string {
+	return r.metadata.derived().selectColumns
+}
+
+// quotedTable returns the table name ready to be written into SQL
+func (r *Repository[T]) quotedTable() string {
+	return r.metadata.derived().quotedTable
+}
+
 // getRelationship returns the relationship metadata for the given relationship name
 func (r *Repository[T]) getRelationship(name string) *RelationshipMetadata {
 	if r.metadata.Relationships == nil {
@@ -121,17 +173,30 @@ func (r *Repository[T]) Authorize(fn AuthorizeFunc[T]) *Repository[T] {
 	copy(newFuncs, r.authorizeFuncs)
 	newFuncs[len(r.authorizeFuncs)] = fn
 
-	return &Repository[T]{
-		db:                r.db,
-		metadata:          r.metadata,
-		middlewareManager: r.middlewareManager,
-		authorizeFuncs:    newFuncs,
-	}
+	clone := r.clone()
+	clone.authorizeFuncs = newFuncs
+	return clone
+}
+
+// clone returns a shallow copy of the repository
+func (r *Repository[T]) clone() *Repository[T] {
+	clone := *r
+	return &clone
 }
 
 func (r *Repository[T]) getInsertFields(model T) (columns []string, values []interface{}) {
-	for _, colMeta := range r.metadata.Columns {
-		if colMeta.IsAutoGenerated {
+	return insertFields(r.options, r.metadata, model)
+}
+
+// insertFields returns the columns and values to insert for model, a value of
+// the struct described by metadata, with encrypted values bound to the keys of
+// options
+func insertFields(options *queryOptions, metadata *ModelMetadata, model interface{}) (columns []string, values []interface{}) {
+	ordered := metadata.OrderedColumns()
+	columns = make([]string, 0, len(ordered))
+	values = make([]interface{}, 0, len(ordered))
+	for _, colMeta := range ordered {
+		if colMeta.IsAutoGenerated || colMeta.Computed != "" {
 			continue
 		}
 
@@ -139,29 +204,53 @@ func (r *Repository[T]) getInsertFields(model T) (columns []string, values []int
 			continue
 		}
 
+		autoTime := colMeta.AutoCreateTime || colMeta.AutoUpdateTime
+
 		if colMeta.IsPointer && colMeta.IsNil != nil {
 			if colMeta.IsNil(model) {
+				if autoTime {
+					columns = append(columns, colMeta.DBName)
+					values = append(values, squirrel.Expr("NOW()"))
+				}
 				continue
 			}
 		}
 
 		value := colMeta.GetValue(model)
+		if autoTime && isZeroTime(value) {
+			value = squirrel.Expr("NOW()")
+		}
 
 		columns = append(columns, colMeta.DBName)
-		values = append(values, value)
+		values = append(values, options.bind(value))
 	}
 
 	return columns, values
 }
 
-func (r *Repository[T]) getAutoGeneratedColumns() []string {
-	var cols []string
-	for _, col := range r.metadata.Columns {
-		if col.IsAutoGenerated {
-			cols = append(cols, col.DBName)
-		}
+// isZeroTime reports whether a timestamp value was left unset
+func isZeroTime(value interface{}) bool {
+	if t, ok := value.(interface{ IsZero() bool }); ok {
+		return t.IsZero()
 	}
-	return cols
+	return value == nil
+}
+
+func (r *Repository[T]) getAutoGeneratedColumns() []string {
+	return autoGeneratedColumns(r.metadata)
+}
+
+// autoGeneratedColumns returns the columns the database fills on insert,
+// including the timestamps insertFields may set to NOW(). The slice is shared
+// and must not be modified.
+func autoGeneratedColumns(metadata *ModelMetadata) []string {
+	return metadata.derived().autoGenerated
+}
+
+// returningClause returns the RETURNING clause reading autoGeneratedColumns
+// back after an insert, or "" when there are none
+func returningClause(metadata *ModelMetadata) string {
+	return metadata.derived().returning
 }
 
 func (r *Repository[T]) getPrimaryKeyValues(record T) map[string]interface{} {
@@ -169,7 +258,7 @@ func (r *Repository[T]) getPrimaryKeyValues(record T) map[string]interface{} {
 	for _, pkCol := range r.metadata.PrimaryKeys {
 		fieldName := r.metadata.ReverseMap[pkCol]
 		if colMeta, exists := r.metadata.Columns[fieldName]; exists && colMeta.GetValue != nil {
-			pkValues[pkCol] = colMeta.GetValue(record)
+			pkValues[pkCol] = r.options.bind(colMeta.GetValue(record))
 		}
 	}
 	return pkValues
@@ -178,12 +267,16 @@ func (r *Repository[T]) getPrimaryKeyValues(record T) map[string]interface{} {
 func (r *Repository[T]) getUpdateFields(model T) map[string]interface{} {
 	fields := make(map[string]interface{})
 
-	for _, colMeta := range r.metadata.Columns {
+	for _, colMeta := range r.metadata.OrderedColumns() {
 		if colMeta.IsPrimaryKey {
 			continue
 		}
 
-		if colMeta.IsAutoGenerated {
+		if colMeta.IsAutoGenerated || colMeta.IsImmutable || colMeta.Computed != "" {
+			continue
+		}
+
+		if colMeta.AutoCreateTime || colMeta.AutoUpdateTime {
 			continue
 		}
 
@@ -192,8 +285,69 @@ func (r *Repository[T]) getUpdateFields(model T) map[string]interface{} {
 		}
 
 		value := colMeta.GetValue(model)
-		fields[colMeta.DBName] = value
+		fields[colMeta.DBName] = r.options.bind(value)
 	}
 
 	return fields
 }
+
+// columnByDBName returns the metadata for a database column name, or nil if unknown
+func (r *Repository[T]) columnByDBName(name string) *ColumnMetadata {
+	if fieldName, ok := r.metadata.ReverseMap[name]; ok {
+		if col, exists := r.metadata.Columns[fieldName]; exists {
+			return col
+		}
+	}
+	return r.metadata.derived().byDBName[name]
+}
+
+// validateColumns ensures every name refers to a known column of the model
+func (r *Repository[T]) validateColumns(op string, columns []string) error {
+	for _, name := range columns {
+		if r.columnByDBName(name) == nil {
+			return &Error{
+				Op:     op,
+				Table:  r.metadata.TableName,
+				Column: name,
+				Err:    ErrUnknownColumn,
+			}
+		}
+	}
+	return nil
+}
+
+// validateUpdateColumns ensures every name refers to a known column that may be updated
+func (r *Repository[T]) validateUpdateColumns(op string, columns []string) error {
+	if err := r.validateColumns(op, columns); err != nil {
+		return err
+	}
+	for _, name := range columns {
+		if col := r.columnByDBName(name); col.IsPrimaryKey || col.IsImmutable || col.Computed != "" {
+			return &Error{
+				Op:     op,
+				Table:  r.metadata.TableName,
+				Column: name,
+				Err:    ErrImmutableColumn,
+			}
+		}
+	}
+	return nil
+}
+
+// isCreationColumn reports whether the named column records when or by whom
+// the row was created, which upserts leave alone on conflict
+func (r *Repository[T]) isCreationColumn(name string) bool {
+	if name == r.actor.createdBy {
+		return true
+	}
+	col := r.columnByDBName(name)
+	return col != nil && col.AutoCreateTime
+}
+
+// isImmutableColumn reports whether the named column, which may be qualified by
+// the table or an alias, may only be set on insert or is computed and cannot be
+// written at all
+func (r *Repository[T]) isImmutableColumn(name string) bool {
+	col := r.columnByDBName(unqualifiedColumn(name))
+	return col != nil && (col.IsImmutable || col.Computed != "")
+}
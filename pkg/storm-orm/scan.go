@@ -0,0 +1,140 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// selectRecords runs query and scans every row into a T, decrypting its
+// encrypted columns. Rows whose columns are all among the model's generated
+// ScanColumns are scanned through ScanFields, without reflection; anything
+// else is left to sqlx.
+func (r *Repository[T]) selectRecords(ctx context.Context, exec DBExecutor, query string, args ...interface{}) ([]T, error) {
+	records, err := r.scanRecords(ctx, exec, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return records, r.options.decrypt(&records)
+}
+
+// scanRecords is selectRecords leaving encrypted columns as they are stored
+func (r *Repository[T]) scanRecords(ctx context.Context, exec DBExecutor, query string, args ...interface{}) ([]T, error) {
+	var records []T
+	if r.metadata.ScanFields == nil {
+		err := exec.SelectContext(ctx, &records, query, args...)
+		return records, err
+	}
+
+	rows, err := exec.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	// Every row is scanned into the same record, reset in between, so the
+	// field pointers are built once per query
+	var record, zero T
+	fields, ok := scanFields(r.metadata, &record, columns)
+	if !ok {
+		err := sqlx.StructScan(rows, &records)
+		return records, err
+	}
+	for rows.Next() {
+		record = zero
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// getRecord runs query, which must return at most one row, and scans the row
+// into record, a pointer to a model described by metadata, like
+// selectRecords. It returns sql.ErrNoRows when there is no row. It reads the
+// columns an INSERT or UPDATE returns into a record that holds the values it
+// wrote, so encrypted columns are not decrypted.
+func getRecord(ctx context.Context, exec DBExecutor, metadata *ModelMetadata, record interface{}, query string, args ...interface{}) error {
+	if metadata.ScanFields == nil {
+		return exec.GetContext(ctx, record, query, args...)
+	}
+
+	rows, err := exec.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	if fields, ok := scanFields(metadata, record, columns); ok {
+		err = rows.Scan(fields...)
+	} else {
+		err = rows.StructScan(record)
+	}
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// scanFields returns the ScanFields pointers into record for columns, in
+// order, reporting false unless every column is a distinct one of the
+// model's ScanColumns
+func scanFields(metadata *ModelMetadata, record interface{}, columns []string) ([]interface{}, bool) {
+	if metadata.ScanFields == nil {
+		return nil, false
+	}
+
+	if sameColumns(columns, metadata.ScanColumns) {
+		return metadata.ScanFields(record), true
+	}
+
+	index := metadata.derived().scanIndex
+	positions := make([]int, len(columns))
+	seen := make(map[int]bool, len(columns))
+	for i, column := range columns {
+		position, ok := index[column]
+		if !ok || seen[position] {
+			return nil, false
+		}
+		seen[position] = true
+		positions[i] = position
+	}
+
+	all := metadata.ScanFields(record)
+	fields := make([]interface{}, len(columns))
+	for i, position := range positions {
+		fields[i] = all[position]
+	}
+	return fields, true
+}
+
+// sameColumns reports whether a and b list the same columns in the same order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var scanTestColumns = []string{"id", "name", "email", "is_active", "created_at", "updated_at"}
+
+// withGeneratedScan adds the ScanColumns and ScanFields the generator would
+// emit for TestUser
+func withGeneratedScan(metadata *ModelMetadata) *ModelMetadata {
+	metadata.ScanColumns = scanTestColumns
+	metadata.ScanFields = func(model interface{}) []interface{} {
+		m := model.(*TestUser)
+		return []interface{}{&m.ID, &m.Name, &m.Email, &m.IsActive, &m.CreatedAt, &m.UpdatedAt}
+	}
+	return metadata
+}
+
+func TestSelectRecords(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := withGeneratedScan(createTestUserMetadata())
+	scanned := 0
+	scanFields := metadata.ScanFields
+	metadata.ScanFields = func(model interface{}) []interface{} {
+		scanned++
+		return scanFields(model)
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	t.Run("scans matching columns through ScanFields", func(t *testing.T) {
+		scanned = 0
+		mock.ExpectQuery("SELECT (.+) FROM users").
+			WillReturnRows(sqlmock.NewRows(scanTestColumns).
+				AddRow(1, "Alice", "alice@example.com", true, now, now).
+				AddRow(2, "Bob", "bob@example.com", false, now, now))
+
+		users, err := repo.Query(context.Background()).Find()
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, 1, scanned, "field pointers are built once per query")
+		assert.Equal(t, "Bob", users[1].Name)
+		assert.False(t, users[1].IsActive)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("scans a subset of columns through ScanFields", func(t *testing.T) {
+		scanned = 0
+		mock.ExpectQuery("SELECT (.+) FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "id"}).
+				AddRow("Carol", 3))
+
+		users, err := repo.Query(context.Background()).Find()
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, 1, scanned)
+		assert.Equal(t, TestUser{ID: 3, Name: "Carol"}, users[0])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("falls back to sqlx for other columns", func(t *testing.T) {
+		scanned = 0
+		mock.ExpectQuery("SELECT (.+) FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "id"}).
+				AddRow(5, "Dan", 5))
+
+		users, err := repo.Query(context.Background()).Find()
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, 0, scanned)
+		assert.Equal(t, TestUser{ID: 5, Name: "Dan"}, users[0])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FindByID reports a missing row", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM users").
+			WillReturnRows(sqlmock.NewRows(scanTestColumns))
+
+		_, err := repo.FindByID(context.Background(), 4)
+		assert.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestGeneratedScanAllocations gates the generated scan path: reading the
+// same rows must allocate less than scanning them with sqlx
+func TestGeneratedScanAllocations(t *testing.T) {
+	allocs := func(metadata *ModelMetadata) float64 {
+		repo, err := NewRepository[TestUser](openBenchDB(t), metadata)
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		return testing.AllocsPerRun(20, func() {
+			if _, err := repo.Query(ctx).Find(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	generated := allocs(withGeneratedScan(createTestUserMetadata()))
+	reflection := allocs(createTestUserMetadata())
+	assert.Less(t, generated, reflection-benchRowCount,
+		"generated scan should save at least one allocation per row")
+}
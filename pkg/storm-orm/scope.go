@@ -0,0 +1,102 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// ScopeFunc refines a query with a reusable set of conditions
+type ScopeFunc[T any] func(query *Query[T]) *Query[T]
+
+// scope is a named ScopeFunc registered on a repository
+type scope[T any] struct {
+	name      string
+	fn        ScopeFunc[T]
+	isDefault bool
+}
+
+// Scope returns a new Repository with a named scope that queries can apply via Query.Scoped
+func (r *Repository[T]) Scope(name string, fn ScopeFunc[T]) *Repository[T] {
+	return r.addScope(scope[T]{name: name, fn: fn})
+}
+
+// DefaultScope returns a new Repository with a named scope applied to every query.
+// Use Query.Unscoped to bypass it.
+func (r *Repository[T]) DefaultScope(name string, fn ScopeFunc[T]) *Repository[T] {
+	return r.addScope(scope[T]{name: name, fn: fn, isDefault: true})
+}
+
+func (r *Repository[T]) addScope(s scope[T]) *Repository[T] {
+	scopes := make([]scope[T], 0, len(r.scopes)+1)
+	for _, existing := range r.scopes {
+		if existing.name != s.name {
+			scopes = append(scopes, existing)
+		}
+	}
+	scopes = append(scopes, s)
+
+	clone := r.clone()
+	clone.scopes = scopes
+	return clone
+}
+
+func (r *Repository[T]) findScope(name string) *scope[T] {
+	for i := range r.scopes {
+		if r.scopes[i].name == name {
+			return &r.scopes[i]
+		}
+	}
+	return nil
+}
+
+// SQLScope builds a ScopeFunc from a raw SQL condition
+func SQLScope[T any](condition string, args ...interface{}) ScopeFunc[T] {
+	return func(query *Query[T]) *Query[T] {
+		return query.Where(Condition{squirrel.Expr(condition, args...)})
+	}
+}
+
+// Scoped applies the named scopes registered on the repository
+func (q *Query[T]) Scoped(names ...string) *Query[T] {
+	for _, name := range names {
+		if q.err != nil {
+			return q
+		}
+
+		s := q.repo.findScope(name)
+		if s == nil {
+			q.err = fmt.Errorf("scope %s not found", name)
+			return q
+		}
+		q.apply(s.fn)
+	}
+	return q
+}
+
+// Unscoped disables the repository's default scopes for this query
+func (q *Query[T]) Unscoped() *Query[T] {
+	q.unscoped = true
+	return q
+}
+
+// applyDefaultScopes applies the repository's default scopes once, right before execution
+func (q *Query[T]) applyDefaultScopes() {
+	if q.unscoped || q.defaultScoped {
+		return
+	}
+	q.defaultScoped = true
+
+	for _, s := range q.repo.scopes {
+		if s.isDefault {
+			q.apply(s.fn)
+		}
+	}
+}
+
+// apply runs fn against the query, keeping q as the query being built
+func (q *Query[T]) apply(fn ScopeFunc[T]) {
+	if result := fn(q); result != nil && result != q {
+		*q = *result
+	}
+}
@@ -0,0 +1,108 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	base, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	repo := base.
+		DefaultScope("active", SQLScope[TestUser]("is_active = ?", true)).
+		Scope("named", func(query *Query[TestUser]) *Query[TestUser] {
+			return query.Where(Column[string]{Name: "name", Table: "users"}.Eq("john"))
+		})
+
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "email", "is_active", "created_at", "updated_at"})
+	}
+
+	t.Run("default scope applies to Find", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(is_active = \$1\)`).
+			WithArgs(true).
+			WillReturnRows(userRows())
+
+		_, err := repo.Query(context.Background()).Find()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("default scope applies to Count", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE \(is_active = \$1\)`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		count, err := repo.Query(context.Background()).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("default scope applies to Update", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET name = \$1 WHERE \(is_active = \$2\)`).
+			WithArgs("jane", true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := repo.Query(context.Background()).
+			Update(Column[string]{Name: "name", Table: "users"}.Set("jane"))
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Unscoped skips default scopes", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users$`).
+			WillReturnRows(userRows())
+
+		_, err := repo.Query(context.Background()).Unscoped().Find()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Scoped applies named scope", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(users\.name = \$1 AND is_active = \$2\)`).
+			WithArgs("john", true).
+			WillReturnRows(userRows())
+
+		_, err := repo.Query(context.Background()).Scoped("named").Find()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Scoped with unknown scope", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).Scoped("missing").Find()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "scope missing not found")
+	})
+
+	t.Run("scopes do not leak into the base repository", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users$`).
+			WillReturnRows(userRows())
+
+		_, err := base.Query(context.Background()).Find()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("redefining a scope replaces it", func(t *testing.T) {
+		replaced := repo.DefaultScope("active", SQLScope[TestUser]("is_active = ?", false))
+
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(is_active = \$1\)`).
+			WithArgs(false).
+			WillReturnRows(userRows())
+
+		_, err := replaced.Query(context.Background()).Find()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
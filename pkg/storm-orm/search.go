@@ -0,0 +1,41 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+)
+
+// unaccentFunction is the IMMUTABLE wrapper around unaccent created by
+// migrations of models with a ci_index:unaccent column
+const unaccentFunction = "storm_unaccent"
+
+// SetUnaccent sets whether StringColumn.ISearch also ignores accents in the
+// queries of this Storm and the repositories created from it. It needs the
+// storm_unaccent function, which migrations create alongside the indexes of
+// ci_index:unaccent columns; DetectUnaccent enables it when that exists.
+func (s *Storm) SetUnaccent(enabled bool) {
+	s.options.unaccent.Store(enabled)
+}
+
+// DetectUnaccent enables accent insensitive search on this Storm when the
+// database has the storm_unaccent function, and reports whether it does
+func (s *Storm) DetectUnaccent(ctx context.Context) (bool, error) {
+	var exists bool
+	err := s.executor.GetContext(ctx, &exists,
+		"SELECT EXISTS (SELECT 1 FROM pg_proc WHERE proname = $1)", unaccentFunction)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up %s: %w", unaccentFunction, err)
+	}
+	s.SetUnaccent(exists)
+	return exists, nil
+}
+
+// foldedExpr returns expr lower cased and, when unaccent is enabled, with
+// accents removed, matching the expression of the generated ci_index
+func foldedExpr(expr string, unaccent bool) string {
+	expr = "lower(" + expr + ")"
+	if unaccent {
+		expr = unaccentFunction + "(" + expr + ")"
+	}
+	return expr
+}
@@ -0,0 +1,57 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+type settingsKey struct{}
+
+// WithSettings returns a context carrying run-time parameters, such as the
+// app.tenant a row level security policy reads with current_setting, on top
+// of any ctx already carries. Transactions begun with the context set them
+// with set_config(name, value, true), so they end with the transaction and
+// never reach other requests sharing the connection. Queries run outside a
+// transaction do not see them.
+func WithSettings(ctx context.Context, settings map[string]string) context.Context {
+	merged := make(map[string]string)
+	for name, value := range SettingsFromContext(ctx) {
+		merged[name] = value
+	}
+	for name, value := range settings {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, settingsKey{}, merged)
+}
+
+// SettingsFromContext returns the settings stored in ctx by WithSettings
+func SettingsFromContext(ctx context.Context) map[string]string {
+	settings, _ := ctx.Value(settingsKey{}).(map[string]string)
+	return settings
+}
+
+// applySettings sets the context's settings, then those of opts, for the
+// current transaction
+func applySettings(ctx context.Context, exec interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}, opts *TransactionOptions) error {
+	settings := SettingsFromContext(ctx)
+	if opts != nil && len(opts.Settings) > 0 {
+		settings = SettingsFromContext(WithSettings(ctx, opts.Settings))
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := exec.ExecContext(ctx, "SELECT set_config($1, $2, true)", name, settings[name]); err != nil {
+			return fmt.Errorf("failed to apply setting %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSettings(t *testing.T) {
+	ctx := WithSettings(context.Background(), map[string]string{"app.tenant": "a", "app.user": "1"})
+	ctx = WithSettings(ctx, map[string]string{"app.tenant": "b"})
+
+	assert.Equal(t, map[string]string{"app.tenant": "b", "app.user": "1"}, SettingsFromContext(ctx))
+	assert.Nil(t, SettingsFromContext(context.Background()))
+}
+
+func TestTransactionSettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	ctx := WithSettings(context.Background(), map[string]string{"app.tenant": "tenant-1", "app.user": "42"})
+
+	t.Run("applies context and option settings", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT set_config\(\$1, \$2, true\)`).WithArgs("app.role", "admin").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SELECT set_config\(\$1, \$2, true\)`).WithArgs("app.tenant", "tenant-1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SELECT set_config\(\$1, \$2, true\)`).WithArgs("app.user", "7").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		opts := &TransactionOptions{Settings: map[string]string{"app.user": "7", "app.role": "admin"}}
+		require.NoError(t, storm.WithTransactionOptions(ctx, opts, func(*Storm) error { return nil }))
+	})
+
+	t.Run("rolls back when a setting fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT set_config`).WithArgs("app.tenant", "tenant-1").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		called := false
+		err := storm.WithTransaction(ctx, func(*Storm) error {
+			called = true
+			return nil
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to apply setting app.tenant")
+		assert.False(t, called)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionSetup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	storm.OnTransaction(func(ctx context.Context, tx DBExecutor) error {
+		_, err := tx.ExecContext(ctx, "SET LOCAL ROLE app_user")
+		return err
+	})
+
+	t.Run("runs registered and option setup in order", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL ROLE app_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SET LOCAL search_path TO tenant_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		opts := &TransactionOptions{Setup: func(ctx context.Context, tx DBExecutor) error {
+			_, err := tx.ExecContext(ctx, "SET LOCAL search_path TO tenant_1")
+			return err
+		}}
+		require.NoError(t, storm.WithTransactionOptions(context.Background(), opts, func(tx *Storm) error {
+			return tx.WithTransaction(context.Background(), func(*Storm) error { return nil })
+		}))
+	})
+
+	t.Run("rolls back when setup fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL ROLE app_user`).WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		called := false
+		err := storm.WithTransaction(context.Background(), func(*Storm) error {
+			called = true
+			return nil
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "transaction setup failed")
+		assert.False(t, called)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,567 @@
+package orm
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ShardSet is a fixed list of databases holding the same tables, each with a
+// share of the rows. Rows are placed by hashing their shard key modulo the
+// number of shards, so adding or removing a shard moves most rows; the list
+// and its order must stay the same for as long as the data lives.
+type ShardSet struct {
+	dbs []*sqlx.DB
+}
+
+// NewShardSet groups already opened databases into a ShardSet. The order of
+// dbs decides which shard every key maps to.
+func NewShardSet(dbs ...*sqlx.DB) (*ShardSet, error) {
+	if len(dbs) == 0 {
+		return nil, &Error{Op: "shards", Err: fmt.Errorf("at least one shard is required")}
+	}
+	for i, db := range dbs {
+		if db == nil {
+			return nil, &Error{Op: "shards", Err: fmt.Errorf("shard %d: database cannot be nil", i)}
+		}
+	}
+	return &ShardSet{dbs: dbs}, nil
+}
+
+// OpenShards opens one database per URL and groups them into a ShardSet
+func OpenShards(driverName string, urls ...string) (*ShardSet, error) {
+	dbs := make([]*sqlx.DB, 0, len(urls))
+	for i, url := range urls {
+		db, err := sqlx.Open(driverName, url)
+		if err != nil {
+			for _, opened := range dbs {
+				opened.Close()
+			}
+			return nil, &Error{Op: "shards", Err: fmt.Errorf("shard %d: %w", i, err)}
+		}
+		dbs = append(dbs, db)
+	}
+	return NewShardSet(dbs...)
+}
+
+// Len returns the number of shards
+func (s *ShardSet) Len() int {
+	return len(s.dbs)
+}
+
+// DB returns the database of shard i
+func (s *ShardSet) DB(i int) *sqlx.DB {
+	return s.dbs[i]
+}
+
+// Index returns the shard a shard key value belongs to. Values are hashed by
+// their text form, so an int64 and a string holding the same digits land on
+// the same shard.
+func (s *ShardSet) Index(key interface{}) (int, error) {
+	data, err := shardKeyBytes(key)
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New32a()
+	h.Write(data)
+	return int(h.Sum32() % uint32(len(s.dbs))), nil
+}
+
+// Close closes every shard
+func (s *ShardSet) Close() error {
+	var errs []error
+	for i, db := range s.dbs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func shardKeyBytes(key interface{}) ([]byte, error) {
+	if valuer, ok := key.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		key = value
+	}
+
+	v := reflect.ValueOf(key)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, ErrNoShardKey
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, ErrNoShardKey
+	}
+
+	switch value := v.Interface().(type) {
+	case []byte:
+		return value, nil
+	case string:
+		return []byte(value), nil
+	default:
+		return []byte(fmt.Sprint(value)), nil
+	}
+}
+
+// ShardError reports which shard a sharded operation failed on
+type ShardError struct {
+	Shard int
+	Err   error
+}
+
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("shard %d: %v", e.Shard, e.Err)
+}
+
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// ShardedRepository routes every operation on T to the shard that owns the
+// record, chosen by the model's shard key column (storm:"shard_key:column").
+// Writes and lookups go to a single shard; FanOut queries all of them.
+type ShardedRepository[T any] struct {
+	shards   *ShardSet
+	repos    []*Repository[T]
+	metadata *ModelMetadata
+	key      *ColumnMetadata
+}
+
+// NewShardedRepository creates a repository per shard for a model that
+// declares a shard key
+func NewShardedRepository[T any](shards *ShardSet, metadata *ModelMetadata) (*ShardedRepository[T], error) {
+	if shards == nil || metadata == nil {
+		return nil, &Error{Op: "initialize", Err: fmt.Errorf("shards and metadata cannot be nil")}
+	}
+	if metadata.ShardKey == "" {
+		return nil, &Error{Op: "initialize", Table: metadata.TableName, Err: ErrNoShardKey}
+	}
+
+	key, ok := metadata.derived().byDBName[metadata.ShardKey]
+	if !ok {
+		return nil, &Error{Op: "initialize", Table: metadata.TableName, Column: metadata.ShardKey, Err: ErrUnknownColumn}
+	}
+
+	repos := make([]*Repository[T], shards.Len())
+	for i := range repos {
+		repo, err := NewRepository[T](shards.DB(i), metadata)
+		if err != nil {
+			return nil, err
+		}
+		repos[i] = repo
+	}
+
+	return &ShardedRepository[T]{
+		shards:   shards,
+		repos:    repos,
+		metadata: metadata,
+		key:      key,
+	}, nil
+}
+
+// Shards returns the repository of every shard, in shard order
+func (s *ShardedRepository[T]) Shards() []*Repository[T] {
+	repos := make([]*Repository[T], len(s.repos))
+	copy(repos, s.repos)
+	return repos
+}
+
+// For returns the repository of the shard owning the given shard key value.
+// Use it for queries that stay within one shard.
+func (s *ShardedRepository[T]) For(key interface{}) (*Repository[T], error) {
+	i, err := s.shards.Index(key)
+	if err != nil {
+		return nil, &Error{Op: "shard", Table: s.metadata.TableName, Column: s.metadata.ShardKey, Err: err}
+	}
+	return s.repos[i], nil
+}
+
+func (s *ShardedRepository[T]) shardOf(op string, record T) (int, error) {
+	i, err := s.shards.Index(s.key.GetValue(record))
+	if err != nil {
+		return 0, &Error{Op: op, Table: s.metadata.TableName, Column: s.metadata.ShardKey, Err: err}
+	}
+	return i, nil
+}
+
+func (s *ShardedRepository[T]) forRecord(op string, record *T) (*Repository[T], error) {
+	if record == nil {
+		return nil, &Error{Op: op, Table: s.metadata.TableName, Err: fmt.Errorf("record cannot be nil")}
+	}
+	i, err := s.shardOf(op, *record)
+	if err != nil {
+		return nil, err
+	}
+	return s.repos[i], nil
+}
+
+// Create inserts the record on the shard its shard key belongs to
+func (s *ShardedRepository[T]) Create(ctx context.Context, record *T) (*T, error) {
+	repo, err := s.forRecord("create", record)
+	if err != nil {
+		return nil, err
+	}
+	return repo.Create(ctx, record)
+}
+
+// Update updates the record on its shard. Changing the shard key of an
+// existing record does not move it.
+func (s *ShardedRepository[T]) Update(ctx context.Context, record *T) (*T, error) {
+	repo, err := s.forRecord("update", record)
+	if err != nil {
+		return nil, err
+	}
+	return repo.Update(ctx, record)
+}
+
+// Upsert inserts or updates the record on its shard
+func (s *ShardedRepository[T]) Upsert(ctx context.Context, record *T, opts UpsertOptions) error {
+	repo, err := s.forRecord("upsert", record)
+	if err != nil {
+		return err
+	}
+	return repo.Upsert(ctx, record, opts)
+}
+
+// DeleteRecord deletes the record from its shard
+func (s *ShardedRepository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error) {
+	repo, err := s.forRecord("delete", record)
+	if err != nil {
+		return nil, err
+	}
+	return repo.DeleteRecord(ctx, record)
+}
+
+// FindByID looks the record up on the shard owning key
+func (s *ShardedRepository[T]) FindByID(ctx context.Context, key, id interface{}) (*T, error) {
+	repo, err := s.For(key)
+	if err != nil {
+		return nil, err
+	}
+	return repo.FindByID(ctx, id)
+}
+
+// CreateMany splits the records by shard and inserts each group with
+// Repository.CreateMany. Each shard commits on its own: when one fails the
+// groups already written to earlier shards stay, and the error is a
+// ShardError naming the failed shard.
+func (s *ShardedRepository[T]) CreateMany(ctx context.Context, records []T) error {
+	positions := make([][]int, len(s.repos))
+	for i := range records {
+		shard, err := s.shardOf("createMany", records[i])
+		if err != nil {
+			return err
+		}
+		positions[shard] = append(positions[shard], i)
+	}
+
+	for shard, indexes := range positions {
+		if len(indexes) == 0 {
+			continue
+		}
+
+		group := make([]T, len(indexes))
+		for j, i := range indexes {
+			group[j] = records[i]
+		}
+		if err := s.repos[shard].CreateMany(ctx, group); err != nil {
+			return &ShardError{Shard: shard, Err: err}
+		}
+		for j, i := range indexes {
+			records[i] = group[j]
+		}
+	}
+	return nil
+}
+
+// FanOut starts a query that runs on every shard at once
+func (s *ShardedRepository[T]) FanOut(ctx context.Context) *FanOutQuery[T] {
+	queries := make([]*Query[T], len(s.repos))
+	for i, repo := range s.repos {
+		queries[i] = repo.Query(ctx)
+	}
+	return &FanOutQuery[T]{queries: queries, metadata: s.metadata}
+}
+
+// FanOutQuery runs the same query on every shard concurrently. Find merges
+// the rows of all shards by the OrderBy columns, or keeps them in shard order
+// when none are given, and then applies Limit to the merged rows.
+type FanOutQuery[T any] struct {
+	queries  []*Query[T]
+	metadata *ModelMetadata
+	orderBy  []fanOutOrder
+	limit    *uint64
+	err      error
+}
+
+// fanOutOrder is an OrderBy expression the merged rows are sorted by
+type fanOutOrder struct {
+	column     *ColumnMetadata
+	desc       bool
+	nullsFirst bool
+}
+
+// Where adds a condition to the query on every shard
+func (f *FanOutQuery[T]) Where(condition Condition) *FanOutQuery[T] {
+	for _, q := range f.queries {
+		q.Where(condition)
+	}
+	return f
+}
+
+// OrderBy orders the merged rows. Each expression names a column, optionally
+// followed by ASC or DESC and NULLS FIRST or NULLS LAST; the merge compares
+// the values in Go, so strings sort bytewise as under the C collation.
+func (f *FanOutQuery[T]) OrderBy(expressions ...string) *FanOutQuery[T] {
+	if f.err != nil {
+		return f
+	}
+	for _, expression := range expressions {
+		order, err := f.parseOrder(expression)
+		if err != nil {
+			f.err = err
+			return f
+		}
+		f.orderBy = append(f.orderBy, order)
+	}
+	for _, q := range f.queries {
+		q.OrderBy(expressions...)
+	}
+	return f
+}
+
+// parseOrder reads an OrderBy expression into the column and direction the
+// merged rows are sorted by
+func (f *FanOutQuery[T]) parseOrder(expression string) (fanOutOrder, error) {
+	invalid := &Error{Op: "fanOut", Table: f.metadata.TableName, Err: fmt.Errorf("cannot merge shards ordered by %q", expression)}
+
+	words := strings.Fields(expression)
+	if len(words) == 0 {
+		return fanOutOrder{}, invalid
+	}
+
+	name := unqualifiedColumn(words[0])
+	column, ok := f.metadata.derived().byDBName[name]
+	if !ok || column.GetValue == nil {
+		return fanOutOrder{}, &Error{Op: "fanOut", Table: f.metadata.TableName, Column: name, Err: ErrUnknownColumn}
+	}
+
+	order := fanOutOrder{column: column}
+	words = words[1:]
+	if len(words) > 0 && (strings.EqualFold(words[0], "ASC") || strings.EqualFold(words[0], "DESC")) {
+		order.desc = strings.EqualFold(words[0], "DESC")
+		words = words[1:]
+	}
+	// NULL sorts as the largest value unless the expression says otherwise
+	order.nullsFirst = order.desc
+	if len(words) == 2 && strings.EqualFold(words[0], "NULLS") && (strings.EqualFold(words[1], "FIRST") || strings.EqualFold(words[1], "LAST")) {
+		order.nullsFirst = strings.EqualFold(words[1], "FIRST")
+		words = nil
+	}
+	if len(words) > 0 {
+		return fanOutOrder{}, invalid
+	}
+	return order, nil
+}
+
+// Limit caps the number of merged rows. Each shard reads at most limit rows,
+// as no more than that many of them can make it into the result.
+func (f *FanOutQuery[T]) Limit(limit uint64) *FanOutQuery[T] {
+	f.limit = &limit
+	for _, q := range f.queries {
+		q.Limit(limit)
+	}
+	return f
+}
+
+// Include eager loads relationships on every shard. Related rows are read
+// from the same shard as the row that references them.
+func (f *FanOutQuery[T]) Include(relationships ...string) *FanOutQuery[T] {
+	for _, q := range f.queries {
+		q.Include(relationships...)
+	}
+	return f
+}
+
+// each runs fn against every shard's query concurrently and returns the
+// first failure by shard order
+func (f *FanOutQuery[T]) each(fn func(i int, q *Query[T]) error) error {
+	errs := make([]error, len(f.queries))
+	var wg sync.WaitGroup
+	for i, q := range f.queries {
+		wg.Add(1)
+		go func(i int, q *Query[T]) {
+			defer wg.Done()
+			errs[i] = fn(i, q)
+		}(i, q)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return &ShardError{Shard: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// Find returns the matching rows of every shard, merged by the OrderBy
+// columns and cut to Limit
+func (f *FanOutQuery[T]) Find() ([]T, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	results := make([][]T, len(f.queries))
+	err := f.each(func(i int, q *Query[T]) error {
+		records, err := q.Find()
+		results[i] = records
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	for _, shardRecords := range results {
+		records = append(records, shardRecords...)
+	}
+
+	if len(f.orderBy) > 0 {
+		sort.SliceStable(records, func(i, j int) bool {
+			return f.compare(records[i], records[j]) < 0
+		})
+	}
+	if f.limit != nil && uint64(len(records)) > *f.limit {
+		records = records[:*f.limit]
+	}
+	return records, nil
+}
+
+// compare orders two records by the OrderBy columns
+func (f *FanOutQuery[T]) compare(a, b T) int {
+	for _, order := range f.orderBy {
+		x, y := order.column.GetValue(a), order.column.GetValue(b)
+
+		if x == nil || y == nil {
+			if x == nil && y == nil {
+				continue
+			}
+			if (x == nil) == order.nullsFirst {
+				return -1
+			}
+			return 1
+		}
+
+		c := compareValues(x, y)
+		if order.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues compares two non-NULL column values of the same Go type,
+// reading driver.Valuer values through their database form
+func compareValues(x, y interface{}) int {
+	if valuer, ok := x.(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil && value != nil {
+			x = value
+		}
+	}
+	if valuer, ok := y.(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil && value != nil {
+			y = value
+		}
+	}
+
+	switch x := x.(type) {
+	case time.Time:
+		if y, ok := y.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case []byte:
+		if y, ok := y.([]byte); ok {
+			return bytes.Compare(x, y)
+		}
+	case bool:
+		if y, ok := y.(bool); ok && x != y {
+			if x {
+				return 1
+			}
+			return -1
+		}
+		return 0
+	}
+
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+	switch {
+	case vx.CanInt() && vy.CanInt():
+		return cmp.Compare(vx.Int(), vy.Int())
+	case vx.CanUint() && vy.CanUint():
+		return cmp.Compare(vx.Uint(), vy.Uint())
+	case vx.CanFloat() && vy.CanFloat():
+		return cmp.Compare(vx.Float(), vy.Float())
+	case vx.Kind() == reflect.String && vy.Kind() == reflect.String:
+		return strings.Compare(vx.String(), vy.String())
+	}
+	return strings.Compare(fmt.Sprint(x), fmt.Sprint(y))
+}
+
+// Count returns the number of matching rows across all shards
+func (f *FanOutQuery[T]) Count() (int64, error) {
+	counts := make([]int64, len(f.queries))
+	err := f.each(func(i int, q *Query[T]) error {
+		count, err := q.Count()
+		counts[i] = count
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+// Exists reports whether any shard has a matching row
+func (f *FanOutQuery[T]) Exists() (bool, error) {
+	found := make([]bool, len(f.queries))
+	err := f.each(func(i int, q *Query[T]) error {
+		exists, err := q.Exists()
+		found[i] = exists
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, exists := range found {
+		if exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
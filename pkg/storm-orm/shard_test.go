@@ -0,0 +1,176 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardSetIndex(t *testing.T) {
+	shards := &ShardSet{dbs: make([]*sqlx.DB, 4)}
+
+	first, err := shards.Index("tenant-1")
+	require.NoError(t, err)
+	again, err := shards.Index("tenant-1")
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	key := "tenant-1"
+	fromPointer, err := shards.Index(&key)
+	require.NoError(t, err)
+	assert.Equal(t, first, fromPointer)
+
+	fromInt, err := shards.Index(int64(42))
+	require.NoError(t, err)
+	fromString, err := shards.Index("42")
+	require.NoError(t, err)
+	assert.Equal(t, fromString, fromInt)
+
+	_, err = shards.Index(nil)
+	assert.ErrorIs(t, err, ErrNoShardKey)
+	_, err = shards.Index((*string)(nil))
+	assert.ErrorIs(t, err, ErrNoShardKey)
+}
+
+func TestShardedRepository(t *testing.T) {
+	db0, mock0, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db0.Close()
+	db1, mock1, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db1.Close()
+
+	shards, err := NewShardSet(sqlx.NewDb(db0, "postgres"), sqlx.NewDb(db1, "postgres"))
+	require.NoError(t, err)
+	mocks := []sqlmock.Sqlmock{mock0, mock1}
+
+	metadata := batchTestMetadata()
+	_, err = NewShardedRepository[validatedUser](shards, metadata)
+	assert.ErrorIs(t, err, ErrNoShardKey)
+
+	metadata.ShardKey = "email"
+	_, err = NewShardedRepository[validatedUser](shards, metadata)
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+
+	metadata.ShardKey = "name"
+	repo, err := NewShardedRepository[validatedUser](shards, metadata)
+	require.NoError(t, err)
+
+	// find two names living on different shards
+	names := map[int]string{}
+	for _, name := range []string{"ada", "bob", "cy", "dee", "eve", "fay"} {
+		i, err := shards.Index(name)
+		require.NoError(t, err)
+		if _, ok := names[i]; !ok {
+			names[i] = name
+		}
+	}
+	require.Len(t, names, 2)
+
+	t.Run("Create routes by shard key", func(t *testing.T) {
+		mocks[1].ExpectQuery(`INSERT INTO users \(name\) VALUES \(\$1\) RETURNING id`).
+			WithArgs(names[1]).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		created, err := repo.Create(context.Background(), &validatedUser{Name: names[1]})
+		require.NoError(t, err)
+		assert.Equal(t, 7, created.ID)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FindByID looks on the key's shard", func(t *testing.T) {
+		mocks[0].ExpectQuery(`SELECT .* FROM users WHERE id = \$1`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, names[0]))
+
+		found, err := repo.FindByID(context.Background(), names[0], 3)
+		require.NoError(t, err)
+		assert.Equal(t, names[0], found.Name)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FanOut Find concatenates shards in order", func(t *testing.T) {
+		for i, mock := range mocks {
+			mock.ExpectQuery(`SELECT .* FROM users`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(i+1, names[i]))
+		}
+
+		records, err := repo.FanOut(context.Background()).Find()
+		require.NoError(t, err)
+		assert.Equal(t, []validatedUser{{ID: 1, Name: names[0]}, {ID: 2, Name: names[1]}}, records)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FanOut Find merges shards by order and limits the merged rows", func(t *testing.T) {
+		mock0.ExpectQuery(`SELECT .* FROM users ORDER BY users.id DESC LIMIT 3`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(9, "a").AddRow(4, "b").AddRow(1, "c"))
+		mock1.ExpectQuery(`SELECT .* FROM users ORDER BY users.id DESC LIMIT 3`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(8, "d").AddRow(7, "e").AddRow(2, "f"))
+
+		records, err := repo.FanOut(context.Background()).OrderBy("users.id DESC").Limit(3).Find()
+		require.NoError(t, err)
+		assert.Equal(t, []validatedUser{{ID: 9, Name: "a"}, {ID: 8, Name: "d"}, {ID: 7, Name: "e"}}, records)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FanOut Find breaks ties with the next column", func(t *testing.T) {
+		mock0.ExpectQuery(`SELECT .* FROM users ORDER BY name, id ASC`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "ada").AddRow(1, "bob"))
+		mock1.ExpectQuery(`SELECT .* FROM users ORDER BY name, id ASC`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "ada"))
+
+		records, err := repo.FanOut(context.Background()).OrderBy("name", "id ASC").Find()
+		require.NoError(t, err)
+		assert.Equal(t, []validatedUser{{ID: 2, Name: "ada"}, {ID: 3, Name: "ada"}, {ID: 1, Name: "bob"}}, records)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FanOut OrderBy rejects expressions it cannot merge by", func(t *testing.T) {
+		_, err := repo.FanOut(context.Background()).OrderBy("email").Find()
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+
+		_, err = repo.FanOut(context.Background()).OrderBy("lower(name)").Find()
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+
+		_, err = repo.FanOut(context.Background()).OrderBy("name DESC, id").Find()
+		assert.Error(t, err)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FanOut Count sums shards", func(t *testing.T) {
+		mock0.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock1.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+		count, err := repo.FanOut(context.Background()).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), count)
+		assert.NoError(t, mock0.ExpectationsWereMet())
+		assert.NoError(t, mock1.ExpectationsWereMet())
+	})
+
+	t.Run("FanOut reports the failing shard", func(t *testing.T) {
+		mock0.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock1.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnError(ErrConnectionFailed)
+
+		_, err := repo.FanOut(context.Background()).Count()
+		var shardErr *ShardError
+		require.ErrorAs(t, err, &shardErr)
+		assert.Equal(t, 1, shardErr.Shard)
+		assert.ErrorIs(t, err, ErrConnectionFailed)
+	})
+
+	t.Run("Create rejects a nil record", func(t *testing.T) {
+		_, err := repo.Create(context.Background(), nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,60 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortField orders results by a column, named as in the database
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// ParseSort reads a sort list such as "-created_at,name", where a leading
+// "-" sorts that column descending. An empty value yields no fields.
+func ParseSort(value string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	fields := make([]SortField, 0, len(parts))
+	for _, part := range parts {
+		field := SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field, field.Desc = field.Field[1:], true
+		} else {
+			field.Field = strings.TrimPrefix(field.Field, "+")
+		}
+		if field.Field == "" {
+			return nil, fmt.Errorf("%w: malformed sort %q", ErrInvalidRequest, value)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Sort adds fields to the query's ordering, like OrderBy. When allowed
+// is given only those columns may be sorted on, otherwise any column except
+// sensitive ones. Other columns fail the query with an error wrapping
+// ErrInvalidRequest.
+func (q *Query[T]) Sort(fields []SortField, allowed ...string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+
+	allowedSet := fieldSet(allowed)
+	for _, field := range fields {
+		if q.repo.requestColumn(field.Field, allowedSet) == nil {
+			q.err = fmt.Errorf("%w: cannot sort on %q", ErrInvalidRequest, field.Field)
+			return q
+		}
+		expr := quoteIdent(q.tableRef()) + "." + quoteIdent(field.Field)
+		if field.Desc {
+			expr += " DESC"
+		}
+		q.orderBy = append(q.orderBy, expr)
+	}
+	return q
+}
@@ -0,0 +1,72 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+	fields, err := ParseSort("-created_at, name,+id")
+	require.NoError(t, err)
+	assert.Equal(t, []SortField{
+		{Field: "created_at", Desc: true},
+		{Field: "name"},
+		{Field: "id"},
+	}, fields)
+
+	fields, err = ParseSort("")
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	for _, value := range []string{"name,,id", "-", "name,"} {
+		_, err := ParseSort(value)
+		assert.ErrorIs(t, err, ErrInvalidRequest, value)
+	}
+}
+
+func TestQuerySort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Columns["Email"].IsSensitive = true
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY users.created_at DESC, users.name, users.id LIMIT 10")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	fields, err := ParseSort("-created_at,name")
+	require.NoError(t, err)
+	_, err = repo.Query(context.Background()).
+		Sort(fields, "created_at", "name").
+		OrderBy("users.id").
+		Limit(10).
+		Find()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		field   string
+		allowed []string
+	}{
+		{"unknown column", "password", nil},
+		{"sensitive column", "email", nil},
+		{"outside allowed columns", "name", []string{"id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.Query(context.Background()).Sort([]SortField{{Field: tt.field}}, tt.allowed...).Find()
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -32,20 +33,79 @@ type Storm struct {
 	db       DBExecutor
 	executor DBExecutor  // Current executor (DB or TX)
 	logger   QueryLogger // Optional query logger
+	dialect  Dialect     // SQL dialect (postgres or cockroachdb)
+
+	// Options shared with the repositories created from this Storm
+	options *queryOptions
+
+	// Statements permitted for raw SQL, nil when unrestricted
+	rawPolicy *RawPolicy
+
+	// Callbacks run at the start of every transaction
+	txSetup []TransactionSetup
+
+	// Middleware run around named queries
+	middlewareManager *middlewareManager
 
 	// Repository registry - will be populated by code generation
 	repositories map[string]interface{}
 }
 
+// Option configures a Storm created by NewStormWithOptions
+type Option func(*Storm)
+
+// WithDialect sets the SQL dialect the Storm speaks, postgres by default
+func WithDialect(dialect Dialect) Option {
+	return func(s *Storm) {
+		s.dialect = dialect
+	}
+}
+
+// WithQueryLogger logs every query the Storm runs to logger
+func WithQueryLogger(logger QueryLogger) Option {
+	return func(s *Storm) {
+		s.logger = logger
+	}
+}
+
+// queryOptions are the options a Storm shares with the repositories created
+// from it, read whenever they build a query
+type queryOptions struct {
+	unaccent    atomic.Bool // Whether ISearch also ignores accents
+	inListLimit int         // Longest IN list written with a placeholder per value
+	keys        KeyProvider // Keys of encrypted columns, nil when unset
+}
+
+func newQueryOptions() *queryOptions {
+	return &queryOptions{inListLimit: DefaultInListLimit}
+}
+
+// unaccentEnabled reports whether ISearch ignores accents. options is nil for
+// repositories created without a Storm.
+func (o *queryOptions) unaccentEnabled() bool {
+	return o != nil && o.unaccent.Load()
+}
+
 func NewStorm(db *sqlx.DB, logger ...QueryLogger) *Storm {
+	if len(logger) > 0 {
+		return NewStormWithOptions(db, WithQueryLogger(logger[0]))
+	}
+	return NewStormWithOptions(db)
+}
+
+// NewStormWithOptions returns a Storm using db, configured by options
+func NewStormWithOptions(db *sqlx.DB, options ...Option) *Storm {
 	storm := &Storm{
 		db:           db,
+		options:      newQueryOptions(),
 		repositories: make(map[string]interface{}),
 	}
+	for _, option := range options {
+		option(storm)
+	}
 
-	if len(logger) > 0 {
-		storm.logger = logger[0]
-		storm.executor = &loggingExecutor{executor: db, logger: logger[0]}
+	if storm.logger != nil {
+		storm.executor = &loggingExecutor{executor: db, logger: storm.logger}
 	} else {
 		storm.executor = db
 	}
@@ -59,6 +119,7 @@ func newStormWithExecutor(db *sqlx.DB, executor DBExecutor, logger QueryLogger)
 	storm := &Storm{
 		db:           db,
 		logger:       logger,
+		options:      newQueryOptions(),
 		repositories: make(map[string]interface{}),
 	}
 
@@ -163,6 +224,13 @@ func (l *loggingExecutor) DriverName() string {
 	return l.executor.DriverName()
 }
 
+// OnTransaction adds a setup callback run at the start of every transaction
+// begun by WithTransaction or WithTransactionOptions. Nested calls reuse the
+// outer transaction and do not run it again.
+func (s *Storm) OnTransaction(setup TransactionSetup) {
+	s.txSetup = append(s.txSetup, setup)
+}
+
 // isInTransaction checks if the current executor is a transaction
 func (s *Storm) isInTransaction() bool {
 
@@ -179,49 +247,38 @@ func (s *Storm) isInTransaction() bool {
 }
 
 func (s *Storm) WithTransaction(ctx context.Context, fn func(*Storm) error) error {
+	return s.WithTransactionOptions(ctx, nil, fn)
+}
 
-	if s.isInTransaction() {
+func (s *Storm) WithTransactionOptions(ctx context.Context, opts *TransactionOptions, fn func(*Storm) error) error {
+	if s.isDryRun() {
 		return fn(s)
 	}
 
-	db, ok := s.db.(*sqlx.DB)
-	if !ok {
-		return fmt.Errorf("cannot start transaction: executor is not a database connection")
-	}
-
-	tx, err := db.BeginTxx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	committed := false
-	defer func() {
-		if !committed {
-			if rbErr := tx.Rollback(); rbErr != nil && rbErr.Error() != "sql: transaction has already been committed or rolled back" {
-
-			}
+	if s.isInTransaction() {
+		if err := applySettings(ctx, s.executor, opts); err != nil {
+			return err
 		}
-	}()
-
-	txStorm := newStormWithExecutor(db, tx, s.logger)
-	if err := fn(txStorm); err != nil {
-		return err
+		if err := runTransactionSetup(ctx, s.executor, nil, opts); err != nil {
+			return err
+		}
+		return fn(s)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	maxRetries := s.maxTransactionRetries(opts)
+	for attempt := 0; ; attempt++ {
+		err := s.runTransaction(ctx, opts, fn)
+		if err == nil || attempt >= maxRetries || !isTransactionRetryError(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
 	}
-	committed = true
-
-	return nil
 }
 
-func (s *Storm) WithTransactionOptions(ctx context.Context, opts *TransactionOptions, fn func(*Storm) error) error {
-
-	if s.isInTransaction() {
-		return fn(s)
-	}
-
+// runTransaction executes fn inside a single database transaction
+func (s *Storm) runTransaction(ctx context.Context, opts *TransactionOptions, fn func(*Storm) error) error {
 	db, ok := s.db.(*sqlx.DB)
 	if !ok {
 		return fmt.Errorf("cannot start transaction: executor is not a database connection")
@@ -242,7 +299,22 @@ func (s *Storm) WithTransactionOptions(ctx context.Context, opts *TransactionOpt
 		}
 	}()
 
+	if err := applyStatementTimeout(ctx, tx, opts); err != nil {
+		return err
+	}
+	if err := applySettings(ctx, tx, opts); err != nil {
+		return err
+	}
+	if err := runTransactionSetup(ctx, tx, s.txSetup, opts); err != nil {
+		return err
+	}
+
 	txStorm := newStormWithExecutor(db, tx, s.logger)
+	txStorm.dialect = s.dialect
+	txStorm.options = s.options
+	txStorm.rawPolicy = s.rawPolicy
+	txStorm.txSetup = s.txSetup
+	txStorm.middlewareManager = s.middlewareManager
 	if err := fn(txStorm); err != nil {
 		return err
 	}
@@ -260,23 +332,19 @@ func (s *Storm) GetExecutor() DBExecutor {
 }
 
 func And(conditions ...Condition) Condition {
-	sqlizers := make([]squirrel.Sqlizer, len(conditions))
-	for i, c := range conditions {
-		sqlizers[i] = c.condition
-	}
-	return Condition{squirrel.And(sqlizers)}
+	return joinConditions(conditions, func(sqlizers []squirrel.Sqlizer) squirrel.Sqlizer {
+		return squirrel.And(sqlizers)
+	})
 }
 
 func Or(conditions ...Condition) Condition {
-	sqlizers := make([]squirrel.Sqlizer, len(conditions))
-	for i, c := range conditions {
-		sqlizers[i] = c.condition
-	}
-	return Condition{squirrel.Or(sqlizers)}
+	return joinConditions(conditions, func(sqlizers []squirrel.Sqlizer) squirrel.Sqlizer {
+		return squirrel.Or(sqlizers)
+	})
 }
 
 func Not(condition Condition) Condition {
-	return Condition{squirrel.Expr("NOT (?)", condition.ToSqlizer())}
+	return condition.Not()
 }
 
 func (s *Storm) GetDB() *sqlx.DB {
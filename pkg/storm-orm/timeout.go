@@ -0,0 +1,76 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithTimeout returns a new Repository whose operations and queries are
+// bounded by the given timeout unless a shorter deadline is already set
+func (r *Repository[T]) WithTimeout(d time.Duration) *Repository[T] {
+	clone := r.clone()
+	clone.timeout = d
+	return clone
+}
+
+// DefaultTimeout returns the repository's default operation timeout
+func (r *Repository[T]) DefaultTimeout() time.Duration {
+	return r.timeout
+}
+
+// withTimeout derives a context bounded by the repository's default timeout
+func (r *Repository[T]) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return contextWithTimeout(ctx, r.timeout)
+}
+
+// Timeout bounds the execution of this query, overriding the repository default
+func (q *Query[T]) Timeout(d time.Duration) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	if d < 0 {
+		q.err = fmt.Errorf("timeout cannot be negative")
+		return q
+	}
+	q.timeout = d
+	return q
+}
+
+// withTimeout applies the query timeout to the query context for the
+// duration of an operation and returns a function restoring it
+func (q *Query[T]) withTimeout() func() {
+	if q.timeout <= 0 {
+		return func() {}
+	}
+
+	parent := q.ctx
+	ctx, cancel := contextWithTimeout(parent, q.timeout)
+	q.ctx = ctx
+
+	return func() {
+		cancel()
+		q.ctx = parent
+	}
+}
+
+func contextWithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// statementTimeoutSQL returns the SET LOCAL statement applying d as the
+// server-side statement timeout for the current transaction. Durations under a
+// millisecond are rounded up, since zero would disable the timeout.
+func statementTimeoutSQL(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return fmt.Sprintf("SET LOCAL statement_timeout = %d", ms)
+}
@@ -0,0 +1,156 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryWithTimeout(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	timed := repo.WithTimeout(2 * time.Second)
+	assert.Equal(t, time.Duration(0), repo.DefaultTimeout())
+	assert.Equal(t, 2*time.Second, timed.DefaultTimeout())
+	assert.Equal(t, 2*time.Second, timed.Query(context.Background()).timeout)
+
+	authorized := timed.Authorize(func(ctx context.Context, q *Query[TestUser]) *Query[TestUser] { return q })
+	assert.Equal(t, 2*time.Second, authorized.DefaultTimeout())
+
+	ctx, cancel := timed.withTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), deadline, 100*time.Millisecond)
+}
+
+func TestContextWithTimeoutKeepsShorterDeadline(t *testing.T) {
+	parent, cancelParent := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelParent()
+
+	ctx, cancel := contextWithTimeout(parent, time.Hour)
+	defer cancel()
+	assert.Equal(t, parent, ctx)
+
+	ctx, cancel = contextWithTimeout(context.Background(), 0)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("query exceeding timeout fails", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users`).
+			WillDelayFor(200 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.Query(context.Background()).Timeout(20 * time.Millisecond).Find()
+		require.Error(t, err)
+	})
+
+	t.Run("context restored after execution", func(t *testing.T) {
+		ctx := context.Background()
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		query := repo.Query(ctx).Timeout(time.Second)
+		count, err := query.Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.Equal(t, ctx, query.ctx)
+	})
+
+	t.Run("negative timeout is rejected", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).Timeout(-time.Second).Find()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timeout cannot be negative")
+	})
+}
+
+func TestTransactionStatementTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL statement_timeout = 1500`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = storm.WithTransactionOptions(context.Background(), &TransactionOptions{StatementTimeout: 1500 * time.Millisecond}, func(*Storm) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatementTimeoutFromDeadline(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	opts := &TransactionOptions{StatementTimeout: time.Hour, StatementTimeoutFromDeadline: true}
+
+	t.Run("capped by the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL statement_timeout = (1[5-9]\d\d|2000)$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		require.NoError(t, storm.WithTransactionOptions(ctx, opts, func(*Storm) error { return nil }))
+	})
+
+	t.Run("falls back without a deadline", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL statement_timeout = 3600000$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		require.NoError(t, storm.WithTransactionOptions(context.Background(), opts, func(*Storm) error { return nil }))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelledContextSkipsExecution(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = repo.Query(ctx).Find()
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.Query(ctx).Count()
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.Create(ctx, &TestUser{Name: "Ada", Email: "ada@example.com"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
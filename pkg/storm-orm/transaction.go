@@ -4,16 +4,39 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 // TransactionOptions configures transaction behavior
 type TransactionOptions struct {
-	Isolation sql.IsolationLevel
-	ReadOnly  bool
+	Isolation  sql.IsolationLevel
+	ReadOnly   bool
+	MaxRetries int // Retries on serialization failures; defaults to 3 on CockroachDB
+
+	// StatementTimeout is applied with SET LOCAL statement_timeout when positive
+	StatementTimeout time.Duration
+
+	// StatementTimeoutFromDeadline caps the statement timeout at the time left
+	// before the context deadline, so the server stops work the caller has
+	// already given up on
+	StatementTimeoutFromDeadline bool
+
+	// Settings are run-time parameters set for the transaction only, such as
+	// the app.tenant a row level security policy reads. They are applied
+	// after those carried by the context from WithSettings.
+	Settings map[string]string
+
+	// Setup runs inside the transaction after the settings are applied and
+	// before the transaction function. An error rolls the transaction back.
+	Setup TransactionSetup
 }
 
+// TransactionSetup prepares a transaction before it is handed to the caller,
+// for example by running SET LOCAL role or setting row level security context
+type TransactionSetup func(ctx context.Context, tx DBExecutor) error
+
 func DefaultTransactionOptions() *TransactionOptions {
 	return &TransactionOptions{
 		Isolation: sql.LevelDefault,
@@ -33,7 +56,8 @@ func (o *TransactionOptions) ToTxOptions() *sql.TxOptions {
 
 // TransactionManager provides utilities for managing transactions across repositories
 type TransactionManager struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	setup []TransactionSetup
 }
 
 func NewTransactionManager(db *sqlx.DB) *TransactionManager {
@@ -74,6 +98,16 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, opts *
 		}
 	}()
 
+	if err := applyStatementTimeout(ctx, tx, opts); err != nil {
+		return err
+	}
+	if err := applySettings(ctx, tx, opts); err != nil {
+		return err
+	}
+	if err := runTransactionSetup(ctx, tx, tm.setup, opts); err != nil {
+		return err
+	}
+
 	err = fn(tx)
 	if err != nil {
 		return err
@@ -87,6 +121,54 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, opts *
 	return nil
 }
 
+// OnTransaction adds a setup callback run at the start of every transaction
+// the manager begins
+func (tm *TransactionManager) OnTransaction(setup TransactionSetup) {
+	tm.setup = append(tm.setup, setup)
+}
+
+// runTransactionSetup calls the registered setup callbacks in order, then the
+// one in opts
+func runTransactionSetup(ctx context.Context, tx DBExecutor, setup []TransactionSetup, opts *TransactionOptions) error {
+	if opts != nil && opts.Setup != nil {
+		setup = append(setup[:len(setup):len(setup)], opts.Setup)
+	}
+	for _, fn := range setup {
+		if err := fn(ctx, tx); err != nil {
+			return fmt.Errorf("transaction setup failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyStatementTimeout sets the transaction-scoped statement timeout if configured
+func applyStatementTimeout(ctx context.Context, tx *sqlx.Tx, opts *TransactionOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	timeout := opts.StatementTimeout
+	if opts.StatementTimeoutFromDeadline {
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return fmt.Errorf("failed to set statement timeout: %w", context.DeadlineExceeded)
+			}
+			if timeout <= 0 || remaining < timeout {
+				timeout = remaining
+			}
+		}
+	}
+
+	if timeout <= 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, statementTimeoutSQL(timeout)); err != nil {
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	return nil
+}
+
 func (r *Repository[T]) GetTransactionManager() (*TransactionManager, error) {
 	db, ok := r.db.(*sqlx.DB)
 	if !ok {
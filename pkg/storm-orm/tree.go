@@ -0,0 +1,107 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// treeLink describes how rows of a self-referential table point at their parent
+type treeLink struct {
+	parentColumn string // Column holding the parent's key (e.g. parent_id)
+	keyColumn    string // Column referenced by parentColumn (usually id)
+}
+
+// isSelfReferential reports whether the relationship targets the repository's own model
+func (r *Repository[T]) isSelfReferential(rel *RelationshipMetadata) bool {
+	if rel.TargetTable != "" {
+		return rel.TargetTable == r.metadata.TableName
+	}
+	return rel.Target == r.metadata.StructName || rel.Target == r.metadata.TableName
+}
+
+// treeLink finds the parent link declared by the model's self-referential
+// belongs_to or has_many relationships
+func (r *Repository[T]) treeLink() (treeLink, error) {
+	var found *treeLink
+	for _, rel := range r.metadata.Relationships {
+		if !r.isSelfReferential(rel) || rel.isPolymorphic() {
+			continue
+		}
+
+		var link treeLink
+		switch rel.Type {
+		case "belongs_to":
+			link = treeLink{parentColumn: rel.ForeignKey, keyColumn: rel.TargetKey}
+		case "has_one", "has_many":
+			link = treeLink{parentColumn: rel.ForeignKey, keyColumn: rel.SourceKey}
+		default:
+			continue
+		}
+		if link.keyColumn == "" {
+			link.keyColumn = "id"
+		}
+
+		if found != nil && *found != link {
+			return treeLink{}, fmt.Errorf("model %s has more than one self-referential relationship", r.metadata.StructName)
+		}
+		found = &link
+	}
+
+	if found == nil {
+		return treeLink{}, fmt.Errorf("model %s has no self-referential relationship", r.metadata.StructName)
+	}
+	return *found, nil
+}
+
+// Descendants returns every record below id in the tree, combined with the
+// query's other conditions, ordering and includes
+func (q *Query[T]) Descendants(id interface{}) ([]T, error) {
+	return q.findInTree("descendants", id, "WITH RECURSIVE storm_tree AS ("+
+		"SELECT %[2]s FROM %[1]s WHERE %[3]s = ? "+
+		"UNION SELECT child.%[2]s FROM %[1]s AS child INNER JOIN storm_tree ON child.%[3]s = storm_tree.%[2]s"+
+		") SELECT %[2]s FROM storm_tree", false)
+}
+
+// Ancestors returns every record above id in the tree, from its parent up to
+// the root. The CTE counts the steps up from id to order the records by, and
+// keeps the path walked so a cycle in the parent links ends the walk.
+func (q *Query[T]) Ancestors(id interface{}) ([]T, error) {
+	return q.findInTree("ancestors", id, "WITH RECURSIVE storm_tree AS ("+
+		"SELECT %[2]s, %[3]s, 1 AS storm_depth, ARRAY[%[2]s] AS storm_path FROM %[1]s WHERE %[2]s = (SELECT %[3]s FROM %[1]s WHERE %[2]s = ?) "+
+		"UNION ALL SELECT parent.%[2]s, parent.%[3]s, storm_tree.storm_depth + 1, storm_tree.storm_path || parent.%[2]s "+
+		"FROM %[1]s AS parent INNER JOIN storm_tree ON parent.%[2]s = storm_tree.%[3]s WHERE NOT parent.%[2]s = ANY(storm_tree.storm_path)"+
+		") SELECT %[2]s AS storm_key, storm_depth FROM storm_tree", true)
+}
+
+// findInTree restricts the query to the keys produced by the recursive CTE and
+// runs it. An ordered CTE also yields a storm_depth per key, and the query is
+// joined to it to order the records by depth ahead of its own ordering.
+func (q *Query[T]) findInTree(op string, id interface{}, cte string, ordered bool) ([]T, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	link, err := q.repo.treeLink()
+	if err != nil {
+		return nil, &Error{
+			Op:    op,
+			Table: q.repo.metadata.TableName,
+			Err:   err,
+		}
+	}
+
+	table := q.repo.quotedTable()
+	keyColumn, parentColumn := quoteIdent(link.keyColumn), quoteIdent(link.parentColumn)
+	subquery := fmt.Sprintf(cte, table, keyColumn, parentColumn)
+	if !ordered {
+		q.whereClause = append(q.whereClause, squirrel.Expr(fmt.Sprintf("%s.%s IN (%s)", quoteIdent(q.tableRef()), keyColumn, subquery), id))
+		return q.Find()
+	}
+
+	q.ancestry = squirrel.Expr(fmt.Sprintf("INNER JOIN (%s) AS storm_ancestry ON %s.%s = storm_ancestry.storm_key", subquery, quoteIdent(q.tableRef()), keyColumn), id)
+	q.orderBy = append([]string{"storm_ancestry.storm_depth"}, q.orderBy...)
+	q.qualifyColumns()
+
+	return q.Find()
+}
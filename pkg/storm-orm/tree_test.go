@@ -0,0 +1,133 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCategory struct {
+	ID       int  `db:"id"`
+	ParentID *int `db:"parent_id"`
+}
+
+func createTestCategoryMetadata() *ModelMetadata {
+	return &ModelMetadata{
+		TableName:  "categories",
+		StructName: "Category",
+		Columns: map[string]*ColumnMetadata{
+			"ID": {
+				FieldName:    "ID",
+				DBName:       "id",
+				IsPrimaryKey: true,
+				GetValue:     func(model interface{}) interface{} { return model.(testCategory).ID },
+			},
+			"ParentID": {
+				FieldName: "ParentID",
+				DBName:    "parent_id",
+				IsPointer: true,
+				GetValue:  func(model interface{}) interface{} { return model.(testCategory).ParentID },
+			},
+		},
+		ReverseMap:  map[string]string{"id": "ID", "parent_id": "ParentID"},
+		PrimaryKeys: []string{"id"},
+		Relationships: map[string]*RelationshipMetadata{
+			"Parent": {
+				Name:        "Parent",
+				Type:        "belongs_to",
+				Target:      "Category",
+				TargetTable: "categories",
+				ForeignKey:  "parent_id",
+				TargetKey:   "id",
+			},
+			"Children": {
+				Name:        "Children",
+				Type:        "has_many",
+				Target:      "Category",
+				TargetTable: "categories",
+				ForeignKey:  "parent_id",
+				SourceKey:   "id",
+			},
+		},
+	}
+}
+
+func TestTreeQueries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[testCategory](sqlx.NewDb(db, "postgres"), createTestCategoryMetadata())
+	require.NoError(t, err)
+
+	t.Run("Descendants", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM categories WHERE \(categories\.id IN \(WITH RECURSIVE storm_tree AS \(SELECT id FROM categories WHERE parent_id = \$1 UNION SELECT child\.id FROM categories AS child INNER JOIN storm_tree ON child\.parent_id = storm_tree\.id\) SELECT id FROM storm_tree\)\)`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1).AddRow(3, 2))
+
+		records, err := repo.Query(context.Background()).Descendants(1)
+		require.NoError(t, err)
+		assert.Len(t, records, 2)
+	})
+
+	t.Run("Ancestors", func(t *testing.T) {
+		// 5 -> 7 -> 2 -> 9: the keys do not follow the tree, so only the depth
+		// the CTE counts orders the ancestors from the parent up
+		mock.ExpectQuery(`SELECT categories\.id, categories\.parent_id FROM categories ` +
+			`INNER JOIN \(WITH RECURSIVE storm_tree AS \(` +
+			`SELECT id, parent_id, 1 AS storm_depth, ARRAY\[id\] AS storm_path FROM categories WHERE id = \(SELECT parent_id FROM categories WHERE id = \$1\) ` +
+			`UNION ALL SELECT parent\.id, parent\.parent_id, storm_tree\.storm_depth \+ 1, storm_tree\.storm_path \|\| parent\.id ` +
+			`FROM categories AS parent INNER JOIN storm_tree ON parent\.id = storm_tree\.parent_id WHERE NOT parent\.id = ANY\(storm_tree\.storm_path\)` +
+			`\) SELECT id AS storm_key, storm_depth FROM storm_tree\) AS storm_ancestry ON categories\.id = storm_ancestry\.storm_key ` +
+			`ORDER BY storm_ancestry\.storm_depth, categories\.id DESC`).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(7, 2).AddRow(2, 9).AddRow(9, nil))
+
+		records, err := repo.Query(context.Background()).
+			OrderBy("categories.id DESC").
+			Ancestors(5)
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []int{7, 2, 9}, []int{records[0].ID, records[1].ID, records[2].ID})
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTreeQueriesRequireSelfReference(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	_, err = repo.Query(context.Background()).Descendants(1)
+	assert.Error(t, err)
+}
+
+func TestTreeLinkConflict(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestCategoryMetadata()
+	metadata.Relationships["Origin"] = &RelationshipMetadata{
+		Name:        "Origin",
+		Type:        "belongs_to",
+		Target:      "Category",
+		TargetTable: "categories",
+		ForeignKey:  "origin_id",
+		TargetKey:   "id",
+	}
+
+	repo, err := NewRepository[testCategory](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	_, err = repo.treeLink()
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// WindowFunc is a window function with its OVER clause. Query.FindAnnotated
+// selects it alongside the model's columns under the name given to As.
+type WindowFunc struct {
+	call        string
+	partitionBy []string
+	orderBy     []string
+	alias       string
+}
+
+// RowNumber numbers the rows of each partition from 1
+func RowNumber() WindowFunc {
+	return WindowFunc{call: "ROW_NUMBER()"}
+}
+
+// Rank ranks the rows of each partition, leaving gaps after ties
+func Rank() WindowFunc {
+	return WindowFunc{call: "RANK()"}
+}
+
+// DenseRank ranks the rows of each partition without gaps after ties
+func DenseRank() WindowFunc {
+	return WindowFunc{call: "DENSE_RANK()"}
+}
+
+// Lag returns col from the row offset rows before the current one, or NULL
+// when there is none
+func Lag(col fmt.Stringer, offset int) WindowFunc {
+	return WindowFunc{call: fmt.Sprintf("LAG(%s, %d)", col, offset)}
+}
+
+// Lead returns col from the row offset rows after the current one, or NULL
+// when there is none
+func Lead(col fmt.Stringer, offset int) WindowFunc {
+	return WindowFunc{call: fmt.Sprintf("LEAD(%s, %d)", col, offset)}
+}
+
+// PartitionBy restarts the window for each distinct value of cols
+func (w WindowFunc) PartitionBy(cols ...fmt.Stringer) WindowFunc {
+	partitionBy := append([]string(nil), w.partitionBy...)
+	for _, col := range cols {
+		partitionBy = append(partitionBy, col.String())
+	}
+	w.partitionBy = partitionBy
+	return w
+}
+
+// OrderBy orders the rows within each partition by expressions such as those
+// returned by Column.Asc and Column.Desc
+func (w WindowFunc) OrderBy(expressions ...string) WindowFunc {
+	w.orderBy = append(append([]string(nil), w.orderBy...), expressions...)
+	return w
+}
+
+// As names the selected value, which the result struct reads by db tag
+func (w WindowFunc) As(alias string) WindowFunc {
+	w.alias = alias
+	return w
+}
+
+// ToSql renders the function and its OVER clause, without the alias
+func (w WindowFunc) ToSql() (string, []interface{}, error) {
+	var over []string
+	if len(w.partitionBy) > 0 {
+		over = append(over, "PARTITION BY "+strings.Join(w.partitionBy, ", "))
+	}
+	if len(w.orderBy) > 0 {
+		over = append(over, "ORDER BY "+strings.Join(w.orderBy, ", "))
+	}
+	return w.call + " OVER (" + strings.Join(over, " ") + ")", nil, nil
+}
+
+// FindAnnotated runs the query with windows selected after the model's
+// columns and scans the rows into dest, a pointer to a slice of structs that
+// embed the model and have a db-tagged field for each window:
+//
+//	type RankedPost struct {
+//	    models.Post
+//	    Rank int64 `db:"rank"`
+//	}
+//
+//	var posts []RankedPost
+//	err := repo.Query(ctx).FindAnnotated(&posts,
+//	    orm.Rank().PartitionBy(models.Posts.AuthorID).OrderBy(models.Posts.Views.Desc()).As("rank"))
+func (q *Query[T]) FindAnnotated(dest interface{}, windows ...WindowFunc) error {
+	q.applyDefaultScopes()
+
+	if q.err != nil {
+		return q.err
+	}
+
+	builder := q.selectBuilder()
+	for _, window := range windows {
+		if window.alias == "" {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("window function %s needs a name, set with As", window.call),
+			}
+		}
+		builder = builder.Column(squirrel.Alias(window, quoteIdent(window.alias)))
+	}
+
+	defer q.withTimeout()()
+
+	return q.repo.executeResultMiddleware(OpQuery, q.ctx, nil, builder, dest, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		var executor DBExecutor = q.repo.db
+		if q.tx != nil {
+			executor = q.tx
+		}
+
+		if err := executor.SelectContext(q.ctx, dest, sqlQuery, args...); err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to execute query: %w", err),
+			}
+		}
+		if err := q.repo.options.decrypt(dest); err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   err,
+			}
+		}
+		return nil
+	})
+}
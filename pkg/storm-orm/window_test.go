@@ -0,0 +1,98 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rankedUser struct {
+	TestUser
+	Rank     int64   `db:"rank"`
+	Previous *string `db:"previous_name"`
+}
+
+func TestWindowFunc(t *testing.T) {
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	activeCol := Column[bool]{Name: "is_active", Table: "users"}
+
+	tests := []struct {
+		name     string
+		window   WindowFunc
+		expected string
+	}{
+		{"row number", RowNumber(), "ROW_NUMBER() OVER ()"},
+		{"rank", Rank().PartitionBy(activeCol).OrderBy(nameCol.Desc()), "RANK() OVER (PARTITION BY users.is_active ORDER BY users.name DESC)"},
+		{"dense rank", DenseRank().OrderBy(nameCol.Asc()), "DENSE_RANK() OVER (ORDER BY users.name ASC)"},
+		{"lag", Lag(nameCol, 1).OrderBy(nameCol.Asc()), "LAG(users.name, 1) OVER (ORDER BY users.name ASC)"},
+		{"lead", Lead(nameCol, 2).PartitionBy(activeCol, nameCol), "LEAD(users.name, 2) OVER (PARTITION BY users.is_active, users.name)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.window.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sql)
+			assert.Empty(t, args)
+		})
+	}
+
+	t.Run("builders do not share state", func(t *testing.T) {
+		base := RowNumber().PartitionBy(activeCol)
+		first := base.PartitionBy(nameCol)
+		second := base.PartitionBy(Column[int]{Name: "id", Table: "users"})
+
+		sql, _, _ := first.ToSql()
+		assert.Equal(t, "ROW_NUMBER() OVER (PARTITION BY users.is_active, users.name)", sql)
+		sql, _, _ = second.ToSql()
+		assert.Equal(t, "ROW_NUMBER() OVER (PARTITION BY users.is_active, users.id)", sql)
+	})
+}
+
+func TestQueryFindAnnotated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	activeCol := Column[bool]{Name: "is_active", Table: "users"}
+
+	t.Run("selects windows after the model columns", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT created_at, email, id, is_active, name, updated_at, (RANK() OVER (PARTITION BY users.is_active ORDER BY users.name ASC)) AS rank, (LAG(users.name, 1) OVER (ORDER BY users.name ASC)) AS previous_name FROM users WHERE (users.is_active = $1)`)).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "email", "id", "is_active", "name", "updated_at", "rank", "previous_name"}).
+				AddRow(now, "ada@example.com", 1, true, "Ada", now, 1, nil).
+				AddRow(now, "bob@example.com", 2, true, "Bob", now, 2, "Ada"))
+
+		var users []rankedUser
+		err := repo.Query(context.Background()).
+			Where(activeCol.Eq(true)).
+			FindAnnotated(&users,
+				Rank().PartitionBy(activeCol).OrderBy(nameCol.Asc()).As("rank"),
+				Lag(nameCol, 1).OrderBy(nameCol.Asc()).As("previous_name"))
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, "Bob", users[1].Name)
+		assert.Equal(t, int64(2), users[1].Rank)
+		assert.Nil(t, users[0].Previous)
+		require.NotNil(t, users[1].Previous)
+		assert.Equal(t, "Ada", *users[1].Previous)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requires a name for every window", func(t *testing.T) {
+		var users []rankedUser
+		err := repo.Query(context.Background()).FindAnnotated(&users, RowNumber())
+		assert.ErrorContains(t, err, "needs a name")
+	})
+}
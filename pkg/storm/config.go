@@ -1,44 +1,100 @@
 package storm
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Supported SQL dialects
+const (
+	DialectPostgres    = "postgres"
+	DialectCockroachDB = "cockroachdb"
+)
+
+// Policies for a migration that keeps other sessions waiting on its locks
+const (
+	MigrationLockWarn  = "warn"
+	MigrationLockAbort = "abort"
+)
+
 // Config holds all Storm configuration
 type Config struct {
 	// Database settings
 	Driver          string        `yaml:"driver" env:"STORM_DRIVER"`
+	Dialect         string        `yaml:"dialect" env:"STORM_DIALECT"`
 	DatabaseURL     string        `yaml:"database_url" env:"STORM_DATABASE_URL"`
 	MaxOpenConns    int           `yaml:"max_open_conns" env:"STORM_MAX_OPEN_CONNS"`
 	MaxIdleConns    int           `yaml:"max_idle_conns" env:"STORM_MAX_IDLE_CONNS"`
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"STORM_CONN_MAX_LIFETIME"`
 
+	// Health settings
+	HealthCheckInterval time.Duration    `yaml:"health_check_interval" env:"STORM_HEALTH_CHECK_INTERVAL"`
+	ConnectionEvents    ConnectionEvents `yaml:"-"`
+
+	// Session settings, applied to every connection the pool opens
+	SearchPath      []string          `yaml:"search_path" env:"STORM_SEARCH_PATH"`
+	ApplicationName string            `yaml:"application_name" env:"STORM_APPLICATION_NAME"`
+	SessionSettings map[string]string `yaml:"session_settings"`
+	// OnConnect runs after the session settings on every new connection
+	OnConnect func(ctx context.Context, conn SessionConn) error `yaml:"-"`
+
 	// Models settings
 	ModelsPackage string `yaml:"models_package" env:"STORM_MODELS_PACKAGE"`
 
 	// Migration settings
 	MigrationsDir   string `yaml:"migrations_dir" env:"STORM_MIGRATIONS_DIR"`
 	MigrationsTable string `yaml:"migrations_table" env:"STORM_MIGRATIONS_TABLE"`
+	// MigrationFileNaming prefixes new migration files with a timestamp
+	// ("timestamp", the default) or the next number in the directory ("sequential")
+	MigrationFileNaming string `yaml:"migration_file_naming" env:"STORM_MIGRATION_FILE_NAMING"`
+	// MigrationHeader is a text/template written at the top of new migration files
+	MigrationHeader string `yaml:"migration_header"`
+	// OnMigrationProgress is called after each statement of a migration being applied
+	OnMigrationProgress func(MigrationProgress) `yaml:"-"`
+	// MigrationLockThreshold is how long a migration may keep other sessions
+	// waiting on its locks before MigrationLockPolicy applies; zero disables
+	// the check
+	MigrationLockThreshold time.Duration `yaml:"migration_lock_threshold" env:"STORM_MIGRATION_LOCK_THRESHOLD"`
+	// MigrationLockPolicy is "warn" (the default) to log the blocked sessions
+	// or "abort" to also cancel the migration
+	MigrationLockPolicy string `yaml:"migration_lock_policy" env:"STORM_MIGRATION_LOCK_POLICY"`
 	AutoMigrate     bool   `yaml:"auto_migrate" env:"STORM_AUTO_MIGRATE"`
 	AutoMigrateOpts AutoMigrateOptions `yaml:"-"`
+	// CheckSchema makes New compare the models with the database and fail on a mismatch
+	CheckSchema bool `yaml:"check_schema" env:"STORM_CHECK_SCHEMA"`
 
 	// ORM settings
-	GenerateHooks bool `yaml:"generate_hooks" env:"STORM_GENERATE_HOOKS"`
-	GenerateTests bool `yaml:"generate_tests" env:"STORM_GENERATE_TESTS"`
-	GenerateMocks bool `yaml:"generate_mocks" env:"STORM_GENERATE_MOCKS"`
+	GenerateHooks    bool   `yaml:"generate_hooks" env:"STORM_GENERATE_HOOKS"`
+	GenerateTests    bool   `yaml:"generate_tests" env:"STORM_GENERATE_TESTS"`
+	GenerateMocks    bool   `yaml:"generate_mocks" env:"STORM_GENERATE_MOCKS"`
+	GenerateHandlers bool   `yaml:"generate_handlers" env:"STORM_GENERATE_HANDLERS"`
+	GenerateDTOs     bool   `yaml:"generate_dtos" env:"STORM_GENERATE_DTOS"`
+	TemplatesDir     string `yaml:"templates_dir" env:"STORM_TEMPLATES_DIR"`
 
 	// Schema settings
 	StrictMode       bool   `yaml:"strict_mode" env:"STORM_STRICT_MODE"`
 	NamingConvention string `yaml:"naming_convention" env:"STORM_NAMING_CONVENTION"`
+	// Plurals adds irregular plurals used to derive table names, e.g. person: people
+	Plurals map[string]string `yaml:"plurals"`
+	// TableNames overrides the derived table name of a model, keyed by struct name
+	TableNames map[string]string `yaml:"table_names"`
+	// IncludeModels limits the structs treated as models to these names or patterns
+	IncludeModels []string `yaml:"include_models"`
+	// ExcludeModels lists struct names or patterns never treated as models
+	ExcludeModels []string `yaml:"exclude_models"`
 
 	// Runtime settings
 	Logger Logger `yaml:"-"`
+	// SlogHandler, when set, also receives the logging of internal components
+	SlogHandler slog.Handler `yaml:"-"`
 	Debug  bool   `yaml:"debug" env:"STORM_DEBUG"`
 }
 
@@ -46,6 +102,7 @@ type Config struct {
 func NewConfig() *Config {
 	return &Config{
 		Driver:           "postgres",
+		Dialect:          DialectPostgres,
 		MaxOpenConns:     25,
 		MaxIdleConns:     5,
 		ConnMaxLifetime:  time.Hour,
@@ -56,6 +113,8 @@ func NewConfig() *Config {
 		GenerateHooks:    true,
 		GenerateTests:    false,
 		GenerateMocks:    false,
+		GenerateHandlers: false,
+		GenerateDTOs:     false,
 		StrictMode:       true,
 		NamingConvention: "snake_case",
 		Logger:           NewDefaultLogger(),
@@ -110,6 +169,9 @@ func (c *Config) LoadEnv() {
 	if driver := os.Getenv("STORM_DRIVER"); driver != "" {
 		c.Driver = driver
 	}
+	if dialect := os.Getenv("STORM_DIALECT"); dialect != "" {
+		c.Dialect = dialect
+	}
 	if maxOpen := os.Getenv("STORM_MAX_OPEN_CONNS"); maxOpen != "" {
 		if val, err := strconv.Atoi(maxOpen); err == nil {
 			c.MaxOpenConns = val
@@ -125,6 +187,22 @@ func (c *Config) LoadEnv() {
 			c.ConnMaxLifetime = val
 		}
 	}
+	if interval := os.Getenv("STORM_HEALTH_CHECK_INTERVAL"); interval != "" {
+		if val, err := time.ParseDuration(interval); err == nil {
+			c.HealthCheckInterval = val
+		}
+	}
+	if path := os.Getenv("STORM_SEARCH_PATH"); path != "" {
+		c.SearchPath = nil
+		for _, schema := range strings.Split(path, ",") {
+			if schema = strings.TrimSpace(schema); schema != "" {
+				c.SearchPath = append(c.SearchPath, schema)
+			}
+		}
+	}
+	if name := os.Getenv("STORM_APPLICATION_NAME"); name != "" {
+		c.ApplicationName = name
+	}
 	if pkg := os.Getenv("STORM_MODELS_PACKAGE"); pkg != "" {
 		c.ModelsPackage = pkg
 	}
@@ -134,9 +212,23 @@ func (c *Config) LoadEnv() {
 	if table := os.Getenv("STORM_MIGRATIONS_TABLE"); table != "" {
 		c.MigrationsTable = table
 	}
+	if naming := os.Getenv("STORM_MIGRATION_FILE_NAMING"); naming != "" {
+		c.MigrationFileNaming = naming
+	}
+	if threshold := os.Getenv("STORM_MIGRATION_LOCK_THRESHOLD"); threshold != "" {
+		if val, err := time.ParseDuration(threshold); err == nil {
+			c.MigrationLockThreshold = val
+		}
+	}
+	if policy := os.Getenv("STORM_MIGRATION_LOCK_POLICY"); policy != "" {
+		c.MigrationLockPolicy = policy
+	}
 	if auto := os.Getenv("STORM_AUTO_MIGRATE"); auto != "" {
 		c.AutoMigrate = auto == "true"
 	}
+	if check := os.Getenv("STORM_CHECK_SCHEMA"); check != "" {
+		c.CheckSchema = check == "true"
+	}
 	if hooks := os.Getenv("STORM_GENERATE_HOOKS"); hooks != "" {
 		c.GenerateHooks = hooks == "true"
 	}
@@ -146,6 +238,15 @@ func (c *Config) LoadEnv() {
 	if mocks := os.Getenv("STORM_GENERATE_MOCKS"); mocks != "" {
 		c.GenerateMocks = mocks == "true"
 	}
+	if handlers := os.Getenv("STORM_GENERATE_HANDLERS"); handlers != "" {
+		c.GenerateHandlers = handlers == "true"
+	}
+	if dtos := os.Getenv("STORM_GENERATE_DTOS"); dtos != "" {
+		c.GenerateDTOs = dtos == "true"
+	}
+	if dir := os.Getenv("STORM_TEMPLATES_DIR"); dir != "" {
+		c.TemplatesDir = dir
+	}
 	if strict := os.Getenv("STORM_STRICT_MODE"); strict != "" {
 		c.StrictMode = strict == "true"
 	}
@@ -167,6 +268,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("database driver is required")
 	}
 
+	if c.Dialect != "" && c.Dialect != DialectPostgres && c.Dialect != DialectCockroachDB {
+		return fmt.Errorf("dialect must be '%s' or '%s'", DialectPostgres, DialectCockroachDB)
+	}
+
 	if c.MaxOpenConns < 1 {
 		return fmt.Errorf("max open connections must be at least 1")
 	}
@@ -179,6 +284,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max idle connections cannot exceed max open connections")
 	}
 
+	if c.HealthCheckInterval < 0 {
+		return fmt.Errorf("health check interval cannot be negative")
+	}
+
+	for name := range c.SessionSettings {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("session setting names cannot be empty")
+		}
+	}
+
 	if c.ModelsPackage == "" {
 		return fmt.Errorf("models package is required")
 	}
@@ -195,9 +310,26 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("naming convention must be 'snake_case' or 'camelCase'")
 	}
 
+	if c.MigrationFileNaming != "" && c.MigrationFileNaming != "timestamp" && c.MigrationFileNaming != "sequential" {
+		return fmt.Errorf("migration file naming must be 'timestamp' or 'sequential'")
+	}
+
+	if c.MigrationLockThreshold < 0 {
+		return fmt.Errorf("migration lock threshold cannot be negative")
+	}
+
+	if c.MigrationLockPolicy != "" && c.MigrationLockPolicy != MigrationLockWarn && c.MigrationLockPolicy != MigrationLockAbort {
+		return fmt.Errorf("migration lock policy must be '%s' or '%s'", MigrationLockWarn, MigrationLockAbort)
+	}
+
 	return nil
 }
 
+// IsCockroachDB reports whether the configuration targets CockroachDB
+func (c *Config) IsCockroachDB() bool {
+	return c.Dialect == DialectCockroachDB
+}
+
 // Clone returns a deep copy of the configuration
 func (c *Config) Clone() *Config {
 	clone := *c
@@ -16,6 +16,8 @@ var (
 	ErrMigrationExists   = errors.New("storm: migration already exists")
 	ErrMigrationNotFound = errors.New("storm: migration not found")
 	ErrDestructiveChange = errors.New("storm: destructive change detected")
+	ErrSchemaMismatch    = errors.New("storm: database schema does not match models")
+	ErrPlanNotApproved   = errors.New("storm: migration plan not approved")
 )
 
 // ErrorType represents the type of error
@@ -2,6 +2,8 @@ package storm
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -27,6 +29,12 @@ type Migrator interface {
 
 	// AutoMigrate reads Go structs and applies schema changes directly to the database
 	AutoMigrate(ctx context.Context, opts AutoMigrateOptions) error
+
+	// DesiredSchema returns the schema described by the models
+	DesiredSchema(ctx context.Context) (*Schema, error)
+
+	// MigrateTenants applies the pending migrations to every tenant schema
+	MigrateTenants(ctx context.Context, opts TenantMigrateOptions) (*TenantMigrationReport, error)
 }
 
 // SchemaInspector analyzes database schema
@@ -64,23 +72,112 @@ type Migration struct {
 	CreatedAt   time.Time
 }
 
+// MigrationProgress reports a statement of a migration being applied
+type MigrationProgress struct {
+	Migration string
+	Schema    string // Tenant schema being migrated by MigrateTenants
+	Statement int    // Statements run so far, including those of an interrupted run being resumed
+	Total     int
+	Elapsed   time.Duration // Since this run of the migration started
+	Resumed   bool          // The migration continues from a journal left by an interrupted run
+}
+
+// TenantMigrateOptions configures applying migrations to each tenant of a
+// schema-per-tenant database
+type TenantMigrateOptions struct {
+	Schemas     []string // Tenant schemas to migrate
+	SchemaQuery string   // Query returning the tenant schema names, used when Schemas is empty
+	Parallelism int      // Tenants migrated at once, 1 when zero
+}
+
+// TenantMigrationResult is the outcome of migrating one tenant schema
+type TenantMigrationResult struct {
+	Schema   string
+	Applied  []string // Migrations applied, in order
+	Pending  int      // Migrations still pending, after a failure
+	Duration time.Duration
+	Err      error
+}
+
+// TenantMigrationReport is the outcome of MigrateTenants, one result per
+// tenant in the order the schemas were listed
+type TenantMigrationReport struct {
+	Tenants []TenantMigrationResult
+}
+
+// Failed returns the results of the tenants whose migration failed
+func (r *TenantMigrationReport) Failed() []TenantMigrationResult {
+	var failed []TenantMigrationResult
+	for _, tenant := range r.Tenants {
+		if tenant.Err != nil {
+			failed = append(failed, tenant)
+		}
+	}
+	return failed
+}
+
+// Err summarizes the failed tenants, or returns nil when all were migrated
+func (r *TenantMigrationReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	messages := make([]string, len(failed))
+	for i, tenant := range failed {
+		messages[i] = fmt.Sprintf("%s: %v", tenant.Schema, tenant.Err)
+	}
+	return fmt.Errorf("%d of %d tenants failed to migrate: %s", len(failed), len(r.Tenants), strings.Join(messages, "; "))
+}
+
 // MigrationStatus represents current migration state
 type MigrationStatus struct {
-	Current   string
-	Available int
-	Pending   int
-	Applied   int
+	Current    string
+	Available  int
+	Pending    int
+	Applied    int
+	Missing    int
+	Migrations []*MigrationInfo
+}
+
+// MigrationState describes a migration relative to the database
+type MigrationState string
+
+const (
+	// MigrationStateApplied is recorded in the database and present on disk
+	MigrationStateApplied MigrationState = "applied"
+	// MigrationStatePending is present on disk but not yet applied
+	MigrationStatePending MigrationState = "pending"
+	// MigrationStateMissing is recorded in the database but its file is gone
+	MigrationStateMissing MigrationState = "missing"
+)
+
+// MigrationInfo describes a single migration in a status report
+type MigrationInfo struct {
+	Name      string
+	State     MigrationState
+	AppliedAt *time.Time
+	Checksum  string
+	// ChecksumValid is false when the file no longer matches the checksum
+	// recorded when it was applied, or when the file is missing
+	ChecksumValid bool
+	Duration      time.Duration
+	AppliedBy     string // user@host that applied the migration
+	StormVersion  string // Storm version that applied the migration
+	SQLHash       string // SHA-256 of the SQL applied
 }
 
 // MigrationRecord represents an applied migration
 type MigrationRecord struct {
-	ID        string
-	Version   string
-	AppliedAt time.Time
-	AppliedBy string
-	Duration  time.Duration
-	Success   bool
-	Error     string
+	ID           string
+	Version      string
+	AppliedAt    time.Time
+	AppliedBy    string // user@host that applied the migration
+	Duration     time.Duration
+	Checksum     string
+	SQLHash      string // SHA-256 of the SQL applied
+	StormVersion string // Storm version that applied the migration
+	Success      bool
+	Error        string
 }
 
 // Schema represents a database schema
@@ -221,13 +318,23 @@ type AutoMigrateOptions struct {
 	DryRun              bool
 	CreateDBIfNotExists bool
 	LockTimeout         time.Duration
+	// PlanFile writes the pending changes to this JSON file, and as SQL next
+	// to it, without applying them
+	PlanFile string
+	// ApprovalFile applies the pending changes only when their plan hash
+	// matches the plan or hash in this file
+	ApprovalFile string
 }
 
 // GenerateOptions configures ORM code generation
 type GenerateOptions struct {
-	PackagePath  string
-	OutputDir    string
-	IncludeHooks bool
-	IncludeTests bool
-	IncludeMocks bool
+	PackagePath     string
+	OutputDir       string
+	IncludeHooks    bool
+	IncludeTests    bool
+	IncludeMocks    bool
+	IncludeHandlers bool
+	IncludeDTOs     bool
+	TemplatesDir    string // Directory of custom templates, see docs/configuration.md
+	Database        string // Named database target the models belong to, if any
 }
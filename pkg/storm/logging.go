@@ -0,0 +1,48 @@
+package storm
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/eleven-am/storm/internal/logger"
+)
+
+// slogLogger adapts a slog.Logger to Logger. The fields passed with each
+// message are the alternating keys and values slog expects.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing through logger. Loggers such as zap
+// and zerolog plug in through their slog handlers, e.g. zapslog.NewHandler.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{logger: l}
+}
+
+func (l *slogLogger) Debug(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelDebug, msg, fields...)
+}
+
+func (l *slogLogger) Info(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, fields...)
+}
+
+func (l *slogLogger) Warn(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelWarn, msg, fields...)
+}
+
+func (l *slogLogger) Error(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelError, msg, fields...)
+}
+
+// applySlogHandler routes the logging of the migrator, schema generator and
+// other internal components through the configured handler. They share one
+// process-wide logger, so the handler of the last client created wins.
+func applySlogHandler(config *Config) {
+	if config.SlogHandler != nil {
+		logger.SetSlogHandler(config.SlogHandler)
+	}
+}
@@ -0,0 +1,106 @@
+package storm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MigrationPlan is the set of schema changes AutoMigrate would apply, written
+// by AutoMigrateOptions.PlanFile for review
+type MigrationPlan struct {
+	Hash        string    `json:"hash"`
+	CreatedAt   time.Time `json:"created_at"`
+	Statements  []string  `json:"statements"`
+	Destructive []string  `json:"destructive,omitempty"`
+}
+
+// NewMigrationPlan builds a plan for the given statements
+func NewMigrationPlan(statements, destructive []string) *MigrationPlan {
+	return &MigrationPlan{
+		Hash:        PlanHash(statements),
+		CreatedAt:   time.Now().UTC(),
+		Statements:  statements,
+		Destructive: destructive,
+	}
+}
+
+// PlanHash identifies a list of migration statements. Whitespace at either
+// end of a statement and a trailing semicolon do not change the hash.
+func PlanHash(statements []string) string {
+	h := sha256.New()
+	for _, stmt := range statements {
+		stmt = strings.TrimSuffix(strings.TrimSpace(stmt), ";")
+		fmt.Fprintf(h, "%d:%s\n", len(stmt), stmt)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SQL returns the statements as a script, headed by the plan hash
+func (p *MigrationPlan) SQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Storm migration plan %s\n", p.Hash)
+	fmt.Fprintf(&b, "-- Generated at: %s\n", p.CreatedAt.Format(time.RFC3339))
+	for _, op := range p.Destructive {
+		fmt.Fprintf(&b, "-- DESTRUCTIVE: %s\n", op)
+	}
+	for _, stmt := range p.Statements {
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(stmt))
+		if !strings.HasSuffix(strings.TrimSpace(stmt), ";") {
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Save writes the plan as JSON to path and as SQL next to it, with the
+// extension replaced by .sql
+func (p *MigrationPlan) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+
+	sqlPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".sql"
+	if err := os.WriteFile(sqlPath, []byte(p.SQL()), 0644); err != nil {
+		return fmt.Errorf("failed to write migration plan SQL: %w", err)
+	}
+	return nil
+}
+
+// ReadApprovedPlanHash reads the plan hash from an approval file, which is
+// either a plan written by MigrationPlan.Save or a file holding only the hash
+func ReadApprovedPlanHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read approval file: %w", err)
+	}
+
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "{") {
+		var plan MigrationPlan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			return "", fmt.Errorf("failed to parse approval file %s: %w", path, err)
+		}
+		content = plan.Hash
+	}
+
+	if content == "" {
+		return "", fmt.Errorf("approval file %s holds no plan hash", path)
+	}
+	return content, nil
+}
@@ -0,0 +1,67 @@
+package storm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanHash(t *testing.T) {
+	statements := []string{"CREATE TABLE users (id UUID)", "ALTER TABLE users ADD COLUMN email TEXT"}
+	hash := PlanHash(statements)
+
+	if PlanHash([]string{"CREATE TABLE users (id UUID);", "  ALTER TABLE users ADD COLUMN email TEXT\n"}) != hash {
+		t.Error("Expected trailing semicolons and whitespace not to change the hash")
+	}
+	if PlanHash([]string{statements[1], statements[0]}) == hash {
+		t.Error("Expected statement order to change the hash")
+	}
+	if PlanHash([]string{"CREATE TABLE users (id UUID)ALTER TABLE users ADD COLUMN email TEXT"}) == hash {
+		t.Error("Expected statement boundaries to change the hash")
+	}
+}
+
+func TestMigrationPlanSaveAndApprove(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plans", "plan.json")
+
+	plan := NewMigrationPlan([]string{"DROP TABLE legacy"}, []string{"Drop table legacy"})
+	if err := plan.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sqlScript, err := os.ReadFile(filepath.Join(dir, "plans", "plan.sql"))
+	if err != nil {
+		t.Fatalf("Expected SQL file next to the plan: %v", err)
+	}
+	for _, want := range []string{plan.Hash, "-- DESTRUCTIVE: Drop table legacy", "DROP TABLE legacy;"} {
+		if !strings.Contains(string(sqlScript), want) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", want, sqlScript)
+		}
+	}
+
+	hash, err := ReadApprovedPlanHash(path)
+	if err != nil {
+		t.Fatalf("ReadApprovedPlanHash failed: %v", err)
+	}
+	if hash != plan.Hash {
+		t.Errorf("Expected hash %s from plan file, got %s", plan.Hash, hash)
+	}
+
+	hashFile := filepath.Join(dir, "approved.txt")
+	if err := os.WriteFile(hashFile, []byte(plan.Hash+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hash, err := ReadApprovedPlanHash(hashFile); err != nil || hash != plan.Hash {
+		t.Errorf("Expected hash %s from hash file, got %s (%v)", plan.Hash, hash, err)
+	}
+
+	emptyFile := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(emptyFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadApprovedPlanHash(emptyFile); err == nil {
+		t.Error("Expected error for an empty approval file")
+	}
+}
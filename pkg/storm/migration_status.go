@@ -0,0 +1,104 @@
+package storm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MigrationSortField selects the ordering of a migration listing
+type MigrationSortField string
+
+const (
+	SortMigrationsByName      MigrationSortField = "name"
+	SortMigrationsByAppliedAt MigrationSortField = "applied_at"
+	SortMigrationsByDuration  MigrationSortField = "duration"
+	SortMigrationsByState     MigrationSortField = "state"
+)
+
+// MigrationListOptions filters and orders the migrations of a status report
+type MigrationListOptions struct {
+	// States keeps only migrations in one of the given states; empty keeps all
+	States     []MigrationState
+	SortBy     MigrationSortField
+	Descending bool
+}
+
+// ParseMigrationState converts a user supplied state name
+func ParseMigrationState(value string) (MigrationState, error) {
+	state := MigrationState(strings.ToLower(strings.TrimSpace(value)))
+	switch state {
+	case MigrationStateApplied, MigrationStatePending, MigrationStateMissing:
+		return state, nil
+	}
+	return "", fmt.Errorf("unknown migration state %q (expected applied, pending or missing)", value)
+}
+
+// ParseMigrationSortField converts a user supplied sort field
+func ParseMigrationSortField(value string) (MigrationSortField, error) {
+	field := MigrationSortField(strings.ToLower(strings.TrimSpace(value)))
+	switch field {
+	case "":
+		return SortMigrationsByName, nil
+	case SortMigrationsByName, SortMigrationsByAppliedAt, SortMigrationsByDuration, SortMigrationsByState:
+		return field, nil
+	}
+	return "", fmt.Errorf("unknown sort field %q (expected name, applied_at, duration or state)", value)
+}
+
+// List returns the migrations matching opts in the requested order
+func (s *MigrationStatus) List(opts MigrationListOptions) []*MigrationInfo {
+	keep := make(map[MigrationState]bool, len(opts.States))
+	for _, state := range opts.States {
+		keep[state] = true
+	}
+
+	var result []*MigrationInfo
+	for _, info := range s.Migrations {
+		if len(keep) == 0 || keep[info.State] {
+			result = append(result, info)
+		}
+	}
+
+	less := migrationLess(opts.SortBy)
+	sort.SliceStable(result, func(i, j int) bool {
+		if opts.Descending {
+			return less(result[j], result[i])
+		}
+		return less(result[i], result[j])
+	})
+
+	return result
+}
+
+// migrationLess orders by the given field, falling back to name for ties
+func migrationLess(field MigrationSortField) func(a, b *MigrationInfo) bool {
+	stateOrder := map[MigrationState]int{
+		MigrationStateApplied: 0,
+		MigrationStatePending: 1,
+		MigrationStateMissing: 2,
+	}
+
+	return func(a, b *MigrationInfo) bool {
+		switch field {
+		case SortMigrationsByAppliedAt:
+			switch {
+			case a.AppliedAt == nil && b.AppliedAt != nil:
+				return false
+			case a.AppliedAt != nil && b.AppliedAt == nil:
+				return true
+			case a.AppliedAt != nil && !a.AppliedAt.Equal(*b.AppliedAt):
+				return a.AppliedAt.Before(*b.AppliedAt)
+			}
+		case SortMigrationsByDuration:
+			if a.Duration != b.Duration {
+				return a.Duration < b.Duration
+			}
+		case SortMigrationsByState:
+			if a.State != b.State {
+				return stateOrder[a.State] < stateOrder[b.State]
+			}
+		}
+		return a.Name < b.Name
+	}
+}
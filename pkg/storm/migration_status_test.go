@@ -0,0 +1,69 @@
+package storm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigrationStatusList(t *testing.T) {
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	status := &MigrationStatus{
+		Migrations: []*MigrationInfo{
+			{Name: "003_add_tags", State: MigrationStatePending, ChecksumValid: true},
+			{Name: "001_create_users", State: MigrationStateApplied, AppliedAt: &second, Duration: 20 * time.Millisecond, ChecksumValid: true},
+			{Name: "002_drop_legacy", State: MigrationStateMissing, AppliedAt: &first, Duration: 5 * time.Millisecond},
+		},
+	}
+
+	names := func(infos []*MigrationInfo) []string {
+		var result []string
+		for _, info := range infos {
+			result = append(result, info.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		opts     MigrationListOptions
+		expected []string
+	}{
+		{"by name", MigrationListOptions{}, []string{"001_create_users", "002_drop_legacy", "003_add_tags"}},
+		{"by name descending", MigrationListOptions{Descending: true}, []string{"003_add_tags", "002_drop_legacy", "001_create_users"}},
+		{"by applied time, pending last", MigrationListOptions{SortBy: SortMigrationsByAppliedAt}, []string{"002_drop_legacy", "001_create_users", "003_add_tags"}},
+		{"by duration", MigrationListOptions{SortBy: SortMigrationsByDuration, Descending: true}, []string{"001_create_users", "002_drop_legacy", "003_add_tags"}},
+		{"by state", MigrationListOptions{SortBy: SortMigrationsByState}, []string{"001_create_users", "003_add_tags", "002_drop_legacy"}},
+		{"filtered", MigrationListOptions{States: []MigrationState{MigrationStatePending, MigrationStateMissing}}, []string{"002_drop_legacy", "003_add_tags"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(status.List(tt.opts))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMigrationOptions(t *testing.T) {
+	if state, err := ParseMigrationState(" Pending "); err != nil || state != MigrationStatePending {
+		t.Errorf("expected pending, got %q (%v)", state, err)
+	}
+	if _, err := ParseMigrationState("done"); err == nil {
+		t.Error("expected an error for an unknown state")
+	}
+	if field, err := ParseMigrationSortField(""); err != nil || field != SortMigrationsByName {
+		t.Errorf("expected name by default, got %q (%v)", field, err)
+	}
+	if _, err := ParseMigrationSortField("size"); err == nil {
+		t.Error("expected an error for an unknown sort field")
+	}
+}
@@ -1,7 +1,10 @@
 package storm
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 )
 
@@ -19,6 +22,17 @@ func WithDriver(driver string) Option {
 	}
 }
 
+// WithDialect sets the SQL dialect (postgres or cockroachdb)
+func WithDialect(dialect string) Option {
+	return func(c *Config) error {
+		if dialect != DialectPostgres && dialect != DialectCockroachDB {
+			return fmt.Errorf("dialect must be '%s' or '%s'", DialectPostgres, DialectCockroachDB)
+		}
+		c.Dialect = dialect
+		return nil
+	}
+}
+
 // WithMaxConnections sets connection pool size
 func WithMaxConnections(max int) Option {
 	return func(c *Config) error {
@@ -53,6 +67,78 @@ func WithConnMaxLifetime(d time.Duration) Option {
 	}
 }
 
+// WithHealthCheckInterval enables the background health checker
+func WithHealthCheckInterval(d time.Duration) Option {
+	return func(c *Config) error {
+		if d <= 0 {
+			return fmt.Errorf("health check interval must be positive")
+		}
+		c.HealthCheckInterval = d
+		return nil
+	}
+}
+
+// WithConnectionEvents sets callbacks for connection open, close and error events
+func WithConnectionEvents(events ConnectionEvents) Option {
+	return func(c *Config) error {
+		c.ConnectionEvents = events
+		return nil
+	}
+}
+
+// WithSearchPath sets the search_path of every new connection
+func WithSearchPath(schemas ...string) Option {
+	return func(c *Config) error {
+		if len(schemas) == 0 {
+			return fmt.Errorf("search path cannot be empty")
+		}
+		for _, schema := range schemas {
+			if strings.TrimSpace(schema) == "" {
+				return fmt.Errorf("search path cannot contain an empty schema")
+			}
+		}
+		c.SearchPath = schemas
+		return nil
+	}
+}
+
+// WithApplicationName sets the application_name reported by every new connection
+func WithApplicationName(name string) Option {
+	return func(c *Config) error {
+		if name == "" {
+			return fmt.Errorf("application name cannot be empty")
+		}
+		c.ApplicationName = name
+		return nil
+	}
+}
+
+// WithSessionSettings sets run-time parameters, such as custom GUCs, on every
+// new connection. They are added to any settings already configured.
+func WithSessionSettings(settings map[string]string) Option {
+	return func(c *Config) error {
+		if c.SessionSettings == nil {
+			c.SessionSettings = make(map[string]string)
+		}
+		for name, value := range settings {
+			if strings.TrimSpace(name) == "" {
+				return fmt.Errorf("session setting names cannot be empty")
+			}
+			c.SessionSettings[name] = value
+		}
+		return nil
+	}
+}
+
+// WithOnConnect sets a callback run on every new connection after the
+// session settings are applied. An error closes the connection.
+func WithOnConnect(fn func(ctx context.Context, conn SessionConn) error) Option {
+	return func(c *Config) error {
+		c.OnConnect = fn
+		return nil
+	}
+}
+
 // WithModelsPackage sets the models package path
 func WithModelsPackage(path string) Option {
 	return func(c *Config) error {
@@ -86,6 +172,46 @@ func WithMigrationsTable(table string) Option {
 	}
 }
 
+// WithMigrationFiles sets how new migration files are named, "timestamp" or
+// "sequential", and the text/template written at the top of them
+func WithMigrationFiles(naming, header string) Option {
+	return func(c *Config) error {
+		if naming != "timestamp" && naming != "sequential" {
+			return fmt.Errorf("migration file naming must be 'timestamp' or 'sequential'")
+		}
+		c.MigrationFileNaming = naming
+		c.MigrationHeader = header
+		return nil
+	}
+}
+
+// WithMigrationProgress sets a callback called after each statement of a
+// migration being applied
+func WithMigrationProgress(fn func(MigrationProgress)) Option {
+	return func(c *Config) error {
+		c.OnMigrationProgress = fn
+		return nil
+	}
+}
+
+// WithMigrationLockMonitor watches the sessions waiting on the locks of a
+// migration being applied. Once one has waited longer than threshold the
+// migration's statement and the blocked queries are logged and, with the
+// "abort" policy, the migration is cancelled and rolled back.
+func WithMigrationLockMonitor(threshold time.Duration, policy string) Option {
+	return func(c *Config) error {
+		if threshold <= 0 {
+			return fmt.Errorf("migration lock threshold must be positive")
+		}
+		if policy != MigrationLockWarn && policy != MigrationLockAbort {
+			return fmt.Errorf("migration lock policy must be '%s' or '%s'", MigrationLockWarn, MigrationLockAbort)
+		}
+		c.MigrationLockThreshold = threshold
+		c.MigrationLockPolicy = policy
+		return nil
+	}
+}
+
 // WithAutoMigrate enables automatic migrations
 func WithAutoMigrate(enabled bool) Option {
 	return func(c *Config) error {
@@ -102,6 +228,14 @@ func WithAutoMigrateOptions(opts AutoMigrateOptions) Option {
 	}
 }
 
+// WithSchemaCheck makes New verify that the database matches the models
+func WithSchemaCheck(enabled bool) Option {
+	return func(c *Config) error {
+		c.CheckSchema = enabled
+		return nil
+	}
+}
+
 // WithGenerateHooks enables hook generation
 func WithGenerateHooks(enabled bool) Option {
 	return func(c *Config) error {
@@ -126,6 +260,30 @@ func WithGenerateMocks(enabled bool) Option {
 	}
 }
 
+// WithGenerateHandlers enables CRUD HTTP handler generation
+func WithGenerateHandlers(enabled bool) Option {
+	return func(c *Config) error {
+		c.GenerateHandlers = enabled
+		return nil
+	}
+}
+
+// WithGenerateDTOs enables request/response DTO generation
+func WithGenerateDTOs(enabled bool) Option {
+	return func(c *Config) error {
+		c.GenerateDTOs = enabled
+		return nil
+	}
+}
+
+// WithTemplatesDir sets the directory of custom code generation templates
+func WithTemplatesDir(dir string) Option {
+	return func(c *Config) error {
+		c.TemplatesDir = dir
+		return nil
+	}
+}
+
 // WithStrictMode enables strict mode
 func WithStrictMode(enabled bool) Option {
 	return func(c *Config) error {
@@ -134,6 +292,52 @@ func WithStrictMode(enabled bool) Option {
 	}
 }
 
+// WithPlural registers an irregular plural used when deriving table names
+func WithPlural(singular, plural string) Option {
+	return func(c *Config) error {
+		if singular == "" || plural == "" {
+			return fmt.Errorf("plural rule requires both a singular and a plural form")
+		}
+		if c.Plurals == nil {
+			c.Plurals = make(map[string]string)
+		}
+		c.Plurals[singular] = plural
+		return nil
+	}
+}
+
+// WithTableName sets the table name of a model, overriding the derived name
+func WithTableName(model, table string) Option {
+	return func(c *Config) error {
+		if model == "" || table == "" {
+			return fmt.Errorf("table name override requires both a model and a table name")
+		}
+		if c.TableNames == nil {
+			c.TableNames = make(map[string]string)
+		}
+		c.TableNames[model] = table
+		return nil
+	}
+}
+
+// WithIncludedModels limits the structs of the models package treated as
+// models to those matching one of the names or path.Match patterns
+func WithIncludedModels(patterns ...string) Option {
+	return func(c *Config) error {
+		c.IncludeModels = append(c.IncludeModels, patterns...)
+		return nil
+	}
+}
+
+// WithExcludedModels keeps structs matching one of the names or path.Match
+// patterns from being treated as models
+func WithExcludedModels(patterns ...string) Option {
+	return func(c *Config) error {
+		c.ExcludeModels = append(c.ExcludeModels, patterns...)
+		return nil
+	}
+}
+
 // WithNamingConvention sets the naming convention
 func WithNamingConvention(convention string) Option {
 	return func(c *Config) error {
@@ -156,6 +360,19 @@ func WithLogger(logger Logger) Option {
 	}
 }
 
+// WithSlogHandler sends all logging, including that of the migrator and
+// schema generator, to handler
+func WithSlogHandler(handler slog.Handler) Option {
+	return func(c *Config) error {
+		if handler == nil {
+			return fmt.Errorf("slog handler cannot be nil")
+		}
+		c.SlogHandler = handler
+		c.Logger = NewSlogLogger(slog.New(handler))
+		return nil
+	}
+}
+
 // WithDebug enables debug mode
 func WithDebug(enabled bool) Option {
 	return func(c *Config) error {
@@ -184,6 +401,9 @@ func WithConfig(other *Config) Option {
 		if other.Driver != "" {
 			c.Driver = other.Driver
 		}
+		if other.Dialect != "" {
+			c.Dialect = other.Dialect
+		}
 		if other.DatabaseURL != "" {
 			c.DatabaseURL = other.DatabaseURL
 		}
@@ -196,6 +416,24 @@ func WithConfig(other *Config) Option {
 		if other.ConnMaxLifetime > 0 {
 			c.ConnMaxLifetime = other.ConnMaxLifetime
 		}
+		if other.HealthCheckInterval > 0 {
+			c.HealthCheckInterval = other.HealthCheckInterval
+		}
+		if !other.ConnectionEvents.isEmpty() {
+			c.ConnectionEvents = other.ConnectionEvents
+		}
+		if len(other.SearchPath) > 0 {
+			c.SearchPath = other.SearchPath
+		}
+		if other.ApplicationName != "" {
+			c.ApplicationName = other.ApplicationName
+		}
+		if len(other.SessionSettings) > 0 {
+			c.SessionSettings = other.SessionSettings
+		}
+		if other.OnConnect != nil {
+			c.OnConnect = other.OnConnect
+		}
 		if other.ModelsPackage != "" {
 			c.ModelsPackage = other.ModelsPackage
 		}
@@ -205,17 +443,52 @@ func WithConfig(other *Config) Option {
 		if other.MigrationsTable != "" {
 			c.MigrationsTable = other.MigrationsTable
 		}
+		if other.MigrationFileNaming != "" {
+			c.MigrationFileNaming = other.MigrationFileNaming
+		}
+		if other.MigrationHeader != "" {
+			c.MigrationHeader = other.MigrationHeader
+		}
+		if other.OnMigrationProgress != nil {
+			c.OnMigrationProgress = other.OnMigrationProgress
+		}
+		if other.MigrationLockThreshold > 0 {
+			c.MigrationLockThreshold = other.MigrationLockThreshold
+		}
+		if other.MigrationLockPolicy != "" {
+			c.MigrationLockPolicy = other.MigrationLockPolicy
+		}
+		if other.TemplatesDir != "" {
+			c.TemplatesDir = other.TemplatesDir
+		}
 		if other.NamingConvention != "" {
 			c.NamingConvention = other.NamingConvention
 		}
+		if len(other.Plurals) > 0 {
+			c.Plurals = other.Plurals
+		}
+		if len(other.TableNames) > 0 {
+			c.TableNames = other.TableNames
+		}
+		if len(other.IncludeModels) > 0 {
+			c.IncludeModels = other.IncludeModels
+		}
+		if len(other.ExcludeModels) > 0 {
+			c.ExcludeModels = other.ExcludeModels
+		}
 		if other.Logger != nil {
 			c.Logger = other.Logger
 		}
+		if other.SlogHandler != nil {
+			c.SlogHandler = other.SlogHandler
+		}
 
 		c.AutoMigrate = other.AutoMigrate
 		c.GenerateHooks = other.GenerateHooks
 		c.GenerateTests = other.GenerateTests
 		c.GenerateMocks = other.GenerateMocks
+		c.GenerateHandlers = other.GenerateHandlers
+		c.GenerateDTOs = other.GenerateDTOs
 		c.StrictMode = other.StrictMode
 		c.Debug = other.Debug
 
@@ -0,0 +1,333 @@
+package storm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PoolStats describes the state of the connection pool
+type PoolStats struct {
+	MaxOpenConnections int           `json:"max_open_connections"`
+	OpenConnections    int           `json:"open_connections"`
+	InUse              int           `json:"in_use"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"wait_count"`
+	WaitDuration       time.Duration `json:"wait_duration"`
+	MaxIdleClosed      int64         `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64         `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64         `json:"max_lifetime_closed"`
+}
+
+// ConnectionEvents holds callbacks fired during the connection lifecycle
+type ConnectionEvents struct {
+	OnOpen  func()
+	OnClose func()
+	OnError func(err error)
+}
+
+func (e ConnectionEvents) isEmpty() bool {
+	return e.OnOpen == nil && e.OnClose == nil && e.OnError == nil
+}
+
+func (e ConnectionEvents) open() {
+	if e.OnOpen != nil {
+		e.OnOpen()
+	}
+}
+
+func (e ConnectionEvents) close() {
+	if e.OnClose != nil {
+		e.OnClose()
+	}
+}
+
+func (e ConnectionEvents) error(err error) {
+	if e.OnError != nil && err != nil {
+		e.OnError(err)
+	}
+}
+
+// SessionConn runs statements on a connection being set up by OnConnect
+type SessionConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) error
+}
+
+// hasSessionSetup reports whether new connections need configuring before use
+func (c *Config) hasSessionSetup() bool {
+	return len(c.SearchPath) > 0 || c.ApplicationName != "" || len(c.SessionSettings) > 0 || c.OnConnect != nil
+}
+
+// setupSession applies the search path, application name and session
+// settings of config to a new connection, then calls OnConnect. Settings made
+// here last for the life of the connection, so every query the pool runs on
+// it sees them.
+func setupSession(ctx context.Context, conn SessionConn, config *Config) error {
+	if len(config.SearchPath) > 0 {
+		if err := conn.ExecContext(ctx, "SELECT set_config('search_path', $1, false)", strings.Join(config.SearchPath, ", ")); err != nil {
+			return fmt.Errorf("failed to set search_path: %w", err)
+		}
+	}
+	if config.ApplicationName != "" {
+		if err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", config.ApplicationName); err != nil {
+			return fmt.Errorf("failed to set application_name: %w", err)
+		}
+	}
+
+	names := make([]string, 0, len(config.SessionSettings))
+	for name := range config.SessionSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := conn.ExecContext(ctx, "SELECT set_config($1, $2, false)", name, config.SessionSettings[name]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+
+	if config.OnConnect != nil {
+		if err := config.OnConnect(ctx, conn); err != nil {
+			return fmt.Errorf("connection setup failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// PoolStats returns the current connection pool statistics
+func (s *Storm) PoolStats() PoolStats {
+	stats := s.db.Stats()
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+// Healthy reports whether the database is reachable. When the background
+// health checker is running the last observed state is returned, otherwise
+// the database is pinged directly.
+func (s *Storm) Healthy() bool {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return false
+	}
+
+	if s.healthStop != nil {
+		return s.healthy.Load()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.db.PingContext(ctx) == nil
+}
+
+// startHealthChecker pings the database every interval until Close is called
+func (s *Storm) startHealthChecker(interval time.Duration) {
+	s.healthStop = make(chan struct{})
+	s.healthDone = make(chan struct{})
+
+	go func() {
+		defer close(s.healthDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.checkHealth(interval)
+
+			select {
+			case <-s.healthStop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Storm) checkHealth(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.db.PingContext(ctx)
+	wasHealthy := s.healthy.Swap(err == nil)
+
+	if err != nil {
+		s.config.ConnectionEvents.error(err)
+		if wasHealthy {
+			s.logger.Warn("Database health check failed", "error", err)
+		}
+	} else if !wasHealthy {
+		s.logger.Info("Database health check succeeded")
+	}
+}
+
+// stopHealthChecker stops the background health checker if it is running
+func (s *Storm) stopHealthChecker() {
+	if s.healthStop == nil {
+		return
+	}
+	close(s.healthStop)
+	<-s.healthDone
+}
+
+// openDB opens the connection pool, instrumenting it with lifecycle events
+// and session setup when configured
+func openDB(config *Config) (*sqlx.DB, error) {
+	if config.ConnectionEvents.isEmpty() && !config.hasSessionSetup() {
+		return sqlx.Open(config.Driver, config.DatabaseURL)
+	}
+	if config.Driver != "postgres" {
+		if config.hasSessionSetup() {
+			return nil, fmt.Errorf("session setup requires the postgres driver, got %s", config.Driver)
+		}
+		return sqlx.Open(config.Driver, config.DatabaseURL)
+	}
+
+	connector, err := pq.NewConnector(config.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := &eventConnector{connector: connector, events: config.ConnectionEvents}
+	if config.hasSessionSetup() {
+		wrapped.setup = config
+	}
+	return sqlx.NewDb(sql.OpenDB(wrapped), config.Driver), nil
+}
+
+// eventConnector wraps a driver.Connector to report connection lifecycle
+// events and set up the session of each new connection
+type eventConnector struct {
+	connector driver.Connector
+	events    ConnectionEvents
+	setup     *Config
+}
+
+func (c *eventConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		c.events.error(err)
+		return nil, err
+	}
+	if c.setup != nil {
+		if err := setupSession(ctx, &sessionConn{conn: conn}, c.setup); err != nil {
+			conn.Close()
+			c.events.error(err)
+			return nil, err
+		}
+	}
+	c.events.open()
+	return &eventConn{Conn: conn, events: c.events}, nil
+}
+
+func (c *eventConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// eventConn wraps a driver.Conn, forwarding optional driver interfaces
+type eventConn struct {
+	driver.Conn
+	events ConnectionEvents
+}
+
+func (c *eventConn) Close() error {
+	err := c.Conn.Close()
+	c.events.close()
+	return err
+}
+
+func (c *eventConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *eventConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *eventConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *eventConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *eventConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *eventConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *eventConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// sessionConn runs setup statements directly on a driver connection
+type sessionConn struct {
+	conn driver.Conn
+}
+
+func (c *sessionConn) ExecContext(ctx context.Context, query string, args ...interface{}) error {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	if execer, ok := c.conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, named)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := c.conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	_, err = stmt.Exec(values)
+	return err
+}
@@ -0,0 +1,227 @@
+package storm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaReport describes how the live database falls short of the schema the
+// models describe. Tables and columns the models do not use are ignored.
+type SchemaReport struct {
+	DesiredHash       string           // SchemaHash of the models' schema
+	MissingTables     []string         // Tables the models need that do not exist
+	MissingColumns    []string         // Columns that do not exist, as table.column
+	MismatchedColumns []ColumnMismatch // Columns whose type or nullability differ
+}
+
+// ColumnMismatch is a column whose definition differs from its model
+type ColumnMismatch struct {
+	Table            string
+	Column           string
+	ExpectedType     string
+	ActualType       string
+	ExpectedNullable bool
+	ActualNullable   bool
+}
+
+// Compatible reports whether the database has everything the models need
+func (r *SchemaReport) Compatible() bool {
+	return len(r.MissingTables) == 0 && len(r.MissingColumns) == 0 && len(r.MismatchedColumns) == 0
+}
+
+// String summarizes the mismatches on one line
+func (r *SchemaReport) String() string {
+	if r.Compatible() {
+		return "schema is compatible"
+	}
+
+	var parts []string
+	if len(r.MissingTables) > 0 {
+		parts = append(parts, "missing tables: "+strings.Join(r.MissingTables, ", "))
+	}
+	if len(r.MissingColumns) > 0 {
+		parts = append(parts, "missing columns: "+strings.Join(r.MissingColumns, ", "))
+	}
+	if len(r.MismatchedColumns) > 0 {
+		mismatches := make([]string, len(r.MismatchedColumns))
+		for i, m := range r.MismatchedColumns {
+			mismatches[i] = fmt.Sprintf("%s.%s (%s, got %s)", m.Table, m.Column,
+				describeColumn(m.ExpectedType, m.ExpectedNullable), describeColumn(m.ActualType, m.ActualNullable))
+		}
+		parts = append(parts, "mismatched columns: "+strings.Join(mismatches, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func describeColumn(columnType string, nullable bool) string {
+	if nullable {
+		return columnType + " NULL"
+	}
+	return columnType + " NOT NULL"
+}
+
+// CheckSchemaCompatibility compares the schema described by the models in
+// Config.ModelsPackage with the live database and reports every table and
+// column that is missing or defined differently. The hash of the models'
+// schema is recorded and available from SchemaHash afterwards. When
+// Config.CheckSchema is set the check runs in New, which then fails with
+// ErrSchemaMismatch instead of starting against an unmigrated database.
+func (s *Storm) CheckSchemaCompatibility(ctx context.Context) (*SchemaReport, error) {
+	desired, err := s.migrator.DesiredSchema(ctx)
+	if err != nil {
+		return nil, NewSchemaError("desired_schema", err)
+	}
+
+	live, err := s.schema.Inspect(ctx)
+	if err != nil {
+		return nil, NewSchemaError("inspect", err)
+	}
+
+	report := CompareSchemas(desired, live)
+
+	s.mu.Lock()
+	s.schemaHash = report.DesiredHash
+	s.mu.Unlock()
+
+	return report, nil
+}
+
+// SchemaHash returns the hash of the models' schema recorded by the last
+// CheckSchemaCompatibility, or an empty string before the first check
+func (s *Storm) SchemaHash() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.schemaHash
+}
+
+// checkSchema runs the startup compatibility check
+func (s *Storm) checkSchema(ctx context.Context) error {
+	report, err := s.CheckSchemaCompatibility(ctx)
+	if err != nil {
+		return err
+	}
+	if !report.Compatible() {
+		return NewSchemaError("check_schema", fmt.Errorf("%w: %s", ErrSchemaMismatch, report)).
+			WithDetails("report", report)
+	}
+
+	s.logger.Info("Schema check passed", "hash", report.DesiredHash)
+	return nil
+}
+
+// CompareSchemas reports what live lacks to serve the models described by desired
+func CompareSchemas(desired, live *Schema) *SchemaReport {
+	report := &SchemaReport{DesiredHash: SchemaHash(desired)}
+
+	for _, tableName := range sortedKeys(desired.Tables) {
+		want := desired.Tables[tableName]
+		have, ok := live.Tables[tableName]
+		if !ok {
+			report.MissingTables = append(report.MissingTables, tableName)
+			continue
+		}
+
+		for _, columnName := range sortedKeys(want.Columns) {
+			wantCol := want.Columns[columnName]
+			haveCol, ok := have.Columns[columnName]
+			if !ok {
+				report.MissingColumns = append(report.MissingColumns, tableName+"."+columnName)
+				continue
+			}
+
+			if !columnTypesMatch(wantCol.Type, haveCol.Type) || wantCol.Nullable != haveCol.Nullable {
+				report.MismatchedColumns = append(report.MismatchedColumns, ColumnMismatch{
+					Table:            tableName,
+					Column:           columnName,
+					ExpectedType:     wantCol.Type,
+					ActualType:       haveCol.Type,
+					ExpectedNullable: wantCol.Nullable,
+					ActualNullable:   haveCol.Nullable,
+				})
+			}
+		}
+	}
+
+	return report
+}
+
+// SchemaHash returns a stable hash of the tables, columns, types and
+// nullability of a schema. Type aliases such as VARCHAR(255) and character
+// varying hash alike, so the hash of the models' schema can be compared across
+// builds and deployments.
+func SchemaHash(schema *Schema) string {
+	h := sha256.New()
+	for _, tableName := range sortedKeys(schema.Tables) {
+		table := schema.Tables[tableName]
+		fmt.Fprintf(h, "table %s\n", tableName)
+		for _, columnName := range sortedKeys(table.Columns) {
+			column := table.Columns[columnName]
+			fmt.Fprintf(h, "column %s %s %t\n", columnName, normalizeColumnType(column.Type), column.Nullable)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// columnTypeAliases maps the spellings accepted in model tags to the names
+// PostgreSQL reports in information_schema
+var columnTypeAliases = map[string]string{
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"float":       "double precision",
+	"bool":        "boolean",
+	"decimal":     "numeric",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+}
+
+// normalizeColumnType reduces a column type to the form information_schema
+// reports, dropping lengths and precisions
+func normalizeColumnType(columnType string) string {
+	t := strings.ToLower(strings.TrimSpace(columnType))
+	if strings.HasSuffix(t, "[]") || t == "array" {
+		return "array"
+	}
+	if i := strings.Index(t, "("); i >= 0 {
+		t = strings.TrimSpace(t[:i] + t[strings.LastIndex(t, ")")+1:])
+	}
+	if alias, ok := columnTypeAliases[t]; ok {
+		return alias
+	}
+	return t
+}
+
+// columnTypesMatch compares a model column type with a live one. Enums and
+// other user-defined types are reported by PostgreSQL without their name, so
+// they match any model type.
+func columnTypesMatch(desired, live string) bool {
+	actual := normalizeColumnType(live)
+	return actual == "user-defined" || normalizeColumnType(desired) == actual
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package storm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fixedSchemaMigrator struct {
+	migrator
+	schema *Schema
+}
+
+func (m *fixedSchemaMigrator) DesiredSchema(ctx context.Context) (*Schema, error) {
+	return m.schema, nil
+}
+
+type fixedSchemaInspector struct {
+	schemaInspector
+	schema *Schema
+}
+
+func (i *fixedSchemaInspector) Inspect(ctx context.Context) (*Schema, error) {
+	return i.schema, nil
+}
+
+func modelSchema() *Schema {
+	return &Schema{Tables: map[string]*Table{
+		"users": {Name: "users", Columns: map[string]*Column{
+			"id":         {Name: "id", Type: "UUID"},
+			"email":      {Name: "email", Type: "VARCHAR(255)"},
+			"created_at": {Name: "created_at", Type: "TIMESTAMPTZ"},
+			"role":       {Name: "role", Type: "user_role"},
+		}},
+		"posts": {Name: "posts", Columns: map[string]*Column{
+			"id": {Name: "id", Type: "SERIAL"},
+		}},
+	}}
+}
+
+func TestCompareSchemas(t *testing.T) {
+	live := &Schema{Tables: map[string]*Table{
+		"users": {Name: "users", Columns: map[string]*Column{
+			"id":         {Name: "id", Type: "uuid"},
+			"email":      {Name: "email", Type: "character varying"},
+			"created_at": {Name: "created_at", Type: "timestamp with time zone"},
+			"role":       {Name: "role", Type: "USER-DEFINED"},
+			"legacy":     {Name: "legacy", Type: "text", Nullable: true},
+		}},
+		"posts": {Name: "posts", Columns: map[string]*Column{
+			"id": {Name: "id", Type: "integer"},
+		}},
+		"schema_migrations": {Name: "schema_migrations"},
+	}}
+
+	report := CompareSchemas(modelSchema(), live)
+	if !report.Compatible() {
+		t.Fatalf("Expected compatible schema, got %s", report)
+	}
+
+	delete(live.Tables, "posts")
+	delete(live.Tables["users"].Columns, "email")
+	live.Tables["users"].Columns["created_at"].Nullable = true
+
+	report = CompareSchemas(modelSchema(), live)
+	if report.Compatible() {
+		t.Fatal("Expected incompatible schema")
+	}
+	if !reflect.DeepEqual(report.MissingTables, []string{"posts"}) {
+		t.Errorf("Expected missing table posts, got %v", report.MissingTables)
+	}
+	if !reflect.DeepEqual(report.MissingColumns, []string{"users.email"}) {
+		t.Errorf("Expected missing column users.email, got %v", report.MissingColumns)
+	}
+	if len(report.MismatchedColumns) != 1 || report.MismatchedColumns[0].Column != "created_at" {
+		t.Fatalf("Expected created_at mismatch, got %+v", report.MismatchedColumns)
+	}
+
+	expected := "missing tables: posts; missing columns: users.email; " +
+		"mismatched columns: users.created_at (TIMESTAMPTZ NOT NULL, got timestamp with time zone NULL)"
+	if report.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, report.String())
+	}
+}
+
+func TestSchemaHash(t *testing.T) {
+	hash := SchemaHash(modelSchema())
+	if hash != SchemaHash(modelSchema()) {
+		t.Error("Expected hash to be stable")
+	}
+
+	aliased := modelSchema()
+	aliased.Tables["users"].Columns["email"].Type = "character varying(100)"
+	if SchemaHash(aliased) != hash {
+		t.Error("Expected type aliases to hash alike")
+	}
+
+	changed := modelSchema()
+	changed.Tables["users"].Columns["email"].Nullable = true
+	if SchemaHash(changed) == hash {
+		t.Error("Expected nullability to change the hash")
+	}
+}
+
+func TestCheckSchemaCompatibility(t *testing.T) {
+	s := &Storm{
+		config:   NewConfig(),
+		logger:   NewDefaultLogger(),
+		migrator: &fixedSchemaMigrator{schema: modelSchema()},
+		schema:   &fixedSchemaInspector{schema: &Schema{Tables: map[string]*Table{}}},
+	}
+
+	report, err := s.CheckSchemaCompatibility(context.Background())
+	if err != nil {
+		t.Fatalf("CheckSchemaCompatibility failed: %v", err)
+	}
+	if !reflect.DeepEqual(report.MissingTables, []string{"posts", "users"}) {
+		t.Errorf("Expected missing tables posts and users, got %v", report.MissingTables)
+	}
+	if s.SchemaHash() != SchemaHash(modelSchema()) {
+		t.Errorf("Expected recorded hash %s, got %s", SchemaHash(modelSchema()), s.SchemaHash())
+	}
+
+	err = s.checkSchema(context.Background())
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("Expected ErrSchemaMismatch, got %v", err)
+	}
+	var stormErr *Error
+	if !errors.As(err, &stormErr) || stormErr.Details["report"] == nil {
+		t.Error("Expected the report in the error details")
+	}
+
+	s.migrator = &migrator{}
+	if _, err := s.CheckSchemaCompatibility(context.Background()); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected ErrNotImplemented, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package storm
+
+import (
+	"context"
+	"fmt"
+)
+
+// AutoMigrateShards applies the models' schema to every shard database in
+// turn, using config for everything except the database URL. Shards are
+// migrated one at a time and the first failure stops the run, leaving the
+// shards before it migrated; running it again picks up where it stopped.
+func AutoMigrateShards(ctx context.Context, config *Config, shardURLs []string, opts ...AutoMigrateOptions) error {
+	if config == nil {
+		return NewConfigError("auto_migrate_shards", fmt.Errorf("config cannot be nil"))
+	}
+	if len(shardURLs) == 0 {
+		return NewConfigError("auto_migrate_shards", fmt.Errorf("at least one shard URL is required"))
+	}
+
+	for i, url := range shardURLs {
+		shardConfig := *config
+		shardConfig.DatabaseURL = url
+		shardConfig.AutoMigrate = false
+		shardConfig.CheckSchema = false
+		shardConfig.HealthCheckInterval = 0
+
+		if err := autoMigrateShard(ctx, &shardConfig, opts); err != nil {
+			return NewMigrationError("auto_migrate_shards", fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return nil
+}
+
+func autoMigrateShard(ctx context.Context, config *Config, opts []AutoMigrateOptions) error {
+	s, err := NewWithConfig(config)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.AutoMigrate(ctx, opts...)
+}
@@ -0,0 +1,15 @@
+package storm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAutoMigrateShardsValidation(t *testing.T) {
+	if err := AutoMigrateShards(context.Background(), nil, []string{"postgres://localhost/a"}); err == nil {
+		t.Error("Expected error for nil config")
+	}
+	if err := AutoMigrateShards(context.Background(), NewConfig(), nil); err == nil {
+		t.Error("Expected error when no shard URLs are given")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -21,10 +22,18 @@ type Storm struct {
 	orm      *ORM
 	schema   SchemaInspector
 
+	// Hash of the models' schema from the last compatibility check
+	schemaHash string
+
 	// Internal state
 	mu     sync.RWMutex
 	closed bool
 	logger Logger
+
+	// Health checking
+	healthy    atomic.Bool
+	healthStop chan struct{}
+	healthDone chan struct{}
 }
 
 // New creates a new Storm instance with the given database URL
@@ -51,10 +60,11 @@ func NewWithConfig(config *Config) (*Storm, error) {
 		return nil, NewConfigError("validate", err)
 	}
 
-	db, err := sqlx.Open(config.Driver, config.DatabaseURL)
+	db, err := openDB(config)
 	if err != nil {
 		return nil, NewConnectionError("open", err)
 	}
+	applySlogHandler(config)
 
 	db.SetMaxOpenConns(config.MaxOpenConns)
 	db.SetMaxIdleConns(config.MaxIdleConns)
@@ -71,6 +81,10 @@ func NewWithConfig(config *Config) (*Storm, error) {
 		return nil, err
 	}
 
+	if config.HealthCheckInterval > 0 {
+		storm.startHealthChecker(config.HealthCheckInterval)
+	}
+
 	return storm, nil
 }
 
@@ -103,6 +117,15 @@ func (s *Storm) initialize() error {
 		}
 	}
 
+	if s.config.CheckSchema {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := s.checkSchema(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -180,6 +203,7 @@ func (s *Storm) Close() error {
 	}
 
 	s.closed = true
+	s.stopHealthChecker()
 	return s.db.Close()
 }
 
@@ -221,10 +245,13 @@ func (s *Storm) Generate(ctx context.Context, opts ...GenerateOptions) error {
 		options = opts[0]
 	} else {
 		options = GenerateOptions{
-			PackagePath:  s.config.ModelsPackage,
-			IncludeHooks: s.config.GenerateHooks,
-			IncludeTests: s.config.GenerateTests,
-			IncludeMocks: s.config.GenerateMocks,
+			PackagePath:     s.config.ModelsPackage,
+			IncludeHooks:    s.config.GenerateHooks,
+			IncludeTests:    s.config.GenerateTests,
+			IncludeMocks:    s.config.GenerateMocks,
+			IncludeHandlers: s.config.GenerateHandlers,
+			IncludeDTOs:     s.config.GenerateDTOs,
+			TemplatesDir:    s.config.TemplatesDir,
 		}
 	}
 
@@ -253,6 +280,13 @@ func (s *Storm) AutoMigrate(ctx context.Context, opts ...AutoMigrateOptions) err
 	return s.migrator.AutoMigrate(ctx, options)
 }
 
+// MigrateTenants applies the pending migrations to every tenant schema,
+// tracking each tenant in a migrations table of its own. Failures are
+// reported per tenant; see TenantMigrationReport.Err.
+func (s *Storm) MigrateTenants(ctx context.Context, opts TenantMigrateOptions) (*TenantMigrationReport, error) {
+	return s.migrator.MigrateTenants(ctx, opts)
+}
+
 type migrator struct {
 	storm *Storm
 }
@@ -285,6 +319,14 @@ func (m *migrator) AutoMigrate(ctx context.Context, opts AutoMigrateOptions) err
 	return ErrNotImplemented
 }
 
+func (m *migrator) DesiredSchema(ctx context.Context) (*Schema, error) {
+	return nil, ErrNotImplemented
+}
+
+func (m *migrator) MigrateTenants(ctx context.Context, opts TenantMigrateOptions) (*TenantMigrationReport, error) {
+	return nil, ErrNotImplemented
+}
+
 type ORM struct {
 	storm *Storm
 	impl  ORMGenerator